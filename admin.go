@@ -0,0 +1,31 @@
+package tracker
+
+import (
+	"embed"
+	"html/template"
+	"net/http"
+)
+
+//go:embed templates/admin.html
+var adminTemplates embed.FS
+
+var adminTemplate = template.Must(template.ParseFS(adminTemplates, "templates/admin.html"))
+
+// adminPage данные шаблона админки
+type adminPage struct {
+	Statuses []string
+}
+
+// adminHandler отдаёт страницу админки /admin. Страница работает поверх HTTP API:
+// ищет посылки, показывает доску со столбцами по статусам и меняет статусы и адреса.
+type adminHandler struct{}
+
+func (h adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := adminTemplate.Execute(w, adminPage{
+		Statuses: parcelStatuses,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
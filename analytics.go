@@ -0,0 +1,207 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// dayLayout формат дня в daily_stats; строки дат сравниваются в порядке времени
+const dayLayout = "2006-01-02"
+
+const (
+	// queryRefreshStats пересчитывает итоги дней с :from по :to не включительно: посылки,
+	// зарегистрированные в день, и доставленные в день с суммой времени доставки.
+	// Архивные посылки тоже считаются, иначе перенос в архив менял бы прошлые итоги.
+	queryRefreshStats = `WITH p AS (
+			SELECT tenant_id, created_at, delivered_at, zone FROM parcel WHERE ` + tenantCond + `
+			UNION ALL SELECT tenant_id, created_at, delivered_at, zone FROM parcel_archive WHERE ` + tenantCond + `
+		)
+		INSERT INTO daily_stats (tenant_id, day, zone, created, delivered, delivery_seconds)
+		SELECT tenant_id, day, zone, SUM(created), SUM(delivered), SUM(seconds) FROM (
+			SELECT tenant_id, substr(created_at, 1, 10) AS day, COALESCE(zone, '') AS zone, 1 AS created, 0 AS delivered, 0 AS seconds
+			FROM p WHERE created_at >= :from AND created_at < :to
+			UNION ALL
+			SELECT tenant_id, substr(delivered_at, 1, 10), COALESCE(zone, ''), 0, 1, (julianday(delivered_at) - julianday(created_at)) * 86400
+			FROM p WHERE delivered_at >= :from AND delivered_at < :to
+		) GROUP BY tenant_id, day, zone`
+	queryDeleteStats = "DELETE FROM daily_stats WHERE day >= :from AND day < :to AND " + tenantCond
+	queryLastStats   = "SELECT COALESCE(MAX(day), '') FROM daily_stats WHERE " + tenantCond
+	queryFirstParcel = `SELECT COALESCE(MIN(created_at), '') FROM (
+		SELECT MIN(created_at) AS created_at FROM parcel WHERE ` + tenantCond + `
+		UNION ALL SELECT MIN(created_at) FROM parcel_archive WHERE ` + tenantCond + `)`
+	queryDailyStats = `SELECT day, zone, SUM(created), SUM(delivered), SUM(delivery_seconds) FROM daily_stats
+		WHERE day >= :from AND day < :to AND ` + tenantCond + ` GROUP BY day, zone ORDER BY day, zone`
+	queryZoneStats = `SELECT zone, SUM(created), SUM(delivered), SUM(delivery_seconds) FROM daily_stats
+		WHERE day >= :from AND day < :to AND ` + tenantCond + ` GROUP BY zone ORDER BY zone`
+)
+
+// ZoneStats итоги зоны доставки за период
+type ZoneStats struct {
+	// Zone зона доставки; пусто — посылки вне зон
+	Zone string
+	// Created сколько посылок зарегистрировано
+	Created int
+	// Delivered сколько посылок доставлено
+	Delivered int
+	// AverageDelivery среднее время от регистрации до доставки доставленных посылок
+	AverageDelivery time.Duration
+}
+
+// DailyStats итоги зоны доставки за день
+type DailyStats struct {
+	// Day начало дня в UTC
+	Day time.Time
+	ZoneStats
+}
+
+// ReportsStore итоги по дням и зонам для дашбордов. Итоги хранятся в таблице daily_stats,
+// поэтому их чтение не просматривает таблицу parcel; пересчитывает их задача analytics.
+// Периоды задаются днями в UTC, from и to входят в период.
+type ReportsStore struct {
+	store ParcelStore
+	// now текущее время; подменяется в тестах
+	now func() time.Time
+}
+
+// NewReportsStore создаёт итоги по посылкам хранилища store
+func NewReportsStore(store ParcelStore) *ReportsStore {
+	return &ReportsStore{store: store, now: time.Now}
+}
+
+// Refresh пересчитывает итоги дней с from по to в одной транзакции
+func (r *ReportsStore) Refresh(ctx context.Context, from, to time.Time) error {
+	return r.store.WithContext(ctx).refreshDailyStats(dayStart(from), dayStart(to).AddDate(0, 0, 1))
+}
+
+// RefreshDue пересчитывает итоги с последнего посчитанного дня, а в первый раз — с первой
+// посылки, по сегодняшний. Это задача планировщика analytics. Последний посчитанный день
+// пересчитывается заново вместе с предыдущим: их итоги могли измениться после прошлого запуска.
+func (r *ReportsStore) RefreshDue(ctx context.Context) error {
+	store := r.store.WithContext(ctx)
+	var last string
+	err := store.queryRow(nil, queryLastStats, sql.Named("tenant", store.tenant())).Scan(&last)
+	if err != nil {
+		return err
+	}
+	if last == "" {
+		err = store.queryRow(nil, queryFirstParcel, sql.Named("tenant", store.tenant())).Scan(&last)
+		if err != nil {
+			return err
+		}
+		if last == "" {
+			// посылок ещё нет
+			return nil
+		}
+	}
+	from, err := time.Parse(dayLayout, last[:len(dayLayout)])
+	if err != nil {
+		return err
+	}
+	return r.Refresh(ctx, from.AddDate(0, 0, -1), r.now())
+}
+
+// Daily возвращает итоги каждой зоны за каждый день с from по to в порядке дней и зон;
+// дней и зон без посылок в ответе нет
+func (r *ReportsStore) Daily(ctx context.Context, from, to time.Time) ([]DailyStats, error) {
+	store := r.store.WithContext(ctx)
+	defer store.metrics.observeQuery("DailyStats", time.Now())
+	rows, err := store.readQuery(queryDailyStats, statsRange(from, to, store.tenant())...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []DailyStats
+	for rows.Next() {
+		var st DailyStats
+		var day string
+		var seconds float64
+		err := rows.Scan(&day, &st.Zone, &st.Created, &st.Delivered, &seconds)
+		if err != nil {
+			return nil, err
+		}
+		st.Day, err = time.Parse(dayLayout, day)
+		if err != nil {
+			return nil, err
+		}
+		st.AverageDelivery = averageDelivery(seconds, st.Delivered)
+		res = append(res, st)
+	}
+	return res, rows.Err()
+}
+
+// Zones возвращает итоги каждой зоны за дни с from по to в порядке зон
+func (r *ReportsStore) Zones(ctx context.Context, from, to time.Time) ([]ZoneStats, error) {
+	store := r.store.WithContext(ctx)
+	defer store.metrics.observeQuery("ZoneStats", time.Now())
+	rows, err := store.readQuery(queryZoneStats, statsRange(from, to, store.tenant())...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []ZoneStats
+	for rows.Next() {
+		var st ZoneStats
+		var seconds float64
+		err := rows.Scan(&st.Zone, &st.Created, &st.Delivered, &seconds)
+		if err != nil {
+			return nil, err
+		}
+		st.AverageDelivery = averageDelivery(seconds, st.Delivered)
+		res = append(res, st)
+	}
+	return res, rows.Err()
+}
+
+// statsRange параметры запроса итогов за дни с from по to компании tenant
+func statsRange(from, to time.Time, tenant string) []any {
+	return []any{
+		sql.Named("from", dayStart(from).Format(dayLayout)),
+		sql.Named("to", dayStart(to).AddDate(0, 0, 1).Format(dayLayout)),
+		sql.Named("tenant", tenant),
+	}
+}
+
+// averageDelivery возвращает среднее время доставки по сумме seconds за n посылок
+func averageDelivery(seconds float64, n int) time.Duration {
+	if n == 0 {
+		return 0
+	}
+	return time.Duration(seconds / float64(n) * float64(time.Second)).Round(time.Second)
+}
+
+// refreshDailyStats пересчитывает итоги дней с from по to не включительно
+func (s ParcelStore) refreshDailyStats(from, to time.Time) error {
+	start := time.Now()
+	span := s.startSpan("RefreshDailyStats")
+	defer span.End()
+
+	args := []any{
+		sql.Named("from", from.Format(dayLayout)),
+		sql.Named("to", to.Format(dayLayout)),
+		sql.Named("tenant", s.tenant()),
+	}
+	err := s.withRetry("store.RefreshDailyStats", func(s ParcelStore) error {
+		tx, err := s.begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		_, err = s.exec(tx, queryDeleteStats, args...)
+		if err != nil {
+			return err
+		}
+		_, err = s.exec(tx, queryRefreshStats, args...)
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("RefreshDailyStats", start)
+	logResult(s.ctx, s.logger, "store.RefreshDailyStats", start, err, "from", from, "to", to)
+	return err
+}
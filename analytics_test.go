@@ -0,0 +1,77 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReportsStore проверяет дневные итоги по зонам, их пересчёт и то, что перенос
+// в архив их не меняет
+func TestReportsStore(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+	store := NewParcelStore(db)
+	day := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+	add := func(createdAt time.Time, zone string, deliveredAt time.Time) int {
+		t.Helper()
+		id := mustAddParcel(t, store, newParcelFixture().createdAt(createdAt).build()).Number
+		_, err := db.Exec("UPDATE parcel SET zone = NULLIF(?, '') WHERE number = ?", zone, id)
+		require.NoError(t, err)
+		if !deliveredAt.IsZero() {
+			_, err = db.Exec("UPDATE parcel SET status = 'delivered', delivered_at = ? WHERE number = ?", deliveredAt.Format(time.RFC3339), id)
+			require.NoError(t, err)
+		}
+		return id
+	}
+	add(day.Add(10*time.Hour), "msk", day.Add(34*time.Hour))
+	add(day.Add(12*time.Hour), "msk", day.Add(24*time.Hour))
+	add(day.Add(33*time.Hour), "", time.Time{})
+
+	reports := NewReportsStore(store)
+	ctx := context.Background()
+	next := day.AddDate(0, 0, 1)
+	wantDaily := []DailyStats{
+		{Day: day, ZoneStats: ZoneStats{Zone: "msk", Created: 2}},
+		{Day: next, ZoneStats: ZoneStats{Zone: "", Created: 1}},
+		{Day: next, ZoneStats: ZoneStats{Zone: "msk", Delivered: 2, AverageDelivery: 18 * time.Hour}},
+	}
+	wantZones := []ZoneStats{
+		{Zone: "", Created: 1},
+		{Zone: "msk", Created: 2, Delivered: 2, AverageDelivery: 18 * time.Hour},
+	}
+	check := func() {
+		t.Helper()
+		daily, err := reports.Daily(ctx, day, next)
+		require.NoError(t, err)
+		assert.Equal(t, wantDaily, daily)
+		zones, err := reports.Zones(ctx, day, next)
+		require.NoError(t, err)
+		assert.Equal(t, wantZones, zones)
+	}
+
+	// refresh
+	require.NoError(t, reports.Refresh(ctx, day, next))
+	check()
+	// повторный пересчёт не удваивает итоги
+	require.NoError(t, reports.Refresh(ctx, day, next))
+	check()
+
+	// archive
+	n, err := store.ArchiveDelivered(time.Now(), 10, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	reports.now = func() time.Time { return next.Add(time.Hour) }
+	require.NoError(t, reports.RefreshDue(ctx))
+	check()
+
+	// period
+	daily, err := reports.Daily(ctx, next, next)
+	require.NoError(t, err)
+	assert.Equal(t, wantDaily[1:], daily)
+}
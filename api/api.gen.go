@@ -0,0 +1,4155 @@
+//go:build go1.22
+
+// Package api provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.4.1 DO NOT EDIT.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/oapi-codegen/runtime"
+	strictnethttp "github.com/oapi-codegen/runtime/strictmiddleware/nethttp"
+)
+
+const (
+	ApiKeyScopes = "apiKey.Scopes"
+)
+
+// Defines values for AttachmentKind.
+const (
+	AttachmentKindCustoms AttachmentKind = "customs"
+	AttachmentKindInvoice AttachmentKind = "invoice"
+	AttachmentKindOther   AttachmentKind = "other"
+	AttachmentKindPhoto   AttachmentKind = "photo"
+)
+
+// Defines values for Channel.
+const (
+	Email Channel = "email"
+	Sms   Channel = "sms"
+)
+
+// Defines values for Locale.
+const (
+	En Locale = "en"
+	Ru Locale = "ru"
+)
+
+// Defines values for Priority.
+const (
+	Express  Priority = "express"
+	Standard Priority = "standard"
+)
+
+// Defines values for Status.
+const (
+	CustomsHold Status = "customs_hold"
+	Delivered   Status = "delivered"
+	Registered  Status = "registered"
+	Sent        Status = "sent"
+)
+
+// Defines values for AddAttachmentParamsKind.
+const (
+	AddAttachmentParamsKindCustoms AddAttachmentParamsKind = "customs"
+	AddAttachmentParamsKindInvoice AddAttachmentParamsKind = "invoice"
+	AddAttachmentParamsKindOther   AddAttachmentParamsKind = "other"
+	AddAttachmentParamsKindPhoto   AddAttachmentParamsKind = "photo"
+)
+
+// Defines values for GetParcelLabelParamsFormat.
+const (
+	Pdf GetParcelLabelParamsFormat = "pdf"
+	Zpl GetParcelLabelParamsFormat = "zpl"
+)
+
+// Attachment defines model for Attachment.
+type Attachment struct {
+	ContentType string         `json:"content_type"`
+	CreatedAt   string         `json:"created_at"`
+	Id          int64          `json:"id"`
+	Kind        AttachmentKind `json:"kind"`
+	Name        string         `json:"name"`
+	Number      int            `json:"number"`
+
+	// Sha256 SHA-256 содержимого в hex
+	Sha256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// AttachmentKind defines model for Attachment.Kind.
+type AttachmentKind string
+
+// Channel defines model for Channel.
+type Channel string
+
+// CourierLocation defines model for CourierLocation.
+type CourierLocation struct {
+	Courier    int     `json:"courier"`
+	Lat        float64 `json:"lat"`
+	Lon        float64 `json:"lon"`
+	ReportedAt string  `json:"reported_at"`
+}
+
+// CourierNearby Курьер, который везёт посылку по начатому маршруту
+type CourierNearby struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+
+	// ReportedAt Когда курьер сообщил положение, в RFC 3339
+	ReportedAt string `json:"reported_at"`
+
+	// StopsBefore Сколько посылок курьер доставит раньше этой
+	StopsBefore int `json:"stops_before"`
+}
+
+// Dimensions Срочность, вес и размеры посылки; если заданы, посылка регистрируется со стоимостью доставки
+type Dimensions struct {
+	HeightCm    int       `json:"height_cm"`
+	LengthCm    int       `json:"length_cm"`
+	Priority    *Priority `json:"priority,omitempty"`
+	WeightGrams int       `json:"weight_grams"`
+	WidthCm     int       `json:"width_cm"`
+}
+
+// Error defines model for Error.
+type Error struct {
+	Error string `json:"error"`
+}
+
+// HandOff defines model for HandOff.
+type HandOff struct {
+	Carrier string `json:"carrier"`
+}
+
+// Locale Язык уведомлений
+type Locale string
+
+// Location defines model for Location.
+type Location struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// NewParcel defines model for NewParcel.
+type NewParcel struct {
+	Address string `json:"address"`
+	Client  int    `json:"client"`
+
+	// Dimensions Срочность, вес и размеры посылки; если заданы, посылка регистрируется со стоимостью доставки
+	Dimensions *Dimensions `json:"dimensions,omitempty"`
+}
+
+// NewWebhook defines model for NewWebhook.
+type NewWebhook struct {
+	Url string `json:"url"`
+}
+
+// NotificationPreference defines model for NotificationPreference.
+type NotificationPreference struct {
+	Channel Channel `json:"channel"`
+	Client  int     `json:"client"`
+
+	// Locale Язык уведомлений
+	Locale    Locale   `json:"locale"`
+	Recipient string   `json:"recipient"`
+	Statuses  []Status `json:"statuses"`
+}
+
+// NotificationPreferenceUpdate defines model for NotificationPreferenceUpdate.
+type NotificationPreferenceUpdate struct {
+	// Locale Язык уведомлений
+	Locale    *Locale   `json:"locale,omitempty"`
+	Recipient string    `json:"recipient"`
+	Statuses  *[]Status `json:"statuses,omitempty"`
+}
+
+// OverdueParcel defines model for OverdueParcel.
+type OverdueParcel struct {
+	// Alerted Отправлено ли оповещение
+	Alerted bool `json:"alerted"`
+
+	// OverdueSeconds На сколько секунд превышен срок
+	OverdueSeconds int    `json:"overdue_seconds"`
+	Parcel         Parcel `json:"parcel"`
+
+	// Since Когда посылка получила текущий статус
+	Since string `json:"since"`
+}
+
+// Parcel defines model for Parcel.
+type Parcel struct {
+	Address   string `json:"address"`
+	Client    int    `json:"client"`
+	CreatedAt string `json:"created_at"`
+
+	// Deadline Обещанный срок доставки в RFC 3339
+	Deadline *string `json:"deadline,omitempty"`
+
+	// Eta Ожидаемое время доставки в RFC 3339
+	Eta    *string `json:"eta,omitempty"`
+	Number int     `json:"number"`
+
+	// Price Стоимость доставки в копейках
+	Price    *int64    `json:"price,omitempty"`
+	Priority *Priority `json:"priority,omitempty"`
+	Status   Status    `json:"status"`
+
+	// StatusLabel Подпись статуса на языке из Accept-Language
+	StatusLabel   string `json:"status_label"`
+	TrackingToken string `json:"tracking_token"`
+	WeightGrams   *int   `json:"weight_grams,omitempty"`
+
+	// Zone Зона доставки по почтовому индексу адреса
+	Zone *string `json:"zone,omitempty"`
+}
+
+// ParcelUpdate defines model for ParcelUpdate.
+type ParcelUpdate struct {
+	Address *string `json:"address,omitempty"`
+	Status  *Status `json:"status,omitempty"`
+}
+
+// Priority defines model for Priority.
+type Priority string
+
+// Quote defines model for Quote.
+type Quote struct {
+	// ChargeableGrams Оплачиваемый вес — фактический или объёмный, если он больше
+	ChargeableGrams int `json:"chargeable_grams"`
+
+	// Price Стоимость доставки в копейках
+	Price    int64    `json:"price"`
+	Priority Priority `json:"priority"`
+
+	// Zone Зона тарифа; * — общий тариф
+	Zone string `json:"zone"`
+}
+
+// QuoteRequest defines model for QuoteRequest.
+type QuoteRequest struct {
+	Address string `json:"address"`
+
+	// Dimensions Срочность, вес и размеры посылки; если заданы, посылка регистрируется со стоимостью доставки
+	Dimensions Dimensions `json:"dimensions"`
+}
+
+// ReadOnly defines model for ReadOnly.
+type ReadOnly struct {
+	// Enabled Отклоняются ли изменения данных
+	Enabled bool `json:"enabled"`
+}
+
+// SLABreach defines model for SLABreach.
+type SLABreach struct {
+	// LateSeconds На сколько секунд посылка опоздала или уже просрочена
+	LateSeconds int    `json:"late_seconds"`
+	Parcel      Parcel `json:"parcel"`
+}
+
+// Shipment defines model for Shipment.
+type Shipment struct {
+	Carrier       string `json:"carrier"`
+	CarrierStatus string `json:"carrier_status"`
+	CreatedAt     string `json:"created_at"`
+	ExternalId    string `json:"external_id"`
+	Number        int    `json:"number"`
+	SyncedAt      string `json:"synced_at"`
+}
+
+// Status defines model for Status.
+type Status string
+
+// StatusChange defines model for StatusChange.
+type StatusChange struct {
+	ChangedAt string `json:"changed_at"`
+	Status    Status `json:"status"`
+
+	// StatusLabel Подпись статуса на языке из Accept-Language
+	StatusLabel string `json:"status_label"`
+}
+
+// TrackingView defines model for TrackingView.
+type TrackingView struct {
+	City string `json:"city"`
+
+	// Courier Курьер, который везёт посылку по начатому маршруту
+	Courier   *CourierNearby `json:"courier,omitempty"`
+	CreatedAt string         `json:"created_at"`
+
+	// Eta Ожидаемое время доставки в RFC 3339
+	Eta     *string        `json:"eta,omitempty"`
+	History []StatusChange `json:"history"`
+	Status  Status         `json:"status"`
+
+	// StatusLabel Подпись статуса на языке из Accept-Language
+	StatusLabel string `json:"status_label"`
+}
+
+// Webhook defines model for Webhook.
+type Webhook struct {
+	Client    int     `json:"client"`
+	CreatedAt string  `json:"created_at"`
+	Id        int64   `json:"id"`
+	Secret    *string `json:"secret,omitempty"`
+	Url       string  `json:"url"`
+}
+
+// WebhookDelivery defines model for WebhookDelivery.
+type WebhookDelivery struct {
+	Attempts  int    `json:"attempts"`
+	CreatedAt string `json:"created_at"`
+	EventId   int64  `json:"event_id"`
+	EventType string `json:"event_type"`
+	Id        int64  `json:"id"`
+	LastError string `json:"last_error"`
+	Url       string `json:"url"`
+	WebhookId int64  `json:"webhook_id"`
+}
+
+// Client defines model for Client.
+type Client = int
+
+// ID defines model for ID.
+type ID = int64
+
+// IfMatch defines model for IfMatch.
+type IfMatch = string
+
+// Number defines model for Number.
+type Number = int
+
+// ListParcelsParams defines parameters for ListParcels.
+type ListParcelsParams struct {
+	Client *int    `form:"client,omitempty" json:"client,omitempty"`
+	Status *Status `form:"status,omitempty" json:"status,omitempty"`
+	Zone   *string `form:"zone,omitempty" json:"zone,omitempty"`
+	Limit  *int    `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// ListOverdueParcelsParams defines parameters for ListOverdueParcels.
+type ListOverdueParcelsParams struct {
+	Client *int `form:"client,omitempty" json:"client,omitempty"`
+	Limit  *int `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// SearchParcelsParams defines parameters for SearchParcels.
+type SearchParcelsParams struct {
+	// Q Слова для поиска, например «Ленина 12»; каждое слово ищется как начало слова
+	Q      string  `form:"q" json:"q"`
+	Client *int    `form:"client,omitempty" json:"client,omitempty"`
+	Status *Status `form:"status,omitempty" json:"status,omitempty"`
+	Limit  *int    `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// ListSLABreachesParams defines parameters for ListSLABreaches.
+type ListSLABreachesParams struct {
+	// From Срок доставки не раньше этого времени в RFC 3339
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To Срок доставки раньше этого времени в RFC 3339; по умолчанию сейчас
+	To     *time.Time `form:"to,omitempty" json:"to,omitempty"`
+	Client *int       `form:"client,omitempty" json:"client,omitempty"`
+	Limit  *int       `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// DeleteParcelParams defines parameters for DeleteParcel.
+type DeleteParcelParams struct {
+	// IfMatch ETag посылки из GET; если посылка с тех пор изменилась, сервер отвечает 412
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// UpdateParcelParams defines parameters for UpdateParcel.
+type UpdateParcelParams struct {
+	// IfMatch ETag посылки из GET; если посылка с тех пор изменилась, сервер отвечает 412
+	IfMatch *IfMatch `json:"If-Match,omitempty"`
+}
+
+// AddAttachmentParams defines parameters for AddAttachment.
+type AddAttachmentParams struct {
+	Kind AddAttachmentParamsKind `form:"kind" json:"kind"`
+
+	// Name Имя файла, под которым документ отдаётся при скачивании
+	Name string `form:"name" json:"name"`
+}
+
+// AddAttachmentParamsKind defines parameters for AddAttachment.
+type AddAttachmentParamsKind string
+
+// GetParcelLabelParams defines parameters for GetParcelLabel.
+type GetParcelLabelParams struct {
+	// Format PDF на страницу 100×150 мм или ZPL для термопринтеров Zebra 203 dpi
+	Format *GetParcelLabelParamsFormat `form:"format,omitempty" json:"format,omitempty"`
+}
+
+// GetParcelLabelParamsFormat defines parameters for GetParcelLabel.
+type GetParcelLabelParamsFormat string
+
+// ExportParcelsCSVParams defines parameters for ExportParcelsCSV.
+type ExportParcelsCSVParams struct {
+	Client *int    `form:"client,omitempty" json:"client,omitempty"`
+	Status *Status `form:"status,omitempty" json:"status,omitempty"`
+
+	// From Посылки, зарегистрированные не раньше этого времени
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To Посылки, зарегистрированные раньше этого времени
+	To *time.Time `form:"to,omitempty" json:"to,omitempty"`
+
+	// Columns Колонки через запятую из number, client, status, address, created_at, tracking_token; по умолчанию все
+	Columns *string `form:"columns,omitempty" json:"columns,omitempty"`
+}
+
+// SetReadOnlyJSONRequestBody defines body for SetReadOnly for application/json ContentType.
+type SetReadOnlyJSONRequestBody = ReadOnly
+
+// SetNotificationPreferenceJSONRequestBody defines body for SetNotificationPreference for application/json ContentType.
+type SetNotificationPreferenceJSONRequestBody = NotificationPreferenceUpdate
+
+// AddWebhookJSONRequestBody defines body for AddWebhook for application/json ContentType.
+type AddWebhookJSONRequestBody = NewWebhook
+
+// ReportCourierLocationJSONRequestBody defines body for ReportCourierLocation for application/json ContentType.
+type ReportCourierLocationJSONRequestBody = Location
+
+// AddParcelJSONRequestBody defines body for AddParcel for application/json ContentType.
+type AddParcelJSONRequestBody = NewParcel
+
+// UpdateParcelJSONRequestBody defines body for UpdateParcel for application/json ContentType.
+type UpdateParcelJSONRequestBody = ParcelUpdate
+
+// HandOffParcelJSONRequestBody defines body for HandOffParcel for application/json ContentType.
+type HandOffParcelJSONRequestBody = HandOff
+
+// QuoteParcelJSONRequestBody defines body for QuoteParcel for application/json ContentType.
+type QuoteParcelJSONRequestBody = QuoteRequest
+
+// ServerInterface represents all server handlers.
+type ServerInterface interface {
+	// Режим только для чтения
+	// (GET /admin/read-only)
+	GetReadOnly(w http.ResponseWriter, r *http.Request)
+	// Переключение режима только для чтения
+	// (PUT /admin/read-only)
+	SetReadOnly(w http.ResponseWriter, r *http.Request)
+	// Настройки уведомлений клиента по каналам
+	// (GET /clients/{client}/notifications)
+	ListNotificationPreferences(w http.ResponseWriter, r *http.Request, client Client)
+	// Отключение уведомлений в канале
+	// (DELETE /clients/{client}/notifications/{channel})
+	DeleteNotificationPreference(w http.ResponseWriter, r *http.Request, client Client, channel Channel)
+	// Включение уведомлений в канале
+	// (PUT /clients/{client}/notifications/{channel})
+	SetNotificationPreference(w http.ResponseWriter, r *http.Request, client Client, channel Channel)
+	// Получение всех посылок клиента
+	// (GET /clients/{client}/parcels)
+	ListClientParcels(w http.ResponseWriter, r *http.Request, client int)
+	// Вебхуки клиента
+	// (GET /clients/{client}/webhooks)
+	ListWebhooks(w http.ResponseWriter, r *http.Request, client Client)
+	// Регистрация вебхука
+	// (POST /clients/{client}/webhooks)
+	AddWebhook(w http.ResponseWriter, r *http.Request, client Client)
+	// Доставки событий, не удавшиеся за все попытки
+	// (GET /clients/{client}/webhooks/failures)
+	ListWebhookFailures(w http.ResponseWriter, r *http.Request, client Client)
+	// Повтор проваленной доставки с новым запасом попыток
+	// (POST /clients/{client}/webhooks/failures/{id}/replay)
+	ReplayWebhookFailure(w http.ResponseWriter, r *http.Request, client Client, id ID)
+	// Удаление вебхука вместе с его доставками
+	// (DELETE /clients/{client}/webhooks/{id})
+	DeleteWebhook(w http.ResponseWriter, r *http.Request, client Client, id ID)
+	// Последнее известное положение курьера
+	// (GET /couriers/{courier}/location)
+	GetCourierLocation(w http.ResponseWriter, r *http.Request, courier int)
+	// Сообщение курьером своего положения
+	// (PUT /couriers/{courier}/location)
+	ReportCourierLocation(w http.ResponseWriter, r *http.Request, courier int)
+	// Поиск посылок по клиенту, статусу и зоне доставки
+	// (GET /parcels)
+	ListParcels(w http.ResponseWriter, r *http.Request, params ListParcelsParams)
+	// Регистрация новой посылки
+	// (POST /parcels)
+	AddParcel(w http.ResponseWriter, r *http.Request)
+	// Посылки, которые дольше срока остаются в статусе registered или sent
+	// (GET /parcels/overdue)
+	ListOverdueParcels(w http.ResponseWriter, r *http.Request, params ListOverdueParcelsParams)
+	// Полнотекстовый поиск посылок по адресу, номеру и трекинг-коду
+	// (GET /parcels/search)
+	SearchParcels(w http.ResponseWriter, r *http.Request, params SearchParcelsParams)
+	// Посылки, не доставленные в обещанный срок
+	// (GET /parcels/sla-breaches)
+	ListSLABreaches(w http.ResponseWriter, r *http.Request, params ListSLABreachesParams)
+	// Удаление посылки
+	// (DELETE /parcels/{number})
+	DeleteParcel(w http.ResponseWriter, r *http.Request, number Number, params DeleteParcelParams)
+	// Получение посылки по номеру
+	// (GET /parcels/{number})
+	GetParcel(w http.ResponseWriter, r *http.Request, number Number)
+	// Изменение статуса и/или адреса посылки
+	// (PATCH /parcels/{number})
+	UpdateParcel(w http.ResponseWriter, r *http.Request, number Number, params UpdateParcelParams)
+	// Документы посылки
+	// (GET /parcels/{number}/attachments)
+	ListAttachments(w http.ResponseWriter, r *http.Request, number Number)
+	// Загрузка документа посылки
+	// (POST /parcels/{number}/attachments)
+	AddAttachment(w http.ResponseWriter, r *http.Request, number Number, params AddAttachmentParams)
+	// Удаление документа посылки
+	// (DELETE /parcels/{number}/attachments/{id})
+	DeleteAttachment(w http.ResponseWriter, r *http.Request, number Number, id int64)
+	// Скачивание документа посылки
+	// (GET /parcels/{number}/attachments/{id})
+	DownloadAttachment(w http.ResponseWriter, r *http.Request, number Number, id int64)
+	// Транспортная этикетка посылки для печати
+	// (GET /parcels/{number}/label)
+	GetParcelLabel(w http.ResponseWriter, r *http.Request, number Number, params GetParcelLabelParams)
+	// Отмена отправления у перевозчика
+	// (DELETE /parcels/{number}/shipment)
+	CancelHandOff(w http.ResponseWriter, r *http.Request, number Number)
+	// Отправление посылки у стороннего перевозчика
+	// (GET /parcels/{number}/shipment)
+	GetShipment(w http.ResponseWriter, r *http.Request, number Number)
+	// Передача посылки стороннему перевозчику
+	// (POST /parcels/{number}/shipment)
+	HandOffParcel(w http.ResponseWriter, r *http.Request, number Number)
+	// Расчёт стоимости доставки до регистрации посылки
+	// (POST /quotes)
+	QuoteParcel(w http.ResponseWriter, r *http.Request)
+	// Выгрузка посылок в CSV для отчётов
+	// (GET /reports/parcels.csv)
+	ExportParcelsCSV(w http.ResponseWriter, r *http.Request, params ExportParcelsCSVParams)
+	// Публичное отслеживание посылки по трекинг-токену
+	// (GET /track/{token})
+	TrackParcel(w http.ResponseWriter, r *http.Request, token string)
+}
+
+// ServerInterfaceWrapper converts contexts to parameters.
+type ServerInterfaceWrapper struct {
+	Handler            ServerInterface
+	HandlerMiddlewares []MiddlewareFunc
+	ErrorHandlerFunc   func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+type MiddlewareFunc func(http.Handler) http.Handler
+
+// GetReadOnly operation middleware
+func (siw *ServerInterfaceWrapper) GetReadOnly(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetReadOnly(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// SetReadOnly operation middleware
+func (siw *ServerInterfaceWrapper) SetReadOnly(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.SetReadOnly(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListNotificationPreferences operation middleware
+func (siw *ServerInterfaceWrapper) ListNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "client" -------------
+	var client Client
+
+	err = runtime.BindStyledParameterWithOptions("simple", "client", r.PathValue("client"), &client, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListNotificationPreferences(w, r, client)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DeleteNotificationPreference operation middleware
+func (siw *ServerInterfaceWrapper) DeleteNotificationPreference(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "client" -------------
+	var client Client
+
+	err = runtime.BindStyledParameterWithOptions("simple", "client", r.PathValue("client"), &client, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "channel" -------------
+	var channel Channel
+
+	err = runtime.BindStyledParameterWithOptions("simple", "channel", r.PathValue("channel"), &channel, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "channel", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteNotificationPreference(w, r, client, channel)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// SetNotificationPreference operation middleware
+func (siw *ServerInterfaceWrapper) SetNotificationPreference(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "client" -------------
+	var client Client
+
+	err = runtime.BindStyledParameterWithOptions("simple", "client", r.PathValue("client"), &client, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "channel" -------------
+	var channel Channel
+
+	err = runtime.BindStyledParameterWithOptions("simple", "channel", r.PathValue("channel"), &channel, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "channel", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.SetNotificationPreference(w, r, client, channel)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListClientParcels operation middleware
+func (siw *ServerInterfaceWrapper) ListClientParcels(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "client" -------------
+	var client int
+
+	err = runtime.BindStyledParameterWithOptions("simple", "client", r.PathValue("client"), &client, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListClientParcels(w, r, client)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListWebhooks operation middleware
+func (siw *ServerInterfaceWrapper) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "client" -------------
+	var client Client
+
+	err = runtime.BindStyledParameterWithOptions("simple", "client", r.PathValue("client"), &client, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListWebhooks(w, r, client)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// AddWebhook operation middleware
+func (siw *ServerInterfaceWrapper) AddWebhook(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "client" -------------
+	var client Client
+
+	err = runtime.BindStyledParameterWithOptions("simple", "client", r.PathValue("client"), &client, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AddWebhook(w, r, client)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListWebhookFailures operation middleware
+func (siw *ServerInterfaceWrapper) ListWebhookFailures(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "client" -------------
+	var client Client
+
+	err = runtime.BindStyledParameterWithOptions("simple", "client", r.PathValue("client"), &client, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListWebhookFailures(w, r, client)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ReplayWebhookFailure operation middleware
+func (siw *ServerInterfaceWrapper) ReplayWebhookFailure(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "client" -------------
+	var client Client
+
+	err = runtime.BindStyledParameterWithOptions("simple", "client", r.PathValue("client"), &client, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "id" -------------
+	var id ID
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ReplayWebhookFailure(w, r, client, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DeleteWebhook operation middleware
+func (siw *ServerInterfaceWrapper) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "client" -------------
+	var client Client
+
+	err = runtime.BindStyledParameterWithOptions("simple", "client", r.PathValue("client"), &client, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "id" -------------
+	var id ID
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteWebhook(w, r, client, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetCourierLocation operation middleware
+func (siw *ServerInterfaceWrapper) GetCourierLocation(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "courier" -------------
+	var courier int
+
+	err = runtime.BindStyledParameterWithOptions("simple", "courier", r.PathValue("courier"), &courier, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "courier", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetCourierLocation(w, r, courier)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ReportCourierLocation operation middleware
+func (siw *ServerInterfaceWrapper) ReportCourierLocation(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "courier" -------------
+	var courier int
+
+	err = runtime.BindStyledParameterWithOptions("simple", "courier", r.PathValue("courier"), &courier, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "courier", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ReportCourierLocation(w, r, courier)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListParcels operation middleware
+func (siw *ServerInterfaceWrapper) ListParcels(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListParcelsParams
+
+	// ------------- Optional query parameter "client" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "client", r.URL.Query(), &params.Client)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "status" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "status", r.URL.Query(), &params.Status)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "status", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "zone" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "zone", r.URL.Query(), &params.Zone)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "zone", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListParcels(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// AddParcel operation middleware
+func (siw *ServerInterfaceWrapper) AddParcel(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AddParcel(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListOverdueParcels operation middleware
+func (siw *ServerInterfaceWrapper) ListOverdueParcels(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListOverdueParcelsParams
+
+	// ------------- Optional query parameter "client" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "client", r.URL.Query(), &params.Client)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListOverdueParcels(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// SearchParcels operation middleware
+func (siw *ServerInterfaceWrapper) SearchParcels(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params SearchParcelsParams
+
+	// ------------- Required query parameter "q" -------------
+
+	if paramValue := r.URL.Query().Get("q"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "q"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "q", r.URL.Query(), &params.Q)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "q", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "client" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "client", r.URL.Query(), &params.Client)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "status" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "status", r.URL.Query(), &params.Status)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "status", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.SearchParcels(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListSLABreaches operation middleware
+func (siw *ServerInterfaceWrapper) ListSLABreaches(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListSLABreachesParams
+
+	// ------------- Optional query parameter "from" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "from", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "to" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "to", r.URL.Query(), &params.To)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "to", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "client" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "client", r.URL.Query(), &params.Client)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListSLABreaches(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DeleteParcel operation middleware
+func (siw *ServerInterfaceWrapper) DeleteParcel(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "number" -------------
+	var number Number
+
+	err = runtime.BindStyledParameterWithOptions("simple", "number", r.PathValue("number"), &number, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "number", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DeleteParcelParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch IfMatch
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "If-Match", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "If-Match", valueList[0], &IfMatch, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "If-Match", Err: err})
+			return
+		}
+
+		params.IfMatch = &IfMatch
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteParcel(w, r, number, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetParcel operation middleware
+func (siw *ServerInterfaceWrapper) GetParcel(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "number" -------------
+	var number Number
+
+	err = runtime.BindStyledParameterWithOptions("simple", "number", r.PathValue("number"), &number, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "number", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetParcel(w, r, number)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// UpdateParcel operation middleware
+func (siw *ServerInterfaceWrapper) UpdateParcel(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "number" -------------
+	var number Number
+
+	err = runtime.BindStyledParameterWithOptions("simple", "number", r.PathValue("number"), &number, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "number", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params UpdateParcelParams
+
+	headers := r.Header
+
+	// ------------- Optional header parameter "If-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Match")]; found {
+		var IfMatch IfMatch
+		n := len(valueList)
+		if n != 1 {
+			siw.ErrorHandlerFunc(w, r, &TooManyValuesForParamError{ParamName: "If-Match", Count: n})
+			return
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "If-Match", valueList[0], &IfMatch, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
+		if err != nil {
+			siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "If-Match", Err: err})
+			return
+		}
+
+		params.IfMatch = &IfMatch
+
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.UpdateParcel(w, r, number, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ListAttachments operation middleware
+func (siw *ServerInterfaceWrapper) ListAttachments(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "number" -------------
+	var number Number
+
+	err = runtime.BindStyledParameterWithOptions("simple", "number", r.PathValue("number"), &number, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "number", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListAttachments(w, r, number)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// AddAttachment operation middleware
+func (siw *ServerInterfaceWrapper) AddAttachment(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "number" -------------
+	var number Number
+
+	err = runtime.BindStyledParameterWithOptions("simple", "number", r.PathValue("number"), &number, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "number", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params AddAttachmentParams
+
+	// ------------- Required query parameter "kind" -------------
+
+	if paramValue := r.URL.Query().Get("kind"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "kind"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "kind", r.URL.Query(), &params.Kind)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "kind", Err: err})
+		return
+	}
+
+	// ------------- Required query parameter "name" -------------
+
+	if paramValue := r.URL.Query().Get("name"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "name"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "name", r.URL.Query(), &params.Name)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "name", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.AddAttachment(w, r, number, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DeleteAttachment operation middleware
+func (siw *ServerInterfaceWrapper) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "number" -------------
+	var number Number
+
+	err = runtime.BindStyledParameterWithOptions("simple", "number", r.PathValue("number"), &number, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "number", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteAttachment(w, r, number, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DownloadAttachment operation middleware
+func (siw *ServerInterfaceWrapper) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "number" -------------
+	var number Number
+
+	err = runtime.BindStyledParameterWithOptions("simple", "number", r.PathValue("number"), &number, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "number", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", r.PathValue("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "id", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DownloadAttachment(w, r, number, id)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetParcelLabel operation middleware
+func (siw *ServerInterfaceWrapper) GetParcelLabel(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "number" -------------
+	var number Number
+
+	err = runtime.BindStyledParameterWithOptions("simple", "number", r.PathValue("number"), &number, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "number", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetParcelLabelParams
+
+	// ------------- Optional query parameter "format" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "format", r.URL.Query(), &params.Format)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "format", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetParcelLabel(w, r, number, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CancelHandOff operation middleware
+func (siw *ServerInterfaceWrapper) CancelHandOff(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "number" -------------
+	var number Number
+
+	err = runtime.BindStyledParameterWithOptions("simple", "number", r.PathValue("number"), &number, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "number", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CancelHandOff(w, r, number)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetShipment operation middleware
+func (siw *ServerInterfaceWrapper) GetShipment(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "number" -------------
+	var number Number
+
+	err = runtime.BindStyledParameterWithOptions("simple", "number", r.PathValue("number"), &number, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "number", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetShipment(w, r, number)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// HandOffParcel operation middleware
+func (siw *ServerInterfaceWrapper) HandOffParcel(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "number" -------------
+	var number Number
+
+	err = runtime.BindStyledParameterWithOptions("simple", "number", r.PathValue("number"), &number, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "number", Err: err})
+		return
+	}
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.HandOffParcel(w, r, number)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// QuoteParcel operation middleware
+func (siw *ServerInterfaceWrapper) QuoteParcel(w http.ResponseWriter, r *http.Request) {
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.QuoteParcel(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ExportParcelsCSV operation middleware
+func (siw *ServerInterfaceWrapper) ExportParcelsCSV(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, ApiKeyScopes, []string{})
+
+	r = r.WithContext(ctx)
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ExportParcelsCSVParams
+
+	// ------------- Optional query parameter "client" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "client", r.URL.Query(), &params.Client)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "status" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "status", r.URL.Query(), &params.Status)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "status", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "from" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "from", r.URL.Query(), &params.From)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "from", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "to" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "to", r.URL.Query(), &params.To)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "to", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "columns" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "columns", r.URL.Query(), &params.Columns)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "columns", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ExportParcelsCSV(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// TrackParcel operation middleware
+func (siw *ServerInterfaceWrapper) TrackParcel(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "token" -------------
+	var token string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "token", r.PathValue("token"), &token, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "token", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.TrackParcel(w, r, token)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+type UnescapedCookieParamError struct {
+	ParamName string
+	Err       error
+}
+
+func (e *UnescapedCookieParamError) Error() string {
+	return fmt.Sprintf("error unescaping cookie parameter '%s'", e.ParamName)
+}
+
+func (e *UnescapedCookieParamError) Unwrap() error {
+	return e.Err
+}
+
+type UnmarshalingParamError struct {
+	ParamName string
+	Err       error
+}
+
+func (e *UnmarshalingParamError) Error() string {
+	return fmt.Sprintf("Error unmarshaling parameter %s as JSON: %s", e.ParamName, e.Err.Error())
+}
+
+func (e *UnmarshalingParamError) Unwrap() error {
+	return e.Err
+}
+
+type RequiredParamError struct {
+	ParamName string
+}
+
+func (e *RequiredParamError) Error() string {
+	return fmt.Sprintf("Query argument %s is required, but not found", e.ParamName)
+}
+
+type RequiredHeaderError struct {
+	ParamName string
+	Err       error
+}
+
+func (e *RequiredHeaderError) Error() string {
+	return fmt.Sprintf("Header parameter %s is required, but not found", e.ParamName)
+}
+
+func (e *RequiredHeaderError) Unwrap() error {
+	return e.Err
+}
+
+type InvalidParamFormatError struct {
+	ParamName string
+	Err       error
+}
+
+func (e *InvalidParamFormatError) Error() string {
+	return fmt.Sprintf("Invalid format for parameter %s: %s", e.ParamName, e.Err.Error())
+}
+
+func (e *InvalidParamFormatError) Unwrap() error {
+	return e.Err
+}
+
+type TooManyValuesForParamError struct {
+	ParamName string
+	Count     int
+}
+
+func (e *TooManyValuesForParamError) Error() string {
+	return fmt.Sprintf("Expected one value for %s, got %d", e.ParamName, e.Count)
+}
+
+// Handler creates http.Handler with routing matching OpenAPI spec.
+func Handler(si ServerInterface) http.Handler {
+	return HandlerWithOptions(si, StdHTTPServerOptions{})
+}
+
+// ServeMux is an abstraction of http.ServeMux.
+type ServeMux interface {
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+	ServeHTTP(w http.ResponseWriter, r *http.Request)
+}
+
+type StdHTTPServerOptions struct {
+	BaseURL          string
+	BaseRouter       ServeMux
+	Middlewares      []MiddlewareFunc
+	ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// HandlerFromMux creates http.Handler with routing matching OpenAPI spec based on the provided mux.
+func HandlerFromMux(si ServerInterface, m ServeMux) http.Handler {
+	return HandlerWithOptions(si, StdHTTPServerOptions{
+		BaseRouter: m,
+	})
+}
+
+func HandlerFromMuxWithBaseURL(si ServerInterface, m ServeMux, baseURL string) http.Handler {
+	return HandlerWithOptions(si, StdHTTPServerOptions{
+		BaseURL:    baseURL,
+		BaseRouter: m,
+	})
+}
+
+// HandlerWithOptions creates http.Handler with additional options
+func HandlerWithOptions(si ServerInterface, options StdHTTPServerOptions) http.Handler {
+	m := options.BaseRouter
+
+	if m == nil {
+		m = http.NewServeMux()
+	}
+	if options.ErrorHandlerFunc == nil {
+		options.ErrorHandlerFunc = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}
+
+	wrapper := ServerInterfaceWrapper{
+		Handler:            si,
+		HandlerMiddlewares: options.Middlewares,
+		ErrorHandlerFunc:   options.ErrorHandlerFunc,
+	}
+
+	m.HandleFunc("GET "+options.BaseURL+"/admin/read-only", wrapper.GetReadOnly)
+	m.HandleFunc("PUT "+options.BaseURL+"/admin/read-only", wrapper.SetReadOnly)
+	m.HandleFunc("GET "+options.BaseURL+"/clients/{client}/notifications", wrapper.ListNotificationPreferences)
+	m.HandleFunc("DELETE "+options.BaseURL+"/clients/{client}/notifications/{channel}", wrapper.DeleteNotificationPreference)
+	m.HandleFunc("PUT "+options.BaseURL+"/clients/{client}/notifications/{channel}", wrapper.SetNotificationPreference)
+	m.HandleFunc("GET "+options.BaseURL+"/clients/{client}/parcels", wrapper.ListClientParcels)
+	m.HandleFunc("GET "+options.BaseURL+"/clients/{client}/webhooks", wrapper.ListWebhooks)
+	m.HandleFunc("POST "+options.BaseURL+"/clients/{client}/webhooks", wrapper.AddWebhook)
+	m.HandleFunc("GET "+options.BaseURL+"/clients/{client}/webhooks/failures", wrapper.ListWebhookFailures)
+	m.HandleFunc("POST "+options.BaseURL+"/clients/{client}/webhooks/failures/{id}/replay", wrapper.ReplayWebhookFailure)
+	m.HandleFunc("DELETE "+options.BaseURL+"/clients/{client}/webhooks/{id}", wrapper.DeleteWebhook)
+	m.HandleFunc("GET "+options.BaseURL+"/couriers/{courier}/location", wrapper.GetCourierLocation)
+	m.HandleFunc("PUT "+options.BaseURL+"/couriers/{courier}/location", wrapper.ReportCourierLocation)
+	m.HandleFunc("GET "+options.BaseURL+"/parcels", wrapper.ListParcels)
+	m.HandleFunc("POST "+options.BaseURL+"/parcels", wrapper.AddParcel)
+	m.HandleFunc("GET "+options.BaseURL+"/parcels/overdue", wrapper.ListOverdueParcels)
+	m.HandleFunc("GET "+options.BaseURL+"/parcels/search", wrapper.SearchParcels)
+	m.HandleFunc("GET "+options.BaseURL+"/parcels/sla-breaches", wrapper.ListSLABreaches)
+	m.HandleFunc("DELETE "+options.BaseURL+"/parcels/{number}", wrapper.DeleteParcel)
+	m.HandleFunc("GET "+options.BaseURL+"/parcels/{number}", wrapper.GetParcel)
+	m.HandleFunc("PATCH "+options.BaseURL+"/parcels/{number}", wrapper.UpdateParcel)
+	m.HandleFunc("GET "+options.BaseURL+"/parcels/{number}/attachments", wrapper.ListAttachments)
+	m.HandleFunc("POST "+options.BaseURL+"/parcels/{number}/attachments", wrapper.AddAttachment)
+	m.HandleFunc("DELETE "+options.BaseURL+"/parcels/{number}/attachments/{id}", wrapper.DeleteAttachment)
+	m.HandleFunc("GET "+options.BaseURL+"/parcels/{number}/attachments/{id}", wrapper.DownloadAttachment)
+	m.HandleFunc("GET "+options.BaseURL+"/parcels/{number}/label", wrapper.GetParcelLabel)
+	m.HandleFunc("DELETE "+options.BaseURL+"/parcels/{number}/shipment", wrapper.CancelHandOff)
+	m.HandleFunc("GET "+options.BaseURL+"/parcels/{number}/shipment", wrapper.GetShipment)
+	m.HandleFunc("POST "+options.BaseURL+"/parcels/{number}/shipment", wrapper.HandOffParcel)
+	m.HandleFunc("POST "+options.BaseURL+"/quotes", wrapper.QuoteParcel)
+	m.HandleFunc("GET "+options.BaseURL+"/reports/parcels.csv", wrapper.ExportParcelsCSV)
+	m.HandleFunc("GET "+options.BaseURL+"/track/{token}", wrapper.TrackParcel)
+
+	return m
+}
+
+type ErrorJSONResponse Error
+
+type GetReadOnlyRequestObject struct {
+}
+
+type GetReadOnlyResponseObject interface {
+	VisitGetReadOnlyResponse(w http.ResponseWriter) error
+}
+
+type GetReadOnly200JSONResponse ReadOnly
+
+func (response GetReadOnly200JSONResponse) VisitGetReadOnlyResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetReadOnly403JSONResponse struct{ ErrorJSONResponse }
+
+func (response GetReadOnly403JSONResponse) VisitGetReadOnlyResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type SetReadOnlyRequestObject struct {
+	Body *SetReadOnlyJSONRequestBody
+}
+
+type SetReadOnlyResponseObject interface {
+	VisitSetReadOnlyResponse(w http.ResponseWriter) error
+}
+
+type SetReadOnly200JSONResponse ReadOnly
+
+func (response SetReadOnly200JSONResponse) VisitSetReadOnlyResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type SetReadOnly403JSONResponse struct{ ErrorJSONResponse }
+
+func (response SetReadOnly403JSONResponse) VisitSetReadOnlyResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListNotificationPreferencesRequestObject struct {
+	Client Client `json:"client"`
+}
+
+type ListNotificationPreferencesResponseObject interface {
+	VisitListNotificationPreferencesResponse(w http.ResponseWriter) error
+}
+
+type ListNotificationPreferences200JSONResponse []NotificationPreference
+
+func (response ListNotificationPreferences200JSONResponse) VisitListNotificationPreferencesResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListNotificationPreferences403JSONResponse struct{ ErrorJSONResponse }
+
+func (response ListNotificationPreferences403JSONResponse) VisitListNotificationPreferencesResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type DeleteNotificationPreferenceRequestObject struct {
+	Client  Client  `json:"client"`
+	Channel Channel `json:"channel"`
+}
+
+type DeleteNotificationPreferenceResponseObject interface {
+	VisitDeleteNotificationPreferenceResponse(w http.ResponseWriter) error
+}
+
+type DeleteNotificationPreference204Response struct {
+}
+
+func (response DeleteNotificationPreference204Response) VisitDeleteNotificationPreferenceResponse(w http.ResponseWriter) error {
+	w.WriteHeader(204)
+	return nil
+}
+
+type DeleteNotificationPreference403JSONResponse struct{ ErrorJSONResponse }
+
+func (response DeleteNotificationPreference403JSONResponse) VisitDeleteNotificationPreferenceResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type DeleteNotificationPreference404JSONResponse Error
+
+func (response DeleteNotificationPreference404JSONResponse) VisitDeleteNotificationPreferenceResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type SetNotificationPreferenceRequestObject struct {
+	Client  Client  `json:"client"`
+	Channel Channel `json:"channel"`
+	Body    *SetNotificationPreferenceJSONRequestBody
+}
+
+type SetNotificationPreferenceResponseObject interface {
+	VisitSetNotificationPreferenceResponse(w http.ResponseWriter) error
+}
+
+type SetNotificationPreference200JSONResponse NotificationPreference
+
+func (response SetNotificationPreference200JSONResponse) VisitSetNotificationPreferenceResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type SetNotificationPreference400JSONResponse struct{ ErrorJSONResponse }
+
+func (response SetNotificationPreference400JSONResponse) VisitSetNotificationPreferenceResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type SetNotificationPreference403JSONResponse Error
+
+func (response SetNotificationPreference403JSONResponse) VisitSetNotificationPreferenceResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListClientParcelsRequestObject struct {
+	Client int `json:"client"`
+}
+
+type ListClientParcelsResponseObject interface {
+	VisitListClientParcelsResponse(w http.ResponseWriter) error
+}
+
+type ListClientParcels200JSONResponse []Parcel
+
+func (response ListClientParcels200JSONResponse) VisitListClientParcelsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListClientParcels403JSONResponse struct{ ErrorJSONResponse }
+
+func (response ListClientParcels403JSONResponse) VisitListClientParcelsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListWebhooksRequestObject struct {
+	Client Client `json:"client"`
+}
+
+type ListWebhooksResponseObject interface {
+	VisitListWebhooksResponse(w http.ResponseWriter) error
+}
+
+type ListWebhooks200JSONResponse []Webhook
+
+func (response ListWebhooks200JSONResponse) VisitListWebhooksResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListWebhooks403JSONResponse struct{ ErrorJSONResponse }
+
+func (response ListWebhooks403JSONResponse) VisitListWebhooksResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListWebhooks404JSONResponse Error
+
+func (response ListWebhooks404JSONResponse) VisitListWebhooksResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type AddWebhookRequestObject struct {
+	Client Client `json:"client"`
+	Body   *AddWebhookJSONRequestBody
+}
+
+type AddWebhookResponseObject interface {
+	VisitAddWebhookResponse(w http.ResponseWriter) error
+}
+
+type AddWebhook201JSONResponse Webhook
+
+func (response AddWebhook201JSONResponse) VisitAddWebhookResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type AddWebhook400JSONResponse struct{ ErrorJSONResponse }
+
+func (response AddWebhook400JSONResponse) VisitAddWebhookResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type AddWebhook403JSONResponse Error
+
+func (response AddWebhook403JSONResponse) VisitAddWebhookResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type AddWebhook404JSONResponse Error
+
+func (response AddWebhook404JSONResponse) VisitAddWebhookResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListWebhookFailuresRequestObject struct {
+	Client Client `json:"client"`
+}
+
+type ListWebhookFailuresResponseObject interface {
+	VisitListWebhookFailuresResponse(w http.ResponseWriter) error
+}
+
+type ListWebhookFailures200JSONResponse []WebhookDelivery
+
+func (response ListWebhookFailures200JSONResponse) VisitListWebhookFailuresResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListWebhookFailures403JSONResponse struct{ ErrorJSONResponse }
+
+func (response ListWebhookFailures403JSONResponse) VisitListWebhookFailuresResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListWebhookFailures404JSONResponse Error
+
+func (response ListWebhookFailures404JSONResponse) VisitListWebhookFailuresResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ReplayWebhookFailureRequestObject struct {
+	Client Client `json:"client"`
+	Id     ID     `json:"id"`
+}
+
+type ReplayWebhookFailureResponseObject interface {
+	VisitReplayWebhookFailureResponse(w http.ResponseWriter) error
+}
+
+type ReplayWebhookFailure202Response struct {
+}
+
+func (response ReplayWebhookFailure202Response) VisitReplayWebhookFailureResponse(w http.ResponseWriter) error {
+	w.WriteHeader(202)
+	return nil
+}
+
+type ReplayWebhookFailure403JSONResponse struct{ ErrorJSONResponse }
+
+func (response ReplayWebhookFailure403JSONResponse) VisitReplayWebhookFailureResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ReplayWebhookFailure404JSONResponse Error
+
+func (response ReplayWebhookFailure404JSONResponse) VisitReplayWebhookFailureResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type DeleteWebhookRequestObject struct {
+	Client Client `json:"client"`
+	Id     ID     `json:"id"`
+}
+
+type DeleteWebhookResponseObject interface {
+	VisitDeleteWebhookResponse(w http.ResponseWriter) error
+}
+
+type DeleteWebhook204Response struct {
+}
+
+func (response DeleteWebhook204Response) VisitDeleteWebhookResponse(w http.ResponseWriter) error {
+	w.WriteHeader(204)
+	return nil
+}
+
+type DeleteWebhook403JSONResponse struct{ ErrorJSONResponse }
+
+func (response DeleteWebhook403JSONResponse) VisitDeleteWebhookResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type DeleteWebhook404JSONResponse Error
+
+func (response DeleteWebhook404JSONResponse) VisitDeleteWebhookResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetCourierLocationRequestObject struct {
+	Courier int `json:"courier"`
+}
+
+type GetCourierLocationResponseObject interface {
+	VisitGetCourierLocationResponse(w http.ResponseWriter) error
+}
+
+type GetCourierLocation200JSONResponse CourierLocation
+
+func (response GetCourierLocation200JSONResponse) VisitGetCourierLocationResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetCourierLocation403JSONResponse struct{ ErrorJSONResponse }
+
+func (response GetCourierLocation403JSONResponse) VisitGetCourierLocationResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetCourierLocation404JSONResponse Error
+
+func (response GetCourierLocation404JSONResponse) VisitGetCourierLocationResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ReportCourierLocationRequestObject struct {
+	Courier int `json:"courier"`
+	Body    *ReportCourierLocationJSONRequestBody
+}
+
+type ReportCourierLocationResponseObject interface {
+	VisitReportCourierLocationResponse(w http.ResponseWriter) error
+}
+
+type ReportCourierLocation200JSONResponse CourierLocation
+
+func (response ReportCourierLocation200JSONResponse) VisitReportCourierLocationResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ReportCourierLocation400JSONResponse struct{ ErrorJSONResponse }
+
+func (response ReportCourierLocation400JSONResponse) VisitReportCourierLocationResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ReportCourierLocation403JSONResponse Error
+
+func (response ReportCourierLocation403JSONResponse) VisitReportCourierLocationResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListParcelsRequestObject struct {
+	Params ListParcelsParams
+}
+
+type ListParcelsResponseObject interface {
+	VisitListParcelsResponse(w http.ResponseWriter) error
+}
+
+type ListParcels200JSONResponse []Parcel
+
+func (response ListParcels200JSONResponse) VisitListParcelsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListParcels403JSONResponse struct{ ErrorJSONResponse }
+
+func (response ListParcels403JSONResponse) VisitListParcelsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type AddParcelRequestObject struct {
+	Body *AddParcelJSONRequestBody
+}
+
+type AddParcelResponseObject interface {
+	VisitAddParcelResponse(w http.ResponseWriter) error
+}
+
+type AddParcel201JSONResponse Parcel
+
+func (response AddParcel201JSONResponse) VisitAddParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type AddParcel400JSONResponse struct{ ErrorJSONResponse }
+
+func (response AddParcel400JSONResponse) VisitAddParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type AddParcel403JSONResponse Error
+
+func (response AddParcel403JSONResponse) VisitAddParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type AddParcel409JSONResponse Error
+
+func (response AddParcel409JSONResponse) VisitAddParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(409)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListOverdueParcelsRequestObject struct {
+	Params ListOverdueParcelsParams
+}
+
+type ListOverdueParcelsResponseObject interface {
+	VisitListOverdueParcelsResponse(w http.ResponseWriter) error
+}
+
+type ListOverdueParcels200JSONResponse []OverdueParcel
+
+func (response ListOverdueParcels200JSONResponse) VisitListOverdueParcelsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListOverdueParcels403JSONResponse struct{ ErrorJSONResponse }
+
+func (response ListOverdueParcels403JSONResponse) VisitListOverdueParcelsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type SearchParcelsRequestObject struct {
+	Params SearchParcelsParams
+}
+
+type SearchParcelsResponseObject interface {
+	VisitSearchParcelsResponse(w http.ResponseWriter) error
+}
+
+type SearchParcels200JSONResponse []Parcel
+
+func (response SearchParcels200JSONResponse) VisitSearchParcelsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type SearchParcels403JSONResponse struct{ ErrorJSONResponse }
+
+func (response SearchParcels403JSONResponse) VisitSearchParcelsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListSLABreachesRequestObject struct {
+	Params ListSLABreachesParams
+}
+
+type ListSLABreachesResponseObject interface {
+	VisitListSLABreachesResponse(w http.ResponseWriter) error
+}
+
+type ListSLABreaches200JSONResponse []SLABreach
+
+func (response ListSLABreaches200JSONResponse) VisitListSLABreachesResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListSLABreaches403JSONResponse struct{ ErrorJSONResponse }
+
+func (response ListSLABreaches403JSONResponse) VisitListSLABreachesResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type DeleteParcelRequestObject struct {
+	Number Number `json:"number"`
+	Params DeleteParcelParams
+}
+
+type DeleteParcelResponseObject interface {
+	VisitDeleteParcelResponse(w http.ResponseWriter) error
+}
+
+type DeleteParcel204Response struct {
+}
+
+func (response DeleteParcel204Response) VisitDeleteParcelResponse(w http.ResponseWriter) error {
+	w.WriteHeader(204)
+	return nil
+}
+
+type DeleteParcel403JSONResponse struct{ ErrorJSONResponse }
+
+func (response DeleteParcel403JSONResponse) VisitDeleteParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type DeleteParcel412JSONResponse struct{ ErrorJSONResponse }
+
+func (response DeleteParcel412JSONResponse) VisitDeleteParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(412)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetParcelRequestObject struct {
+	Number Number `json:"number"`
+}
+
+type GetParcelResponseObject interface {
+	VisitGetParcelResponse(w http.ResponseWriter) error
+}
+
+type GetParcel200ResponseHeaders struct {
+	ETag string
+}
+
+type GetParcel200JSONResponse struct {
+	Body    Parcel
+	Headers GetParcel200ResponseHeaders
+}
+
+func (response GetParcel200JSONResponse) VisitGetParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", fmt.Sprint(response.Headers.ETag))
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type GetParcel403JSONResponse struct{ ErrorJSONResponse }
+
+func (response GetParcel403JSONResponse) VisitGetParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetParcel404JSONResponse Error
+
+func (response GetParcel404JSONResponse) VisitGetParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type UpdateParcelRequestObject struct {
+	Number Number `json:"number"`
+	Params UpdateParcelParams
+	Body   *UpdateParcelJSONRequestBody
+}
+
+type UpdateParcelResponseObject interface {
+	VisitUpdateParcelResponse(w http.ResponseWriter) error
+}
+
+type UpdateParcel200ResponseHeaders struct {
+	ETag string
+}
+
+type UpdateParcel200JSONResponse struct {
+	Body    Parcel
+	Headers UpdateParcel200ResponseHeaders
+}
+
+func (response UpdateParcel200JSONResponse) VisitUpdateParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", fmt.Sprint(response.Headers.ETag))
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type UpdateParcel400JSONResponse struct{ ErrorJSONResponse }
+
+func (response UpdateParcel400JSONResponse) VisitUpdateParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type UpdateParcel403JSONResponse Error
+
+func (response UpdateParcel403JSONResponse) VisitUpdateParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type UpdateParcel404JSONResponse Error
+
+func (response UpdateParcel404JSONResponse) VisitUpdateParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type UpdateParcel409JSONResponse Error
+
+func (response UpdateParcel409JSONResponse) VisitUpdateParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(409)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type UpdateParcel412JSONResponse struct{ ErrorJSONResponse }
+
+func (response UpdateParcel412JSONResponse) VisitUpdateParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(412)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListAttachmentsRequestObject struct {
+	Number Number `json:"number"`
+}
+
+type ListAttachmentsResponseObject interface {
+	VisitListAttachmentsResponse(w http.ResponseWriter) error
+}
+
+type ListAttachments200JSONResponse []Attachment
+
+func (response ListAttachments200JSONResponse) VisitListAttachmentsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListAttachments403JSONResponse struct{ ErrorJSONResponse }
+
+func (response ListAttachments403JSONResponse) VisitListAttachmentsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ListAttachments404JSONResponse Error
+
+func (response ListAttachments404JSONResponse) VisitListAttachmentsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type AddAttachmentRequestObject struct {
+	Number Number `json:"number"`
+	Params AddAttachmentParams
+	Body   io.Reader
+}
+
+type AddAttachmentResponseObject interface {
+	VisitAddAttachmentResponse(w http.ResponseWriter) error
+}
+
+type AddAttachment201JSONResponse Attachment
+
+func (response AddAttachment201JSONResponse) VisitAddAttachmentResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type AddAttachment400JSONResponse struct{ ErrorJSONResponse }
+
+func (response AddAttachment400JSONResponse) VisitAddAttachmentResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type AddAttachment403JSONResponse Error
+
+func (response AddAttachment403JSONResponse) VisitAddAttachmentResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type AddAttachment404JSONResponse Error
+
+func (response AddAttachment404JSONResponse) VisitAddAttachmentResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type AddAttachment413JSONResponse Error
+
+func (response AddAttachment413JSONResponse) VisitAddAttachmentResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(413)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type DeleteAttachmentRequestObject struct {
+	Number Number `json:"number"`
+	Id     int64  `json:"id"`
+}
+
+type DeleteAttachmentResponseObject interface {
+	VisitDeleteAttachmentResponse(w http.ResponseWriter) error
+}
+
+type DeleteAttachment204Response struct {
+}
+
+func (response DeleteAttachment204Response) VisitDeleteAttachmentResponse(w http.ResponseWriter) error {
+	w.WriteHeader(204)
+	return nil
+}
+
+type DeleteAttachment403JSONResponse struct{ ErrorJSONResponse }
+
+func (response DeleteAttachment403JSONResponse) VisitDeleteAttachmentResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type DeleteAttachment404JSONResponse Error
+
+func (response DeleteAttachment404JSONResponse) VisitDeleteAttachmentResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type DownloadAttachmentRequestObject struct {
+	Number Number `json:"number"`
+	Id     int64  `json:"id"`
+}
+
+type DownloadAttachmentResponseObject interface {
+	VisitDownloadAttachmentResponse(w http.ResponseWriter) error
+}
+
+type DownloadAttachment200ResponseHeaders struct {
+	ContentDisposition string
+}
+
+type DownloadAttachment200AsteriskResponse struct {
+	Body          io.Reader
+	Headers       DownloadAttachment200ResponseHeaders
+	ContentType   string
+	ContentLength int64
+}
+
+func (response DownloadAttachment200AsteriskResponse) VisitDownloadAttachmentResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", response.ContentType)
+	if response.ContentLength != 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(response.ContentLength))
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprint(response.Headers.ContentDisposition))
+	w.WriteHeader(200)
+
+	if closer, ok := response.Body.(io.ReadCloser); ok {
+		defer closer.Close()
+	}
+	_, err := io.Copy(w, response.Body)
+	return err
+}
+
+type DownloadAttachment403JSONResponse struct{ ErrorJSONResponse }
+
+func (response DownloadAttachment403JSONResponse) VisitDownloadAttachmentResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type DownloadAttachment404JSONResponse Error
+
+func (response DownloadAttachment404JSONResponse) VisitDownloadAttachmentResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetParcelLabelRequestObject struct {
+	Number Number `json:"number"`
+	Params GetParcelLabelParams
+}
+
+type GetParcelLabelResponseObject interface {
+	VisitGetParcelLabelResponse(w http.ResponseWriter) error
+}
+
+type GetParcelLabel200ApplicationpdfResponse struct {
+	Body          io.Reader
+	ContentLength int64
+}
+
+func (response GetParcelLabel200ApplicationpdfResponse) VisitGetParcelLabelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/pdf")
+	if response.ContentLength != 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(response.ContentLength))
+	}
+	w.WriteHeader(200)
+
+	if closer, ok := response.Body.(io.ReadCloser); ok {
+		defer closer.Close()
+	}
+	_, err := io.Copy(w, response.Body)
+	return err
+}
+
+type GetParcelLabel200ApplicationzplResponse struct {
+	Body          io.Reader
+	ContentLength int64
+}
+
+func (response GetParcelLabel200ApplicationzplResponse) VisitGetParcelLabelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/zpl")
+	if response.ContentLength != 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(response.ContentLength))
+	}
+	w.WriteHeader(200)
+
+	if closer, ok := response.Body.(io.ReadCloser); ok {
+		defer closer.Close()
+	}
+	_, err := io.Copy(w, response.Body)
+	return err
+}
+
+type GetParcelLabel400JSONResponse struct{ ErrorJSONResponse }
+
+func (response GetParcelLabel400JSONResponse) VisitGetParcelLabelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetParcelLabel403JSONResponse Error
+
+func (response GetParcelLabel403JSONResponse) VisitGetParcelLabelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetParcelLabel404JSONResponse Error
+
+func (response GetParcelLabel404JSONResponse) VisitGetParcelLabelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type CancelHandOffRequestObject struct {
+	Number Number `json:"number"`
+}
+
+type CancelHandOffResponseObject interface {
+	VisitCancelHandOffResponse(w http.ResponseWriter) error
+}
+
+type CancelHandOff204Response struct {
+}
+
+func (response CancelHandOff204Response) VisitCancelHandOffResponse(w http.ResponseWriter) error {
+	w.WriteHeader(204)
+	return nil
+}
+
+type CancelHandOff403JSONResponse struct{ ErrorJSONResponse }
+
+func (response CancelHandOff403JSONResponse) VisitCancelHandOffResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type CancelHandOff404JSONResponse Error
+
+func (response CancelHandOff404JSONResponse) VisitCancelHandOffResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetShipmentRequestObject struct {
+	Number Number `json:"number"`
+}
+
+type GetShipmentResponseObject interface {
+	VisitGetShipmentResponse(w http.ResponseWriter) error
+}
+
+type GetShipment200JSONResponse Shipment
+
+func (response GetShipment200JSONResponse) VisitGetShipmentResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetShipment403JSONResponse struct{ ErrorJSONResponse }
+
+func (response GetShipment403JSONResponse) VisitGetShipmentResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetShipment404JSONResponse Error
+
+func (response GetShipment404JSONResponse) VisitGetShipmentResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type HandOffParcelRequestObject struct {
+	Number Number `json:"number"`
+	Body   *HandOffParcelJSONRequestBody
+}
+
+type HandOffParcelResponseObject interface {
+	VisitHandOffParcelResponse(w http.ResponseWriter) error
+}
+
+type HandOffParcel201JSONResponse Shipment
+
+func (response HandOffParcel201JSONResponse) VisitHandOffParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type HandOffParcel400JSONResponse struct{ ErrorJSONResponse }
+
+func (response HandOffParcel400JSONResponse) VisitHandOffParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type HandOffParcel403JSONResponse Error
+
+func (response HandOffParcel403JSONResponse) VisitHandOffParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type HandOffParcel404JSONResponse Error
+
+func (response HandOffParcel404JSONResponse) VisitHandOffParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type HandOffParcel409JSONResponse Error
+
+func (response HandOffParcel409JSONResponse) VisitHandOffParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(409)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type QuoteParcelRequestObject struct {
+	Body *QuoteParcelJSONRequestBody
+}
+
+type QuoteParcelResponseObject interface {
+	VisitQuoteParcelResponse(w http.ResponseWriter) error
+}
+
+type QuoteParcel200JSONResponse Quote
+
+func (response QuoteParcel200JSONResponse) VisitQuoteParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type QuoteParcel400JSONResponse struct{ ErrorJSONResponse }
+
+func (response QuoteParcel400JSONResponse) VisitQuoteParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type QuoteParcel403JSONResponse Error
+
+func (response QuoteParcel403JSONResponse) VisitQuoteParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type QuoteParcel422JSONResponse Error
+
+func (response QuoteParcel422JSONResponse) VisitQuoteParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(422)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ExportParcelsCSVRequestObject struct {
+	Params ExportParcelsCSVParams
+}
+
+type ExportParcelsCSVResponseObject interface {
+	VisitExportParcelsCSVResponse(w http.ResponseWriter) error
+}
+
+type ExportParcelsCSV200TextcsvResponse struct {
+	Body          io.Reader
+	ContentLength int64
+}
+
+func (response ExportParcelsCSV200TextcsvResponse) VisitExportParcelsCSVResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "text/csv")
+	if response.ContentLength != 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(response.ContentLength))
+	}
+	w.WriteHeader(200)
+
+	if closer, ok := response.Body.(io.ReadCloser); ok {
+		defer closer.Close()
+	}
+	_, err := io.Copy(w, response.Body)
+	return err
+}
+
+type ExportParcelsCSV400JSONResponse struct{ ErrorJSONResponse }
+
+func (response ExportParcelsCSV400JSONResponse) VisitExportParcelsCSVResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ExportParcelsCSV403JSONResponse Error
+
+func (response ExportParcelsCSV403JSONResponse) VisitExportParcelsCSVResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type TrackParcelRequestObject struct {
+	Token string `json:"token"`
+}
+
+type TrackParcelResponseObject interface {
+	VisitTrackParcelResponse(w http.ResponseWriter) error
+}
+
+type TrackParcel200JSONResponse TrackingView
+
+func (response TrackParcel200JSONResponse) VisitTrackParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type TrackParcel404JSONResponse struct{ ErrorJSONResponse }
+
+func (response TrackParcel404JSONResponse) VisitTrackParcelResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(404)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// StrictServerInterface represents all server handlers.
+type StrictServerInterface interface {
+	// Режим только для чтения
+	// (GET /admin/read-only)
+	GetReadOnly(ctx context.Context, request GetReadOnlyRequestObject) (GetReadOnlyResponseObject, error)
+	// Переключение режима только для чтения
+	// (PUT /admin/read-only)
+	SetReadOnly(ctx context.Context, request SetReadOnlyRequestObject) (SetReadOnlyResponseObject, error)
+	// Настройки уведомлений клиента по каналам
+	// (GET /clients/{client}/notifications)
+	ListNotificationPreferences(ctx context.Context, request ListNotificationPreferencesRequestObject) (ListNotificationPreferencesResponseObject, error)
+	// Отключение уведомлений в канале
+	// (DELETE /clients/{client}/notifications/{channel})
+	DeleteNotificationPreference(ctx context.Context, request DeleteNotificationPreferenceRequestObject) (DeleteNotificationPreferenceResponseObject, error)
+	// Включение уведомлений в канале
+	// (PUT /clients/{client}/notifications/{channel})
+	SetNotificationPreference(ctx context.Context, request SetNotificationPreferenceRequestObject) (SetNotificationPreferenceResponseObject, error)
+	// Получение всех посылок клиента
+	// (GET /clients/{client}/parcels)
+	ListClientParcels(ctx context.Context, request ListClientParcelsRequestObject) (ListClientParcelsResponseObject, error)
+	// Вебхуки клиента
+	// (GET /clients/{client}/webhooks)
+	ListWebhooks(ctx context.Context, request ListWebhooksRequestObject) (ListWebhooksResponseObject, error)
+	// Регистрация вебхука
+	// (POST /clients/{client}/webhooks)
+	AddWebhook(ctx context.Context, request AddWebhookRequestObject) (AddWebhookResponseObject, error)
+	// Доставки событий, не удавшиеся за все попытки
+	// (GET /clients/{client}/webhooks/failures)
+	ListWebhookFailures(ctx context.Context, request ListWebhookFailuresRequestObject) (ListWebhookFailuresResponseObject, error)
+	// Повтор проваленной доставки с новым запасом попыток
+	// (POST /clients/{client}/webhooks/failures/{id}/replay)
+	ReplayWebhookFailure(ctx context.Context, request ReplayWebhookFailureRequestObject) (ReplayWebhookFailureResponseObject, error)
+	// Удаление вебхука вместе с его доставками
+	// (DELETE /clients/{client}/webhooks/{id})
+	DeleteWebhook(ctx context.Context, request DeleteWebhookRequestObject) (DeleteWebhookResponseObject, error)
+	// Последнее известное положение курьера
+	// (GET /couriers/{courier}/location)
+	GetCourierLocation(ctx context.Context, request GetCourierLocationRequestObject) (GetCourierLocationResponseObject, error)
+	// Сообщение курьером своего положения
+	// (PUT /couriers/{courier}/location)
+	ReportCourierLocation(ctx context.Context, request ReportCourierLocationRequestObject) (ReportCourierLocationResponseObject, error)
+	// Поиск посылок по клиенту, статусу и зоне доставки
+	// (GET /parcels)
+	ListParcels(ctx context.Context, request ListParcelsRequestObject) (ListParcelsResponseObject, error)
+	// Регистрация новой посылки
+	// (POST /parcels)
+	AddParcel(ctx context.Context, request AddParcelRequestObject) (AddParcelResponseObject, error)
+	// Посылки, которые дольше срока остаются в статусе registered или sent
+	// (GET /parcels/overdue)
+	ListOverdueParcels(ctx context.Context, request ListOverdueParcelsRequestObject) (ListOverdueParcelsResponseObject, error)
+	// Полнотекстовый поиск посылок по адресу, номеру и трекинг-коду
+	// (GET /parcels/search)
+	SearchParcels(ctx context.Context, request SearchParcelsRequestObject) (SearchParcelsResponseObject, error)
+	// Посылки, не доставленные в обещанный срок
+	// (GET /parcels/sla-breaches)
+	ListSLABreaches(ctx context.Context, request ListSLABreachesRequestObject) (ListSLABreachesResponseObject, error)
+	// Удаление посылки
+	// (DELETE /parcels/{number})
+	DeleteParcel(ctx context.Context, request DeleteParcelRequestObject) (DeleteParcelResponseObject, error)
+	// Получение посылки по номеру
+	// (GET /parcels/{number})
+	GetParcel(ctx context.Context, request GetParcelRequestObject) (GetParcelResponseObject, error)
+	// Изменение статуса и/или адреса посылки
+	// (PATCH /parcels/{number})
+	UpdateParcel(ctx context.Context, request UpdateParcelRequestObject) (UpdateParcelResponseObject, error)
+	// Документы посылки
+	// (GET /parcels/{number}/attachments)
+	ListAttachments(ctx context.Context, request ListAttachmentsRequestObject) (ListAttachmentsResponseObject, error)
+	// Загрузка документа посылки
+	// (POST /parcels/{number}/attachments)
+	AddAttachment(ctx context.Context, request AddAttachmentRequestObject) (AddAttachmentResponseObject, error)
+	// Удаление документа посылки
+	// (DELETE /parcels/{number}/attachments/{id})
+	DeleteAttachment(ctx context.Context, request DeleteAttachmentRequestObject) (DeleteAttachmentResponseObject, error)
+	// Скачивание документа посылки
+	// (GET /parcels/{number}/attachments/{id})
+	DownloadAttachment(ctx context.Context, request DownloadAttachmentRequestObject) (DownloadAttachmentResponseObject, error)
+	// Транспортная этикетка посылки для печати
+	// (GET /parcels/{number}/label)
+	GetParcelLabel(ctx context.Context, request GetParcelLabelRequestObject) (GetParcelLabelResponseObject, error)
+	// Отмена отправления у перевозчика
+	// (DELETE /parcels/{number}/shipment)
+	CancelHandOff(ctx context.Context, request CancelHandOffRequestObject) (CancelHandOffResponseObject, error)
+	// Отправление посылки у стороннего перевозчика
+	// (GET /parcels/{number}/shipment)
+	GetShipment(ctx context.Context, request GetShipmentRequestObject) (GetShipmentResponseObject, error)
+	// Передача посылки стороннему перевозчику
+	// (POST /parcels/{number}/shipment)
+	HandOffParcel(ctx context.Context, request HandOffParcelRequestObject) (HandOffParcelResponseObject, error)
+	// Расчёт стоимости доставки до регистрации посылки
+	// (POST /quotes)
+	QuoteParcel(ctx context.Context, request QuoteParcelRequestObject) (QuoteParcelResponseObject, error)
+	// Выгрузка посылок в CSV для отчётов
+	// (GET /reports/parcels.csv)
+	ExportParcelsCSV(ctx context.Context, request ExportParcelsCSVRequestObject) (ExportParcelsCSVResponseObject, error)
+	// Публичное отслеживание посылки по трекинг-токену
+	// (GET /track/{token})
+	TrackParcel(ctx context.Context, request TrackParcelRequestObject) (TrackParcelResponseObject, error)
+}
+
+type StrictHandlerFunc = strictnethttp.StrictHTTPHandlerFunc
+type StrictMiddlewareFunc = strictnethttp.StrictHTTPMiddlewareFunc
+
+type StrictHTTPServerOptions struct {
+	RequestErrorHandlerFunc  func(w http.ResponseWriter, r *http.Request, err error)
+	ResponseErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+func NewStrictHandler(ssi StrictServerInterface, middlewares []StrictMiddlewareFunc) ServerInterface {
+	return &strictHandler{ssi: ssi, middlewares: middlewares, options: StrictHTTPServerOptions{
+		RequestErrorHandlerFunc: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		},
+		ResponseErrorHandlerFunc: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		},
+	}}
+}
+
+func NewStrictHandlerWithOptions(ssi StrictServerInterface, middlewares []StrictMiddlewareFunc, options StrictHTTPServerOptions) ServerInterface {
+	return &strictHandler{ssi: ssi, middlewares: middlewares, options: options}
+}
+
+type strictHandler struct {
+	ssi         StrictServerInterface
+	middlewares []StrictMiddlewareFunc
+	options     StrictHTTPServerOptions
+}
+
+// GetReadOnly operation middleware
+func (sh *strictHandler) GetReadOnly(w http.ResponseWriter, r *http.Request) {
+	var request GetReadOnlyRequestObject
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.GetReadOnly(ctx, request.(GetReadOnlyRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "GetReadOnly")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(GetReadOnlyResponseObject); ok {
+		if err := validResponse.VisitGetReadOnlyResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// SetReadOnly operation middleware
+func (sh *strictHandler) SetReadOnly(w http.ResponseWriter, r *http.Request) {
+	var request SetReadOnlyRequestObject
+
+	var body SetReadOnlyJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.SetReadOnly(ctx, request.(SetReadOnlyRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "SetReadOnly")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(SetReadOnlyResponseObject); ok {
+		if err := validResponse.VisitSetReadOnlyResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// ListNotificationPreferences operation middleware
+func (sh *strictHandler) ListNotificationPreferences(w http.ResponseWriter, r *http.Request, client Client) {
+	var request ListNotificationPreferencesRequestObject
+
+	request.Client = client
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ListNotificationPreferences(ctx, request.(ListNotificationPreferencesRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ListNotificationPreferences")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ListNotificationPreferencesResponseObject); ok {
+		if err := validResponse.VisitListNotificationPreferencesResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// DeleteNotificationPreference operation middleware
+func (sh *strictHandler) DeleteNotificationPreference(w http.ResponseWriter, r *http.Request, client Client, channel Channel) {
+	var request DeleteNotificationPreferenceRequestObject
+
+	request.Client = client
+	request.Channel = channel
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.DeleteNotificationPreference(ctx, request.(DeleteNotificationPreferenceRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "DeleteNotificationPreference")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(DeleteNotificationPreferenceResponseObject); ok {
+		if err := validResponse.VisitDeleteNotificationPreferenceResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// SetNotificationPreference operation middleware
+func (sh *strictHandler) SetNotificationPreference(w http.ResponseWriter, r *http.Request, client Client, channel Channel) {
+	var request SetNotificationPreferenceRequestObject
+
+	request.Client = client
+	request.Channel = channel
+
+	var body SetNotificationPreferenceJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.SetNotificationPreference(ctx, request.(SetNotificationPreferenceRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "SetNotificationPreference")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(SetNotificationPreferenceResponseObject); ok {
+		if err := validResponse.VisitSetNotificationPreferenceResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// ListClientParcels operation middleware
+func (sh *strictHandler) ListClientParcels(w http.ResponseWriter, r *http.Request, client int) {
+	var request ListClientParcelsRequestObject
+
+	request.Client = client
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ListClientParcels(ctx, request.(ListClientParcelsRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ListClientParcels")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ListClientParcelsResponseObject); ok {
+		if err := validResponse.VisitListClientParcelsResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// ListWebhooks operation middleware
+func (sh *strictHandler) ListWebhooks(w http.ResponseWriter, r *http.Request, client Client) {
+	var request ListWebhooksRequestObject
+
+	request.Client = client
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ListWebhooks(ctx, request.(ListWebhooksRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ListWebhooks")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ListWebhooksResponseObject); ok {
+		if err := validResponse.VisitListWebhooksResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// AddWebhook operation middleware
+func (sh *strictHandler) AddWebhook(w http.ResponseWriter, r *http.Request, client Client) {
+	var request AddWebhookRequestObject
+
+	request.Client = client
+
+	var body AddWebhookJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.AddWebhook(ctx, request.(AddWebhookRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "AddWebhook")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(AddWebhookResponseObject); ok {
+		if err := validResponse.VisitAddWebhookResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// ListWebhookFailures operation middleware
+func (sh *strictHandler) ListWebhookFailures(w http.ResponseWriter, r *http.Request, client Client) {
+	var request ListWebhookFailuresRequestObject
+
+	request.Client = client
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ListWebhookFailures(ctx, request.(ListWebhookFailuresRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ListWebhookFailures")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ListWebhookFailuresResponseObject); ok {
+		if err := validResponse.VisitListWebhookFailuresResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// ReplayWebhookFailure operation middleware
+func (sh *strictHandler) ReplayWebhookFailure(w http.ResponseWriter, r *http.Request, client Client, id ID) {
+	var request ReplayWebhookFailureRequestObject
+
+	request.Client = client
+	request.Id = id
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ReplayWebhookFailure(ctx, request.(ReplayWebhookFailureRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ReplayWebhookFailure")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ReplayWebhookFailureResponseObject); ok {
+		if err := validResponse.VisitReplayWebhookFailureResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// DeleteWebhook operation middleware
+func (sh *strictHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request, client Client, id ID) {
+	var request DeleteWebhookRequestObject
+
+	request.Client = client
+	request.Id = id
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.DeleteWebhook(ctx, request.(DeleteWebhookRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "DeleteWebhook")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(DeleteWebhookResponseObject); ok {
+		if err := validResponse.VisitDeleteWebhookResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// GetCourierLocation operation middleware
+func (sh *strictHandler) GetCourierLocation(w http.ResponseWriter, r *http.Request, courier int) {
+	var request GetCourierLocationRequestObject
+
+	request.Courier = courier
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.GetCourierLocation(ctx, request.(GetCourierLocationRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "GetCourierLocation")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(GetCourierLocationResponseObject); ok {
+		if err := validResponse.VisitGetCourierLocationResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// ReportCourierLocation operation middleware
+func (sh *strictHandler) ReportCourierLocation(w http.ResponseWriter, r *http.Request, courier int) {
+	var request ReportCourierLocationRequestObject
+
+	request.Courier = courier
+
+	var body ReportCourierLocationJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ReportCourierLocation(ctx, request.(ReportCourierLocationRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ReportCourierLocation")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ReportCourierLocationResponseObject); ok {
+		if err := validResponse.VisitReportCourierLocationResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// ListParcels operation middleware
+func (sh *strictHandler) ListParcels(w http.ResponseWriter, r *http.Request, params ListParcelsParams) {
+	var request ListParcelsRequestObject
+
+	request.Params = params
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ListParcels(ctx, request.(ListParcelsRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ListParcels")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ListParcelsResponseObject); ok {
+		if err := validResponse.VisitListParcelsResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// AddParcel operation middleware
+func (sh *strictHandler) AddParcel(w http.ResponseWriter, r *http.Request) {
+	var request AddParcelRequestObject
+
+	var body AddParcelJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.AddParcel(ctx, request.(AddParcelRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "AddParcel")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(AddParcelResponseObject); ok {
+		if err := validResponse.VisitAddParcelResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// ListOverdueParcels operation middleware
+func (sh *strictHandler) ListOverdueParcels(w http.ResponseWriter, r *http.Request, params ListOverdueParcelsParams) {
+	var request ListOverdueParcelsRequestObject
+
+	request.Params = params
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ListOverdueParcels(ctx, request.(ListOverdueParcelsRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ListOverdueParcels")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ListOverdueParcelsResponseObject); ok {
+		if err := validResponse.VisitListOverdueParcelsResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// SearchParcels operation middleware
+func (sh *strictHandler) SearchParcels(w http.ResponseWriter, r *http.Request, params SearchParcelsParams) {
+	var request SearchParcelsRequestObject
+
+	request.Params = params
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.SearchParcels(ctx, request.(SearchParcelsRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "SearchParcels")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(SearchParcelsResponseObject); ok {
+		if err := validResponse.VisitSearchParcelsResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// ListSLABreaches operation middleware
+func (sh *strictHandler) ListSLABreaches(w http.ResponseWriter, r *http.Request, params ListSLABreachesParams) {
+	var request ListSLABreachesRequestObject
+
+	request.Params = params
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ListSLABreaches(ctx, request.(ListSLABreachesRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ListSLABreaches")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ListSLABreachesResponseObject); ok {
+		if err := validResponse.VisitListSLABreachesResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// DeleteParcel operation middleware
+func (sh *strictHandler) DeleteParcel(w http.ResponseWriter, r *http.Request, number Number, params DeleteParcelParams) {
+	var request DeleteParcelRequestObject
+
+	request.Number = number
+	request.Params = params
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.DeleteParcel(ctx, request.(DeleteParcelRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "DeleteParcel")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(DeleteParcelResponseObject); ok {
+		if err := validResponse.VisitDeleteParcelResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// GetParcel operation middleware
+func (sh *strictHandler) GetParcel(w http.ResponseWriter, r *http.Request, number Number) {
+	var request GetParcelRequestObject
+
+	request.Number = number
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.GetParcel(ctx, request.(GetParcelRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "GetParcel")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(GetParcelResponseObject); ok {
+		if err := validResponse.VisitGetParcelResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// UpdateParcel operation middleware
+func (sh *strictHandler) UpdateParcel(w http.ResponseWriter, r *http.Request, number Number, params UpdateParcelParams) {
+	var request UpdateParcelRequestObject
+
+	request.Number = number
+	request.Params = params
+
+	var body UpdateParcelJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.UpdateParcel(ctx, request.(UpdateParcelRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "UpdateParcel")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(UpdateParcelResponseObject); ok {
+		if err := validResponse.VisitUpdateParcelResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// ListAttachments operation middleware
+func (sh *strictHandler) ListAttachments(w http.ResponseWriter, r *http.Request, number Number) {
+	var request ListAttachmentsRequestObject
+
+	request.Number = number
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ListAttachments(ctx, request.(ListAttachmentsRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ListAttachments")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ListAttachmentsResponseObject); ok {
+		if err := validResponse.VisitListAttachmentsResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// AddAttachment operation middleware
+func (sh *strictHandler) AddAttachment(w http.ResponseWriter, r *http.Request, number Number, params AddAttachmentParams) {
+	var request AddAttachmentRequestObject
+
+	request.Number = number
+	request.Params = params
+
+	request.Body = r.Body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.AddAttachment(ctx, request.(AddAttachmentRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "AddAttachment")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(AddAttachmentResponseObject); ok {
+		if err := validResponse.VisitAddAttachmentResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// DeleteAttachment operation middleware
+func (sh *strictHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request, number Number, id int64) {
+	var request DeleteAttachmentRequestObject
+
+	request.Number = number
+	request.Id = id
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.DeleteAttachment(ctx, request.(DeleteAttachmentRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "DeleteAttachment")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(DeleteAttachmentResponseObject); ok {
+		if err := validResponse.VisitDeleteAttachmentResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// DownloadAttachment operation middleware
+func (sh *strictHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request, number Number, id int64) {
+	var request DownloadAttachmentRequestObject
+
+	request.Number = number
+	request.Id = id
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.DownloadAttachment(ctx, request.(DownloadAttachmentRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "DownloadAttachment")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(DownloadAttachmentResponseObject); ok {
+		if err := validResponse.VisitDownloadAttachmentResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// GetParcelLabel operation middleware
+func (sh *strictHandler) GetParcelLabel(w http.ResponseWriter, r *http.Request, number Number, params GetParcelLabelParams) {
+	var request GetParcelLabelRequestObject
+
+	request.Number = number
+	request.Params = params
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.GetParcelLabel(ctx, request.(GetParcelLabelRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "GetParcelLabel")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(GetParcelLabelResponseObject); ok {
+		if err := validResponse.VisitGetParcelLabelResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// CancelHandOff operation middleware
+func (sh *strictHandler) CancelHandOff(w http.ResponseWriter, r *http.Request, number Number) {
+	var request CancelHandOffRequestObject
+
+	request.Number = number
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.CancelHandOff(ctx, request.(CancelHandOffRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "CancelHandOff")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(CancelHandOffResponseObject); ok {
+		if err := validResponse.VisitCancelHandOffResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// GetShipment operation middleware
+func (sh *strictHandler) GetShipment(w http.ResponseWriter, r *http.Request, number Number) {
+	var request GetShipmentRequestObject
+
+	request.Number = number
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.GetShipment(ctx, request.(GetShipmentRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "GetShipment")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(GetShipmentResponseObject); ok {
+		if err := validResponse.VisitGetShipmentResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// HandOffParcel operation middleware
+func (sh *strictHandler) HandOffParcel(w http.ResponseWriter, r *http.Request, number Number) {
+	var request HandOffParcelRequestObject
+
+	request.Number = number
+
+	var body HandOffParcelJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.HandOffParcel(ctx, request.(HandOffParcelRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "HandOffParcel")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(HandOffParcelResponseObject); ok {
+		if err := validResponse.VisitHandOffParcelResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// QuoteParcel operation middleware
+func (sh *strictHandler) QuoteParcel(w http.ResponseWriter, r *http.Request) {
+	var request QuoteParcelRequestObject
+
+	var body QuoteParcelJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.QuoteParcel(ctx, request.(QuoteParcelRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "QuoteParcel")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(QuoteParcelResponseObject); ok {
+		if err := validResponse.VisitQuoteParcelResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// ExportParcelsCSV operation middleware
+func (sh *strictHandler) ExportParcelsCSV(w http.ResponseWriter, r *http.Request, params ExportParcelsCSVParams) {
+	var request ExportParcelsCSVRequestObject
+
+	request.Params = params
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ExportParcelsCSV(ctx, request.(ExportParcelsCSVRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ExportParcelsCSV")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ExportParcelsCSVResponseObject); ok {
+		if err := validResponse.VisitExportParcelsCSVResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// TrackParcel operation middleware
+func (sh *strictHandler) TrackParcel(w http.ResponseWriter, r *http.Request, token string) {
+	var request TrackParcelRequestObject
+
+	request.Token = token
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.TrackParcel(ctx, request.(TrackParcelRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "TrackParcel")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(TrackParcelResponseObject); ok {
+		if err := validResponse.VisitTrackParcelResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
@@ -0,0 +1,529 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// App приложение трекера: БД, хранилище, сервис и серверы.
+// Останавливается так, чтобы не прерывать транзакции на середине:
+// сначала перестаёт принимать запросы и дожидается текущих,
+// затем завершает фоновые задачи и только после этого закрывает БД.
+type App struct {
+	cfg    config.Config
+	logger Logger
+	db     *sql.DB
+	// reader БД для чтения; nil, если в настройках она не задана
+	reader *sql.DB
+	// cacheClient подключение к Redis для кеша посылок; nil, если кеш не в Redis
+	cacheClient *redis.Client
+	// carriers перевозчики из настроек, статусы которых сверяет CarrierSync
+	carriers map[string]CarrierLink
+	store    ParcelStore
+	// shards БД депо; nil, если в настройках они не заданы
+	shards  *ShardedStore
+	service ParcelService
+	metrics *Metrics
+	// eta оценка времени доставки; nil, если eta.lookback не задан
+	eta *ETAEstimator
+
+	grpcServer *grpc.Server
+	httpServer *http.Server
+	httpAddr   string
+	// errCh получает ошибки серверов и приёма событий сканирования, завершившихся не по Stop
+	errCh chan error
+
+	// ctx отменяется при остановке и сигнализирует фоновым задачам о завершении
+	ctx        context.Context
+	cancel     context.CancelFunc
+	background sync.WaitGroup
+	stopOnce   sync.Once
+	stopErr    error
+
+	// stopTracing отправляет накопленные спаны и останавливает трассировщик
+	stopTracing func(context.Context) error
+	// closePublisher закрывает подключение к шине событий outbox; задаётся в Start
+	closePublisher func() error
+}
+
+// NewApp подключается к БД из настроек и приводит её схему к актуальной версии.
+// Логи пишутся в stderr с уровнем и в формате из настроек.
+func NewApp(cfg config.Config) (*App, error) {
+	base, err := NewLogger(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		return nil, err
+	}
+	redactor := NewRedactor(cfg.Redaction)
+	logger := NewRedactingLogger(base, redactor)
+
+	// трассировщик настраивается до хранилища, чтобы оно взяло уже готовый TracerProvider
+	stopTracing, err := setupTracing(context.Background(), cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := OpenDB(cfg.DB)
+	if err != nil {
+		stopTracing(context.Background())
+		return nil, err
+	}
+
+	err = Migrate(db)
+	if err != nil {
+		db.Close()
+		stopTracing(context.Background())
+		return nil, err
+	}
+
+	metrics := NewMetrics()
+	opts := []ServiceOption{WithLogger(logger), WithMetrics(metrics)}
+	if cfg.RateLimit.RPS > 0 {
+		opts = append(opts, WithRateLimit(cfg.RateLimit.RPS, cfg.RateLimit.Burst))
+	}
+	carriers := newCarriers(cfg.Carriers)
+	opts = append(opts, WithCarriers(carriers), WithLabelSender(cfg.Labels.Sender), WithPublicURL(cfg.PublicURL),
+		WithOverdueSLA(overdueSLA(cfg.Overdue)), WithDuplicatePolicy(duplicatePolicy(cfg.Duplicates)),
+		WithGeocoder(newGeocoder(cfg.Geocoder)))
+	blobs, err := NewBlobStore(cfg.Attachments)
+	if err != nil {
+		db.Close()
+		stopTracing(context.Background())
+		return nil, err
+	}
+	if blobs != nil {
+		opts = append(opts, WithBlobStore(blobs, cfg.Attachments.MaxSize))
+	}
+
+	storeOpts := []StoreOption{
+		WithStoreLogger(logger),
+		WithStoreMetrics(metrics),
+		WithRetryPolicy(retryPolicy(cfg.DB.Retry)),
+		WithTimeouts(storeTimeouts(cfg.DB.Timeouts)),
+		WithPool(poolOptions(cfg.DB.Pool)),
+		WithSlowQueryLog(cfg.DB.SlowQueryThreshold),
+		WithRedaction(redactor),
+		WithReadOnly(cfg.ReadOnly),
+		WithArchiveReads(cfg.Archive.After > 0),
+	}
+	if len(cfg.DB.Encryption.Keys) > 0 {
+		keys, err := NewStaticKeys(cfg.DB.Encryption)
+		if err != nil {
+			db.Close()
+			stopTracing(context.Background())
+			return nil, err
+		}
+		storeOpts = append(storeOpts, WithEncryption(keys))
+	}
+	// БД депо не читают из реплики основной БД и не делят с ней кеш
+	shardOpts := slices.Clone(storeOpts)
+	var reader *sql.DB
+	if cfg.DB.Reader.Path != "" {
+		reader, err = OpenReaderDB(cfg.DB)
+		if err != nil {
+			db.Close()
+			stopTracing(context.Background())
+			return nil, err
+		}
+		storeOpts = append(storeOpts, WithReader(reader, poolOptions(cfg.DB.Reader.Pool)))
+	}
+
+	var cacheClient *redis.Client
+	switch {
+	case cfg.DB.Cache.RedisAddr != "":
+		cacheClient = redis.NewClient(&redis.Options{Addr: cfg.DB.Cache.RedisAddr})
+		storeOpts = append(storeOpts, WithCache(NewRedisCache(cacheClient, cfg.DB.Cache.TTL, logger)))
+	case cfg.DB.Cache.Size > 0:
+		storeOpts = append(storeOpts, WithCache(NewLRUCache(cfg.DB.Cache.Size, cfg.DB.Cache.TTL)))
+	}
+
+	store := NewParcelStore(db, storeOpts...)
+	if cfg.Features.Enabled(config.FeatureDebug) {
+		// ошибка проверки планов не мешает запуску: это только диагностика
+		if err := store.LogQueryPlans(); err != nil {
+			logger.Log(context.Background(), slog.LevelError, "не удалось получить планы запросов", "op", "store.ExplainQueries", "error", err)
+		}
+	}
+	var shards *ShardedStore
+	if len(cfg.DB.Shards) > 0 {
+		shards, err = OpenShards(cfg.DB, shardOpts...)
+		if err != nil {
+			db.Close()
+			if reader != nil {
+				reader.Close()
+			}
+			if cacheClient != nil {
+				cacheClient.Close()
+			}
+			stopTracing(context.Background())
+			return nil, err
+		}
+	}
+	var eta *ETAEstimator
+	if cfg.ETA.Lookback > 0 {
+		eta = NewETAEstimator(store, cfg.ETA)
+		opts = append(opts, WithETA(eta))
+	}
+	var writer *BatchWriter
+	if cfg.DB.Batch.Size > 1 {
+		writer = NewBatchWriter(store, cfg.DB.Batch.Size, cfg.DB.Batch.Delay)
+		opts = append(opts, WithBatchWriter(writer))
+	}
+	// спан ParcelStorage.<метод> объединяет спаны ParcelStore всех попыток операции сервиса
+	opts = append(opts, WithStorage(Wrap(store.Storage(), TracingMiddleware(otel.GetTracerProvider()))))
+	// фоновые задачи обходят посылки всех компаний
+	ctx, cancel := context.WithCancel(withAnyTenant(context.Background()))
+	app := &App{
+		cfg:    cfg,
+		logger: logger,
+		db:     db,
+		reader: reader,
+
+		cacheClient: cacheClient,
+		carriers:    carriers,
+		store:       store,
+		shards:      shards,
+		service:     NewParcelService(store, opts...),
+		metrics:     metrics,
+		eta:         eta,
+		errCh:       make(chan error, 3),
+		ctx:         ctx,
+		cancel:      cancel,
+
+		stopTracing: stopTracing,
+	}
+	// запись пачками работает до Stop: он отменяет её после остановки серверов,
+	// поэтому регистрации из уже принятых запросов успевают записаться
+	if writer != nil {
+		app.Go(writer.Run)
+	}
+	return app, nil
+}
+
+// Go запускает фоновую задачу; Stop отменяет её контекст и дожидается завершения
+func (a *App) Go(task func(ctx context.Context)) {
+	a.background.Add(1)
+	go func() {
+		defer a.background.Done()
+		task(a.ctx)
+	}()
+}
+
+// Start запускает серверы из настроек. Пустой адрес означает, что сервер не запускается.
+// Адреса занимаются сразу, поэтому ошибка прослушивания возвращается из Start;
+// уже запущенные к этому моменту серверы останавливает Stop.
+func (a *App) Start() error {
+	grpcOpts := append([]grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}, RequestIDServerOptions()...)
+	grpcOpts = append(grpcOpts, TenantServerOptions()...)
+	handler := newHTTPHandler(a.service, a.cfg.Features)
+	if a.cfg.Auth.RequireAPIKey {
+		grpcOpts = append(grpcOpts, APIKeyServerOptions(a.service)...)
+		handler = APIKeyMiddleware(a.service, handler)
+	}
+	// компания из заголовка определяется до проверки ключа, который может её заменить
+	handler = TenantMiddleware(handler)
+	// идентификатор запроса назначается до проверки ключа, чтобы он был и в ответах с отказом
+	handler = RequestIDMiddleware(handler)
+	// спан запроса начинается до проверки ключа, чтобы в трассировку попадали и отказы
+	handler = otelhttp.NewHandler(handler, "http",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}))
+
+	publisher, closePublisher, err := newEventPublisher(a.cfg.Outbox, a.logger)
+	if err != nil {
+		return err
+	}
+	a.closePublisher = closePublisher
+	var publishers fanoutPublisher
+	if publisher != nil {
+		publishers = append(publishers, publisher)
+	}
+	if a.cfg.Features.Enabled(config.FeatureWebhooks) {
+		w := a.cfg.Webhooks
+		dispatcher := NewWebhookDispatcher(a.store, retryPolicy(w.Retry), w.Timeout, w.Interval, w.BatchSize)
+		publishers = append(publishers, dispatcher)
+		a.Go(dispatcher.Run)
+	}
+	// уведомления идут последними: если откажет получатель до них,
+	// релей повторит событие, и получатель не получит уведомление дважды
+	notifiers := newNotifiers(a.cfg.Notify, a.cfg.PublicURL)
+	if len(notifiers) > 0 {
+		publishers = append(publishers, NewNotificationPublisher(a.store, notifiers))
+	}
+	if a.cfg.Telegram.Token != "" {
+		bot := NewTelegramBot(a.service, a.cfg.Telegram)
+		publishers = append(publishers, bot)
+		a.Go(bot.Run)
+	}
+	scheduler := NewScheduler(a.cfg.Jobs, a.logger, a.metrics)
+	if len(publishers) > 0 {
+		relay := NewOutboxRelay(a.store, publishers, a.cfg.Outbox.BatchSize)
+		scheduler.Add(Job{Name: config.JobOutbox, Enabled: true, Interval: a.cfg.Outbox.Interval, Run: relay.Relay})
+	}
+	if a.cfg.Reports.Dir != "" {
+		reports := NewReports(a.store, a.cfg.Reports.Dir)
+		scheduler.Add(Job{Name: config.JobReports, Enabled: true, Interval: time.Hour, Run: reports.GenerateDue})
+		scheduler.Add(Job{Name: config.JobSLA, Enabled: true, Interval: time.Hour, Run: reports.GenerateSLADue})
+	}
+	sla := overdueSLA(a.cfg.Overdue)
+	alerts := NewOverdueAlerts(a.store, sla, notifiers, a.cfg.Overdue.Alerts, a.metrics)
+	scheduler.Add(Job{
+		Name:     config.JobOverdue,
+		Enabled:  sla.Registered > 0 || sla.Sent > 0,
+		Interval: 15 * time.Minute,
+		Jitter:   time.Minute,
+		Run:      alerts.Check,
+	})
+	archiver := NewArchiver(a.store, a.cfg.Archive.After, a.cfg.Archive.BatchSize, a.cfg.Archive.Dir)
+	scheduler.Add(Job{
+		Name:     config.JobArchive,
+		Enabled:  a.cfg.Archive.After > 0,
+		Interval: 24 * time.Hour,
+		Jitter:   time.Hour,
+		Run:      archiver.Archive,
+	})
+	backupSink, err := NewBackupSink(a.cfg.Backup)
+	if err != nil {
+		return err
+	}
+	backups := NewBackups(a.store, a.cfg.DB, backupSink, a.cfg.Backup.Keep)
+	scheduler.Add(Job{
+		Name:     config.JobBackup,
+		Enabled:  a.cfg.Backup.Enabled(),
+		Interval: 24 * time.Hour,
+		Jitter:   time.Hour,
+		Run:      backups.Backup,
+	})
+	reencryptor := NewReencryptor(a.store, a.cfg.DB.Encryption.BatchSize)
+	scheduler.Add(Job{
+		Name:     config.JobReencrypt,
+		Enabled:  len(a.cfg.DB.Encryption.Keys) > 0,
+		Interval: time.Hour,
+		Jitter:   5 * time.Minute,
+		Run:      reencryptor.Reencrypt,
+	})
+	maintenance := NewMaintenance(a.store)
+	scheduler.Add(Job{
+		Name:     config.JobMaintenance,
+		Interval: 7 * 24 * time.Hour,
+		Jitter:   time.Hour,
+		Run:      maintenance.Run,
+	})
+	retention := a.cfg.History.Retention
+	scheduler.Add(Job{
+		Name:     config.JobHistoryPruning,
+		Enabled:  retention > 0,
+		Interval: 24 * time.Hour,
+		Jitter:   time.Hour,
+		Run: func(ctx context.Context) error {
+			_, err := a.store.WithContext(ctx).PruneHistory(time.Now().Add(-retention))
+			return err
+		},
+	})
+	changeRetention := a.cfg.DB.Changes.Retention
+	scheduler.Add(Job{
+		Name:     config.JobChangeLogPruning,
+		Enabled:  changeRetention > 0,
+		Interval: time.Hour,
+		Jitter:   5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			var errs []error
+			for _, store := range a.changeLogStores() {
+				_, err := store.WithContext(ctx).PruneChangeLog(time.Now().Add(-changeRetention))
+				errs = append(errs, err)
+			}
+			return errors.Join(errs...)
+		},
+	})
+	analytics := NewReportsStore(a.store)
+	scheduler.Add(Job{
+		Name:     config.JobAnalytics,
+		Enabled:  true,
+		Interval: time.Hour,
+		Jitter:   5 * time.Minute,
+		Run:      analytics.RefreshDue,
+	})
+	a.Go(scheduler.Run)
+
+	if c := a.cfg.DB.Changes; c.Interval > 0 {
+		for _, store := range a.changeLogStores() {
+			a.Go(NewChangeWatcher(store, c, a.logger).Run)
+		}
+	}
+	if m := a.cfg.DB.Monitor; m.Interval > 0 {
+		a.Go(NewHealthMonitor(a.db, "primary", m, a.metrics, a.logger).Run)
+		if a.reader != nil {
+			a.Go(NewHealthMonitor(a.reader, "reader", m, a.metrics, a.logger).Run)
+		}
+	}
+
+	if a.eta != nil {
+		a.Go(a.eta.Run)
+	}
+
+	if len(a.carriers) > 0 {
+		carrierSync := NewCarrierSync(a.store, a.carriers, a.cfg.Carriers.SyncInterval, a.cfg.Carriers.BatchSize)
+		a.Go(carrierSync.Run)
+	}
+
+	if a.cfg.Scans.NATSURL != "" {
+		conn, err := nats.Connect(a.cfg.Scans.NATSURL, nats.Name("tracker-scans"))
+		if err != nil {
+			return err
+		}
+		consumer := NewScanConsumer(a.store)
+		a.Go(func(ctx context.Context) {
+			defer conn.Close()
+			err := consumer.RunNATS(ctx, conn, a.cfg.Scans.Subject, a.cfg.Scans.Queue)
+			if err != nil {
+				a.errCh <- err
+			}
+		})
+	}
+
+	if a.cfg.GRPC.Addr != "" {
+		lis, err := net.Listen("tcp", a.cfg.GRPC.Addr)
+		if err != nil {
+			return err
+		}
+		a.grpcServer = NewGRPCServer(a.service, grpcOpts...)
+		a.logger.Log(a.ctx, slog.LevelInfo, "gRPC-сервер запущен", "addr", lis.Addr().String())
+		go func() {
+			err := a.grpcServer.Serve(lis)
+			if err != nil {
+				a.errCh <- err
+			}
+		}()
+	}
+
+	if a.cfg.HTTP.Addr != "" {
+		lis, err := net.Listen("tcp", a.cfg.HTTP.Addr)
+		if err != nil {
+			return err
+		}
+		a.httpAddr = lis.Addr().String()
+		a.httpServer = &http.Server{Handler: handler}
+		a.logger.Log(a.ctx, slog.LevelInfo, "HTTP-сервер запущен", "addr", a.httpAddr)
+		go func() {
+			err := a.httpServer.Serve(lis)
+			if !errors.Is(err, http.ErrServerClosed) {
+				a.errCh <- err
+			}
+		}()
+	}
+
+	return nil
+}
+
+// HTTPAddr возвращает адрес, который слушает HTTP-сервер после Start,
+// например, когда в настройках указан порт 0
+func (a *App) HTTPAddr() string {
+	return a.httpAddr
+}
+
+// changeLogStores возвращает хранилища БД со своим журналом изменений: основной
+// и каждого депо
+func (a *App) changeLogStores() []ParcelStore {
+	stores := []ParcelStore{a.store}
+	if a.shards != nil {
+		for _, sh := range a.shards.Shards() {
+			stores = append(stores, sh.Store)
+		}
+	}
+	return stores
+}
+
+// Stop останавливает приложение; ctx ограничивает ожидание текущих запросов.
+// Повторные вызовы возвращают результат первого.
+func (a *App) Stop(ctx context.Context) error {
+	a.stopOnce.Do(func() {
+		err := a.stopServers(ctx)
+
+		a.cancel()
+		a.background.Wait()
+
+		if a.closePublisher != nil {
+			err = errors.Join(err, a.closePublisher())
+		}
+		err = errors.Join(err, a.store.Close())
+		if a.shards != nil {
+			err = errors.Join(err, a.shards.Close())
+		}
+		if a.reader != nil {
+			err = errors.Join(err, a.reader.Close())
+		}
+		if a.cacheClient != nil {
+			err = errors.Join(err, a.cacheClient.Close())
+		}
+		a.stopErr = errors.Join(err, a.db.Close(), a.stopTracing(ctx))
+		if a.grpcServer != nil || a.httpServer != nil {
+			a.logger.Log(context.Background(), slog.LevelInfo, "приложение остановлено", "error", a.stopErr)
+		}
+	})
+	return a.stopErr
+}
+
+// stopServers перестаёт принимать запросы и дожидается завершения текущих
+func (a *App) stopServers(ctx context.Context) error {
+	// долгие потоки изменений сами не завершаются, поэтому закрываем их первыми
+	a.store.changes.close()
+
+	var err error
+	if a.httpServer != nil {
+		err = a.httpServer.Shutdown(ctx)
+	}
+	if a.grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			a.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			a.grpcServer.Stop()
+			err = errors.Join(err, ctx.Err())
+		}
+	}
+	return err
+}
+
+// Run запускает приложение и работает до SIGINT/SIGTERM, отмены ctx
+// или ошибки сервера, после чего останавливает его в пределах ShutdownTimeout
+func (a *App) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := a.Start()
+	if err != nil {
+		return errors.Join(err, a.Stop(context.Background()))
+	}
+
+	select {
+	case <-ctx.Done():
+	case err = <-a.errCh:
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), a.cfg.ShutdownTimeout)
+	defer cancel()
+	return errors.Join(err, a.Stop(stopCtx))
+}
@@ -0,0 +1,51 @@
+package tracker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAppStop проверяет, что остановка завершает открытые потоки изменений
+// и закрывает БД только после них
+func TestAppStop(t *testing.T) {
+	// prepare
+	cfg := testConfig
+	cfg.DB = testDBConfig(t)
+	cfg.HTTP.Addr = "127.0.0.1:0"
+	cfg.GRPC.Addr = ""
+
+	app, err := NewApp(cfg)
+	require.NoError(t, err)
+	require.NoError(t, app.Start())
+
+	resp, err := http.Get("http://" + app.HTTPAddr() + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var finished bool
+	app.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		finished = true
+	})
+
+	// stop
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, app.Stop(ctx))
+
+	// check
+	// поток /events завершён сервером, а не по таймауту
+	_, err = io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.True(t, finished)
+	assert.Error(t, app.db.Ping())
+
+	// повторная остановка безопасна
+	assert.NoError(t, app.Stop(ctx))
+}
@@ -0,0 +1,210 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// queryArchiveCandidates доставленные посылки, последний статус которых установлен раньше
+	// :before; без истории (её могла удалить задача history_pruning) — зарегистрированные раньше
+	queryArchiveCandidates = `SELECT p.number FROM parcel p WHERE p.status = 'delivered' AND ` + tenantCond + `
+		AND COALESCE((SELECT MAX(h.changed_at) FROM parcel_history h WHERE h.number = p.number), p.created_at) < :before
+		ORDER BY p.number LIMIT :limit`
+	// archivedNumbers номера посылок порции, переданные в :numbers массивом JSON
+	archivedNumbers     = "SELECT value FROM json_each(:numbers)"
+	queryArchiveParcels = `INSERT INTO parcel_archive (number, client, status, address, created_at, tracking_token, tenant_id, delivered_at, archived_at,
+			priority, weight, price, zone, deadline)
+		SELECT p.number, p.client, p.status, p.address, p.created_at, p.tracking_token, p.tenant_id,
+			COALESCE(p.delivered_at, (SELECT MAX(h.changed_at) FROM parcel_history h WHERE h.number = p.number), p.created_at), :archived_at,
+			p.priority, p.weight, p.price, p.zone, p.deadline
+		FROM parcel p WHERE p.number IN (` + archivedNumbers + `)`
+	queryArchiveHistory = `INSERT INTO parcel_history_archive (id, number, status, changed_at, request_id)
+		SELECT id, number, status, changed_at, request_id FROM parcel_history WHERE number IN (` + archivedNumbers + `)`
+	queryPruneArchivedHistory = "DELETE FROM parcel_history WHERE number IN (" + archivedNumbers + ")"
+	queryPruneArchivedAlerts  = "DELETE FROM overdue_alert WHERE number IN (" + archivedNumbers + ")"
+	queryPruneArchivedParcels = "DELETE FROM parcel WHERE number IN (" + archivedNumbers + ")"
+
+	// archivedColumns колонки parcel_archive в порядке parcelColumns. Версии у архивной
+	// посылки нет: она больше не меняется, а ожидаемое время доставки ей не нужно.
+	archivedColumns       = "number, client, status, address, created_at, COALESCE(tracking_token, ''), tenant_id, delivered_at, 0, '', priority, COALESCE(weight, 0), COALESCE(price, 0), COALESCE(zone, ''), COALESCE(deadline, '')"
+	queryArchivedByNumber = "SELECT " + archivedColumns + " FROM parcel_archive WHERE number = :number AND " + tenantCond
+	queryArchivedByToken  = "SELECT " + archivedColumns + " FROM parcel_archive WHERE tracking_token = :token AND " + tenantCond
+	queryArchivedHistory  = "SELECT number, status, changed_at, COALESCE(request_id, '') FROM parcel_history_archive WHERE number = :number AND EXISTS (SELECT 1 FROM parcel_archive WHERE number = :number AND " + tenantCond + ") ORDER BY id"
+	// queryParcelsByClientWithArchive посылки клиента вместе с архивными в порядке номеров
+	queryParcelsByClientWithArchive = "SELECT " + parcelColumns + " FROM parcel WHERE client = :client AND " + tenantCond +
+		" UNION ALL SELECT " + archivedColumns + " FROM parcel_archive WHERE client = :client AND " + tenantCond + " ORDER BY number"
+)
+
+// WithArchiveReads включает чтение архива: Get, GetByToken и History находят посылку
+// в архиве, если её нет в parcel, а GetByClient возвращает посылки клиента вместе
+// с архивными. Так старые трекинг-токены и номера продолжают открываться после переноса.
+// List, поиск и выгрузки архив по-прежнему не читают.
+func WithArchiveReads(enabled bool) StoreOption {
+	return func(s *ParcelStore) {
+		s.archiveReads = enabled
+	}
+}
+
+// archivedQueries запросы чтения архива для проверки их планов
+var archivedQueries = []string{
+	queryArchivedByNumber,
+	queryArchivedByToken,
+	queryArchivedHistory,
+	queryParcelsByClientWithArchive,
+}
+
+// ArchiveDelivered переносит в архив до limit посылок, доставленных раньше before:
+// посылка с историей копируется в таблицы parcel_archive и parcel_history_archive
+// и удаляется из parcel в одной транзакции. Get, GetByClient и List архивные посылки
+// не возвращают, если не включено WithArchiveReads. Если w не nil, перенесённые посылки после фиксации записываются в w
+// в формате ExportJSON, и такой файл можно загрузить обратно через ImportJSON.
+// Событий outbox перенос не создаёт. Возвращает число перенесённых посылок.
+func (s ParcelStore) ArchiveDelivered(before time.Time, limit int, w io.Writer) (int, error) {
+	start := time.Now()
+	span := s.startSpan("ArchiveDelivered")
+	defer span.End()
+
+	var numbers []int
+	var records bytes.Buffer
+	err := s.withRetry("store.ArchiveDelivered", func(s ParcelStore) error {
+		records.Reset()
+		var err error
+		numbers, err = s.archiveDelivered(before, limit, &records, w != nil)
+		return err
+	})
+	for _, number := range numbers {
+		s.invalidate(number)
+	}
+	if err == nil && w != nil && len(numbers) > 0 {
+		_, err = records.WriteTo(w)
+	}
+	spanError(span, err)
+	s.metrics.observeQuery("ArchiveDelivered", start)
+	logResult(s.ctx, s.logger, "store.ArchiveDelivered", start, err, "before", before, "archived", len(numbers))
+	return len(numbers), err
+}
+
+// archiveDelivered переносит одну порцию посылок в транзакции и возвращает их номера;
+// при withRecords посылки с историей записываются в records до удаления
+func (s ParcelStore) archiveDelivered(before time.Time, limit int, records io.Writer, withRecords bool) ([]int, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := s.query(tx, queryArchiveCandidates,
+		sql.Named("before", before.UTC().Format(time.RFC3339)),
+		sql.Named("limit", limit),
+		sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, err
+	}
+	var numbers []int
+	for rows.Next() {
+		var n int
+		err = rows.Scan(&n)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		numbers = append(numbers, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(numbers) == 0 {
+		return nil, nil
+	}
+
+	list, err := json.Marshal(numbers)
+	if err != nil {
+		return nil, err
+	}
+	arg := sql.Named("numbers", string(list))
+	if withRecords {
+		rows, err := s.query(tx, fmt.Sprintf(queryParcelRecords, archivedNumbers), arg)
+		if err != nil {
+			return nil, err
+		}
+		_, err = s.writeParcelRecords(records, rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = s.exec(tx, queryArchiveParcels, arg, sql.Named("archived_at", time.Now().UTC().Format(time.RFC3339)))
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range []string{queryArchiveHistory, queryPruneArchivedHistory, queryPruneArchivedAlerts, queryPruneArchivedParcels} {
+		_, err = s.exec(tx, q, arg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return numbers, tx.Commit()
+}
+
+// Archiver задача планировщика archive: переносит в архив посылки, доставленные
+// раньше срока хранения after, порциями по batch посылок
+type Archiver struct {
+	store ParcelStore
+	after time.Duration
+	batch int
+	// dir каталог файлов архива; пусто — посылки только переносятся в таблицы архива
+	dir string
+	// now текущее время; подменяется в тестах
+	now func() time.Time
+}
+
+// NewArchiver создаёт перенос в архив; если dir задан, перенесённые посылки ещё и
+// дописываются в файл archive-<дата>.ndjson в этом каталоге
+func NewArchiver(store ParcelStore, after time.Duration, batch int, dir string) *Archiver {
+	return &Archiver{store: store, after: after, batch: batch, dir: dir, now: time.Now}
+}
+
+// Path возвращает путь к файлу архива за день day
+func (a *Archiver) Path(day time.Time) string {
+	return filepath.Join(a.dir, "archive-"+day.UTC().Format("2006-01-02")+".ndjson")
+}
+
+// Archive переносит порции, пока не перенесены все подходящие посылки или не отменён ctx.
+// Каждая порция — отдельная транзакция, чтобы перенос не держал запись в БД надолго.
+func (a *Archiver) Archive(ctx context.Context) error {
+	now := a.now()
+	store := a.store.WithContext(ctx)
+
+	var w io.Writer
+	if a.dir != "" {
+		err := os.MkdirAll(a.dir, 0o755)
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(a.Path(now), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	for ctx.Err() == nil {
+		n, err := store.ArchiveDelivered(now.Add(-a.after), a.batch, w)
+		if err != nil {
+			return err
+		}
+		if n < a.batch {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,123 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestArchiveDelivered проверяет перенос доставленных посылок в архив и файл архива
+func TestArchiveDelivered(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	// отдельная БД, чтобы в архив не попали посылки других тестов
+	db := openTestDB(t)
+
+	// пул из одного соединения, как в настройках по умолчанию: архивирование идёт в транзакции
+	store := NewParcelStore(db, WithPool(PoolOptions{MaxOpenConns: 1, MaxIdleConns: 1}))
+	client := randRange.Intn(10_000_000) + 1
+	add := func(status string) int {
+		t.Helper()
+		p := getTestParcel()
+		p.Client = client
+		id, err := store.Add(p)
+		require.NoError(t, err)
+		if status != ParcelStatusRegistered {
+			require.NoError(t, store.SetStatus(id, status))
+		}
+		return id
+	}
+	delivered := []int{add(ParcelStatusDelivered), add(ParcelStatusDelivered), add(ParcelStatusDelivered)}
+	sent := add(ParcelStatusSent)
+
+	// archive
+	// срок хранения ещё не прошёл
+	n, err := store.ArchiveDelivered(time.Now().Add(-time.Hour), 10, nil)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	archiver := NewArchiver(store, time.Hour, 2, t.TempDir())
+	now := time.Now().Add(2 * time.Hour)
+	archiver.now = func() time.Time { return now }
+	require.NoError(t, archiver.Archive(context.Background()))
+
+	// check
+	// перенесены все доставленные посылки, хотя порция меньше их числа
+	parcels, err := store.GetByClient(client)
+	require.NoError(t, err)
+	require.Len(t, parcels, 1)
+	assert.Equal(t, sent, parcels[0].Number)
+	_, err = store.Get(delivered[0])
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	history, err := store.History(delivered[0])
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	var archived, archivedHistory int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM parcel_archive WHERE client = ?", client).Scan(&archived))
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM parcel_history_archive WHERE number = ?", delivered[0]).Scan(&archivedHistory))
+	assert.Equal(t, len(delivered), archived)
+	assert.Equal(t, 2, archivedHistory)
+
+	// файл архива загружается обратно через ImportJSON
+	f, err := os.Open(archiver.Path(now))
+	require.NoError(t, err)
+	defer f.Close()
+	n, err = store.ImportJSON(f)
+	require.NoError(t, err)
+	assert.Equal(t, len(delivered), n)
+	stored, err := store.Get(delivered[0])
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusDelivered, stored.Status)
+	history, err = store.History(delivered[0])
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+// TestArchiveReads проверяет, что с WithArchiveReads архивная посылка находится по номеру
+// и трекинг-токену, с историей, и входит в посылки клиента
+func TestArchiveReads(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+	store := NewParcelStore(db, WithArchiveReads(true))
+	p := newParcelFixture().client(7).build()
+	p.TrackingToken = "archivedtoken"
+	archived := mustAddParcel(t, store, p)
+	require.NoError(t, store.SetStatus(archived.Number, ParcelStatusDelivered))
+	active := mustAddParcel(t, store, newParcelFixture().client(7).build())
+	n, err := store.ArchiveDelivered(time.Now().Add(time.Hour), 10, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	// get
+	p, err = store.Get(archived.Number)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusDelivered, p.Status)
+	assert.Equal(t, archived.Address, p.Address)
+	p, err = store.GetByToken(archived.TrackingToken)
+	require.NoError(t, err)
+	assert.Equal(t, archived.Number, p.Number)
+	history, err := store.History(archived.Number)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, ParcelStatusDelivered, history[1].Status)
+
+	// client
+	parcels, err := store.GetByClient(7)
+	require.NoError(t, err)
+	require.Len(t, parcels, 2)
+	assert.Equal(t, archived.Number, parcels[0].Number)
+	assert.Equal(t, active.Number, parcels[1].Number)
+
+	// без WithArchiveReads архивной посылки нет
+	_, err = NewParcelStore(db).Get(archived.Number)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,383 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// Виды документов посылки
+const (
+	AttachmentInvoice = "invoice"
+	AttachmentCustoms = "customs"
+	AttachmentPhoto   = "photo"
+	AttachmentOther   = "other"
+)
+
+// maxAttachmentName наибольшая длина имени документа в символах
+const maxAttachmentName = 255
+
+var (
+	// ErrNoBlobStore возвращается при работе с документами, если не задано, где их хранить
+	ErrNoBlobStore = errors.New("хранилище документов не настроено")
+	// ErrInvalidAttachment возвращается при неизвестном виде документа или неверном имени файла
+	ErrInvalidAttachment = errors.New("неверный документ")
+	// ErrAttachmentTooLarge возвращается, если документ больше attachments.max_size
+	ErrAttachmentTooLarge = errors.New("документ слишком большой")
+	// ErrNoAttachment возвращается, если у посылки нет документа с таким номером
+	ErrNoAttachment = errors.New("документ не найден")
+)
+
+const (
+	queryAddAttachment = `INSERT INTO attachment (number, tenant_id, kind, name, content_type, size, sha256, blob_key,
+			created_at, uploaded_by, request_id)
+		VALUES (:number, :tenant, :kind, :name, :content_type, :size, :sha256, :blob_key,
+			:created_at, :uploaded_by, NULLIF(:request_id, ''))`
+	attachmentColumns = "id, number, kind, name, content_type, size, sha256, blob_key, created_at"
+	queryAttachments  = "SELECT " + attachmentColumns + " FROM attachment WHERE number = :number AND " + tenantCond + " ORDER BY id"
+	queryAttachment   = "SELECT " + attachmentColumns + " FROM attachment WHERE id = :id AND number = :number AND " + tenantCond
+	// queryDeleteAttachment удаляет запись о документе; содержимое удаляет сервис из BlobStore
+	queryDeleteAttachment  = "DELETE FROM attachment WHERE id = :id"
+	queryDeleteAttachments = "DELETE FROM attachment WHERE number = :number"
+)
+
+// BlobStore хранилище содержимого документов: каталог на диске или бакет S3.
+// Те же DirSink и S3Sink хранят снимки БД.
+type BlobStore interface {
+	// Put сохраняет содержимое под именем name, читая size байт из r, и возвращает, где оно лежит
+	Put(ctx context.Context, name string, r io.Reader, size int64) (string, error)
+	// Get открывает содержимое name; для отсутствующего возвращается ошибка os.ErrNotExist
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// Delete удаляет содержимое name
+	Delete(ctx context.Context, name string) error
+}
+
+// NewBlobStore создаёт хранилище документов из настроек: бакет S3, если он задан,
+// иначе каталог cfg.Dir. Без них возвращается nil — документы не принимаются.
+func NewBlobStore(cfg config.Attachments) (BlobStore, error) {
+	if cfg.S3.Bucket != "" {
+		return NewS3Sink(cfg.S3)
+	}
+	if cfg.Dir != "" {
+		return NewDirSink(cfg.Dir), nil
+	}
+	return nil, nil
+}
+
+// WithBlobStore задаёт хранилище документов посылок и наибольший размер документа в байтах
+func WithBlobStore(b BlobStore, maxSize int64) ServiceOption {
+	return func(s *ParcelService) {
+		s.blobs = b
+		s.maxAttachment = maxSize
+	}
+}
+
+// Attachment документ, приложенный к посылке: накладная, таможенная форма или фотография
+type Attachment struct {
+	ID     int64
+	Number int
+	// Kind вид документа: invoice, customs, photo или other
+	Kind string
+	// Name имя файла, под которым документ загрузили
+	Name string
+	// ContentType тип содержимого, определённый по его первым байтам
+	ContentType string
+	Size        int64
+	// SHA256 хеш содержимого в hex для проверки скачанного документа
+	SHA256    string
+	CreatedAt string
+	// key имя содержимого в BlobStore
+	key string
+}
+
+// validAttachmentName проверяет имя файла: непустое, без путей и управляющих символов,
+// чтобы его можно было отдать в заголовке Content-Disposition
+func validAttachmentName(name string) bool {
+	if name == "" || utf8.RuneCountInString(name) > maxAttachmentName || !utf8.ValidString(name) {
+		return false
+	}
+	return !strings.ContainsFunc(name, func(r rune) bool {
+		return r == '/' || r == '\\' || r == '"' || unicode.IsControl(r)
+	})
+}
+
+// newBlobKey возвращает случайное имя содержимого документа; имя файла в него
+// не входит, поэтому ключ безопасен и для каталога, и для бакета
+func newBlobKey() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read не возвращает ошибок
+	rand.Read(b)
+	return "attachment-" + hex.EncodeToString(b)
+}
+
+// AddAttachment записывает документ a посылки a.Number, содержимое которого уже
+// лежит в хранилище, и возвращает его с номером
+func (s ParcelStore) AddAttachment(a Attachment) (Attachment, error) {
+	start := time.Now()
+	span := s.startSpan("AddAttachment", attrNumber.Int(a.Number))
+	defer span.End()
+
+	err := s.withRetry("store.AddAttachment", func(s ParcelStore) error {
+		var err error
+		a.ID, err = s.addAttachment(a)
+		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("AddAttachment", start)
+	logResult(s.ctx, s.logger, "store.AddAttachment", start, err, "number", a.Number, "attachment", a.ID, "kind", a.Kind, "size", a.Size)
+	return a, err
+}
+
+func (s ParcelStore) addAttachment(a Attachment) (int64, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var status, tenant string
+	var client int
+	err = s.queryRow(tx, queryParcelStatus, sql.Named("number", a.Number), sql.Named("tenant", s.tenant())).Scan(&status, &client, &tenant)
+	if err != nil {
+		return 0, err
+	}
+	var uploadedBy any
+	if c, ok := CallerFromContext(s.ctx); ok {
+		uploadedBy = c.Client
+	}
+	res, err := s.exec(tx, queryAddAttachment,
+		sql.Named("number", a.Number),
+		sql.Named("tenant", tenant),
+		sql.Named("kind", a.Kind),
+		sql.Named("name", a.Name),
+		sql.Named("content_type", a.ContentType),
+		sql.Named("size", a.Size),
+		sql.Named("sha256", a.SHA256),
+		sql.Named("blob_key", a.key),
+		sql.Named("created_at", a.CreatedAt),
+		sql.Named("uploaded_by", uploadedBy),
+		sql.Named("request_id", RequestIDFromContext(s.ctx)))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// Attachments возвращает документы посылки number в порядке загрузки
+func (s ParcelStore) Attachments(number int) ([]Attachment, error) {
+	defer s.metrics.observeQuery("Attachments", time.Now())
+	span := s.startSpan("Attachments", attrNumber.Int(number))
+	defer span.End()
+
+	rows, err := s.readQuery(queryAttachments, sql.Named("number", number), sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer rows.Close()
+
+	var res []Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		res = append(res, a)
+	}
+	return res, spanError(span, rows.Err())
+}
+
+// Attachment возвращает документ id посылки number или sql.ErrNoRows, если его нет
+func (s ParcelStore) Attachment(number int, id int64) (Attachment, error) {
+	defer s.metrics.observeQuery("Attachment", time.Now())
+	span := s.startSpan("Attachment", attrNumber.Int(number))
+	defer span.End()
+
+	a, err := scanAttachment(s.readRow(queryAttachment,
+		sql.Named("id", id), sql.Named("number", number), sql.Named("tenant", s.tenant())))
+	return a, spanError(span, err)
+}
+
+func scanAttachment(row rowScanner) (Attachment, error) {
+	var a Attachment
+	err := row.Scan(&a.ID, &a.Number, &a.Kind, &a.Name, &a.ContentType, &a.Size, &a.SHA256, &a.key, &a.CreatedAt)
+	return a, err
+}
+
+// DeleteAttachment удаляет запись о документе id посылки number и возвращает её,
+// чтобы удалить содержимое; для отсутствующего документа возвращается sql.ErrNoRows
+func (s ParcelStore) DeleteAttachment(number int, id int64) (Attachment, error) {
+	start := time.Now()
+	span := s.startSpan("DeleteAttachment", attrNumber.Int(number))
+	defer span.End()
+
+	var a Attachment
+	err := s.withRetry("store.DeleteAttachment", func(s ParcelStore) error {
+		var err error
+		a, err = s.deleteAttachment(number, id)
+		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("DeleteAttachment", start)
+	logResult(s.ctx, s.logger, "store.DeleteAttachment", start, err, "number", number, "attachment", id)
+	return a, err
+}
+
+func (s ParcelStore) deleteAttachment(number int, id int64) (Attachment, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return Attachment{}, err
+	}
+	defer tx.Rollback()
+
+	a, err := scanAttachment(s.queryRow(tx, queryAttachment,
+		sql.Named("id", id), sql.Named("number", number), sql.Named("tenant", s.tenant())))
+	if err != nil {
+		return Attachment{}, err
+	}
+	_, err = s.exec(tx, queryDeleteAttachment, sql.Named("id", id))
+	if err != nil {
+		return Attachment{}, err
+	}
+	return a, tx.Commit()
+}
+
+// AddAttachment прикладывает к посылке документ вида kind с именем файла name и
+// содержимым из r. Содержимое сначала сохраняется в хранилище документов, затем
+// записываются его размер, хеш и тип; если запись не удалась, содержимое удаляется.
+// Клиент прикладывает документы только к своим посылкам.
+func (s ParcelService) AddAttachment(ctx context.Context, number int, kind, name string, r io.Reader) (Attachment, error) {
+	err := s.check(ctx, actionAttachments)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if s.blobs == nil {
+		return Attachment{}, ErrNoBlobStore
+	}
+	switch kind {
+	case AttachmentInvoice, AttachmentCustoms, AttachmentPhoto, AttachmentOther:
+	default:
+		return Attachment{}, fmt.Errorf("%w: вид %q", ErrInvalidAttachment, kind)
+	}
+	if !validAttachmentName(name) {
+		return Attachment{}, fmt.Errorf("%w: имя файла %q", ErrInvalidAttachment, name)
+	}
+	// проверяем доступ к посылке до чтения содержимого
+	_, err = s.Get(ctx, number)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, io.LimitReader(r, s.maxAttachment+1))
+	if err != nil {
+		return Attachment{}, err
+	}
+	if int64(buf.Len()) > s.maxAttachment {
+		return Attachment{}, fmt.Errorf("%w: больше %d байт", ErrAttachmentTooLarge, s.maxAttachment)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	a := Attachment{
+		Number:      number,
+		Kind:        kind,
+		Name:        name,
+		ContentType: http.DetectContentType(buf.Bytes()),
+		Size:        int64(buf.Len()),
+		SHA256:      hex.EncodeToString(sum[:]),
+		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+		key:         newBlobKey(),
+	}
+	_, err = s.blobs.Put(ctx, a.key, bytes.NewReader(buf.Bytes()), a.Size)
+	if err != nil {
+		return Attachment{}, err
+	}
+	a, err = s.store.WithContext(ctx).AddAttachment(a)
+	if err != nil {
+		s.deleteBlob(ctx, a.key)
+		return Attachment{}, err
+	}
+	return a, nil
+}
+
+// Attachments возвращает документы посылки
+func (s ParcelService) Attachments(ctx context.Context, number int) ([]Attachment, error) {
+	p, err := s.Get(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.WithContext(ctx).Attachments(p.Number)
+}
+
+// OpenAttachment возвращает документ id посылки number и его содержимое; содержимое закрывает вызывающий
+func (s ParcelService) OpenAttachment(ctx context.Context, number int, id int64) (Attachment, io.ReadCloser, error) {
+	if s.blobs == nil {
+		return Attachment{}, nil, ErrNoBlobStore
+	}
+	p, err := s.Get(ctx, number)
+	if err != nil {
+		return Attachment{}, nil, err
+	}
+	a, err := s.store.WithContext(ctx).Attachment(p.Number, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Attachment{}, nil, ErrNoAttachment
+	}
+	if err != nil {
+		return Attachment{}, nil, err
+	}
+	body, err := s.blobs.Get(ctx, a.key)
+	if err != nil {
+		return Attachment{}, nil, err
+	}
+	return a, body, nil
+}
+
+// DeleteAttachment удаляет документ id посылки number вместе с содержимым
+func (s ParcelService) DeleteAttachment(ctx context.Context, number int, id int64) error {
+	err := s.check(ctx, actionAttachments)
+	if err != nil {
+		return err
+	}
+	if s.blobs == nil {
+		return ErrNoBlobStore
+	}
+	p, err := s.Get(ctx, number)
+	if err != nil {
+		return err
+	}
+	a, err := s.store.WithContext(ctx).DeleteAttachment(p.Number, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoAttachment
+	}
+	if err != nil {
+		return err
+	}
+	s.deleteBlob(ctx, a.key)
+	return nil
+}
+
+// deleteBlob удаляет содержимое документа, запись о котором удалена или не появилась.
+// Ошибка только пишется в лог: без записи содержимое уже никто не прочитает.
+func (s ParcelService) deleteBlob(ctx context.Context, key string) {
+	err := s.blobs.Delete(ctx, key)
+	if err != nil {
+		s.logger.Log(ctx, slog.LevelWarn, "содержимое документа не удалено",
+			"op", "service.deleteBlob", "key", key, "error", err)
+	}
+}
@@ -0,0 +1,106 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAttachments проверяет загрузку, скачивание и удаление документов посылки
+func TestAttachments(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	tenant := fmt.Sprintf("attachments-%d", randRange.Intn(10_000_000))
+	ctx := WithTenant(context.Background(), tenant)
+	dir := t.TempDir()
+	service := NewParcelService(NewParcelStore(db), WithBlobStore(NewDirSink(dir), 1024))
+	owner := WithCaller(ctx, Caller{Client: 1000, Role: RoleClient, Tenant: tenant})
+	stranger := WithCaller(ctx, Caller{Client: 1001, Role: RoleClient, Tenant: tenant})
+
+	p, err := service.Register(ctx, 1000, "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+
+	// without store
+	_, err = NewParcelService(NewParcelStore(db)).AddAttachment(ctx, p.Number, AttachmentInvoice, "invoice.pdf", strings.NewReader("%PDF-1.4"))
+	assert.ErrorIs(t, err, ErrNoBlobStore)
+
+	// validate
+	_, err = service.AddAttachment(ctx, p.Number, "receipt", "invoice.pdf", strings.NewReader("%PDF-1.4"))
+	assert.ErrorIs(t, err, ErrInvalidAttachment)
+	for _, name := range []string{"", "../invoice.pdf", "a\nb.pdf", strings.Repeat("я", maxAttachmentName+1)} {
+		_, err = service.AddAttachment(ctx, p.Number, AttachmentInvoice, name, strings.NewReader("%PDF-1.4"))
+		assert.ErrorIs(t, err, ErrInvalidAttachment, name)
+	}
+	_, err = service.AddAttachment(ctx, p.Number, AttachmentPhoto, "big.jpg", bytes.NewReader(make([]byte, 1025)))
+	assert.ErrorIs(t, err, ErrAttachmentTooLarge)
+	_, err = service.AddAttachment(stranger, p.Number, AttachmentInvoice, "invoice.pdf", strings.NewReader("%PDF-1.4"))
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	// add
+	content := "%PDF-1.4 счёт"
+	invoice, err := service.AddAttachment(owner, p.Number, AttachmentInvoice, "счёт №1.pdf", strings.NewReader(content))
+	require.NoError(t, err)
+	sum := sha256.Sum256([]byte(content))
+	assert.Equal(t, "application/pdf", invoice.ContentType)
+	assert.Equal(t, int64(len(content)), invoice.Size)
+	assert.Equal(t, hex.EncodeToString(sum[:]), invoice.SHA256)
+	photo, err := service.AddAttachment(ctx, p.Number, AttachmentPhoto, "photo.txt", strings.NewReader("не фото"))
+	require.NoError(t, err)
+
+	list, err := service.Attachments(ctx, p.Number)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, invoice.ID, list[0].ID)
+	assert.Equal(t, "счёт №1.pdf", list[0].Name)
+	assert.Equal(t, photo.ID, list[1].ID)
+
+	// download
+	handler := NewHTTPHandler(service)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), tenant)))
+	}))
+	defer srv.Close()
+	resp, err := http.Get(fmt.Sprintf("%s/parcels/%d/attachments/%d", srv.URL, p.Number, invoice.ID))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "attachment; filename*=utf-8''%D1%81%D1%87%D1%91%D1%82%20%E2%84%961.pdf", resp.Header.Get("Content-Disposition"))
+	assert.Equal(t, content, string(body))
+
+	// документ другой посылки по чужому номеру не отдаётся
+	resp, err = http.Get(fmt.Sprintf("%s/parcels/%d/attachments/%d", srv.URL, p.Number+1, invoice.ID))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// delete
+	require.NoError(t, service.DeleteAttachment(ctx, p.Number, photo.ID))
+	assert.ErrorIs(t, service.DeleteAttachment(ctx, p.Number, photo.ID), ErrNoAttachment)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// содержимое документов удаляется вместе с посылкой
+	require.NoError(t, service.Delete(ctx, p.Number))
+	left, err := filepath.Glob(filepath.Join(dir, "attachment-*"))
+	require.NoError(t, err)
+	assert.Empty(t, left)
+}
@@ -0,0 +1,73 @@
+package tracker
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Действия с посылкой в журнале аудита
+const (
+	// AuditRegister посылка зарегистрирована: через API, пачкой, из манифеста или разделением
+	AuditRegister = "register"
+)
+
+// AuditEntry запись журнала аудита. Она делается в транзакции самого действия,
+// поэтому в журнале нет действий, которые откатились, и нет действий без записи.
+type AuditEntry struct {
+	ID     int64
+	Action string
+	Number int
+	Client int
+	Tenant string
+	// RequestID запрос, выполнивший действие; пусто для фоновых задач и CLI
+	RequestID string
+	CreatedAt string
+}
+
+const (
+	queryInsertAudit = "INSERT INTO audit_log (action, number, client, tenant_id, request_id, created_at) VALUES (:action, :number, :client, :tenant, NULLIF(:request_id, ''), :created_at)"
+	queryAuditLog    = "SELECT id, action, number, client, tenant_id, COALESCE(request_id, ''), created_at FROM audit_log WHERE number = :number AND " + tenantCond + " ORDER BY id"
+)
+
+// addAudit записывает действие e в журнал аудита в транзакции tx
+func (s ParcelStore) addAudit(tx *sql.Tx, e AuditEntry) error {
+	if e.Tenant == "" {
+		e.Tenant = s.tenant()
+	}
+	if e.CreatedAt == "" {
+		e.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	_, err := s.exec(tx, queryInsertAudit,
+		sql.Named("action", e.Action),
+		sql.Named("number", e.Number),
+		sql.Named("client", e.Client),
+		sql.Named("tenant", e.Tenant),
+		sql.Named("request_id", e.RequestID),
+		sql.Named("created_at", e.CreatedAt))
+	return err
+}
+
+// AuditLog возвращает записи журнала аудита посылки number в порядке действий.
+// Журнал читается из основной БД: реплика может не знать о последних действиях.
+func (s ParcelStore) AuditLog(number int) ([]AuditEntry, error) {
+	defer s.metrics.observeQuery("AuditLog", time.Now())
+	span := s.startSpan("AuditLog", attrNumber.Int(number))
+	defer span.End()
+
+	rows, err := s.query(nil, queryAuditLog, sql.Named("number", number), sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer rows.Close()
+
+	var res []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		err := rows.Scan(&e.ID, &e.Action, &e.Number, &e.Client, &e.Tenant, &e.RequestID, &e.CreatedAt)
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		res = append(res, e)
+	}
+	return res, spanError(span, rows.Err())
+}
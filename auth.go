@@ -0,0 +1,194 @@
+package tracker
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// apiKeyHeader заголовок HTTP и ключ метаданных gRPC с API-ключом клиента
+const apiKeyHeader = "X-API-Key"
+
+// apiKeyBytes количество случайных байт в API-ключе
+const apiKeyBytes = 24
+
+var (
+	// ErrUnauthenticated возвращается, если API-ключ не передан или неизвестен
+	ErrUnauthenticated = errors.New("требуется действительный API-ключ")
+	// ErrForbidden возвращается, если роль пользователя не позволяет выполнить операцию
+	// или клиент обращается к чужим посылкам
+	ErrForbidden = errors.New("недостаточно прав для операции")
+)
+
+// Caller пользователь, от имени которого выполняется запрос.
+// Для роли client идентификатор совпадает с идентификатором клиента посылок.
+type Caller struct {
+	Client int
+	Role   Role
+	// Tenant компания, которой выпущен API-ключ пользователя
+	Tenant string
+}
+
+type callerKey struct{}
+
+// WithCaller возвращает контекст с клиентом, выполняющим запрос, и его компанией,
+// если она известна: пользователь видит только данные компании своего ключа
+func WithCaller(ctx context.Context, c Caller) context.Context {
+	if c.Tenant != "" {
+		ctx = WithTenant(ctx, c.Tenant)
+	}
+	return context.WithValue(ctx, callerKey{}, c)
+}
+
+// CallerFromContext возвращает клиента, выполняющего запрос.
+// Если аутентификация отключена, клиента в контексте нет.
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	c, ok := ctx.Value(callerKey{}).(Caller)
+	return c, ok
+}
+
+// hashAPIKey возвращает хеш ключа; в БД хранятся только хеши
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueAPIKey выпускает новый API-ключ клиента компании из ctx. Ключ показывается только один раз.
+func (s ParcelService) IssueAPIKey(ctx context.Context, client int) (string, error) {
+	b := make([]byte, apiKeyBytes)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(b)
+
+	err = s.store.WithContext(ctx).AddAPIKey(hashAPIKey(key), client)
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Authenticate возвращает пользователя, которому принадлежит API-ключ, вместе с его ролью и компанией
+func (s ParcelService) Authenticate(ctx context.Context, key string) (Caller, error) {
+	if key == "" {
+		return Caller{}, ErrUnauthenticated
+	}
+	return s.authenticateHash(ctx, hashAPIKey(key))
+}
+
+// authenticateHash возвращает пользователя по хешу API-ключа; поиск ключа идёт
+// в спане и с отменой запроса ctx
+func (s ParcelService) authenticateHash(ctx context.Context, hash string) (Caller, error) {
+	client, tenant, err := s.store.WithContext(ctx).ClientByAPIKey(hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Caller{}, ErrUnauthenticated
+	}
+	if err != nil {
+		return Caller{}, err
+	}
+
+	// пользователи без назначенной роли считаются клиентами
+	role, err := s.store.WithContext(WithTenant(context.Background(), tenant)).Role(client)
+	if errors.Is(err, sql.ErrNoRows) {
+		role = RoleClient
+	} else if err != nil {
+		return Caller{}, err
+	}
+
+	return Caller{Client: client, Role: role, Tenant: tenant}, nil
+}
+
+// isPublicPath сообщает, доступен ли путь без API-ключа: публичное отслеживание /track/,
+// страница админки /admin (она не содержит данных и сама обращается к API с ключом),
+// метрики /metrics для сбора Prometheus и проверки состояния /healthz и /readyz
+func isPublicPath(path string) bool {
+	switch path {
+	case "/admin", "/metrics", "/healthz", "/readyz":
+		return true
+	}
+	return strings.HasPrefix(path, "/track/")
+}
+
+// APIKeyMiddleware пропускает к next только запросы с действительным API-ключом
+// и кладёт пользователя в контекст запроса. Без ключа доступны пути из isPublicPath.
+func APIKeyMiddleware(service ParcelService, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPublicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		caller, err := service.Authenticate(r.Context(), r.Header.Get(apiKeyHeader))
+		if errors.Is(err, ErrUnauthenticated) {
+			writeJSONError(w, http.StatusUnauthorized, err)
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		trace.SpanFromContext(r.Context()).SetAttributes(attrClient.Int(caller.Client))
+		next.ServeHTTP(w, r.WithContext(WithCaller(r.Context(), caller)))
+	})
+}
+
+// APIKeyServerOptions возвращает перехватчики gRPC, требующие API-ключ в метаданных x-api-key
+func APIKeyServerOptions(service ParcelService) []grpc.ServerOption {
+	authenticate := func(ctx context.Context) (context.Context, error) {
+		var key string
+		md, _ := metadata.FromIncomingContext(ctx)
+		if keys := md.Get(apiKeyHeader); len(keys) > 0 {
+			key = keys[0]
+		}
+
+		caller, err := service.Authenticate(ctx, key)
+		if errors.Is(err, ErrUnauthenticated) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		trace.SpanFromContext(ctx).SetAttributes(attrClient.Int(caller.Client))
+		return WithCaller(ctx, caller), nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := authenticate(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := authenticate(ss.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, contextStream{ServerStream: ss, ctx: ctx})
+		}),
+	}
+}
+
+// contextStream подменяет контекст потока gRPC, например на контекст с клиентом
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,66 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAPIKeyMiddleware проверяет аутентификацию по API-ключу и доступ только к своим посылкам
+func TestAPIKeyMiddleware(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	service := NewParcelService(NewParcelStore(db))
+	srv := httptest.NewServer(APIKeyMiddleware(service, NewHTTPHandler(service)))
+	defer srv.Close()
+
+	owner := randRange.Intn(10_000_000)
+	stranger := owner + 1
+	key, err := service.IssueAPIKey(context.Background(), owner)
+	require.NoError(t, err)
+	strangerKey, err := service.IssueAPIKey(context.Background(), stranger)
+	require.NoError(t, err)
+
+	p, err := service.Register(context.Background(), owner, "test")
+	require.NoError(t, err)
+
+	do := func(method, path, key, body string) int {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(apiKeyHeader, key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// check
+	// без ключа или с неизвестным ключом запрос отклоняется
+	clientPath := fmt.Sprintf("/clients/%d/parcels", owner)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, clientPath, "", ""))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, clientPath, "unknown", ""))
+
+	// клиент видит свои посылки, но не чужие
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, clientPath, key, ""))
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, clientPath, strangerKey, ""))
+
+	// менять адрес может только владелец посылки
+	parcelPath := fmt.Sprintf("/parcels/%d", p.Number)
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPatch, parcelPath, strangerKey, `{"address": "other"}`))
+	assert.Equal(t, http.StatusOK, do(http.MethodPatch, parcelPath, key, `{"address": "new test address"}`))
+
+	// публичное отслеживание работает без ключа
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/track/"+p.TrackingToken, "", ""))
+}
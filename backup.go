@@ -0,0 +1,278 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// backupPages сколько страниц SQLite копируется за шаг резервного копирования
+const backupPages = 1024
+
+// sqliteBackuper соединение драйвера modernc.org/sqlite с онлайн-копированием БД
+type sqliteBackuper interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// BackupDB записывает снимок БД db в файл path. SQLite копируется через онлайн-бэкап:
+// чтения и записи во время копирования не блокируются надолго, а снимок согласован.
+// Для остальных драйверов вызывается pg_dump, путь к БД из cfg.Path — строка подключения.
+func BackupDB(ctx context.Context, db *sql.DB, cfg config.DB, path string) error {
+	if cfg.Driver != "sqlite" {
+		return runDBTool(ctx, "pg_dump", "--format=custom", "--file="+path, "--dbname="+cfg.Path)
+	}
+	return withSQLiteBackup(ctx, db, func(c sqliteBackuper) (*sqlite.Backup, error) {
+		return c.NewBackup(path)
+	})
+}
+
+// RestoreDB заменяет содержимое БД db снимком из файла path, записанным BackupDB.
+// Снимок может быть старой версии схемы, поэтому после восстановления нужны миграции.
+func RestoreDB(ctx context.Context, db *sql.DB, cfg config.DB, path string) error {
+	// SQLite создал бы вместо отсутствующего снимка пустую БД и восстановил бы из неё
+	_, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if cfg.Driver != "sqlite" {
+		return runDBTool(ctx, "pg_restore", "--clean", "--if-exists", "--no-owner", "--dbname="+cfg.Path, path)
+	}
+	return withSQLiteBackup(ctx, db, func(c sqliteBackuper) (*sqlite.Backup, error) {
+		return c.NewRestore(path)
+	})
+}
+
+// withSQLiteBackup начинает копирование на соединении пула db и выполняет его до конца
+func withSQLiteBackup(ctx context.Context, db *sql.DB, start func(sqliteBackuper) (*sqlite.Backup, error)) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(dc any) error {
+		dc = unwrapConn(dc)
+		c, ok := dc.(sqliteBackuper)
+		if !ok {
+			return fmt.Errorf("соединение %T не поддерживает резервное копирование", dc)
+		}
+		b, err := start(c)
+		if err != nil {
+			return err
+		}
+		more := true
+		for more && err == nil {
+			more, err = b.Step(backupPages)
+			if err == nil {
+				err = ctx.Err()
+			}
+		}
+		return errors.Join(err, b.Finish())
+	})
+}
+
+// runDBTool запускает утилиту СУБД и возвращает ошибку с её выводом в stderr
+func runDBTool(ctx context.Context, name string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// BackupSink хранилище снимков БД: каталог на диске или бакет S3
+type BackupSink interface {
+	// Put сохраняет снимок под именем name, читая size байт из r, и возвращает, где он лежит
+	Put(ctx context.Context, name string, r io.Reader, size int64) (string, error)
+	// List возвращает имена сохранённых снимков в любом порядке
+	List(ctx context.Context) ([]string, error)
+	// Delete удаляет снимок name
+	Delete(ctx context.Context, name string) error
+}
+
+// DirSink хранит снимки в каталоге на диске
+type DirSink struct {
+	dir string
+}
+
+// NewDirSink создаёт хранилище снимков в каталоге dir; каталог создаётся при первом снимке
+func NewDirSink(dir string) *DirSink {
+	return &DirSink{dir: dir}
+}
+
+// Put записывает снимок под временным именем и переименовывает, поэтому
+// недописанный снимок не попадает в каталог под именем готового
+func (d *DirSink) Put(_ context.Context, name string, r io.Reader, _ int64) (string, error) {
+	err := os.MkdirAll(d.dir, 0o755)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(d.dir, ".backup-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return "", err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(d.dir, name)
+	return path, os.Rename(tmp.Name(), path)
+}
+
+// List возвращает имена файлов каталога, кроме скрытых временных
+func (d *DirSink) List(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	return names, nil
+}
+
+// Get открывает файл name
+func (d *DirSink) Get(_ context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(d.dir, name))
+}
+
+// Delete удаляет файл снимка
+func (d *DirSink) Delete(_ context.Context, name string) error {
+	return os.Remove(filepath.Join(d.dir, name))
+}
+
+// NewBackupSink создаёт хранилище снимков из настроек: бакет S3, если он задан,
+// иначе каталог cfg.Dir или текущий каталог
+func NewBackupSink(cfg config.Backup) (BackupSink, error) {
+	if cfg.S3.Bucket != "" {
+		return NewS3Sink(cfg.S3)
+	}
+	dir := cfg.Dir
+	if dir == "" {
+		dir = "."
+	}
+	return NewDirSink(dir), nil
+}
+
+// Backups снимки БД хранилища в sink; хранятся последние keep снимков
+type Backups struct {
+	store ParcelStore
+	cfg   config.DB
+	sink  BackupSink
+	keep  int
+	// now текущее время; подменяется в тестах
+	now func() time.Time
+}
+
+// NewBackups создаёт снимки БД с настройками cfg в sink; keep 0 — хранить все
+func NewBackups(store ParcelStore, cfg config.DB, sink BackupSink, keep int) *Backups {
+	return &Backups{store: store, cfg: cfg, sink: sink, keep: keep, now: time.Now}
+}
+
+// ext расширение файлов снимков: копия файла SQLite или архив pg_dump
+func (b *Backups) ext() string {
+	if b.cfg.Driver == "sqlite" {
+		return ".db"
+	}
+	return ".dump"
+}
+
+// Name возвращает имя снимка, сделанного в момент t
+func (b *Backups) Name(t time.Time) string {
+	return "tracker-" + t.UTC().Format("20060102T150405Z") + b.ext()
+}
+
+// Create записывает снимок БД во временный файл, сохраняет его в sink и возвращает,
+// где снимок лежит. Затем из sink удаляются снимки сверх keep, начиная с самых старых.
+func (b *Backups) Create(ctx context.Context) (string, error) {
+	tmp, err := os.CreateTemp("", ".backup-*"+b.ext())
+	if err != nil {
+		return "", err
+	}
+	// SQLite пишет снимок в пустой файл сам, а pg_dump перезаписывает его
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	err = BackupDB(ctx, b.store.db, b.cfg, tmp.Name())
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(tmp.Name())
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	location, err := b.sink.Put(ctx, b.Name(b.now()), f, info.Size())
+	if err != nil {
+		return "", err
+	}
+	return location, b.prune(ctx)
+}
+
+// prune удаляет из sink старые снимки, оставляя последние keep;
+// прочие файлы в sink не трогаются
+func (b *Backups) prune(ctx context.Context) error {
+	if b.keep == 0 {
+		return nil
+	}
+	names, err := b.sink.List(ctx)
+	if err != nil {
+		return err
+	}
+	names = slices.DeleteFunc(names, func(name string) bool {
+		return !strings.HasPrefix(name, "tracker-") || !strings.HasSuffix(name, b.ext())
+	})
+	// время в имени снимка упорядочено так же, как строки
+	slices.Sort(names)
+	var errs []error
+	for len(names) > b.keep {
+		errs = append(errs, b.sink.Delete(ctx, names[0]))
+		names = names[1:]
+	}
+	return errors.Join(errs...)
+}
+
+// Backup задача планировщика backup: записывает очередной снимок БД
+func (b *Backups) Backup(ctx context.Context) error {
+	location, err := b.Create(ctx)
+	if err != nil {
+		return err
+	}
+	b.store.logger.Log(ctx, slog.LevelInfo, "снимок БД записан", "op", "backups.Create", "location", location)
+	return nil
+}
@@ -0,0 +1,250 @@
+package tracker
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// s3UnsignedPayload значение x-amz-content-sha256 для тела, которое не входит в подпись:
+// снимок отправляется потоком, не читая его дважды ради хеша
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Sink хранит снимки в бакете S3-совместимого хранилища (AWS S3, MinIO, Yandex Object
+// Storage). Запросы подписываются AWS Signature V4, бакет адресуется в пути: endpoint/bucket/key.
+type S3Sink struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	prefix    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	// now текущее время для подписи; подменяется в тестах
+	now func() time.Time
+}
+
+// NewS3Sink создаёт хранилище снимков в бакете cfg.Bucket; снимки кладутся с префиксом cfg.Prefix
+func NewS3Sink(cfg config.S3) (*S3Sink, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("backup.s3.endpoint: %w", err)
+	}
+	return &S3Sink{
+		endpoint:  endpoint,
+		region:    cfg.Region,
+		bucket:    cfg.Bucket,
+		prefix:    cfg.Prefix,
+		accessKey: cfg.AccessKeyID,
+		secretKey: cfg.SecretAccessKey,
+		client:    &http.Client{},
+		now:       time.Now,
+	}, nil
+}
+
+// Put загружает снимок объектом prefix+name одним запросом PUT
+func (s *S3Sink) Put(ctx context.Context, name string, r io.Reader, size int64) (string, error) {
+	req, err := s.request(ctx, http.MethodPut, s.prefix+name, nil, r)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	resp, err := s.do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return "s3://" + s.bucket + "/" + s.prefix + name, nil
+}
+
+// Get открывает объект prefix+name для чтения
+func (s *S3Sink) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	req, err := s.request(ctx, http.MethodGet, s.prefix+name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// s3ListResult ответ ListObjectsV2
+type s3ListResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List возвращает имена объектов с префиксом prefix без самого префикса и без вложенных «каталогов»
+func (s *S3Sink) List(ctx context.Context) ([]string, error) {
+	var names []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.prefix}, "delimiter": {"/"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s.request(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.do(req)
+		if err != nil {
+			return nil, err
+		}
+		var res s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3: список объектов: %w", err)
+		}
+		for _, c := range res.Contents {
+			names = append(names, strings.TrimPrefix(c.Key, s.prefix))
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return names, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+// Delete удаляет объект prefix+name
+func (s *S3Sink) Delete(ctx context.Context, name string) error {
+	req, err := s.request(ctx, http.MethodDelete, s.prefix+name, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// request собирает подписанный запрос к объекту key бакета; пустой key — запрос к самому бакету
+func (s *S3Sink) request(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := *s.endpoint
+	u.Path += "/" + s.bucket
+	u.RawPath = s.endpoint.EscapedPath() + "/" + s3Escape(s.bucket, true)
+	if key != "" {
+		u.Path += "/" + key
+		u.RawPath += "/" + s3Escape(key, false)
+	}
+	u.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, u.RawPath)
+	return req, nil
+}
+
+// do выполняет запрос и возвращает ошибку с текстом ответа, если хранилище его отклонило;
+// ошибка об отсутствующем объекте или бакете оборачивает os.ErrNotExist
+func (s *S3Sink) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err = fmt.Errorf("s3: %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(msg)))
+		if resp.StatusCode == http.StatusNotFound {
+			err = fmt.Errorf("%w: %w", os.ErrNotExist, err)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// sign добавляет к запросу подпись AWS Signature V4 с заголовками host,
+// x-amz-content-sha256 и x-amz-date; path — путь запроса в кодировке S3
+func (s *S3Sink) sign(req *http.Request, path string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + s3UnsignedPayload,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{now.Format("20060102"), s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 возвращает HMAC-SHA256 данных data с ключом key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape кодирует строку для подписи S3: всё, кроме A-Z, a-z, 0-9, '-', '.', '_' и '~',
+// заменяется на %XX; '/' остаётся как есть, если не задан encodeSlash
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3CanonicalQuery кодирует параметры запроса, как того требует подпись: по порядку ключей
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
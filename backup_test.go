@@ -0,0 +1,162 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// TestBackups проверяет снимки БД, удаление старых снимков и восстановление
+func TestBackups(t *testing.T) {
+	// prepare
+	// отдельная БД, чтобы восстановление не затронуло данные других тестов
+	cfg := testDBConfig(t)
+	db := migrateTestDB(t, cfg)
+
+	store := NewParcelStore(db)
+	kept, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	backups := NewBackups(store, cfg, NewDirSink(dir), 2)
+	now := time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC)
+	backups.now = func() time.Time { return now }
+
+	// backup
+	var paths []string
+	for i := 0; i < 3; i++ {
+		require.NoError(t, backups.Backup(context.Background()))
+		paths = append(paths, filepath.Join(dir, backups.Name(now)))
+		now = now.Add(24 * time.Hour)
+	}
+
+	// check
+	// остались два последних снимка и ни одного временного файла
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{filepath.Base(paths[1]), filepath.Base(paths[2])}, names)
+	assert.Equal(t, "tracker-20240502T030000Z.db", names[0])
+
+	// restore
+	// посылка, добавленная после снимка, после восстановления пропадает
+	lost, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+	require.NoError(t, RestoreDB(context.Background(), db, cfg, paths[2]))
+
+	_, err = store.Get(kept)
+	assert.NoError(t, err)
+	_, err = store.Get(lost)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// из отсутствующего снимка БД не восстанавливается
+	err = RestoreDB(context.Background(), db, cfg, filepath.Join(dir, "missing.db"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = store.Get(kept)
+	assert.NoError(t, err)
+}
+
+// fakeS3 хранит объекты одного бакета в памяти и проверяет подпись запросов
+type fakeS3 struct {
+	t       *testing.T
+	objects map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	assert.True(f.t, strings.HasPrefix(r.Header.Get("Authorization"),
+		"AWS4-HMAC-SHA256 Credential=key/20240501/ru-central1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="))
+	assert.Equal(f.t, "20240501T030000Z", r.Header.Get("X-Amz-Date"))
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/backups/")
+	switch {
+	case r.Method == http.MethodPut && ok:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = string(body)
+	case r.Method == http.MethodGet && ok:
+		body, found := f.objects[key]
+		if !found {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, body)
+	case r.Method == http.MethodDelete && ok:
+		delete(f.objects, key)
+	case r.Method == http.MethodGet && r.URL.Path == "/backups":
+		assert.Equal(f.t, "2", r.URL.Query().Get("list-type"))
+		prefix := r.URL.Query().Get("prefix")
+		fmt.Fprint(w, "<ListBucketResult>")
+		for k := range f.objects {
+			if strings.HasPrefix(k, prefix) {
+				fmt.Fprintf(w, "<Contents><Key>%s</Key></Contents>", k)
+			}
+		}
+		fmt.Fprint(w, "<IsTruncated>false</IsTruncated></ListBucketResult>")
+	default:
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+	}
+}
+
+// TestS3Sink проверяет отправку снимков в бакет S3 и удаление старых
+func TestS3Sink(t *testing.T) {
+	// prepare
+	s3 := &fakeS3{t: t, objects: map[string]string{"other/tracker-20240101T000000Z.db": "чужой"}}
+	srv := httptest.NewServer(s3)
+	defer srv.Close()
+
+	sink, err := NewS3Sink(config.S3{Endpoint: srv.URL, Region: "ru-central1", Bucket: "backups",
+		Prefix: "tracker/", AccessKeyID: "key", SecretAccessKey: "secret"})
+	require.NoError(t, err)
+	now := time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC)
+	sink.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// put, list, delete
+	for _, name := range []string{"tracker-20240501T030000Z.db", "tracker-20240502T030000Z.db", "tracker-20240503T030000Z.db"} {
+		location, err := sink.Put(ctx, name, strings.NewReader(name), int64(len(name)))
+		require.NoError(t, err)
+		assert.Equal(t, "s3://backups/tracker/"+name, location)
+	}
+	backups := NewBackups(ParcelStore{}, testConfig.DB, sink, 2)
+	require.NoError(t, backups.prune(ctx))
+
+	// check
+	// удаляются только старые снимки с префиксом трекера
+	names, err := sink.List(ctx)
+	require.NoError(t, err)
+	slices.Sort(names)
+	assert.Equal(t, []string{"tracker-20240502T030000Z.db", "tracker-20240503T030000Z.db"}, names)
+	assert.Equal(t, "tracker-20240503T030000Z.db", s3.objects["tracker/tracker-20240503T030000Z.db"])
+	assert.Contains(t, s3.objects, "other/tracker-20240101T000000Z.db")
+
+	// get
+	body, err := sink.Get(ctx, "tracker-20240503T030000Z.db")
+	require.NoError(t, err)
+	content, err := io.ReadAll(body)
+	require.NoError(t, body.Close())
+	require.NoError(t, err)
+	assert.Equal(t, "tracker-20240503T030000Z.db", string(content))
+	_, err = sink.Get(ctx, "tracker-20240501T030000Z.db")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// ошибка хранилища возвращается с его ответом
+	sink.bucket = "missing"
+	_, err = sink.List(ctx)
+	assert.ErrorContains(t, err, "NoSuchBucket")
+}
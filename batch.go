@@ -0,0 +1,191 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBatchWriterStopped возвращается при добавлении посылки через остановленный BatchWriter
+var ErrBatchWriterStopped = errors.New("запись пачками остановлена")
+
+// AddBatch добавляет посылки в одной транзакции — все или ни одной — и возвращает
+// их номера в том же порядке. Одна транзакция вместо многих нужна ради одной
+// фиксации на диск: при synchronous=FULL именно fsync фиксации занимает
+// большую часть времени Add.
+func (s ParcelStore) AddBatch(parcels []Parcel) ([]int, error) {
+	requestIDs := make([]string, len(parcels))
+	for i := range requestIDs {
+		requestIDs[i] = RequestIDFromContext(s.ctx)
+	}
+	return s.addBatch(parcels, requestIDs)
+}
+
+// addBatch добавляет посылки в одной транзакции; requestIDs[i] — идентификатор
+// запроса, зарегистрировавшего parcels[i]
+func (s ParcelStore) addBatch(parcels []Parcel, requestIDs []string) ([]int, error) {
+	start := time.Now()
+	span := s.startSpan("AddBatch")
+	defer span.End()
+
+	var ids []int
+	err := s.withRetry("store.AddBatch", func(s ParcelStore) error {
+		tx, err := s.begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		ids = make([]int, len(parcels))
+		for i, p := range parcels {
+			ids[i], err = s.insertParcel(tx, p, requestIDs[i])
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("AddBatch", start)
+	logResult(s.ctx, s.logger, "store.AddBatch", start, err, "count", len(parcels))
+	if err != nil {
+		return nil, err
+	}
+	// посылки пачки BatchWriter несут компанию зарегистрировавшего их запроса
+	for i, id := range ids {
+		store := s
+		if tenant := parcels[i].Tenant; tenant != "" {
+			store = s.WithContext(WithTenant(s.ctx, tenant))
+		}
+		store.added(id)
+	}
+	return ids, nil
+}
+
+// BatchWriter собирает одновременные Add в пачки и записывает каждую пачку
+// одной транзакцией AddBatch. Пачка пишется, как только набралось size посылок
+// или прошло delay с первой посылки в ней, поэтому одиночная запись
+// задерживается не больше чем на delay.
+type BatchWriter struct {
+	store    ParcelStore
+	size     int
+	delay    time.Duration
+	requests chan batchRequest
+	// stopped закрывается, когда Run завершился
+	stopped chan struct{}
+}
+
+// batchRequest посылка, ожидающая записи в пачке
+type batchRequest struct {
+	ctx    context.Context
+	parcel Parcel
+	result chan batchResult
+}
+
+type batchResult struct {
+	number int
+	err    error
+}
+
+// NewBatchWriter создаёт запись пачками до size посылок с ожиданием не дольше delay.
+// Посылки записываются, пока работает Run.
+func NewBatchWriter(store ParcelStore, size int, delay time.Duration) *BatchWriter {
+	return &BatchWriter{
+		store:    store,
+		size:     max(size, 1),
+		delay:    delay,
+		requests: make(chan batchRequest),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Add добавляет посылку в ближайшую пачку и ждёт её записи. Если ctx отменён
+// после того, как посылку взяли в пачку, посылка всё равно может быть записана.
+func (w *BatchWriter) Add(ctx context.Context, p Parcel) (int, error) {
+	// пачку записывает фоновая задача, поэтому компания запроса передаётся с посылкой
+	if p.Tenant == "" {
+		p.Tenant = TenantFromContext(ctx)
+	}
+	req := batchRequest{ctx: ctx, parcel: p, result: make(chan batchResult, 1)}
+	select {
+	case w.requests <- req:
+	case <-w.stopped:
+		return 0, ErrBatchWriterStopped
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	select {
+	case r := <-req.result:
+		return r.number, r.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// Run собирает и записывает пачки, пока не отменён ctx. Уже собранная пачка
+// при отмене дозаписывается, новые посылки после этого не принимаются.
+func (w *BatchWriter) Run(ctx context.Context) {
+	defer close(w.stopped)
+
+	for {
+		var batch []batchRequest
+		select {
+		case <-ctx.Done():
+			return
+		case req := <-w.requests:
+			batch = append(batch, req)
+		}
+
+		timer := time.NewTimer(w.delay)
+	collect:
+		for len(batch) < w.size {
+			select {
+			case req := <-w.requests:
+				batch = append(batch, req)
+			case <-timer.C:
+				break collect
+			case <-ctx.Done():
+				break collect
+			}
+		}
+		timer.Stop()
+
+		w.write(batch)
+	}
+}
+
+// write записывает пачку. Если пачка не записалась, посылки записываются по одной,
+// чтобы ошибка одной посылки не отменяла регистрацию остальных.
+func (w *BatchWriter) write(batch []batchRequest) {
+	parcels := make([]Parcel, len(batch))
+	requestIDs := make([]string, len(batch))
+	for i, req := range batch {
+		parcels[i] = req.parcel
+		requestIDs[i] = RequestIDFromContext(req.ctx)
+	}
+
+	ids, err := w.store.addBatch(parcels, requestIDs)
+	if err == nil {
+		for i, req := range batch {
+			req.result <- batchResult{number: ids[i]}
+		}
+		return
+	}
+	if len(batch) == 1 {
+		batch[0].result <- batchResult{err: err}
+		return
+	}
+	for _, req := range batch {
+		number, err := w.store.WithContext(req.ctx).Add(req.parcel)
+		req.result <- batchResult{number: number, err: err}
+	}
+}
+
+// WithBatchWriter регистрирует посылки через запись пачками вместо отдельной
+// транзакции на каждую посылку
+func WithBatchWriter(w *BatchWriter) ServiceOption {
+	return func(s *ParcelService) {
+		s.writer = w
+	}
+}
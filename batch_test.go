@@ -0,0 +1,231 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAddBatch проверяет добавление пачки посылок одной транзакцией
+func TestAddBatch(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	client := randRange.Intn(10_000_000)
+	parcels := make([]Parcel, 3)
+	for i := range parcels {
+		parcels[i] = getTestParcel()
+		parcels[i].Client = client
+	}
+
+	// add
+	ids, err := store.AddBatch(parcels)
+	require.NoError(t, err)
+
+	// check
+	require.Len(t, ids, 3)
+	stored, err := store.GetByClient(client)
+	require.NoError(t, err)
+	require.Len(t, stored, 3)
+	for i, p := range stored {
+		assert.Equal(t, ids[i], p.Number)
+		history, err := store.History(p.Number)
+		require.NoError(t, err)
+		assert.Len(t, history, 1)
+	}
+
+	// пачка с ошибкой не добавляет ни одной посылки
+	token, err := newTrackingToken()
+	require.NoError(t, err)
+	parcels[0].TrackingToken, parcels[1].TrackingToken = token, token
+	_, err = store.AddBatch(parcels)
+	require.Error(t, err)
+	stored, err = store.GetByClient(client)
+	require.NoError(t, err)
+	assert.Len(t, stored, 3)
+}
+
+// TestBatchWriter проверяет, что одновременные регистрации записываются пачками,
+// а ошибка одной посылки не мешает остальным
+func TestBatchWriter(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	metrics := NewMetrics()
+	store := NewParcelStore(db, WithStoreMetrics(metrics))
+	writer := NewBatchWriter(store, 10, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		writer.Run(ctx)
+		close(done)
+	}()
+	service := NewParcelService(store, WithBatchWriter(writer))
+	client := randRange.Intn(10_000_000)
+
+	// register
+	// десять регистраций заполняют пачку, не дожидаясь задержки
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.Register(context.Background(), client, "test")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// check
+	// все десять посылок записаны одной пачкой
+	assert.Equal(t, 10.0, testutil.ToFloat64(metrics.parcelsAdded))
+	var m dto.Metric
+	require.NoError(t, metrics.queryDuration.WithLabelValues("AddBatch").(prometheus.Histogram).Write(&m))
+	assert.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+	parcels, err := store.GetByClient(client)
+	require.NoError(t, err)
+	assert.Len(t, parcels, 10)
+
+	// посылка с занятым трекинг-токеном не мешает второй посылке из той же пачки
+	token := parcels[0].TrackingToken
+	results := make(chan error, 2)
+	for _, tok := range []string{token, ""} {
+		go func() {
+			p := getTestParcel()
+			p.Client, p.TrackingToken = client, tok
+			_, err := writer.Add(context.Background(), p)
+			results <- err
+		}()
+	}
+	var errs int
+	for range 2 {
+		if <-results != nil {
+			errs++
+		}
+	}
+	assert.Equal(t, 1, errs)
+	parcels, err = store.GetByClient(client)
+	require.NoError(t, err)
+	assert.Len(t, parcels, 11)
+
+	// после остановки посылки не принимаются
+	cancel()
+	<-done
+	_, err = writer.Add(context.Background(), getTestParcel())
+	assert.ErrorIs(t, err, ErrBatchWriterStopped)
+}
+
+// benchmarkStore создаёт хранилище во временной БД с size посылками у size/10 клиентов;
+// synchronous — режим фиксации SQLite (NORMAL или FULL)
+func benchmarkStore(b *testing.B, size int, synchronous string) ParcelStore {
+	b.Helper()
+	cfg := testConfig.DB
+	cfg.Path = filepath.Join(b.TempDir(), "bench.db")
+	cfg.SQLite.Synchronous = synchronous
+	db, err := OpenDB(cfg)
+	require.NoError(b, err)
+	b.Cleanup(func() { db.Close() })
+	require.NoError(b, Migrate(db))
+
+	store := NewParcelStore(db)
+	const batch = 1000
+	for n := 0; n < size; n += batch {
+		parcels := make([]Parcel, min(batch, size-n))
+		for i := range parcels {
+			parcels[i] = getTestParcel()
+			parcels[i].Client = (n + i) % max(size/10, 1)
+			parcels[i].Address = fmt.Sprintf("г. Тверь, ул. Ленина, д. %d, кв. %d", (n+i)%200, i)
+		}
+		_, err := store.AddBatch(parcels)
+		require.NoError(b, err)
+	}
+	return store
+}
+
+// benchmarkSizes размеры таблицы parcel в бенчмарках
+var benchmarkSizes = []int{1_000, 100_000}
+
+func BenchmarkAdd(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("rows=%d", size), func(b *testing.B) {
+			store := benchmarkStore(b, size, "NORMAL")
+			b.ResetTimer()
+			for range b.N {
+				_, err := store.Add(getTestParcel())
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+// BenchmarkAddParallel сравнивает 32 одновременные регистрации по одной транзакции
+// на посылку и через BatchWriter. С synchronous=FULL каждая фиксация ждёт fsync,
+// и запись пачками выигрывает больше всего.
+func BenchmarkAddParallel(b *testing.B) {
+	for _, synchronous := range []string{"NORMAL", "FULL"} {
+		store := benchmarkStore(b, benchmarkSizes[0], synchronous)
+		b.Run("sync="+synchronous+"/single", func(b *testing.B) {
+			b.SetParallelism(32)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_, err := store.Add(getTestParcel())
+					require.NoError(b, err)
+				}
+			})
+		})
+		b.Run("sync="+synchronous+"/batched", func(b *testing.B) {
+			writer := NewBatchWriter(store, 64, time.Millisecond)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go writer.Run(ctx)
+			b.SetParallelism(32)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_, err := writer.Add(context.Background(), getTestParcel())
+					require.NoError(b, err)
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("rows=%d", size), func(b *testing.B) {
+			store := benchmarkStore(b, size, "NORMAL")
+			b.ResetTimer()
+			for i := range b.N {
+				_, err := store.Get(i%size + 1)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+func BenchmarkGetByClient(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("rows=%d", size), func(b *testing.B) {
+			store := benchmarkStore(b, size, "NORMAL")
+			b.ResetTimer()
+			for i := range b.N {
+				parcels, err := store.GetByClient(i % (size / 10))
+				require.NoError(b, err)
+				require.Len(b, parcels, 10)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Форматы счёта
+const (
+	InvoicePDF = "pdf"
+	InvoiceCSV = "csv"
+)
+
+var (
+	// ErrNothingToInvoice возвращается, если за месяц клиенту не доставлено ни одной посылки
+	ErrNothingToInvoice = errors.New("за месяц клиенту не доставлено посылок")
+	// ErrUnknownInvoiceFormat возвращается при запросе счёта в формате, которого трекер не формирует
+	ErrUnknownInvoiceFormat = errors.New("неизвестный формат счёта")
+)
+
+const (
+	queryInvoiceID = "SELECT id FROM invoice WHERE tenant_id = :tenant AND client = :client AND month = :month"
+	queryInvoice   = "SELECT id, client, month, total, created_at FROM invoice WHERE id = :id"
+	// queryBillableParcels посылки клиента, впервые доставленные за месяц, в том числе уже перенесённые в архив
+	queryBillableParcels = `SELECT number, delivered_at, priority, COALESCE(weight, 0), COALESCE(price, 0) FROM parcel
+			WHERE client = :client AND tenant_id = :tenant AND delivered_at >= :from AND delivered_at < :to
+		UNION ALL
+		SELECT number, delivered_at, priority, COALESCE(weight, 0), COALESCE(price, 0) FROM parcel_archive
+			WHERE client = :client AND tenant_id = :tenant AND delivered_at >= :from AND delivered_at < :to
+		ORDER BY number`
+	queryInsertInvoice = `INSERT INTO invoice (tenant_id, client, month, total, created_at, request_id)
+		VALUES (:tenant, :client, :month, :total, :created_at, NULLIF(:request_id, ''))`
+	queryInsertInvoiceLine = `INSERT INTO invoice_line (invoice_id, number, delivered_at, priority, weight, price)
+		VALUES (:invoice_id, :number, :delivered_at, :priority, :weight, :price)`
+	queryInvoiceLines = "SELECT number, delivered_at, priority, weight, price FROM invoice_line WHERE invoice_id = :id ORDER BY number"
+)
+
+// Invoice счёт клиенту за доставку посылок за месяц; суммы в копейках
+type Invoice struct {
+	ID     int64
+	Client int
+	// Month первое число месяца в UTC
+	Month     time.Time
+	Total     int64
+	CreatedAt string
+	Lines     []InvoiceLine
+}
+
+// InvoiceLine строка счёта: доставленная посылка и стоимость её доставки. У посылок,
+// зарегистрированных без расчёта стоимости, цена 0.
+type InvoiceLine struct {
+	Number      int
+	DeliveredAt string
+	Priority    string
+	Weight      int
+	Price       int64
+}
+
+// GenerateInvoice формирует счёт клиенту за посылки, впервые доставленные в месяц month,
+// и сохраняет его со строками. Счёт за месяц формируется один раз: повторный вызов
+// возвращает уже сохранённый счёт, даже если с тех пор доставлены новые посылки.
+func (s ParcelStore) GenerateInvoice(client int, month time.Time) (Invoice, error) {
+	start := time.Now()
+	span := s.startSpan("GenerateInvoice", attrClient.Int(client))
+	defer span.End()
+
+	var invoice Invoice
+	err := s.withRetry("store.GenerateInvoice", func(s ParcelStore) error {
+		var err error
+		invoice, err = s.generateInvoice(client, monthStart(month))
+		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("GenerateInvoice", start)
+	logResult(s.ctx, s.logger, "store.GenerateInvoice", start, err, "client", client,
+		"month", monthStart(month).Format("2006-01"), "invoice", invoice.ID, "total", invoice.Total)
+	return invoice, err
+}
+
+func (s ParcelStore) generateInvoice(client int, month time.Time) (Invoice, error) {
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return Invoice{}, err
+	}
+	tx, err := s.begin()
+	if err != nil {
+		return Invoice{}, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = s.queryRow(tx, queryInvoiceID,
+		sql.Named("tenant", tenant),
+		sql.Named("client", client),
+		sql.Named("month", month.Format("2006-01"))).Scan(&id)
+	if err == nil {
+		return s.loadInvoice(tx, id)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Invoice{}, err
+	}
+
+	rows, err := s.query(tx, queryBillableParcels,
+		sql.Named("client", client),
+		sql.Named("tenant", tenant),
+		sql.Named("from", month.Format(time.RFC3339)),
+		sql.Named("to", month.AddDate(0, 1, 0).Format(time.RFC3339)))
+	if err != nil {
+		return Invoice{}, err
+	}
+	invoice := Invoice{Client: client, Month: month, CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	for rows.Next() {
+		var l InvoiceLine
+		err = rows.Scan(&l.Number, &l.DeliveredAt, &l.Priority, &l.Weight, &l.Price)
+		if err != nil {
+			rows.Close()
+			return Invoice{}, err
+		}
+		invoice.Lines = append(invoice.Lines, l)
+		invoice.Total += l.Price
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return Invoice{}, err
+	}
+	if len(invoice.Lines) == 0 {
+		return Invoice{}, ErrNothingToInvoice
+	}
+
+	res, err := s.exec(tx, queryInsertInvoice,
+		sql.Named("tenant", tenant),
+		sql.Named("client", client),
+		sql.Named("month", month.Format("2006-01")),
+		sql.Named("total", invoice.Total),
+		sql.Named("created_at", invoice.CreatedAt),
+		sql.Named("request_id", RequestIDFromContext(s.ctx)))
+	if err != nil {
+		return Invoice{}, err
+	}
+	invoice.ID, err = res.LastInsertId()
+	if err != nil {
+		return Invoice{}, err
+	}
+	for _, l := range invoice.Lines {
+		_, err = s.exec(tx, queryInsertInvoiceLine,
+			sql.Named("invoice_id", invoice.ID),
+			sql.Named("number", l.Number),
+			sql.Named("delivered_at", l.DeliveredAt),
+			sql.Named("priority", l.Priority),
+			sql.Named("weight", l.Weight),
+			sql.Named("price", l.Price))
+		if err != nil {
+			return Invoice{}, err
+		}
+	}
+	return invoice, tx.Commit()
+}
+
+// Invoice возвращает сохранённый счёт клиенту за месяц month или sql.ErrNoRows, если его ещё не формировали
+func (s ParcelStore) Invoice(client int, month time.Time) (Invoice, error) {
+	defer s.metrics.observeQuery("Invoice", time.Now())
+	span := s.startSpan("Invoice", attrClient.Int(client))
+	defer span.End()
+
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return Invoice{}, spanError(span, err)
+	}
+	var id int64
+	err = s.queryRow(nil, queryInvoiceID,
+		sql.Named("tenant", tenant),
+		sql.Named("client", client),
+		sql.Named("month", monthStart(month).Format("2006-01"))).Scan(&id)
+	if err != nil {
+		return Invoice{}, spanError(span, err)
+	}
+	invoice, err := s.loadInvoice(nil, id)
+	return invoice, spanError(span, err)
+}
+
+// loadInvoice читает счёт id со строками; tx может быть nil
+func (s ParcelStore) loadInvoice(tx *sql.Tx, id int64) (Invoice, error) {
+	var invoice Invoice
+	var month string
+	err := s.queryRow(tx, queryInvoice, sql.Named("id", id)).Scan(&invoice.ID, &invoice.Client, &month, &invoice.Total, &invoice.CreatedAt)
+	if err != nil {
+		return Invoice{}, err
+	}
+	invoice.Month, err = time.Parse("2006-01", month)
+	if err != nil {
+		return Invoice{}, err
+	}
+
+	rows, err := s.query(tx, queryInvoiceLines, sql.Named("id", id))
+	if err != nil {
+		return Invoice{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l InvoiceLine
+		err = rows.Scan(&l.Number, &l.DeliveredAt, &l.Priority, &l.Weight, &l.Price)
+		if err != nil {
+			return Invoice{}, err
+		}
+		invoice.Lines = append(invoice.Lines, l)
+	}
+	return invoice, rows.Err()
+}
+
+// Write записывает счёт в формате format (pdf или csv)
+func (inv Invoice) Write(w io.Writer, format string) error {
+	switch format {
+	case InvoicePDF:
+		return inv.WritePDF(w)
+	case InvoiceCSV:
+		return inv.WriteCSV(w)
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownInvoiceFormat, format)
+	}
+}
+
+// WriteCSV записывает строки счёта в CSV с заголовком; суммы в рублях с копейками через точку
+func (inv Invoice) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"number", "delivered_at", "priority", "weight", "price"})
+	for _, l := range inv.Lines {
+		cw.Write([]string{strconv.Itoa(l.Number), l.DeliveredAt, l.Priority, strconv.Itoa(l.Weight), rubles(l.Price)})
+	}
+	cw.Write([]string{"total", "", "", "", rubles(inv.Total)})
+	cw.Flush()
+	return cw.Error()
+}
+
+// rubles записывает сумму в копейках числом рублей с двумя знаками после точки
+func rubles(kopecks int64) string {
+	return fmt.Sprintf("%d.%02d", kopecks/100, kopecks%100)
+}
+
+// WritePDF записывает счёт на листе A4: реквизиты, таблица посылок и итог
+func (inv Invoice) WritePDF(w io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(fmt.Sprintf("Счёт № %d", inv.ID), true)
+	pdf.AddUTF8FontFromBytes("go", "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes("go", "B", gobold.TTF)
+	pdf.SetMargins(15, 15, 15)
+	pdf.AddPage()
+
+	pdf.SetFont("go", "B", 16)
+	pdf.CellFormat(0, 10, fmt.Sprintf("Счёт № %d за %s", inv.ID, inv.Month.Format("2006-01")), "", 1, "L", false, 0, "")
+	pdf.SetFont("go", "", 11)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Клиент: %d", inv.Client), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Сформирован: "+inv.CreatedAt, "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	widths := []float64{30, 55, 35, 30, 30}
+	row := func(style string, cells ...string) {
+		pdf.SetFont("go", style, 10)
+		for i, c := range cells {
+			align := "L"
+			if i >= 3 {
+				align = "R"
+			}
+			pdf.CellFormat(widths[i], 7, c, "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	row("B", "Посылка", "Доставлена", "Срочность", "Вес, г", "Сумма, руб.")
+	for _, l := range inv.Lines {
+		row("", strconv.Itoa(l.Number), l.DeliveredAt, l.Priority, strconv.Itoa(l.Weight), rubles(l.Price))
+	}
+	pdf.Ln(2)
+	pdf.SetFont("go", "B", 12)
+	pdf.CellFormat(0, 8, "Итого: "+formatPrice(inv.Total), "", 1, "R", false, 0, "")
+	return pdf.Output(w)
+}
+
+// GenerateInvoice формирует счёт клиенту за месяц; доступно администратору
+func (s ParcelService) GenerateInvoice(ctx context.Context, client int, month time.Time) (Invoice, error) {
+	err := s.check(ctx, actionBilling)
+	if err != nil {
+		return Invoice{}, err
+	}
+	return s.store.WithContext(ctx).GenerateInvoice(client, month)
+}
+
+// Invoice возвращает сформированный счёт; клиент видит только свои счета
+func (s ParcelService) Invoice(ctx context.Context, client int, month time.Time) (Invoice, error) {
+	err := s.check(ctx, actionRead)
+	if err != nil {
+		return Invoice{}, err
+	}
+	err = authorizeOwner(ctx, client)
+	if err != nil {
+		return Invoice{}, err
+	}
+	return s.store.WithContext(ctx).Invoice(client, month)
+}
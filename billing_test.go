@@ -0,0 +1,93 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerateInvoice проверяет счёт за доставленные за месяц посылки, в том числе архивные
+func TestGenerateInvoice(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	tenant := fmt.Sprintf("billing-%d", randRange.Intn(10_000_000))
+	ctx := WithTenant(context.Background(), tenant)
+	store := NewParcelStore(db)
+	service := NewParcelService(store)
+	require.NoError(t, service.SetTariff(ctx, Tariff{Zone: anyZone, Priority: PriorityStandard, Base: 30000, PerKg: 5000}))
+
+	const client = 1000
+	month := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	register := func(deliveredAt time.Time, quoted bool) int {
+		var p Parcel
+		var err error
+		if quoted {
+			p, err = service.RegisterQuoted(ctx, client, "Псков, ул. Колотушкина, д. 5", QuoteRequest{WeightGrams: 1500, LengthCm: 10, WidthCm: 10, HeightCm: 10})
+		} else {
+			p, err = service.Register(ctx, client, "Псков, ул. Колотушкина, д. 5")
+		}
+		require.NoError(t, err)
+		require.NoError(t, service.SetStatus(ctx, p.Number, ParcelStatusSent))
+		require.NoError(t, service.SetStatus(ctx, p.Number, ParcelStatusDelivered))
+		_, err = db.Exec("UPDATE parcel SET delivered_at = ? WHERE number = ?", deliveredAt.Format(time.RFC3339), p.Number)
+		require.NoError(t, err)
+		return p.Number
+	}
+	archived := register(month.Add(24*time.Hour), true)
+	// архивная посылка тоже попадает в счёт
+	n, err := store.WithContext(ctx).ArchiveDelivered(time.Now().Add(time.Hour), 100, nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	priced := register(month.Add(10*24*time.Hour), true)
+	free := register(month.Add(20*24*time.Hour), false)
+	register(month.AddDate(0, 1, 0), true)
+
+	// счета формирует администратор
+	_, err = service.GenerateInvoice(WithCaller(ctx, Caller{Client: client, Role: RoleClient}), client, month)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	// generate
+	invoice, err := service.GenerateInvoice(ctx, client, month.Add(15*24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, month, invoice.Month)
+	assert.Equal(t, int64(2*40000), invoice.Total)
+	require.Len(t, invoice.Lines, 3)
+	assert.Equal(t, []int{archived, priced, free}, []int{invoice.Lines[0].Number, invoice.Lines[1].Number, invoice.Lines[2].Number})
+	assert.Equal(t, InvoiceLine{Number: priced, DeliveredAt: month.Add(10 * 24 * time.Hour).Format(time.RFC3339),
+		Priority: PriorityStandard, Weight: 1500, Price: 40000}, invoice.Lines[1])
+	assert.Zero(t, invoice.Lines[2].Price)
+
+	// счёт за месяц формируется один раз
+	again, err := service.GenerateInvoice(ctx, client, month)
+	require.NoError(t, err)
+	assert.Equal(t, invoice, again)
+	stored, err := service.Invoice(WithCaller(ctx, Caller{Client: client, Role: RoleClient}), client, month)
+	require.NoError(t, err)
+	assert.Equal(t, invoice, stored)
+	_, err = service.Invoice(ctx, client, month.AddDate(0, 1, 0))
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = service.GenerateInvoice(ctx, client, month.AddDate(0, -1, 0))
+	assert.ErrorIs(t, err, ErrNothingToInvoice)
+
+	// render
+	var buf bytes.Buffer
+	require.NoError(t, invoice.Write(&buf, InvoiceCSV))
+	assert.Equal(t, fmt.Sprintf("number,delivered_at,priority,weight,price\n"+
+		"%d,2024-03-02T00:00:00Z,standard,1500,400.00\n"+
+		"%d,2024-03-11T00:00:00Z,standard,1500,400.00\n"+
+		"%d,2024-03-21T00:00:00Z,standard,0,0.00\n"+
+		"total,,,,800.00\n", archived, priced, free), buf.String())
+	buf.Reset()
+	require.NoError(t, invoice.Write(&buf, InvoicePDF))
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+	assert.ErrorIs(t, invoice.Write(&buf, "xml"), ErrUnknownInvoiceFormat)
+}
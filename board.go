@@ -0,0 +1,121 @@
+package tracker
+
+import (
+	"database/sql"
+	"slices"
+	"time"
+)
+
+// defaultBoardLimit сколько посылок в колонке доски, если BoardOptions.Limit не задан
+const defaultBoardLimit = 50
+
+// boardWindow колонки подзапроса доски: все колонки parcel, число посылок в статусе
+// и место посылки в колонке статуса
+const boardWindow = "*, COUNT(*) OVER (PARTITION BY status) AS board_count, ROW_NUMBER() OVER (PARTITION BY status ORDER BY number) AS board_rank"
+
+// BoardOptions фильтры доски диспетчера; нулевые значения не ограничивают выборку
+type BoardOptions struct {
+	Client int
+	// Zone зона доставки посылок
+	Zone string
+	// Limit сколько посылок показывать в колонке; по умолчанию defaultBoardLimit
+	Limit int
+}
+
+// BoardColumn колонка доски: посылки в одном статусе
+type BoardColumn struct {
+	Status string
+	// Count сколько всего посылок в статусе, а не только в Parcels
+	Count int
+	// Parcels первые Limit посылок статуса в порядке номеров: дольше всех ждущие сверху
+	Parcels []Parcel
+}
+
+// Board доска диспетчера: колонки по статусам
+type Board struct {
+	// Columns колонки в порядке жизненного цикла посылки: registered, sent, customs_hold,
+	// delivered. Колонки статусов без посылок тоже есть, с нулевым Count.
+	Columns []BoardColumn
+}
+
+// boardRow читает строку доски: колонки parcelColumns и за ними board_count
+type boardRow struct {
+	rows  timedRows
+	count *int
+}
+
+func (r boardRow) Scan(dest ...any) error {
+	return r.rows.Scan(append(dest, r.count)...)
+}
+
+// query возвращает запрос доски компании tenant и его аргументы
+func (opts BoardOptions) query(tenant string) (string, []any) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultBoardLimit
+	}
+	inner, args := ListOptions{Client: opts.Client, Zone: opts.Zone}.query(boardWindow, tenant).build()
+	query := "SELECT " + parcelColumns + ", board_count FROM (" + inner + ") WHERE board_rank <= :limit ORDER BY status, number"
+	return query, append(args, sql.Named("limit", limit))
+}
+
+// GetBoard возвращает посылки, сгруппированные по статусам, для доски диспетчера.
+// Доска читается одним запросом: оконные функции считают посылки каждого статуса
+// и отсекают лишние, поэтому в Go попадает не больше Limit строк на колонку.
+// Архивные посылки на доску не попадают. Доску одного депо возвращает ShardedStore.Board.
+func (s ParcelStore) GetBoard(opts BoardOptions) (Board, error) {
+	defer s.metrics.observeQuery("GetBoard", time.Now())
+	span := s.startSpan("GetBoard", attrClient.Int(opts.Client))
+	defer span.End()
+
+	query, args := opts.query(s.tenant())
+	rows, err := s.readUnprepared(query, args...)
+	if err != nil {
+		return Board{}, spanError(span, err)
+	}
+	defer rows.Close()
+
+	var board Board
+	for _, status := range parcelStatuses {
+		board.Columns = append(board.Columns, BoardColumn{Status: status})
+	}
+	columns := remapColumns(board.Columns)
+	for rows.Next() {
+		var count int
+		p, err := scanParcel(boardRow{rows: rows, count: &count})
+		if err == nil {
+			p, err = s.openParcel(p)
+		}
+		if err != nil {
+			return Board{}, spanError(span, err)
+		}
+		col, ok := columns[p.Status]
+		if !ok {
+			// статус, которого нет в parcelStatuses, идёт в конец доски
+			board.Columns = append(board.Columns, BoardColumn{Status: p.Status})
+			columns = remapColumns(board.Columns)
+			col = columns[p.Status]
+		}
+		col.Count = count
+		col.Parcels = append(col.Parcels, p)
+	}
+	return board, spanError(span, rows.Err())
+}
+
+// remapColumns возвращает колонки доски по статусу; указатели ведут в columns
+func remapColumns(columns []BoardColumn) map[string]*BoardColumn {
+	m := make(map[string]*BoardColumn, len(columns))
+	for i := range columns {
+		m[columns[i].Status] = &columns[i]
+	}
+	return m
+}
+
+// Column возвращает колонку доски со статусом status
+func (b Board) Column(status string) (BoardColumn, bool) {
+	i := slices.IndexFunc(b.Columns, func(c BoardColumn) bool { return c.Status == status })
+	if i < 0 {
+		return BoardColumn{}, false
+	}
+	return b.Columns[i], true
+}
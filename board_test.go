@@ -0,0 +1,59 @@
+package tracker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetBoard проверяет порядок колонок доски, полные счётчики при урезанных
+// списках и фильтр по клиенту
+func TestGetBoard(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	store := newTestStore(t)
+	var registered []int
+	for range 3 {
+		registered = append(registered, mustAddParcel(t, store, getTestParcel()).Number)
+	}
+	sent := mustAddParcel(t, store, getTestParcel()).Number
+	require.NoError(t, store.SetStatus(sent, ParcelStatusSent))
+	other := mustAddParcel(t, store, newParcelFixture().client(7).build()).Number
+
+	// board
+	board, err := store.GetBoard(BoardOptions{Limit: 2})
+	require.NoError(t, err)
+	var statuses []string
+	for _, c := range board.Columns {
+		statuses = append(statuses, c.Status)
+	}
+	assert.Equal(t, parcelStatuses, statuses)
+
+	col, ok := board.Column(ParcelStatusRegistered)
+	require.True(t, ok)
+	assert.Equal(t, 4, col.Count)
+	require.Len(t, col.Parcels, 2)
+	assert.Equal(t, registered[:2], []int{col.Parcels[0].Number, col.Parcels[1].Number})
+	assert.Equal(t, getTestParcel().Address, col.Parcels[0].Address)
+
+	col, _ = board.Column(ParcelStatusSent)
+	assert.Equal(t, 1, col.Count)
+	require.Len(t, col.Parcels, 1)
+	assert.Equal(t, sent, col.Parcels[0].Number)
+
+	col, _ = board.Column(ParcelStatusDelivered)
+	assert.Zero(t, col.Count)
+	assert.Empty(t, col.Parcels)
+
+	// client
+	board, err = store.GetBoard(BoardOptions{Client: 7})
+	require.NoError(t, err)
+	col, _ = board.Column(ParcelStatusRegistered)
+	assert.Equal(t, 1, col.Count)
+	require.Len(t, col.Parcels, 1)
+	assert.Equal(t, other, col.Parcels[0].Number)
+	col, _ = board.Column(ParcelStatusSent)
+	assert.Zero(t, col.Count)
+}
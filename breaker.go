@@ -0,0 +1,263 @@
+package tracker
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ErrStoreUnavailable возвращается без обращения к БД, пока размыкатель разомкнут:
+// последние запросы подряд не получили ответа от БД
+var ErrStoreUnavailable = errors.New("БД временно недоступна")
+
+// circuitBreaker размыкатель цепи к БД. После failures сбоев подряд цепь размыкается,
+// и запросы сразу получают ErrStoreUnavailable, а не занимают горутины в ожидании
+// недоступной БД. Через cooldown к БД пропускается один пробный запрос: успех замыкает
+// цепь, сбой размыкает её ещё на cooldown.
+type circuitBreaker struct {
+	failures int
+	cooldown time.Duration
+	// now текущее время; подменяется в тестах
+	now func() time.Time
+
+	mu sync.Mutex
+	// streak сколько сбоев было подряд
+	streak int
+	// openUntil до какого момента цепь разомкнута; нулевое время — цепь замкнута
+	openUntil time.Time
+	// probing пробный запрос уже выполняется, остальные ждут его результата
+	probing bool
+}
+
+func newCircuitBreaker(failures int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{failures: failures, cooldown: cooldown, now: time.Now}
+}
+
+// allow возвращает ErrStoreUnavailable, если цепь разомкнута. Когда cooldown истёк,
+// пропускает один пробный запрос.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if b.probing || b.now().Before(b.openUntil) {
+		return ErrStoreUnavailable
+	}
+	b.probing = true
+	return nil
+}
+
+// record учитывает результат запроса к БД, выполненного в контексте ctx. Отменённый
+// вызывающим запрос ничего не говорит о БД и не учитывается.
+func (b *circuitBreaker) record(ctx context.Context, err error) {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		b.mu.Lock()
+		b.probing = false
+		b.mu.Unlock()
+		return
+	}
+	outage := isOutage(err) || (err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !outage {
+		b.streak, b.openUntil, b.probing = 0, time.Time{}, false
+		return
+	}
+	b.streak++
+	if b.probing || b.streak >= b.failures {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+	b.probing = false
+}
+
+// isOutage сообщает, что запрос не выполнен из-за состояния БД, а не самого запроса:
+// БД заблокирована, файл недоступен или повреждён, диск заполнен, соединение потеряно.
+// Нарушение ограничения или ошибка в тексте запроса — это ответ БД, а не сбой.
+func isOutage(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var e *sqlite.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED, sqlite3.SQLITE_NOMEM, sqlite3.SQLITE_READONLY,
+		sqlite3.SQLITE_IOERR, sqlite3.SQLITE_CORRUPT, sqlite3.SQLITE_FULL, sqlite3.SQLITE_CANTOPEN,
+		sqlite3.SQLITE_PROTOCOL, sqlite3.SQLITE_NOTADB:
+		return true
+	default:
+		return false
+	}
+}
+
+// contextConn соединение драйвера, которое принимает контекст во всех операциях;
+// так умеют modernc.org/sqlite и драйверы Postgres
+type contextConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+}
+
+// contextStmt подготовленный запрос драйвера, который принимает контекст
+type contextStmt interface {
+	driver.Stmt
+	driver.StmtExecContext
+	driver.StmtQueryContext
+}
+
+// breakerConnector открывает соединения через connector, и их запросы проходят
+// через размыкатель. Размыкатель стоит под пулом database/sql, поэтому видит каждый
+// запрос, в том числе из транзакций и QueryRow, и учитывает ошибки открытия соединений.
+type breakerConnector struct {
+	connector driver.Connector
+	breaker   *circuitBreaker
+}
+
+func (c breakerConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	conn, err := c.connector.Connect(ctx)
+	c.breaker.record(ctx, err)
+	if err != nil {
+		return nil, err
+	}
+	cc, ok := conn.(contextConn)
+	if !ok {
+		// соединение без контекста размыкатель пропускает как есть
+		return conn, nil
+	}
+	return breakerConn{contextConn: cc, breaker: c.breaker}, nil
+}
+
+func (c breakerConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// breakerConn соединение, операции которого проверяет и учитывает размыкатель
+type breakerConn struct {
+	contextConn
+	breaker *circuitBreaker
+}
+
+func (c breakerConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	tx, err := c.contextConn.BeginTx(ctx, opts)
+	c.breaker.record(ctx, err)
+	if err != nil {
+		return nil, err
+	}
+	return breakerTx{Tx: tx, breaker: c.breaker}, nil
+}
+
+func (c breakerConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	st, err := c.contextConn.PrepareContext(ctx, query)
+	c.breaker.record(ctx, err)
+	if err != nil {
+		return nil, err
+	}
+	cs, ok := st.(contextStmt)
+	if !ok {
+		return st, nil
+	}
+	return breakerStmt{contextStmt: cs, breaker: c.breaker}, nil
+}
+
+func (c breakerConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	res, err := c.contextConn.ExecContext(ctx, query, args)
+	c.breaker.record(ctx, err)
+	return res, err
+}
+
+func (c breakerConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	rows, err := c.contextConn.QueryContext(ctx, query, args)
+	c.breaker.record(ctx, err)
+	return rows, err
+}
+
+// ResetSession и IsValid передают пулу решение соединения под размыкателем,
+// например устаревшего после переподключения монитора
+func (c breakerConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.contextConn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c breakerConn) IsValid() bool {
+	if v, ok := c.contextConn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c breakerConn) Ping(ctx context.Context) error {
+	if err := c.breaker.allow(); err != nil {
+		return err
+	}
+	err := c.contextConn.Ping(ctx)
+	c.breaker.record(ctx, err)
+	return err
+}
+
+// breakerStmt подготовленный запрос, выполнение которого проверяет и учитывает размыкатель
+type breakerStmt struct {
+	contextStmt
+	breaker *circuitBreaker
+}
+
+func (s breakerStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	res, err := s.contextStmt.ExecContext(ctx, args)
+	s.breaker.record(ctx, err)
+	return res, err
+}
+
+func (s breakerStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if err := s.breaker.allow(); err != nil {
+		return nil, err
+	}
+	rows, err := s.contextStmt.QueryContext(ctx, args)
+	s.breaker.record(ctx, err)
+	return rows, err
+}
+
+// breakerTx транзакция, сбой фиксации которой учитывает размыкатель
+type breakerTx struct {
+	driver.Tx
+	breaker *circuitBreaker
+}
+
+func (t breakerTx) Commit() error {
+	err := t.Tx.Commit()
+	t.breaker.record(context.Background(), err)
+	return err
+}
@@ -0,0 +1,89 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"modernc.org/sqlite"
+)
+
+// TestCircuitBreaker проверяет размыкание после сбоев подряд и пробный запрос после паузы
+func TestCircuitBreaker(t *testing.T) {
+	// prepare
+	ctx := context.Background()
+	now := time.Now()
+	b := newCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	// ответ БД с ошибкой в запросе сбоем не считается и сбрасывает счётчик
+	b.record(ctx, driver.ErrBadConn)
+	b.record(ctx, errors.New("no such table"))
+	b.record(ctx, driver.ErrBadConn)
+	require.NoError(t, b.allow())
+	// отменённый вызывающим запрос не учитывается
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	b.record(canceled, driver.ErrBadConn)
+	require.NoError(t, b.allow())
+
+	// open
+	b.record(ctx, driver.ErrBadConn)
+	assert.ErrorIs(t, b.allow(), ErrStoreUnavailable)
+
+	// probe
+	// после паузы проходит один пробный запрос; его сбой снова размыкает цепь
+	now = now.Add(time.Minute)
+	require.NoError(t, b.allow())
+	assert.ErrorIs(t, b.allow(), ErrStoreUnavailable)
+	b.record(ctx, driver.ErrBadConn)
+	assert.ErrorIs(t, b.allow(), ErrStoreUnavailable)
+
+	// успешный пробный запрос замыкает цепь
+	now = now.Add(time.Minute)
+	require.NoError(t, b.allow())
+	b.record(ctx, nil)
+	assert.NoError(t, b.allow())
+	assert.NoError(t, b.allow())
+}
+
+// TestStoreBreaker проверяет, что хранилище над недоступной БД сразу получает
+// ErrStoreUnavailable, а после восстановления БД снова работает
+func TestStoreBreaker(t *testing.T) {
+	// prepare
+	// каталога БД ещё нет, поэтому соединения не открываются
+	dir := filepath.Join(t.TempDir(), "data")
+	dsn := sqliteDSN(filepath.Join(dir, "tracker.db"), testConfig.DB.SQLite)
+	now := time.Now()
+	b := newCircuitBreaker(3, time.Minute)
+	b.now = func() time.Time { return now }
+	db := sql.OpenDB(breakerConnector{connector: newFailoverConnector(&sqlite.Driver{}, []string{dsn}), breaker: b})
+	t.Cleanup(func() { db.Close() })
+
+	// open
+	for range 3 {
+		assert.Error(t, db.Ping())
+	}
+	store := NewParcelStore(db)
+	_, err := store.Get(1)
+	assert.ErrorIs(t, err, ErrStoreUnavailable)
+	_, err = store.Add(getTestParcel())
+	assert.ErrorIs(t, err, ErrStoreUnavailable)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErrorCode(err))
+
+	// recover
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	now = now.Add(time.Minute)
+	require.NoError(t, Migrate(db))
+	id := mustAddParcel(t, store, getTestParcel()).Number
+	_, err = store.Get(id)
+	assert.NoError(t, err)
+}
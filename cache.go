@@ -0,0 +1,111 @@
+package tracker
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// ParcelCache кеш посылок по номеру для ParcelStore.Get.
+// Ошибки кеша не должны ломать чтение, поэтому реализация сама решает, что с ними делать,
+// и при сбое сообщает о промахе.
+type ParcelCache interface {
+	Get(ctx context.Context, number int) (Parcel, bool)
+	Set(ctx context.Context, p Parcel)
+	Invalidate(ctx context.Context, number int)
+}
+
+// WithCache кеширует результаты Get в c. SetStatus, SetAddress и Delete сбрасывают
+// запись посылки после записи в БД. Чтение, начатое до изменения, может успеть
+// положить в кеш старую версию, поэтому записи кеша должны иметь ограниченный срок жизни.
+func WithCache(c ParcelCache) StoreOption {
+	return func(s *ParcelStore) {
+		s.cache = c
+	}
+}
+
+// LRUCache кеш посылок в памяти процесса: хранит не больше size последних посылок,
+// каждую не дольше ttl
+type LRUCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu    sync.Mutex
+	order *list.List
+	items map[int]*list.Element
+}
+
+type lruEntry struct {
+	parcel  Parcel
+	expires time.Time
+}
+
+// NewLRUCache создаёт кеш на size посылок; ttl = 0 — без ограничения срока жизни
+func NewLRUCache(size int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		size:  size,
+		ttl:   ttl,
+		now:   time.Now,
+		order: list.New(),
+		items: map[int]*list.Element{},
+	}
+}
+
+// Get возвращает посылку из кеша, если она там есть и её срок не истёк
+func (c *LRUCache) Get(_ context.Context, number int) (Parcel, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[number]
+	if !ok {
+		return Parcel{}, false
+	}
+	e := el.Value.(lruEntry)
+	if c.ttl > 0 && c.now().After(e.expires) {
+		c.remove(el)
+		return Parcel{}, false
+	}
+	c.order.MoveToFront(el)
+	return e.parcel, true
+}
+
+// Set кладёт посылку в кеш, вытесняя самую давно запрошенную при переполнении
+func (c *LRUCache) Set(_ context.Context, p Parcel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := lruEntry{parcel: p, expires: c.now().Add(c.ttl)}
+	if el, ok := c.items[p.Number]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[p.Number] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// Invalidate удаляет посылку из кеша
+func (c *LRUCache) Invalidate(_ context.Context, number int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[number]; ok {
+		c.remove(el)
+	}
+}
+
+// Len возвращает количество посылок в кеше
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRUCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(lruEntry).parcel.Number)
+}
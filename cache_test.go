@@ -0,0 +1,76 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLRUCache проверяет вытеснение давно запрошенных посылок и срок жизни записей
+func TestLRUCache(t *testing.T) {
+	// prepare
+	ctx := context.Background()
+	now := time.Now()
+	cache := NewLRUCache(2, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.Set(ctx, Parcel{Number: 1})
+	cache.Set(ctx, Parcel{Number: 2})
+	// обращение к 1 делает самой давней посылку 2
+	_, ok := cache.Get(ctx, 1)
+	require.True(t, ok)
+	cache.Set(ctx, Parcel{Number: 3})
+
+	// check
+	_, ok = cache.Get(ctx, 2)
+	assert.False(t, ok)
+	assert.Equal(t, 2, cache.Len())
+
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.Get(ctx, 1)
+	assert.False(t, ok)
+}
+
+// TestStoreCache проверяет, что Get берёт посылку из кеша, а изменения сбрасывают её
+func TestStoreCache(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	metrics := NewMetrics()
+	cache := NewLRUCache(10, time.Minute)
+	store := NewParcelStore(db, WithCache(cache), WithStoreMetrics(metrics))
+
+	id, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+
+	// get
+	_, err = store.Get(id)
+	require.NoError(t, err)
+	stored, err := store.Get(id)
+	require.NoError(t, err)
+
+	// check
+	assert.Equal(t, ParcelStatusRegistered, stored.Status)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.cacheLookups.WithLabelValues("hit")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.cacheLookups.WithLabelValues("miss")))
+
+	// set status
+	require.NoError(t, store.SetStatus(id, ParcelStatusSent))
+	stored, err = store.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusSent, stored.Status)
+
+	// delete
+	require.NoError(t, store.SetStatus(id, ParcelStatusRegistered))
+	require.NoError(t, store.Delete(id))
+	_, err = store.Get(id)
+	assert.Error(t, err)
+	_, ok := cache.Get(context.Background(), id)
+	assert.False(t, ok)
+}
@@ -0,0 +1,422 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+var (
+	// ErrUnknownCarrier возвращается при передаче посылки перевозчику, которого нет в настройках
+	ErrUnknownCarrier = errors.New("неизвестный перевозчик")
+	// ErrAlreadyHandedOff возвращается при повторной передаче посылки перевозчику
+	ErrAlreadyHandedOff = errors.New("посылка уже передана перевозчику")
+	// ErrNoShipment возвращается сервисом для посылки, которая не передавалась перевозчику
+	ErrNoShipment = errors.New("посылка не передавалась перевозчику")
+)
+
+const (
+	queryInsertShipment = `INSERT INTO shipment (number, carrier, external_id, created_at, synced_at)
+		VALUES (:number, :carrier, :external_id, :created_at, :created_at) ON CONFLICT (number) DO NOTHING`
+	queryShipment       = "SELECT number, carrier, external_id, carrier_status, created_at, synced_at FROM shipment WHERE number = :number"
+	queryDeleteShipment = "DELETE FROM shipment WHERE number = :number"
+	// queryStaleShipments отправления недоставленных посылок, дольше всех не сверявшиеся с перевозчиком
+	queryStaleShipments = `SELECT s.number, s.carrier, s.external_id, s.carrier_status, s.created_at, s.synced_at
+		FROM shipment s JOIN parcel p ON p.number = s.number
+		WHERE p.status != 'delivered' AND ` + tenantCond + ` ORDER BY s.synced_at LIMIT :limit`
+	querySyncShipment = "UPDATE shipment SET carrier_status = :carrier_status, synced_at = :synced_at WHERE number = :number"
+)
+
+// Carrier сторонний перевозчик, которому передаётся доставка посылки.
+// Перевозчик идентифицирует отправление своим номером externalID.
+type Carrier interface {
+	// CreateShipment оформляет отправление посылки и возвращает его номер у перевозчика
+	CreateShipment(ctx context.Context, p Parcel) (string, error)
+	// FetchStatus возвращает текущий статус отправления в терминах перевозчика
+	FetchStatus(ctx context.Context, externalID string) (string, error)
+	CancelShipment(ctx context.Context, externalID string) error
+}
+
+// Shipment посылка, переданная перевозчику
+type Shipment struct {
+	Number     int    `json:"number"`
+	Carrier    string `json:"carrier"`
+	ExternalID string `json:"external_id"`
+	// CarrierStatus последний статус отправления у перевозчика; пустой до первой синхронизации
+	CarrierStatus string `json:"carrier_status"`
+	CreatedAt     string `json:"created_at"`
+	SyncedAt      string `json:"synced_at"`
+}
+
+// AddShipment запоминает отправление посылки у перевозчика.
+// Если посылка уже передана, возвращает ErrAlreadyHandedOff.
+func (s ParcelStore) AddShipment(sh Shipment) error {
+	return s.withRetry("store.AddShipment", func(s ParcelStore) error {
+		res, err := s.exec(nil, queryInsertShipment,
+			sql.Named("number", sh.Number),
+			sql.Named("carrier", sh.Carrier),
+			sql.Named("external_id", sh.ExternalID),
+			sql.Named("created_at", sh.CreatedAt))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrAlreadyHandedOff
+		}
+		return nil
+	})
+}
+
+// Shipment возвращает отправление посылки; если посылка не передавалась перевозчику, — sql.ErrNoRows
+func (s ParcelStore) Shipment(number int) (Shipment, error) {
+	var sh Shipment
+	err := s.queryRow(nil, queryShipment, sql.Named("number", number)).
+		Scan(&sh.Number, &sh.Carrier, &sh.ExternalID, &sh.CarrierStatus, &sh.CreatedAt, &sh.SyncedAt)
+	return sh, err
+}
+
+// DeleteShipment забывает отправление посылки
+func (s ParcelStore) DeleteShipment(number int) error {
+	return s.withRetry("store.DeleteShipment", func(s ParcelStore) error {
+		_, err := s.exec(nil, queryDeleteShipment, sql.Named("number", number))
+		return err
+	})
+}
+
+// staleShipments возвращает до limit отправлений недоставленных посылок, начиная с давно не сверявшихся
+func (s ParcelStore) staleShipments(limit int) ([]Shipment, error) {
+	st, err := s.stmt(nil, queryStaleShipments)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := st.Query(sql.Named("limit", limit), sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []Shipment
+	for rows.Next() {
+		var sh Shipment
+		err := rows.Scan(&sh.Number, &sh.Carrier, &sh.ExternalID, &sh.CarrierStatus, &sh.CreatedAt, &sh.SyncedAt)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, sh)
+	}
+	return res, rows.Err()
+}
+
+// syncShipment сохраняет статус отправления у перевозчика и время сверки
+func (s ParcelStore) syncShipment(number int, carrierStatus string) error {
+	return s.withRetry("store.SyncShipment", func(s ParcelStore) error {
+		_, err := s.exec(nil, querySyncShipment,
+			sql.Named("number", number),
+			sql.Named("carrier_status", carrierStatus),
+			sql.Named("synced_at", time.Now().UTC().Format(time.RFC3339)))
+		return err
+	})
+}
+
+// CarrierLink перевозчик вместе с переводом его статусов в статусы трекера
+type CarrierLink struct {
+	Carrier  Carrier
+	Statuses map[string]string
+}
+
+// WithCarriers задаёт перевозчиков, которым сервис передаёт посылки, по именам
+func WithCarriers(carriers map[string]CarrierLink) ServiceOption {
+	return func(s *ParcelService) {
+		s.carriers = carriers
+	}
+}
+
+// newCarriers создаёт перевозчиков из настроек
+func newCarriers(cfg config.Carriers) map[string]CarrierLink {
+	carriers := map[string]CarrierLink{}
+	for _, c := range cfg.List {
+		carriers[c.Name] = CarrierLink{Carrier: NewHTTPCarrier(c.URL, c.Token, cfg.Timeout), Statuses: c.Statuses}
+	}
+	return carriers
+}
+
+// HandOff передаёт посылку перевозчику carrier: оформляет у него отправление,
+// после чего статус посылки обновляется по статусам перевозчика (CarrierSync)
+func (s ParcelService) HandOff(ctx context.Context, number int, carrier string) (Shipment, error) {
+	err := s.check(ctx, actionHandOff)
+	if err != nil {
+		return Shipment{}, err
+	}
+	link, ok := s.carriers[carrier]
+	if !ok {
+		return Shipment{}, ErrUnknownCarrier
+	}
+
+	store := s.store.WithContext(ctx)
+	p, err := store.Get(number)
+	if err != nil {
+		return Shipment{}, err
+	}
+	_, err = store.Shipment(number)
+	if err == nil {
+		return Shipment{}, ErrAlreadyHandedOff
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Shipment{}, err
+	}
+
+	externalID, err := link.Carrier.CreateShipment(ctx, p)
+	if err != nil {
+		return Shipment{}, fmt.Errorf("перевозчик %s: %w", carrier, err)
+	}
+	sh := Shipment{Number: number, Carrier: carrier, ExternalID: externalID, CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	sh.SyncedAt = sh.CreatedAt
+	err = store.AddShipment(sh)
+	if err != nil {
+		// посылку одновременно передали ещё раз или отправление не сохранилось:
+		// лишнее отправление у перевозчика отменяется
+		cancelErr := link.Carrier.CancelShipment(ctx, externalID)
+		if cancelErr != nil {
+			s.logger.Log(ctx, slog.LevelError, "не удалось отменить лишнее отправление",
+				withRequestID(ctx, []any{"number", number, "carrier", carrier, "external_id", externalID, "error", cancelErr})...)
+		}
+		return Shipment{}, err
+	}
+	return sh, nil
+}
+
+// Shipment возвращает отправление посылки у перевозчика
+func (s ParcelService) Shipment(ctx context.Context, number int) (Shipment, error) {
+	// проверяем доступ к самой посылке
+	_, err := s.Get(ctx, number)
+	if err != nil {
+		return Shipment{}, err
+	}
+	sh, err := s.store.WithContext(ctx).Shipment(number)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Shipment{}, ErrNoShipment
+	}
+	return sh, err
+}
+
+// CancelHandOff отменяет отправление у перевозчика и забывает его; статус посылки не меняется
+func (s ParcelService) CancelHandOff(ctx context.Context, number int) error {
+	err := s.check(ctx, actionHandOff)
+	if err != nil {
+		return err
+	}
+
+	store := s.store.WithContext(ctx)
+	sh, err := store.Shipment(number)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoShipment
+	}
+	if err != nil {
+		return err
+	}
+	// отправление перевозчика, которого убрали из настроек, просто забывается
+	if link, ok := s.carriers[sh.Carrier]; ok {
+		err = link.Carrier.CancelShipment(ctx, sh.ExternalID)
+		if err != nil {
+			return fmt.Errorf("перевозчик %s: %w", sh.Carrier, err)
+		}
+	}
+	return store.DeleteShipment(number)
+}
+
+// CarrierSync переносит статусы отправлений у перевозчиков на посылки.
+// Статус перевозчика переводится в статус трекера и применяется как событие сканирования
+// (ApplyScan), поэтому посылка не откатывается назад, а каждый статус применяется один раз.
+type CarrierSync struct {
+	store    ParcelStore
+	carriers map[string]CarrierLink
+	interval time.Duration
+	batch    int
+}
+
+// NewCarrierSync создаёт синхронизацию, которая раз в interval сверяет до batch отправлений
+func NewCarrierSync(store ParcelStore, carriers map[string]CarrierLink, interval time.Duration, batch int) *CarrierSync {
+	return &CarrierSync{store: store, carriers: carriers, interval: interval, batch: batch}
+}
+
+// Run сверяет отправления, пока не отменён ctx
+func (c *CarrierSync) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := c.SyncOnce(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			c.store.logger.Log(ctx, slog.LevelError, "не удалось синхронизировать статусы перевозчиков", "op", "carriers.Sync", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncOnce сверяет одну порцию отправлений и возвращает число посылок, у которых сменился статус.
+// Недоступный перевозчик не останавливает сверку остальных: ошибка попадает в лог,
+// а отправление будет проверено в следующий раз.
+func (c *CarrierSync) SyncOnce(ctx context.Context) (int, error) {
+	store := c.store.WithContext(ctx)
+	shipments, err := store.staleShipments(c.batch)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, sh := range shipments {
+		if ctx.Err() != nil {
+			return changed, ctx.Err()
+		}
+		link, ok := c.carriers[sh.Carrier]
+		if !ok {
+			continue
+		}
+
+		external, err := link.Carrier.FetchStatus(ctx, sh.ExternalID)
+		if err != nil {
+			store.logger.Log(ctx, slog.LevelWarn, "перевозчик не вернул статус отправления",
+				"op", "carriers.Sync", "number", sh.Number, "carrier", sh.Carrier, "error", err)
+			continue
+		}
+		applied, err := c.apply(ctx, sh, link, external)
+		if err != nil {
+			return changed, err
+		}
+		if applied {
+			changed++
+		}
+		err = store.syncShipment(sh.Number, external)
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// apply переносит статус перевозчика external на посылку отправления sh
+func (c *CarrierSync) apply(ctx context.Context, sh Shipment, link CarrierLink, external string) (bool, error) {
+	status, ok := link.Statuses[external]
+	if !ok {
+		if external != sh.CarrierStatus {
+			c.store.logger.Log(ctx, slog.LevelWarn, "статус перевозчика не переводится в статус трекера",
+				"op", "carriers.Sync", "number", sh.Number, "carrier", sh.Carrier, "carrier_status", external)
+		}
+		return false, nil
+	}
+
+	// идентификатор события один для каждого статуса отправления, поэтому повторная
+	// сверка с тем же статусом ничего не меняет; он же попадает в историю посылки
+	id := fmt.Sprintf("carrier:%s:%s:%s", sh.Carrier, sh.ExternalID, external)
+	applied, err := c.store.WithContext(WithRequestID(ctx, id)).ApplyScan(ScanEvent{ID: id, Number: sh.Number, Status: status})
+	if errors.Is(err, ErrInvalidTransition) || errors.Is(err, sql.ErrNoRows) {
+		c.store.logger.Log(ctx, slog.LevelWarn, "статус перевозчика не применён",
+			"op", "carriers.Sync", "number", sh.Number, "carrier", sh.Carrier, "carrier_status", external, "error", err)
+		return false, nil
+	}
+	return applied, err
+}
+
+// HTTPCarrier перевозчик с JSON API отправлений:
+//
+//	POST   {url}/shipments       {"reference": "<номер посылки>", "address": "..."} → {"id": "..."}
+//	GET    {url}/shipments/{id}  → {"status": "..."}
+//	DELETE {url}/shipments/{id}
+//
+// Токен передаётся в заголовке Authorization: Bearer.
+type HTTPCarrier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewHTTPCarrier(baseURL, token string, timeout time.Duration) *HTTPCarrier {
+	return &HTTPCarrier{url: strings.TrimRight(baseURL, "/"), token: token, client: &http.Client{Timeout: timeout}}
+}
+
+func (c *HTTPCarrier) CreateShipment(ctx context.Context, p Parcel) (string, error) {
+	var res struct {
+		ID string `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/shipments", map[string]string{
+		"reference": fmt.Sprint(p.Number),
+		"address":   p.Address,
+	}, &res)
+	if err != nil {
+		return "", err
+	}
+	if res.ID == "" {
+		return "", errors.New("перевозчик не вернул номер отправления")
+	}
+	return res.ID, nil
+}
+
+func (c *HTTPCarrier) FetchStatus(ctx context.Context, externalID string) (string, error) {
+	var res struct {
+		Status string `json:"status"`
+	}
+	err := c.do(ctx, http.MethodGet, "/shipments/"+url.PathEscape(externalID), nil, &res)
+	return res.Status, err
+}
+
+func (c *HTTPCarrier) CancelShipment(ctx context.Context, externalID string) error {
+	return c.do(ctx, http.MethodDelete, "/shipments/"+url.PathEscape(externalID), nil, nil)
+}
+
+// do выполняет запрос к API перевозчика; успехом считается любой ответ 2xx
+func (c *HTTPCarrier) do(ctx context.Context, method, path string, body, res any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("перевозчик ответил %s", resp.Status)
+	}
+	if res == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
@@ -0,0 +1,148 @@
+package tracker_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// fakeCarrier перевозчик в памяти: все отправления в статусе status
+type fakeCarrier struct {
+	status    string
+	created   []int
+	cancelled []string
+}
+
+func (c *fakeCarrier) CreateShipment(_ context.Context, p tracker.Parcel) (string, error) {
+	c.created = append(c.created, p.Number)
+	return fmt.Sprintf("ext-%d", p.Number), nil
+}
+
+func (c *fakeCarrier) FetchStatus(_ context.Context, _ string) (string, error) {
+	return c.status, nil
+}
+
+func (c *fakeCarrier) CancelShipment(_ context.Context, externalID string) error {
+	c.cancelled = append(c.cancelled, externalID)
+	return nil
+}
+
+// TestCarrierSync проверяет передачу посылки перевозчику и перенос его статусов на посылку
+func TestCarrierSync(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	carrier := &fakeCarrier{status: "accepted"}
+	carriers := map[string]tracker.CarrierLink{
+		"fast": {Carrier: carrier, Statuses: map[string]string{"in_transit": tracker.ParcelStatusSent, "done": tracker.ParcelStatusDelivered}},
+	}
+	store := tracker.NewParcelStore(db)
+	service := tracker.NewParcelService(store, tracker.WithCarriers(carriers))
+	ctx := context.Background()
+
+	parcel, err := service.Register(ctx, testsupport.Intn(10_000_000), "test")
+	require.NoError(t, err)
+
+	_, err = service.HandOff(ctx, parcel.Number, "slow")
+	require.ErrorIs(t, err, tracker.ErrUnknownCarrier)
+	sh, err := service.HandOff(ctx, parcel.Number, "fast")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("ext-%d", parcel.Number), sh.ExternalID)
+	_, err = service.HandOff(ctx, parcel.Number, "fast")
+	require.ErrorIs(t, err, tracker.ErrAlreadyHandedOff)
+
+	carrierSync := tracker.NewCarrierSync(store, carriers, time.Minute, 1_000_000)
+	syncOnce := func() {
+		t.Helper()
+		_, err := carrierSync.SyncOnce(ctx)
+		require.NoError(t, err)
+	}
+	status := func() string {
+		t.Helper()
+		p, err := store.Get(parcel.Number)
+		require.NoError(t, err)
+		return p.Status
+	}
+
+	// sync
+	// статус, которого нет в соответствии, посылку не меняет
+	syncOnce()
+	assert.Equal(t, tracker.ParcelStatusRegistered, status())
+
+	carrier.status = "in_transit"
+	syncOnce()
+	assert.Equal(t, tracker.ParcelStatusSent, status())
+	syncOnce()
+
+	carrier.status = "done"
+	syncOnce()
+
+	// check
+	assert.Equal(t, tracker.ParcelStatusDelivered, status())
+	sh, err = service.Shipment(ctx, parcel.Number)
+	require.NoError(t, err)
+	assert.Equal(t, "done", sh.CarrierStatus)
+
+	history, err := store.History(parcel.Number)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, fmt.Sprintf("carrier:fast:ext-%d:in_transit", parcel.Number), history[1].RequestID)
+
+	require.NoError(t, service.CancelHandOff(ctx, parcel.Number))
+	assert.Equal(t, []string{sh.ExternalID}, carrier.cancelled)
+	_, err = service.Shipment(ctx, parcel.Number)
+	assert.ErrorIs(t, err, tracker.ErrNoShipment)
+}
+
+// TestHTTPCarrier проверяет запросы к JSON API перевозчика
+func TestHTTPCarrier(t *testing.T) {
+	// prepare
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /shipments", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		assert.Equal(t, "7", body["reference"])
+		assert.Equal(t, "адрес", body["address"])
+		json.NewEncoder(w).Encode(map[string]string{"id": "A-1"})
+	})
+	mux.HandleFunc("GET /shipments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"status": "in_transit:" + r.PathValue("id")})
+	})
+	mux.HandleFunc("DELETE /shipments/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	carrier := tracker.NewHTTPCarrier(srv.URL+"/", "secret", time.Second)
+	ctx := context.Background()
+
+	// check
+	id, err := carrier.CreateShipment(ctx, tracker.Parcel{Number: 7, Address: "адрес"})
+	require.NoError(t, err)
+	assert.Equal(t, "A-1", id)
+
+	status, err := carrier.FetchStatus(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "in_transit:A-1", status)
+
+	assert.Error(t, carrier.CancelShipment(ctx, id))
+}
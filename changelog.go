@@ -0,0 +1,145 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// changeLogBatch сколько записей журнала изменений читается одним запросом
+const changeLogBatch = 500
+
+const (
+	queryLastChange     = "SELECT COALESCE(MAX(id), 0) FROM change_log"
+	queryChangesAfter   = "SELECT id, type, number, tenant_id, COALESCE(status, '') FROM change_log WHERE id > :id ORDER BY id LIMIT :limit"
+	queryPruneChangeLog = "DELETE FROM change_log WHERE changed_at < :before"
+)
+
+// ChangeWatcher передаёт обработчикам OnChange изменения посылок, сделанные любым
+// процессом, открывшим ту же БД: другим экземпляром трекера, CLI или sqlite3. Их
+// записывают в журнал change_log триггеры таблицы parcel, а наблюдатель читает журнал
+// раз в interval. Обработчик обновлений SQLite здесь не подходит: он видит только
+// изменения своего соединения.
+//
+// Пока наблюдатель работает, изменения этого процесса тоже приходят из журнала,
+// поэтому каждое изменение обработчики получают один раз, но с задержкой до interval.
+// Журнал свой у каждой БД, поэтому у каждой БД депо ShardedStore нужен свой наблюдатель.
+type ChangeWatcher struct {
+	store    ParcelStore
+	interval time.Duration
+	logger   Logger
+	// last номер последней переданной записи журнала; started — он прочитан из БД
+	last    int64
+	started bool
+}
+
+// NewChangeWatcher создаёт наблюдателя за журналом изменений БД хранилища store
+func NewChangeWatcher(store ParcelStore, cfg config.Changes, logger Logger) *ChangeWatcher {
+	return &ChangeWatcher{store: store, interval: cfg.Interval, logger: logger}
+}
+
+// Run читает журнал раз в interval до отмены ctx. Изменения, сделанные до запуска,
+// обработчики не получают. После отмены журнал читается последний раз: изменения
+// после прошлого опроса хранилище само обработчикам уже не передало.
+func (w *ChangeWatcher) Run(ctx context.Context) {
+	defer w.store.hooks.watched.Store(false)
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+	for {
+		err := w.Poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			w.logger.Log(ctx, slog.LevelWarn, "журнал изменений не прочитан", "op", "changes.Poll", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			w.drain(context.WithoutCancel(ctx))
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// drain передаёт обработчикам записи журнала, оставшиеся к остановке наблюдателя
+func (w *ChangeWatcher) drain(ctx context.Context) {
+	if !w.started {
+		return
+	}
+	err := w.Poll(ctx)
+	if err != nil {
+		w.logger.Log(ctx, slog.LevelWarn, "журнал изменений не дочитан при остановке", "op", "changes.Poll", "error", err)
+	}
+}
+
+// Poll передаёт обработчикам записи журнала, появившиеся после прошлого вызова.
+// Первый вызов только запоминает конец журнала и переключает обработчики на журнал.
+func (w *ChangeWatcher) Poll(ctx context.Context) error {
+	store := w.store.WithContext(ctx)
+	if !w.started {
+		// конец журнала читается до переключения: изменение между ними обработчики
+		// получат дважды, но не пропустят
+		err := store.queryRow(nil, queryLastChange).Scan(&w.last)
+		if err != nil {
+			return err
+		}
+		w.started = true
+		store.hooks.watched.Store(true)
+		return nil
+	}
+
+	for {
+		events, last, err := store.changesAfter(w.last, changeLogBatch)
+		if err != nil {
+			return err
+		}
+		w.last = last
+		store.hooks.deliver(events...)
+		if len(events) < changeLogBatch {
+			return nil
+		}
+	}
+}
+
+// changesAfter возвращает до limit записей журнала после записи after и номер последней
+func (s ParcelStore) changesAfter(after int64, limit int) ([]Event, int64, error) {
+	rows, err := s.query(nil, queryChangesAfter, sql.Named("id", after), sql.Named("limit", limit))
+	if err != nil {
+		return nil, after, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		err := rows.Scan(&after, &e.Type, &e.Number, &e.Tenant, &e.Status)
+		if err != nil {
+			return nil, after, err
+		}
+		events = append(events, e)
+	}
+	return events, after, rows.Err()
+}
+
+// PruneChangeLog удаляет записи журнала изменений, сделанные раньше before,
+// и возвращает их число
+func (s ParcelStore) PruneChangeLog(before time.Time) (int, error) {
+	start := time.Now()
+	span := s.startSpan("PruneChangeLog")
+	defer span.End()
+
+	var n int64
+	err := s.withRetry("store.PruneChangeLog", func(s ParcelStore) error {
+		res, err := s.exec(nil, queryPruneChangeLog, sql.Named("before", before.UTC().Format(time.RFC3339)))
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("PruneChangeLog", start)
+	logResult(s.ctx, s.logger, "store.PruneChangeLog", start, err, "before", before, "deleted", n)
+	return int(n), err
+}
@@ -0,0 +1,99 @@
+package tracker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// TestChangeWatcher проверяет, что наблюдатель передаёт обработчикам изменения другого
+// процесса, в том числе сделанные в обход трекера, а изменения своего процесса — один раз
+func TestChangeWatcher(t *testing.T) {
+	// prepare
+	// два соединения с одним файлом: свой процесс и чужой
+	cfg := testDBConfig(t)
+	store := NewParcelStore(migrateTestDB(t, cfg))
+	otherDB := migrateTestDB(t, cfg)
+	other := NewParcelStore(otherDB)
+	before := mustAddParcel(t, other, getTestParcel()).Number
+
+	var events []Event
+	store.OnChange(func(e Event) {
+		events = append(events, e)
+	})
+	watcher := NewChangeWatcher(store, config.Changes{Interval: time.Second}, discardLogger{})
+	ctx := context.Background()
+	require.NoError(t, watcher.Poll(ctx))
+
+	// change
+	id := mustAddParcel(t, other, getTestParcel()).Number
+	require.NoError(t, other.SetAddress(id, "new test address"))
+	_, err := otherDB.Exec("UPDATE parcel SET status = 'sent' WHERE number = ?", id)
+	require.NoError(t, err)
+	require.NoError(t, other.Delete(before))
+	own := mustAddParcel(t, store, getTestParcel()).Number
+	acme := mustAddParcel(t, other.WithContext(WithTenant(ctx, "acme")), newParcelFixture().tenant("acme").build()).Number
+	// изменения своего процесса тоже приходят из журнала
+	assert.Empty(t, events)
+
+	// poll
+	require.NoError(t, watcher.Poll(ctx))
+	assert.Equal(t, []Event{
+		{Type: EventParcelAdded, Number: id, Tenant: DefaultTenant},
+		{Type: EventParcelAddressChanged, Number: id, Tenant: DefaultTenant},
+		{Type: EventParcelStatusChanged, Number: id, Tenant: DefaultTenant, Status: ParcelStatusSent},
+		{Type: EventParcelDeleted, Number: before, Tenant: DefaultTenant},
+		{Type: EventParcelAdded, Number: own, Tenant: DefaultTenant},
+		{Type: EventParcelAdded, Number: acme, Tenant: "acme"},
+	}, events)
+	require.NoError(t, watcher.Poll(ctx))
+	assert.Len(t, events, 6)
+
+	// prune
+	n, err := store.PruneChangeLog(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 7, n)
+}
+
+// TestChangeWatcherDrain проверяет, что остановленный наблюдатель дочитывает журнал:
+// изменение после последнего опроса не теряется
+func TestChangeWatcherDrain(t *testing.T) {
+	// prepare
+	cfg := testDBConfig(t)
+	store := NewParcelStore(migrateTestDB(t, cfg))
+	other := NewParcelStore(migrateTestDB(t, cfg))
+	var mu sync.Mutex
+	var events []Event
+	store.OnChange(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	})
+
+	// следующий опрос по расписанию будет только через час
+	watcher := NewChangeWatcher(store, config.Changes{Interval: time.Hour}, discardLogger{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watcher.Run(ctx)
+	}()
+	require.Eventually(t, store.hooks.watched.Load, 5*time.Second, 10*time.Millisecond)
+
+	// stop
+	id := mustAddParcel(t, other, getTestParcel()).Number
+	cancel()
+	<-done
+
+	// check
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []Event{{Type: EventParcelAdded, Number: id, Tenant: DefaultTenant}}, events)
+	assert.False(t, store.hooks.watched.Load())
+}
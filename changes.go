@@ -0,0 +1,164 @@
+package tracker
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ParcelChange описывает изменение статуса посылки в хранилище.
+// Он же используется как запись истории статусов посылки.
+type ParcelChange struct {
+	Number    int    `json:"number"`
+	Status    string `json:"status"`
+	ChangedAt string `json:"changed_at"`
+	// RequestID идентификатор запроса, в котором установлен статус;
+	// есть только в истории, подписчикам изменений он не рассылается
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// changeFeedBuffer размер буфера канала одного подписчика
+const changeFeedBuffer = 16
+
+// changeFeed рассылает изменения посылок всем подписчикам.
+// Медленный подписчик не блокирует хранилище: если его буфер заполнен,
+// изменение для него пропускается.
+type changeFeed struct {
+	mu     sync.Mutex
+	subs   map[chan ParcelChange]struct{}
+	closed bool
+}
+
+func newChangeFeed() *changeFeed {
+	return &changeFeed{subs: map[chan ParcelChange]struct{}{}}
+}
+
+// subscribe регистрирует нового подписчика и возвращает канал изменений
+// и функцию отписки, которая закрывает канал.
+// После close канал возвращается уже закрытым.
+func (f *changeFeed) subscribe() (<-chan ParcelChange, func()) {
+	ch := make(chan ParcelChange, changeFeedBuffer)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	f.subs[ch] = struct{}{}
+
+	cancel := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subs[ch]; ok {
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+
+	return ch, cancel
+}
+
+// close закрывает каналы всех подписчиков, чтобы завершились
+// долгие потоки изменений (gRPC, WebSocket, SSE)
+func (f *changeFeed) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	for ch := range f.subs {
+		delete(f.subs, ch)
+		close(ch)
+	}
+}
+
+// publish отправляет изменения всем подписчикам
+func (f *changeFeed) publish(changes ...ParcelChange) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, c := range changes {
+		for ch := range f.subs {
+			select {
+			case ch <- c:
+			default:
+			}
+		}
+	}
+}
+
+// Event изменение посылки, о котором хранилище сообщает обработчикам OnChange
+type Event struct {
+	// Type тип изменения: EventParcelAdded, EventParcelStatusChanged,
+	// EventParcelAddressChanged или EventParcelDeleted
+	Type   string
+	Number int
+	// Tenant компания посылки. Пусто, если изменение сделала фоновая задача по всем
+	// компаниям, а событие пришло от хранилища, а не из журнала ChangeWatcher.
+	Tenant string
+	// Status новый статус; есть только в parcel.status_changed
+	Status string
+}
+
+// changeHook обработчик изменений с номером, по которому его снимают
+type changeHook struct {
+	id   int
+	hook func(Event)
+}
+
+// changeHooks обработчики изменений, общие для всех копий хранилища
+type changeHooks struct {
+	mu     sync.Mutex
+	nextID int
+	hooks  []changeHook
+	// watched изменения приходят обработчикам из журнала ChangeWatcher, а не от хранилища
+	watched atomic.Bool
+}
+
+// add регистрирует hook и возвращает функцию, которая его снимает
+func (h *changeHooks) add(hook func(Event)) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextID++
+	id := h.nextID
+	h.hooks = append(h.hooks, changeHook{id: id, hook: hook})
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.hooks = slices.DeleteFunc(h.hooks, func(c changeHook) bool { return c.id == id })
+	}
+}
+
+// call передаёт обработчикам изменения, сделанные хранилищем этого процесса. Пока
+// работает ChangeWatcher, они придут из журнала вместе с остальными, и call ничего не делает.
+func (h *changeHooks) call(events ...Event) {
+	if h.watched.Load() {
+		return
+	}
+	h.deliver(events...)
+}
+
+// event возвращает изменение посылки number для обработчиков OnChange
+// с компанией хранилища
+func (s ParcelStore) event(typ string, number int, status string) Event {
+	e := Event{Type: typ, Number: number, Status: status}
+	if tenant := s.tenant(); tenant != anyTenant {
+		e.Tenant = tenant
+	}
+	return e
+}
+
+// deliver вызывает обработчики для каждого события в порядке регистрации. Обработчики
+// вызываются без блокировки, поэтому могут сами регистрировать и снимать обработчики.
+func (h *changeHooks) deliver(events ...Event) {
+	h.mu.Lock()
+	hooks := slices.Clone(h.hooks)
+	h.mu.Unlock()
+
+	for _, e := range events {
+		for _, c := range hooks {
+			c.hook(e)
+		}
+	}
+}
@@ -0,0 +1,1807 @@
+package tracker
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// cliApp общее состояние команд: флаги, настройки и приложение
+// с подключением к БД, хранилищем и сервисом
+type cliApp struct {
+	*App
+
+	configPath string
+	dbPath     string
+	tenant     string
+	serve      serveFlags
+	cfg        config.Config
+}
+
+// serveFlags флаги команды serve, переопределяющие настройки
+type serveFlags struct {
+	grpcAddr, httpAddr string
+	requireAPIKey      bool
+	rateRPS            float64
+	rateBurst          int
+}
+
+// newRootCmd собирает дерево команд трекера
+func newRootCmd() *cobra.Command {
+	app := &cliApp{}
+
+	root := &cobra.Command{
+		Use:          "tracker",
+		Short:        "Сервис отслеживания посылок",
+		SilenceUsage: true,
+		// загрузка настроек, подключение к БД и миграции выполняются перед любой командой
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			err := app.loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			err = ValidateTenant(app.tenant)
+			if err != nil {
+				return fmt.Errorf("--tenant: %w", err)
+			}
+			return app.open()
+		},
+	}
+	root.PersistentFlags().StringVar(&app.configPath, "config", os.Getenv(config.EnvPrefix+"CONFIG"), "YAML-файл с настройками")
+	root.PersistentFlags().StringVar(&app.dbPath, "db", config.Default().DB.Path, "путь к файлу БД; важнее настроек")
+	root.PersistentFlags().StringVar(&app.tenant, "tenant", cmp.Or(os.Getenv(config.EnvPrefix+"TENANT"), DefaultTenant), "компания, с посылками которой работают команды")
+
+	root.AddCommand(
+		app.parcelCmd(),
+		app.serveCmd(),
+		app.demoCmd(),
+		app.tuiCmd(),
+		app.migrateCmd(),
+		app.maintenanceCmd(),
+		app.seedCmd(),
+		app.loadtestCmd(),
+		app.apiKeyCmd(),
+		app.clientCmd(),
+		app.depotCmd(),
+		app.tariffCmd(),
+		app.zoneCmd(),
+		app.filterCmd(),
+		app.codCmd(),
+		app.customsCmd(),
+		app.attachmentCmd(),
+		app.invoiceCmd(),
+		app.routeCmd(),
+		app.courierCmd(),
+		app.importCmd(),
+		app.exportCmd(),
+		app.restoreCmd(),
+		app.backupCmd(),
+		app.reportCmd(),
+	)
+	app.closeAfterRun(root)
+
+	return root
+}
+
+// closeAfterRun оборачивает команды так, чтобы приложение закрывалось и после ошибки:
+// PersistentPostRunE в этом случае не вызывается, и БД осталась бы открытой
+func (a *cliApp) closeAfterRun(cmd *cobra.Command) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			err := run(cmd, args)
+			return errors.Join(err, a.close())
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		a.closeAfterRun(sub)
+	}
+}
+
+// loadConfig загружает настройки; явно указанные флаги команды важнее файла и окружения
+func (a *cliApp) loadConfig(cmd *cobra.Command) error {
+	cfg, err := config.Load(a.configPath)
+	if err != nil {
+		return err
+	}
+
+	flags := cmd.Flags()
+	changed := func(name string) bool {
+		f := flags.Lookup(name)
+		return f != nil && f.Changed
+	}
+	if changed("db") {
+		cfg.DB.Path = a.dbPath
+	}
+	if changed("grpc") {
+		cfg.GRPC.Addr = a.serve.grpcAddr
+	}
+	if changed("http") {
+		cfg.HTTP.Addr = a.serve.httpAddr
+	}
+	if changed("require-api-key") {
+		cfg.Auth.RequireAPIKey = a.serve.requireAPIKey
+	}
+	if changed("rate-limit-rps") {
+		cfg.RateLimit.RPS = a.serve.rateRPS
+	}
+	if changed("rate-limit-burst") {
+		cfg.RateLimit.Burst = a.serve.rateBurst
+	}
+
+	a.cfg = cfg
+	return cfg.Validate()
+}
+
+// open создаёт приложение: подключается к БД и приводит её схему к актуальной версии
+func (a *cliApp) open() error {
+	app, err := NewApp(a.cfg)
+	if err != nil {
+		return err
+	}
+	a.App = app
+	return nil
+}
+
+// context возвращает контекст команды с компанией из --tenant
+func (a *cliApp) context() context.Context {
+	return WithTenant(context.Background(), a.tenant)
+}
+
+func (a *cliApp) close() error {
+	if a.App == nil {
+		return nil
+	}
+	return a.App.Stop(context.Background())
+}
+
+func (a *cliApp) parcelCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "parcel",
+		Short: "Работа с посылками",
+	}
+
+	var client int
+	var address string
+	var dims QuoteRequest
+	add := &cobra.Command{
+		Use:   "add",
+		Short: "Зарегистрировать посылку",
+		Long:  "Зарегистрировать посылку; с --weight и размерами в неё записывается стоимость доставки по тарифу.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var p Parcel
+			var err error
+			if cmd.Flags().Changed("weight") {
+				p, err = a.service.RegisterQuoted(a.context(), client, address, dims)
+			} else {
+				p, err = a.service.Register(a.context(), client, address)
+			}
+			if err != nil {
+				return err
+			}
+			printParcel(cmd.OutOrStdout(), p)
+			if p.Price != 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "Стоимость доставки (%s): %s\n", p.Priority, formatPrice(p.Price))
+			}
+			return nil
+		},
+	}
+	add.Flags().IntVar(&client, "client", 0, "идентификатор клиента")
+	add.Flags().StringVar(&address, "address", "", "адрес доставки")
+	dimensionFlags(add, &dims)
+	add.MarkFlagRequired("client")
+	add.MarkFlagRequired("address")
+
+	quote := &cobra.Command{
+		Use:   "quote",
+		Short: "Рассчитать стоимость доставки посылки до регистрации",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			q, err := a.service.Quote(a.context(), address, dims)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Стоимость доставки (%s, зона %s, оплачиваемый вес %d г): %s\n",
+				q.Priority, q.Zone, q.ChargeableGrams, formatPrice(q.Price))
+			return nil
+		},
+	}
+	quote.Flags().StringVar(&address, "address", "", "адрес доставки")
+	dimensionFlags(quote, &dims)
+	quote.MarkFlagRequired("address")
+	quote.MarkFlagRequired("weight")
+
+	get := &cobra.Command{
+		Use:   "get <number>",
+		Short: "Показать посылку",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			p, err := a.service.Get(a.context(), number)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParcelNotFound
+			}
+			if err != nil {
+				return err
+			}
+			printParcel(cmd.OutOrStdout(), p)
+			return nil
+		},
+	}
+
+	var listClient int
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "Показать посылки клиента",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parcels, err := a.service.ClientParcels(a.context(), listClient)
+			if err != nil {
+				return err
+			}
+			for _, p := range parcels {
+				printParcel(cmd.OutOrStdout(), p)
+			}
+			return nil
+		},
+	}
+	list.Flags().IntVar(&listClient, "client", 0, "идентификатор клиента")
+	list.MarkFlagRequired("client")
+
+	var searchOpts ListOptions
+	search := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Найти посылки по адресу, номеру или трекинг-коду",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parcels, err := a.service.Search(a.context(), args[0], searchOpts)
+			if err != nil {
+				return err
+			}
+			for _, p := range parcels {
+				printParcel(cmd.OutOrStdout(), p)
+			}
+			return nil
+		},
+	}
+	search.Flags().IntVar(&searchOpts.Client, "client", 0, "только посылки клиента")
+	search.Flags().StringVar(&searchOpts.Status, "status", "", "только посылки в статусе")
+	search.Flags().StringVar(&searchOpts.Zone, "zone", "", "только посылки зоны")
+	search.Flags().IntVar(&searchOpts.Limit, "limit", searchDefaultLimit, "сколько посылок показать")
+
+	var overdueOpts ListOptions
+	overdue := &cobra.Command{
+		Use:   "overdue",
+		Short: "Показать посылки, которые дольше срока остаются в статусе registered или sent",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parcels, err := a.service.ListOverdue(a.context(), overdueOpts)
+			if err != nil {
+				return err
+			}
+			for _, p := range parcels {
+				fmt.Fprintf(cmd.OutOrStdout(), "Посылка № %d клиента %d в статусе %s с %s, срок превышен на %s\n",
+					p.Number, p.Client, p.Status, p.Since, p.Overdue.Round(time.Minute))
+			}
+			return nil
+		},
+	}
+	overdue.Flags().IntVar(&overdueOpts.Client, "client", 0, "только посылки клиента")
+	overdue.Flags().IntVar(&overdueOpts.Limit, "limit", 0, "сколько посылок показать; 0 — все")
+
+	var breachesOpts ListOptions
+	var breachesFrom, breachesTo string
+	breaches := &cobra.Command{
+		Use:   "breaches",
+		Short: "Показать посылки, не доставленные в обещанный срок",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, err := parseCLITime(breachesFrom)
+			if err != nil {
+				return fmt.Errorf("--from: %w", err)
+			}
+			to, err := parseCLITime(breachesTo)
+			if err != nil {
+				return fmt.Errorf("--to: %w", err)
+			}
+			parcels, err := a.service.SLABreaches(a.context(), from, to, breachesOpts)
+			if err != nil {
+				return err
+			}
+			for _, p := range parcels {
+				fmt.Fprintf(cmd.OutOrStdout(), "Посылка № %d клиента %d в статусе %s со сроком %s, опоздание %s\n",
+					p.Number, p.Client, p.Status, p.Deadline, p.Late.Round(time.Minute))
+			}
+			return nil
+		},
+	}
+	breaches.Flags().StringVar(&breachesFrom, "from", "", "срок доставки не раньше даты или времени в RFC 3339")
+	breaches.Flags().StringVar(&breachesTo, "to", "", "срок доставки раньше даты или времени в RFC 3339; по умолчанию сейчас")
+	breaches.Flags().IntVar(&breachesOpts.Client, "client", 0, "только посылки клиента")
+	breaches.Flags().IntVar(&breachesOpts.Limit, "limit", 0, "сколько посылок показать; 0 — все")
+
+	setStatus := &cobra.Command{
+		Use:   "set-status <number> <status>",
+		Short: "Изменить статус посылки",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			return a.service.SetStatus(a.context(), number, args[1])
+		},
+	}
+
+	setAddress := &cobra.Command{
+		Use:   "set-address <number> <address>",
+		Short: "Изменить адрес доставки зарегистрированной посылки",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			return a.service.ChangeAddress(a.context(), number, args[1])
+		},
+	}
+
+	del := &cobra.Command{
+		Use:   "delete <number>",
+		Short: "Удалить зарегистрированную посылку",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			return a.service.Delete(a.context(), number)
+		},
+	}
+
+	var reason string
+	rollback := &cobra.Command{
+		Use:   "rollback <number>",
+		Short: "Откатить последнюю смену статуса посылки",
+		Long: "Вернуть посылку к предыдущему статусу из истории, например после ошибочного сканирования.\n" +
+			"Откат и его причина записываются в журнал status_rollback.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			rb, err := a.service.Rollback(a.context(), number, reason)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParcelNotFound
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Статус посылки %d откачен: %s -> %s\n", rb.Number, rb.From, rb.To)
+			return nil
+		},
+	}
+	rollback.Flags().StringVar(&reason, "reason", "", "причина отката (обязательно)")
+
+	merge := &cobra.Command{
+		Use:   "merge <number> <number>...",
+		Short: "Объединить посылки в первую из них",
+		Long: "Объединить посылки одного клиента на один адрес в одном статусе, например при перепаковке на складе.\n" +
+			"Статусы остальных посылок дальше меняются вместе со статусом первой.",
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			numbers, err := parseNumbers(args)
+			if err != nil {
+				return err
+			}
+			p, err := a.service.Merge(a.context(), numbers)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParcelNotFound
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Посылки объединены в посылку %d\n", p.Number)
+			return nil
+		},
+	}
+
+	split := &cobra.Command{
+		Use:   "split <number> <parts>",
+		Short: "Разделить посылку на части",
+		Long: "Разделить посылку на части, например при перепаковке на складе. Первая часть — сама посылка,\n" +
+			"остальные регистрируются с тем же клиентом, адресом, статусом и историей.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			n, err := strconv.Atoi(args[1])
+			if err != nil {
+				return err
+			}
+			parts, err := a.service.Split(a.context(), number, n)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParcelNotFound
+			}
+			if err != nil {
+				return err
+			}
+			for _, p := range parts {
+				printParcel(cmd.OutOrStdout(), p)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(add, quote, get, list, search, overdue, breaches, setStatus, setAddress, del, rollback, merge, split)
+	return cmd
+}
+
+// dimensionFlags добавляет команде флаги срочности, веса и размеров посылки
+func dimensionFlags(cmd *cobra.Command, dims *QuoteRequest) {
+	cmd.Flags().StringVar(&dims.Priority, "priority", PriorityStandard, "срочность доставки: standard или express")
+	cmd.Flags().IntVar(&dims.WeightGrams, "weight", 0, "вес в граммах")
+	cmd.Flags().IntVar(&dims.LengthCm, "length", 0, "длина в сантиметрах")
+	cmd.Flags().IntVar(&dims.WidthCm, "width", 0, "ширина в сантиметрах")
+	cmd.Flags().IntVar(&dims.HeightCm, "height", 0, "высота в сантиметрах")
+}
+
+func (a *cliApp) tariffCmd() *cobra.Command {
+	var days int
+
+	cmd := &cobra.Command{
+		Use:   "tariff",
+		Short: "Тарифы доставки",
+	}
+
+	set := &cobra.Command{
+		Use:   "set <zone> <priority> <base> <per-kg>",
+		Short: "Задать тариф доставки в зону",
+		Long: "Задать стоимость доставки посылки и каждого начатого килограмма в копейках.\n" +
+			"Зона — город назначения; тариф зоны * действует для городов без своего тарифа.\n" +
+			"С --days посылкам по тарифу обещается срок доставки в днях от регистрации.",
+		Args: cobra.ExactArgs(4),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			base, err := strconv.ParseInt(args[2], 10, 64)
+			if err != nil {
+				return err
+			}
+			perKg, err := strconv.ParseInt(args[3], 10, 64)
+			if err != nil {
+				return err
+			}
+			return a.service.SetTariff(a.context(), Tariff{Zone: args[0], Priority: args[1], Base: base, PerKg: perKg, Days: days})
+		},
+	}
+	set.Flags().IntVar(&days, "days", 0, "обещанный срок доставки в днях; 0 — срок не обещается")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "Показать тарифы",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tariffs, err := a.service.Tariffs(a.context())
+			if err != nil {
+				return err
+			}
+			for _, t := range tariffs {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\t%s + %s за кг", t.Zone, t.Priority, formatPrice(t.Base), formatPrice(t.PerKg))
+				if t.Days > 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), ", срок %d дн.", t.Days)
+				}
+				fmt.Fprintln(cmd.OutOrStdout())
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(set, list)
+	return cmd
+}
+
+func (a *cliApp) zoneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "zone",
+		Short: "Зоны доставки по почтовым индексам",
+	}
+
+	set := &cobra.Command{
+		Use:   "set <zone> <from> <to>",
+		Short: "Задать зону доставки",
+		Long: "Задать зону для почтовых индексов от from до to включительно.\n" +
+			"Зона определяется при регистрации и смене адреса посылки и действует как зона тарифа.",
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.service.SetZone(a.context(), Zone{Name: args[0], From: args[1], To: args[2]})
+		},
+	}
+
+	del := &cobra.Command{
+		Use:   "delete <from>",
+		Short: "Удалить зону с нижней границей from",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := a.service.DeleteZone(a.context(), args[0])
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("зоны с индекса %s нет", args[0])
+			}
+			return err
+		},
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "Показать зоны",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			zones, err := a.service.Zones(a.context())
+			if err != nil {
+				return err
+			}
+			for _, z := range zones {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s-%s\n", z.Name, z.From, z.To)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(set, del, list)
+	return cmd
+}
+
+func (a *cliApp) filterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "filter",
+		Short: "Сохранённые поиски посылок",
+	}
+
+	var q SavedSearch
+	var from, to string
+	save := &cobra.Command{
+		Use:   "save <name>",
+		Short: "Сохранить поиск по статусу, зоне и времени регистрации",
+		Long: "Сохранить поиск или заменить свой поиск с тем же именем.\n" +
+			"С --period поиск выбирает посылки, зарегистрированные за период до выполнения, например 168h — за неделю.\n" +
+			"С --shared поиск видят и выполняют все пользователи компании.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			q.Name = args[0]
+			q.From, err = parseCLITime(from)
+			if err != nil {
+				return fmt.Errorf("--from: %w", err)
+			}
+			q.To, err = parseCLITime(to)
+			if err != nil {
+				return fmt.Errorf("--to: %w", err)
+			}
+			_, err = a.service.SaveSearch(a.context(), q)
+			return err
+		},
+	}
+	save.Flags().StringVar(&q.Status, "status", "", "посылки в статусе")
+	save.Flags().StringVar(&q.Zone, "zone", "", "посылки зоны доставки")
+	save.Flags().StringVar(&from, "from", "", "посылки, зарегистрированные не раньше даты (2006-01-02 или RFC 3339)")
+	save.Flags().StringVar(&to, "to", "", "посылки, зарегистрированные раньше даты (2006-01-02 или RFC 3339)")
+	save.Flags().DurationVar(&q.Period, "period", 0, "посылки, зарегистрированные за период до выполнения; вместо --from")
+	save.Flags().BoolVar(&q.Shared, "shared", false, "сделать поиск общим для компании")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "Показать свои и общие поиски",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			searches, err := a.service.SavedSearches(a.context())
+			if err != nil {
+				return err
+			}
+			for _, q := range searches {
+				printSavedSearch(cmd.OutOrStdout(), q)
+			}
+			return nil
+		},
+	}
+
+	var limit int
+	run := &cobra.Command{
+		Use:   "run <name>",
+		Short: "Выполнить сохранённый поиск",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parcels, err := a.service.ListSaved(a.context(), args[0], limit)
+			if err != nil {
+				return err
+			}
+			for _, p := range parcels {
+				printParcel(cmd.OutOrStdout(), p)
+			}
+			return nil
+		},
+	}
+	run.Flags().IntVar(&limit, "limit", 50, "наибольшее число посылок; 0 — без ограничения")
+
+	del := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Удалить свой сохранённый поиск",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.service.DeleteSavedSearch(a.context(), args[0])
+		},
+	}
+
+	cmd.AddCommand(save, list, run, del)
+	return cmd
+}
+
+// printSavedSearch выводит имя поиска и его фильтры одной строкой
+func printSavedSearch(w io.Writer, q SavedSearch) {
+	filters := []string{}
+	if q.Status != "" {
+		filters = append(filters, "статус "+q.Status)
+	}
+	if q.Zone != "" {
+		filters = append(filters, "зона "+q.Zone)
+	}
+	if q.Period > 0 {
+		filters = append(filters, "за "+q.Period.String())
+	}
+	if !q.From.IsZero() {
+		filters = append(filters, "с "+q.From.Format(time.RFC3339))
+	}
+	if !q.To.IsZero() {
+		filters = append(filters, "до "+q.To.Format(time.RFC3339))
+	}
+	if len(filters) == 0 {
+		filters = append(filters, "все посылки")
+	}
+	shared := ""
+	if q.Shared {
+		shared = "\tобщий"
+	}
+	fmt.Fprintf(w, "%s\t%s%s\n", q.Name, strings.Join(filters, ", "), shared)
+}
+
+func (a *cliApp) serveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Запустить gRPC- и/или HTTP-сервер",
+		Long:  "Запускает серверы и работает до SIGINT или SIGTERM, после чего дожидается текущих запросов и закрывает БД.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if a.cfg.GRPC.Addr == "" && a.cfg.HTTP.Addr == "" {
+				return fmt.Errorf("укажите --grpc и/или --http")
+			}
+			return a.App.Run(cmd.Context())
+		},
+	}
+	defaults := config.Default()
+	cmd.Flags().StringVar(&a.serve.grpcAddr, "grpc", defaults.GRPC.Addr, "адрес gRPC-сервера, например :50051; пустой — не запускать")
+	cmd.Flags().StringVar(&a.serve.httpAddr, "http", defaults.HTTP.Addr, "адрес HTTP-сервера; пустой — не запускать")
+	cmd.Flags().BoolVar(&a.serve.requireAPIKey, "require-api-key", defaults.Auth.RequireAPIKey, "требовать API-ключ пользователя в HTTP- и gRPC-запросах")
+	cmd.Flags().Float64Var(&a.serve.rateRPS, "rate-limit-rps", defaults.RateLimit.RPS, "допустимое число запросов в секунду от одного пользователя; 0 — без ограничения")
+	cmd.Flags().IntVar(&a.serve.rateBurst, "rate-limit-burst", defaults.RateLimit.Burst, "допустимый всплеск запросов от одного пользователя")
+	return cmd
+}
+
+func (a *cliApp) demoCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "demo",
+		Short: "Проверить основную функциональность сервиса на демонстрационных посылках",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDemo(a.service)
+		},
+	}
+}
+
+func (a *cliApp) tuiCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tui",
+		Short: "Открыть терминальный дашборд диспетчера",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return RunTUI(a.service)
+		},
+	}
+}
+
+func (a *cliApp) migrateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Привести схему БД к актуальной версии",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// сами миграции уже применены при подключении к БД
+			version, err := SchemaVersion(a.db)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Версия схемы БД: %d\n", version)
+			return nil
+		},
+	}
+}
+
+func (a *cliApp) maintenanceCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "maintenance",
+		Short: "Проверить целостность БД, сжать её и обновить статистику запросов",
+		Long: "Выполнить PRAGMA integrity_check, VACUUM и ANALYZE для файла SQLite.\n" +
+			"Если БД повреждена, VACUUM не выполняется, а найденные ошибки выводятся.\n" +
+			"VACUUM переписывает файл целиком и на это время блокирует запись.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if a.cfg.DB.Driver != "sqlite" {
+				return fmt.Errorf("обслуживание работает только с SQLite, а не с %s", a.cfg.DB.Driver)
+			}
+			report, err := MaintainDB(context.Background(), a.db)
+			out := cmd.OutOrStdout()
+			for _, p := range report.Problems {
+				fmt.Fprintln(out, p)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Целостность: ok\nРазмер БД: %d → %d байт, освобождено %d байт за %s\n",
+				report.SizeBefore, report.SizeAfter, report.Reclaimed(), report.Duration.Round(time.Millisecond))
+			return nil
+		},
+	}
+}
+
+func (a *cliApp) seedCmd() *cobra.Command {
+	var opts SeedOptions
+	var from, to string
+
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Заполнить БД сгенерированными посылками с историей",
+		Long: "Сгенерировать посылки с адресами в разных городах, клиентами с разным числом посылок\n" +
+			"и историей статусов за период --from–--to (по умолчанию последние 30 дней) для демонстраций\n" +
+			"и нагрузочных тестов. С одним --seed получаются одни и те же посылки.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if from != "" {
+				opts.From, err = time.Parse(time.DateOnly, from)
+				if err != nil {
+					return fmt.Errorf("--from: %w", err)
+				}
+			}
+			if to != "" {
+				opts.To, err = time.Parse(time.DateOnly, to)
+				if err != nil {
+					return fmt.Errorf("--to: %w", err)
+				}
+			}
+			if !cmd.Flags().Changed("seed") {
+				opts.Seed = uint64(time.Now().UnixNano())
+			}
+			n, err := a.service.Seed(a.context(), opts)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Сгенерировано посылок: %d\n", n)
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&opts.Parcels, "count", 10, "количество посылок")
+	cmd.Flags().IntVar(&opts.Clients, "clients", 3, "количество клиентов, между которыми распределяются посылки")
+	cmd.Flags().StringVar(&from, "from", "", "начало периода регистрации посылок, ГГГГ-ММ-ДД")
+	cmd.Flags().StringVar(&to, "to", "", "конец периода регистрации посылок, ГГГГ-ММ-ДД; по умолчанию сейчас")
+	cmd.Flags().Uint64Var(&opts.Seed, "seed", 0, "зерно генератора; по умолчанию случайное")
+	return cmd
+}
+
+func (a *cliApp) loadtestCmd() *cobra.Command {
+	opts := LoadOptions{Duration: 30 * time.Second}
+
+	cmd := &cobra.Command{
+		Use:   "loadtest",
+		Short: "Нагрузить сервис регистрациями, сканированиями и запросами",
+		Long: "Выполнять операции с заданными частотами в секунду в течение --duration и вывести\n" +
+			"число операций, долю ошибок и перцентили длительностей по каждой операции.\n" +
+			"Посылки регистрируются в БД из настроек, поэтому запускайте тест на отдельной БД или компании.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report, err := a.service.RunLoad(a.context(), opts)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+			fmt.Fprintln(w, "операция\tвыполнено\tв сек\tошибки\tпропущено\tp50\tp90\tp99\tmax\t")
+			for _, s := range report.Ops {
+				fmt.Fprintf(w, "%s\t%d\t%.1f\t%.2f%%\t%d\t%s\t%s\t%s\t%s\t\n", s.Op, s.Count, s.Rate,
+					100*s.ErrorRate(), s.Dropped, s.P50.Round(time.Microsecond), s.P90.Round(time.Microsecond),
+					s.P99.Round(time.Microsecond), s.Max.Round(time.Microsecond))
+			}
+			err = w.Flush()
+			if err != nil {
+				return err
+			}
+			for _, op := range loadOps {
+				if e := report.FirstErrors[op]; e != nil {
+					fmt.Fprintf(out, "первая ошибка %s: %v\n", op, e)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&opts.Duration, "duration", opts.Duration, "длительность теста")
+	cmd.Flags().IntVar(&opts.Workers, "workers", 8, "сколько операций выполняется одновременно")
+	cmd.Flags().Float64Var(&opts.Adds, "adds", 10, "регистраций посылок в секунду")
+	cmd.Flags().Float64Var(&opts.Scans, "scans", 20, "сканирований со сменой статуса в секунду")
+	cmd.Flags().Float64Var(&opts.Queries, "queries", 50, "запросов посылки или посылок клиента в секунду")
+	cmd.Flags().IntVar(&opts.Clients, "clients", 100, "количество клиентов, между которыми распределяются посылки")
+	return cmd
+}
+
+func (a *cliApp) apiKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "api-key",
+		Short: "Управление API-ключами",
+	}
+
+	var role string
+	issue := &cobra.Command{
+		Use:   "issue <subject>",
+		Short: "Выпустить API-ключ пользователю и назначить ему роль",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			subject, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			err = a.service.AssignRole(a.context(), subject, Role(role))
+			if err != nil {
+				return err
+			}
+			key, err := a.service.IssueAPIKey(a.context(), subject)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "API-ключ пользователя %d с ролью %s: %s\n", subject, role, key)
+			return nil
+		},
+	}
+	issue.Flags().StringVar(&role, "role", string(RoleClient), "роль пользователя: client, operator, courier или admin")
+
+	cmd.AddCommand(issue)
+	return cmd
+}
+
+func (a *cliApp) clientCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "client",
+		Short: "Управление клиентами",
+	}
+
+	forget := &cobra.Command{
+		Use:   "forget <client>",
+		Short: "Удалить персональные данные клиента",
+		Long: "Заменить адреса всех посылок клиента, в том числе архивных, и удалить получателей уведомлений,\n" +
+			"подписки в Telegram, API-ключи и роль клиента. Посылки и история статусов остаются для отчётов.\n" +
+			"Отменить удаление нельзя.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			erasure, err := a.service.ForgetClient(a.context(), client)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Персональные данные клиента %d удалены, обезличено посылок: %d\n",
+				erasure.Client, erasure.Parcels)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(forget)
+	return cmd
+}
+
+// depotCmd команды для посылок в БД депо из db.shards
+func (a *cliApp) depotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "depot",
+		Short: "Работа с посылками в БД депо",
+	}
+	// shards возвращает БД депо в компании из --tenant
+	shards := func() (*ShardedStore, error) {
+		if a.shards == nil {
+			return nil, errors.New("БД депо не заданы в db.shards")
+		}
+		return a.shards.WithContext(a.context()), nil
+	}
+
+	add := &cobra.Command{
+		Use:   "add <depot> <client> <address>",
+		Short: "Зарегистрировать посылку в БД депо",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := strconv.Atoi(args[1])
+			if err != nil {
+				return err
+			}
+			s, err := shards()
+			if err != nil {
+				return err
+			}
+			token, err := newTrackingToken()
+			if err != nil {
+				return err
+			}
+			number, err := s.Add(args[0], Parcel{
+				Client:        client,
+				Status:        ParcelStatusRegistered,
+				Address:       args[2],
+				CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+				TrackingToken: token,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Посылка № %d зарегистрирована в депо %s\n", number, args[0])
+			return nil
+		},
+	}
+
+	get := &cobra.Command{
+		Use:   "get <number>",
+		Short: "Показать посылку из БД её депо",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			s, err := shards()
+			if err != nil {
+				return err
+			}
+			p, err := s.Get(number)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParcelNotFound
+			}
+			if err != nil {
+				return err
+			}
+			printParcel(cmd.OutOrStdout(), p)
+			return nil
+		},
+	}
+
+	list := &cobra.Command{
+		Use:   "list <client>",
+		Short: "Показать посылки клиента из БД всех депо",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			s, err := shards()
+			if err != nil {
+				return err
+			}
+			parcels, err := s.GetByClient(client)
+			if err != nil {
+				return err
+			}
+			for _, p := range parcels {
+				printParcel(cmd.OutOrStdout(), p)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(add, get, list)
+	return cmd
+}
+
+func (a *cliApp) codCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cod",
+		Short: "Наложенные платежи",
+	}
+
+	var currency string
+	set := &cobra.Command{
+		Use:   "set <number> <amount>",
+		Short: "Задать наложенный платёж посылки",
+		Long:  "Задать сумму, которую курьер получит с получателя при доставке, в копейках или других минимальных единицах валюты.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			amount, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return err
+			}
+			err = a.service.SetCOD(a.context(), number, amount, currency)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParcelNotFound
+			}
+			return err
+		},
+	}
+	set.Flags().StringVar(&currency, "currency", DefaultCurrency, "код валюты ISO 4217")
+
+	get := &cobra.Command{
+		Use:   "get <number>",
+		Short: "Показать наложенный платёж посылки",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			cod, err := a.service.COD(a.context(), number)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNoCOD
+			}
+			if err != nil {
+				return err
+			}
+			state := "не получен"
+			if cod.Collected() {
+				state = "получен " + cod.CollectedAt
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Наложенный платёж посылки № %d: %d %s, %s\n", cod.Number, cod.Amount, cod.Currency, state)
+			return nil
+		},
+	}
+
+	collect := &cobra.Command{
+		Use:   "collect <number>",
+		Short: "Отметить, что наложенный платёж сдан в кассу",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			cod, err := a.service.MarkCODCollected(a.context(), number)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParcelNotFound
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Платёж %d %s за посылку № %d получен\n", cod.Amount, cod.Currency, cod.Number)
+			return nil
+		},
+	}
+
+	report := &cobra.Command{
+		Use:   "report",
+		Short: "Неполученные платежи за доставленные посылки по курьерам",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			debts, err := a.service.UncollectedCOD(a.context())
+			if err != nil {
+				return err
+			}
+			for _, d := range debts {
+				fmt.Fprintf(cmd.OutOrStdout(), "курьер %d\t%d посылок\t%d %s\n", d.Courier, d.Parcels, d.Amount, d.Currency)
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(set, get, collect, report)
+	return cmd
+}
+
+func (a *cliApp) customsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "customs",
+		Short: "Таможенные декларации международных посылок",
+	}
+
+	var c Customs
+	set := &cobra.Command{
+		Use:   "set <number>",
+		Short: "Подать или исправить таможенную декларацию посылки",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			c.Number = number
+			err = a.service.SetCustoms(a.context(), c)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParcelNotFound
+			}
+			return err
+		},
+	}
+	set.Flags().StringVar(&c.HSCode, "hs-code", "", "код товара по ТН ВЭД, 6, 8 или 10 цифр")
+	set.Flags().StringVar(&c.Contents, "contents", "", "описание содержимого")
+	set.Flags().StringVar(&c.OriginCountry, "origin", "", "страна происхождения, код ISO 3166-1 alpha-2")
+
+	get := &cobra.Command{
+		Use:   "get <number>",
+		Short: "Показать таможенную декларацию посылки",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			c, err := a.service.Customs(a.context(), number)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNoCustoms
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Декларация посылки № %d: %s, %s, %s, статус %s\n", c.Number, c.HSCode, c.Contents, c.OriginCountry, c.Status)
+			return nil
+		},
+	}
+
+	// status создаёт команду, отмечающую таможенный статус посылки
+	status := func(use, short, customsStatus string) *cobra.Command {
+		return &cobra.Command{
+			Use:   use + " <number>",
+			Short: short,
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				number, err := strconv.Atoi(args[0])
+				if err != nil {
+					return err
+				}
+				_, err = a.service.SetCustomsStatus(a.context(), number, customsStatus)
+				if errors.Is(err, sql.ErrNoRows) {
+					return ErrParcelNotFound
+				}
+				return err
+			},
+		}
+	}
+
+	cmd.AddCommand(set, get,
+		status("hold", "Отметить, что посылку задержала таможня", CustomsHeld),
+		status("clear", "Отметить, что таможня выпустила посылку", CustomsCleared))
+	return cmd
+}
+
+func (a *cliApp) attachmentCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "attachment",
+		Short: "Документы посылок: накладные, таможенные формы, фотографии",
+	}
+
+	var kind string
+	add := &cobra.Command{
+		Use:   "add <number> <file>",
+		Short: "Приложить документ к посылке",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(args[1])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			att, err := a.service.AddAttachment(a.context(), number, kind, filepath.Base(args[1]), f)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParcelNotFound
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Документ %d приложен к посылке № %d\n", att.ID, att.Number)
+			return nil
+		},
+	}
+	add.Flags().StringVar(&kind, "kind", AttachmentOther, "вид документа: invoice, customs, photo или other")
+
+	list := &cobra.Command{
+		Use:   "list <number>",
+		Short: "Показать документы посылки",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			attachments, err := a.service.Attachments(a.context(), number)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParcelNotFound
+			}
+			if err != nil {
+				return err
+			}
+			for _, att := range attachments {
+				fmt.Fprintf(cmd.OutOrStdout(), "%d\t%s\t%s\t%s\t%d байт\t%s\n", att.ID, att.Kind, att.Name, att.ContentType, att.Size, att.CreatedAt)
+			}
+			return nil
+		},
+	}
+
+	var output string
+	get := &cobra.Command{
+		Use:   "get <number> <id>",
+		Short: "Скачать документ посылки",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			id, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return err
+			}
+			att, body, err := a.service.OpenAttachment(a.context(), number, id)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParcelNotFound
+			}
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+			if output == "" {
+				output = att.Name
+			}
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, body)
+			return errors.Join(err, f.Close())
+		},
+	}
+	get.Flags().StringVarP(&output, "output", "o", "", "файл документа; по умолчанию имя, под которым его загрузили")
+
+	del := &cobra.Command{
+		Use:   "delete <number> <id>",
+		Short: "Удалить документ посылки",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			number, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			id, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return err
+			}
+			err = a.service.DeleteAttachment(a.context(), number, id)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParcelNotFound
+			}
+			return err
+		},
+	}
+
+	cmd.AddCommand(add, list, get, del)
+	return cmd
+}
+
+func (a *cliApp) invoiceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "invoice",
+		Short: "Счета клиентам",
+	}
+
+	var month, format, output string
+	// write записывает счёт в файл --output или в стандартный вывод
+	write := func(cmd *cobra.Command, invoice Invoice) error {
+		w := cmd.OutOrStdout()
+		if output != "" {
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		return invoice.Write(w, format)
+	}
+	// parse разбирает клиента и месяц счёта; по умолчанию месяц прошедший
+	parse := func(args []string) (int, time.Time, error) {
+		client, err := strconv.Atoi(args[0])
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		m := monthStart(time.Now()).AddDate(0, -1, 0)
+		if month != "" {
+			m, err = time.Parse("2006-01", month)
+			if err != nil {
+				return 0, time.Time{}, fmt.Errorf("--month: %w", err)
+			}
+		}
+		return client, m, nil
+	}
+
+	generate := &cobra.Command{
+		Use:   "generate <client>",
+		Short: "Сформировать счёт клиенту за посылки, доставленные за месяц",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, m, err := parse(args)
+			if err != nil {
+				return err
+			}
+			invoice, err := a.service.GenerateInvoice(a.context(), client, m)
+			if err != nil {
+				return err
+			}
+			return write(cmd, invoice)
+		},
+	}
+
+	get := &cobra.Command{
+		Use:   "get <client>",
+		Short: "Показать сформированный счёт клиенту",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, m, err := parse(args)
+			if err != nil {
+				return err
+			}
+			invoice, err := a.service.Invoice(a.context(), client, m)
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("счёт клиенту %d за %s ещё не сформирован", client, m.Format("2006-01"))
+			}
+			if err != nil {
+				return err
+			}
+			return write(cmd, invoice)
+		},
+	}
+
+	for _, c := range []*cobra.Command{generate, get} {
+		c.Flags().StringVar(&month, "month", "", "месяц счёта в виде 2006-01; по умолчанию прошедший")
+		c.Flags().StringVar(&format, "format", InvoiceCSV, "формат счёта: csv или pdf")
+		c.Flags().StringVarP(&output, "output", "o", "", "файл счёта; по умолчанию стандартный вывод")
+	}
+	cmd.AddCommand(generate, get)
+	return cmd
+}
+
+func (a *cliApp) routeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "route",
+		Short: "Маршруты курьеров",
+	}
+
+	var day, zone string
+	build := &cobra.Command{
+		Use:   "build <courier> [<number>...]",
+		Short: "Составить маршрут курьера из посылок в порядке доставки",
+		Long:  "Составить маршрут курьера из посылок в порядке доставки или, с --zone, из всех посылок зоны, которые можно везти.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			courier, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			if (zone == "") == (len(args) == 1) {
+				return errors.New("укажите номера посылок или --zone")
+			}
+			var route Route
+			if zone != "" {
+				route, err = a.service.BuildZoneRoute(a.context(), courier, day, zone)
+			} else {
+				var numbers []int
+				numbers, err = parseNumbers(args[1:])
+				if err != nil {
+					return err
+				}
+				route, err = a.service.BuildRoute(a.context(), courier, day, numbers)
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrParcelNotFound
+			}
+			if err != nil {
+				return err
+			}
+			printRoute(cmd.OutOrStdout(), route)
+			return nil
+		},
+	}
+	build.Flags().StringVar(&day, "day", time.Now().Format(time.DateOnly), "день маршрута в виде 2006-01-02")
+	build.Flags().StringVar(&zone, "zone", "", "составить маршрут из посылок зоны")
+
+	// routeRun команда над маршрутом по его идентификатору
+	routeRun := func(run func(ctx context.Context, id int64) (Route, error)) func(*cobra.Command, []string) error {
+		return func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+			route, err := run(a.context(), id)
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("маршрут %d не найден", id)
+			}
+			if err != nil {
+				return err
+			}
+			printRoute(cmd.OutOrStdout(), route)
+			return nil
+		}
+	}
+	get := &cobra.Command{
+		Use:   "get <id>",
+		Short: "Показать маршрут",
+		Args:  cobra.ExactArgs(1),
+		RunE: routeRun(func(ctx context.Context, id int64) (Route, error) {
+			return a.service.Route(ctx, id)
+		}),
+	}
+	reorder := &cobra.Command{
+		Use:   "reorder <id> <number>...",
+		Short: "Изменить порядок посылок в маршруте",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			numbers, err := parseNumbers(args[1:])
+			if err != nil {
+				return err
+			}
+			return routeRun(func(ctx context.Context, id int64) (Route, error) {
+				err := a.service.ReorderRoute(ctx, id, numbers)
+				if err != nil {
+					return Route{}, err
+				}
+				return a.service.Route(ctx, id)
+			})(cmd, args)
+		},
+	}
+	start := &cobra.Command{
+		Use:   "start <id>",
+		Short: "Начать маршрут: зарегистрированные посылки получают статус sent",
+		Args:  cobra.ExactArgs(1),
+		RunE: routeRun(func(ctx context.Context, id int64) (Route, error) {
+			return a.service.StartRoute(ctx, id)
+		}),
+	}
+	complete := &cobra.Command{
+		Use:   "complete <id>",
+		Short: "Завершить маршрут",
+		Args:  cobra.ExactArgs(1),
+		RunE: routeRun(func(ctx context.Context, id int64) (Route, error) {
+			return a.service.CompleteRoute(ctx, id)
+		}),
+	}
+
+	cmd.AddCommand(build, get, reorder, start, complete)
+	return cmd
+}
+
+// printRoute выводит маршрут одной строкой
+func printRoute(w io.Writer, r Route) {
+	fmt.Fprintf(w, "Маршрут %d курьера %d на %s (%s): %v\n", r.ID, r.Courier, r.Day, r.Status, r.Parcels)
+}
+
+// parseNumbers разбирает номера посылок из аргументов команды
+func parseNumbers(args []string) ([]int, error) {
+	numbers := make([]int, len(args))
+	for i, arg := range args {
+		var err error
+		numbers[i], err = strconv.Atoi(arg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return numbers, nil
+}
+
+func (a *cliApp) courierCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "courier",
+		Short: "Положение курьеров",
+	}
+
+	locate := &cobra.Command{
+		Use:   "locate <courier> <lat> <lon>",
+		Short: "Сообщить положение курьера",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			courier, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			lat, err := strconv.ParseFloat(args[1], 64)
+			if err != nil {
+				return err
+			}
+			lon, err := strconv.ParseFloat(args[2], 64)
+			if err != nil {
+				return err
+			}
+			loc, err := a.service.ReportLocation(a.context(), courier, lat, lon)
+			if err != nil {
+				return err
+			}
+			printLocation(cmd.OutOrStdout(), loc)
+			return nil
+		},
+	}
+
+	location := &cobra.Command{
+		Use:   "location <courier>",
+		Short: "Показать последнее известное положение курьера",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			courier, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			loc, err := a.service.LastLocation(a.context(), courier)
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("курьер %d ещё не сообщал положение", courier)
+			}
+			if err != nil {
+				return err
+			}
+			printLocation(cmd.OutOrStdout(), loc)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(locate, location)
+	return cmd
+}
+
+// printLocation выводит положение курьера одной строкой
+func printLocation(w io.Writer, loc CourierLocation) {
+	fmt.Fprintf(w, "Курьер %d: %.6f, %.6f на %s\n", loc.Courier, loc.Lat, loc.Lon, loc.ReportedAt)
+}
+
+func (a *cliApp) importCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Импортировать посылки из манифеста перевозчика",
+		Long: "Импортировать посылки из манифеста перевозчика в формате CSV или EDI.\n" +
+			"Строки без номера регистрируют новые посылки, с номером — меняют статус и адрес существующих.\n" +
+			"Корректные строки импортируются в одной транзакции, об остальных выводятся ошибки.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			report, err := a.service.ImportManifest(a.context(), format, f, a.cfg.Import.CSV)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Добавлено посылок: %d, изменено: %d\n", report.Added, report.Updated)
+			for _, e := range report.Errors {
+				fmt.Fprintln(out, e)
+			}
+			if len(report.Errors) > 0 {
+				return fmt.Errorf("отклонено строк манифеста: %d", len(report.Errors))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", ManifestCSV, "формат манифеста: csv или edi")
+	return cmd
+}
+
+func (a *cliApp) exportCmd() *cobra.Command {
+	var opts ListOptions
+	var format, output, columns, from, to string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Выгрузить посылки: с историей в NDJSON или для отчётов в CSV",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			opts.From, err = parseCLITime(from)
+			if err != nil {
+				return fmt.Errorf("--from: %w", err)
+			}
+			opts.To, err = parseCLITime(to)
+			if err != nil {
+				return fmt.Errorf("--to: %w", err)
+			}
+			cols, err := ParseCSVColumns(columns)
+			if err != nil {
+				return err
+			}
+
+			w := cmd.OutOrStdout()
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			switch format {
+			case "ndjson":
+				_, err = a.store.ExportJSON(w, opts)
+			case "csv":
+				_, err = a.service.ExportCSV(a.context(), w, CSVExportOptions{ListOptions: opts, Columns: cols})
+			default:
+				err = fmt.Errorf("неизвестный формат выгрузки %q", format)
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "ndjson", "формат выгрузки: ndjson или csv")
+	cmd.Flags().IntVar(&opts.Client, "client", 0, "выгрузить только посылки клиента")
+	cmd.Flags().StringVar(&opts.Status, "status", "", "выгрузить только посылки в статусе")
+	cmd.Flags().StringVar(&from, "from", "", "выгрузить посылки, зарегистрированные не раньше даты (2006-01-02 или RFC 3339)")
+	cmd.Flags().StringVar(&to, "to", "", "выгрузить посылки, зарегистрированные раньше даты (2006-01-02 или RFC 3339)")
+	cmd.Flags().StringVar(&columns, "columns", "", "колонки CSV через запятую; по умолчанию все")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "файл выгрузки; по умолчанию стандартный вывод")
+	return cmd
+}
+
+func (a *cliApp) reportCmd() *cobra.Command {
+	var month string
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Сформировать месячный отчёт в xlsx",
+		Long: "Сформировать операционный отчёт за месяц: статусы посылок, время доставки и объёмы клиентов.\n" +
+			"Отчёт кладётся в каталог reports.dir из настроек или в текущий каталог.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// по умолчанию отчёт за прошедший месяц, как и по расписанию
+			m := monthStart(time.Now()).AddDate(0, -1, 0)
+			if month != "" {
+				var err error
+				m, err = time.Parse("2006-01", month)
+				if err != nil {
+					return fmt.Errorf("--month: %w", err)
+				}
+			}
+			dir := a.cfg.Reports.Dir
+			if dir == "" {
+				dir = "."
+			}
+			path, err := NewReports(a.store, dir).Generate(a.context(), m)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Отчёт сохранён в %s\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&month, "month", "", "месяц отчёта в виде 2006-01; по умолчанию прошедший")
+	return cmd
+}
+
+// parseCLITime разбирает время из флага: дату или время в RFC 3339; пустая строка — нулевое время
+func parseCLITime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func (a *cliApp) restoreCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <file>",
+		Short: "Загрузить посылки из выгрузки export с теми же номерами и историей",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			n, err := a.store.ImportJSON(f)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Загружено посылок: %d\n", n)
+			return nil
+		},
+	}
+}
+
+func (a *cliApp) backupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Снимки БД целиком",
+	}
+
+	var output string
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Записать снимок БД",
+		Long: "Записать снимок БД: SQLite копируется онлайн-бэкапом, Postgres — через pg_dump.\n" +
+			"Без --output снимок с временем в имени кладётся в бакет backup.s3.bucket, каталог backup.dir\n" +
+			"из настроек или в текущий каталог, а старые снимки сверх backup.keep удаляются.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			path := output
+			if path != "" {
+				err := BackupDB(ctx, a.db, a.cfg.DB, path)
+				if err != nil {
+					return err
+				}
+			} else {
+				sink, err := NewBackupSink(a.cfg.Backup)
+				if err != nil {
+					return err
+				}
+				path, err = NewBackups(a.store, a.cfg.DB, sink, a.cfg.Backup.Keep).Create(ctx)
+				if err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Снимок БД сохранён в %s\n", path)
+			return nil
+		},
+	}
+	create.Flags().StringVarP(&output, "output", "o", "", "файл снимка")
+
+	restore := &cobra.Command{
+		Use:   "restore <file>",
+		Short: "Заменить содержимое БД снимком из backup create",
+		Long: "Заменить содержимое БД снимком из backup create и привести схему к актуальной версии.\n" +
+			"Изменения после снимка теряются; serve на время восстановления лучше остановить.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := RestoreDB(context.Background(), a.db, a.cfg.DB, args[0])
+			if err != nil {
+				return err
+			}
+			err = Migrate(a.db)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "БД восстановлена из %s\n", args[0])
+			return nil
+		},
+	}
+
+	cmd.AddCommand(create, restore)
+	return cmd
+}
+
+// printParcel выводит посылку одной строкой
+func printParcel(w io.Writer, p Parcel) {
+	fmt.Fprintf(w, "Посылка № %d на адрес %s от клиента с идентификатором %d зарегистрирована %s, статус %s\n",
+		p.Number, p.Address, p.Client, p.CreatedAt, p.Status)
+}
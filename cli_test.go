@@ -0,0 +1,78 @@
+package tracker
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runCLI выполняет команду трекера с БД path и аргументами args и возвращает её вывод
+func runCLI(t *testing.T, path string, args ...string) (string, error) {
+	t.Helper()
+
+	cmd := newRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"--db", path}, args...))
+
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+// TestCLIParcel проверяет команды работы с посылками
+func TestCLIParcel(t *testing.T) {
+	// prepare
+	// посылка добавляется напрямую, чтобы знать её номер
+	cfg := testDBConfig(t)
+	db := migrateTestDB(t, cfg)
+
+	store := NewParcelStore(db)
+	parcel := getTestParcel()
+	parcel.Client = randRange.Intn(10_000_000)
+	id, err := store.Add(parcel)
+	require.NoError(t, err)
+	number := strconv.Itoa(id)
+
+	// set-address, set-status
+	_, err = runCLI(t, cfg.Path, "parcel", "set-address", number, "new test address")
+	require.NoError(t, err)
+	_, err = runCLI(t, cfg.Path, "parcel", "set-status", number, ParcelStatusSent)
+	require.NoError(t, err)
+	_, err = runCLI(t, cfg.Path, "parcel", "set-status", number, "lost")
+	assert.ErrorIs(t, err, ErrUnknownStatus)
+
+	// get, list
+	out, err := runCLI(t, cfg.Path, "parcel", "get", number)
+	require.NoError(t, err)
+	assert.Contains(t, out, "new test address")
+	assert.Contains(t, out, ParcelStatusSent)
+
+	out, err = runCLI(t, cfg.Path, "parcel", "list", "--client", strconv.Itoa(parcel.Client))
+	require.NoError(t, err)
+	assert.Contains(t, out, fmt.Sprintf("Посылка № %d ", id))
+
+	// delete
+	// отправленная посылка не удаляется, а после возврата в registered — удаляется
+	_, err = runCLI(t, cfg.Path, "parcel", "delete", number)
+	require.NoError(t, err)
+	_, err = store.Get(id)
+	require.NoError(t, err)
+
+	require.NoError(t, store.SetStatus(id, ParcelStatusRegistered))
+	_, err = runCLI(t, cfg.Path, "parcel", "delete", number)
+	require.NoError(t, err)
+	_, err = runCLI(t, cfg.Path, "parcel", "get", number)
+	assert.ErrorIs(t, err, ErrParcelNotFound)
+}
+
+// TestCLIMigrate проверяет, что migrate сообщает актуальную версию схемы
+func TestCLIMigrate(t *testing.T) {
+	out, err := runCLI(t, testDBConfig(t).Path, "migrate")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("Версия схемы БД: %d\n", migrations[len(migrations)-1].version), out)
+}
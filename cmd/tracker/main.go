@@ -0,0 +1,15 @@
+// Команда tracker — трекер посылок: API, фоновые задачи и командная строка.
+package main
+
+import (
+	"os"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+)
+
+func main() {
+	err := tracker.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,311 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// DefaultCurrency валюта наложенного платежа, если она не указана
+const DefaultCurrency = "RUB"
+
+var (
+	// ErrNoCOD возвращается при получении платежа за посылку без наложенного платежа
+	ErrNoCOD = errors.New("у посылки нет наложенного платежа")
+	// ErrCODCollected возвращается при повторном получении или изменении уже полученного платежа
+	ErrCODCollected = errors.New("наложенный платёж уже получен")
+	// ErrCODNotDelivered возвращается при получении платежа за ещё не доставленную посылку
+	ErrCODNotDelivered = errors.New("посылка ещё не доставлена")
+	// ErrCODDelivered возвращается при изменении наложенного платежа доставленной посылки
+	ErrCODDelivered = errors.New("наложенный платёж нельзя изменить после доставки")
+	// ErrInvalidCOD возвращается при неположительной сумме или неверном коде валюты
+	ErrInvalidCOD = errors.New("неверная сумма или валюта наложенного платежа")
+)
+
+// currencyCode код валюты ISO 4217
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+const (
+	queryUpsertCOD = `INSERT INTO cod (number, tenant_id, amount, currency) VALUES (:number, :tenant, :amount, :currency)
+		ON CONFLICT (number) DO UPDATE SET amount = excluded.amount, currency = excluded.currency`
+	queryCOD = `SELECT number, amount, currency, COALESCE(delivered_at, ''), COALESCE(courier, 0),
+			COALESCE(collected_at, ''), COALESCE(collected_by, 0), COALESCE(collected_role, '')
+		FROM cod WHERE number = :number AND ` + tenantCond
+	queryCODDelivered = `UPDATE cod SET delivered_at = :delivered_at, courier = :courier
+		WHERE number = :number AND delivered_at IS NULL`
+	// после отката доставки платёж снова ждёт доставки, если его ещё не получили
+	queryCODUndelivered = "UPDATE cod SET delivered_at = NULL, courier = NULL WHERE number = :number AND collected_at IS NULL"
+	queryCollectCOD     = `UPDATE cod SET collected_at = :collected_at, collected_by = :collected_by,
+			collected_role = NULLIF(:collected_role, ''), request_id = NULLIF(:request_id, '')
+		WHERE number = :number AND collected_at IS NULL`
+	queryDeleteCOD = "DELETE FROM cod WHERE number = :number"
+	// queryUncollectedCOD неполученные платежи доставленных посылок по курьерам и валютам
+	queryUncollectedCOD = `SELECT COALESCE(courier, 0), currency, COUNT(*), SUM(amount) FROM cod
+		WHERE delivered_at IS NOT NULL AND collected_at IS NULL AND ` + tenantCond + `
+		GROUP BY COALESCE(courier, 0), currency ORDER BY COALESCE(courier, 0), currency`
+)
+
+// COD наложенный платёж: сумма, которую курьер получает с получателя при доставке
+type COD struct {
+	Number int
+	// Amount сумма в минимальных единицах валюты, например в копейках
+	Amount   int64
+	Currency string
+	// DeliveredAt когда посылку доставили; Courier — курьер, который её доставил, 0 — доставил не курьер
+	DeliveredAt string
+	Courier     int
+	// CollectedAt когда платёж сдали в кассу и кто его принял; пусто, пока платёж не получен
+	CollectedAt string
+	CollectedBy Caller
+}
+
+// Collected сообщает, что платёж получен
+func (c COD) Collected() bool {
+	return c.CollectedAt != ""
+}
+
+// UncollectedCOD неполученные платежи курьера в одной валюте
+type UncollectedCOD struct {
+	// Courier курьер, который доставил посылки; 0 — посылки доставил не курьер
+	Courier  int
+	Currency string
+	Parcels  int
+	Amount   int64
+}
+
+// SetCOD задаёт наложенный платёж посылки number или меняет его до доставки
+func (s ParcelStore) SetCOD(number int, amount int64, currency string) error {
+	start := time.Now()
+	span := s.startSpan("SetCOD", attrNumber.Int(number))
+	defer span.End()
+
+	err := s.withRetry("store.SetCOD", func(s ParcelStore) error {
+		return s.setCOD(number, amount, currency)
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("SetCOD", start)
+	logResult(s.ctx, s.logger, "store.SetCOD", start, err, "number", number, "amount", amount, "currency", currency)
+	return err
+}
+
+func (s ParcelStore) setCOD(number int, amount int64, currency string) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status, tenant string
+	var client int
+	err = s.queryRow(tx, queryParcelStatus, sql.Named("number", number), sql.Named("tenant", s.tenant())).Scan(&status, &client, &tenant)
+	if err != nil {
+		return err
+	}
+	if status == ParcelStatusDelivered {
+		return ErrCODDelivered
+	}
+	cod, err := s.cod(tx, number)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	// платёж мог быть получен до отката доставки
+	if cod.Collected() {
+		return ErrCODCollected
+	}
+	_, err = s.exec(tx, queryUpsertCOD,
+		sql.Named("number", number),
+		sql.Named("tenant", tenant),
+		sql.Named("amount", amount),
+		sql.Named("currency", currency))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// COD возвращает наложенный платёж посылки number или sql.ErrNoRows, если его нет
+func (s ParcelStore) COD(number int) (COD, error) {
+	defer s.metrics.observeQuery("COD", time.Now())
+	span := s.startSpan("COD", attrNumber.Int(number))
+	defer span.End()
+
+	cod, err := s.cod(nil, number)
+	return cod, spanError(span, err)
+}
+
+func (s ParcelStore) cod(tx *sql.Tx, number int) (COD, error) {
+	var c COD
+	var role string
+	err := s.queryRow(tx, queryCOD, sql.Named("number", number), sql.Named("tenant", s.tenant())).Scan(
+		&c.Number, &c.Amount, &c.Currency, &c.DeliveredAt, &c.Courier, &c.CollectedAt, &c.CollectedBy.Client, &role)
+	c.CollectedBy.Role = Role(role)
+	return c, err
+}
+
+// codDelivered отмечает в транзакции tx доставку посылок changes с наложенным платежом.
+// Курьером записывается пользователь из контекста хранилища, если у него роль courier.
+func (s ParcelStore) codDelivered(tx *sql.Tx, changes []ParcelChange) error {
+	var courier any
+	if c, ok := CallerFromContext(s.ctx); ok && c.Role == RoleCourier {
+		courier = c.Client
+	}
+	for _, c := range changes {
+		_, err := s.exec(tx, queryCODDelivered,
+			sql.Named("delivered_at", c.ChangedAt),
+			sql.Named("courier", courier),
+			sql.Named("number", c.Number))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarkCODCollected отмечает, что наложенный платёж за доставленную посылку сдан в кассу.
+// Кто и когда принял платёж, записывается вместе с ним; подписчики outbox получают
+// событие parcel.cod_collected.
+func (s ParcelStore) MarkCODCollected(number int) (COD, error) {
+	start := time.Now()
+	span := s.startSpan("MarkCODCollected", attrNumber.Int(number))
+	defer span.End()
+
+	var cod COD
+	err := s.withRetry("store.MarkCODCollected", func(s ParcelStore) error {
+		var err error
+		cod, err = s.markCODCollected(number)
+		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("MarkCODCollected", start)
+	logResult(s.ctx, s.logger, "store.MarkCODCollected", start, err, "number", number,
+		"amount", cod.Amount, "currency", cod.Currency, "courier", cod.Courier)
+	return cod, err
+}
+
+func (s ParcelStore) markCODCollected(number int) (COD, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return COD{}, err
+	}
+	defer tx.Rollback()
+
+	var status, tenant string
+	var client int
+	err = s.queryRow(tx, queryParcelStatus, sql.Named("number", number), sql.Named("tenant", s.tenant())).Scan(&status, &client, &tenant)
+	if err != nil {
+		return COD{}, err
+	}
+	cod, err := s.cod(tx, number)
+	if errors.Is(err, sql.ErrNoRows) {
+		return COD{}, ErrNoCOD
+	}
+	if err != nil {
+		return COD{}, err
+	}
+	if cod.Collected() {
+		return cod, ErrCODCollected
+	}
+	if cod.DeliveredAt == "" {
+		return cod, ErrCODNotDelivered
+	}
+
+	cod.CollectedAt = time.Now().UTC().Format(time.RFC3339)
+	cod.CollectedBy, _ = CallerFromContext(s.ctx)
+	var collectedBy any
+	if cod.CollectedBy.Role != "" {
+		collectedBy = cod.CollectedBy.Client
+	}
+	_, err = s.exec(tx, queryCollectCOD,
+		sql.Named("collected_at", cod.CollectedAt),
+		sql.Named("collected_by", collectedBy),
+		sql.Named("collected_role", string(cod.CollectedBy.Role)),
+		sql.Named("request_id", RequestIDFromContext(s.ctx)),
+		sql.Named("number", number))
+	if err != nil {
+		return cod, err
+	}
+	err = s.addOutbox(tx, OutboxEvent{Type: EventParcelCODCollected, Number: number, Client: client, Status: status, Tenant: tenant})
+	if err != nil {
+		return cod, err
+	}
+	return cod, tx.Commit()
+}
+
+// UncollectedCOD возвращает суммы неполученных платежей за доставленные посылки по курьерам
+func (s ParcelStore) UncollectedCOD() ([]UncollectedCOD, error) {
+	defer s.metrics.observeQuery("UncollectedCOD", time.Now())
+	span := s.startSpan("UncollectedCOD")
+	defer span.End()
+
+	rows, err := s.readQuery(queryUncollectedCOD, sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer rows.Close()
+
+	var res []UncollectedCOD
+	for rows.Next() {
+		var u UncollectedCOD
+		err := rows.Scan(&u.Courier, &u.Currency, &u.Parcels, &u.Amount)
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		res = append(res, u)
+	}
+	return res, spanError(span, rows.Err())
+}
+
+// SetCOD задаёт наложенный платёж посылки; клиент может задать его только своей посылке
+func (s ParcelService) SetCOD(ctx context.Context, number int, amount int64, currency string) error {
+	err := s.check(ctx, actionRegister)
+	if err != nil {
+		return err
+	}
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	if amount <= 0 || !currencyCode.MatchString(currency) {
+		return fmt.Errorf("%w: %d %s", ErrInvalidCOD, amount, currency)
+	}
+	store := s.store.WithContext(ctx)
+	p, err := store.Get(number)
+	if err != nil {
+		return err
+	}
+	err = authorizeOwner(ctx, p.Client)
+	if err != nil {
+		return err
+	}
+	return store.SetCOD(number, amount, currency)
+}
+
+// COD возвращает наложенный платёж посылки
+func (s ParcelService) COD(ctx context.Context, number int) (COD, error) {
+	p, err := s.Get(ctx, number)
+	if err != nil {
+		return COD{}, err
+	}
+	return s.store.WithContext(ctx).COD(p.Number)
+}
+
+// MarkCODCollected отмечает, что наложенный платёж за посылку сдан в кассу;
+// доступно оператору и администратору
+func (s ParcelService) MarkCODCollected(ctx context.Context, number int) (COD, error) {
+	err := s.check(ctx, actionCOD)
+	if err != nil {
+		return COD{}, err
+	}
+	return s.store.WithContext(ctx).MarkCODCollected(number)
+}
+
+// UncollectedCOD возвращает неполученные платежи за доставленные посылки по курьерам
+func (s ParcelService) UncollectedCOD(ctx context.Context) ([]UncollectedCOD, error) {
+	err := s.check(ctx, actionCOD)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.WithContext(ctx).UncollectedCOD()
+}
@@ -0,0 +1,90 @@
+package tracker_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestCOD проверяет наложенный платёж: курьера при доставке, получение с журналом и отчёт
+func TestCOD(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	tenant := fmt.Sprintf("cod-%d", testsupport.Intn(10_000_000))
+	ctx := tracker.WithTenant(context.Background(), tenant)
+	store := tracker.NewParcelStore(db)
+	service := tracker.NewParcelService(store)
+	courier := tracker.WithCaller(ctx, tracker.Caller{Client: 7, Role: tracker.RoleCourier, Tenant: tenant})
+	operator := tracker.WithCaller(ctx, tracker.Caller{Client: 3, Role: tracker.RoleOperator, Tenant: tenant})
+
+	var numbers []int
+	for range 3 {
+		p, err := service.Register(ctx, 1000, "Псков, ул. Колотушкина, д. 5")
+		require.NoError(t, err)
+		numbers = append(numbers, p.Number)
+	}
+
+	// set
+	require.NoError(t, service.SetCOD(ctx, numbers[0], 150000, ""))
+	require.NoError(t, service.SetCOD(ctx, numbers[1], 50000, "RUB"))
+	require.NoError(t, service.SetCOD(ctx, numbers[2], 2000, "USD"))
+	assert.ErrorIs(t, service.SetCOD(ctx, numbers[0], 0, "RUB"), tracker.ErrInvalidCOD)
+	assert.ErrorIs(t, service.SetCOD(ctx, numbers[0], 100, "rub"), tracker.ErrInvalidCOD)
+	assert.ErrorIs(t, service.SetCOD(tracker.WithCaller(ctx, tracker.Caller{Client: 1001, Role: tracker.RoleClient}), numbers[0], 100, ""), tracker.ErrForbidden)
+
+	// платёж за недоставленную посылку получить нельзя
+	_, err := service.MarkCODCollected(operator, numbers[0])
+	assert.ErrorIs(t, err, tracker.ErrCODNotDelivered)
+
+	// deliver
+	for _, n := range numbers {
+		require.NoError(t, service.SetStatus(courier, n, tracker.ParcelStatusSent))
+		require.NoError(t, service.SetStatus(courier, n, tracker.ParcelStatusDelivered))
+	}
+	assert.ErrorIs(t, service.SetCOD(ctx, numbers[0], 100, ""), tracker.ErrCODDelivered)
+
+	report, err := service.UncollectedCOD(operator)
+	require.NoError(t, err)
+	assert.Equal(t, []tracker.UncollectedCOD{
+		{Courier: 7, Currency: "RUB", Parcels: 2, Amount: 200000},
+		{Courier: 7, Currency: "USD", Parcels: 1, Amount: 2000},
+	}, report)
+
+	// collect
+	_, err = service.MarkCODCollected(courier, numbers[0])
+	assert.ErrorIs(t, err, tracker.ErrForbidden)
+	cod, err := service.MarkCODCollected(operator, numbers[0])
+	require.NoError(t, err)
+	assert.Equal(t, 7, cod.Courier)
+	_, err = service.MarkCODCollected(operator, numbers[0])
+	assert.ErrorIs(t, err, tracker.ErrCODCollected)
+
+	cod, err = service.COD(ctx, numbers[0])
+	require.NoError(t, err)
+	assert.True(t, cod.Collected())
+	assert.Equal(t, tracker.Caller{Client: 3, Role: tracker.RoleOperator}, cod.CollectedBy)
+
+	var events int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM outbox WHERE type = ? AND number = ?", tracker.EventParcelCODCollected, numbers[0]).Scan(&events))
+	assert.Equal(t, 1, events)
+
+	report, err = service.UncollectedCOD(operator)
+	require.NoError(t, err)
+	assert.Equal(t, []tracker.UncollectedCOD{
+		{Courier: 7, Currency: "RUB", Parcels: 1, Amount: 50000},
+		{Courier: 7, Currency: "USD", Parcels: 1, Amount: 2000},
+	}, report)
+
+	_, err = service.MarkCODCollected(operator, numbers[0]+1_000_000)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,98 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+)
+
+// Виды кодов для посылок
+const (
+	// CodeQR QR-код, обычно со ссылкой для отслеживания
+	CodeQR = "qr"
+	// CodeBarcode линейный штрихкод Code 128, обычно с номером посылки
+	CodeBarcode = "code128"
+)
+
+// ErrUnknownCode возвращается при запросе кода вида, которого трекер не строит
+var ErrUnknownCode = errors.New("неизвестный вид кода")
+
+// TrackingURL возвращает ссылку для отслеживания посылки по трекинг-токену
+// на публичном адресе трекера base, например https://track.example.com
+func TrackingURL(base, token string) string {
+	return strings.TrimRight(base, "/") + "/track/" + url.PathEscape(token)
+}
+
+// EncodeCode кодирует content кодом вида kind. У штрихкода высота один модуль:
+// WriteCodePNG и WriteCodeSVG растягивают его до нужной высоты.
+func EncodeCode(kind, content string) (barcode.Barcode, error) {
+	switch kind {
+	case CodeQR:
+		// средний уровень коррекции ошибок выдерживает потёртую этикетку
+		return qr.Encode(content, qr.M, qr.Auto)
+	case CodeBarcode:
+		return code128.Encode(content)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownCode, kind)
+	}
+}
+
+// WriteCodePNG записывает код вида kind с содержимым content в PNG размером width×height точек
+func WriteCodePNG(w io.Writer, kind, content string, width, height int) error {
+	code, err := EncodeCode(kind, content)
+	if err != nil {
+		return err
+	}
+	scaled, err := barcode.Scale(code, width, height)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, scaled)
+}
+
+// WriteCodeSVG записывает код вида kind с содержимым content в SVG размером width×height.
+// Тёмные модули подряд в строке рисуются одним прямоугольником, чтобы файл был меньше.
+func WriteCodeSVG(w io.Writer, kind, content string, width, height int) error {
+	code, err := EncodeCode(kind, content)
+	if err != nil {
+		return err
+	}
+
+	b := code.Bounds()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" preserveAspectRatio="none" shape-rendering="crispEdges">`,
+		width, height, b.Dx(), b.Dy())
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="#fff"/>`, b.Dx(), b.Dy())
+	for y := range b.Dy() {
+		for x := 0; x < b.Dx(); {
+			if !codeModuleDark(code, x, y) {
+				x++
+				continue
+			}
+			run := 1
+			for x+run < b.Dx() && codeModuleDark(code, x+run, y) {
+				run++
+			}
+			fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" height="1"/>`, x, y, run)
+			x += run
+		}
+	}
+	sb.WriteString("</svg>\n")
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// codeModuleDark сообщает, тёмный ли модуль кода в точке x, y
+func codeModuleDark(code barcode.Barcode, x, y int) bool {
+	b := code.Bounds()
+	r, _, _, _ := code.At(b.Min.X+x, b.Min.Y+y).RGBA()
+	return r == 0
+}
@@ -0,0 +1,47 @@
+package tracker_test
+
+import (
+	"bytes"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+)
+
+// TestCodes проверяет QR-код и штрихкод в PNG и SVG
+func TestCodes(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	url := tracker.TrackingURL("https://track.example.com/", "a b")
+	assert.Equal(t, "https://track.example.com/track/a%20b", url)
+
+	// png
+	var buf bytes.Buffer
+	require.NoError(t, tracker.WriteCodePNG(&buf, tracker.CodeQR, url, 200, 200))
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 200, img.Bounds().Dx())
+	assert.Equal(t, 200, img.Bounds().Dy())
+
+	buf.Reset()
+	require.NoError(t, tracker.WriteCodePNG(&buf, tracker.CodeBarcode, "42", 300, 80))
+	img, err = png.Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 80, img.Bounds().Dy())
+
+	// svg
+	buf.Reset()
+	require.NoError(t, tracker.WriteCodeSVG(&buf, tracker.CodeBarcode, "42", 300, 80))
+	svg := buf.String()
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="80"`))
+	// штрихкод Code 128 начинается со стартового символа: две полосы, потом одна
+	assert.Contains(t, svg, `<rect x="0" y="0" width="2" height="1"/>`)
+
+	assert.ErrorIs(t, tracker.WriteCodePNG(io.Discard, "ean13", "42", 10, 10), tracker.ErrUnknownCode)
+}
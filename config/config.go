@@ -0,0 +1,1356 @@
+// Package config загружает настройки трекера из YAML-файла и переменных окружения.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix префикс переменных окружения трекера
+const EnvPrefix = "TRACKER_"
+
+// Config настройки трекера
+type Config struct {
+	DB        DB        `yaml:"db"`
+	HTTP      Server    `yaml:"http"`
+	GRPC      Server    `yaml:"grpc"`
+	Auth      Auth      `yaml:"auth"`
+	RateLimit RateLimit `yaml:"rate_limit"`
+	Tracing   Tracing   `yaml:"tracing"`
+	Outbox    Outbox    `yaml:"outbox"`
+	Scans     Scans     `yaml:"scans"`
+	Webhooks  Webhooks  `yaml:"webhooks"`
+	Notify    Notify    `yaml:"notify"`
+	Telegram  Telegram  `yaml:"telegram"`
+	Carriers  Carriers  `yaml:"carriers"`
+	Geocoder  Geocoder  `yaml:"geocoder"`
+	Import    Import    `yaml:"import"`
+	Reports   Reports   `yaml:"reports"`
+	Labels    Labels    `yaml:"labels"`
+	History   History   `yaml:"history"`
+	Overdue   Overdue   `yaml:"overdue"`
+	// Duplicates поиск повторной регистрации одной посылки
+	Duplicates Duplicates `yaml:"duplicates"`
+	// ETA оценка времени доставки новых посылок
+	ETA     ETA     `yaml:"eta"`
+	Archive Archive `yaml:"archive"`
+	Backup  Backup  `yaml:"backup"`
+	// Attachments хранилище документов, приложенных к посылкам
+	Attachments Attachments `yaml:"attachments"`
+	Redaction   Redaction   `yaml:"redaction"`
+	// Jobs настройки периодических задач serve по имени задачи
+	Jobs Jobs `yaml:"jobs"`
+	// PublicURL адрес, по которому трекер доступен клиентам, например
+	// https://track.example.com; от него строятся ссылки для отслеживания
+	// в QR-кодах на этикетках и в письмах
+	PublicURL string `yaml:"public_url"`
+	// ReadOnly запуск в режиме только для чтения, например на реплике;
+	// администратор может переключить режим через API
+	ReadOnly bool `yaml:"read_only"`
+	// ShutdownTimeout сколько ждать завершения обрабатываемых запросов при остановке
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// LogLevel уровень логирования: debug, info, warn или error
+	LogLevel string `yaml:"log_level"`
+	// LogFormat формат логов: text или json
+	LogFormat string `yaml:"log_format"`
+	// Features флаги функциональности, которую можно включать и выключать без сборки
+	Features Features `yaml:"features"`
+}
+
+// DB настройки подключения к БД
+type DB struct {
+	Driver string `yaml:"driver"`
+	Path   string `yaml:"path"`
+	SQLite SQLite `yaml:"sqlite"`
+	Retry  Retry  `yaml:"retry"`
+	Pool   Pool   `yaml:"pool"`
+	// Reader БД только для чтения, например реплика Postgres или копия файла SQLite
+	Reader Reader `yaml:"reader"`
+	Cache  Cache  `yaml:"cache"`
+	Batch  Batch  `yaml:"batch"`
+	// SlowQueryThreshold запросы дольше порога записываются в лог с параметрами
+	// и стеком вызова; 0 — не записывать
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+	// Encryption шифрование адресов посылок в БД
+	Encryption Encryption `yaml:"encryption"`
+	// Shards БД депо: посылки каждого депо хранятся в своём файле SQLite
+	Shards []Shard `yaml:"shards"`
+	// Breaker размыкатель цепи: после сбоев БД подряд запросы сразу получают ошибку
+	Breaker Breaker `yaml:"breaker"`
+	// Timeouts сколько ждать одну операцию хранилища, прежде чем вернуть ошибку
+	Timeouts Timeouts `yaml:"timeouts"`
+	// Monitor фоновая проверка БД и переподключение после сбоев
+	Monitor Monitor `yaml:"monitor"`
+	// Changes чтение журнала изменений, которые делают в БД другие процессы
+	Changes Changes `yaml:"changes"`
+}
+
+// Changes журнал изменений change_log, который пишут триггеры БД
+type Changes struct {
+	// Interval как часто читать журнал и передавать изменения обработчикам OnChange;
+	// 0 — обработчики получают только изменения этого процесса
+	Interval time.Duration `yaml:"interval"`
+	// Retention сколько хранить записи журнала; 0 — не удалять
+	Retention time.Duration `yaml:"retention"`
+}
+
+// Monitor фоновая проверка основной БД
+type Monitor struct {
+	// Interval как часто проверять, что БД отвечает; 0 — не проверять
+	Interval time.Duration `yaml:"interval"`
+	// Failures после скольких неудачных проверок подряд соединения открываются заново
+	Failures int `yaml:"failures"`
+	// Standby путь к резервной БД, на которую соединения переключаются после сбоев
+	// основной и обратно; пустой — соединения открываются заново к той же БД
+	Standby string `yaml:"standby"`
+}
+
+// Timeouts ограничения времени операций хранилища; 0 — без ограничения
+type Timeouts struct {
+	// Read один запрос чтения, включая ожидание соединения из пула
+	Read time.Duration `yaml:"read"`
+	// Write одна запись вместе с её транзакцией и повторами при блокировке БД
+	Write time.Duration `yaml:"write"`
+}
+
+// Breaker размыкатель цепи к БД
+type Breaker struct {
+	// Failures после скольких сбоев БД подряд цепь размыкается; 0 — размыкатель выключен
+	Failures int `yaml:"failures"`
+	// Cooldown сколько цепь остаётся разомкнутой до пробного запроса
+	Cooldown time.Duration `yaml:"cooldown"`
+}
+
+// Shard БД посылок одного депо или региона
+type Shard struct {
+	// Depot код депо, по которому посылка регистрируется в его БД
+	Depot string `yaml:"depot"`
+	// Prefix префикс номеров посылок депо: номер — префикс и девять цифр
+	Prefix int `yaml:"prefix"`
+	// Path путь к файлу SQLite депо
+	Path string `yaml:"path"`
+}
+
+// Encryption ключи AES-256 для шифрования адресов; без ключей адреса хранятся открыто
+type Encryption struct {
+	// Keys ключи в base64 по идентификатору; прежние ключи остаются здесь,
+	// пока задача reencrypt не перешифрует ими зашифрованные адреса
+	Keys map[string]string `yaml:"keys"`
+	// Current идентификатор ключа, которым шифруются новые адреса
+	Current string `yaml:"current"`
+	// BatchSize сколько адресов перешифровывается одной транзакцией
+	BatchSize int `yaml:"batch_size"`
+}
+
+// Cache кеш посылок для чтения по номеру: в памяти процесса или в Redis
+type Cache struct {
+	// Size наибольшее число посылок в кеше в памяти; 0 — кеш выключен
+	Size int `yaml:"size"`
+	// RedisAddr адрес Redis (host:port); если задан, кеш общий для всех экземпляров
+	// трекера и Size не используется
+	RedisAddr string `yaml:"redis_addr"`
+	// TTL срок жизни записи; ограничивает, как долго может продержаться устаревшая версия
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// Batch запись новых посылок пачками: одновременные регистрации записываются
+// одной транзакцией, то есть одной фиксацией на диск
+type Batch struct {
+	// Size наибольшее число посылок в пачке; 0 или 1 — каждая посылка пишется сразу
+	Size int `yaml:"size"`
+	// Delay сколько пачка ждёт следующих посылок после первой
+	Delay time.Duration `yaml:"delay"`
+}
+
+// Reader настройки БД для чтения. Если путь не задан, чтение идёт из основной БД.
+type Reader struct {
+	Path string `yaml:"path"`
+	Pool Pool   `yaml:"pool"`
+}
+
+// Pool настройки пула соединений database/sql; нулевые значения — без ограничения
+type Pool struct {
+	// MaxOpenConns наибольшее число открытых соединений. SQLite допускает только
+	// одного писателя, поэтому по умолчанию соединение одно: записи ждут в пуле,
+	// а не получают «database is locked» от самой БД
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+// Retry повтор записи, упёршейся в блокировку БД
+type Retry struct {
+	// MaxAttempts наибольшее число попыток, включая первую; 1 — без повторов
+	MaxAttempts int           `yaml:"max_attempts"`
+	BaseDelay   time.Duration `yaml:"base_delay"`
+	MaxDelay    time.Duration `yaml:"max_delay"`
+}
+
+// SQLite параметры соединений с SQLite, которые задаются при их открытии
+type SQLite struct {
+	// JournalMode режим журнала: WAL позволяет читать во время записи
+	JournalMode string `yaml:"journal_mode"`
+	// BusyTimeout сколько ждать снятия блокировки БД, прежде чем вернуть «database is locked»
+	BusyTimeout time.Duration `yaml:"busy_timeout"`
+	Synchronous string        `yaml:"synchronous"`
+	ForeignKeys bool          `yaml:"foreign_keys"`
+	// TxLock режим начала транзакций: immediate сразу берёт блокировку записи,
+	// и транзакция не упирается в блокировку посередине
+	TxLock string `yaml:"txlock"`
+}
+
+// Server настройки сервера; пустой адрес означает, что сервер не запускается
+type Server struct {
+	Addr string `yaml:"addr"`
+}
+
+// Tracing настройки экспорта трассировки OpenTelemetry
+type Tracing struct {
+	// Endpoint адрес OTLP/HTTP-коллектора, например http://localhost:4318;
+	// пустой адрес отключает экспорт спанов
+	Endpoint    string `yaml:"endpoint"`
+	ServiceName string `yaml:"service_name"`
+	// SampleRatio доля трассировок, которые записываются, от 0 до 1
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// Outbox настройки отправки событий из таблицы outbox во внешнюю шину
+type Outbox struct {
+	// Publisher куда отправлять события: nats, kafka или log — в лог; пусто — не отправлять,
+	// события копятся в outbox до включения отправки
+	Publisher string        `yaml:"publisher"`
+	Interval  time.Duration `yaml:"interval"`
+	BatchSize int           `yaml:"batch_size"`
+	NATS      NATS          `yaml:"nats"`
+	Kafka     Kafka         `yaml:"kafka"`
+}
+
+// NATS настройки отправки событий в NATS
+type NATS struct {
+	URL string `yaml:"url"`
+	// SubjectPrefix начало темы; событие уходит в тему <prefix>.<тип события>
+	SubjectPrefix string `yaml:"subject_prefix"`
+}
+
+// Kafka настройки отправки событий в Kafka
+type Kafka struct {
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
+}
+
+// Scans настройки приёма событий сканирования посылок со складов
+type Scans struct {
+	// NATSURL адрес NATS, из которого читаются события; пусто — приём выключен
+	NATSURL string `yaml:"nats_url"`
+	Subject string `yaml:"subject"`
+	// Queue группа подписчиков NATS: экземпляры трекера в одной группе делят события
+	Queue string `yaml:"queue"`
+}
+
+// Webhooks настройки доставки событий на вебхуки клиентов (флаг функциональности webhooks)
+type Webhooks struct {
+	// Retry повторы неудачной доставки; после MaxAttempts попыток доставка считается проваленной
+	Retry     Retry         `yaml:"retry"`
+	Timeout   time.Duration `yaml:"timeout"`
+	Interval  time.Duration `yaml:"interval"`
+	BatchSize int           `yaml:"batch_size"`
+}
+
+// Notify настройки уведомлений получателей о смене статусов посылок.
+// Канал включается, если задан его адрес: smtp.addr для email, sms.url для SMS.
+type Notify struct {
+	SMTP    SMTP          `yaml:"smtp"`
+	SMS     SMS           `yaml:"sms"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// SMTP настройки отправки уведомлений по email
+type SMTP struct {
+	// Addr адрес SMTP-сервера host:port
+	Addr     string `yaml:"addr"`
+	From     string `yaml:"from"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// SMS настройки отправки уведомлений через HTTP-шлюз SMS
+type SMS struct {
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"`
+}
+
+// Telegram настройки бота Telegram; бот запускается, если задан token
+type Telegram struct {
+	Token string `yaml:"token"`
+	// APIURL адрес Bot API; меняется для локального сервера Bot API и тестов
+	APIURL string `yaml:"api_url"`
+	// PollTimeout сколько Bot API держит запрос обновлений, пока их нет
+	PollTimeout time.Duration `yaml:"poll_timeout"`
+}
+
+// Carriers сторонние перевозчики, которым передаются посылки, и синхронизация их статусов
+type Carriers struct {
+	// SyncInterval как часто запрашивать статусы переданных посылок у перевозчиков
+	SyncInterval time.Duration `yaml:"sync_interval"`
+	// BatchSize сколько посылок проверять за одну синхронизацию
+	BatchSize int           `yaml:"batch_size"`
+	Timeout   time.Duration `yaml:"timeout"`
+	List      []Carrier     `yaml:"list"`
+}
+
+// Carrier перевозчик с HTTP API отправлений
+type Carrier struct {
+	Name  string `yaml:"name"`
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"`
+	// Statuses соответствие статусов перевозчика статусам трекера;
+	// статусы, которых здесь нет, не меняют статус посылки
+	Statuses map[string]string `yaml:"statuses"`
+}
+
+// Geocoder сервис, который по адресу доставки возвращает его координаты
+type Geocoder struct {
+	// URL адрес HTTP API геокодера; пустой — координаты не определяются
+	URL     string        `yaml:"url"`
+	Token   string        `yaml:"token"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Import разбор манифестов перевозчиков при импорте посылок
+type Import struct {
+	CSV CSVImport `yaml:"csv"`
+}
+
+// CSVImport формат CSV-манифеста
+type CSVImport struct {
+	// Comma разделитель полей, один символ
+	Comma string `yaml:"comma"`
+	// Columns заголовки колонок с полями посылки number, client, address и status;
+	// поле с пустым заголовком из манифеста не читается
+	Columns map[string]string `yaml:"columns"`
+}
+
+// Reports ежемесячные отчёты в xlsx и ежедневные отчёты о нарушении сроков доставки
+type Reports struct {
+	// Dir каталог, в который serve кладёт отчёты за прошедшие месяц и сутки;
+	// пустой каталог отключает формирование отчётов по расписанию
+	Dir string `yaml:"dir"`
+}
+
+// History хранение истории статусов
+type History struct {
+	// Retention сколько хранить историю доставленных посылок; задача history_pruning
+	// удаляет более старые записи. 0 — хранить всегда.
+	Retention time.Duration `yaml:"retention"`
+}
+
+// Overdue сроки, дольше которых посылка не должна оставаться в статусе; 0 — статус не проверяется
+type Overdue struct {
+	Registered time.Duration `yaml:"registered"`
+	Sent       time.Duration `yaml:"sent"`
+	// Alerts кому отправлять оповещения о застрявших посылках
+	Alerts []Alert `yaml:"alerts"`
+}
+
+// Режимы Duplicates.Mode
+const (
+	// DuplicatesWarn повторная регистрация возвращает уже зарегистрированную посылку
+	DuplicatesWarn = "warn"
+	// DuplicatesReject повторная регистрация отклоняется с ошибкой
+	DuplicatesReject = "reject"
+)
+
+// Duplicates поиск повторной регистрации, например повторной отправки формы нестабильным
+// клиентом: у клиента уже есть посылка на тот же адрес, зарегистрированная не раньше
+// Window назад
+type Duplicates struct {
+	// Window за какое время искать такую же посылку; 0 — не искать
+	Window time.Duration `yaml:"window"`
+	// Mode что делать с повтором: warn или reject
+	Mode string `yaml:"mode"`
+}
+
+// ETA оценка ожидаемого времени доставки по средним срокам уже доставленных посылок
+type ETA struct {
+	// Lookback за какое время брать доставленные посылки; 0 — не оценивать
+	Lookback time.Duration `yaml:"lookback"`
+	// MinSamples сколько доставленных посылок в город нужно, чтобы оценивать
+	// по ним, а не по средним срокам всех городов
+	MinSamples int `yaml:"min_samples"`
+	// Refresh как часто пересчитывать средние сроки
+	Refresh time.Duration `yaml:"refresh"`
+}
+
+// Alert получатель оповещений в канале уведомлений email или sms
+type Alert struct {
+	Channel   string `yaml:"channel"`
+	Recipient string `yaml:"recipient"`
+}
+
+// Режимы скрытия поля в Redaction
+const (
+	// RedactNone поле показывается как есть
+	RedactNone = "none"
+	// RedactMask поле скрывается частично: от адреса остаётся город, от почты — домен,
+	// от идентификатора клиента, телефона и чата — последние две цифры
+	RedactMask = "mask"
+	// RedactRemove поле скрывается целиком
+	RedactRemove = "remove"
+)
+
+// Redaction как скрывать персональные данные в логах, включая тексты ошибок,
+// и в публичном отслеживании; для каждого поля задаётся режим RedactNone,
+// RedactMask или RedactRemove
+type Redaction struct {
+	// Address адрес доставки
+	Address string `yaml:"address"`
+	// Client идентификатор клиента
+	Client string `yaml:"client"`
+	// Recipient получатель уведомлений: почта, телефон или чат Telegram
+	Recipient string `yaml:"recipient"`
+	// City город из адреса в публичном отслеживании по трекинг-токену
+	City string `yaml:"city"`
+	// CSV скрывать адрес и клиента и в выгрузке CSV
+	CSV bool `yaml:"csv"`
+}
+
+// Archive перенос давно доставленных посылок из parcel в таблицы архива
+type Archive struct {
+	// After через сколько после доставки посылка переносится в архив; 0 — не переносить
+	After time.Duration `yaml:"after"`
+	// BatchSize сколько посылок переносится одной транзакцией
+	BatchSize int `yaml:"batch_size"`
+	// Dir каталог, в файлы которого перенесённые посылки дописываются в NDJSON; пусто — не записывать
+	Dir string `yaml:"dir"`
+}
+
+// Backup снимки БД задачи backup и команды tracker backup create
+type Backup struct {
+	// Dir каталог снимков; если не задан и он, и s3.bucket, задача не запускается
+	Dir string `yaml:"dir"`
+	// Keep сколько последних снимков хранить в каталоге или бакете; 0 — хранить все
+	Keep int `yaml:"keep"`
+	// S3 бакет S3-совместимого хранилища, куда снимки отправляются вместо каталога
+	S3 S3 `yaml:"s3"`
+}
+
+// S3 бакет S3-совместимого хранилища; используется, если задан bucket
+type S3 struct {
+	// Endpoint адрес хранилища, например https://s3.eu-central-1.amazonaws.com или http://minio:9000
+	Endpoint string `yaml:"endpoint"`
+	Region   string `yaml:"region"`
+	Bucket   string `yaml:"bucket"`
+	// Prefix начало ключей снимков, например tracker/
+	Prefix          string `yaml:"prefix"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+}
+
+// Enabled сообщает, задано ли, куда класть снимки
+func (b Backup) Enabled() bool {
+	return b.Dir != "" || b.S3.Bucket != ""
+}
+
+// Attachments хранилище документов посылок: накладных, таможенных форм, фотографий
+type Attachments struct {
+	// Dir каталог документов; если не задан и он, и s3.bucket, вложения не принимаются
+	Dir string `yaml:"dir"`
+	// S3 бакет S3-совместимого хранилища, куда документы кладутся вместо каталога
+	S3 S3 `yaml:"s3"`
+	// MaxSize наибольший размер документа в байтах
+	MaxSize int64 `yaml:"max_size"`
+}
+
+// Enabled сообщает, задано ли, где хранить документы
+func (a Attachments) Enabled() bool {
+	return a.Dir != "" || a.S3.Bucket != ""
+}
+
+// Jobs настройки периодических задач по имени задачи
+type Jobs map[string]Job
+
+// Job настройки периодической задачи; незаданное значение оставляет значение задачи по умолчанию
+type Job struct {
+	// Enabled включает или выключает задачу
+	Enabled *bool `yaml:"enabled"`
+	// Interval пауза между запусками
+	Interval time.Duration `yaml:"interval"`
+	// Jitter наибольшая случайная добавка к паузе, чтобы задачи нескольких
+	// экземпляров трекера не запускались одновременно
+	Jitter time.Duration `yaml:"jitter"`
+}
+
+// Периодические задачи serve
+const (
+	// JobOutbox отправка событий outbox; по умолчанию раз в outbox.interval, если задан получатель
+	JobOutbox = "outbox"
+	// JobReports отчёт за прошедший месяц; по умолчанию раз в час, если задан reports.dir
+	JobReports = "reports"
+	// JobSLA отчёт о посылках, не доставленных в обещанный срок, за прошедшие сутки;
+	// по умолчанию раз в час, если задан reports.dir
+	JobSLA = "sla"
+	// JobHistoryPruning удаление старой истории доставленных посылок; по умолчанию
+	// раз в сутки, если задан history.retention
+	JobHistoryPruning = "history_pruning"
+	// JobOverdue поиск застрявших посылок и оповещения о них; по умолчанию раз в 15 минут,
+	// если задан overdue.registered или overdue.sent
+	JobOverdue = "overdue"
+	// JobArchive перенос доставленных посылок в архив; по умолчанию раз в сутки, если задан archive.after
+	JobArchive = "archive"
+	// JobBackup снимок БД; по умолчанию раз в сутки, если задан backup.dir или backup.s3.bucket
+	JobBackup = "backup"
+	// JobMaintenance проверка целостности, VACUUM и ANALYZE файла SQLite; по умолчанию
+	// выключена, включается в jobs.maintenance и тогда идёт раз в неделю
+	JobMaintenance = "maintenance"
+	// JobReencrypt шифрование открытых адресов и перешифровка адресов новым ключом после
+	// смены db.encryption.current; по умолчанию раз в час, если заданы ключи
+	JobReencrypt = "reencrypt"
+	// JobChangeLogPruning удаление старых записей журнала изменений change_log; по умолчанию
+	// раз в час, если задан db.changes.retention
+	JobChangeLogPruning = "change_log_pruning"
+	// JobAnalytics пересчёт дневных итогов по зонам в daily_stats; по умолчанию раз в час
+	JobAnalytics = "analytics"
+)
+
+// knownJobs задачи, для которых можно задать настройки в jobs
+var knownJobs = []string{JobOutbox, JobReports, JobSLA, JobHistoryPruning, JobOverdue, JobArchive, JobBackup, JobMaintenance, JobReencrypt, JobChangeLogPruning, JobAnalytics}
+
+// validate проверяет, что настройки заданы только для известных задач и без отрицательных значений
+func (j Jobs) validate() []error {
+	var errs []error
+	for name, job := range j {
+		if !slices.Contains(knownJobs, name) {
+			errs = append(errs, fmt.Errorf("jobs: неизвестная задача %q", name))
+		}
+		if job.Interval < 0 || job.Jitter < 0 {
+			errs = append(errs, fmt.Errorf("jobs.%s: interval и jitter не могут быть отрицательными", name))
+		}
+	}
+	return errs
+}
+
+// Labels транспортные этикетки посылок
+type Labels struct {
+	// Sender адрес отправителя, который печатается на этикетках
+	Sender string `yaml:"sender"`
+}
+
+// Auth настройки аутентификации
+type Auth struct {
+	RequireAPIKey bool `yaml:"require_api_key"`
+}
+
+// RateLimit ограничение частоты запросов одного пользователя; RPS = 0 — без ограничения
+type RateLimit struct {
+	RPS   float64 `yaml:"rps"`
+	Burst int     `yaml:"burst"`
+}
+
+// Features флаги функциональности по имени
+type Features map[string]bool
+
+// Enabled сообщает, включена ли функциональность name
+func (f Features) Enabled(name string) bool {
+	return f[name]
+}
+
+// Флаги функциональности HTTP-сервера
+const (
+	FeatureGraphQL   = "graphql"
+	FeatureWebSocket = "websocket"
+	FeatureEvents    = "events"
+	FeatureAdminUI   = "admin_ui"
+	// FeatureDebug включает pprof, /debug/store и запись планов запросов в лог
+	// при запуске; по умолчанию выключен
+	FeatureDebug = "debug"
+	// FeatureWebhooks доставка событий на вебхуки клиентов и API для их регистрации; по умолчанию выключен
+	FeatureWebhooks = "webhooks"
+)
+
+// Default возвращает настройки по умолчанию
+func Default() Config {
+	return Config{
+		DB: DB{
+			Driver: "sqlite",
+			Path:   "tracker.db",
+			SQLite: SQLite{
+				JournalMode: "WAL",
+				BusyTimeout: 5 * time.Second,
+				Synchronous: "NORMAL",
+				ForeignKeys: true,
+				TxLock:      "immediate",
+			},
+			Retry: Retry{
+				MaxAttempts: 5,
+				BaseDelay:   10 * time.Millisecond,
+				MaxDelay:    500 * time.Millisecond,
+			},
+			Pool: Pool{MaxOpenConns: 1, MaxIdleConns: 1},
+			// читателей SQLite может быть много, ограничение только на их число
+			Reader: Reader{Pool: Pool{MaxOpenConns: 4, MaxIdleConns: 4}},
+			Cache:  Cache{TTL: 30 * time.Second},
+			Batch:  Batch{Delay: 2 * time.Millisecond},
+
+			SlowQueryThreshold: 500 * time.Millisecond,
+			Encryption:         Encryption{BatchSize: 500},
+			Breaker:            Breaker{Cooldown: 30 * time.Second},
+			Timeouts:           Timeouts{Read: 10 * time.Second, Write: 30 * time.Second},
+			Monitor:            Monitor{Interval: 10 * time.Second, Failures: 3},
+			Changes:            Changes{Retention: 24 * time.Hour},
+		},
+		HTTP:      Server{Addr: ":8080"},
+		RateLimit: RateLimit{Burst: 10},
+		Tracing:   Tracing{ServiceName: "tracker", SampleRatio: 1},
+		Outbox: Outbox{
+			Interval:  time.Second,
+			BatchSize: 100,
+			NATS:      NATS{SubjectPrefix: "tracker"},
+			Kafka:     Kafka{Topic: "tracker.parcels"},
+		},
+		Scans: Scans{Subject: "tracker.scans", Queue: "tracker"},
+		Webhooks: Webhooks{
+			Retry:     Retry{MaxAttempts: 8, BaseDelay: 10 * time.Second, MaxDelay: time.Hour},
+			Timeout:   10 * time.Second,
+			Interval:  5 * time.Second,
+			BatchSize: 50,
+		},
+		Notify:      Notify{Timeout: 10 * time.Second},
+		Telegram:    Telegram{APIURL: "https://api.telegram.org", PollTimeout: 30 * time.Second},
+		Overdue:     Overdue{Registered: 72 * time.Hour, Sent: 14 * 24 * time.Hour},
+		Duplicates:  Duplicates{Mode: DuplicatesWarn},
+		ETA:         ETA{Lookback: 30 * 24 * time.Hour, MinSamples: 5, Refresh: time.Hour},
+		Archive:     Archive{BatchSize: 500},
+		Backup:      Backup{Keep: 7, S3: S3{Region: "us-east-1"}},
+		Attachments: Attachments{MaxSize: 10 << 20, S3: S3{Region: "us-east-1"}},
+		Redaction:   Redaction{Address: RedactMask, Client: RedactNone, Recipient: RedactMask, City: RedactNone},
+		Carriers:    Carriers{SyncInterval: 5 * time.Minute, BatchSize: 100, Timeout: 10 * time.Second},
+		Geocoder:    Geocoder{Timeout: 5 * time.Second},
+		Import: Import{CSV: CSVImport{
+			Comma:   ",",
+			Columns: map[string]string{"number": "number", "client": "client", "address": "address", "status": "status"},
+		}},
+		ShutdownTimeout: 10 * time.Second,
+		LogLevel:        "info",
+		LogFormat:       "text",
+		Features: Features{
+			FeatureGraphQL:   true,
+			FeatureWebSocket: true,
+			FeatureEvents:    true,
+			FeatureAdminUI:   true,
+		},
+	}
+}
+
+// Load возвращает настройки по умолчанию, дополненные YAML-файлом path
+// (если он указан) и переменными окружения TRACKER_*, которые важнее файла
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		err = yaml.Unmarshal(data, &cfg)
+		if err != nil {
+			return cfg, fmt.Errorf("разбор %s: %w", path, err)
+		}
+	}
+
+	err := cfg.applyEnv(os.LookupEnv)
+	if err != nil {
+		return cfg, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// applyEnv переопределяет настройки переменными окружения
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	env := func(name string) (string, bool) {
+		return lookup(EnvPrefix + name)
+	}
+
+	if v, ok := env("DB_DRIVER"); ok {
+		c.DB.Driver = v
+	}
+	if v, ok := env("DB_PATH"); ok {
+		c.DB.Path = v
+	}
+	if v, ok := env("DB_READER_PATH"); ok {
+		c.DB.Reader.Path = v
+	}
+	if v, ok := env("DB_CACHE_SIZE"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%sDB_CACHE_SIZE: %w", EnvPrefix, err)
+		}
+		c.DB.Cache.Size = n
+	}
+	if v, ok := env("DB_CACHE_REDIS_ADDR"); ok {
+		c.DB.Cache.RedisAddr = v
+	}
+	if v, ok := env("SQLITE_JOURNAL_MODE"); ok {
+		c.DB.SQLite.JournalMode = v
+	}
+	if v, ok := env("SQLITE_BUSY_TIMEOUT"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sSQLITE_BUSY_TIMEOUT: %w", EnvPrefix, err)
+		}
+		c.DB.SQLite.BusyTimeout = d
+	}
+	if v, ok := env("SQLITE_SYNCHRONOUS"); ok {
+		c.DB.SQLite.Synchronous = v
+	}
+	if v, ok := env("SQLITE_FOREIGN_KEYS"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%sSQLITE_FOREIGN_KEYS: %w", EnvPrefix, err)
+		}
+		c.DB.SQLite.ForeignKeys = b
+	}
+	if v, ok := env("SQLITE_TXLOCK"); ok {
+		c.DB.SQLite.TxLock = v
+	}
+	if v, ok := env("DB_RETRY_MAX_ATTEMPTS"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%sDB_RETRY_MAX_ATTEMPTS: %w", EnvPrefix, err)
+		}
+		c.DB.Retry.MaxAttempts = n
+	}
+	if v, ok := env("DB_BREAKER_FAILURES"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%sDB_BREAKER_FAILURES: %w", EnvPrefix, err)
+		}
+		c.DB.Breaker.Failures = n
+	}
+	if v, ok := env("DB_BREAKER_COOLDOWN"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sDB_BREAKER_COOLDOWN: %w", EnvPrefix, err)
+		}
+		c.DB.Breaker.Cooldown = d
+	}
+	if v, ok := env("DB_READ_TIMEOUT"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sDB_READ_TIMEOUT: %w", EnvPrefix, err)
+		}
+		c.DB.Timeouts.Read = d
+	}
+	if v, ok := env("DB_WRITE_TIMEOUT"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sDB_WRITE_TIMEOUT: %w", EnvPrefix, err)
+		}
+		c.DB.Timeouts.Write = d
+	}
+	if v, ok := env("DB_MONITOR_INTERVAL"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sDB_MONITOR_INTERVAL: %w", EnvPrefix, err)
+		}
+		c.DB.Monitor.Interval = d
+	}
+	if v, ok := env("DB_STANDBY_PATH"); ok {
+		c.DB.Monitor.Standby = v
+	}
+	if v, ok := env("DB_CHANGES_INTERVAL"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sDB_CHANGES_INTERVAL: %w", EnvPrefix, err)
+		}
+		c.DB.Changes.Interval = d
+	}
+	if v, ok := env("DB_BATCH_SIZE"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%sDB_BATCH_SIZE: %w", EnvPrefix, err)
+		}
+		c.DB.Batch.Size = n
+	}
+	if v, ok := env("DB_ENCRYPTION_KEYS"); ok {
+		c.DB.Encryption.Keys = map[string]string{}
+		for _, pair := range strings.Split(v, ",") {
+			id, key, ok := strings.Cut(pair, ":")
+			if !ok {
+				return fmt.Errorf("%sDB_ENCRYPTION_KEYS: нужны пары <идентификатор>:<ключ>, а не %q", EnvPrefix, pair)
+			}
+			c.DB.Encryption.Keys[id] = key
+		}
+	}
+	if v, ok := env("DB_ENCRYPTION_CURRENT"); ok {
+		c.DB.Encryption.Current = v
+	}
+	if v, ok := env("DB_SHARDS"); ok {
+		c.DB.Shards = nil
+		for _, item := range strings.Split(v, ",") {
+			parts := strings.SplitN(item, ":", 3)
+			if len(parts) != 3 {
+				return fmt.Errorf("%sDB_SHARDS: нужны тройки <депо>:<префикс>:<путь>, а не %q", EnvPrefix, item)
+			}
+			prefix, err := strconv.Atoi(parts[1])
+			if err != nil {
+				return fmt.Errorf("%sDB_SHARDS: %w", EnvPrefix, err)
+			}
+			c.DB.Shards = append(c.DB.Shards, Shard{Depot: parts[0], Prefix: prefix, Path: parts[2]})
+		}
+	}
+	if v, ok := env("DB_SLOW_QUERY_THRESHOLD"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sDB_SLOW_QUERY_THRESHOLD: %w", EnvPrefix, err)
+		}
+		c.DB.SlowQueryThreshold = d
+	}
+	if v, ok := env("DB_MAX_OPEN_CONNS"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%sDB_MAX_OPEN_CONNS: %w", EnvPrefix, err)
+		}
+		c.DB.Pool.MaxOpenConns = n
+	}
+	if v, ok := env("DB_MAX_IDLE_CONNS"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%sDB_MAX_IDLE_CONNS: %w", EnvPrefix, err)
+		}
+		c.DB.Pool.MaxIdleConns = n
+	}
+	if v, ok := env("HTTP_ADDR"); ok {
+		c.HTTP.Addr = v
+	}
+	if v, ok := env("GRPC_ADDR"); ok {
+		c.GRPC.Addr = v
+	}
+	if v, ok := env("TRACING_ENDPOINT"); ok {
+		c.Tracing.Endpoint = v
+	}
+	if v, ok := env("TRACING_SAMPLE_RATIO"); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%sTRACING_SAMPLE_RATIO: %w", EnvPrefix, err)
+		}
+		c.Tracing.SampleRatio = f
+	}
+	if v, ok := env("OUTBOX_PUBLISHER"); ok {
+		c.Outbox.Publisher = v
+	}
+	if v, ok := env("OUTBOX_NATS_URL"); ok {
+		c.Outbox.NATS.URL = v
+	}
+	if v, ok := env("OUTBOX_KAFKA_BROKERS"); ok {
+		c.Outbox.Kafka.Brokers = strings.Split(v, ",")
+	}
+	if v, ok := env("OUTBOX_KAFKA_TOPIC"); ok {
+		c.Outbox.Kafka.Topic = v
+	}
+	if v, ok := env("SCANS_NATS_URL"); ok {
+		c.Scans.NATSURL = v
+	}
+	if v, ok := env("NOTIFY_SMTP_ADDR"); ok {
+		c.Notify.SMTP.Addr = v
+	}
+	if v, ok := env("NOTIFY_SMTP_PASSWORD"); ok {
+		c.Notify.SMTP.Password = v
+	}
+	if v, ok := env("NOTIFY_SMS_URL"); ok {
+		c.Notify.SMS.URL = v
+	}
+	if v, ok := env("NOTIFY_SMS_TOKEN"); ok {
+		c.Notify.SMS.Token = v
+	}
+	if v, ok := env("TELEGRAM_TOKEN"); ok {
+		c.Telegram.Token = v
+	}
+	if v, ok := env("GEOCODER_URL"); ok {
+		c.Geocoder.URL = v
+	}
+	if v, ok := env("GEOCODER_TOKEN"); ok {
+		c.Geocoder.Token = v
+	}
+	if v, ok := env("REPORTS_DIR"); ok {
+		c.Reports.Dir = v
+	}
+	if v, ok := env("HISTORY_RETENTION"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sHISTORY_RETENTION: %w", EnvPrefix, err)
+		}
+		c.History.Retention = d
+	}
+	if v, ok := env("OVERDUE_REGISTERED"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sOVERDUE_REGISTERED: %w", EnvPrefix, err)
+		}
+		c.Overdue.Registered = d
+	}
+	if v, ok := env("OVERDUE_SENT"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sOVERDUE_SENT: %w", EnvPrefix, err)
+		}
+		c.Overdue.Sent = d
+	}
+	if v, ok := env("DUPLICATES_WINDOW"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sDUPLICATES_WINDOW: %w", EnvPrefix, err)
+		}
+		c.Duplicates.Window = d
+	}
+	if v, ok := env("DUPLICATES_MODE"); ok {
+		c.Duplicates.Mode = v
+	}
+	if v, ok := env("ETA_LOOKBACK"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sETA_LOOKBACK: %w", EnvPrefix, err)
+		}
+		c.ETA.Lookback = d
+	}
+	if v, ok := env("ARCHIVE_AFTER"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sARCHIVE_AFTER: %w", EnvPrefix, err)
+		}
+		c.Archive.After = d
+	}
+	if v, ok := env("ARCHIVE_DIR"); ok {
+		c.Archive.Dir = v
+	}
+	if v, ok := env("BACKUP_DIR"); ok {
+		c.Backup.Dir = v
+	}
+	if v, ok := env("BACKUP_S3_ENDPOINT"); ok {
+		c.Backup.S3.Endpoint = v
+	}
+	if v, ok := env("BACKUP_S3_REGION"); ok {
+		c.Backup.S3.Region = v
+	}
+	if v, ok := env("BACKUP_S3_BUCKET"); ok {
+		c.Backup.S3.Bucket = v
+	}
+	if v, ok := env("BACKUP_S3_ACCESS_KEY_ID"); ok {
+		c.Backup.S3.AccessKeyID = v
+	}
+	if v, ok := env("BACKUP_S3_SECRET_ACCESS_KEY"); ok {
+		c.Backup.S3.SecretAccessKey = v
+	}
+	if v, ok := env("ATTACHMENTS_DIR"); ok {
+		c.Attachments.Dir = v
+	}
+	if v, ok := env("ATTACHMENTS_S3_ENDPOINT"); ok {
+		c.Attachments.S3.Endpoint = v
+	}
+	if v, ok := env("ATTACHMENTS_S3_REGION"); ok {
+		c.Attachments.S3.Region = v
+	}
+	if v, ok := env("ATTACHMENTS_S3_BUCKET"); ok {
+		c.Attachments.S3.Bucket = v
+	}
+	if v, ok := env("ATTACHMENTS_S3_ACCESS_KEY_ID"); ok {
+		c.Attachments.S3.AccessKeyID = v
+	}
+	if v, ok := env("ATTACHMENTS_S3_SECRET_ACCESS_KEY"); ok {
+		c.Attachments.S3.SecretAccessKey = v
+	}
+	if v, ok := env("ATTACHMENTS_MAX_SIZE"); ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%sATTACHMENTS_MAX_SIZE: %w", EnvPrefix, err)
+		}
+		c.Attachments.MaxSize = n
+	}
+	if v, ok := env("REDACTION_ADDRESS"); ok {
+		c.Redaction.Address = v
+	}
+	if v, ok := env("REDACTION_CLIENT"); ok {
+		c.Redaction.Client = v
+	}
+	if v, ok := env("REDACTION_RECIPIENT"); ok {
+		c.Redaction.Recipient = v
+	}
+	if v, ok := env("REDACTION_CITY"); ok {
+		c.Redaction.City = v
+	}
+	if v, ok := env("PUBLIC_URL"); ok {
+		c.PublicURL = v
+	}
+	if v, ok := env("READ_ONLY"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%sREAD_ONLY: %w", EnvPrefix, err)
+		}
+		c.ReadOnly = b
+	}
+	if v, ok := env("SHUTDOWN_TIMEOUT"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%sSHUTDOWN_TIMEOUT: %w", EnvPrefix, err)
+		}
+		c.ShutdownTimeout = d
+	}
+	if v, ok := env("LOG_LEVEL"); ok {
+		c.LogLevel = v
+	}
+	if v, ok := env("LOG_FORMAT"); ok {
+		c.LogFormat = v
+	}
+	if v, ok := env("REQUIRE_API_KEY"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%sREQUIRE_API_KEY: %w", EnvPrefix, err)
+		}
+		c.Auth.RequireAPIKey = b
+	}
+	if v, ok := env("RATE_LIMIT_RPS"); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%sRATE_LIMIT_RPS: %w", EnvPrefix, err)
+		}
+		c.RateLimit.RPS = f
+	}
+	if v, ok := env("RATE_LIMIT_BURST"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%sRATE_LIMIT_BURST: %w", EnvPrefix, err)
+		}
+		c.RateLimit.Burst = n
+	}
+	// TRACKER_FEATURES: список через запятую, «-» перед именем выключает флаг, например "graphql,-admin_ui"
+	if v, ok := env("FEATURES"); ok {
+		if c.Features == nil {
+			c.Features = Features{}
+		}
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			enabled := !strings.HasPrefix(name, "-")
+			c.Features[strings.TrimPrefix(name, "-")] = enabled
+		}
+	}
+
+	return nil
+}
+
+// Validate проверяет согласованность настроек
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.DB.Driver == "" {
+		errs = append(errs, errors.New("не указан драйвер БД"))
+	}
+	if c.DB.Path == "" {
+		errs = append(errs, errors.New("не указан путь к БД"))
+	}
+	errs = append(errs, c.DB.SQLite.validate()...)
+	if c.DB.Retry.MaxAttempts < 1 {
+		errs = append(errs, errors.New("db.retry.max_attempts должен быть не меньше 1"))
+	}
+	if c.DB.Retry.BaseDelay < 0 || c.DB.Retry.MaxDelay < c.DB.Retry.BaseDelay {
+		errs = append(errs, errors.New("db.retry: задержки должны удовлетворять 0 ≤ base_delay ≤ max_delay"))
+	}
+	if c.DB.Batch.Size < 0 || (c.DB.Batch.Size > 1 && c.DB.Batch.Delay <= 0) {
+		errs = append(errs, errors.New("db.batch: size не может быть отрицательным, delay должен быть положительным"))
+	}
+	if b := c.DB.Breaker; b.Failures < 0 || (b.Failures > 0 && b.Cooldown <= 0) {
+		errs = append(errs, errors.New("db.breaker: failures не может быть отрицательным, cooldown должен быть положительным"))
+	}
+	if c.DB.Timeouts.Read < 0 || c.DB.Timeouts.Write < 0 {
+		errs = append(errs, errors.New("db.timeouts: read и write не могут быть отрицательными"))
+	}
+	// ожидание блокировки внутри SQLite контекст не прерывает, его ограничивает только busy_timeout
+	if w := c.DB.Timeouts.Write; c.DB.Driver == "sqlite" && w > 0 && w < c.DB.SQLite.BusyTimeout {
+		errs = append(errs, errors.New("db.timeouts.write должен быть не меньше db.sqlite.busy_timeout"))
+	}
+	if m := c.DB.Monitor; m.Interval < 0 || (m.Interval > 0 && m.Failures < 1) {
+		errs = append(errs, errors.New("db.monitor: interval не может быть отрицательным, failures должен быть не меньше 1"))
+	}
+	if m := c.DB.Monitor; m.Standby != "" && (m.Interval == 0 || m.Standby == c.DB.Path) {
+		errs = append(errs, errors.New("db.monitor.standby: нужен включённый монитор и путь, отличный от db.path"))
+	}
+	if ch := c.DB.Changes; ch.Interval < 0 || ch.Retention < 0 {
+		errs = append(errs, errors.New("db.changes: interval и retention не могут быть отрицательными"))
+	}
+	// запись, удалённая до того, как её прочитали, для обработчиков потеряна
+	if ch := c.DB.Changes; ch.Interval > 0 && ch.Retention > 0 && ch.Retention <= ch.Interval {
+		errs = append(errs, errors.New("db.changes.retention должен быть больше db.changes.interval"))
+	}
+	if c.DB.SlowQueryThreshold < 0 {
+		errs = append(errs, errors.New("db.slow_query_threshold не может быть отрицательным"))
+	}
+	if c.DB.Pool.MaxOpenConns < 0 || c.DB.Pool.MaxIdleConns < 0 || c.DB.Pool.ConnMaxLifetime < 0 || c.DB.Pool.ConnMaxIdleTime < 0 {
+		errs = append(errs, errors.New("db.pool: значения не могут быть отрицательными"))
+	}
+	if r := c.DB.Reader.Pool; r.MaxOpenConns < 0 || r.MaxIdleConns < 0 || r.ConnMaxLifetime < 0 || r.ConnMaxIdleTime < 0 {
+		errs = append(errs, errors.New("db.reader.pool: значения не могут быть отрицательными"))
+	}
+	if c.DB.Cache.Size < 0 || c.DB.Cache.TTL < 0 {
+		errs = append(errs, errors.New("db.cache: значения не могут быть отрицательными"))
+	}
+	if c.DB.Cache.RedisAddr != "" && c.DB.Cache.TTL == 0 {
+		errs = append(errs, errors.New("db.cache.ttl обязателен для кеша в Redis: без него запись, которую не удалось сбросить, не устареет"))
+	}
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("неизвестный уровень логирования %q", c.LogLevel))
+	}
+	switch c.LogFormat {
+	case "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("неизвестный формат логов %q", c.LogFormat))
+	}
+	for _, f := range []struct{ name, mode string }{{"address", c.Redaction.Address}, {"client", c.Redaction.Client},
+		{"recipient", c.Redaction.Recipient}, {"city", c.Redaction.City}} {
+		switch f.mode {
+		case RedactNone, RedactMask, RedactRemove:
+		default:
+			errs = append(errs, fmt.Errorf("redaction.%s: неизвестный режим %q, нужен none, mask или remove", f.name, f.mode))
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio должен быть от 0 до 1"))
+	}
+	switch c.Outbox.Publisher {
+	case "", "log":
+	case "nats":
+		if c.Outbox.NATS.URL == "" || c.Outbox.NATS.SubjectPrefix == "" {
+			errs = append(errs, errors.New("outbox.nats: нужны url и subject_prefix"))
+		}
+	case "kafka":
+		if len(c.Outbox.Kafka.Brokers) == 0 || c.Outbox.Kafka.Topic == "" {
+			errs = append(errs, errors.New("outbox.kafka: нужны brokers и topic"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("неизвестный получатель событий outbox %q", c.Outbox.Publisher))
+	}
+	if c.Scans.NATSURL != "" && c.Scans.Subject == "" {
+		errs = append(errs, errors.New("scans.subject обязателен при заданном scans.nats_url"))
+	}
+	if w := c.Webhooks; w.Retry.MaxAttempts < 1 || w.Timeout <= 0 || w.Interval <= 0 || w.BatchSize < 1 {
+		errs = append(errs, errors.New("webhooks: retry.max_attempts и batch_size не меньше 1, timeout и interval положительные"))
+	}
+	if c.Notify.SMTP.Addr != "" && c.Notify.SMTP.From == "" {
+		errs = append(errs, errors.New("notify.smtp.from обязателен при заданном notify.smtp.addr"))
+	}
+	if (c.Notify.SMTP.Addr != "" || c.Notify.SMS.URL != "") && c.Notify.Timeout <= 0 {
+		errs = append(errs, errors.New("notify.timeout должен быть положительным"))
+	}
+	if c.Telegram.Token != "" && (c.Telegram.APIURL == "" || c.Telegram.PollTimeout < time.Second) {
+		errs = append(errs, errors.New("telegram: нужен api_url, poll_timeout не меньше секунды"))
+	}
+	errs = append(errs, c.Carriers.validate()...)
+	if c.Geocoder.URL != "" && c.Geocoder.Timeout <= 0 {
+		errs = append(errs, errors.New("geocoder.timeout должен быть положительным"))
+	}
+	errs = append(errs, c.Import.CSV.validate()...)
+	if u, err := url.Parse(c.PublicURL); c.PublicURL != "" && (err != nil || u.Scheme == "" || u.Host == "") {
+		errs = append(errs, fmt.Errorf("public_url должен быть абсолютным адресом, а не %q", c.PublicURL))
+	}
+	if c.Overdue.Registered < 0 || c.Overdue.Sent < 0 {
+		errs = append(errs, errors.New("overdue: сроки не могут быть отрицательными"))
+	}
+	for _, a := range c.Overdue.Alerts {
+		if (a.Channel != "email" && a.Channel != "sms") || a.Recipient == "" {
+			errs = append(errs, fmt.Errorf("overdue.alerts: нужен канал email или sms и получатель, а не %q %q", a.Channel, a.Recipient))
+		}
+	}
+	if c.Duplicates.Window < 0 {
+		errs = append(errs, errors.New("duplicates.window не может быть отрицательным"))
+	}
+	switch c.Duplicates.Mode {
+	case DuplicatesWarn, DuplicatesReject:
+	default:
+		errs = append(errs, fmt.Errorf("duplicates.mode: неизвестный режим %q, нужен warn или reject", c.Duplicates.Mode))
+	}
+	if c.ETA.Lookback < 0 || c.ETA.MinSamples < 0 || (c.ETA.Lookback > 0 && c.ETA.Refresh <= 0) {
+		errs = append(errs, errors.New("eta: lookback и min_samples не могут быть отрицательными, refresh должен быть положительным"))
+	}
+	if c.Archive.After < 0 || c.Archive.BatchSize < 1 {
+		errs = append(errs, errors.New("archive: after не может быть отрицательным, batch_size не меньше 1"))
+	}
+	if j, ok := c.Jobs[JobArchive]; ok && j.Enabled != nil && *j.Enabled && c.Archive.After == 0 {
+		errs = append(errs, errors.New("jobs.archive: задаче нужен archive.after"))
+	}
+	if c.Backup.Keep < 0 {
+		errs = append(errs, errors.New("backup.keep не может быть отрицательным"))
+	}
+	if j, ok := c.Jobs[JobBackup]; ok && j.Enabled != nil && *j.Enabled && !c.Backup.Enabled() {
+		errs = append(errs, errors.New("jobs.backup: задаче нужен backup.dir или backup.s3.bucket"))
+	}
+	if e := c.DB.Encryption; len(e.Keys) > 0 {
+		if _, ok := e.Keys[e.Current]; !ok {
+			errs = append(errs, fmt.Errorf("db.encryption.current: нет ключа %q в db.encryption.keys", e.Current))
+		}
+		if e.BatchSize < 1 {
+			errs = append(errs, errors.New("db.encryption.batch_size должен быть не меньше 1"))
+		}
+	}
+	errs = append(errs, validateShards(c.DB)...)
+	if j, ok := c.Jobs[JobReencrypt]; ok && j.Enabled != nil && *j.Enabled && len(c.DB.Encryption.Keys) == 0 {
+		errs = append(errs, errors.New("jobs.reencrypt: задаче нужны db.encryption.keys"))
+	}
+	if j, ok := c.Jobs[JobMaintenance]; ok && j.Enabled != nil && *j.Enabled && c.DB.Driver != "sqlite" {
+		errs = append(errs, errors.New("jobs.maintenance: обслуживание работает только с SQLite"))
+	}
+	if s3 := c.Backup.S3; s3.Bucket != "" {
+		if c.Backup.Dir != "" {
+			errs = append(errs, errors.New("backup: задайте dir или s3.bucket, но не оба"))
+		}
+		if s3.Endpoint == "" || s3.Region == "" || s3.AccessKeyID == "" || s3.SecretAccessKey == "" {
+			errs = append(errs, errors.New("backup.s3: нужны endpoint, region, access_key_id и secret_access_key"))
+		}
+	}
+	if s3 := c.Attachments.S3; s3.Bucket != "" {
+		if c.Attachments.Dir != "" {
+			errs = append(errs, errors.New("attachments: задайте dir или s3.bucket, но не оба"))
+		}
+		if s3.Endpoint == "" || s3.Region == "" || s3.AccessKeyID == "" || s3.SecretAccessKey == "" {
+			errs = append(errs, errors.New("attachments.s3: нужны endpoint, region, access_key_id и secret_access_key"))
+		}
+	}
+	if c.Attachments.MaxSize < 1 {
+		errs = append(errs, errors.New("attachments.max_size должен быть положительным"))
+	}
+	if c.History.Retention < 0 {
+		errs = append(errs, errors.New("history.retention не может быть отрицательным"))
+	}
+	errs = append(errs, c.Jobs.validate()...)
+	if j, ok := c.Jobs[JobHistoryPruning]; ok && j.Enabled != nil && *j.Enabled && c.History.Retention == 0 {
+		errs = append(errs, errors.New("jobs.history_pruning: задаче нужен history.retention"))
+	}
+	if j, ok := c.Jobs[JobChangeLogPruning]; ok && j.Enabled != nil && *j.Enabled && c.DB.Changes.Retention == 0 {
+		errs = append(errs, errors.New("jobs.change_log_pruning: задаче нужен db.changes.retention"))
+	}
+	if c.Outbox.Interval <= 0 || c.Outbox.BatchSize < 1 {
+		errs = append(errs, errors.New("outbox: interval должен быть положительным, batch_size не меньше 1"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout должен быть положительным"))
+	}
+	if c.RateLimit.RPS < 0 {
+		errs = append(errs, errors.New("rate_limit.rps не может быть отрицательным"))
+	}
+	if c.RateLimit.RPS > 0 && c.RateLimit.Burst < 1 {
+		errs = append(errs, errors.New("rate_limit.burst должен быть не меньше 1"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate проверяет перевозчиков: имена уникальны, у каждого есть url,
+// а статусы перевозчика переводятся в статусы трекера
+func (c Carriers) validate() []error {
+	if len(c.List) == 0 {
+		return nil
+	}
+
+	var errs []error
+	if c.SyncInterval <= 0 || c.BatchSize < 1 || c.Timeout <= 0 {
+		errs = append(errs, errors.New("carriers: sync_interval и timeout должны быть положительными, batch_size не меньше 1"))
+	}
+	names := map[string]bool{}
+	for _, carrier := range c.List {
+		if carrier.Name == "" || carrier.URL == "" {
+			errs = append(errs, errors.New("carriers: у перевозчика нужны name и url"))
+		}
+		if names[carrier.Name] {
+			errs = append(errs, fmt.Errorf("carriers: перевозчик %q задан дважды", carrier.Name))
+		}
+		names[carrier.Name] = true
+		for external, status := range carrier.Statuses {
+			switch status {
+			case "registered", "sent", "delivered":
+			default:
+				errs = append(errs, fmt.Errorf("carriers: статус %q перевозчика %q переводится в неизвестный статус %q", external, carrier.Name, status))
+			}
+		}
+	}
+	return errs
+}
+
+// validate проверяет формат CSV-манифеста: разделитель из одного символа
+// и колонки только для известных полей посылки
+func (c CSVImport) validate() []error {
+	var errs []error
+	if len([]rune(c.Comma)) != 1 || c.Comma == "\n" || c.Comma == "\"" {
+		errs = append(errs, fmt.Errorf("import.csv.comma должен быть одним символом, а не %q", c.Comma))
+	}
+	for field := range c.Columns {
+		switch field {
+		case "number", "client", "address", "status":
+		default:
+			errs = append(errs, fmt.Errorf("import.csv.columns: неизвестное поле посылки %q", field))
+		}
+	}
+	return errs
+}
+
+// validate проверяет значения параметров SQLite; пустое значение оставляет умолчание SQLite
+func (s SQLite) validate() []error {
+	var errs []error
+
+	oneOf := func(name, value string, allowed ...string) {
+		if value == "" {
+			return
+		}
+		for _, a := range allowed {
+			if strings.EqualFold(value, a) {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("недопустимое значение %s %q", name, value))
+	}
+	oneOf("db.sqlite.journal_mode", s.JournalMode, "DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF")
+	oneOf("db.sqlite.synchronous", s.Synchronous, "OFF", "NORMAL", "FULL", "EXTRA")
+	oneOf("db.sqlite.txlock", s.TxLock, "deferred", "immediate", "exclusive")
+	if s.BusyTimeout < 0 {
+		errs = append(errs, errors.New("db.sqlite.busy_timeout не может быть отрицательным"))
+	}
+
+	return errs
+}
+
+// validateShards проверяет БД депо: коды, префиксы и файлы не должны повторяться
+func validateShards(db DB) []error {
+	if len(db.Shards) == 0 {
+		return nil
+	}
+	var errs []error
+	if db.Driver != "sqlite" {
+		errs = append(errs, errors.New("db.shards: БД депо работают только с SQLite"))
+	}
+	depots := map[string]bool{}
+	prefixes := map[int]bool{}
+	paths := map[string]bool{db.Path: true}
+	for i, sh := range db.Shards {
+		if sh.Depot == "" || depots[sh.Depot] {
+			errs = append(errs, fmt.Errorf("db.shards[%d]: код депо пустой или повторяется", i))
+		}
+		if sh.Prefix < 1 || sh.Prefix > 9_999 || prefixes[sh.Prefix] {
+			errs = append(errs, fmt.Errorf("db.shards[%d]: префикс от 1 до 9999 не должен повторяться", i))
+		}
+		if sh.Path == "" || paths[sh.Path] {
+			errs = append(errs, fmt.Errorf("db.shards[%d]: путь пустой или совпадает с другой БД", i))
+		}
+		depots[sh.Depot], prefixes[sh.Prefix], paths[sh.Path] = true, true, true
+	}
+	return errs
+}
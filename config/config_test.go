@@ -0,0 +1,162 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoad проверяет порядок применения настроек: значения по умолчанию, файл, окружение
+func TestLoad(t *testing.T) {
+	// prepare
+	path := filepath.Join(t.TempDir(), "tracker.yaml")
+	err := os.WriteFile(path, []byte(`
+db:
+  path: /var/lib/tracker.db
+http:
+  addr: ":9090"
+log_level: debug
+features:
+  admin_ui: false
+history:
+  retention: 720h
+jobs:
+  reports:
+    interval: 30m
+  history_pruning:
+    enabled: true
+`), 0o600)
+	require.NoError(t, err)
+
+	t.Setenv("TRACKER_HTTP_ADDR", ":7070")
+	t.Setenv("TRACKER_FEATURES", "-graphql,beta")
+
+	// load
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	// check
+	// драйвер не переопределён и остаётся по умолчанию
+	assert.Equal(t, "sqlite", cfg.DB.Driver)
+	assert.Equal(t, "/var/lib/tracker.db", cfg.DB.Path)
+	// окружение важнее файла
+	assert.Equal(t, ":7070", cfg.HTTP.Addr)
+	assert.Equal(t, "debug", cfg.LogLevel)
+
+	assert.False(t, cfg.Features.Enabled(FeatureAdminUI))
+	assert.False(t, cfg.Features.Enabled(FeatureGraphQL))
+	assert.True(t, cfg.Features.Enabled(FeatureEvents))
+	assert.True(t, cfg.Features.Enabled("beta"))
+
+	assert.Equal(t, 720*time.Hour, cfg.History.Retention)
+	assert.Equal(t, 30*time.Minute, cfg.Jobs[JobReports].Interval)
+	assert.Nil(t, cfg.Jobs[JobReports].Enabled)
+	require.NotNil(t, cfg.Jobs[JobHistoryPruning].Enabled)
+	assert.True(t, *cfg.Jobs[JobHistoryPruning].Enabled)
+}
+
+// TestLoadInvalid проверяет отклонение некорректных настроек
+func TestLoadInvalid(t *testing.T) {
+	t.Setenv("TRACKER_LOG_LEVEL", "verbose")
+	_, err := Load("")
+	assert.Error(t, err)
+
+	t.Setenv("TRACKER_LOG_LEVEL", "info")
+	t.Setenv("TRACKER_RATE_LIMIT_RPS", "fast")
+	_, err = Load("")
+	assert.Error(t, err)
+
+	// настройки неизвестной задачи и удаление истории без срока хранения
+	cfg := Default()
+	cfg.Jobs = Jobs{"cleanup": {}}
+	assert.Error(t, cfg.Validate())
+	enabled := true
+	cfg.Jobs = Jobs{JobHistoryPruning: {Enabled: &enabled}}
+	assert.Error(t, cfg.Validate())
+	cfg.History.Retention = time.Hour
+	assert.NoError(t, cfg.Validate())
+	cfg.Jobs = Jobs{JobArchive: {Enabled: &enabled}}
+	assert.Error(t, cfg.Validate())
+	cfg.Archive.After = 30 * 24 * time.Hour
+	assert.NoError(t, cfg.Validate())
+	cfg.Jobs = Jobs{JobBackup: {Enabled: &enabled}}
+	assert.Error(t, cfg.Validate())
+	cfg.Backup.Dir = "backups"
+	assert.NoError(t, cfg.Validate())
+	// бакет S3 вместо каталога и без ключей доступа
+	cfg.Backup.S3.Bucket = "backups"
+	assert.Error(t, cfg.Validate())
+	cfg.Backup.Dir = ""
+	assert.Error(t, cfg.Validate())
+	cfg.Backup.S3 = S3{Endpoint: "http://minio:9000", Region: "us-east-1", Bucket: "backups", AccessKeyID: "key", SecretAccessKey: "secret"}
+	assert.NoError(t, cfg.Validate())
+	// документы посылок: размер положительный, бакет с ключами доступа
+	cfg.Attachments.MaxSize = 0
+	assert.Error(t, cfg.Validate())
+	cfg.Attachments.MaxSize = 1 << 20
+	cfg.Attachments.S3.Bucket = "attachments"
+	assert.Error(t, cfg.Validate())
+	cfg.Attachments.S3 = S3{Endpoint: "http://minio:9000", Region: "us-east-1", Bucket: "attachments", AccessKeyID: "key", SecretAccessKey: "secret"}
+	assert.NoError(t, cfg.Validate())
+	// перешифровке нужны ключи, а текущий ключ должен быть среди них
+	cfg.Jobs = Jobs{JobReencrypt: {Enabled: &enabled}}
+	assert.Error(t, cfg.Validate())
+	cfg.DB.Encryption.Keys = map[string]string{"k1": "key"}
+	cfg.DB.Encryption.Current = "k2"
+	assert.Error(t, cfg.Validate())
+	cfg.DB.Encryption.Current = "k1"
+	assert.NoError(t, cfg.Validate())
+	cfg.Redaction.Client = "hash"
+	assert.Error(t, cfg.Validate())
+	cfg.Redaction.Client = RedactRemove
+	assert.NoError(t, cfg.Validate())
+	cfg.Duplicates.Mode = "merge"
+	assert.Error(t, cfg.Validate())
+	cfg.Duplicates.Mode = DuplicatesReject
+	assert.NoError(t, cfg.Validate())
+	cfg.ETA.Refresh = 0
+	assert.Error(t, cfg.Validate())
+	cfg.ETA.Lookback = 0
+	assert.NoError(t, cfg.Validate())
+	// размыкателю нужно время до пробного запроса
+	cfg.DB.Breaker = Breaker{Failures: 5}
+	assert.Error(t, cfg.Validate())
+	cfg.DB.Breaker.Cooldown = 30 * time.Second
+	assert.NoError(t, cfg.Validate())
+	cfg.DB.Timeouts.Write = -time.Second
+	assert.Error(t, cfg.Validate())
+	// запись ждёт блокировку SQLite не меньше busy_timeout
+	cfg.DB.Timeouts.Write = time.Second
+	assert.Error(t, cfg.Validate())
+	cfg.DB.Timeouts.Write = 0
+	assert.NoError(t, cfg.Validate())
+	// на резервную БД переключает монитор
+	cfg.DB.Monitor = Monitor{Standby: "standby.db"}
+	assert.Error(t, cfg.Validate())
+	cfg.DB.Monitor = Monitor{Interval: time.Second, Failures: 3, Standby: cfg.DB.Path}
+	assert.Error(t, cfg.Validate())
+	cfg.DB.Monitor.Standby = "standby.db"
+	assert.NoError(t, cfg.Validate())
+	// записи журнала изменений живут дольше одного интервала чтения
+	cfg.DB.Changes = Changes{Interval: time.Hour, Retention: time.Hour}
+	assert.Error(t, cfg.Validate())
+	cfg.DB.Changes.Retention = 24 * time.Hour
+	assert.NoError(t, cfg.Validate())
+	// префиксы и файлы депо не повторяются
+	cfg.DB.Shards = []Shard{{Depot: "msk", Prefix: 1, Path: "msk.db"}, {Depot: "spb", Prefix: 1, Path: "spb.db"}}
+	assert.Error(t, cfg.Validate())
+	cfg.DB.Shards[1].Prefix = 2
+	assert.NoError(t, cfg.Validate())
+	cfg.DB.Shards[1].Path = cfg.DB.Path
+	assert.Error(t, cfg.Validate())
+	cfg.DB.Shards = nil
+	// обслуживание только для SQLite
+	cfg.Jobs = Jobs{JobMaintenance: {Enabled: &enabled}}
+	assert.NoError(t, cfg.Validate())
+	cfg.DB.Driver = "pgx"
+	assert.Error(t, cfg.Validate())
+}
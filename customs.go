@@ -0,0 +1,260 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+// Статусы таможенного оформления посылки
+const (
+	// CustomsPending декларация подана, таможня её ещё не проверила
+	CustomsPending = "pending"
+	// CustomsHeld посылку задержала таможня; сама посылка переходит в статус customs_hold
+	CustomsHeld = "held"
+	// CustomsCleared таможня выпустила посылку; задержанная посылка снова в пути
+	CustomsCleared = "cleared"
+)
+
+// maxCustomsContents наибольшая длина описания содержимого в символах
+const maxCustomsContents = 200
+
+var (
+	// ErrNoCustoms возвращается при смене таможенного статуса посылки без декларации
+	ErrNoCustoms = errors.New("у посылки нет таможенной декларации")
+	// ErrInvalidCustoms возвращается при неверном коде ТН ВЭД, описании содержимого,
+	// стране происхождения или таможенном статусе
+	ErrInvalidCustoms = errors.New("неверные таможенные данные")
+	// ErrCustomsDelivered возвращается при изменении декларации доставленной посылки
+	ErrCustomsDelivered = errors.New("таможенные данные нельзя изменить после доставки")
+)
+
+var (
+	// hsCode код товара по ТН ВЭД: 6 цифр Гармонизированной системы и до 4 национальных
+	hsCode = regexp.MustCompile(`^[0-9]{6}([0-9]{2}){0,2}$`)
+	// countryCode код страны ISO 3166-1 alpha-2
+	countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+const (
+	// при повторной подаче декларации таможенный статус сохраняется
+	queryUpsertCustoms = `INSERT INTO customs (number, tenant_id, hs_code, contents, origin_country, status, updated_at)
+			VALUES (:number, :tenant, :hs_code, :contents, :origin_country, :status, :updated_at)
+		ON CONFLICT (number) DO UPDATE SET hs_code = excluded.hs_code, contents = excluded.contents,
+			origin_country = excluded.origin_country, updated_at = excluded.updated_at`
+	queryCustoms = `SELECT number, hs_code, contents, origin_country, status, updated_at
+		FROM customs WHERE number = :number AND ` + tenantCond
+	querySetCustomsStatus = "UPDATE customs SET status = :status, updated_at = :updated_at WHERE number = :number"
+	queryDeleteCustoms    = "DELETE FROM customs WHERE number = :number"
+)
+
+// Customs таможенная декларация международной посылки
+type Customs struct {
+	Number int
+	// HSCode код товара по ТН ВЭД, 6, 8 или 10 цифр
+	HSCode string
+	// Contents описание содержимого
+	Contents string
+	// OriginCountry страна происхождения товара, код ISO 3166-1 alpha-2
+	OriginCountry string
+	// Status таможенный статус: pending, held или cleared
+	Status    string
+	UpdatedAt string
+}
+
+// validate проверяет поля декларации
+func (c Customs) validate() error {
+	switch {
+	case !hsCode.MatchString(c.HSCode):
+		return fmt.Errorf("%w: код ТН ВЭД %q", ErrInvalidCustoms, c.HSCode)
+	case c.Contents == "" || utf8.RuneCountInString(c.Contents) > maxCustomsContents:
+		return fmt.Errorf("%w: описание содержимого должно быть от 1 до %d символов", ErrInvalidCustoms, maxCustomsContents)
+	case !countryCode.MatchString(c.OriginCountry):
+		return fmt.Errorf("%w: страна происхождения %q", ErrInvalidCustoms, c.OriginCountry)
+	}
+	return nil
+}
+
+// SetCustoms подаёт или исправляет таможенную декларацию посылки c.Number до её доставки.
+// Новая декларация получает статус pending, у поданной статус не меняется.
+func (s ParcelStore) SetCustoms(c Customs) error {
+	start := time.Now()
+	span := s.startSpan("SetCustoms", attrNumber.Int(c.Number))
+	defer span.End()
+
+	err := s.withRetry("store.SetCustoms", func(s ParcelStore) error {
+		return s.setCustoms(c)
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("SetCustoms", start)
+	logResult(s.ctx, s.logger, "store.SetCustoms", start, err, "number", c.Number, "hs_code", c.HSCode, "origin_country", c.OriginCountry)
+	return err
+}
+
+func (s ParcelStore) setCustoms(c Customs) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status, tenant string
+	var client int
+	err = s.queryRow(tx, queryParcelStatus, sql.Named("number", c.Number), sql.Named("tenant", s.tenant())).Scan(&status, &client, &tenant)
+	if err != nil {
+		return err
+	}
+	if status == ParcelStatusDelivered {
+		return ErrCustomsDelivered
+	}
+	_, err = s.exec(tx, queryUpsertCustoms,
+		sql.Named("number", c.Number),
+		sql.Named("tenant", tenant),
+		sql.Named("hs_code", c.HSCode),
+		sql.Named("contents", c.Contents),
+		sql.Named("origin_country", c.OriginCountry),
+		sql.Named("status", CustomsPending),
+		sql.Named("updated_at", time.Now().UTC().Format(time.RFC3339)))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Customs возвращает таможенную декларацию посылки number или sql.ErrNoRows, если её нет
+func (s ParcelStore) Customs(number int) (Customs, error) {
+	defer s.metrics.observeQuery("Customs", time.Now())
+	span := s.startSpan("Customs", attrNumber.Int(number))
+	defer span.End()
+
+	c, err := s.customs(nil, number)
+	return c, spanError(span, err)
+}
+
+func (s ParcelStore) customs(tx *sql.Tx, number int) (Customs, error) {
+	var c Customs
+	err := s.queryRow(tx, queryCustoms, sql.Named("number", number), sql.Named("tenant", s.tenant())).Scan(
+		&c.Number, &c.HSCode, &c.Contents, &c.OriginCountry, &c.Status, &c.UpdatedAt)
+	return c, err
+}
+
+// SetCustomsStatus меняет таможенный статус посылки number. Вместе с ним в той же
+// транзакции меняется статус самой посылки: задержанная таможней недоставленная
+// посылка переходит в customs_hold, а выпущенная из customs_hold — снова в sent.
+func (s ParcelStore) SetCustomsStatus(number int, status string) (Customs, error) {
+	start := time.Now()
+	span := s.startSpan("SetCustomsStatus", attrNumber.Int(number), attrStatus.String(status))
+	defer span.End()
+
+	var c Customs
+	var changes []ParcelChange
+	err := s.withRetry("store.SetCustomsStatus", func(s ParcelStore) error {
+		var err error
+		c, changes, err = s.setCustomsStatus(number, status)
+		return err
+	})
+	s.notify(changes)
+	spanError(span, err)
+	s.metrics.observeQuery("SetCustomsStatus", start)
+	logResult(s.ctx, s.logger, "store.SetCustomsStatus", start, err, "number", number, "customs_status", status, "changes", len(changes))
+	if err == nil && len(changes) > 0 {
+		s.metrics.statusChanged(changes[0].Status)
+	}
+	return c, err
+}
+
+func (s ParcelStore) setCustomsStatus(number int, status string) (Customs, []ParcelChange, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return Customs{}, nil, err
+	}
+	defer tx.Rollback()
+
+	var old, tenant string
+	var client int
+	err = s.queryRow(tx, queryParcelStatus, sql.Named("number", number), sql.Named("tenant", s.tenant())).Scan(&old, &client, &tenant)
+	if err != nil {
+		return Customs{}, nil, err
+	}
+	c, err := s.customs(tx, number)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Customs{}, nil, ErrNoCustoms
+	}
+	if err != nil {
+		return Customs{}, nil, err
+	}
+
+	c.Status, c.UpdatedAt = status, time.Now().UTC().Format(time.RFC3339)
+	_, err = s.exec(tx, querySetCustomsStatus,
+		sql.Named("status", c.Status),
+		sql.Named("updated_at", c.UpdatedAt),
+		sql.Named("number", number))
+	if err != nil {
+		return c, nil, err
+	}
+
+	var parcelStatus string
+	switch {
+	case status == CustomsHeld && (old == ParcelStatusRegistered || old == ParcelStatusSent):
+		parcelStatus = ParcelStatusCustomsHold
+	case status == CustomsCleared && old == ParcelStatusCustomsHold:
+		parcelStatus = ParcelStatusSent
+	}
+	var changes []ParcelChange
+	if parcelStatus != "" {
+		changes, err = s.updateStatus(tx, number, client, tenant, old, parcelStatus)
+		if err != nil {
+			return c, nil, err
+		}
+	}
+	return c, changes, tx.Commit()
+}
+
+// SetCustoms подаёт таможенную декларацию посылки; клиент может подать её только для своей посылки
+func (s ParcelService) SetCustoms(ctx context.Context, c Customs) error {
+	err := s.check(ctx, actionRegister)
+	if err != nil {
+		return err
+	}
+	err = c.validate()
+	if err != nil {
+		return err
+	}
+	store := s.store.WithContext(ctx)
+	p, err := store.Get(c.Number)
+	if err != nil {
+		return err
+	}
+	err = authorizeOwner(ctx, p.Client)
+	if err != nil {
+		return err
+	}
+	return store.SetCustoms(c)
+}
+
+// Customs возвращает таможенную декларацию посылки
+func (s ParcelService) Customs(ctx context.Context, number int) (Customs, error) {
+	p, err := s.Get(ctx, number)
+	if err != nil {
+		return Customs{}, err
+	}
+	return s.store.WithContext(ctx).Customs(p.Number)
+}
+
+// SetCustomsStatus отмечает решение таможни по посылке; доступно тем, кто меняет статусы посылок
+func (s ParcelService) SetCustomsStatus(ctx context.Context, number int, status string) (Customs, error) {
+	err := s.check(ctx, actionSetStatus)
+	if err != nil {
+		return Customs{}, err
+	}
+	switch status {
+	case CustomsPending, CustomsHeld, CustomsCleared:
+	default:
+		return Customs{}, fmt.Errorf("%w: таможенный статус %q", ErrInvalidCustoms, status)
+	}
+	return s.store.WithContext(ctx).SetCustomsStatus(number, status)
+}
@@ -0,0 +1,98 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCustoms проверяет таможенную декларацию, её проверку и задержку посылки таможней
+func TestCustoms(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	tenant := fmt.Sprintf("customs-%d", randRange.Intn(10_000_000))
+	ctx := WithTenant(context.Background(), tenant)
+	service := NewParcelService(NewParcelStore(db))
+	courier := WithCaller(ctx, Caller{Client: 7, Role: RoleCourier, Tenant: tenant})
+
+	p, err := service.Register(ctx, 1000, "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+	_, err = service.Customs(ctx, p.Number)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = service.SetCustomsStatus(courier, p.Number, CustomsHeld)
+	assert.ErrorIs(t, err, ErrNoCustoms)
+
+	// validate
+	declaration := Customs{Number: p.Number, HSCode: "61091000", Contents: "Футболки хлопковые, 3 шт.", OriginCountry: "TR"}
+	invalid := []Customs{
+		{Number: p.Number, HSCode: "6109", Contents: "Футболки", OriginCountry: "TR"},
+		{Number: p.Number, HSCode: "6109100", Contents: "Футболки", OriginCountry: "TR"},
+		{Number: p.Number, HSCode: "61091000", Contents: "", OriginCountry: "TR"},
+		{Number: p.Number, HSCode: "61091000", Contents: strings.Repeat("я", maxCustomsContents+1), OriginCountry: "TR"},
+		{Number: p.Number, HSCode: "61091000", Contents: "Футболки", OriginCountry: "tr"},
+		{Number: p.Number, HSCode: "61091000", Contents: "Футболки", OriginCountry: "TUR"},
+	}
+	for _, c := range invalid {
+		assert.ErrorIs(t, service.SetCustoms(ctx, c), ErrInvalidCustoms, c)
+	}
+	assert.ErrorIs(t, service.SetCustoms(WithCaller(ctx, Caller{Client: 1001, Role: RoleClient}), declaration), ErrForbidden)
+
+	// set
+	require.NoError(t, service.SetCustoms(ctx, declaration))
+	got, err := service.Customs(ctx, p.Number)
+	require.NoError(t, err)
+	assert.Equal(t, declaration.HSCode, got.HSCode)
+	assert.Equal(t, declaration.Contents, got.Contents)
+	assert.Equal(t, "TR", got.OriginCountry)
+	assert.Equal(t, CustomsPending, got.Status)
+
+	// hold
+	require.NoError(t, service.SetStatus(courier, p.Number, ParcelStatusSent))
+	_, err = service.SetCustomsStatus(courier, p.Number, "lost")
+	assert.ErrorIs(t, err, ErrInvalidCustoms)
+	got, err = service.SetCustomsStatus(courier, p.Number, CustomsHeld)
+	require.NoError(t, err)
+	assert.Equal(t, CustomsHeld, got.Status)
+	stored, err := service.Get(ctx, p.Number)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusCustomsHold, stored.Status)
+
+	// исправленная декларация не снимает задержку
+	declaration.Contents = "Футболки хлопковые, 4 шт."
+	require.NoError(t, service.SetCustoms(ctx, declaration))
+	got, err = service.Customs(ctx, p.Number)
+	require.NoError(t, err)
+	assert.Equal(t, CustomsHeld, got.Status)
+
+	// задержанную посылку нельзя вернуть к регистрации
+	assert.ErrorIs(t, CheckTransition(ParcelStatusCustomsHold, ParcelStatusRegistered), ErrInvalidTransition)
+	assert.NoError(t, CheckTransition(ParcelStatusSent, ParcelStatusCustomsHold))
+
+	// clear
+	_, err = service.SetCustomsStatus(courier, p.Number, CustomsCleared)
+	require.NoError(t, err)
+	history, err := service.History(ctx, p.Number)
+	require.NoError(t, err)
+	var statuses []string
+	for _, c := range history {
+		statuses = append(statuses, c.Status)
+	}
+	assert.Equal(t, []string{ParcelStatusRegistered, ParcelStatusSent, ParcelStatusCustomsHold, ParcelStatusSent}, statuses)
+
+	// после доставки декларацию не изменить
+	require.NoError(t, service.SetStatus(courier, p.Number, ParcelStatusDelivered))
+	assert.ErrorIs(t, service.SetCustoms(ctx, declaration), ErrCustomsDelivered)
+	_, err = service.SetCustomsStatus(courier, p.Number, CustomsHeld)
+	require.NoError(t, err)
+	stored, err = service.Get(ctx, p.Number)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusDelivered, stored.Status)
+}
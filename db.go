@@ -0,0 +1,135 @@
+package tracker
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// OpenDB открывает БД по настройкам. Для SQLite параметры из cfg.SQLite передаются
+// в DSN и применяются к каждому новому соединению пула: PRAGMA, выполненная через
+// одно соединение, на остальные не действует. Резервная БД из cfg.Monitor.Standby
+// открывается с теми же параметрами, когда на неё переключит HealthMonitor.
+func OpenDB(cfg config.DB) (*sql.DB, error) {
+	dsns := []string{dbDSN(cfg.Driver, cfg.Path, cfg.SQLite)}
+	if cfg.Monitor.Standby != "" {
+		dsns = append(dsns, dbDSN(cfg.Driver, cfg.Monitor.Standby, cfg.SQLite))
+	}
+	return openDB(cfg.Driver, dsns, cfg)
+}
+
+// OpenReaderDB открывает БД для чтения из cfg.Reader с теми же параметрами, что и OpenDB.
+// Соединения SQLite открываются с query_only, чтобы запись через них не прошла случайно.
+func OpenReaderDB(cfg config.DB) (*sql.DB, error) {
+	dsn := cfg.Reader.Path
+	if cfg.Driver == "sqlite" {
+		dsn = sqliteDSN(cfg.Reader.Path, cfg.SQLite) + "&_pragma=query_only(1)"
+	}
+	return openDB(cfg.Driver, []string{dsn}, cfg)
+}
+
+// dbDSN DSN БД по пути path: для SQLite с параметрами соединения o
+func dbDSN(driverName, path string, o config.SQLite) string {
+	if driverName == "sqlite" {
+		return sqliteDSN(path, o)
+	}
+	return path
+}
+
+// openDB открывает БД драйвера driverName по первому из dsns. С монитором из
+// cfg.Monitor соединения открываются через failoverConnector, который переключается
+// на следующий из dsns; с числом сбоев из cfg.Breaker запросы проходят через свой
+// размыкатель (circuitBreaker).
+func openDB(driverName string, dsns []string, cfg config.DB) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dsns[0])
+	if err != nil || (cfg.Breaker.Failures == 0 && cfg.Monitor.Interval == 0) {
+		return db, err
+	}
+	// sql.Open только находит драйвер, соединений он ещё не открывал
+	drv := db.Driver()
+	db.Close()
+	var c driver.Connector = newFailoverConnector(drv, dsns)
+	if b := cfg.Breaker; b.Failures > 0 {
+		c = breakerConnector{connector: c, breaker: newCircuitBreaker(b.Failures, b.Cooldown)}
+	}
+	return sql.OpenDB(c), nil
+}
+
+// PoolOptions настройки пула соединений *sql.DB; нулевые значения — без ограничения
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// poolOptions переводит настройки пула в параметры *sql.DB
+func poolOptions(p config.Pool) PoolOptions {
+	return PoolOptions{
+		MaxOpenConns:    p.MaxOpenConns,
+		MaxIdleConns:    p.MaxIdleConns,
+		ConnMaxLifetime: p.ConnMaxLifetime,
+		ConnMaxIdleTime: p.ConnMaxIdleTime,
+	}
+}
+
+// apply настраивает пул соединений db
+func (o PoolOptions) apply(db *sql.DB) {
+	db.SetMaxOpenConns(o.MaxOpenConns)
+	db.SetMaxIdleConns(o.MaxIdleConns)
+	db.SetConnMaxLifetime(o.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(o.ConnMaxIdleTime)
+}
+
+// WithPool настраивает пул соединений БД хранилища. Для SQLite разумно одно соединение
+// на запись (MaxOpenConns = 1): тогда конкурирующие записи ждут своей очереди в пуле.
+func WithPool(o PoolOptions) StoreOption {
+	return func(s *ParcelStore) {
+		o.apply(s.db)
+	}
+}
+
+// WithReader направляет чтения посылок и истории в db, а запись по-прежнему идёт
+// в основную БД. Данные реплики могут отставать, но изменения (SetStatus, SetAddress,
+// Delete) проверяют статус посылки в основной БД, поэтому отставание их не обходит.
+func WithReader(db *sql.DB, o PoolOptions) StoreOption {
+	return func(s *ParcelStore) {
+		o.apply(db)
+		s.reader = db
+		s.readStmts = newStmtCache(db)
+	}
+}
+
+// sqliteDSN дополняет путь к файлу БД параметрами соединения драйвера modernc.org/sqlite
+func sqliteDSN(path string, o config.SQLite) string {
+	q := url.Values{}
+	// busy_timeout идёт первым, чтобы следующие PRAGMA тоже ждали снятия блокировки
+	if o.BusyTimeout > 0 {
+		q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", o.BusyTimeout.Milliseconds()))
+	}
+	if o.JournalMode != "" {
+		q.Add("_pragma", fmt.Sprintf("journal_mode(%s)", o.JournalMode))
+	}
+	if o.Synchronous != "" {
+		q.Add("_pragma", fmt.Sprintf("synchronous(%s)", o.Synchronous))
+	}
+	if o.ForeignKeys {
+		q.Add("_pragma", "foreign_keys(1)")
+	} else {
+		q.Add("_pragma", "foreign_keys(0)")
+	}
+	if o.TxLock != "" {
+		q.Set("_txlock", strings.ToLower(o.TxLock))
+	}
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + q.Encode()
+}
@@ -0,0 +1,96 @@
+package tracker
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenDBPragmas проверяет, что параметры SQLite из настроек действуют на соединения пула
+func TestOpenDBPragmas(t *testing.T) {
+	// prepare
+	cfg := testConfig.DB
+	cfg.Path = filepath.Join(t.TempDir(), "pragmas.db")
+
+	db, err := OpenDB(cfg)
+	require.NoError(t, err)
+	defer db.Close()
+	// второе соединение должно получить те же параметры, что и первое
+	db.SetMaxIdleConns(2)
+	busy, err := db.Begin()
+	require.NoError(t, err)
+	defer busy.Rollback()
+
+	// check
+	var journalMode string
+	var busyTimeout, foreignKeys int
+	require.NoError(t, db.QueryRow("PRAGMA journal_mode").Scan(&journalMode))
+	require.NoError(t, db.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout))
+	require.NoError(t, db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys))
+
+	assert.Equal(t, "wal", journalMode)
+	assert.Equal(t, int(cfg.SQLite.BusyTimeout.Milliseconds()), busyTimeout)
+	assert.Equal(t, 1, foreignKeys)
+}
+
+// TestPoolOptions проверяет, что настройки пула применяются к БД хранилища
+func TestPoolOptions(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	NewParcelStore(db, WithPool(poolOptions(testConfig.DB.Pool)))
+
+	// check
+	assert.Equal(t, testConfig.DB.Pool.MaxOpenConns, db.Stats().MaxOpenConnections)
+}
+
+// TestWithReader проверяет, что чтения идут в БД для чтения, а запись — в основную БД
+func TestWithReader(t *testing.T) {
+	// prepare
+	dir := t.TempDir()
+	cfg := testConfig.DB
+	cfg.Path = filepath.Join(dir, "writer.db")
+	cfg.Reader.Path = filepath.Join(dir, "reader.db")
+
+	// в «реплике» заранее лежит посылка, которой нет в основной БД
+	mirror, err := OpenDB(config.DB{Driver: cfg.Driver, Path: cfg.Reader.Path, SQLite: cfg.SQLite})
+	require.NoError(t, err)
+	require.NoError(t, Migrate(mirror))
+	number, err := NewParcelStore(mirror).Add(getTestParcel())
+	require.NoError(t, err)
+	require.NoError(t, mirror.Close())
+
+	db, err := OpenDB(cfg)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, Migrate(db))
+
+	reader, err := OpenReaderDB(cfg)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	store := NewParcelStore(db, WithReader(reader, poolOptions(cfg.Reader.Pool)))
+	defer store.Close()
+
+	// check
+	p, err := store.Get(number)
+	require.NoError(t, err)
+	assert.Equal(t, number, p.Number)
+
+	_, err = store.Add(getTestParcel())
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM parcel").Scan(&count))
+	assert.Equal(t, 1, count)
+
+	// через соединения для чтения запись невозможна
+	_, err = reader.Exec("DELETE FROM parcel")
+	assert.Error(t, err)
+	assert.Equal(t, cfg.Reader.Pool.MaxOpenConns, reader.Stats().MaxOpenConnections)
+}
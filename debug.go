@@ -0,0 +1,163 @@
+package tracker
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"regexp"
+	"strings"
+)
+
+// StoreStats состояние хранилища для диагностики
+type StoreStats struct {
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	MaxOpenConnections int   `json:"max_open_connections"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+	// PreparedStatements количество подготовленных запросов в кеше хранилища
+	PreparedStatements int `json:"prepared_statements"`
+	SchemaVersion      int `json:"schema_version"`
+}
+
+// Stats возвращает статистику пула подключений к БД и версию схемы
+func (s ParcelStore) Stats() (StoreStats, error) {
+	version, err := SchemaVersion(s.db)
+	if err != nil {
+		return StoreStats{}, err
+	}
+
+	db := s.db.Stats()
+	return StoreStats{
+		OpenConnections:    db.OpenConnections,
+		InUse:              db.InUse,
+		Idle:               db.Idle,
+		MaxOpenConnections: db.MaxOpenConnections,
+		WaitCount:          db.WaitCount,
+		WaitDurationMs:     db.WaitDuration.Milliseconds(),
+		PreparedStatements: s.stmts.len(),
+		SchemaVersion:      version,
+	}, nil
+}
+
+// QueryPlan план выполнения запроса по EXPLAIN QUERY PLAN
+type QueryPlan struct {
+	Query string
+	// Steps шаги плана в порядке вывода SQLite
+	Steps []string
+	// Scans таблицы, которые запрос просматривает целиком, без индекса
+	Scans []string
+}
+
+// explainedQueries семейства запросов, план которых проверяет ExplainQueries:
+// подготовленные запросы и запросы, которые собираются из фильтров
+func explainedQueries() []string {
+	queries := append(append([]string(nil), hotQueries...), archivedQueries...)
+	for _, opts := range []ListOptions{{Client: 1}, {Status: ParcelStatusSent}, {Client: 1, Status: ParcelStatusSent}} {
+		query, _ := opts.query(parcelColumns, DefaultTenant).orderBy("number").build()
+		queries = append(queries, query)
+	}
+	board, _ := BoardOptions{Client: 1}.query(DefaultTenant)
+	return append(queries, fmt.Sprintf(searchQuery, ""), queryOverdue, board)
+}
+
+var (
+	// queryParamPattern именованный параметр запроса, например :number
+	queryParamPattern = regexp.MustCompile(`:([a-z_][a-z0-9_]*)`)
+	// tableScanPattern шаг плана, который просматривает таблицу целиком; просмотр по
+	// индексу («SCAN outbox USING INDEX ...»), подзапроса и виртуальной таблицы FTS5 не в счёт
+	tableScanPattern = regexp.MustCompile(`^SCAN (?:TABLE )?(\w+)(?: AS \w+)?$`)
+)
+
+// ExplainQueries возвращает планы выполнения семейств запросов хранилища.
+// Параметры запросов подставляются как NULL: на выбор индекса это не влияет.
+func (s ParcelStore) ExplainQueries() ([]QueryPlan, error) {
+	var plans []QueryPlan
+	for _, query := range explainedQueries() {
+		var args []any
+		for _, m := range queryParamPattern.FindAllStringSubmatch(query, -1) {
+			args = append(args, sql.Named(m[1], nil))
+		}
+		plan, err := s.explain(query, args)
+		if err != nil {
+			return nil, fmt.Errorf("план запроса %q: %w", query, err)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+func (s ParcelStore) explain(query string, args []any) (QueryPlan, error) {
+	plan := QueryPlan{Query: query}
+	rows, err := s.db.Query("EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		return plan, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		err = rows.Scan(&id, &parent, &notUsed, &detail)
+		if err != nil {
+			return plan, err
+		}
+		plan.Steps = append(plan.Steps, detail)
+		if m := tableScanPattern.FindStringSubmatch(detail); m != nil {
+			plan.Scans = append(plan.Scans, m[1])
+		}
+	}
+	return plan, rows.Err()
+}
+
+// LogQueryPlans записывает в лог планы семейств запросов и предупреждает о запросах,
+// которые просматривают таблицу целиком: так видно, что после изменения схемы
+// или запроса пропал нужный индекс. Вызывается при запуске в режиме отладки.
+func (s ParcelStore) LogQueryPlans() error {
+	plans, err := s.ExplainQueries()
+	if err != nil {
+		return err
+	}
+	for _, p := range plans {
+		s.logger.Log(s.ctx, slog.LevelInfo, "план запроса", "op", "store.ExplainQueries",
+			"query", p.Query, "plan", strings.Join(p.Steps, "; "))
+		if len(p.Scans) > 0 {
+			s.logger.Log(s.ctx, slog.LevelWarn, "запрос просматривает таблицу целиком", "op", "store.ExplainQueries",
+				"query", p.Query, "tables", strings.Join(p.Scans, ", "))
+		}
+	}
+	return nil
+}
+
+// newDebugHandler отдаёт профили pprof в /debug/pprof/ и состояние хранилища в /debug/store.
+// Подключается флагом функциональности debug и пускает только администраторов.
+func newDebugHandler(service ParcelService) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("GET /debug/store", func(w http.ResponseWriter, r *http.Request) {
+		stats, err := service.store.Stats()
+		if err != nil {
+			writeJSONError(w, httpErrorCode(err), err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := service.check(r.Context(), actionDebug)
+		if err != nil {
+			writeJSONError(w, httpErrorCode(err), err)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
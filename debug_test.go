@@ -0,0 +1,97 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// TestDebugStore проверяет, что /debug/store подключается флагом debug и доступен только администратору
+func TestDebugStore(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	service := NewParcelService(NewParcelStore(db))
+	features := config.Features{config.FeatureDebug: true}
+
+	// роль пользователя задаётся заголовком вместо API-ключа
+	handler := newHTTPHandler(service, features)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithCaller(r.Context(), Caller{Client: 1, Role: Role(r.Header.Get("Role"))})
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	}))
+	defer srv.Close()
+
+	get := func(path string, role Role) *http.Response {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Role", string(role))
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// check
+	resp := get("/debug/store", RoleOperator)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	var stats StoreStats
+	resp = get("/debug/store", RoleAdmin)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	resp.Body.Close()
+	assert.Equal(t, migrations[len(migrations)-1].version, stats.SchemaVersion)
+
+	resp = get("/debug/pprof/", RoleAdmin)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// без флага эндпоинтов нет
+	srv.Config.Handler = NewHTTPHandler(service)
+	resp = get("/debug/store", RoleAdmin)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// TestExplainQueries проверяет, что семейства запросов хранилища идут по индексам
+func TestExplainQueries(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, "info", "text")
+	require.NoError(t, err)
+	store := NewParcelStore(db, WithStoreLogger(logger))
+
+	// explain
+	plans, err := store.ExplainQueries()
+	require.NoError(t, err)
+
+	// check
+	require.Len(t, plans, len(explainedQueries()))
+	for _, p := range plans {
+		assert.Empty(t, p.Scans, p.Query)
+	}
+	require.NoError(t, store.LogQueryPlans())
+	assert.Contains(t, buf.String(), "USING INDEX parcel_client_idx")
+	assert.NotContains(t, buf.String(), "level=WARN")
+
+	// запрос по колонке без индекса просматривает таблицу
+	plan, err := store.explain("SELECT number FROM parcel WHERE address = :address", []any{sql.Named("address", nil)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"parcel"}, plan.Scans)
+}
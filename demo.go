@@ -0,0 +1,82 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+)
+
+// runDemo проверяет основную функциональность сервиса на одном клиенте.
+// Демонстрация выполняется без пользователя в контексте, т.е. без ограничений по ролям.
+func runDemo(service ParcelService) error {
+	ctx := context.Background()
+
+	// регистрация посылки
+	client := 1
+	address := "Псков, д. Пушкина, ул. Колотушкина, д. 5"
+	p, err := service.Register(ctx, client, address)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Новая посылка № %d на адрес %s от клиента с идентификатором %d зарегистрирована %s\n",
+		p.Number, p.Address, p.Client, p.CreatedAt)
+
+	// изменение адреса
+	newAddress := "Саратов, д. Верхние Зори, ул. Козлова, д. 25"
+	err = service.ChangeAddress(ctx, p.Number, newAddress)
+	if err != nil {
+		return err
+	}
+
+	// изменение статуса
+	err = service.NextStatus(ctx, p.Number)
+	if err != nil {
+		return err
+	}
+	p, err = service.Get(ctx, p.Number)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("У посылки № %d новый статус: %s\n", p.Number, p.Status)
+
+	// вывод посылок клиента
+	err = service.PrintClientParcels(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	// попытка удаления отправленной посылки
+	err = service.Delete(ctx, p.Number)
+	if err != nil {
+		return err
+	}
+
+	// вывод посылок клиента
+	// предыдущая посылка не должна удалиться, т.к. её статус НЕ «зарегистрирована»
+	err = service.PrintClientParcels(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	// регистрация новой посылки
+	p, err = service.Register(ctx, client, address)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Новая посылка № %d на адрес %s от клиента с идентификатором %d зарегистрирована %s\n",
+		p.Number, p.Address, p.Client, p.CreatedAt)
+
+	// удаление новой посылки
+	err = service.Delete(ctx, p.Number)
+	if err != nil {
+		return err
+	}
+
+	// вывод посылок клиента
+	// здесь не должно быть последней посылки, т.к. она должна была успешно удалиться
+	err = service.PrintClientParcels(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
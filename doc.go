@@ -0,0 +1,5 @@
+// Package tracker — трекер посылок: хранилище ParcelStore, сервис ParcelService,
+// HTTP, gRPC и GraphQL API, фоновые задачи и команды tracker. Программа собирается
+// из cmd/tracker, а другие сервисы импортируют пакет, чтобы работать с посылками
+// через ParcelStore и ParcelService напрямую.
+package tracker
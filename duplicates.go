@@ -0,0 +1,144 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// ErrDuplicateParcel возвращается при повторной регистрации посылки, если
+// DuplicatePolicy требует отклонять повторы
+var ErrDuplicateParcel = errors.New("такая посылка уже зарегистрирована")
+
+// queryRecentClientParcels посылки клиента, зарегистрированные не раньше :since, начиная с последней
+const queryRecentClientParcels = "SELECT number, address FROM parcel WHERE client = :client AND created_at >= :since AND " + tenantCond + " ORDER BY number DESC"
+
+// DuplicatePolicy поиск повторной регистрации: посылка считается повтором, если у клиента
+// уже есть посылка на тот же адрес, зарегистрированная не раньше Window назад
+type DuplicatePolicy struct {
+	// Window за какое время искать такую же посылку; 0 — не искать
+	Window time.Duration
+	// Reject отклонять повтор с ErrDuplicateParcel; иначе возвращается уже
+	// зарегистрированная посылка, а повтор только пишется в лог
+	Reject bool
+}
+
+// duplicatePolicy возвращает поиск повторов из настроек
+func duplicatePolicy(cfg config.Duplicates) DuplicatePolicy {
+	return DuplicatePolicy{Window: cfg.Window, Reject: cfg.Mode == config.DuplicatesReject}
+}
+
+// WithDuplicatePolicy задаёт, как Register поступает с повторной регистрацией посылки
+func WithDuplicatePolicy(p DuplicatePolicy) ServiceOption {
+	return func(s *ParcelService) {
+		s.duplicates = p
+	}
+}
+
+// sameAddress сравнивает адреса без учёта регистра и лишних пробелов
+func sameAddress(a, b string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a), " "), strings.Join(strings.Fields(b), " "))
+}
+
+// AddUnique регистрирует посылку p, если у клиента нет такой же посылки, зарегистрированной
+// за window, и возвращает её номер. Если такая посылка есть, новая не регистрируется,
+// а возвращается номер уже зарегистрированной и duplicate = true. Поиск и регистрация
+// выполняются в одной транзакции записи, поэтому из одновременных повторов
+// регистрируется только первый.
+func (s ParcelStore) AddUnique(p Parcel, window time.Duration) (number int, duplicate bool, err error) {
+	start := time.Now()
+	span := s.startSpan("AddUnique", attrClient.Int(p.Client))
+	defer span.End()
+
+	err = s.withRetry("store.AddUnique", func(s ParcelStore) error {
+		var err error
+		number, duplicate, err = s.addUnique(p, window)
+		return err
+	})
+	span.SetAttributes(attrNumber.Int(number))
+	spanError(span, err)
+	s.metrics.observeQuery("AddUnique", start)
+	logResult(s.ctx, s.logger, "store.AddUnique", start, err, "number", number, "client", p.Client, "duplicate", duplicate)
+	if err == nil && !duplicate {
+		s.added(number)
+	}
+	return number, duplicate, err
+}
+
+func (s ParcelStore) addUnique(p Parcel, window time.Duration) (int, bool, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	number, err := s.findDuplicate(tx, p.Client, p.Address, time.Now().Add(-window))
+	if err != nil {
+		return 0, false, err
+	}
+	if number != 0 {
+		return number, true, nil
+	}
+	number, err = s.insertParcel(tx, p, RequestIDFromContext(s.ctx))
+	if err != nil {
+		return 0, false, err
+	}
+	return number, false, tx.Commit()
+}
+
+// findDuplicate возвращает в транзакции tx номер последней посылки клиента на адрес
+// address, зарегистрированной не раньше since, или 0, если такой нет
+func (s ParcelStore) findDuplicate(tx *sql.Tx, client int, address string, since time.Time) (int, error) {
+	rows, err := s.query(tx, queryRecentClientParcels,
+		sql.Named("client", client),
+		sql.Named("since", since.UTC().Format(time.RFC3339)),
+		sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var number int
+		var stored string
+		err := rows.Scan(&number, &stored)
+		if err != nil {
+			return 0, err
+		}
+		// адреса могут быть зашифрованы, поэтому сравниваются уже открытыми
+		stored, err = s.openAddress(stored)
+		if err != nil {
+			return 0, err
+		}
+		if sameAddress(stored, address) {
+			return number, nil
+		}
+	}
+	return 0, rows.Err()
+}
+
+// addUnique регистрирует посылку parcel с поиском повтора по политике сервиса. Повтор
+// пишется в лог; без reject возвращается уже зарегистрированная посылка.
+func (s ParcelService) addUnique(ctx context.Context, parcel Parcel) (Parcel, error) {
+	store := s.store.WithContext(ctx)
+	number, duplicate, err := store.AddUnique(parcel, s.duplicates.Window)
+	if err != nil {
+		return parcel, err
+	}
+	if !duplicate {
+		parcel.Number = number
+		return parcel, nil
+	}
+	s.logger.Log(ctx, slog.LevelWarn, "повторная регистрация посылки",
+		withRequestID(ctx, []any{"op", "service.Register", "client", parcel.Client, "number", number, "rejected", s.duplicates.Reject})...)
+	if s.duplicates.Reject {
+		return parcel, fmt.Errorf("%w: № %d", ErrDuplicateParcel, number)
+	}
+	return store.Get(number)
+}
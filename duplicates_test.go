@@ -0,0 +1,101 @@
+package tracker_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestRegisterDuplicate проверяет поиск повторной регистрации посылки в обоих режимах
+func TestRegisterDuplicate(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	store := tracker.NewParcelStore(db)
+	warn := tracker.NewParcelService(store, tracker.WithDuplicatePolicy(tracker.DuplicatePolicy{Window: 10 * time.Minute}))
+	reject := tracker.NewParcelService(store, tracker.WithDuplicatePolicy(tracker.DuplicatePolicy{Window: 10 * time.Minute, Reject: true}))
+	ctx := context.Background()
+	client := testsupport.Intn(10_000_000)
+
+	first, err := warn.Register(ctx, client, "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+
+	// warn
+	// повтор с другим регистром и пробелами возвращает уже зарегистрированную посылку
+	again, err := warn.Register(ctx, client, " псков,  ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+	assert.Equal(t, first.Number, again.Number)
+	assert.Equal(t, first.TrackingToken, again.TrackingToken)
+	parcels, err := store.GetByClient(client)
+	require.NoError(t, err)
+	assert.Len(t, parcels, 1)
+
+	// reject
+	_, err = reject.Register(ctx, client, "Псков, ул. Колотушкина, д. 5")
+	assert.ErrorIs(t, err, tracker.ErrDuplicateParcel)
+
+	// другой адрес и другой клиент — не повторы
+	other, err := reject.Register(ctx, client, "Саратов, ул. Козлова, д. 25")
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Number, other.Number)
+	_, err = reject.Register(ctx, client+1, "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+
+	// без окна повторы не ищутся
+	_, err = tracker.NewParcelService(store).Register(ctx, client, "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+	parcels, err = store.GetByClient(client)
+	require.NoError(t, err)
+	assert.Len(t, parcels, 3)
+}
+
+// TestRegisterDuplicateConcurrent проверяет, что из одновременных повторов регистрируется только один
+func TestRegisterDuplicateConcurrent(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	store := tracker.NewParcelStore(db)
+	service := tracker.NewParcelService(store, tracker.WithDuplicatePolicy(tracker.DuplicatePolicy{Window: 10 * time.Minute, Reject: true}))
+	ctx := context.Background()
+	client := testsupport.Intn(10_000_000)
+
+	// register
+	const n = 8
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.Register(ctx, client, "Псков, ул. Колотушкина, д. 5")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	// check
+	var ok int
+	for err := range errs {
+		if err == nil {
+			ok++
+			continue
+		}
+		assert.ErrorIs(t, err, tracker.ErrDuplicateParcel)
+	}
+	assert.Equal(t, 1, ok)
+	parcels, err := store.GetByClient(client)
+	require.NoError(t, err)
+	assert.Len(t, parcels, 1)
+}
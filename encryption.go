@@ -0,0 +1,306 @@
+package tracker
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// encryptedPrefix начало зашифрованного значения колонки: enc:v1:<ключ>:<base64(nonce|шифротекст)>.
+// Значения без него считаются открытыми: так читаются строки, записанные до включения
+// шифрования, пока задача reencrypt их не зашифрует.
+const encryptedPrefix = "enc:v1:"
+
+// ErrUnknownKey возвращает KeyProvider, если ключа с таким идентификатором нет
+var ErrUnknownKey = errors.New("неизвестный ключ шифрования")
+
+// KeyProvider выдаёт ключи AES-256 для шифрования колонок: текущий для новых
+// значений и любой из прежних по идентификатору для расшифровки. Реализация может
+// хранить ключи в настройках, KMS или Vault.
+type KeyProvider interface {
+	// CurrentKey возвращает идентификатор и ключ, которым шифруются новые значения
+	CurrentKey(ctx context.Context) (string, []byte, error)
+	// Key возвращает ключ по идентификатору или ErrUnknownKey
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// StaticKeys ключи из настроек encryption
+type StaticKeys struct {
+	keys    map[string][]byte
+	current string
+}
+
+// NewStaticKeys создаёт KeyProvider из ключей в base64 и идентификатора текущего ключа
+func NewStaticKeys(cfg config.Encryption) (*StaticKeys, error) {
+	k := &StaticKeys{keys: make(map[string][]byte, len(cfg.Keys)), current: cfg.Current}
+	for id, encoded := range cfg.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption.keys.%s: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("encryption.keys.%s: нужен ключ AES-256 из 32 байт, а не %d", id, len(key))
+		}
+		k.keys[id] = key
+	}
+	if _, ok := k.keys[k.current]; !ok {
+		return nil, fmt.Errorf("encryption.current: %w %q", ErrUnknownKey, k.current)
+	}
+	return k, nil
+}
+
+// CurrentKey возвращает ключ encryption.current
+func (k *StaticKeys) CurrentKey(context.Context) (string, []byte, error) {
+	return k.current, k.keys[k.current], nil
+}
+
+// Key возвращает ключ из encryption.keys
+func (k *StaticKeys) Key(_ context.Context, id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// WithEncryption включает шифрование адресов посылок ключами keys: хранилище
+// шифрует адрес при записи и расшифровывает при чтении, вызывающий код видит
+// открытый адрес. Зашифрованный адрес не попадает в полнотекстовый индекс,
+// поэтому Search находит посылки только по номеру и трекинг-токену.
+func WithEncryption(keys KeyProvider) StoreOption {
+	return func(s *ParcelStore) {
+		s.keys = keys
+	}
+}
+
+// fieldGCM возвращает AES-GCM с ключом key
+func fieldGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptField шифрует значение колонки field текущим ключом keys; имя колонки
+// входит в аутентификацию, поэтому значение нельзя переставить в другую колонку
+func encryptField(ctx context.Context, keys KeyProvider, field, value string) (string, error) {
+	id, key, err := keys.CurrentKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := fieldGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(field))
+	return encryptedPrefix + id + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptField расшифровывает значение колонки field; открытое значение возвращается как есть
+func decryptField(ctx context.Context, keys KeyProvider, field, value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return value, nil
+	}
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", fmt.Errorf("%s: повреждённое зашифрованное значение", field)
+	}
+	key, err := keys.Key(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := fieldGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("%s: повреждённое зашифрованное значение", field)
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", field, err)
+	}
+	return string(plain), nil
+}
+
+// sealAddress проверяет адрес (validateAddress) и возвращает его в том виде, в котором
+// он хранится в БД. Через него проходит каждый записываемый адрес, поэтому неверный
+// адрес не попадает в БД ни при регистрации, ни при смене адреса, загрузке или импорте.
+func (s ParcelStore) sealAddress(address string) (string, error) {
+	err := validateAddress(address)
+	if err != nil {
+		return "", err
+	}
+	if s.keys == nil {
+		return address, nil
+	}
+	return encryptField(s.ctx, s.keys, "address", address)
+}
+
+// openAddress возвращает открытый адрес по значению из БД
+func (s ParcelStore) openAddress(address string) (string, error) {
+	if s.keys == nil {
+		return address, nil
+	}
+	return decryptField(s.ctx, s.keys, "address", address)
+}
+
+// openParcel расшифровывает адрес посылки, прочитанной из БД
+func (s ParcelStore) openParcel(p Parcel) (Parcel, error) {
+	var err error
+	p.Address, err = s.openAddress(p.Address)
+	return p, err
+}
+
+const (
+	// queryStaleAddresses адреса, зашифрованные не ключом :prefix или открытые
+	queryStaleAddresses = `SELECT number, address FROM %s
+		WHERE substr(address, 1, length(:prefix)) <> :prefix AND ` + tenantCond + ` ORDER BY number LIMIT :limit`
+	queryResealAddress = "UPDATE %s SET address = :new WHERE number = :number AND address = :old"
+)
+
+// ReencryptAddresses перешифровывает текущим ключом до limit адресов в parcel и столько же
+// в parcel_archive, которые открыты или зашифрованы прежним ключом, и возвращает их число.
+// Адрес, изменённый за время перешифровки, пропускается и перешифруется при следующем вызове.
+// Событий outbox перешифровка не создаёт: адрес для получателей не меняется.
+func (s ParcelStore) ReencryptAddresses(limit int) (int, error) {
+	start := time.Now()
+	span := s.startSpan("ReencryptAddresses")
+	defer span.End()
+
+	n := 0
+	var err error
+	if s.keys == nil {
+		err = errors.New("шифрование адресов не включено")
+	}
+	for _, table := range []string{"parcel", "parcel_archive"} {
+		if err != nil {
+			break
+		}
+		var resealed []int
+		err = s.withRetry("store.ReencryptAddresses", func(s ParcelStore) error {
+			var err error
+			resealed, err = s.reencryptAddresses(table, limit)
+			return err
+		})
+		for _, number := range resealed {
+			s.invalidate(number)
+		}
+		n += len(resealed)
+	}
+	spanError(span, err)
+	s.metrics.observeQuery("ReencryptAddresses", start)
+	logResult(s.ctx, s.logger, "store.ReencryptAddresses", start, err, "reencrypted", n)
+	return n, err
+}
+
+// reencryptAddresses перешифровывает порцию адресов таблицы table в транзакции
+// и возвращает номера посылок, адреса которых изменились
+func (s ParcelStore) reencryptAddresses(table string, limit int) ([]int, error) {
+	id, _, err := s.keys.CurrentKey(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := s.query(tx, fmt.Sprintf(queryStaleAddresses, table),
+		sql.Named("prefix", encryptedPrefix+id+":"),
+		sql.Named("limit", limit),
+		sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, err
+	}
+	type stale struct {
+		number  int
+		address string
+	}
+	var list []stale
+	for rows.Next() {
+		var st stale
+		err = rows.Scan(&st.number, &st.address)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, st)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var numbers []int
+	for _, st := range list {
+		plain, err := decryptField(s.ctx, s.keys, "address", st.address)
+		if err != nil {
+			return nil, fmt.Errorf("посылка %d: %w", st.number, err)
+		}
+		sealed, err := encryptField(s.ctx, s.keys, "address", plain)
+		if err != nil {
+			return nil, err
+		}
+		res, err := s.exec(tx, fmt.Sprintf(queryResealAddress, table),
+			sql.Named("new", sealed),
+			sql.Named("number", st.number),
+			sql.Named("old", st.address))
+		if err != nil {
+			return nil, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			numbers = append(numbers, st.number)
+		}
+	}
+	return numbers, tx.Commit()
+}
+
+// Reencryptor задача планировщика reencrypt: после смены encryption.current
+// перешифровывает адреса новым ключом, а открытые адреса шифрует, порциями по batch
+type Reencryptor struct {
+	store ParcelStore
+	batch int
+}
+
+// NewReencryptor создаёт перешифровку адресов хранилища с включённым шифрованием
+func NewReencryptor(store ParcelStore, batch int) *Reencryptor {
+	return &Reencryptor{store: store, batch: batch}
+}
+
+// Reencrypt перешифровывает порции, пока не останется устаревших адресов или не отменён ctx
+func (r *Reencryptor) Reencrypt(ctx context.Context) error {
+	store := r.store.WithContext(ctx)
+	for ctx.Err() == nil {
+		n, err := store.ReencryptAddresses(r.batch)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
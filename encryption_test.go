@@ -0,0 +1,96 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// testKey возвращает ключ AES-256 в base64, заполненный байтом b
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+// TestEncryptAddresses проверяет шифрование адресов в БД и перешифровку новым ключом
+func TestEncryptAddresses(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	// отдельная БД: перешифровка затрагивает все посылки
+	db := openTestDB(t)
+
+	rawAddress := func(number int) string {
+		t.Helper()
+		var address string
+		require.NoError(t, db.QueryRow("SELECT address FROM parcel WHERE number = ?", number).Scan(&address))
+		return address
+	}
+	// посылка, записанная до включения шифрования
+	legacy, err := NewParcelStore(db).Add(getTestParcel())
+	require.NoError(t, err)
+
+	keys, err := NewStaticKeys(config.Encryption{Keys: map[string]string{"k1": testKey(1)}, Current: "k1"})
+	require.NoError(t, err)
+	store := NewParcelStore(db, WithEncryption(keys))
+
+	// add
+	p := getTestParcel()
+	p.Address = "Псков, ул. Колотушкина, д. 5"
+	number, err := store.Add(p)
+	require.NoError(t, err)
+
+	// check
+	// в БД адрес зашифрован, хранилище отдаёт открытый
+	assert.True(t, strings.HasPrefix(rawAddress(number), "enc:v1:k1:"))
+	assert.NotContains(t, rawAddress(number), "Псков")
+	stored, err := store.Get(number)
+	require.NoError(t, err)
+	assert.Equal(t, p.Address, stored.Address)
+	require.NoError(t, store.SetAddress(number, "Саратов, ул. Козлова, д. 25"))
+	parcels, err := store.GetByClient(p.Client)
+	require.NoError(t, err)
+	require.NotEmpty(t, parcels)
+	assert.Equal(t, "Саратов, ул. Козлова, д. 25", parcels[len(parcels)-1].Address)
+	var export bytes.Buffer
+	_, err = store.ExportJSON(&export, ListOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, export.String(), "Саратов")
+	// открытый адрес читается как есть
+	stored, err = store.Get(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, "test", stored.Address)
+
+	// reencrypt
+	// новый ключ k2; k1 остаётся для чтения, пока адреса не перешифрованы
+	keys, err = NewStaticKeys(config.Encryption{Keys: map[string]string{"k1": testKey(1), "k2": testKey(2)}, Current: "k2"})
+	require.NoError(t, err)
+	store = NewParcelStore(db, WithEncryption(keys))
+	require.NoError(t, NewReencryptor(store, 1).Reencrypt(context.Background()))
+
+	assert.True(t, strings.HasPrefix(rawAddress(number), "enc:v1:k2:"))
+	assert.True(t, strings.HasPrefix(rawAddress(legacy), "enc:v1:k2:"))
+	n, err := store.ReencryptAddresses(10)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	// без прежнего ключа адреса не читаются
+	keys, err = NewStaticKeys(config.Encryption{Keys: map[string]string{"k1": testKey(1)}, Current: "k1"})
+	require.NoError(t, err)
+	_, err = NewParcelStore(db, WithEncryption(keys)).Get(number)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	// адрес нельзя перенести в другую колонку или подменить
+	sealed := rawAddress(number)
+	_, err = decryptField(context.Background(), keys, "note", sealed)
+	assert.Error(t, err)
+
+	_, err = NewStaticKeys(config.Encryption{Keys: map[string]string{"short": "c2hvcnQ="}, Current: "short"})
+	assert.Error(t, err)
+}
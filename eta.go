@@ -0,0 +1,261 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+const (
+	// queryTransitTimes время регистрации, первой отправки и первой доставки посылок,
+	// доставленных не раньше :since, начиная с последних
+	queryTransitTimes = `SELECT p.address, p.created_at,
+			COALESCE((SELECT MIN(h.changed_at) FROM parcel_history h WHERE h.number = p.number AND h.status = 'sent'), ''),
+			p.delivered_at
+		FROM parcel p WHERE p.status = 'delivered' AND p.delivered_at >= :since AND ` + tenantCond + `
+		ORDER BY p.delivered_at DESC LIMIT :limit`
+	// queryStatusSince когда посылка получила текущий статус: последняя запись истории, а без неё — регистрация
+	queryStatusSince = `SELECT p.status, p.address, COALESCE((SELECT MAX(h.changed_at) FROM parcel_history h WHERE h.number = p.number), p.created_at)
+		FROM parcel p WHERE p.number = :number AND ` + tenantCond
+	querySetETA = "UPDATE parcel SET eta = NULLIF(:eta, '') WHERE number = :number AND " + tenantCond
+)
+
+// etaSampleLimit по скольким последним доставленным посылкам считаются средние сроки
+const etaSampleLimit = 5000
+
+// TransitTimes средние сроки этапов доставки: от регистрации до отправки и от отправки до доставки
+type TransitTimes struct {
+	Registered time.Duration
+	Sent       time.Duration
+	// Samples по скольким посылкам посчитаны сроки
+	Samples int
+}
+
+// remaining сколько в среднем осталось до доставки посылки в статусе status
+func (t TransitTimes) remaining(status string) time.Duration {
+	switch status {
+	case ParcelStatusRegistered:
+		return t.Registered + t.Sent
+	case ParcelStatusSent, ParcelStatusCustomsHold:
+		return t.Sent
+	default:
+		return 0
+	}
+}
+
+// transitSum суммы сроков для подсчёта средних
+type transitSum struct {
+	registered, sent time.Duration
+	n                int
+}
+
+func (s *transitSum) add(registered, sent time.Duration) {
+	s.registered += registered
+	s.sent += sent
+	s.n++
+}
+
+func (s transitSum) average() TransitTimes {
+	if s.n == 0 {
+		return TransitTimes{}
+	}
+	return TransitTimes{Registered: s.registered / time.Duration(s.n), Sent: s.sent / time.Duration(s.n), Samples: s.n}
+}
+
+// TransitTimes возвращает средние сроки доставки посылок, доставленных не раньше since,
+// по городам назначения и по всем посылкам вместе. Посылки без отметки отправки не учитываются.
+func (s ParcelStore) TransitTimes(since time.Time) (map[string]TransitTimes, TransitTimes, error) {
+	defer s.metrics.observeQuery("TransitTimes", time.Now())
+	span := s.startSpan("TransitTimes")
+	defer span.End()
+
+	rows, err := s.readQuery(queryTransitTimes,
+		sql.Named("since", since.UTC().Format(time.RFC3339)),
+		sql.Named("tenant", s.tenant()),
+		sql.Named("limit", etaSampleLimit))
+	if err != nil {
+		return nil, TransitTimes{}, spanError(span, err)
+	}
+	defer rows.Close()
+
+	cities := map[string]*transitSum{}
+	var all transitSum
+	for rows.Next() {
+		var address, created, sent, delivered string
+		err := rows.Scan(&address, &created, &sent, &delivered)
+		if err != nil {
+			return nil, TransitTimes{}, spanError(span, err)
+		}
+		if sent == "" {
+			continue
+		}
+		var t [3]time.Time
+		for i, v := range []string{created, sent, delivered} {
+			t[i], err = time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, TransitTimes{}, spanError(span, err)
+			}
+		}
+		// адреса могут быть зашифрованы, поэтому город берётся из открытого адреса
+		address, err = s.openAddress(address)
+		if err != nil {
+			return nil, TransitTimes{}, spanError(span, err)
+		}
+		city := addressCity(address)
+		if cities[city] == nil {
+			cities[city] = &transitSum{}
+		}
+		cities[city].add(t[1].Sub(t[0]), t[2].Sub(t[1]))
+		all.add(t[1].Sub(t[0]), t[2].Sub(t[1]))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, TransitTimes{}, spanError(span, err)
+	}
+
+	res := make(map[string]TransitTimes, len(cities))
+	for city, sum := range cities {
+		res[city] = sum.average()
+	}
+	return res, all.average(), nil
+}
+
+// SetETA записывает ожидаемое время доставки посылки; пустое eta стирает оценку.
+// Версия посылки не меняется: оценка — производное значение, а не изменение посылки.
+func (s ParcelStore) SetETA(number int, eta string) error {
+	start := time.Now()
+	span := s.startSpan("SetETA", attrNumber.Int(number))
+	defer span.End()
+
+	err := s.withRetry("store.SetETA", func(s ParcelStore) error {
+		_, err := s.exec(nil, querySetETA, sql.Named("eta", eta), sql.Named("number", number), sql.Named("tenant", s.tenant()))
+		return err
+	})
+	s.invalidate(number)
+	spanError(span, err)
+	s.metrics.observeQuery("SetETA", start)
+	logResult(s.ctx, s.logger, "store.SetETA", start, err, "number", number, "eta", eta)
+	return err
+}
+
+// ETAEstimator оценивает время доставки по средним срокам этапов посылок, доставленных
+// за последние Lookback, в тот же город назначения. Если таких посылок меньше MinSamples,
+// берутся средние по всем городам, а без доставленных посылок оценки нет.
+type ETAEstimator struct {
+	store      ParcelStore
+	lookback   time.Duration
+	minSamples int
+	// refresh как долго сроки считаются актуальными, прежде чем их пересчитать
+	refresh time.Duration
+
+	mu       sync.Mutex
+	cities   map[string]TransitTimes
+	all      TransitTimes
+	loadedAt time.Time
+	// now текущее время; подменяется в тестах
+	now func() time.Time
+}
+
+// NewETAEstimator создаёт оценку времени доставки по настройкам eta
+func NewETAEstimator(store ParcelStore, cfg config.ETA) *ETAEstimator {
+	return &ETAEstimator{store: store, lookback: cfg.Lookback, minSamples: cfg.MinSamples, refresh: cfg.Refresh, now: time.Now}
+}
+
+// times возвращает средние сроки для города city, при необходимости пересчитав их
+func (e *ETAEstimator) times(ctx context.Context, city string) (TransitTimes, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	if e.cities == nil || now.Sub(e.loadedAt) >= e.refresh {
+		// сроки считаются по посылкам всех компаний: маршруты у них общие
+		cities, all, err := e.store.WithContext(withAnyTenant(ctx)).TransitTimes(now.Add(-e.lookback))
+		if err != nil {
+			return TransitTimes{}, err
+		}
+		e.cities, e.all, e.loadedAt = cities, all, now
+	}
+	if t := e.cities[city]; t.Samples >= e.minSamples {
+		return t, nil
+	}
+	return e.all, nil
+}
+
+// Estimate возвращает ожидаемое время доставки посылки в статусе status, полученном в since,
+// на адрес address в RFC 3339 или пустую строку, если оценить его не по чему.
+// Время в прошлом заменяется текущим: посылка опаздывает, но ещё может приехать.
+func (e *ETAEstimator) Estimate(ctx context.Context, address, status string, since time.Time) (string, error) {
+	if status == ParcelStatusDelivered {
+		return "", nil
+	}
+	t, err := e.times(ctx, addressCity(address))
+	if err != nil || t.Samples == 0 {
+		return "", err
+	}
+	eta := since.Add(t.remaining(status))
+	if now := e.now(); eta.Before(now) {
+		eta = now
+	}
+	return eta.UTC().Format(time.RFC3339), nil
+}
+
+// Update пересчитывает и записывает ожидаемое время доставки посылки number по её текущему статусу
+func (e *ETAEstimator) Update(ctx context.Context, number int) error {
+	store := e.store.WithContext(ctx)
+	var status, address, since string
+	err := store.queryRow(nil, queryStatusSince, sql.Named("number", number), sql.Named("tenant", store.tenant())).Scan(&status, &address, &since)
+	if err != nil {
+		return err
+	}
+	// у доставленной посылки оценка остаётся для сравнения с фактическим временем
+	if status == ParcelStatusDelivered {
+		return nil
+	}
+	address, err = store.openAddress(address)
+	if err != nil {
+		return err
+	}
+	at, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return err
+	}
+	eta, err := e.Estimate(ctx, address, status, at)
+	if err != nil {
+		return err
+	}
+	return store.SetETA(number, eta)
+}
+
+// Run пересчитывает время доставки посылок, статус которых сменился, пока не отменён ctx.
+// Видны изменения только этого процесса; остальные посылки сохраняют прежнюю оценку
+// до следующей смены статуса здесь.
+func (e *ETAEstimator) Run(ctx context.Context) {
+	changes, cancel := e.store.Subscribe()
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case c := <-changes:
+			if c.Status == ParcelStatusDelivered {
+				continue
+			}
+			err := e.Update(ctx, c.Number)
+			if err != nil {
+				e.store.logger.Log(ctx, slog.LevelError, "не удалось обновить ожидаемое время доставки",
+					"op", "eta.Update", "number", c.Number, "error", err)
+			}
+		}
+	}
+}
+
+// WithETA задаёт оценку времени доставки, которую Register записывает в новые посылки
+func WithETA(e *ETAEstimator) ServiceOption {
+	return func(s *ParcelService) {
+		s.eta = e
+	}
+}
@@ -0,0 +1,74 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// TestETA проверяет оценку времени доставки по срокам доставленных посылок в тот же город
+func TestETA(t *testing.T) {
+	// prepare
+	db, store := singleConnStore(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	at := func(d time.Duration) string {
+		return now.Add(d).Format(time.RFC3339)
+	}
+	city := fmt.Sprintf("Город-%d", randRange.Intn(10_000_000))
+	address := city + ", ул. Колотушкина, д. 5"
+	// две доставленные посылки: сутки до отправки и двое суток в пути
+	for range 2 {
+		p := getTestParcel()
+		p.Address = address
+		number, err := store.Add(p)
+		require.NoError(t, err)
+		_, err = db.Exec("UPDATE parcel SET status = 'delivered', created_at = ?, delivered_at = ? WHERE number = ?",
+			at(-5*24*time.Hour), at(-2*24*time.Hour), number)
+		require.NoError(t, err)
+		_, err = db.Exec("INSERT INTO parcel_history (number, status, changed_at) VALUES (?, 'sent', ?), (?, 'delivered', ?)",
+			number, at(-4*24*time.Hour), number, at(-2*24*time.Hour))
+		require.NoError(t, err)
+	}
+
+	eta := NewETAEstimator(store, config.ETA{Lookback: 7 * 24 * time.Hour, MinSamples: 2, Refresh: time.Hour})
+	eta.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// estimate
+	got, err := eta.Estimate(ctx, address, ParcelStatusRegistered, now)
+	require.NoError(t, err)
+	assert.Equal(t, at(3*24*time.Hour), got)
+	got, err = eta.Estimate(ctx, address, ParcelStatusSent, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, at(2*24*time.Hour-time.Hour), got)
+	// опоздавшая посылка ожидается сейчас
+	got, err = eta.Estimate(ctx, address, ParcelStatusSent, now.Add(-10*24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, at(0), got)
+
+	// register
+	service := NewParcelService(store, WithETA(eta))
+	p, err := service.Register(ctx, randRange.Intn(10_000_000), address)
+	require.NoError(t, err)
+	stored, err := store.Get(p.Number)
+	require.NoError(t, err)
+	assert.Equal(t, at(3*24*time.Hour), stored.ETA)
+
+	// после отправки остаётся только срок в пути
+	require.NoError(t, store.SetStatus(p.Number, ParcelStatusSent))
+	_, err = db.Exec("UPDATE parcel_history SET changed_at = ? WHERE number = ? AND status = 'sent'", at(0), p.Number)
+	require.NoError(t, err)
+	require.NoError(t, eta.Update(ctx, p.Number))
+	stored, err = store.Get(p.Number)
+	require.NoError(t, err)
+	assert.Equal(t, at(2*24*time.Hour), stored.ETA)
+	view, err := service.Track(ctx, stored.TrackingToken)
+	require.NoError(t, err)
+	assert.Equal(t, stored.ETA, view.ETA)
+}
@@ -0,0 +1,113 @@
+package tracker
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// eventIDHeader заголовок сообщения с идентификатором события outbox.
+// Nats-Msg-Id JetStream использует для отбрасывания повторов.
+const eventIDHeader = "Nats-Msg-Id"
+
+// natsFlushTimeout сколько ждать подтверждения NATS, если у контекста нет срока
+const natsFlushTimeout = 5 * time.Second
+
+// NATSPublisher публикует события в NATS в тему <prefix>.<тип события>,
+// например tracker.parcel.status_changed
+type NATSPublisher struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+// NewNATSPublisher подключается к NATS по url
+func NewNATSPublisher(url, prefix string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("tracker"))
+	if err != nil {
+		return nil, err
+	}
+	return &NATSPublisher{conn: conn, prefix: prefix}, nil
+}
+
+// Publish отправляет событие и ждёт, пока сервер NATS его примет
+func (p *NATSPublisher) Publish(ctx context.Context, e OutboxEntry) error {
+	msg := nats.NewMsg(p.prefix + "." + e.Type)
+	msg.Data = e.Payload
+	msg.Header.Set(eventIDHeader, strconv.FormatInt(e.ID, 10))
+
+	err := p.conn.PublishMsg(msg)
+	if err != nil {
+		return err
+	}
+	// без Flush ошибка соединения обнаружится только на следующем событии,
+	// а это уже будет отмечено опубликованным
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, natsFlushTimeout)
+		defer cancel()
+	}
+	return p.conn.FlushWithContext(ctx)
+}
+
+// Close отправляет оставшиеся сообщения и закрывает соединение
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
+
+// KafkaPublisher публикует события в топик Kafka. Ключ сообщения — номер посылки,
+// поэтому события одной посылки попадают в одну партицию и читаются по порядку.
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher создаёт публикацию в топик topic на брокерах brokers
+func NewKafkaPublisher(brokers []string, topic string) *KafkaPublisher {
+	return &KafkaPublisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}}
+}
+
+// Publish отправляет событие и ждёт подтверждения всех реплик
+func (p *KafkaPublisher) Publish(ctx context.Context, e OutboxEntry) error {
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(strconv.Itoa(e.Number)),
+		Value: e.Payload,
+		Headers: []kafka.Header{
+			{Key: "type", Value: []byte(e.Type)},
+			{Key: "id", Value: []byte(strconv.FormatInt(e.ID, 10))},
+		},
+	})
+}
+
+// Close закрывает соединения с брокерами
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// newEventPublisher создаёт получателя событий outbox по настройкам и функцию,
+// которая его закрывает. Без получателя в настройках возвращает nil.
+func newEventPublisher(cfg config.Outbox, logger Logger) (EventPublisher, func() error, error) {
+	noClose := func() error { return nil }
+	switch cfg.Publisher {
+	case "log":
+		return LogPublisher{Logger: logger}, noClose, nil
+	case "nats":
+		p, err := NewNATSPublisher(cfg.NATS.URL, cfg.NATS.SubjectPrefix)
+		if err != nil {
+			return nil, nil, err
+		}
+		return p, p.Close, nil
+	case "kafka":
+		p := NewKafkaPublisher(cfg.Kafka.Brokers, cfg.Kafka.Topic)
+		return p, p.Close, nil
+	}
+	return nil, noClose, nil
+}
@@ -0,0 +1,44 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNATSPublisher проверяет, что релей outbox публикует события в NATS с темой по типу события
+func TestNATSPublisher(t *testing.T) {
+	// prepare
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	srv := natsserver.RunServer(&opts)
+	defer srv.Shutdown()
+
+	conn, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer conn.Close()
+	sub, err := conn.SubscribeSync("tracker.parcel.>")
+	require.NoError(t, err)
+	require.NoError(t, conn.Flush())
+
+	publisher, err := NewNATSPublisher(srv.ClientURL(), "tracker")
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	entry := OutboxEntry{ID: 42, Type: EventParcelStatusChanged, Number: 7, Payload: []byte(`{"number":7}`)}
+
+	// publish
+	require.NoError(t, publisher.Publish(context.Background(), entry))
+
+	// check
+	msg, err := sub.NextMsg(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "tracker.parcel.status_changed", msg.Subject)
+	assert.Equal(t, entry.Payload, msg.Data)
+	assert.Equal(t, "42", msg.Header.Get(eventIDHeader))
+}
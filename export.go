@@ -0,0 +1,361 @@
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const queryRestoreParcel = `INSERT INTO parcel (number, client, status, address, created_at, tracking_token, tenant_id, version)
+	VALUES (:number, :client, :status, :address, :created_at, NULLIF(:tracking_token, ''), :tenant, MAX(:version, 1)) ON CONFLICT (number) DO NOTHING`
+
+// queryRestoreDeliveredAt время первой доставки загруженной посылки по её истории
+const queryRestoreDeliveredAt = `UPDATE parcel SET delivered_at = COALESCE(
+	(SELECT MIN(h.changed_at) FROM parcel_history h WHERE h.number = parcel.number AND h.status = 'delivered'),
+	created_at) WHERE number = :number AND status = 'delivered'`
+
+var (
+	// ErrParcelExists возвращается при загрузке выгрузки, если посылка с таким номером уже есть в БД
+	ErrParcelExists = errors.New("посылка с таким номером уже есть")
+	// ErrUnknownColumn возвращается при выгрузке CSV с колонкой, которой нет у посылки
+	ErrUnknownColumn = errors.New("неизвестная колонка выгрузки")
+)
+
+// CSVColumns колонки выгрузки CSV в порядке по умолчанию
+var CSVColumns = []string{"number", "client", "status", "address", "created_at", "tracking_token"}
+
+// csvColumnValue значения колонок выгрузки CSV для посылки
+var csvColumnValue = map[string]func(Parcel) string{
+	"number":         func(p Parcel) string { return strconv.Itoa(p.Number) },
+	"client":         func(p Parcel) string { return strconv.Itoa(p.Client) },
+	"status":         func(p Parcel) string { return p.Status },
+	"address":        func(p Parcel) string { return p.Address },
+	"created_at":     func(p Parcel) string { return p.CreatedAt },
+	"tracking_token": func(p Parcel) string { return p.TrackingToken },
+}
+
+// CSVExportOptions выгрузка CSV: фильтры посылок и колонки в нужном порядке;
+// без колонок выгружаются все CSVColumns
+type CSVExportOptions struct {
+	ListOptions
+	Columns []string
+}
+
+// ParseCSVColumns разбирает список колонок через запятую, как его передают
+// в командной строке и HTTP-запросе, и проверяет, что колонки известны
+func ParseCSVColumns(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+	var columns []string
+	for _, c := range strings.Split(list, ",") {
+		c = strings.TrimSpace(c)
+		if _, ok := csvColumnValue[c]; !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownColumn, c)
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// parcelRecord посылка с историей в выгрузке NDJSON: одна посылка на строку
+type parcelRecord struct {
+	Number        int    `json:"number"`
+	Client        int    `json:"client"`
+	Status        string `json:"status"`
+	Address       string `json:"address"`
+	CreatedAt     string `json:"created_at"`
+	TrackingToken string `json:"tracking_token,omitempty"`
+	// Version версия посылки; в выгрузках без неё посылка загружается с версией 1
+	Version int             `json:"version,omitempty"`
+	History []historyRecord `json:"history"`
+}
+
+// historyRecord запись истории статусов в выгрузке
+type historyRecord struct {
+	Status    string `json:"status"`
+	ChangedAt string `json:"changed_at"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ExportJSON выгружает в w посылки, подходящие под opts, вместе с историей статусов
+// в формате NDJSON в порядке номеров. Посылки читаются одним запросом и пишутся по мере
+// чтения, поэтому выгрузка не держит в памяти всю БД. Возвращает число выгруженных посылок.
+func (s ParcelStore) ExportJSON(w io.Writer, opts ListOptions) (int, error) {
+	start := time.Now()
+	span := s.startSpan("ExportJSON", attrClient.Int(opts.Client), attrStatus.String(opts.Status))
+	defer span.End()
+
+	// выгрузка читает строки, пока их принимает w, и её длительность ограничивает
+	// контекст запроса, а не тайм-аут чтения
+	s.timeouts.Read = 0
+	n, err := s.exportJSON(w, opts)
+	spanError(span, err)
+	s.metrics.observeQuery("ExportJSON", start)
+	logResult(s.ctx, s.logger, "store.ExportJSON", start, err, "parcels", n)
+	return n, err
+}
+
+func (s ParcelStore) exportJSON(w io.Writer, opts ListOptions) (int, error) {
+	// отбор посылок подзапросом, чтобы Limit ограничивал посылки, а не строки истории
+	filter, args := opts.query("number", s.tenant()).orderBy("number").limitTo(opts.Limit).build()
+
+	rows, err := s.readUnprepared(fmt.Sprintf(queryParcelRecords, filter), args...)
+	if err != nil {
+		return 0, err
+	}
+	return s.writeParcelRecords(w, rows)
+}
+
+// queryParcelRecords посылки с историей для выгрузки NDJSON; %s — подзапрос с номерами посылок
+const queryParcelRecords = `SELECT p.number, p.client, p.status, p.address, p.created_at, COALESCE(p.tracking_token, ''), p.version,
+	h.status, h.changed_at, COALESCE(h.request_id, '')
+	FROM parcel p LEFT JOIN parcel_history h ON h.number = p.number
+	WHERE p.number IN (%s) ORDER BY p.number, h.id`
+
+// writeParcelRecords записывает в w посылки из строк запроса queryParcelRecords
+// с расшифрованными адресами и возвращает их число; rows закрывается
+func (s ParcelStore) writeParcelRecords(w io.Writer, rows timedRows) (int, error) {
+	defer rows.Close()
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	// строки одной посылки идут подряд; посылка пишется, когда начинается следующая
+	var rec *parcelRecord
+	n := 0
+	flush := func() error {
+		if rec == nil {
+			return nil
+		}
+		n++
+		return enc.Encode(rec)
+	}
+	for rows.Next() {
+		var p parcelRecord
+		var status, changedAt sql.NullString
+		var requestID sql.NullString
+		err := rows.Scan(&p.Number, &p.Client, &p.Status, &p.Address, &p.CreatedAt, &p.TrackingToken, &p.Version,
+			&status, &changedAt, &requestID)
+		if err != nil {
+			return n, err
+		}
+		if rec == nil || rec.Number != p.Number {
+			err = flush()
+			if err != nil {
+				return n, err
+			}
+			p.Address, err = s.openAddress(p.Address)
+			if err != nil {
+				return n, err
+			}
+			p.History = []historyRecord{}
+			rec = &p
+		}
+		if status.Valid {
+			rec.History = append(rec.History, historyRecord{Status: status.String, ChangedAt: changedAt.String, RequestID: requestID.String})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	err := flush()
+	if err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
+
+// ImportJSON загружает посылки из выгрузки ExportJSON с теми же номерами, трекинг-токенами,
+// историей и временем изменений. Загрузка идёт в одной транзакции: если посылка с таким
+// номером уже есть или строка выгрузки некорректна, ничего не загружается.
+// Событий outbox загрузка не создаёт — это перенос данных, а не изменения посылок.
+// Поток нельзя прочитать повторно, поэтому запись при блокировке БД не повторяется.
+// Возвращает число загруженных посылок.
+func (s ParcelStore) ImportJSON(r io.Reader) (int, error) {
+	start := time.Now()
+	span := s.startSpan("ImportJSON")
+	defer span.End()
+
+	n, err := s.importJSON(r)
+	spanError(span, err)
+	s.metrics.observeQuery("ImportJSON", start)
+	logResult(s.ctx, s.logger, "store.ImportJSON", start, err, "parcels", n)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s ParcelStore) importJSON(r io.Reader) (int, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	n := 0
+	for {
+		var p parcelRecord
+		err := dec.Decode(&p)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return n, fmt.Errorf("посылка %d выгрузки: %w", n+1, err)
+		}
+		err = s.restoreParcel(tx, p)
+		if err != nil {
+			return n, fmt.Errorf("посылка %d выгрузки (№ %d): %w", n+1, p.Number, err)
+		}
+		n++
+	}
+
+	return n, tx.Commit()
+}
+
+// restoreParcel проверяет посылку из выгрузки и добавляет её с историей в транзакции tx
+func (s ParcelStore) restoreParcel(tx *sql.Tx, p parcelRecord) error {
+	if p.Number < 1 || p.Client < 1 || p.CreatedAt == "" {
+		return errors.New("нужны номер, клиент и время регистрации")
+	}
+	if _, ok := statusRank[p.Status]; !ok {
+		return fmt.Errorf("%w %q", ErrUnknownStatus, p.Status)
+	}
+
+	// посылки выгрузки загружаются в компанию, от имени которой идёт загрузка
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return err
+	}
+	address, err := s.sealAddress(p.Address)
+	if err != nil {
+		return err
+	}
+	res, err := s.exec(tx, queryRestoreParcel,
+		sql.Named("number", p.Number),
+		sql.Named("client", p.Client),
+		sql.Named("status", p.Status),
+		sql.Named("address", address),
+		sql.Named("created_at", p.CreatedAt),
+		sql.Named("tracking_token", p.TrackingToken),
+		sql.Named("tenant", tenant),
+		sql.Named("version", p.Version))
+	if err != nil {
+		return err
+	}
+	added, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if added == 0 {
+		return ErrParcelExists
+	}
+
+	for _, h := range p.History {
+		if _, ok := statusRank[h.Status]; !ok {
+			return fmt.Errorf("%w %q в истории", ErrUnknownStatus, h.Status)
+		}
+		err = s.addHistory(tx, ParcelChange{Number: p.Number, Status: h.Status, ChangedAt: h.ChangedAt, RequestID: h.RequestID})
+		if err != nil {
+			return err
+		}
+	}
+	_, err = s.exec(tx, queryRestoreDeliveredAt, sql.Named("number", p.Number))
+	return err
+}
+
+// ExportCSV выгружает в w посылки, подходящие под фильтры opts, в CSV с заголовком.
+// Строки пишутся по мере чтения из БД, поэтому выгрузка любого размера
+// не собирается в памяти. С redaction.csv адрес и клиент скрываются, как в логах.
+// Возвращает число выгруженных посылок.
+func (s ParcelStore) ExportCSV(w io.Writer, opts CSVExportOptions) (int, error) {
+	start := time.Now()
+	span := s.startSpan("ExportCSV", attrClient.Int(opts.Client), attrStatus.String(opts.Status))
+	defer span.End()
+
+	// выгрузка читает строки, пока их принимает w, и её длительность ограничивает
+	// контекст запроса, а не тайм-аут чтения
+	s.timeouts.Read = 0
+	n, err := s.exportCSV(w, opts)
+	spanError(span, err)
+	s.metrics.observeQuery("ExportCSV", start)
+	logResult(s.ctx, s.logger, "store.ExportCSV", start, err, "parcels", n)
+	return n, err
+}
+
+func (s ParcelStore) exportCSV(w io.Writer, opts CSVExportOptions) (int, error) {
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = CSVColumns
+	}
+	for _, c := range columns {
+		if _, ok := csvColumnValue[c]; !ok {
+			return 0, fmt.Errorf("%w %q", ErrUnknownColumn, c)
+		}
+	}
+
+	query, args := opts.query(parcelColumns, s.tenant()).orderBy("number").limitTo(opts.Limit).build()
+	rows, err := s.readUnprepared(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	cw := csv.NewWriter(w)
+	err = cw.Write(columns)
+	if err != nil {
+		return 0, err
+	}
+	record := make([]string, len(columns))
+	n := 0
+	for rows.Next() {
+		p, err := scanParcel(rows)
+		if err == nil {
+			p, err = s.openParcel(p)
+		}
+		if err != nil {
+			return n, err
+		}
+		for i, c := range columns {
+			record[i] = s.redactor.csvValue(c, csvColumnValue[c](p))
+		}
+		err = cw.Write(record)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	cw.Flush()
+	return n, cw.Error()
+}
+
+// ExportCSV выгружает посылки в CSV; клиент может выгружать только свои посылки
+func (s ParcelService) ExportCSV(ctx context.Context, w io.Writer, opts CSVExportOptions) (int, error) {
+	err := s.check(ctx, actionRead)
+	if err != nil {
+		return 0, err
+	}
+	if c, ok := CallerFromContext(ctx); ok && c.Role == RoleClient {
+		err = authorizeOwner(ctx, opts.Client)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return s.store.WithContext(ctx).ExportCSV(w, opts)
+}
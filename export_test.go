@@ -0,0 +1,153 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExportImportJSON проверяет, что выгрузка и загрузка NDJSON сохраняют номера,
+// трекинг-токены, историю и время изменений посылок
+func TestExportImportJSON(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	parcel := getTestParcel()
+	parcel.Client = randRange.Intn(10_000_000)
+	token, err := newTrackingToken()
+	require.NoError(t, err)
+	parcel.TrackingToken = token
+
+	first, err := store.Add(parcel)
+	require.NoError(t, err)
+	require.NoError(t, store.SetStatus(first, ParcelStatusSent))
+	parcel.TrackingToken = ""
+	second, err := store.Add(parcel)
+	require.NoError(t, err)
+
+	parcels, err := store.GetByClient(parcel.Client)
+	require.NoError(t, err)
+	histories := map[int][]ParcelChange{}
+	for _, p := range parcels {
+		histories[p.Number], err = store.History(p.Number)
+		require.NoError(t, err)
+	}
+
+	// export
+	var buf bytes.Buffer
+	n, err := store.ExportJSON(&buf, ListOptions{Client: parcel.Client})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var rec parcelRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	assert.Equal(t, first, rec.Number)
+	require.Len(t, rec.History, 2)
+	assert.Equal(t, ParcelStatusSent, rec.History[1].Status)
+
+	var limited bytes.Buffer
+	n, err = store.ExportJSON(&limited, ListOptions{Client: parcel.Client, Status: ParcelStatusRegistered, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, lines[1]+"\n", limited.String())
+
+	// посылки удаляются из БД, как при переносе в другое окружение
+	for _, number := range []int{first, second} {
+		_, err = db.Exec("DELETE FROM parcel_history WHERE number = ?", number)
+		require.NoError(t, err)
+		_, err = db.Exec("DELETE FROM parcel WHERE number = ?", number)
+		require.NoError(t, err)
+	}
+
+	// import
+	n, err = store.ImportJSON(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// check
+	restored, err := store.GetByClient(parcel.Client)
+	require.NoError(t, err)
+	assert.Equal(t, parcels, restored)
+	for _, p := range restored {
+		history, err := store.History(p.Number)
+		require.NoError(t, err)
+		assert.Equal(t, histories[p.Number], history)
+	}
+
+	// повторная загрузка ничего не меняет
+	_, err = store.ImportJSON(bytes.NewReader(buf.Bytes()))
+	assert.ErrorIs(t, err, ErrParcelExists)
+	_, err = store.ImportJSON(strings.NewReader(`{"number": 1, "client": 1, "status": "lost", "created_at": "2024-01-01T00:00:00Z"}`))
+	assert.ErrorIs(t, err, ErrUnknownStatus)
+	history, err := store.History(first)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+// TestExportCSV проверяет выгрузку CSV с выбранными колонками и фильтрами
+// и её отдачу по HTTP
+func TestExportCSV(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	parcel := getTestParcel()
+	parcel.Client = randRange.Intn(10_000_000)
+	parcel.Address = "Псков, ул. Колотушкина, д. 5"
+	parcel.CreatedAt = "2021-03-01T10:00:00Z"
+	march, err := store.Add(parcel)
+	require.NoError(t, err)
+	parcel.CreatedAt = "2021-05-01T10:00:00Z"
+	may, err := store.Add(parcel)
+	require.NoError(t, err)
+
+	// export
+	var buf bytes.Buffer
+	n, err := store.ExportCSV(&buf, CSVExportOptions{
+		ListOptions: ListOptions{Client: parcel.Client, From: time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)},
+		Columns:     []string{"number", "address"},
+	})
+	require.NoError(t, err)
+
+	// check
+	assert.Equal(t, 1, n)
+	assert.Equal(t, fmt.Sprintf("number,address\n%d,\"Псков, ул. Колотушкина, д. 5\"\n", may), buf.String())
+
+	_, err = store.ExportCSV(io.Discard, CSVExportOptions{Columns: []string{"weight"}})
+	assert.ErrorIs(t, err, ErrUnknownColumn)
+	_, err = ParseCSVColumns("number, weight")
+	assert.ErrorIs(t, err, ErrUnknownColumn)
+
+	srv := httptest.NewServer(NewHTTPHandler(NewParcelService(store)))
+	defer srv.Close()
+
+	resp, err := http.Get(fmt.Sprintf("%s/reports/parcels.csv?client=%d&to=2021-04-01T00:00:00Z&columns=number,status", srv.URL, parcel.Client))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("number,status\n%d,registered\n", march), string(body))
+
+	resp, err = http.Get(srv.URL + "/reports/parcels.csv?columns=weight")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
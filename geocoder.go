@@ -0,0 +1,177 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// ErrAddressNotFound возвращается геокодером, который не знает координат адреса
+var ErrAddressNotFound = errors.New("координаты адреса не найдены")
+
+const (
+	queryUpsertCoordinates = `INSERT INTO parcel_geo (number, tenant_id, lat, lon, geocoded_at)
+		VALUES (:number, :tenant, :lat, :lon, :geocoded_at)
+		ON CONFLICT (number) DO UPDATE SET lat = excluded.lat, lon = excluded.lon, geocoded_at = excluded.geocoded_at`
+	queryCoordinates       = "SELECT lat, lon FROM parcel_geo WHERE number = :number AND " + tenantCond
+	queryDeleteCoordinates = "DELETE FROM parcel_geo WHERE number = :number"
+)
+
+// Coordinates широта и долгота точки в градусах
+type Coordinates struct {
+	Lat float64
+	Lon float64
+}
+
+// Geocoder определяет координаты адреса доставки. Координаты нужны для расчёта
+// стоимости и маршрутов по расстоянию; если адрес неизвестен, возвращается ErrAddressNotFound.
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) (Coordinates, error)
+}
+
+// NopGeocoder геокодер по умолчанию: координаты не определяются
+type NopGeocoder struct{}
+
+func (NopGeocoder) Geocode(context.Context, string) (Coordinates, error) {
+	return Coordinates{}, ErrAddressNotFound
+}
+
+// HTTPGeocoder геокодер с JSON API:
+//
+//	GET {url}/geocode?address=... → {"lat": 57.8136, "lon": 28.3496}
+//
+// Ответ 404 означает, что адрес не найден. Токен передаётся в заголовке Authorization: Bearer.
+type HTTPGeocoder struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewHTTPGeocoder(baseURL, token string, timeout time.Duration) *HTTPGeocoder {
+	return &HTTPGeocoder{url: strings.TrimRight(baseURL, "/"), token: token, client: &http.Client{Timeout: timeout}}
+}
+
+func (g *HTTPGeocoder) Geocode(ctx context.Context, address string) (Coordinates, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.url+"/geocode?address="+url.QueryEscape(address), nil)
+	if err != nil {
+		return Coordinates{}, err
+	}
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return Coordinates{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		io.Copy(io.Discard, resp.Body)
+		return Coordinates{}, ErrAddressNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(io.Discard, resp.Body)
+		return Coordinates{}, fmt.Errorf("геокодер ответил %s", resp.Status)
+	}
+	var res struct {
+		Lat *float64 `json:"lat"`
+		Lon *float64 `json:"lon"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return Coordinates{}, err
+	}
+	if res.Lat == nil || res.Lon == nil || !validLocation(*res.Lat, *res.Lon) {
+		return Coordinates{}, errors.New("геокодер вернул неверные координаты")
+	}
+	return Coordinates{Lat: *res.Lat, Lon: *res.Lon}, nil
+}
+
+// newGeocoder создаёт геокодер из настроек; без geocoder.url координаты не определяются
+func newGeocoder(cfg config.Geocoder) Geocoder {
+	if cfg.URL == "" {
+		return NopGeocoder{}
+	}
+	return NewHTTPGeocoder(cfg.URL, cfg.Token, cfg.Timeout)
+}
+
+// WithGeocoder задаёт геокодер адресов доставки; по умолчанию используется NopGeocoder
+func WithGeocoder(g Geocoder) ServiceOption {
+	return func(s *ParcelService) {
+		s.geocoder = g
+	}
+}
+
+// SetCoordinates записывает координаты адреса доставки посылки number
+func (s ParcelStore) SetCoordinates(number int, c Coordinates) error {
+	start := time.Now()
+	span := s.startSpan("SetCoordinates", attrNumber.Int(number))
+	defer span.End()
+
+	err := s.withRetry("store.SetCoordinates", func(s ParcelStore) error {
+		tenant, err := s.ownTenant()
+		if err != nil {
+			return err
+		}
+		_, err = s.exec(nil, queryUpsertCoordinates,
+			sql.Named("number", number),
+			sql.Named("tenant", tenant),
+			sql.Named("lat", c.Lat),
+			sql.Named("lon", c.Lon),
+			sql.Named("geocoded_at", time.Now().UTC().Format(time.RFC3339)))
+		return err
+	})
+	// координаты указывают на адрес, поэтому в лог не попадают
+	spanError(span, err)
+	s.metrics.observeQuery("SetCoordinates", start)
+	logResult(s.ctx, s.logger, "store.SetCoordinates", start, err, "number", number)
+	return err
+}
+
+// Coordinates возвращает координаты адреса доставки посылки number
+// или sql.ErrNoRows, если они не определены
+func (s ParcelStore) Coordinates(number int) (Coordinates, error) {
+	defer s.metrics.observeQuery("Coordinates", time.Now())
+	span := s.startSpan("Coordinates", attrNumber.Int(number))
+	defer span.End()
+
+	var c Coordinates
+	err := s.readRow(queryCoordinates, sql.Named("number", number), sql.Named("tenant", s.tenant())).Scan(&c.Lat, &c.Lon)
+	return c, spanError(span, err)
+}
+
+// geocode определяет координаты адреса посылки number и записывает их. Без координат
+// посылка доставляется как обычно, поэтому ошибки геокодера только попадают в лог.
+func (s ParcelService) geocode(ctx context.Context, number int, address string) {
+	c, err := s.geocoder.Geocode(ctx, address)
+	if errors.Is(err, ErrAddressNotFound) {
+		return
+	}
+	if err == nil {
+		err = s.store.WithContext(ctx).SetCoordinates(number, c)
+	}
+	if err != nil {
+		s.logger.Log(ctx, slog.LevelWarn, "не удалось определить координаты адреса",
+			withRequestID(ctx, []any{"op", "service.Geocode", "number", number, "error", err})...)
+	}
+}
+
+// Coordinates возвращает координаты адреса доставки посылки; клиент видит только свои посылки
+func (s ParcelService) Coordinates(ctx context.Context, number int) (Coordinates, error) {
+	_, err := s.Get(ctx, number)
+	if err != nil {
+		return Coordinates{}, err
+	}
+	return s.store.WithContext(ctx).Coordinates(number)
+}
@@ -0,0 +1,105 @@
+package tracker_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// mapGeocoder геокодер для тестов: координаты известных адресов из словаря
+type mapGeocoder map[string]tracker.Coordinates
+
+func (g mapGeocoder) Geocode(_ context.Context, address string) (tracker.Coordinates, error) {
+	if address == "сбой" {
+		return tracker.Coordinates{}, errors.New("геокодер недоступен")
+	}
+	c, ok := g[address]
+	if !ok {
+		return tracker.Coordinates{}, tracker.ErrAddressNotFound
+	}
+	return c, nil
+}
+
+// TestGeocode проверяет координаты адреса при регистрации и смене адреса
+func TestGeocode(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	tenant := fmt.Sprintf("geo-%d", testsupport.Intn(10_000_000))
+	ctx := tracker.WithTenant(context.Background(), tenant)
+	pskov := tracker.Coordinates{Lat: 57.8136, Lon: 28.3496}
+	tver := tracker.Coordinates{Lat: 56.8587, Lon: 35.9176}
+	service := tracker.NewParcelService(tracker.NewParcelStore(db), tracker.WithGeocoder(mapGeocoder{
+		"Псков, ул. Колотушкина, д. 5": pskov,
+		"Тверь, ул. Советская, д. 1":   tver,
+	}))
+
+	// register
+	p, err := service.Register(ctx, 1000, "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+	got, err := service.Coordinates(ctx, p.Number)
+	require.NoError(t, err)
+	assert.Equal(t, pskov, got)
+
+	// change address
+	require.NoError(t, service.ChangeAddress(ctx, p.Number, "Тверь, ул. Советская, д. 1"))
+	got, err = service.Coordinates(ctx, p.Number)
+	require.NoError(t, err)
+	assert.Equal(t, tver, got)
+	// координаты прежнего адреса не остаются у посылки с неизвестным адресом
+	require.NoError(t, service.ChangeAddress(ctx, p.Number, "Псков, ул. Неизвестная, д. 1"))
+	_, err = service.Coordinates(ctx, p.Number)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// ошибка геокодера не мешает регистрации
+	p, err = service.Register(ctx, 1000, "сбой")
+	require.NoError(t, err)
+	_, err = service.Coordinates(ctx, p.Number)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// без геокодера координаты не определяются
+	p, err = tracker.NewParcelService(tracker.NewParcelStore(db)).Register(ctx, 1000, "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+	_, err = service.Coordinates(ctx, p.Number)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+// TestHTTPGeocoder проверяет запросы к HTTP API геокодера
+func TestHTTPGeocoder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/geocode", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		switch r.URL.Query().Get("address") {
+		case "Псков, ул. Колотушкина, д. 5":
+			json.NewEncoder(w).Encode(map[string]float64{"lat": 57.8136, "lon": 28.3496})
+		case "Псков":
+			w.Write([]byte(`{"lat": 91, "lon": 0}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	g := tracker.NewHTTPGeocoder(srv.URL+"/", "secret", time.Second)
+	c, err := g.Geocode(context.Background(), "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+	assert.Equal(t, tracker.Coordinates{Lat: 57.8136, Lon: 28.3496}, c)
+	_, err = g.Geocode(context.Background(), "Тверь")
+	assert.ErrorIs(t, err, tracker.ErrAddressNotFound)
+	_, err = g.Geocode(context.Background(), "Псков")
+	assert.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// update перезаписывает эталонные файлы вместо сравнения с ними:
+// go test -run Golden -update .
+var update = flag.Bool("update", false, "перезаписать эталонные файлы testdata/*.golden")
+
+// assertGolden сравнивает got с эталоном testdata/<name>.golden. Изменение формата
+// выгрузки или документа видно в диффе эталона, а не только по упавшему тесту.
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		require.NoError(t, os.MkdirAll("testdata", 0o755))
+		require.NoError(t, os.WriteFile(path, got, 0o644))
+		return
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "эталон создаётся командой go test -run Golden -update .")
+	if bytes.Equal(want, got) {
+		return
+	}
+	if bytes.HasPrefix(want, []byte("%PDF-")) {
+		t.Errorf("%s: документ отличается от эталона (%d байт вместо %d)", path, len(got), len(want))
+		return
+	}
+	assert.Equal(t, string(want), string(got), path)
+}
+
+// stablePDF записывает в PDF одно и то же время создания и упорядочивает шрифты, чтобы
+// документы совпадали с эталоном байт в байт. Настройки задаются для всего пакета fpdf,
+// поэтому тест с ними нельзя запускать через t.Parallel.
+func stablePDF(t *testing.T) {
+	at := time.Date(2024, time.April, 1, 9, 0, 0, 0, time.UTC)
+	fpdf.SetDefaultCreationDate(at)
+	fpdf.SetDefaultModificationDate(at)
+	fpdf.SetDefaultCatalogSort(true)
+	t.Cleanup(func() {
+		fpdf.SetDefaultCreationDate(time.Time{})
+		fpdf.SetDefaultModificationDate(time.Time{})
+		fpdf.SetDefaultCatalogSort(false)
+	})
+}
+
+// goldenParcels посылки с постоянными номерами, временем и кодами отслеживания
+var goldenParcels = []parcelRecord{
+	{Number: 1, Client: 1000, Status: ParcelStatusDelivered, Address: "Псков, ул. Колотушкина, д. 5",
+		CreatedAt: "2024-03-01T10:00:00Z", TrackingToken: "tok-1", Version: 3, History: []historyRecord{
+			{Status: ParcelStatusRegistered, ChangedAt: "2024-03-01T10:00:00Z"},
+			{Status: ParcelStatusSent, ChangedAt: "2024-03-02T08:30:00Z", RequestID: "req-1"},
+			{Status: ParcelStatusDelivered, ChangedAt: "2024-03-04T17:15:00Z"},
+		}},
+	{Number: 2, Client: 1000, Status: ParcelStatusSent, Address: "Москва, наб. \"Пресненская\", д. 12, кв. 3",
+		CreatedAt: "2024-03-05T12:00:00Z", TrackingToken: "tok-2", Version: 2, History: []historyRecord{
+			{Status: ParcelStatusRegistered, ChangedAt: "2024-03-05T12:00:00Z"},
+			{Status: ParcelStatusSent, ChangedAt: "2024-03-06T09:00:00Z"},
+		}},
+	{Number: 3, Client: 1001, Status: ParcelStatusRegistered, Address: "Казань, ул. Баумана, д. 1",
+		CreatedAt: "2024-03-07T00:00:00Z", TrackingToken: "tok-3", Version: 1, History: []historyRecord{
+			{Status: ParcelStatusRegistered, ChangedAt: "2024-03-07T00:00:00Z"},
+		}},
+}
+
+// TestExportGolden сверяет выгрузки NDJSON и CSV с эталонами
+func TestExportGolden(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	store := newTestStore(t)
+	var ndjson bytes.Buffer
+	enc := json.NewEncoder(&ndjson)
+	for _, p := range goldenParcels {
+		require.NoError(t, enc.Encode(p))
+	}
+	_, err := store.ImportJSON(&ndjson)
+	require.NoError(t, err)
+
+	// json
+	var buf bytes.Buffer
+	_, err = store.ExportJSON(&buf, ListOptions{})
+	require.NoError(t, err)
+	assertGolden(t, "export.ndjson", buf.Bytes())
+
+	// csv
+	buf.Reset()
+	_, err = store.ExportCSV(&buf, CSVExportOptions{})
+	require.NoError(t, err)
+	assertGolden(t, "export.csv", buf.Bytes())
+}
+
+// TestInvoiceGolden сверяет счёт в CSV и PDF с эталонами
+func TestInvoiceGolden(t *testing.T) {
+	stablePDF(t)
+	invoice := Invoice{
+		ID:        7,
+		Client:    1000,
+		Month:     time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		Total:     81050,
+		CreatedAt: "2024-04-01T09:00:00Z",
+		Lines: []InvoiceLine{
+			{Number: 1, DeliveredAt: "2024-03-04T17:15:00Z", Priority: PriorityStandard, Weight: 1500, Price: 40000},
+			{Number: 4, DeliveredAt: "2024-03-20T11:00:00Z", Priority: PriorityExpress, Weight: 250, Price: 41050},
+			{Number: 5, DeliveredAt: "2024-03-29T15:45:00Z", Priority: PriorityStandard},
+		},
+	}
+
+	for _, format := range []string{InvoiceCSV, InvoicePDF} {
+		var buf bytes.Buffer
+		require.NoError(t, invoice.Write(&buf, format))
+		assertGolden(t, "invoice."+format, buf.Bytes())
+	}
+}
+
+// TestLabelGolden сверяет этикетку в ZPL и PDF с эталонами
+func TestLabelGolden(t *testing.T) {
+	stablePDF(t)
+	p := goldenParcels[1]
+	label := Label{
+		Parcel:      Parcel{Number: p.Number, Client: p.Client, Address: p.Address, TrackingToken: p.TrackingToken},
+		Sender:      "Склад №1, Москва",
+		TrackingURL: "https://track.example.com/track/" + p.TrackingToken,
+	}
+
+	for _, format := range []string{LabelZPL, LabelPDF} {
+		var buf bytes.Buffer
+		require.NoError(t, label.Write(&buf, format))
+		assertGolden(t, "label."+format, buf.Bytes())
+	}
+}
@@ -0,0 +1,177 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"net/http"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+//go:embed schema.graphql
+var graphqlSchema string
+
+// NewGraphQLHandler возвращает обработчик GraphQL-запросов по схеме schema.graphql
+func NewGraphQLHandler(service ParcelService) http.Handler {
+	schema := graphql.MustParseSchema(graphqlSchema, &graphqlResolver{service: service},
+		graphql.UseFieldResolvers())
+	return &relay.Handler{Schema: schema}
+}
+
+// graphqlResolver корневой резолвер запросов и мутаций
+type graphqlResolver struct {
+	service ParcelService
+}
+
+func (r *graphqlResolver) Parcel(ctx context.Context, args struct{ Number int32 }) (*parcelResolver, error) {
+	p, err := r.service.Get(ctx, int(args.Number))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &parcelResolver{p: p, service: r.service}, nil
+}
+
+func (r *graphqlResolver) Client(args struct{ ID int32 }) *clientResolver {
+	return &clientResolver{id: int(args.ID), service: r.service}
+}
+
+func (r *graphqlResolver) RegisterParcel(ctx context.Context, args struct {
+	Client  int32
+	Address string
+}) (*parcelResolver, error) {
+	p, err := r.service.Register(ctx, int(args.Client), args.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &parcelResolver{p: p, service: r.service}, nil
+}
+
+func (r *graphqlResolver) SetStatus(ctx context.Context, args struct {
+	Number int32
+	Status string
+}) (*parcelResolver, error) {
+	err := r.service.SetStatus(ctx, int(args.Number), args.Status)
+	if err != nil {
+		return nil, err
+	}
+	return r.mustParcel(ctx, args.Number)
+}
+
+func (r *graphqlResolver) ChangeAddress(ctx context.Context, args struct {
+	Number  int32
+	Address string
+}) (*parcelResolver, error) {
+	err := r.service.ChangeAddress(ctx, int(args.Number), args.Address)
+	if err != nil {
+		return nil, err
+	}
+	return r.mustParcel(ctx, args.Number)
+}
+
+func (r *graphqlResolver) DeleteParcel(ctx context.Context, args struct{ Number int32 }) (bool, error) {
+	err := r.service.Delete(ctx, int(args.Number))
+	if err != nil {
+		return false, err
+	}
+
+	// посылка удаляется только в статусе registered, поэтому проверяем, что её больше нет
+	_, err = r.service.Get(ctx, int(args.Number))
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, nil
+	}
+	return false, err
+}
+
+// mustParcel возвращает посылку после мутации; её отсутствие считается ошибкой
+func (r *graphqlResolver) mustParcel(ctx context.Context, number int32) (*parcelResolver, error) {
+	p, err := r.service.Get(ctx, int(number))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrParcelNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &parcelResolver{p: p, service: r.service}, nil
+}
+
+type clientResolver struct {
+	id      int
+	service ParcelService
+}
+
+func (r *clientResolver) ID() int32 {
+	return int32(r.id)
+}
+
+func (r *clientResolver) Parcels(ctx context.Context) ([]*parcelResolver, error) {
+	parcels, err := r.service.ClientParcels(ctx, r.id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*parcelResolver, 0, len(parcels))
+	for _, p := range parcels {
+		res = append(res, &parcelResolver{p: p, service: r.service})
+	}
+	return res, nil
+}
+
+type parcelResolver struct {
+	p       Parcel
+	service ParcelService
+}
+
+func (r *parcelResolver) Number() int32 {
+	return int32(r.p.Number)
+}
+
+func (r *parcelResolver) Client() *clientResolver {
+	return &clientResolver{id: r.p.Client, service: r.service}
+}
+
+func (r *parcelResolver) Status() string {
+	return r.p.Status
+}
+
+func (r *parcelResolver) Address() string {
+	return r.p.Address
+}
+
+func (r *parcelResolver) CreatedAt() string {
+	return r.p.CreatedAt
+}
+
+func (r *parcelResolver) TrackingToken() string {
+	return r.p.TrackingToken
+}
+
+func (r *parcelResolver) History(ctx context.Context) ([]*statusChangeResolver, error) {
+	history, err := r.service.History(ctx, r.p.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*statusChangeResolver, 0, len(history))
+	for _, c := range history {
+		res = append(res, &statusChangeResolver{c: c})
+	}
+	return res, nil
+}
+
+type statusChangeResolver struct {
+	c ParcelChange
+}
+
+func (r *statusChangeResolver) Status() string {
+	return r.c.Status
+}
+
+func (r *statusChangeResolver) ChangedAt() string {
+	return r.c.ChangedAt
+}
@@ -0,0 +1,70 @@
+package tracker_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestGraphQLClientParcelsHistory проверяет получение клиента с посылками и их историей одним запросом
+func TestGraphQLClientParcelsHistory(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	store := tracker.NewParcelStore(db)
+	srv := httptest.NewServer(tracker.NewHTTPHandler(tracker.NewParcelService(store)))
+	defer srv.Close()
+
+	parcel := testsupport.NewParcel().Build()
+	parcel.Client = testsupport.Intn(10_000_000)
+	id, err := store.Add(parcel)
+	require.NoError(t, err)
+	require.NoError(t, store.SetStatus(id, tracker.ParcelStatusSent))
+
+	// query
+	query := fmt.Sprintf(`{ client(id: %d) { id parcels { number status history { status } } } }`, parcel.Client)
+	body, err := json.Marshal(map[string]string{"query": query})
+	require.NoError(t, err)
+
+	resp, err := http.Post(srv.URL+"/graphql", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// check
+	var res struct {
+		Data struct {
+			Client struct {
+				ID      int
+				Parcels []struct {
+					Number  int
+					Status  string
+					History []struct{ Status string }
+				}
+			}
+		}
+		Errors []any
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	require.Empty(t, res.Errors)
+
+	assert.Equal(t, parcel.Client, res.Data.Client.ID)
+	require.Len(t, res.Data.Client.Parcels, 1)
+	got := res.Data.Client.Parcels[0]
+	assert.Equal(t, id, got.Number)
+	assert.Equal(t, tracker.ParcelStatusSent, got.Status)
+	require.Len(t, got.History, 2)
+	assert.Equal(t, tracker.ParcelStatusRegistered, got.History[0].Status)
+	assert.Equal(t, tracker.ParcelStatusSent, got.History[1].Status)
+}
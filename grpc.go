@@ -0,0 +1,158 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/trackerpb"
+)
+
+//go:generate buf generate
+
+// grpcServer реализует trackerpb.ParcelTrackerServer поверх ParcelService
+type grpcServer struct {
+	trackerpb.UnimplementedParcelTrackerServer
+	service ParcelService
+}
+
+// NewGRPCServer создаёт gRPC-сервер с зарегистрированным сервисом ParcelTracker
+func NewGRPCServer(service ParcelService, opts ...grpc.ServerOption) *grpc.Server {
+	srv := grpc.NewServer(opts...)
+	trackerpb.RegisterParcelTrackerServer(srv, grpcServer{service: service})
+	return srv
+}
+
+func (s grpcServer) AddParcel(ctx context.Context, req *trackerpb.AddParcelRequest) (*trackerpb.Parcel, error) {
+	p, err := s.service.Register(ctx, int(req.GetClient()), req.GetAddress())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return parcelToProto(p), nil
+}
+
+func (s grpcServer) GetParcel(ctx context.Context, req *trackerpb.GetParcelRequest) (*trackerpb.Parcel, error) {
+	p, err := s.service.Get(ctx, int(req.GetNumber()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return parcelToProto(p), nil
+}
+
+func (s grpcServer) ListByClient(ctx context.Context, req *trackerpb.ListByClientRequest) (*trackerpb.ListByClientResponse, error) {
+	parcels, err := s.service.ClientParcels(ctx, int(req.GetClient()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	res := &trackerpb.ListByClientResponse{}
+	for _, p := range parcels {
+		res.Parcels = append(res.Parcels, parcelToProto(p))
+	}
+	return res, nil
+}
+
+func (s grpcServer) SetStatus(ctx context.Context, req *trackerpb.SetStatusRequest) (*trackerpb.Empty, error) {
+	err := s.service.SetStatus(ctx, int(req.GetNumber()), req.GetStatus())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &trackerpb.Empty{}, nil
+}
+
+func (s grpcServer) SetAddress(ctx context.Context, req *trackerpb.SetAddressRequest) (*trackerpb.Empty, error) {
+	err := s.service.ChangeAddress(ctx, int(req.GetNumber()), req.GetAddress())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &trackerpb.Empty{}, nil
+}
+
+func (s grpcServer) DeleteParcel(ctx context.Context, req *trackerpb.DeleteParcelRequest) (*trackerpb.Empty, error) {
+	err := s.service.Delete(ctx, int(req.GetNumber()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &trackerpb.Empty{}, nil
+}
+
+func (s grpcServer) WatchParcel(req *trackerpb.WatchParcelRequest, stream trackerpb.ParcelTracker_WatchParcelServer) error {
+	number := int(req.GetNumber())
+
+	// подписываемся до чтения текущего статуса, чтобы не пропустить изменения между ними
+	changes, cancel := s.service.Watch(number)
+	defer cancel()
+
+	p, err := s.service.Get(stream.Context(), number)
+	if err != nil {
+		return grpcError(err)
+	}
+
+	err = stream.Send(&trackerpb.ParcelStatusUpdate{Number: int64(p.Number), Status: p.Status, ChangedAt: p.CreatedAt})
+	if err != nil {
+		return err
+	}
+	if p.Status == ParcelStatusDelivered {
+		return nil
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case c, ok := <-changes:
+			if !ok {
+				return nil
+			}
+			err := stream.Send(&trackerpb.ParcelStatusUpdate{Number: int64(c.Number), Status: c.Status, ChangedAt: c.ChangedAt})
+			if err != nil {
+				return err
+			}
+			if c.Status == ParcelStatusDelivered {
+				return nil
+			}
+		}
+	}
+}
+
+// parcelToProto переводит посылку в сообщение trackerpb.Parcel
+func parcelToProto(p Parcel) *trackerpb.Parcel {
+	return &trackerpb.Parcel{
+		Number:        int64(p.Number),
+		Client:        int64(p.Client),
+		Status:        p.Status,
+		Address:       p.Address,
+		CreatedAt:     p.CreatedAt,
+		TrackingToken: p.TrackingToken,
+	}
+}
+
+// grpcError переводит ошибки сервиса в gRPC-статусы
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return status.Error(codes.NotFound, ErrParcelNotFound.Error())
+	case errors.Is(err, ErrUnknownStatus), errors.Is(err, ErrInvalidAddress):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, ErrUnauthenticated):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, ErrRateLimited):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, ErrReadOnly), errors.Is(err, ErrStoreUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, ErrStoreTimeout):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, ErrDuplicateParcel):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ErrMergedParcel), errors.Is(err, ErrInvalidTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
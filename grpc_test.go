@@ -0,0 +1,130 @@
+package tracker_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+	"github.com/DaniilStelmakh/tracker-parcel-go/trackerpb"
+)
+
+// TestGRPCParcelLifecycle проверяет добавление, получение, изменение и удаление посылки через gRPC
+func TestGRPCParcelLifecycle(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	// подключение к БД и запуск сервера в памяти
+	db := testsupport.OpenDB(t)
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := tracker.NewGRPCServer(tracker.NewParcelService(tracker.NewParcelStore(db)))
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := trackerpb.NewParcelTrackerClient(conn)
+	ctx := context.Background()
+	clientID := int64(testsupport.Intn(10_000_000))
+
+	// add
+	added, err := client.AddParcel(ctx, &trackerpb.AddParcelRequest{Client: clientID, Address: "test"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, added.GetNumber())
+	assert.Equal(t, tracker.ParcelStatusRegistered, added.GetStatus())
+
+	// get
+	stored, err := client.GetParcel(ctx, &trackerpb.GetParcelRequest{Number: added.GetNumber()})
+	require.NoError(t, err)
+	assert.Equal(t, added.GetAddress(), stored.GetAddress())
+
+	// list by client
+	list, err := client.ListByClient(ctx, &trackerpb.ListByClientRequest{Client: clientID})
+	require.NoError(t, err)
+	require.Len(t, list.GetParcels(), 1)
+	assert.Equal(t, added.GetNumber(), list.GetParcels()[0].GetNumber())
+
+	// set address
+	_, err = client.SetAddress(ctx, &trackerpb.SetAddressRequest{Number: added.GetNumber(), Address: "new test address"})
+	require.NoError(t, err)
+
+	// set status
+	// неизвестный статус отклоняется
+	_, err = client.SetStatus(ctx, &trackerpb.SetStatusRequest{Number: added.GetNumber(), Status: "lost"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// delete
+	// после удаления посылка не находится
+	_, err = client.DeleteParcel(ctx, &trackerpb.DeleteParcelRequest{Number: added.GetNumber()})
+	require.NoError(t, err)
+
+	_, err = client.GetParcel(ctx, &trackerpb.GetParcelRequest{Number: added.GetNumber()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// TestGRPCWatchParcel проверяет, что WatchParcel присылает изменения статуса до доставки
+func TestGRPCWatchParcel(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	store := tracker.NewParcelStore(db)
+	lis := bufconn.Listen(1024 * 1024)
+	srv := tracker.NewGRPCServer(tracker.NewParcelService(store))
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := trackerpb.NewParcelTrackerClient(conn)
+	id, err := store.Add(testsupport.NewParcel().Build())
+	require.NoError(t, err)
+
+	// watch
+	// первым приходит текущий статус
+	stream, err := client.WatchParcel(context.Background(), &trackerpb.WatchParcelRequest{Number: int64(id)})
+	require.NoError(t, err)
+
+	update, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, tracker.ParcelStatusRegistered, update.GetStatus())
+
+	// check
+	// каждое изменение статуса приходит в поток, после доставки поток завершается
+	require.NoError(t, store.SetStatus(id, tracker.ParcelStatusSent))
+	update, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, tracker.ParcelStatusSent, update.GetStatus())
+
+	require.NoError(t, store.SetStatus(id, tracker.ParcelStatusDelivered))
+	update, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, tracker.ParcelStatusDelivered, update.GetStatus())
+
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,85 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// healthCheckTimeout сколько ждать ответа БД при проверке состояния
+const healthCheckTimeout = 2 * time.Second
+
+// HealthReport результат проверки состояния сервиса
+type HealthReport struct {
+	// Status ok, если проверка пройдена, иначе fail
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// SchemaVersion и следующие поля заполняются только проверкой готовности
+	SchemaVersion   int              `json:"schema_version,omitempty"`
+	ExpectedVersion int              `json:"expected_version,omitempty"`
+	Migrations      []MigrationState `json:"migrations,omitempty"`
+}
+
+// Health проверяет, что основная БД и БД для чтения отвечают
+func (s ParcelStore) Health(ctx context.Context) HealthReport {
+	err := s.db.PingContext(ctx)
+	if err == nil && s.reader != s.db {
+		err = s.reader.PingContext(ctx)
+	}
+	if err != nil {
+		return HealthReport{Status: "fail", Error: err.Error()}
+	}
+	return HealthReport{Status: "ok"}
+}
+
+// Ready проверяет, что БД отвечает и все миграции схемы применены
+func (s ParcelStore) Ready(ctx context.Context) HealthReport {
+	report := s.Health(ctx)
+	if report.Status != "ok" {
+		return report
+	}
+
+	report.ExpectedVersion = migrations[len(migrations)-1].version
+	version, err := SchemaVersion(s.db)
+	if err != nil {
+		return HealthReport{Status: "fail", Error: err.Error()}
+	}
+	report.SchemaVersion = version
+
+	report.Migrations, err = MigrationStatus(s.db)
+	if err != nil {
+		return HealthReport{Status: "fail", Error: err.Error()}
+	}
+
+	if version != report.ExpectedVersion {
+		report.Status = "fail"
+		report.Error = "схема БД не соответствует версии приложения"
+	}
+	return report
+}
+
+// healthHandler отвечает на /healthz (БД отвечает) и /readyz (БД отвечает, схема актуальна):
+// 200 при успешной проверке и 503 при неуспешной, в теле — HealthReport
+type healthHandler struct {
+	store ParcelStore
+	ready bool
+}
+
+func (h healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	report := h.store.Health(ctx)
+	if h.ready {
+		report = h.store.Ready(ctx)
+	}
+
+	code := http.StatusOK
+	if report.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
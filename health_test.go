@@ -0,0 +1,49 @@
+package tracker
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHealthReady проверяет /healthz и /readyz на актуальной и неприведённой схеме
+func TestHealthReady(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	srv := httptest.NewServer(NewHTTPHandler(NewParcelService(NewParcelStore(db))))
+	defer srv.Close()
+
+	// check
+	getJSON(t, srv.URL+"/healthz", http.StatusOK, nil)
+
+	var report HealthReport
+	getJSON(t, srv.URL+"/readyz", http.StatusOK, &report)
+	assert.Equal(t, "ok", report.Status)
+	assert.Equal(t, report.ExpectedVersion, report.SchemaVersion)
+	require.Len(t, report.Migrations, len(migrations))
+	assert.NotEmpty(t, report.Migrations[len(migrations)-1].AppliedAt)
+
+	// БД без миграций отвечает, но к работе не готова
+	empty, err := sql.Open(testConfig.DB.Driver, filepath.Join(t.TempDir(), "empty.db"))
+	require.NoError(t, err)
+	defer empty.Close()
+	srv.Config.Handler = NewHTTPHandler(NewParcelService(NewParcelStore(empty)))
+
+	getJSON(t, srv.URL+"/healthz", http.StatusOK, nil)
+
+	resp, err := http.Get(srv.URL + "/readyz")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.Equal(t, "fail", report.Status)
+}
@@ -0,0 +1,799 @@
+package tracker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/api"
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+//go:generate oapi-codegen -config api/config.yaml api/openapi.yaml
+
+// httpServer реализует api.StrictServerInterface поверх ParcelService
+type httpServer struct {
+	service ParcelService
+	// webhooks включён ли флаг функциональности webhooks; без него API вебхуков отвечает 404
+	webhooks bool
+}
+
+// NewHTTPHandler возвращает обработчик HTTP API, описанного в api/openapi.yaml,
+// вместе с дополнительными эндпоинтами /graphql, /ws, /events и админкой /admin
+func NewHTTPHandler(service ParcelService) http.Handler {
+	return newHTTPHandler(service, config.Default().Features)
+}
+
+// newHTTPHandler собирает HTTP-обработчик; дополнительные эндпоинты
+// подключаются только при включённых флагах функциональности
+func newHTTPHandler(service ParcelService, features config.Features) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /healthz", healthHandler{store: service.store})
+	mux.Handle("GET /readyz", healthHandler{store: service.store, ready: true})
+	if features.Enabled(config.FeatureGraphQL) {
+		mux.Handle("/graphql", NewGraphQLHandler(service))
+	}
+	if features.Enabled(config.FeatureWebSocket) {
+		mux.Handle("/ws", wsHandler{service: service})
+	}
+	if features.Enabled(config.FeatureEvents) {
+		mux.Handle("GET /events", sseHandler{service: service})
+	}
+	if features.Enabled(config.FeatureAdminUI) {
+		mux.Handle("GET /admin", adminHandler{})
+	}
+	if features.Enabled(config.FeatureDebug) {
+		mux.Handle("/debug/", newDebugHandler(service))
+	}
+	if service.metrics != nil {
+		mux.Handle("GET /metrics", service.metrics.Handler())
+	}
+
+	strict := api.NewStrictHandlerWithOptions(httpServer{service: service, webhooks: features.Enabled(config.FeatureWebhooks)}, nil, api.StrictHTTPServerOptions{
+		RequestErrorHandlerFunc: func(w http.ResponseWriter, r *http.Request, err error) {
+			writeJSONError(w, http.StatusBadRequest, err)
+		},
+		ResponseErrorHandlerFunc: func(w http.ResponseWriter, r *http.Request, err error) {
+			code := httpErrorCode(err)
+			if code == http.StatusNotFound {
+				err = ErrParcelNotFound
+			}
+			writeJSONError(w, code, err)
+		},
+	})
+	return LocaleMiddleware(api.HandlerFromMux(strict, mux))
+}
+
+// httpErrorCode возвращает код HTTP-ответа для ошибки сервиса
+func httpErrorCode(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUnknownStatus), errors.Is(err, ErrUnknownColumn),
+		errors.Is(err, ErrUnknownPriority), errors.Is(err, ErrInvalidDimensions),
+		errors.Is(err, ErrInvalidLocation), errors.Is(err, ErrInvalidZone),
+		errors.Is(err, ErrInvalidAttachment), errors.Is(err, ErrInvalidAddress):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrAttachmentTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrNoBlobStore):
+		return http.StatusNotImplemented
+	case errors.Is(err, ErrNoTariff):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrReadOnly), errors.Is(err, ErrStoreUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrStoreTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, ErrDuplicateParcel), errors.Is(err, ErrMergedParcel), errors.Is(err, ErrInvalidTransition):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s httpServer) AddParcel(ctx context.Context, req api.AddParcelRequestObject) (api.AddParcelResponseObject, error) {
+	if req.Body.Address == "" {
+		return api.AddParcel400JSONResponse{ErrorJSONResponse: api.ErrorJSONResponse{Error: "адрес не указан"}}, nil
+	}
+
+	var p Parcel
+	var err error
+	if req.Body.Dimensions != nil {
+		p, err = s.service.RegisterQuoted(ctx, req.Body.Client, req.Body.Address, quoteRequestFromAPI(*req.Body.Dimensions))
+	} else {
+		p, err = s.service.Register(ctx, req.Body.Client, req.Body.Address)
+	}
+	if errors.Is(err, ErrDuplicateParcel) {
+		return api.AddParcel409JSONResponse{Error: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return api.AddParcel201JSONResponse(parcelToAPI(ctx, p)), nil
+}
+
+func (s httpServer) QuoteParcel(ctx context.Context, req api.QuoteParcelRequestObject) (api.QuoteParcelResponseObject, error) {
+	q, err := s.service.Quote(ctx, req.Body.Address, quoteRequestFromAPI(req.Body.Dimensions))
+	if err != nil {
+		return nil, err
+	}
+	return api.QuoteParcel200JSONResponse{
+		Zone:            q.Zone,
+		Priority:        api.Priority(q.Priority),
+		ChargeableGrams: q.ChargeableGrams,
+		Price:           q.Price,
+	}, nil
+}
+
+// quoteRequestFromAPI переводит срочность, вес и размеры из модели HTTP API
+func quoteRequestFromAPI(d api.Dimensions) QuoteRequest {
+	req := QuoteRequest{WeightGrams: d.WeightGrams, LengthCm: d.LengthCm, WidthCm: d.WidthCm, HeightCm: d.HeightCm}
+	if d.Priority != nil {
+		req.Priority = string(*d.Priority)
+	}
+	return req
+}
+
+func (s httpServer) GetParcel(ctx context.Context, req api.GetParcelRequestObject) (api.GetParcelResponseObject, error) {
+	p, err := s.service.Get(ctx, req.Number)
+	if err != nil {
+		return nil, err
+	}
+	return api.GetParcel200JSONResponse{
+		Body:    parcelToAPI(ctx, p),
+		Headers: api.GetParcel200ResponseHeaders{ETag: ETag(p.Version)},
+	}, nil
+}
+
+func (s httpServer) UpdateParcel(ctx context.Context, req api.UpdateParcelRequestObject) (api.UpdateParcelResponseObject, error) {
+	p, err := s.service.Get(ctx, req.Number)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = ifMatch(ctx, p, req.Params.IfMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	// менять адрес можно только у зарегистрированной посылки,
+	// поэтому адрес обновляется раньше статуса
+	if req.Body.Address != nil {
+		if p.Status != ParcelStatusRegistered {
+			return api.UpdateParcel409JSONResponse{Error: "адрес можно изменить только у зарегистрированной посылки"}, nil
+		}
+		err = s.service.ChangeAddress(ctx, req.Number, *req.Body.Address)
+		if err != nil {
+			return nil, err
+		}
+		// смена адреса увеличила версию, и статус меняется уже от неё
+		if version := IfVersionFromContext(ctx); version != 0 {
+			ctx = WithIfVersion(ctx, version+1)
+		}
+	}
+
+	if req.Body.Status != nil {
+		err = s.service.SetStatus(ctx, req.Number, string(*req.Body.Status))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p, err = s.service.Get(ctx, req.Number)
+	if err != nil {
+		return nil, err
+	}
+	return api.UpdateParcel200JSONResponse{
+		Body:    parcelToAPI(ctx, p),
+		Headers: api.UpdateParcel200ResponseHeaders{ETag: ETag(p.Version)},
+	}, nil
+}
+
+func (s httpServer) DeleteParcel(ctx context.Context, req api.DeleteParcelRequestObject) (api.DeleteParcelResponseObject, error) {
+	if req.Params.IfMatch != nil {
+		p, err := s.service.Get(ctx, req.Number)
+		if err != nil {
+			return nil, err
+		}
+		ctx, err = ifMatch(ctx, p, req.Params.IfMatch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.service.Delete(ctx, req.Number)
+	if err != nil {
+		return nil, err
+	}
+	return api.DeleteParcel204Response{}, nil
+}
+
+// ifMatch проверяет заголовок If-Match по текущей версии посылки p и возвращает контекст,
+// изменения в котором применятся, только если посылка не изменится до них.
+// Без заголовка и с If-Match: * версия не проверяется.
+func ifMatch(ctx context.Context, p Parcel, header *string) (context.Context, error) {
+	if header == nil || *header == "*" {
+		return ctx, nil
+	}
+	version, ok := parseETag(*header)
+	if !ok || version != p.Version {
+		return ctx, ErrVersionMismatch
+	}
+	return WithIfVersion(ctx, version), nil
+}
+
+func (s httpServer) ListParcels(ctx context.Context, req api.ListParcelsRequestObject) (api.ListParcelsResponseObject, error) {
+	var opts ListOptions
+	if req.Params.Client != nil {
+		opts.Client = *req.Params.Client
+	}
+	if req.Params.Status != nil {
+		opts.Status = string(*req.Params.Status)
+	}
+	if req.Params.Zone != nil {
+		opts.Zone = *req.Params.Zone
+	}
+	if req.Params.Limit != nil {
+		opts.Limit = *req.Params.Limit
+	}
+
+	parcels, err := s.service.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ListParcels200JSONResponse{}
+	for _, p := range parcels {
+		res = append(res, parcelToAPI(ctx, p))
+	}
+	return res, nil
+}
+
+func (s httpServer) SearchParcels(ctx context.Context, req api.SearchParcelsRequestObject) (api.SearchParcelsResponseObject, error) {
+	var opts ListOptions
+	if req.Params.Client != nil {
+		opts.Client = *req.Params.Client
+	}
+	if req.Params.Status != nil {
+		opts.Status = string(*req.Params.Status)
+	}
+	if req.Params.Limit != nil {
+		opts.Limit = *req.Params.Limit
+	}
+
+	parcels, err := s.service.Search(ctx, req.Params.Q, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.SearchParcels200JSONResponse{}
+	for _, p := range parcels {
+		res = append(res, parcelToAPI(ctx, p))
+	}
+	return res, nil
+}
+
+func (s httpServer) ListOverdueParcels(ctx context.Context, req api.ListOverdueParcelsRequestObject) (api.ListOverdueParcelsResponseObject, error) {
+	var opts ListOptions
+	if req.Params.Client != nil {
+		opts.Client = *req.Params.Client
+	}
+	if req.Params.Limit != nil {
+		opts.Limit = *req.Params.Limit
+	}
+
+	parcels, err := s.service.ListOverdue(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ListOverdueParcels200JSONResponse{}
+	for _, p := range parcels {
+		res = append(res, api.OverdueParcel{
+			Parcel:         parcelToAPI(ctx, p.Parcel),
+			Since:          p.Since,
+			OverdueSeconds: int(p.Overdue.Seconds()),
+			Alerted:        p.Alerted,
+		})
+	}
+	return res, nil
+}
+
+func (s httpServer) ListSLABreaches(ctx context.Context, req api.ListSLABreachesRequestObject) (api.ListSLABreachesResponseObject, error) {
+	var from, to time.Time
+	if req.Params.From != nil {
+		from = *req.Params.From
+	}
+	if req.Params.To != nil {
+		to = *req.Params.To
+	}
+	var opts ListOptions
+	if req.Params.Client != nil {
+		opts.Client = *req.Params.Client
+	}
+	if req.Params.Limit != nil {
+		opts.Limit = *req.Params.Limit
+	}
+
+	breaches, err := s.service.SLABreaches(ctx, from, to, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ListSLABreaches200JSONResponse{}
+	for _, b := range breaches {
+		res = append(res, api.SLABreach{
+			Parcel:      parcelToAPI(ctx, b.Parcel),
+			LateSeconds: int(b.Late.Seconds()),
+		})
+	}
+	return res, nil
+}
+
+func (s httpServer) GetParcelLabel(ctx context.Context, req api.GetParcelLabelRequestObject) (api.GetParcelLabelResponseObject, error) {
+	format := LabelPDF
+	if req.Params.Format != nil {
+		format = string(*req.Params.Format)
+	}
+	if format != LabelPDF && format != LabelZPL {
+		err := fmt.Errorf("%w %q", ErrUnknownLabelFormat, format)
+		return api.GetParcelLabel400JSONResponse{ErrorJSONResponse: api.ErrorJSONResponse{Error: err.Error()}}, nil
+	}
+
+	label, err := s.service.Label(ctx, req.Number)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = label.Write(&buf, format)
+	if err != nil {
+		return nil, err
+	}
+	if format == LabelZPL {
+		return api.GetParcelLabel200ApplicationzplResponse{Body: &buf, ContentLength: int64(buf.Len())}, nil
+	}
+	return api.GetParcelLabel200ApplicationpdfResponse{Body: &buf, ContentLength: int64(buf.Len())}, nil
+}
+
+// ExportParcelsCSV отдаёт выгрузку по мере её записи: CSV пишет горутина в канал,
+// из которого читается тело ответа. Ответ начинается, только когда выгрузка записала
+// заголовок, поэтому ошибки прав и колонок ещё возвращаются обычным JSON-ответом.
+func (s httpServer) ExportParcelsCSV(ctx context.Context, req api.ExportParcelsCSVRequestObject) (api.ExportParcelsCSVResponseObject, error) {
+	var opts CSVExportOptions
+	if req.Params.Client != nil {
+		opts.Client = *req.Params.Client
+	}
+	if req.Params.Status != nil {
+		opts.Status = string(*req.Params.Status)
+	}
+	if req.Params.From != nil {
+		opts.From = *req.Params.From
+	}
+	if req.Params.To != nil {
+		opts.To = *req.Params.To
+	}
+	if req.Params.Columns != nil {
+		columns, err := ParseCSVColumns(*req.Params.Columns)
+		if err != nil {
+			return api.ExportParcelsCSV400JSONResponse{ErrorJSONResponse: api.ErrorJSONResponse{Error: err.Error()}}, nil
+		}
+		opts.Columns = columns
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := s.service.ExportCSV(ctx, pw, opts)
+		pw.CloseWithError(err)
+	}()
+	body := bufio.NewReader(pr)
+	_, err := body.Peek(1)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	// тело закрывается после отправки ответа, и горутина, если клиент отключился,
+	// получает ошибку записи и завершается
+	return api.ExportParcelsCSV200TextcsvResponse{Body: pipeBody{Reader: body, Closer: pr}}, nil
+}
+
+// pipeBody тело ответа, которое читается из канала и закрывает его
+type pipeBody struct {
+	*bufio.Reader
+	io.Closer
+}
+
+func (s httpServer) ListClientParcels(ctx context.Context, req api.ListClientParcelsRequestObject) (api.ListClientParcelsResponseObject, error) {
+	parcels, err := s.service.ClientParcels(ctx, req.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ListClientParcels200JSONResponse{}
+	for _, p := range parcels {
+		res = append(res, parcelToAPI(ctx, p))
+	}
+	return res, nil
+}
+
+func (s httpServer) GetShipment(ctx context.Context, req api.GetShipmentRequestObject) (api.GetShipmentResponseObject, error) {
+	sh, err := s.service.Shipment(ctx, req.Number)
+	if errors.Is(err, ErrNoShipment) {
+		return api.GetShipment404JSONResponse{Error: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return api.GetShipment200JSONResponse(shipmentToAPI(sh)), nil
+}
+
+func (s httpServer) HandOffParcel(ctx context.Context, req api.HandOffParcelRequestObject) (api.HandOffParcelResponseObject, error) {
+	sh, err := s.service.HandOff(ctx, req.Number, req.Body.Carrier)
+	switch {
+	case errors.Is(err, ErrUnknownCarrier):
+		return api.HandOffParcel400JSONResponse{ErrorJSONResponse: api.ErrorJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, ErrAlreadyHandedOff):
+		return api.HandOffParcel409JSONResponse{Error: err.Error()}, nil
+	case err != nil:
+		return nil, err
+	}
+	return api.HandOffParcel201JSONResponse(shipmentToAPI(sh)), nil
+}
+
+func (s httpServer) CancelHandOff(ctx context.Context, req api.CancelHandOffRequestObject) (api.CancelHandOffResponseObject, error) {
+	err := s.service.CancelHandOff(ctx, req.Number)
+	if errors.Is(err, ErrNoShipment) {
+		return api.CancelHandOff404JSONResponse{Error: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return api.CancelHandOff204Response{}, nil
+}
+
+func (s httpServer) ListAttachments(ctx context.Context, req api.ListAttachmentsRequestObject) (api.ListAttachmentsResponseObject, error) {
+	attachments, err := s.service.Attachments(ctx, req.Number)
+	if err != nil {
+		return nil, err
+	}
+	res := api.ListAttachments200JSONResponse{}
+	for _, a := range attachments {
+		res = append(res, attachmentToAPI(a))
+	}
+	return res, nil
+}
+
+func (s httpServer) AddAttachment(ctx context.Context, req api.AddAttachmentRequestObject) (api.AddAttachmentResponseObject, error) {
+	a, err := s.service.AddAttachment(ctx, req.Number, string(req.Params.Kind), req.Params.Name, req.Body)
+	if err != nil {
+		return nil, err
+	}
+	return api.AddAttachment201JSONResponse(attachmentToAPI(a)), nil
+}
+
+func (s httpServer) DownloadAttachment(ctx context.Context, req api.DownloadAttachmentRequestObject) (api.DownloadAttachmentResponseObject, error) {
+	a, body, err := s.service.OpenAttachment(ctx, req.Number, req.Id)
+	if errors.Is(err, ErrNoAttachment) {
+		return api.DownloadAttachment404JSONResponse{Error: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return api.DownloadAttachment200AsteriskResponse{
+		Body:          body,
+		Headers:       api.DownloadAttachment200ResponseHeaders{ContentDisposition: mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+		ContentType:   a.ContentType,
+		ContentLength: a.Size,
+	}, nil
+}
+
+func (s httpServer) DeleteAttachment(ctx context.Context, req api.DeleteAttachmentRequestObject) (api.DeleteAttachmentResponseObject, error) {
+	err := s.service.DeleteAttachment(ctx, req.Number, req.Id)
+	if errors.Is(err, ErrNoAttachment) {
+		return api.DeleteAttachment404JSONResponse{Error: err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return api.DeleteAttachment204Response{}, nil
+}
+
+// attachmentToAPI переводит документ посылки в представление API
+func attachmentToAPI(a Attachment) api.Attachment {
+	return api.Attachment{
+		Id:          a.ID,
+		Number:      a.Number,
+		Kind:        api.AttachmentKind(a.Kind),
+		Name:        a.Name,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		Sha256:      a.SHA256,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+func (s httpServer) TrackParcel(ctx context.Context, req api.TrackParcelRequestObject) (api.TrackParcelResponseObject, error) {
+	view, err := s.service.Track(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	locale := LocaleFromContext(ctx)
+	res := api.TrackParcel200JSONResponse{
+		Status:      api.Status(view.Status),
+		StatusLabel: StatusLabel(view.Status, locale),
+		City:        view.City,
+		CreatedAt:   view.CreatedAt,
+		Eta:         optional(view.ETA),
+		History:     []api.StatusChange{},
+	}
+	for _, c := range view.History {
+		res.History = append(res.History, api.StatusChange{
+			Status:      api.Status(c.Status),
+			StatusLabel: StatusLabel(c.Status, locale),
+			ChangedAt:   c.ChangedAt,
+		})
+	}
+	if c := view.Courier; c != nil {
+		res.Courier = &api.CourierNearby{Lat: c.Lat, Lon: c.Lon, ReportedAt: c.ReportedAt, StopsBefore: c.StopsBefore}
+	}
+	return res, nil
+}
+
+func (s httpServer) GetCourierLocation(ctx context.Context, req api.GetCourierLocationRequestObject) (api.GetCourierLocationResponseObject, error) {
+	loc, err := s.service.LastLocation(ctx, req.Courier)
+	if err != nil {
+		return nil, err
+	}
+	return api.GetCourierLocation200JSONResponse(courierLocationToAPI(loc)), nil
+}
+
+func (s httpServer) ReportCourierLocation(ctx context.Context, req api.ReportCourierLocationRequestObject) (api.ReportCourierLocationResponseObject, error) {
+	loc, err := s.service.ReportLocation(ctx, req.Courier, req.Body.Lat, req.Body.Lon)
+	if err != nil {
+		return nil, err
+	}
+	return api.ReportCourierLocation200JSONResponse(courierLocationToAPI(loc)), nil
+}
+
+// courierLocationToAPI переводит положение курьера в модель HTTP API
+func courierLocationToAPI(loc CourierLocation) api.CourierLocation {
+	return api.CourierLocation{Courier: loc.Courier, Lat: loc.Lat, Lon: loc.Lon, ReportedAt: loc.ReportedAt}
+}
+
+// Ошибки API вебхуков, которые отдаются с кодом 404
+var (
+	errWebhooksDisabled = api.Error{Error: "вебхуки выключены"}
+	errWebhookNotFound  = api.Error{Error: "вебхук не найден"}
+	errDeliveryNotFound = api.Error{Error: "проваленная доставка не найдена"}
+)
+
+func (s httpServer) ListWebhooks(ctx context.Context, req api.ListWebhooksRequestObject) (api.ListWebhooksResponseObject, error) {
+	if !s.webhooks {
+		return api.ListWebhooks404JSONResponse(errWebhooksDisabled), nil
+	}
+	hooks, err := s.service.Webhooks(ctx, req.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ListWebhooks200JSONResponse{}
+	for _, w := range hooks {
+		res = append(res, webhookToAPI(w))
+	}
+	return res, nil
+}
+
+func (s httpServer) AddWebhook(ctx context.Context, req api.AddWebhookRequestObject) (api.AddWebhookResponseObject, error) {
+	if !s.webhooks {
+		return api.AddWebhook404JSONResponse(errWebhooksDisabled), nil
+	}
+	w, err := s.service.AddWebhook(ctx, req.Client, req.Body.Url)
+	if errors.Is(err, ErrInvalidWebhookURL) {
+		return api.AddWebhook400JSONResponse{ErrorJSONResponse: api.ErrorJSONResponse{Error: err.Error()}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return api.AddWebhook201JSONResponse(webhookToAPI(w)), nil
+}
+
+func (s httpServer) DeleteWebhook(ctx context.Context, req api.DeleteWebhookRequestObject) (api.DeleteWebhookResponseObject, error) {
+	if !s.webhooks {
+		return api.DeleteWebhook404JSONResponse(errWebhooksDisabled), nil
+	}
+	err := s.service.DeleteWebhook(ctx, req.Client, req.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return api.DeleteWebhook404JSONResponse(errWebhookNotFound), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return api.DeleteWebhook204Response{}, nil
+}
+
+func (s httpServer) ListWebhookFailures(ctx context.Context, req api.ListWebhookFailuresRequestObject) (api.ListWebhookFailuresResponseObject, error) {
+	if !s.webhooks {
+		return api.ListWebhookFailures404JSONResponse(errWebhooksDisabled), nil
+	}
+	failures, err := s.service.WebhookFailures(ctx, req.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ListWebhookFailures200JSONResponse{}
+	for _, d := range failures {
+		res = append(res, api.WebhookDelivery{
+			Id:        d.ID,
+			WebhookId: d.WebhookID,
+			Url:       d.URL,
+			EventId:   d.EventID,
+			EventType: d.EventType,
+			Attempts:  d.Attempts,
+			LastError: d.LastError,
+			CreatedAt: d.CreatedAt,
+		})
+	}
+	return res, nil
+}
+
+func (s httpServer) ReplayWebhookFailure(ctx context.Context, req api.ReplayWebhookFailureRequestObject) (api.ReplayWebhookFailureResponseObject, error) {
+	if !s.webhooks {
+		return api.ReplayWebhookFailure404JSONResponse(errWebhooksDisabled), nil
+	}
+	err := s.service.ReplayWebhookFailure(ctx, req.Client, req.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return api.ReplayWebhookFailure404JSONResponse(errDeliveryNotFound), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return api.ReplayWebhookFailure202Response{}, nil
+}
+
+func (s httpServer) ListNotificationPreferences(ctx context.Context, req api.ListNotificationPreferencesRequestObject) (api.ListNotificationPreferencesResponseObject, error) {
+	prefs, err := s.service.NotificationPreferences(ctx, req.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ListNotificationPreferences200JSONResponse{}
+	for _, p := range prefs {
+		res = append(res, preferenceToAPI(p))
+	}
+	return res, nil
+}
+
+func (s httpServer) SetNotificationPreference(ctx context.Context, req api.SetNotificationPreferenceRequestObject) (api.SetNotificationPreferenceResponseObject, error) {
+	pref := NotificationPreference{Client: req.Client, Channel: string(req.Channel), Recipient: req.Body.Recipient}
+	if req.Body.Locale != nil {
+		pref.Locale = string(*req.Body.Locale)
+	}
+	if req.Body.Statuses != nil {
+		for _, status := range *req.Body.Statuses {
+			pref.Statuses = append(pref.Statuses, string(status))
+		}
+	}
+
+	pref, err := s.service.SetNotificationPreference(ctx, pref)
+	if errors.Is(err, ErrInvalidPreference) || errors.Is(err, ErrUnknownStatus) {
+		return api.SetNotificationPreference400JSONResponse{ErrorJSONResponse: api.ErrorJSONResponse{Error: err.Error()}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return api.SetNotificationPreference200JSONResponse(preferenceToAPI(pref)), nil
+}
+
+func (s httpServer) DeleteNotificationPreference(ctx context.Context, req api.DeleteNotificationPreferenceRequestObject) (api.DeleteNotificationPreferenceResponseObject, error) {
+	err := s.service.DeleteNotificationPreference(ctx, req.Client, string(req.Channel))
+	if errors.Is(err, sql.ErrNoRows) {
+		return api.DeleteNotificationPreference404JSONResponse{Error: "уведомления в этом канале не настроены"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return api.DeleteNotificationPreference204Response{}, nil
+}
+
+func (s httpServer) GetReadOnly(ctx context.Context, req api.GetReadOnlyRequestObject) (api.GetReadOnlyResponseObject, error) {
+	enabled, err := s.service.ReadOnly(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return api.GetReadOnly200JSONResponse{Enabled: enabled}, nil
+}
+
+func (s httpServer) SetReadOnly(ctx context.Context, req api.SetReadOnlyRequestObject) (api.SetReadOnlyResponseObject, error) {
+	err := s.service.SetReadOnly(ctx, req.Body.Enabled)
+	if err != nil {
+		return nil, err
+	}
+	return api.SetReadOnly200JSONResponse{Enabled: req.Body.Enabled}, nil
+}
+
+// preferenceToAPI переводит настройку уведомлений в модель HTTP API
+func preferenceToAPI(p NotificationPreference) api.NotificationPreference {
+	res := api.NotificationPreference{
+		Client:    p.Client,
+		Channel:   api.Channel(p.Channel),
+		Recipient: p.Recipient,
+		Statuses:  []api.Status{},
+		Locale:    api.Locale(p.Locale),
+	}
+	for _, status := range p.Statuses {
+		res.Statuses = append(res.Statuses, api.Status(status))
+	}
+	return res
+}
+
+// shipmentToAPI переводит отправление в модель HTTP API
+func shipmentToAPI(sh Shipment) api.Shipment {
+	return api.Shipment{
+		Number:        sh.Number,
+		Carrier:       sh.Carrier,
+		ExternalId:    sh.ExternalID,
+		CarrierStatus: sh.CarrierStatus,
+		CreatedAt:     sh.CreatedAt,
+		SyncedAt:      sh.SyncedAt,
+	}
+}
+
+// webhookToAPI переводит вебхук в модель HTTP API; пустой секрет не выводится
+func webhookToAPI(w Webhook) api.Webhook {
+	res := api.Webhook{Id: w.ID, Client: w.Client, Url: w.URL, CreatedAt: w.CreatedAt}
+	if w.Secret != "" {
+		res.Secret = &w.Secret
+	}
+	return res
+}
+
+// optional возвращает указатель на v или nil для пустой строки, чтобы необязательное поле не попадало в ответ
+func optional(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+// parcelToAPI переводит посылку в модель HTTP API
+func parcelToAPI(ctx context.Context, p Parcel) api.Parcel {
+	res := api.Parcel{
+		Number:        p.Number,
+		Client:        p.Client,
+		Status:        api.Status(p.Status),
+		StatusLabel:   StatusLabel(p.Status, LocaleFromContext(ctx)),
+		Address:       p.Address,
+		CreatedAt:     p.CreatedAt,
+		TrackingToken: p.TrackingToken,
+		Eta:           optional(p.ETA),
+		Zone:          optional(p.Zone),
+		Deadline:      optional(p.Deadline),
+	}
+	if p.Price != 0 {
+		priority := api.Priority(p.Priority)
+		res.Priority, res.WeightGrams, res.Price = &priority, &p.Weight, &p.Price
+	}
+	return res
+}
+
+// writeJSONError отвечает ошибкой в формате api.Error
+func writeJSONError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(api.Error{Error: err.Error()})
+}
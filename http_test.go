@@ -0,0 +1,269 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/api"
+)
+
+// TestHTTPParcelLifecycle проверяет регистрацию, изменение и удаление посылки через HTTP API
+func TestHTTPParcelLifecycle(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	srv := httptest.NewServer(NewHTTPHandler(NewParcelService(NewParcelStore(db))))
+	defer srv.Close()
+
+	client := randRange.Intn(10_000_000)
+
+	// add
+	resp, err := http.Post(srv.URL+"/parcels", "application/json",
+		strings.NewReader(fmt.Sprintf(`{"client": %d, "address": "test"}`, client)))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var added api.Parcel
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&added))
+	assert.NotEmpty(t, added.Number)
+	assert.Equal(t, api.Registered, added.Status)
+
+	// list by client
+	var list []api.Parcel
+	getJSON(t, fmt.Sprintf("%s/clients/%d/parcels", srv.URL, client), http.StatusOK, &list)
+	require.Len(t, list, 1)
+	assert.Equal(t, added, list[0])
+
+	// update
+	// адрес меняется у зарегистрированной посылки, после отправки — нет
+	parcelURL := fmt.Sprintf("%s/parcels/%d", srv.URL, added.Number)
+	updated := patchJSON(t, parcelURL, `{"address": "new test address", "status": "sent"}`, http.StatusOK)
+	assert.Equal(t, "new test address", updated.Address)
+	assert.Equal(t, api.Sent, updated.Status)
+
+	patchJSON(t, parcelURL, `{"address": "other address"}`, http.StatusConflict)
+	patchJSON(t, parcelURL, `{"status": "lost"}`, http.StatusBadRequest)
+
+	// delete
+	// отправленная посылка не удаляется
+	req, err := http.NewRequest(http.MethodDelete, parcelURL, nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	var stored api.Parcel
+	getJSON(t, parcelURL, http.StatusOK, &stored)
+	assert.Equal(t, updated, stored)
+
+	getJSON(t, fmt.Sprintf("%s/parcels/%d", srv.URL, -1), http.StatusNotFound, nil)
+}
+
+// getJSON выполняет GET-запрос, проверяет код ответа и декодирует тело в v
+func getJSON(t *testing.T, url string, code int, v any) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, code, resp.StatusCode)
+
+	if v != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+}
+
+// patchJSON выполняет PATCH-запрос, проверяет код ответа и возвращает посылку из тела
+func patchJSON(t *testing.T, url, body string, code int) api.Parcel {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, code, resp.StatusCode)
+
+	var p api.Parcel
+	if code == http.StatusOK {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+	}
+	return p
+}
+
+// TestHTTPIfMatch проверяет ETag посылки и отказ в изменении по устаревшему If-Match
+func TestHTTPIfMatch(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	service := NewParcelService(NewParcelStore(db))
+	srv := httptest.NewServer(NewHTTPHandler(service))
+	defer srv.Close()
+
+	p, err := service.Register(context.Background(), randRange.Intn(10_000_000), "test")
+	require.NoError(t, err)
+	parcelURL := fmt.Sprintf("%s/parcels/%d", srv.URL, p.Number)
+
+	do := func(method, body, etag string) *http.Response {
+		req, err := http.NewRequest(method, parcelURL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if etag != "" {
+			req.Header.Set("If-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	// get
+	resp := do(http.MethodGet, "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	// update
+	// адрес и статус в одном запросе меняются по одному ETag
+	resp = do(http.MethodPatch, `{"address": "new test address", "status": "sent"}`, etag)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"3"`, resp.Header.Get("ETag"))
+
+	// check
+	// устаревший и чужой ETag отклоняются, посылка не меняется
+	assert.Equal(t, http.StatusPreconditionFailed, do(http.MethodPatch, `{"status": "delivered"}`, etag).StatusCode)
+	assert.Equal(t, http.StatusPreconditionFailed, do(http.MethodPatch, `{"status": "delivered"}`, `W/"3"`).StatusCode)
+	assert.Equal(t, http.StatusPreconditionFailed, do(http.MethodDelete, "", etag).StatusCode)
+	stored, err := service.Get(context.Background(), p.Number)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusSent, stored.Status)
+
+	// хранилище тоже проверяет версию, поэтому изменение, успевшее раньше, не затирается
+	err = service.store.WithContext(WithIfVersion(context.Background(), 2)).SetStatus(p.Number, ParcelStatusDelivered)
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+	assert.Equal(t, http.StatusOK, do(http.MethodPatch, `{"status": "delivered"}`, `"3"`).StatusCode)
+}
+
+// TestIfVersionSingleConn проверяет отказ по устаревшей версии при пуле из одного соединения:
+// проверка версии выполняется в транзакции изменения
+func TestIfVersionSingleConn(t *testing.T) {
+	// prepare
+	_, store := singleConnStore(t)
+	require.NoError(t, store.Close())
+	number, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+	stale := store.WithContext(WithIfVersion(context.Background(), 99))
+
+	// check
+	withinDeadline(t, func() {
+		assert.ErrorIs(t, stale.SetAddress(number, "new test address"), ErrVersionMismatch)
+		assert.ErrorIs(t, stale.Delete(number), ErrVersionMismatch)
+	})
+}
+
+// TestHTTPTrackParcel проверяет публичное отслеживание посылки по трекинг-токену
+func TestHTTPTrackParcel(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	service := NewParcelService(NewParcelStore(db))
+	srv := httptest.NewServer(NewHTTPHandler(service))
+	defer srv.Close()
+
+	// add
+	// трекинг-токен выдаётся при регистрации
+	p, err := service.Register(context.Background(), randRange.Intn(10_000_000), "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+	require.NotEmpty(t, p.TrackingToken)
+	require.NoError(t, service.SetStatus(context.Background(), p.Number, ParcelStatusSent))
+
+	// track
+	// в ответе есть город и история, но нет полного адреса и клиента
+	var raw map[string]any
+	getJSON(t, srv.URL+"/track/"+p.TrackingToken, http.StatusOK, &raw)
+	assert.Equal(t, "Псков", raw["city"])
+	assert.Equal(t, ParcelStatusSent, raw["status"])
+	assert.Len(t, raw["history"], 2)
+	assert.NotContains(t, raw, "address")
+	assert.NotContains(t, raw, "client")
+	assert.NotContains(t, raw, "number")
+	assert.Equal(t, "в пути", raw["status_label"])
+
+	// подпись статуса на языке из Accept-Language
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/track/"+p.TrackingToken, nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9,ru;q=0.8")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, LocaleEN, resp.Header.Get("Content-Language"))
+	var view api.TrackingView
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&view))
+	assert.Equal(t, "in transit", view.StatusLabel)
+	assert.Equal(t, "registered", view.History[0].StatusLabel)
+
+	getJSON(t, srv.URL+"/track/unknown", http.StatusNotFound, nil)
+}
+
+// TestHTTPListParcelsAndAdmin проверяет поиск посылок и отдачу страницы админки
+func TestHTTPListParcelsAndAdmin(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	srv := httptest.NewServer(NewHTTPHandler(NewParcelService(store)))
+	defer srv.Close()
+
+	parcel := getTestParcel()
+	parcel.Client = randRange.Intn(10_000_000)
+	registered, err := store.Add(parcel)
+	require.NoError(t, err)
+	sent, err := store.Add(parcel)
+	require.NoError(t, err)
+	require.NoError(t, store.SetStatus(sent, ParcelStatusSent))
+
+	// list
+	var list []api.Parcel
+	getJSON(t, fmt.Sprintf("%s/parcels?client=%d", srv.URL, parcel.Client), http.StatusOK, &list)
+	require.Len(t, list, 2)
+	assert.Equal(t, registered, list[0].Number)
+
+	getJSON(t, fmt.Sprintf("%s/parcels?client=%d&status=sent", srv.URL, parcel.Client), http.StatusOK, &list)
+	require.Len(t, list, 1)
+	assert.Equal(t, sent, list[0].Number)
+
+	// admin
+	// страница содержит столбцы всех статусов
+	resp, err := http.Get(srv.URL + "/admin")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	for _, status := range []string{ParcelStatusRegistered, ParcelStatusSent, ParcelStatusDelivered} {
+		assert.Contains(t, string(body), `data-status="`+status+`"`)
+	}
+	assert.Contains(t, string(body), `const statuses = ["registered", "sent", "customs_hold", "delivered"];`)
+}
@@ -0,0 +1,140 @@
+package tracker
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Языки подписей статусов и уведомлений
+const (
+	LocaleRU = "ru"
+	LocaleEN = "en"
+)
+
+// defaultLocale язык, если получатель не выбрал поддерживаемый
+const defaultLocale = LocaleRU
+
+// messages подписи статусов и тексты уведомлений на одном языке
+type messages struct {
+	// statuses подписи статусов для людей
+	statuses map[string]string
+	// sent, delivered и status — уведомления о смене статуса; overdue — оповещение о застрявшей посылке
+	sent, delivered, status, overdue string
+	// subject, track и qr — тема письма, ссылка для отслеживания и подпись QR-кода в письме
+	subject, track, qr string
+}
+
+// catalog переводы по языкам
+var catalog = map[string]messages{
+	LocaleRU: {
+		statuses: map[string]string{
+			ParcelStatusRegistered:  "зарегистрирована",
+			ParcelStatusSent:        "в пути",
+			ParcelStatusCustomsHold: "на таможне",
+			ParcelStatusDelivered:   "доставлена",
+		},
+		sent:      "Посылка №%d отправлена. Отслеживать её можно по коду %s.",
+		delivered: "Посылка №%d доставлена.",
+		status:    "Статус посылки №%d: %s.",
+		overdue:   "Посылка №%d в статусе %s с %s, срок превышен на %s.",
+		subject:   "Посылка №%d",
+		track:     "Отследить посылку",
+		qr:        "QR-код со ссылкой для отслеживания",
+	},
+	LocaleEN: {
+		statuses: map[string]string{
+			ParcelStatusRegistered:  "registered",
+			ParcelStatusSent:        "in transit",
+			ParcelStatusCustomsHold: "held at customs",
+			ParcelStatusDelivered:   "delivered",
+		},
+		sent:      "Parcel #%d has been sent. Track it with code %s.",
+		delivered: "Parcel #%d has been delivered.",
+		status:    "Parcel #%d status: %s.",
+		overdue:   "Parcel #%d has been in status %s since %s, overdue by %s.",
+		subject:   "Parcel #%d",
+		track:     "Track parcel",
+		qr:        "QR code with the tracking link",
+	},
+}
+
+// localeMessages возвращает переводы языка locale или языка по умолчанию
+func localeMessages(locale string) messages {
+	m, ok := catalog[locale]
+	if !ok {
+		return catalog[defaultLocale]
+	}
+	return m
+}
+
+// supportedLocale переведён ли трекер на язык locale
+func supportedLocale(locale string) bool {
+	_, ok := catalog[locale]
+	return ok
+}
+
+// StatusLabel возвращает подпись статуса на языке locale; неизвестный статус возвращается как есть
+func StatusLabel(status, locale string) string {
+	label, ok := localeMessages(locale).statuses[status]
+	if !ok {
+		return status
+	}
+	return label
+}
+
+// NegotiateLocale выбирает язык по заголовку Accept-Language, например
+// «en-US,en;q=0.9,ru;q=0.8»: поддерживаемый язык с наибольшим весом q, а при
+// равных весах — указанный раньше. Региональный вариант языка (en-US) считается
+// самим языком. Без подходящего языка возвращается язык по умолчанию.
+func NegotiateLocale(acceptLanguage string) string {
+	best, bestQ := defaultLocale, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+		}
+		lang, _, _ := strings.Cut(tag, "-")
+		if lang == "*" {
+			lang = defaultLocale
+		}
+		if supportedLocale(lang) && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+type localeKey struct{}
+
+// WithLocale возвращает контекст с языком подписей и уведомлений
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFromContext возвращает язык из контекста или язык по умолчанию
+func LocaleFromContext(ctx context.Context) string {
+	locale, ok := ctx.Value(localeKey{}).(string)
+	if !ok || locale == "" {
+		return defaultLocale
+	}
+	return locale
+}
+
+// LocaleMiddleware выбирает язык подписей статусов по заголовку Accept-Language,
+// кладёт его в контекст запроса и возвращает в заголовке Content-Language
+func LocaleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := NegotiateLocale(r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(WithLocale(r.Context(), locale)))
+	})
+}
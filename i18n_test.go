@@ -0,0 +1,42 @@
+package tracker_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+)
+
+// TestNegotiateLocale проверяет выбор языка по заголовку Accept-Language
+func TestNegotiateLocale(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", tracker.LocaleRU},
+		{"en", tracker.LocaleEN},
+		{"en-US,en;q=0.9,ru;q=0.8", tracker.LocaleEN},
+		{"ru;q=0.5, EN-gb;q=0.7", tracker.LocaleEN},
+		{"de-DE,de;q=0.9", tracker.LocaleRU},
+		{"de, en;q=0.3", tracker.LocaleEN},
+		{"en;q=0, ru;q=0.1", tracker.LocaleRU},
+		{"*", tracker.LocaleRU},
+		{"en;q=abc", tracker.LocaleRU},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tracker.NegotiateLocale(tt.header), tt.header)
+	}
+}
+
+// TestStatusLabel проверяет подписи статусов и язык по умолчанию
+func TestStatusLabel(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "в пути", tracker.StatusLabel(tracker.ParcelStatusSent, tracker.LocaleRU))
+	assert.Equal(t, "in transit", tracker.StatusLabel(tracker.ParcelStatusSent, tracker.LocaleEN))
+	assert.Equal(t, "доставлена", tracker.StatusLabel(tracker.ParcelStatusDelivered, "de"))
+	assert.Equal(t, "lost", tracker.StatusLabel("lost", tracker.LocaleEN))
+}
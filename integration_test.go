@@ -0,0 +1,187 @@
+//go:build integration
+
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// storeBackend хранилище, на котором прогоняется общий набор интеграционных тестов
+type storeBackend struct {
+	name string
+	// open возвращает пустое хранилище над отдельной БД теста
+	open func(t *testing.T) ParcelStore
+}
+
+// storeBackends конфигурации хранилища для интеграционных тестов. Запросы хранилища
+// написаны для SQLite, и другой реализации пока нет, поэтому в наборе варианты
+// работы с SQLite: отдельная БД чтения, кеш, шифрование адресов и БД депо. Новая
+// реализация хранилища добавляется сюда и проходит тот же набор тестов.
+func storeBackends() []storeBackend {
+	return []storeBackend{
+		{name: "sqlite", open: func(t *testing.T) ParcelStore {
+			return newTestStore(t)
+		}},
+		{name: "sqlite-reader", open: func(t *testing.T) ParcelStore {
+			cfg := testDBConfig(t)
+			db := migrateTestDB(t, cfg)
+			cfg.Reader.Path = cfg.Path
+			reader, err := OpenReaderDB(cfg)
+			require.NoError(t, err)
+			t.Cleanup(func() { reader.Close() })
+			return NewParcelStore(db, WithReader(reader, PoolOptions{}))
+		}},
+		{name: "sqlite-cache", open: func(t *testing.T) ParcelStore {
+			return newTestStore(t, WithCache(NewLRUCache(100, time.Minute)))
+		}},
+		{name: "sqlite-encrypted", open: func(t *testing.T) ParcelStore {
+			keys, err := NewStaticKeys(config.Encryption{Keys: map[string]string{"k1": testKey(1)}, Current: "k1"})
+			require.NoError(t, err)
+			return newTestStore(t, WithEncryption(keys))
+		}},
+		{name: "sqlite-shard", open: func(t *testing.T) ParcelStore {
+			cfg := testDBConfig(t)
+			cfg.Shards = []config.Shard{{Depot: "msk", Prefix: 7, Path: filepath.Join(t.TempDir(), "msk.db")}}
+			shards, err := OpenShards(cfg)
+			require.NoError(t, err)
+			t.Cleanup(func() { shards.Close() })
+			store, err := shards.Depot("msk")
+			require.NoError(t, err)
+			return store
+		}},
+	}
+}
+
+// TestStoreBackends прогоняет общий набор тестов хранилища на каждой конфигурации
+// из storeBackends. Набор собирается только с тегом integration:
+// go test -tags integration -run StoreBackends .
+func TestStoreBackends(t *testing.T) {
+	suite := []struct {
+		name string
+		run  func(t *testing.T, store ParcelStore)
+	}{
+		{"Lifecycle", testStoreLifecycle},
+		{"GetByClient", testStoreGetByClient},
+		{"Tenants", testStoreTenants},
+		{"ConcurrentAdd", testStoreConcurrentAdd},
+	}
+	for _, backend := range storeBackends() {
+		t.Run(backend.name, func(t *testing.T) {
+			t.Parallel()
+			for _, tt := range suite {
+				t.Run(tt.name, func(t *testing.T) {
+					tt.run(t, backend.open(t))
+				})
+			}
+		})
+	}
+}
+
+// testStoreLifecycle проверяет путь посылки: регистрацию, смену адреса и статуса,
+// историю и удаление
+func testStoreLifecycle(t *testing.T, store ParcelStore) {
+	// add
+	parcel := mustAddParcel(t, store, getTestParcel())
+	stored, err := store.Get(parcel.Number)
+	require.NoError(t, err)
+	assert.Equal(t, parcel.Client, stored.Client)
+	assert.Equal(t, parcel.Status, stored.Status)
+	assert.Equal(t, parcel.Address, stored.Address)
+	assert.Equal(t, parcel.CreatedAt, stored.CreatedAt)
+
+	// set address
+	require.NoError(t, store.SetAddress(parcel.Number, "new test address"))
+	stored, err = store.Get(parcel.Number)
+	require.NoError(t, err)
+	assert.Equal(t, "new test address", stored.Address)
+
+	// set status
+	require.NoError(t, store.SetStatus(parcel.Number, ParcelStatusSent))
+	stored, err = store.Get(parcel.Number)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusSent, stored.Status)
+	history, err := store.History(parcel.Number)
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+	assert.Equal(t, ParcelStatusSent, history[len(history)-1].Status)
+
+	// delete
+	// отправленная посылка остаётся на месте, зарегистрированная — удаляется
+	require.NoError(t, store.Delete(parcel.Number))
+	_, err = store.Get(parcel.Number)
+	require.NoError(t, err)
+	registered := mustAddParcel(t, store, getTestParcel())
+	require.NoError(t, store.Delete(registered.Number))
+	_, err = store.Get(registered.Number)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+// testStoreGetByClient проверяет выборку посылок клиента
+func testStoreGetByClient(t *testing.T, store ParcelStore) {
+	client := randRange.Intn(10_000_000)
+	want := map[int]bool{}
+	for range 3 {
+		want[mustAddParcel(t, store, newParcelFixture().client(client).build()).Number] = true
+	}
+	mustAddParcel(t, store, newParcelFixture().client(client+1).build())
+
+	parcels, err := store.GetByClient(client)
+	require.NoError(t, err)
+	require.Len(t, parcels, len(want))
+	for _, p := range parcels {
+		assert.True(t, want[p.Number], p.Number)
+		assert.Equal(t, client, p.Client)
+	}
+}
+
+// testStoreTenants проверяет, что посылки одной компании не видны другой
+func testStoreTenants(t *testing.T, store ParcelStore) {
+	tenant := fmt.Sprintf("backend-%d", randRange.Intn(10_000_000))
+	acme := store.WithContext(WithTenant(context.Background(), tenant))
+	parcel := mustAddParcel(t, acme, newParcelFixture().tenant(tenant).build())
+
+	_, err := store.Get(parcel.Number)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	// чужая компания не меняет статус посылки
+	require.NoError(t, store.SetStatus(parcel.Number, ParcelStatusSent))
+	stored, err := acme.Get(parcel.Number)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusRegistered, stored.Status)
+}
+
+// testStoreConcurrentAdd проверяет регистрацию посылок из нескольких горутин:
+// конкурирующие записи ждут своей очереди, а не теряются
+func testStoreConcurrentAdd(t *testing.T, store ParcelStore) {
+	client := randRange.Intn(10_000_000)
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.Add(newParcelFixture().client(client).build())
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	parcels, err := store.GetByClient(client)
+	require.NoError(t, err)
+	assert.Len(t, parcels, workers)
+}
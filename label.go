@@ -0,0 +1,199 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Форматы транспортной этикетки
+const (
+	LabelPDF = "pdf"
+	LabelZPL = "zpl"
+)
+
+// ErrUnknownLabelFormat возвращается при запросе этикетки в формате, которого трекер не печатает
+var ErrUnknownLabelFormat = errors.New("неизвестный формат этикетки")
+
+// Размер этикетки 100×150 мм — стандартная транспортная этикетка 4×6 дюймов
+const (
+	labelWidthMM  = 100.0
+	labelHeightMM = 150.0
+	// labelDotsPerMM разрешение термопринтера 203 dpi, на которое рассчитана ZPL-этикетка
+	labelDotsPerMM = 8
+)
+
+// Label транспортная этикетка посылки: номер, штрихкод с номером и адреса
+type Label struct {
+	Parcel Parcel
+	// Sender адрес отправителя; пустой адрес на этикетку не выводится
+	Sender string
+	// TrackingURL ссылка для отслеживания, которая печатается QR-кодом;
+	// без ссылки QR-кода на этикетке нет
+	TrackingURL string
+}
+
+// barcode возвращает штрихкод Code 128 с номером посылки, который сканируют на складах
+func (l Label) barcode() (barcode.Barcode, error) {
+	return EncodeCode(CodeBarcode, strconv.Itoa(l.Parcel.Number))
+}
+
+// Write записывает этикетку в формате format (pdf или zpl)
+func (l Label) Write(w io.Writer, format string) error {
+	switch format {
+	case LabelPDF:
+		return l.WritePDF(w)
+	case LabelZPL:
+		return l.WriteZPL(w)
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownLabelFormat, format)
+	}
+}
+
+// WritePDF записывает этикетку в PDF на страницу 100×150 мм.
+// Шрифты Go встраиваются в документ: в стандартных шрифтах PDF нет кириллицы.
+func (l Label) WritePDF(w io.Writer) error {
+	code, err := l.barcode()
+	if err != nil {
+		return err
+	}
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{UnitStr: "mm", Size: fpdf.SizeType{Wd: labelWidthMM, Ht: labelHeightMM}})
+	pdf.SetTitle(fmt.Sprintf("Посылка № %d", l.Parcel.Number), true)
+	pdf.AddUTF8FontFromBytes("go", "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes("go", "B", gobold.TTF)
+	pdf.SetMargins(5, 5, 5)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+
+	const width = labelWidthMM - 10
+	block := func(title, text string) {
+		pdf.SetFont("go", "B", 9)
+		pdf.CellFormat(width, 5, title, "", 1, "L", false, 0, "")
+		pdf.SetFont("go", "", 12)
+		pdf.MultiCell(width, 6, text, "", "L", false)
+		pdf.Ln(3)
+	}
+	if l.Sender != "" {
+		block("Отправитель", l.Sender)
+	}
+	block("Получатель", l.Parcel.Address)
+
+	pdf.SetFont("go", "B", 20)
+	pdf.CellFormat(width, 10, fmt.Sprintf("№ %d", l.Parcel.Number), "T", 1, "C", false, 0, "")
+
+	// штрихкод рисуется полосами во всю ширину этикетки под номером
+	top := pdf.GetY() + 2
+	drawCode(pdf, code, 5, top, width, 25)
+	pdf.SetY(top + 27)
+
+	if l.Parcel.TrackingToken != "" {
+		pdf.SetFont("go", "", 10)
+		pdf.CellFormat(width, 5, "Код отслеживания: "+l.Parcel.TrackingToken, "", 1, "C", false, 0, "")
+	}
+	if l.TrackingURL != "" {
+		qr, err := EncodeCode(CodeQR, l.TrackingURL)
+		if err != nil {
+			return err
+		}
+		const size = 35
+		drawCode(pdf, qr, (labelWidthMM-size)/2, pdf.GetY()+3, size, size)
+	}
+	return pdf.Output(w)
+}
+
+// drawCode рисует код в прямоугольнике x, y, width×height мм: каждый тёмный
+// модуль — закрашенный прямоугольник, поэтому код печатается без растра
+func drawCode(pdf *fpdf.Fpdf, code barcode.Barcode, x, y, width, height float64) {
+	b := code.Bounds()
+	mw, mh := width/float64(b.Dx()), height/float64(b.Dy())
+	for row := range b.Dy() {
+		for col := range b.Dx() {
+			if codeModuleDark(code, col, row) {
+				pdf.Rect(x+float64(col)*mw, y+float64(row)*mh, mw, mh, "F")
+			}
+		}
+	}
+}
+
+// WriteZPL записывает этикетку на языке термопринтеров Zebra для 203 dpi.
+// Текст передаётся в UTF-8 (^CI28); для кириллицы в принтер должен быть загружен
+// шрифт с ней, например шрифт 0 в прошивках с поддержкой Unicode.
+func (l Label) WriteZPL(w io.Writer) error {
+	var b strings.Builder
+	field := func(x, y, size int, text string) {
+		fmt.Fprintf(&b, "^FO%d,%d^A0N,%d,%d^FB%d,4,0,L^FH^FD%s^FS\n",
+			x, y, size, size, int(labelWidthMM*labelDotsPerMM)-2*x, zplEscape(text))
+	}
+
+	b.WriteString("^XA\n^CI28\n")
+	fmt.Fprintf(&b, "^PW%d\n^LL%d\n", int(labelWidthMM*labelDotsPerMM), int(labelHeightMM*labelDotsPerMM))
+	y := 40
+	if l.Sender != "" {
+		field(40, y, 28, "Отправитель")
+		field(40, y+35, 36, l.Sender)
+		y += 200
+	}
+	field(40, y, 28, "Получатель")
+	field(40, y+35, 36, l.Parcel.Address)
+	y += 220
+
+	fmt.Fprintf(&b, "^FO40,%d^GB720,3,3^FS\n", y)
+	field(40, y+20, 60, fmt.Sprintf("№ %d", l.Parcel.Number))
+	// штрихкод Code 128 печатает сам принтер
+	fmt.Fprintf(&b, "^FO40,%d^BY3^BCN,200,N,N,N^FD%d^FS\n", y+100, l.Parcel.Number)
+	if l.Parcel.TrackingToken != "" {
+		field(40, y+330, 30, "Код отслеживания: "+l.Parcel.TrackingToken)
+	}
+	if l.TrackingURL != "" {
+		// QR-код модели 2 с увеличением 6 и средней коррекцией ошибок (MA —
+		// уровень M с автоматическим выбором режима кодирования)
+		fmt.Fprintf(&b, "^FO250,%d^BQN,2,6^FH^FDMA,%s^FS\n", y+380, zplEscape(l.TrackingURL))
+	}
+	b.WriteString("^XZ\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// zplEscape экранирует управляющие символы ZPL для поля с ^FH: символы ^ и ~
+// начинают команды, а символ _ начинает шестнадцатеричный код
+func zplEscape(s string) string {
+	return strings.NewReplacer("_", "_5F", "^", "_5E", "~", "_7E").Replace(s)
+}
+
+// WithPublicURL задаёт публичный адрес трекера, от которого строятся ссылки
+// для отслеживания на этикетках
+func WithPublicURL(base string) ServiceOption {
+	return func(s *ParcelService) {
+		s.publicURL = base
+	}
+}
+
+// WithLabelSender задаёт адрес отправителя, который печатается на этикетках
+func WithLabelSender(sender string) ServiceOption {
+	return func(s *ParcelService) {
+		s.labelSender = sender
+	}
+}
+
+// Label возвращает этикетку посылки; права те же, что на просмотр посылки
+func (s ParcelService) Label(ctx context.Context, number int) (Label, error) {
+	p, err := s.Get(ctx, number)
+	if err != nil {
+		return Label{}, err
+	}
+	label := Label{Parcel: p, Sender: s.labelSender}
+	if s.publicURL != "" && p.TrackingToken != "" {
+		label.TrackingURL = TrackingURL(s.publicURL, p.TrackingToken)
+	}
+	return label, nil
+}
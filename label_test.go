@@ -0,0 +1,90 @@
+package tracker
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLabel проверяет этикетку в ZPL и PDF
+func TestLabel(t *testing.T) {
+	// prepare
+	label := Label{
+		Parcel: Parcel{Number: 42, Address: "Псков, ул. Колотушкина, д. 5 ^стр_1~", TrackingToken: "abc"},
+		Sender: "Склад №1, Москва",
+	}
+
+	// zpl
+	var zpl bytes.Buffer
+	require.NoError(t, label.Write(&zpl, LabelZPL))
+	assert.Contains(t, zpl.String(), "^FDПсков, ул. Колотушкина, д. 5 _5Eстр_5F1_7E^FS")
+	assert.Contains(t, zpl.String(), "^FDСклад №1, Москва^FS")
+	assert.Contains(t, zpl.String(), "^BCN,200,N,N,N^FD42^FS")
+
+	// pdf
+	var pdf bytes.Buffer
+	require.NoError(t, label.Write(&pdf, LabelPDF))
+	assert.True(t, bytes.HasPrefix(pdf.Bytes(), []byte("%PDF-")))
+
+	assert.ErrorIs(t, label.Write(io.Discard, "png"), ErrUnknownLabelFormat)
+
+	// qr
+	label.TrackingURL = "https://track.example.com/track/abc"
+	zpl.Reset()
+	require.NoError(t, label.Write(&zpl, LabelZPL))
+	assert.Contains(t, zpl.String(), "^BQN,2,6^FH^FDMA,https://track.example.com/track/abc^FS")
+	require.NoError(t, label.Write(io.Discard, LabelPDF))
+}
+
+// TestHTTPLabel проверяет выдачу этикетки посылки по HTTP
+func TestHTTPLabel(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	p := getTestParcel()
+	// трекинг-токен для ссылки в QR-коде
+	token, err := newTrackingToken()
+	require.NoError(t, err)
+	p.TrackingToken = token
+	id, err := store.Add(p)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(NewHTTPHandler(NewParcelService(store, WithLabelSender("Склад №1"), WithPublicURL("https://track.example.com"))))
+	defer srv.Close()
+	get := func(query string) *http.Response {
+		t.Helper()
+		resp, err := http.Get(fmt.Sprintf("%s/parcels/%d/label%s", srv.URL, id, query))
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	// check
+	resp := get("")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+
+	resp = get("?format=zpl")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/zpl", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "^FDСклад №1^FS")
+	assert.Contains(t, string(body), "^FDMA,https://track.example.com/track/"+p.TrackingToken)
+
+	assert.Equal(t, http.StatusBadRequest, get("?format=png").StatusCode)
+
+	resp, err = http.Get(srv.URL + "/parcels/999999999/label")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
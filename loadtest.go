@@ -0,0 +1,329 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Операции нагрузочного теста
+const (
+	LoadAdd   = "add"
+	LoadScan  = "scan"
+	LoadQuery = "query"
+)
+
+// loadOps операции нагрузочного теста в порядке отчёта
+var loadOps = []string{LoadAdd, LoadScan, LoadQuery}
+
+// loadPreload сколько существующих посылок нагрузочный тест берёт для сканирований и запросов
+const loadPreload = 1000
+
+// ErrInvalidLoad возвращается при неположительной длительности или числе исполнителей и отрицательной частоте
+var ErrInvalidLoad = errors.New("неверные параметры нагрузочного теста")
+
+// LoadOptions длительность и частоты операций нагрузочного теста
+type LoadOptions struct {
+	// Duration сколько длится тест
+	Duration time.Duration
+	// Workers сколько операций может выполняться одновременно
+	Workers int
+	// Adds, Scans и Queries частоты операций в секунду: регистрации посылки,
+	// сканирования со сменой статуса на следующий и чтения посылки или посылок клиента
+	Adds, Scans, Queries float64
+	// Clients между сколькими клиентами (1..Clients) распределяются регистрации и запросы
+	Clients int
+}
+
+// validate проверяет параметры нагрузочного теста
+func (o LoadOptions) validate() error {
+	if o.Duration <= 0 || o.Workers < 1 || o.Clients < 1 || o.Adds < 0 || o.Scans < 0 || o.Queries < 0 ||
+		o.Adds+o.Scans+o.Queries == 0 {
+		return fmt.Errorf("%w: нужны длительность, исполнители, клиенты и хотя бы одна частота", ErrInvalidLoad)
+	}
+	return nil
+}
+
+// rate возвращает частоту операции op
+func (o LoadOptions) rate(op string) float64 {
+	switch op {
+	case LoadAdd:
+		return o.Adds
+	case LoadScan:
+		return o.Scans
+	default:
+		return o.Queries
+	}
+}
+
+// LoadStats итоги одной операции нагрузочного теста
+type LoadStats struct {
+	Op string
+	// Count сколько операций выполнено, Errors — сколько из них завершились ошибкой
+	Count, Errors int
+	// Dropped сколько операций не начато вовремя, потому что все исполнители были заняты
+	Dropped int
+	// Rate выполненных операций в секунду
+	Rate float64
+	// P50, P90, P99 и Max перцентили и наибольшая длительность выполненных операций
+	P50, P90, P99, Max time.Duration
+}
+
+// ErrorRate доля операций, завершившихся ошибкой
+func (s LoadStats) ErrorRate() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Count)
+}
+
+// LoadReport итоги нагрузочного теста по операциям в порядке add, scan, query
+type LoadReport struct {
+	Elapsed time.Duration
+	Ops     []LoadStats
+	// FirstErrors первая ошибка каждой операции, чтобы понять, почему растёт доля ошибок
+	FirstErrors map[string]error
+}
+
+// percentile возвращает перцентиль p (0..1) отсортированных длительностей по ближайшему рангу
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p+0.999999) - 1
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
+
+// loadParcel посылка, которую нагрузочный тест сканирует, и её последний известный статус
+type loadParcel struct {
+	number int
+	status string
+}
+
+// loadRun состояние нагрузочного теста: посылки для операций и собранные длительности
+type loadRun struct {
+	service ParcelService
+	opts    LoadOptions
+
+	mu sync.Mutex
+	// active посылки, которые ещё можно сканировать; посылка убирается из списка на время
+	// сканирования, поэтому одну посылку не сканируют одновременно
+	active []loadParcel
+	// numbers все известные номера посылок для запросов
+	numbers   []int
+	durations map[string][]time.Duration
+	errors    map[string]int
+	dropped   map[string]int
+	first     map[string]error
+	rnd       *rand.Rand
+}
+
+// RunLoad нагружает сервис операциями с частотами из opts, пока не пройдёт opts.Duration
+// или не отменится ctx, и возвращает перцентили длительностей и долю ошибок по операциям.
+// Операции запускаются по расписанию независимо от того, успевает ли сервис, поэтому
+// при перегрузке растут длительности и число пропущенных операций, а не падает частота.
+// Сканирования и запросы берут посылки, зарегистрированные тестом, и до loadPreload
+// посылок, уже бывших в компании из ctx.
+func (s ParcelService) RunLoad(ctx context.Context, opts LoadOptions) (LoadReport, error) {
+	err := opts.validate()
+	if err != nil {
+		return LoadReport{}, err
+	}
+	r := &loadRun{
+		service:   s,
+		opts:      opts,
+		durations: map[string][]time.Duration{},
+		errors:    map[string]int{},
+		dropped:   map[string]int{},
+		first:     map[string]error{},
+		rnd:       rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0)),
+	}
+	existing, err := s.List(ctx, ListOptions{Limit: loadPreload})
+	if err != nil {
+		return LoadReport{}, err
+	}
+	for _, p := range existing {
+		r.track(p.Number, p.Status)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+	jobs := make(chan string, opts.Workers)
+	var workers sync.WaitGroup
+	for range opts.Workers {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for op := range jobs {
+				r.run(context.WithoutCancel(ctx), op)
+			}
+		}()
+	}
+
+	start := time.Now()
+	var schedulers sync.WaitGroup
+	for _, op := range loadOps {
+		rate := opts.rate(op)
+		if rate == 0 {
+			continue
+		}
+		schedulers.Add(1)
+		go func() {
+			defer schedulers.Done()
+			r.schedule(ctx, op, rate, jobs)
+		}()
+	}
+	schedulers.Wait()
+	close(jobs)
+	workers.Wait()
+
+	return r.report(time.Since(start)), nil
+}
+
+// schedule отправляет операцию op исполнителям rate раз в секунду, пока не отменится ctx.
+// Если все исполнители заняты, операция считается пропущенной.
+func (r *loadRun) schedule(ctx context.Context, op string, rate float64, jobs chan<- string) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			select {
+			case jobs <- op:
+			default:
+				r.mu.Lock()
+				r.dropped[op]++
+				r.mu.Unlock()
+			}
+		}
+	}
+}
+
+// run выполняет операцию op и запоминает её длительность и ошибку
+func (r *loadRun) run(ctx context.Context, op string) {
+	start := time.Now()
+	var err error
+	switch op {
+	case LoadAdd:
+		err = r.add(ctx)
+	case LoadScan:
+		err = r.scan(ctx)
+	default:
+		err = r.query(ctx)
+	}
+	elapsed := time.Since(start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.durations[op] = append(r.durations[op], elapsed)
+	if err != nil {
+		r.errors[op]++
+		if r.first[op] == nil {
+			r.first[op] = err
+		}
+	}
+}
+
+// add регистрирует посылку случайного клиента
+func (r *loadRun) add(ctx context.Context) error {
+	r.mu.Lock()
+	client := r.rnd.IntN(r.opts.Clients) + 1
+	house := r.rnd.IntN(120) + 1
+	r.mu.Unlock()
+
+	p, err := r.service.Register(ctx, client, fmt.Sprintf("Москва, ул. Нагрузочная, д. %d", house))
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.track(p.Number, p.Status)
+	return nil
+}
+
+// scan переводит случайную недоставленную посылку в следующий статус. Если сканировать
+// нечего, операция ничего не делает.
+func (r *loadRun) scan(ctx context.Context) error {
+	r.mu.Lock()
+	if len(r.active) == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	i := r.rnd.IntN(len(r.active))
+	p := r.active[i]
+	r.active[i] = r.active[len(r.active)-1]
+	r.active = r.active[:len(r.active)-1]
+	r.mu.Unlock()
+
+	next, _ := NextParcelStatus(p.status)
+	err := r.service.SetStatus(ctx, p.number, next)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := NextParcelStatus(next); ok {
+		r.active = append(r.active, loadParcel{p.number, next})
+	}
+	return nil
+}
+
+// query читает случайную известную посылку или, через раз, посылки случайного клиента
+func (r *loadRun) query(ctx context.Context) error {
+	r.mu.Lock()
+	byClient := len(r.numbers) == 0 || r.rnd.IntN(2) == 0
+	client := r.rnd.IntN(r.opts.Clients) + 1
+	number := 0
+	if len(r.numbers) > 0 {
+		number = r.numbers[r.rnd.IntN(len(r.numbers))]
+	}
+	r.mu.Unlock()
+
+	if byClient {
+		_, err := r.service.ClientParcels(ctx, client)
+		return err
+	}
+	_, err := r.service.Get(ctx, number)
+	return err
+}
+
+// track добавляет посылку к известным; вызывается под r.mu
+func (r *loadRun) track(number int, status string) {
+	r.numbers = append(r.numbers, number)
+	if _, ok := NextParcelStatus(status); ok {
+		r.active = append(r.active, loadParcel{number, status})
+	}
+}
+
+// report собирает итоги по операциям
+func (r *loadRun) report(elapsed time.Duration) LoadReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := LoadReport{Elapsed: elapsed, FirstErrors: r.first}
+	for _, op := range loadOps {
+		if r.opts.rate(op) == 0 {
+			continue
+		}
+		d := r.durations[op]
+		slices.Sort(d)
+		report.Ops = append(report.Ops, LoadStats{
+			Op:      op,
+			Count:   len(d),
+			Errors:  r.errors[op],
+			Dropped: r.dropped[op],
+			Rate:    float64(len(d)) / elapsed.Seconds(),
+			P50:     percentile(d, 0.5),
+			P90:     percentile(d, 0.9),
+			P99:     percentile(d, 0.99),
+			Max:     percentile(d, 1),
+		})
+	}
+	return report
+}
@@ -0,0 +1,62 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunLoad проверяет, что нагрузочный тест выполняет операции и считает перцентили
+func TestRunLoad(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	ctx := WithTenant(context.Background(), fmt.Sprintf("load-%d", randRange.Intn(10_000_000)))
+	service := NewParcelService(NewParcelStore(db))
+	_, err := service.Register(ctx, 1, "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+
+	// run
+	opts := LoadOptions{Duration: 500 * time.Millisecond, Workers: 4, Adds: 40, Scans: 40, Queries: 40, Clients: 5}
+	report, err := service.RunLoad(ctx, opts)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, report.Elapsed, opts.Duration)
+	require.Len(t, report.Ops, 3)
+	for i, s := range report.Ops {
+		assert.Equal(t, loadOps[i], s.Op)
+		assert.Positive(t, s.Count, s.Op)
+		assert.Zero(t, s.Errors, report.FirstErrors[s.Op])
+		assert.LessOrEqual(t, s.P50, s.P90)
+		assert.LessOrEqual(t, s.P90, s.P99)
+		assert.LessOrEqual(t, s.P99, s.Max)
+	}
+	// сканирования продвигают посылки, зарегистрированные до и во время теста
+	delivered, err := service.List(ctx, ListOptions{Status: ParcelStatusDelivered})
+	require.NoError(t, err)
+	sent, err := service.List(ctx, ListOptions{Status: ParcelStatusSent})
+	require.NoError(t, err)
+	assert.NotEmpty(t, append(delivered, sent...))
+
+	// validate
+	_, err = service.RunLoad(ctx, LoadOptions{Duration: time.Second, Workers: 1, Clients: 1})
+	assert.ErrorIs(t, err, ErrInvalidLoad)
+}
+
+// TestPercentile проверяет перцентили по ближайшему рангу
+func TestPercentile(t *testing.T) {
+	var d []time.Duration
+	for i := 1; i <= 100; i++ {
+		d = append(d, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, percentile(d, 0.5))
+	assert.Equal(t, 99*time.Millisecond, percentile(d, 0.99))
+	assert.Equal(t, 100*time.Millisecond, percentile(d, 1))
+	assert.Equal(t, time.Millisecond, percentile(d[:1], 0.5))
+	assert.Zero(t, percentile(nil, 0.5))
+}
@@ -0,0 +1,151 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// courierLocationTTL через сколько последнее известное положение курьера считается
+// устаревшим и больше не показывается получателям
+const courierLocationTTL = 30 * time.Minute
+
+// ErrInvalidLocation возвращается при координатах вне допустимых широты и долготы
+var ErrInvalidLocation = errors.New("неверные координаты")
+
+const (
+	queryInsertLocation = `INSERT INTO courier_location (tenant_id, courier, lat, lon, reported_at)
+		VALUES (:tenant, :courier, :lat, :lon, :reported_at)`
+	queryLastLocation = `SELECT courier, lat, lon, reported_at FROM courier_location
+		WHERE courier = :courier AND ` + tenantCond + ` ORDER BY id DESC LIMIT 1`
+	// queryCourierNearby последнее положение курьера, который везёт посылку по начатому
+	// маршруту, и число недоставленных посылок маршрута перед ней
+	queryCourierNearby = `SELECT l.courier, l.lat, l.lon, l.reported_at,
+			(SELECT COUNT(*) FROM route_stop o JOIN parcel p ON p.number = o.number
+				WHERE o.route_id = r.id AND o.position < s.position AND p.status != '` + ParcelStatusDelivered + `')
+		FROM route_stop s
+		JOIN route r ON r.id = s.route_id
+		JOIN courier_location l ON l.id = (SELECT MAX(id) FROM courier_location
+			WHERE tenant_id = r.tenant_id AND courier = r.courier)
+		WHERE s.number = :number AND r.tenant_id = :tenant AND r.status = '` + RouteStarted + `'`
+)
+
+// CourierLocation положение курьера, которое он сообщил
+type CourierLocation struct {
+	Courier    int
+	Lat        float64
+	Lon        float64
+	ReportedAt string
+}
+
+// CourierNearby сведения для получателя о курьере, который везёт его посылку
+type CourierNearby struct {
+	CourierLocation
+	// StopsBefore сколько посылок курьер доставит раньше этой
+	StopsBefore int
+}
+
+// validLocation проверяет, что широта и долгота в допустимых пределах
+func validLocation(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+// ReportLocation записывает текущее положение курьера courier. Хранятся все сообщённые
+// положения, последним известным считается записанное позже остальных.
+func (s ParcelStore) ReportLocation(courier int, lat, lon float64) (CourierLocation, error) {
+	start := time.Now()
+	span := s.startSpan("ReportLocation", attrClient.Int(courier))
+	defer span.End()
+
+	loc := CourierLocation{Courier: courier, Lat: lat, Lon: lon, ReportedAt: time.Now().UTC().Format(time.RFC3339)}
+	err := s.withRetry("store.ReportLocation", func(s ParcelStore) error {
+		tenant, err := s.ownTenant()
+		if err != nil {
+			return err
+		}
+		_, err = s.exec(nil, queryInsertLocation,
+			sql.Named("tenant", tenant),
+			sql.Named("courier", courier),
+			sql.Named("lat", lat),
+			sql.Named("lon", lon),
+			sql.Named("reported_at", loc.ReportedAt))
+		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("ReportLocation", start)
+	logResult(s.ctx, s.logger, "store.ReportLocation", start, err, "courier", courier)
+	return loc, err
+}
+
+// LastLocation возвращает последнее известное положение курьера courier
+// или sql.ErrNoRows, если он ещё не сообщал о себе
+func (s ParcelStore) LastLocation(courier int) (CourierLocation, error) {
+	defer s.metrics.observeQuery("LastLocation", time.Now())
+	span := s.startSpan("LastLocation", attrClient.Int(courier))
+	defer span.End()
+
+	var loc CourierLocation
+	err := s.readRow(queryLastLocation, sql.Named("courier", courier), sql.Named("tenant", s.tenant())).Scan(
+		&loc.Courier, &loc.Lat, &loc.Lon, &loc.ReportedAt)
+	return loc, spanError(span, err)
+}
+
+// CourierNearby возвращает положение курьера, который везёт посылку p по начатому маршруту.
+// Если посылка не в пути, не входит в начатый маршрут или курьер давно не сообщал
+// о себе, возвращается nil.
+func (s ParcelStore) CourierNearby(p Parcel) (*CourierNearby, error) {
+	if p.Status != ParcelStatusSent {
+		return nil, nil
+	}
+	defer s.metrics.observeQuery("CourierNearby", time.Now())
+	span := s.startSpan("CourierNearby", attrNumber.Int(p.Number))
+	defer span.End()
+
+	var n CourierNearby
+	err := s.readRow(queryCourierNearby, sql.Named("number", p.Number), sql.Named("tenant", p.Tenant)).Scan(
+		&n.Courier, &n.Lat, &n.Lon, &n.ReportedAt, &n.StopsBefore)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	reported, err := time.Parse(time.RFC3339, n.ReportedAt)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	if time.Since(reported) > courierLocationTTL {
+		return nil, nil
+	}
+	return &n, nil
+}
+
+// ReportLocation записывает положение курьера; курьер сообщает только своё положение
+func (s ParcelService) ReportLocation(ctx context.Context, courier int, lat, lon float64) (CourierLocation, error) {
+	err := s.check(ctx, actionSetStatus)
+	if err != nil {
+		return CourierLocation{}, err
+	}
+	err = authorizeCourier(ctx, courier)
+	if err != nil {
+		return CourierLocation{}, err
+	}
+	if !validLocation(lat, lon) {
+		return CourierLocation{}, ErrInvalidLocation
+	}
+	return s.store.WithContext(ctx).ReportLocation(courier, lat, lon)
+}
+
+// LastLocation возвращает последнее известное положение курьера; курьер видит только своё
+func (s ParcelService) LastLocation(ctx context.Context, courier int) (CourierLocation, error) {
+	err := s.check(ctx, actionRead)
+	if err != nil {
+		return CourierLocation{}, err
+	}
+	err = authorizeCourier(ctx, courier)
+	if err != nil {
+		return CourierLocation{}, err
+	}
+	return s.store.WithContext(ctx).LastLocation(courier)
+}
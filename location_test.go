@@ -0,0 +1,86 @@
+package tracker_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestCourierNearby проверяет положение курьера и сведения о нём при отслеживании посылки из маршрута
+func TestCourierNearby(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	tenant := fmt.Sprintf("location-%d", testsupport.Intn(10_000_000))
+	ctx := tracker.WithTenant(context.Background(), tenant)
+	service := tracker.NewParcelService(tracker.NewParcelStore(db))
+	courier := tracker.WithCaller(ctx, tracker.Caller{Client: 7, Role: tracker.RoleCourier, Tenant: tenant})
+
+	var parcels []tracker.Parcel
+	for range 3 {
+		p, err := service.Register(ctx, 1000, "Псков, ул. Колотушкина, д. 5")
+		require.NoError(t, err)
+		parcels = append(parcels, p)
+	}
+	route, err := service.BuildRoute(ctx, 7, "2024-05-01", []int{parcels[0].Number, parcels[1].Number})
+	require.NoError(t, err)
+
+	// report
+	_, err = service.ReportLocation(courier, 8, 57.8, 28.3)
+	assert.ErrorIs(t, err, tracker.ErrForbidden)
+	_, err = service.ReportLocation(courier, 7, 91, 28.3)
+	assert.ErrorIs(t, err, tracker.ErrInvalidLocation)
+	_, err = service.LastLocation(courier, 7)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = service.ReportLocation(courier, 7, 57.81, 28.33)
+	require.NoError(t, err)
+	loc, err := service.ReportLocation(courier, 7, 57.82, 28.34)
+	require.NoError(t, err)
+	got, err := service.LastLocation(courier, 7)
+	require.NoError(t, err)
+	assert.Equal(t, loc, got)
+
+	// пока маршрут не начат, курьер получателю не показывается
+	view, err := service.Track(context.Background(), parcels[1].TrackingToken)
+	require.NoError(t, err)
+	assert.Nil(t, view.Courier)
+
+	// track
+	_, err = service.StartRoute(courier, route.ID)
+	require.NoError(t, err)
+	view, err = service.Track(context.Background(), parcels[1].TrackingToken)
+	require.NoError(t, err)
+	assert.Equal(t, &tracker.CourierNearby{CourierLocation: loc, StopsBefore: 1}, view.Courier)
+
+	require.NoError(t, service.SetStatus(courier, parcels[0].Number, tracker.ParcelStatusDelivered))
+	view, err = service.Track(context.Background(), parcels[1].TrackingToken)
+	require.NoError(t, err)
+	require.NotNil(t, view.Courier)
+	assert.Zero(t, view.Courier.StopsBefore)
+
+	// посылка вне маршрута и доставленная посылка курьера не показывают
+	view, err = service.Track(context.Background(), parcels[2].TrackingToken)
+	require.NoError(t, err)
+	assert.Nil(t, view.Courier)
+	view, err = service.Track(context.Background(), parcels[0].TrackingToken)
+	require.NoError(t, err)
+	assert.Nil(t, view.Courier)
+
+	// устаревшее положение не показывается
+	_, err = db.Exec("UPDATE courier_location SET reported_at = ? WHERE tenant_id = ?",
+		time.Now().Add(-time.Hour).UTC().Format(time.RFC3339), tenant)
+	require.NoError(t, err)
+	view, err = service.Track(context.Background(), parcels[1].TrackingToken)
+	require.NoError(t, err)
+	assert.Nil(t, view.Courier)
+}
@@ -0,0 +1,60 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Logger структурированный логгер трекера. *slog.Logger подходит без обёрток,
+// для zap, zerolog и других библиотек достаточно адаптера с этим одним методом.
+type Logger interface {
+	Log(ctx context.Context, level slog.Level, msg string, args ...any)
+}
+
+// discardLogger ничего не пишет; используется, когда логгер не задан
+type discardLogger struct{}
+
+func (discardLogger) Log(context.Context, slog.Level, string, ...any) {}
+
+// NewLogger создаёт slog-логгер, который пишет в w записи уровня level
+// (debug, info, warn или error) в формате text или json
+func NewLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	err := lvl.UnmarshalText([]byte(level))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("неизвестный формат логов %q", format)
+	}
+}
+
+// logResult записывает результат операции op вместе с её длительностью:
+// успех на уровне Info, ошибку — на уровне Error
+func logResult(ctx context.Context, logger Logger, op string, start time.Time, err error, args ...any) {
+	args = append(args, "op", op, "duration", time.Since(start))
+	args = withRequestID(ctx, args)
+	if err != nil {
+		logger.Log(ctx, slog.LevelError, "операция завершилась ошибкой", append(args, "error", err)...)
+		return
+	}
+	logger.Log(ctx, slog.LevelInfo, "операция выполнена", args...)
+}
+
+// withRequestID добавляет к атрибутам записи идентификатор запроса из ctx, если он есть
+func withRequestID(ctx context.Context, args []any) []any {
+	if id := RequestIDFromContext(ctx); id != "" {
+		args = append(args, "request_id", id)
+	}
+	return args
+}
@@ -0,0 +1,58 @@
+package tracker_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestStoreLogging проверяет, что изменение статуса попадает в лог с прежним и новым статусом
+func TestStoreLogging(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	var buf bytes.Buffer
+	logger, err := tracker.NewLogger(&buf, "info", "text")
+	require.NoError(t, err)
+	store := tracker.NewParcelStore(db, tracker.WithStoreLogger(logger))
+
+	id, err := store.Add(testsupport.NewParcel().Build())
+	require.NoError(t, err)
+
+	// set status
+	require.NoError(t, store.SetStatus(id, tracker.ParcelStatusSent))
+
+	// check
+	assert.Contains(t, buf.String(), fmt.Sprintf("number=%d old_status=registered new_status=sent op=store.SetStatus", id))
+}
+
+// TestServiceLogging проверяет, что отклонённая операция пишется в лог на уровне warn
+func TestServiceLogging(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	var buf bytes.Buffer
+	logger, err := tracker.NewLogger(&buf, "warn", "text")
+	require.NoError(t, err)
+	service := tracker.NewParcelService(tracker.NewParcelStore(db), tracker.WithLogger(logger))
+
+	// delete
+	ctx := tracker.WithCaller(context.Background(), tracker.Caller{Client: 1, Role: tracker.RoleCourier})
+	err = service.Delete(ctx, 1)
+	require.ErrorIs(t, err, tracker.ErrForbidden)
+
+	// check
+	assert.Contains(t, buf.String(), "level=WARN")
+	assert.Contains(t, buf.String(), "action=delete client=1 role=courier")
+}
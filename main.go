@@ -1,9 +1,10 @@
-package main
+package tracker
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
-	"time"
+	"log/slog"
 
 	_ "modernc.org/sqlite"
 )
@@ -12,6 +13,26 @@ const (
 	ParcelStatusRegistered = "registered"
 	ParcelStatusSent       = "sent"
 	ParcelStatusDelivered  = "delivered"
+	// ParcelStatusCustomsHold посылка в пути, но задержана таможней
+	ParcelStatusCustomsHold = "customs_hold"
+)
+
+// parcelStatuses статусы посылки в порядке её пути
+var parcelStatuses = []string{ParcelStatusRegistered, ParcelStatusSent, ParcelStatusCustomsHold, ParcelStatusDelivered}
+
+var (
+	// ErrUnknownStatus возвращается при попытке установить статус, которого нет в трекере
+	ErrUnknownStatus = errors.New("неизвестный статус посылки")
+	// ErrParcelNotFound сообщение об отсутствии посылки для клиентов API;
+	// само хранилище в этом случае возвращает sql.ErrNoRows
+	ErrParcelNotFound = errors.New("посылка не найдена")
+	// ErrInvalidTransition возвращается при попытке вернуть посылку к предыдущему статусу
+	ErrInvalidTransition = errors.New("недопустимая смена статуса посылки")
+	// ErrConflict возвращается, когда статус посылки успел измениться с момента,
+	// как его прочитал вызывающий
+	ErrConflict = errors.New("статус посылки изменился")
+	// ErrInvalidAddress возвращается для пустого, слишком длинного или непечатаемого адреса
+	ErrInvalidAddress = errors.New("неверный адрес доставки")
 )
 
 type Parcel struct {
@@ -20,39 +41,120 @@ type Parcel struct {
 	Status    string
 	Address   string
 	CreatedAt string
+	// TrackingToken открытый идентификатор для отслеживания посылки без учётной записи
+	TrackingToken string
+	// Tenant компания, которой принадлежит посылка
+	Tenant string
+	// DeliveredAt когда посылка впервые получила статус delivered; пусто, если ещё не доставлена
+	DeliveredAt string
+	// Version версия посылки: растёт с каждым изменением статуса или адреса
+	Version int
+	// ETA ожидаемое время доставки в RFC 3339; пусто, если оценки нет
+	ETA string
+	// Priority срочность доставки: standard или express
+	Priority string
+	// Weight вес в граммах и Price стоимость доставки в копейках; 0, если посылка
+	// зарегистрирована без расчёта стоимости
+	Weight int
+	Price  int64
+	// Zone зона доставки по почтовому индексу адреса; пусто, если индекс не входит ни в одну зону
+	Zone string
+	// Deadline обещанный по тарифу срок доставки в RFC 3339; пусто, если срок не обещан
+	Deadline string
 }
 
 type ParcelService struct {
 	store ParcelStore
+	// storage основные операции с посылками: store.Storage() или его обёртка (WithStorage)
+	storage ParcelStorage
+	limiter *rateLimiter
+	logger  Logger
+	metrics *Metrics
+	// carriers перевозчики по именам, которым можно передать посылку (HandOff)
+	carriers map[string]CarrierLink
+	// labelSender адрес отправителя на транспортных этикетках
+	labelSender string
+	// publicURL публичный адрес трекера для ссылок на отслеживание
+	publicURL string
+	// writer запись новых посылок пачками; nil — каждая посылка в своей транзакции
+	writer *BatchWriter
+	// overdue сроки статусов, после которых посылка считается застрявшей
+	overdue OverdueSLA
+	// duplicates поиск повторной регистрации посылки в Register
+	duplicates DuplicatePolicy
+	// eta оценка времени доставки новых посылок; nil — не оценивать
+	eta *ETAEstimator
+	// geocoder определение координат адресов доставки
+	geocoder Geocoder
+	// blobs хранилище документов посылок; nil — документы не принимаются
+	blobs BlobStore
+	// maxAttachment наибольший размер документа в байтах
+	maxAttachment int64
 }
 
-func NewParcelService(store ParcelStore) ParcelService {
-	return ParcelService{store: store}
+// ServiceOption настраивает ParcelService при создании
+type ServiceOption func(*ParcelService)
+
+// WithLogger задаёт логгер, в который сервис пишет отклонённые операции пользователей
+func WithLogger(logger Logger) ServiceOption {
+	return func(s *ParcelService) {
+		s.logger = logger
+	}
 }
 
-func (s ParcelService) Register(client int, address string) (Parcel, error) {
-	parcel := Parcel{
-		Client:    client,
-		Status:    ParcelStatusRegistered,
-		Address:   address,
-		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+// WithMetrics задаёт метрики, в которые сервис пишет результаты проверки операций;
+// их же отдаёт эндпоинт /metrics HTTP-сервера
+func WithMetrics(m *Metrics) ServiceOption {
+	return func(s *ParcelService) {
+		s.metrics = m
 	}
+}
 
-	id, err := s.store.Add(parcel)
-	if err != nil {
-		return parcel, err
+// WithStorage задаёт хранилище основных операций сервиса: чтения посылки и посылок
+// клиента, смены статуса и адреса и удаления. Обычно это store.Storage(), обёрнутый
+// StoreMiddleware через Wrap, а в тестах — заглушка.
+func WithStorage(storage ParcelStorage) ServiceOption {
+	return func(s *ParcelService) {
+		s.storage = storage
 	}
+}
+
+func NewParcelService(store ParcelStore, opts ...ServiceOption) ParcelService {
+	s := ParcelService{store: store, storage: store.Storage(), logger: discardLogger{}, geocoder: NopGeocoder{}}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
 
-	parcel.Number = id
+// check выполняется в начале каждой операции от имени пользователя:
+// ограничивает частоту его запросов и проверяет права роли
+func (s ParcelService) check(ctx context.Context, act action) error {
+	err := s.limiter.allow(ctx)
+	if err == nil {
+		err = authorize(ctx, act)
+	}
+	s.metrics.operation(act, err)
+	if err != nil {
+		c, _ := CallerFromContext(ctx)
+		s.logger.Log(ctx, slog.LevelWarn, "операция отклонена",
+			withRequestID(ctx, []any{"action", act, "client", c.Client, "role", c.Role, "error", err})...)
+	}
+	return err
+}
 
-	fmt.Printf("Новая посылка № %d на адрес %s от клиента с идентификатором %d зарегистрирована %s\n",
-		parcel.Number, parcel.Address, parcel.Client, parcel.CreatedAt)
+func (s ParcelService) Register(ctx context.Context, client int, address string) (Parcel, error) {
+	return s.register(ctx, client, address, nil)
+}
 
-	return parcel, nil
+// register регистрирует посылку через TrackParcelWorkflow; если задан req, в посылку
+// записываются срочность, вес и стоимость доставки по тарифу компании
+func (s ParcelService) register(ctx context.Context, client int, address string, req *QuoteRequest) (Parcel, error) {
+	return NewTrackParcelWorkflow(s).Run(ctx, TrackParcelRequest{Client: client, Address: address, Quote: req})
 }
 
-func (s ParcelService) PrintClientParcels(client int) error {
-	parcels, err := s.store.GetByClient(client)
+func (s ParcelService) PrintClientParcels(ctx context.Context, client int) error {
+	parcels, err := s.ClientParcels(ctx, client)
 	if err != nil {
 		return err
 	}
@@ -67,113 +169,255 @@ func (s ParcelService) PrintClientParcels(client int) error {
 	return nil
 }
 
-func (s ParcelService) NextStatus(number int) error {
-	parcel, err := s.store.Get(number)
+func (s ParcelService) Get(ctx context.Context, number int) (Parcel, error) {
+	err := s.check(ctx, actionRead)
 	if err != nil {
-		return err
+		return Parcel{}, err
 	}
 
-	var nextStatus string
-	switch parcel.Status {
-	case ParcelStatusRegistered:
-		nextStatus = ParcelStatusSent
-	case ParcelStatusSent:
-		nextStatus = ParcelStatusDelivered
-	case ParcelStatusDelivered:
-		return nil
+	p, err := s.storage.Get(ctx, number)
+	if err != nil {
+		return p, err
 	}
 
-	fmt.Printf("У посылки № %d новый статус: %s\n", number, nextStatus)
-
-	return s.store.SetStatus(number, nextStatus)
+	err = authorizeOwner(ctx, p.Client)
+	if err != nil {
+		return Parcel{}, err
+	}
+	return p, nil
 }
 
-func (s ParcelService) ChangeAddress(number int, address string) error {
-	return s.store.SetAddress(number, address)
+// Track возвращает посылку по трекинг-токену в виде, безопасном для публичного показа.
+// Доступен без аутентификации.
+func (s ParcelService) Track(ctx context.Context, token string) (TrackingView, error) {
+	// трекинг-токен уникален среди всех компаний, поэтому посылка ищется без учёта компании запроса
+	store := s.store.WithContext(withAnyTenant(ctx))
+	p, err := store.GetByToken(token)
+	if err != nil {
+		return TrackingView{}, err
+	}
+
+	history, err := store.History(p.Number)
+	if err != nil {
+		return TrackingView{}, err
+	}
+
+	nearby, err := store.CourierNearby(p)
+	if err != nil {
+		return TrackingView{}, err
+	}
+
+	view := newTrackingView(p, history, s.store.redactor)
+	view.Courier = nearby
+	return view, nil
 }
 
-func (s ParcelService) Delete(number int) error {
-	return s.store.Delete(number)
+func (s ParcelService) ClientParcels(ctx context.Context, client int) ([]Parcel, error) {
+	err := s.check(ctx, actionRead)
+	if err != nil {
+		return nil, err
+	}
+	err = authorizeOwner(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storage.GetByClient(ctx, client)
 }
 
-func main() {
-	//подключение к БД
-	db, err := sql.Open("sqlite", "tracker.db")
+// List возвращает посылки по фильтрам; клиент может выбирать только свои посылки
+func (s ParcelService) List(ctx context.Context, opts ListOptions) ([]Parcel, error) {
+	err := s.check(ctx, actionRead)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, err
+	}
+	if c, ok := CallerFromContext(ctx); ok && c.Role == RoleClient {
+		err = authorizeOwner(ctx, opts.Client)
+		if err != nil {
+			return nil, err
+		}
 	}
-	defer db.Close()
 
-	// создаем объект ParcelStore
-	store := NewParcelStore(db)
-	service := NewParcelService(store)
+	return s.store.WithContext(ctx).List(opts)
+}
 
-	// регистрация посылки
-	client := 1
-	address := "Псков, д. Пушкина, ул. Колотушкина, д. 5"
-	p, err := service.Register(client, address)
+func (s ParcelService) SetStatus(ctx context.Context, number int, status string) error {
+	err := s.check(ctx, actionSetStatus)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
+	}
+
+	switch status {
+	case ParcelStatusRegistered, ParcelStatusSent, ParcelStatusCustomsHold, ParcelStatusDelivered:
+	default:
+		return ErrUnknownStatus
 	}
 
-	// изменение адреса
-	newAddress := "Саратов, д. Верхние Зори, ул. Козлова, д. 25"
-	err = service.ChangeAddress(p.Number, newAddress)
+	return s.storage.SetStatus(ctx, number, status)
+}
+
+// SetStatusIf меняет статус посылки на status, только если сейчас у неё статус expected;
+// иначе возвращается ErrConflict и статус не меняется
+func (s ParcelService) SetStatusIf(ctx context.Context, number int, expected, status string) error {
+	err := s.check(ctx, actionSetStatus)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
+	}
+
+	switch status {
+	case ParcelStatusRegistered, ParcelStatusSent, ParcelStatusCustomsHold, ParcelStatusDelivered:
+	default:
+		return ErrUnknownStatus
 	}
 
-	// изменение статуса
-	err = service.NextStatus(p.Number)
+	return s.store.WithContext(ctx).SetStatusIf(number, expected, status)
+}
+
+func (s ParcelService) History(ctx context.Context, number int) ([]ParcelChange, error) {
+	// проверяем доступ к самой посылке
+	_, err := s.Get(ctx, number)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, err
 	}
 
-	// вывод посылок клиента
-	err = service.PrintClientParcels(client)
+	return s.store.WithContext(ctx).History(number)
+}
+
+// Subscribe подписывается на изменения статусов всех посылок
+func (s ParcelService) Subscribe() (<-chan ParcelChange, func()) {
+	return s.store.Subscribe()
+}
+
+// Watch подписывается на изменения статуса посылки number.
+// Канал закрывается после вызова функции отписки.
+func (s ParcelService) Watch(number int) (<-chan ParcelChange, func()) {
+	changes, cancel := s.store.Subscribe()
+
+	out := make(chan ParcelChange, changeFeedBuffer)
+	go func() {
+		defer close(out)
+		for c := range changes {
+			if c.Number != number {
+				continue
+			}
+			select {
+			case out <- c:
+			default:
+			}
+		}
+	}()
+
+	return out, cancel
+}
+
+func (s ParcelService) NextStatus(ctx context.Context, number int) error {
+	err := s.check(ctx, actionSetStatus)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
 	}
 
-	// попытка удаления отправленной посылки
-	err = service.Delete(p.Number)
+	parcel, err := s.storage.Get(ctx, number)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
+	}
+
+	nextStatus, ok := NextParcelStatus(parcel.Status)
+	if !ok {
+		return nil
+	}
+
+	return s.storage.SetStatus(ctx, number, nextStatus)
+}
+
+// NextParcelStatus возвращает статус, следующий за status; задержанная таможней
+// посылка продолжает путь. false означает, что посылка уже доставлена и дальше
+// продвигать её некуда.
+func NextParcelStatus(status string) (string, bool) {
+	switch status {
+	case ParcelStatusRegistered, ParcelStatusCustomsHold:
+		return ParcelStatusSent, true
+	case ParcelStatusSent:
+		return ParcelStatusDelivered, true
+	default:
+		return "", false
 	}
+}
+
+// statusRank порядок статусов на пути посылки. Задержка на таможне — остановка
+// в пути, поэтому посылка переходит между sent и customs_hold в обе стороны.
+var statusRank = map[string]int{
+	ParcelStatusRegistered:  1,
+	ParcelStatusSent:        2,
+	ParcelStatusCustomsHold: 2,
+	ParcelStatusDelivered:   3,
+}
 
-	// вывод посылок клиента
-	// предыдущая посылка не должна удалиться, т.к. её статус НЕ «зарегистрирована»
-	err = service.PrintClientParcels(client)
+// CheckTransition проверяет, что посылку можно перевести из статуса old в status:
+// статус известен и не предшествует текущему. Шаги можно пропускать, например
+// сразу отметить доставленной посылку, отправку которой сканер не зафиксировал.
+func CheckTransition(old, status string) error {
+	next, ok := statusRank[status]
+	if !ok {
+		return ErrUnknownStatus
+	}
+	if next < statusRank[old] {
+		return ErrInvalidTransition
+	}
+	return nil
+}
+
+func (s ParcelService) ChangeAddress(ctx context.Context, number int, address string) error {
+	err := s.check(ctx, actionChangeAddress)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
+	}
+
+	// клиент может менять адрес только своих посылок
+	if _, ok := CallerFromContext(ctx); ok {
+		p, err := s.storage.Get(ctx, number)
+		if err != nil {
+			return err
+		}
+		err = authorizeOwner(ctx, p.Client)
+		if err != nil {
+			return err
+		}
 	}
 
-	// регистрация новой посылки
-	p, err = service.Register(client, address)
+	err = s.storage.SetAddress(ctx, number, address)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
 	}
+	s.geocode(ctx, number, address)
+	return nil
+}
 
-	// удаление новой посылки
-	err = service.Delete(p.Number)
+func (s ParcelService) Delete(ctx context.Context, number int) error {
+	err := s.check(ctx, actionDelete)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
 	}
 
-	// вывод посылок клиента
-	// здесь не должно быть последней посылки, т.к. она должна была успешно удалиться
-	err = service.PrintClientParcels(client)
+	// записи о документах удаляются вместе с посылкой, а их содержимое — после неё
+	var attachments []Attachment
+	if s.blobs != nil {
+		attachments, err = s.store.WithContext(ctx).Attachments(number)
+		if err != nil {
+			return err
+		}
+	}
+	err = s.storage.Delete(ctx, number)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
+	}
+	for _, a := range attachments {
+		s.deleteBlob(ctx, a.key)
 	}
+	return nil
+}
+
+// Execute выполняет команду tracker с аргументами процесса. Точка входа программы —
+// cmd/tracker; сам пакет можно импортировать в другие сервисы как библиотеку.
+func Execute() error {
+	return newRootCmd().Execute()
 }
@@ -0,0 +1,124 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// MaintenanceReport результат обслуживания файла SQLite
+type MaintenanceReport struct {
+	// SizeBefore и SizeAfter размер БД в байтах до и после VACUUM
+	SizeBefore int64
+	SizeAfter  int64
+	// Problems ошибки integrity_check; пусто — БД цела
+	Problems []string
+	Duration time.Duration
+}
+
+// Reclaimed возвращает, сколько байт освободил VACUUM
+func (r MaintenanceReport) Reclaimed() int64 {
+	return r.SizeBefore - r.SizeAfter
+}
+
+// ErrDBCorrupted возвращает MaintainDB, если integrity_check нашёл повреждения
+var ErrDBCorrupted = errors.New("БД повреждена")
+
+// MaintainDB проверяет целостность файла SQLite и, если он цел, сжимает его VACUUM
+// и обновляет статистику планировщика запросов ANALYZE. Все шаги выполняются на одном
+// соединении; VACUUM переписывает файл целиком и на это время блокирует запись.
+// Если integrity_check нашёл повреждения, VACUUM не выполняется, а вместе с отчётом
+// возвращается ErrDBCorrupted.
+func MaintainDB(ctx context.Context, db *sql.DB) (MaintenanceReport, error) {
+	start := time.Now()
+	var report MaintenanceReport
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return report, err
+	}
+	defer conn.Close()
+
+	report.SizeBefore, err = sqliteSize(ctx, conn)
+	if err != nil {
+		return report, err
+	}
+	report.Problems, err = integrityCheck(ctx, conn)
+	if err != nil {
+		return report, err
+	}
+	if len(report.Problems) > 0 {
+		report.SizeAfter = report.SizeBefore
+		report.Duration = time.Since(start)
+		return report, fmt.Errorf("%w: %s", ErrDBCorrupted, strings.Join(report.Problems, "; "))
+	}
+
+	for _, q := range []string{"VACUUM", "ANALYZE"} {
+		_, err = conn.ExecContext(ctx, q)
+		if err != nil {
+			return report, fmt.Errorf("%s: %w", q, err)
+		}
+	}
+	report.SizeAfter, err = sqliteSize(ctx, conn)
+	report.Duration = time.Since(start)
+	return report, err
+}
+
+// sqliteSize возвращает размер БД в байтах по числу и размеру страниц
+func sqliteSize(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var pages, pageSize int64
+	err := conn.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pages)
+	if err != nil {
+		return 0, err
+	}
+	err = conn.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize)
+	return pages * pageSize, err
+}
+
+// integrityCheck выполняет PRAGMA integrity_check и возвращает найденные ошибки
+func integrityCheck(ctx context.Context, conn *sql.Conn) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		err := rows.Scan(&line)
+		if err != nil {
+			return nil, err
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	return problems, rows.Err()
+}
+
+// Maintenance задача планировщика maintenance: обслуживание файла БД хранилища
+type Maintenance struct {
+	store ParcelStore
+}
+
+// NewMaintenance создаёт обслуживание БД хранилища
+func NewMaintenance(store ParcelStore) *Maintenance {
+	return &Maintenance{store: store}
+}
+
+// Run выполняет MaintainDB и пишет отчёт в лог; повреждение БД возвращается ошибкой задачи
+func (m *Maintenance) Run(ctx context.Context) error {
+	report, err := MaintainDB(ctx, m.store.db)
+	if err != nil {
+		return err
+	}
+	m.store.logger.Log(ctx, slog.LevelInfo, "БД обслужена", "op", "maintenance.Run",
+		"size_before", report.SizeBefore, "size_after", report.SizeAfter,
+		"reclaimed", report.Reclaimed(), "duration", report.Duration)
+	return nil
+}
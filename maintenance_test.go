@@ -0,0 +1,49 @@
+package tracker_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestMaintainDB проверяет, что обслуживание освобождает место после удаления посылок
+func TestMaintainDB(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	store := tracker.NewParcelStore(db)
+	var numbers []int
+	for i := 0; i < 300; i++ {
+		p := testsupport.NewParcel().Build()
+		p.Address = strings.Repeat("длинный адрес ", 35)
+		id, err := store.Add(p)
+		require.NoError(t, err)
+		numbers = append(numbers, id)
+	}
+	for _, id := range numbers {
+		require.NoError(t, store.Delete(id))
+	}
+
+	// maintain
+	report, err := tracker.MaintainDB(context.Background(), db)
+	require.NoError(t, err)
+
+	// check
+	assert.Empty(t, report.Problems)
+	assert.Positive(t, report.Reclaimed())
+	assert.Equal(t, report.SizeBefore-report.SizeAfter, report.Reclaimed())
+
+	// повторное обслуживание освобождать уже нечего, но задача проходит
+	require.NoError(t, tracker.NewMaintenance(store).Run(context.Background()))
+	report, err = tracker.MaintainDB(context.Background(), db)
+	require.NoError(t, err)
+	assert.Zero(t, report.Reclaimed())
+}
@@ -0,0 +1,487 @@
+package tracker
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// Форматы манифестов перевозчиков
+const (
+	ManifestCSV = "csv"
+	ManifestEDI = "edi"
+)
+
+var (
+	// ErrUnknownManifestFormat возвращается при импорте манифеста в формате, которого трекер не разбирает
+	ErrUnknownManifestFormat = errors.New("неизвестный формат манифеста")
+	// ErrInvalidManifestRow возвращается для строки манифеста, в которой не хватает полей
+	// или поля некорректны
+	ErrInvalidManifestRow = errors.New("некорректная строка манифеста")
+	// ErrAddressLocked возвращается при импорте нового адреса посылки, которая уже не зарегистрирована
+	ErrAddressLocked = errors.New("адрес можно изменить только у зарегистрированной посылки")
+)
+
+// ManifestRow строка манифеста перевозчика. Строка без номера регистрирует новую посылку,
+// строка с номером меняет статус и адрес существующей; пустые поля не меняются.
+type ManifestRow struct {
+	// Line номер строки CSV или сегмента EDI, с которого начинается строка
+	Line    int
+	Number  int
+	Client  int
+	Address string
+	Status  string
+}
+
+// RowError ошибка строки манифеста; такая строка не импортируется
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("строка %d: %v", e.Line, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// ImportReport итог импорта манифеста
+type ImportReport struct {
+	Added   int
+	Updated int
+	// Errors отклонённые строки в порядке их номеров
+	Errors []RowError
+	// added номера добавленных посылок для обработчиков OnChange
+	added []int
+}
+
+// ParseCSVManifest разбирает CSV-манифест. Первая строка — заголовок; колонки с полями
+// посылки находятся по заголовкам из cfg.Columns, остальные колонки пропускаются.
+// Строки с некорректными полями попадают в список ошибок, а не прерывают разбор.
+func ParseCSVManifest(r io.Reader, cfg config.CSVImport) ([]ManifestRow, []RowError, error) {
+	cr := csv.NewReader(r)
+	if cfg.Comma != "" {
+		cr.Comma = []rune(cfg.Comma)[0]
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("заголовок манифеста: %w", err)
+	}
+	// index номер колонки каждого поля посылки, которое читается из манифеста
+	index := map[string]int{}
+	for field, name := range cfg.Columns {
+		if name == "" {
+			continue
+		}
+		i := slices.IndexFunc(header, func(h string) bool { return strings.EqualFold(strings.TrimSpace(h), name) })
+		if i < 0 {
+			return nil, nil, fmt.Errorf("в манифесте нет колонки %q для поля %s", name, field)
+		}
+		index[field] = i
+	}
+	value := func(record []string, field string) string {
+		i, ok := index[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []ManifestRow
+	var rowErrs []RowError
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				// после ошибки кавычек csv.Reader продолжает со следующей строки
+				rowErrs = append(rowErrs, RowError{Line: parseErr.Line, Err: fmt.Errorf("%w: %v", ErrInvalidManifestRow, parseErr.Err)})
+				continue
+			}
+			return nil, nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		row, err := newManifestRow(line, value(record, "number"), value(record, "client"), value(record, "address"), value(record, "status"))
+		if err != nil {
+			rowErrs = append(rowErrs, RowError{Line: line, Err: err})
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrs, nil
+}
+
+// ParseEDIManifest разбирает манифест в упрощённом EDIFACT: сегменты заканчиваются «'»,
+// элементы разделяются «+», а «?» экранирует следующий символ. Посылку описывают сегменты
+//
+//	CNI+<порядковый номер>+<номер посылки>'  начало посылки; без номера — новая посылка
+//	NAD+CN+<клиент>+<адрес>'                 получатель
+//	STS+<статус>'                            статус
+//
+// Служебные сегменты (UNA, UNB, UNH, BGM, UNT, UNZ) и остальные сегменты пропускаются.
+// Номер строки в отчёте — номер сегмента CNI.
+func ParseEDIManifest(r io.Reader) ([]ManifestRow, []RowError, error) {
+	segments, err := readEDISegments(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []ManifestRow
+	var rowErrs []RowError
+	var fields map[string]string
+	line := 0
+	flush := func() {
+		if fields == nil {
+			return
+		}
+		row, err := newManifestRow(line, fields["number"], fields["client"], fields["address"], fields["status"])
+		if err != nil {
+			rowErrs = append(rowErrs, RowError{Line: line, Err: err})
+		} else {
+			rows = append(rows, row)
+		}
+		fields = nil
+	}
+
+	for i, seg := range segments {
+		element := func(n int) string {
+			if n >= len(seg) {
+				return ""
+			}
+			return strings.TrimSpace(seg[n])
+		}
+		switch seg[0] {
+		case "CNI":
+			flush()
+			line = i + 1
+			fields = map[string]string{"number": element(2)}
+		case "NAD":
+			if fields != nil && element(1) == "CN" {
+				fields["client"] = element(2)
+				fields["address"] = element(3)
+			}
+		case "STS":
+			if fields != nil {
+				fields["status"] = element(1)
+			}
+		case "UNT", "UNZ":
+			flush()
+		}
+	}
+	flush()
+	return rows, rowErrs, nil
+}
+
+// readEDISegments читает сегменты EDI, разбитые на элементы; переводы строк между
+// сегментами не учитываются
+func readEDISegments(r io.Reader) ([][]string, error) {
+	var segments [][]string
+	var seg []string
+	var elem strings.Builder
+	escaped := false
+
+	br := bufio.NewReader(r)
+	for {
+		c, _, err := br.ReadRune()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case escaped:
+			elem.WriteRune(c)
+			escaped = false
+		case c == '?':
+			escaped = true
+		case c == '+':
+			seg = append(seg, elem.String())
+			elem.Reset()
+		case c == '\'':
+			seg = append(seg, elem.String())
+			elem.Reset()
+			segments = append(segments, seg)
+			seg = nil
+		case c == '\r' || c == '\n':
+		default:
+			elem.WriteRune(c)
+		}
+	}
+	if len(seg) > 0 || strings.TrimSpace(elem.String()) != "" {
+		return nil, fmt.Errorf("%w: последний сегмент EDI не завершён «'»", ErrInvalidManifestRow)
+	}
+	return segments, nil
+}
+
+// newManifestRow проверяет поля строки манифеста. Новой посылке нужны клиент и адрес,
+// существующей — новый статус или адрес; статус должен быть известен трекеру.
+func newManifestRow(line int, number, client, address, status string) (ManifestRow, error) {
+	row := ManifestRow{Line: line, Address: address, Status: strings.ToLower(status)}
+
+	var err error
+	if number != "" {
+		row.Number, err = strconv.Atoi(number)
+		if err != nil || row.Number < 1 {
+			return row, fmt.Errorf("%w: номер посылки %q", ErrInvalidManifestRow, number)
+		}
+	}
+	if client != "" {
+		row.Client, err = strconv.Atoi(client)
+		if err != nil || row.Client < 1 {
+			return row, fmt.Errorf("%w: идентификатор клиента %q", ErrInvalidManifestRow, client)
+		}
+	}
+	if row.Status != "" {
+		if _, ok := statusRank[row.Status]; !ok {
+			return row, fmt.Errorf("%w %q", ErrUnknownStatus, status)
+		}
+	}
+
+	switch {
+	case row.Number == 0 && (row.Client == 0 || row.Address == ""):
+		return row, fmt.Errorf("%w: для новой посылки нужны клиент и адрес", ErrInvalidManifestRow)
+	case row.Number != 0 && row.Status == "" && row.Address == "":
+		return row, fmt.Errorf("%w: для посылки %d не указаны ни статус, ни адрес", ErrInvalidManifestRow, row.Number)
+	}
+	if row.Address != "" {
+		err = validateAddress(row.Address)
+		if err != nil {
+			return row, fmt.Errorf("%w: %w", ErrInvalidManifestRow, err)
+		}
+	}
+	return row, nil
+}
+
+// ImportParcels добавляет и изменяет посылки по строкам манифеста в одной транзакции.
+// Строки, которые нельзя применить (посылки нет, статус возвращает её назад, адрес
+// уже не изменить), попадают в отчёт и не меняют БД; остальные строки применяются.
+// Ошибка БД откатывает весь импорт.
+func (s ParcelStore) ImportParcels(rows []ManifestRow) (ImportReport, error) {
+	start := time.Now()
+	span := s.startSpan("ImportParcels")
+	defer span.End()
+
+	var report ImportReport
+	var changes []ParcelChange
+	var numbers []int
+	err := s.withRetry("store.ImportParcels", func(s ParcelStore) error {
+		var err error
+		report, changes, numbers, err = s.importParcels(rows)
+		return err
+	})
+	for _, number := range numbers {
+		s.invalidate(number)
+	}
+	spanError(span, err)
+	s.metrics.observeQuery("ImportParcels", start)
+	logResult(s.ctx, s.logger, "store.ImportParcels", start, err,
+		"rows", len(rows), "added", report.Added, "updated", report.Updated, "rejected", len(report.Errors))
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	s.added(report.added...)
+	for _, c := range changes {
+		s.metrics.statusChanged(c.Status)
+	}
+	s.notify(changes)
+	return report, nil
+}
+
+// importParcels выполняет импорт и возвращает отчёт, изменения статусов для подписчиков
+// и номера изменённых посылок для сброса кеша
+func (s ParcelStore) importParcels(rows []ManifestRow) (ImportReport, []ParcelChange, []int, error) {
+	var report ImportReport
+	var changes []ParcelChange
+	var numbers []int
+
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return report, nil, nil, err
+	}
+	tx, err := s.begin()
+	if err != nil {
+		return report, nil, nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, row := range rows {
+		if row.Number == 0 {
+			status := row.Status
+			if status == "" {
+				status = ParcelStatusRegistered
+			}
+			token, err := newTrackingToken()
+			if err != nil {
+				return report, nil, nil, err
+			}
+			address, err := s.sealAddress(row.Address)
+			if err != nil {
+				return report, nil, nil, err
+			}
+			res, err := s.exec(tx, queryInsertParcel,
+				sql.Named("client", row.Client),
+				sql.Named("status", status),
+				sql.Named("address", address),
+				sql.Named("created_at", now),
+				sql.Named("tracking_token", token),
+				sql.Named("tenant", tenant),
+				sql.Named("eta", ""),
+				sql.Named("priority", ""),
+				sql.Named("weight", 0),
+				sql.Named("price", 0),
+				sql.Named("deadline", ""),
+				sql.Named("postal", postalCode(row.Address)))
+			if err != nil {
+				return report, nil, nil, err
+			}
+			id, err := res.LastInsertId()
+			if err != nil {
+				return report, nil, nil, err
+			}
+			err = s.addHistory(tx, ParcelChange{Number: int(id), Status: status, ChangedAt: now, RequestID: RequestIDFromContext(s.ctx)})
+			if err != nil {
+				return report, nil, nil, err
+			}
+			err = s.addOutbox(tx, OutboxEvent{Type: EventParcelAdded, Number: int(id), Client: row.Client, Status: status, Tenant: tenant})
+			if err != nil {
+				return report, nil, nil, err
+			}
+			err = s.addAudit(tx, AuditEntry{Action: AuditRegister, Number: int(id), Client: row.Client, Tenant: tenant, RequestID: RequestIDFromContext(s.ctx)})
+			if err != nil {
+				return report, nil, nil, err
+			}
+			report.Added++
+			report.added = append(report.added, int(id))
+			continue
+		}
+
+		var old string
+		var client int
+		err := s.queryRow(tx, queryParcelStatus, sql.Named("number", row.Number), sql.Named("tenant", tenant)).Scan(&old, &client, &tenant)
+		if errors.Is(err, sql.ErrNoRows) {
+			report.Errors = append(report.Errors, RowError{Line: row.Line, Err: ErrParcelNotFound})
+			continue
+		}
+		if err != nil {
+			return report, nil, nil, err
+		}
+		// все проверки строки выполняются до записи, чтобы отклонённая строка ничего не меняла
+		// статус и адрес посылки в составе другой отдельно не меняются
+		if row.Status != "" || row.Address != "" {
+			parent, err := s.mergedInto(tx, row.Number)
+			if err != nil {
+				return report, nil, nil, err
+			}
+			if parent != 0 {
+				report.Errors = append(report.Errors, RowError{Line: row.Line,
+					Err: fmt.Errorf("%w: посылка в составе № %d", ErrMergedParcel, parent)})
+				continue
+			}
+		}
+		if row.Status != "" {
+			err = CheckTransition(old, row.Status)
+			if err != nil {
+				report.Errors = append(report.Errors, RowError{Line: row.Line, Err: err})
+				continue
+			}
+		}
+		if row.Address != "" && old != ParcelStatusRegistered {
+			report.Errors = append(report.Errors, RowError{Line: row.Line, Err: ErrAddressLocked})
+			continue
+		}
+
+		if row.Address != "" {
+			address, err := s.sealAddress(row.Address)
+			if err != nil {
+				return report, nil, nil, err
+			}
+			// строки манифеста применяются без проверки версии посылки
+			_, err = s.exec(tx, queryUpdateAddress,
+				sql.Named("address", address),
+				sql.Named("postal", postalCode(row.Address)),
+				sql.Named("number", row.Number),
+				sql.Named("status", ParcelStatusRegistered),
+				sql.Named("version", 0),
+				sql.Named("tenant", tenant))
+			if err != nil {
+				return report, nil, nil, err
+			}
+			_, err = s.exec(tx, queryDeleteCoordinates, sql.Named("number", row.Number))
+			if err != nil {
+				return report, nil, nil, err
+			}
+			err = s.addOutbox(tx, OutboxEvent{Type: EventParcelAddressChanged, Number: row.Number})
+			if err != nil {
+				return report, nil, nil, err
+			}
+		}
+		if row.Status != "" && row.Status != old {
+			changed, err := s.updateStatus(tx, row.Number, client, tenant, old, row.Status)
+			if err != nil {
+				return report, nil, nil, err
+			}
+			changes = append(changes, changed...)
+		}
+		numbers = append(numbers, row.Number)
+		report.Updated++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return report, nil, nil, err
+	}
+	return report, changes, numbers, nil
+}
+
+// ImportManifest разбирает манифест перевозчика в формате format (csv или edi)
+// и импортирует посылки из него. Отчёт содержит ошибки и разбора, и импорта.
+func (s ParcelService) ImportManifest(ctx context.Context, format string, r io.Reader, cfg config.CSVImport) (ImportReport, error) {
+	err := s.check(ctx, actionImport)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	var rows []ManifestRow
+	var rowErrs []RowError
+	switch format {
+	case ManifestCSV:
+		rows, rowErrs, err = ParseCSVManifest(r, cfg)
+	case ManifestEDI:
+		rows, rowErrs, err = ParseEDIManifest(r)
+	default:
+		err = fmt.Errorf("%w %q", ErrUnknownManifestFormat, format)
+	}
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	report, err := s.store.WithContext(ctx).ImportParcels(rows)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	report.Errors = append(report.Errors, rowErrs...)
+	slices.SortStableFunc(report.Errors, func(a, b RowError) int { return a.Line - b.Line })
+	return report, nil
+}
@@ -0,0 +1,112 @@
+package tracker_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestImportManifest проверяет импорт CSV-манифеста: корректные строки применяются,
+// остальные попадают в отчёт и ничего не меняют
+func TestImportManifest(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	store := tracker.NewParcelStore(db)
+	service := tracker.NewParcelService(store)
+
+	parcel := testsupport.NewParcel().Build()
+	parcel.Client = testsupport.Intn(10_000_000)
+	registered, err := store.Add(parcel)
+	require.NoError(t, err)
+	sent, err := store.Add(parcel)
+	require.NoError(t, err)
+	require.NoError(t, store.SetStatus(sent, tracker.ParcelStatusSent))
+
+	client := testsupport.Intn(10_000_000) + 1
+	manifest := strings.Join([]string{
+		"Номер;Клиент;Адрес;Статус;Вес",
+		fmt.Sprintf(";%d;Томск, пр. Ленина, д. 36;;1.5", client),
+		fmt.Sprintf("%d;;Казань, ул. Баумана, д. 7;Sent;2", registered),
+		"999999999;;;delivered;1",
+		fmt.Sprintf("%d;;;registered;1", sent),
+		";abc;Москва;;1",
+		fmt.Sprintf("%d;;Москва;;1", sent),
+		fmt.Sprintf("%d;;;lost;1", sent),
+		fmt.Sprintf(";%d;\"Томск,\u202eпр. Ленина\";;1", client),
+	}, "\n")
+	cfg := config.CSVImport{Comma: ";", Columns: map[string]string{
+		"number": "номер", "client": "клиент", "address": "адрес", "status": "статус",
+	}}
+
+	// import
+	report, err := service.ImportManifest(context.Background(), tracker.ManifestCSV, strings.NewReader(manifest), cfg)
+	require.NoError(t, err)
+
+	// check
+	assert.Equal(t, 1, report.Added)
+	assert.Equal(t, 1, report.Updated)
+	require.Len(t, report.Errors, 6)
+	for i, want := range []error{tracker.ErrParcelNotFound, tracker.ErrInvalidTransition, tracker.ErrInvalidManifestRow, tracker.ErrAddressLocked, tracker.ErrUnknownStatus, tracker.ErrInvalidAddress} {
+		assert.Equal(t, i+4, report.Errors[i].Line)
+		assert.ErrorIs(t, report.Errors[i], want)
+	}
+
+	parcels, err := store.GetByClient(client)
+	require.NoError(t, err)
+	require.Len(t, parcels, 1)
+	assert.Equal(t, tracker.ParcelStatusRegistered, parcels[0].Status)
+	assert.NotEmpty(t, parcels[0].TrackingToken)
+
+	p, err := store.Get(registered)
+	require.NoError(t, err)
+	assert.Equal(t, tracker.ParcelStatusSent, p.Status)
+	assert.Equal(t, "Казань, ул. Баумана, д. 7", p.Address)
+
+	p, err = store.Get(sent)
+	require.NoError(t, err)
+	assert.Equal(t, tracker.ParcelStatusSent, p.Status)
+	assert.Equal(t, parcel.Address, p.Address)
+
+	_, err = service.ImportManifest(context.Background(), tracker.ManifestCSV, strings.NewReader("number\n1"), cfg)
+	assert.Error(t, err)
+	_, err = service.ImportManifest(context.Background(), "xml", strings.NewReader(""), cfg)
+	assert.ErrorIs(t, err, tracker.ErrUnknownManifestFormat)
+}
+
+// TestParseEDIManifest проверяет разбор сегментов манифеста EDI
+func TestParseEDIManifest(t *testing.T) {
+	// prepare
+	manifest := "UNB+UNOC:3+CARRIER+TRACKER'\n" +
+		"UNH+1+IFTMIN:D:96A:UN'\n" +
+		"CNI+1'\nNAD+CN+42+Псков?, ул. Колотушкина?+д. 5'\n" +
+		"CNI+2+17'\nDTM+137:20240101:102'\nSTS+delivered'\n" +
+		"CNI+3'\nNAD+CN+42'\n" +
+		"UNT+9+1'\nUNZ+1+1'\n"
+
+	// parse
+	rows, rowErrs, err := tracker.ParseEDIManifest(strings.NewReader(manifest))
+	require.NoError(t, err)
+
+	// check
+	assert.Equal(t, []tracker.ManifestRow{
+		{Line: 3, Client: 42, Address: "Псков, ул. Колотушкина+д. 5"},
+		{Line: 5, Number: 17, Status: tracker.ParcelStatusDelivered},
+	}, rows)
+	require.Len(t, rowErrs, 1)
+	assert.Equal(t, 8, rowErrs[0].Line)
+	assert.ErrorIs(t, rowErrs[0], tracker.ErrInvalidManifestRow)
+
+	_, _, err = tracker.ParseEDIManifest(strings.NewReader("CNI+1'NAD+CN+42+Псков"))
+	assert.ErrorIs(t, err, tracker.ErrInvalidManifestRow)
+}
@@ -0,0 +1,241 @@
+package tracker
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics метрики хранилища и сервиса в формате Prometheus.
+// Методы безопасно вызывать у nil: тогда метрики не собираются.
+type Metrics struct {
+	registry          *prometheus.Registry
+	parcelsAdded      prometheus.Counter
+	statusTransitions *prometheus.CounterVec
+	queryDuration     *prometheus.HistogramVec
+	operations        *prometheus.CounterVec
+	cacheLookups      *prometheus.CounterVec
+	slowQueries       prometheus.Counter
+	jobRuns           *prometheus.CounterVec
+	jobDuration       *prometheus.HistogramVec
+	jobLastSuccess    *prometheus.GaugeVec
+	overdueParcels    *prometheus.GaugeVec
+	overdueAlerts     *prometheus.CounterVec
+	dbUp              *prometheus.GaugeVec
+	dbPingDuration    *prometheus.HistogramVec
+	dbReconnects      *prometheus.CounterVec
+}
+
+// NewMetrics создаёт метрики в собственном реестре вместе со стандартными метриками процесса и Go
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		parcelsAdded: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "parcels_added_total",
+			Help: "Количество зарегистрированных посылок.",
+		}),
+		statusTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "status_transitions_total",
+			Help: "Количество смен статуса посылок по новому статусу.",
+		}, []string{"status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "store_query_duration_seconds",
+			Help:    "Длительность запросов к БД по методам ParcelStore.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8),
+		}, []string{"method"}),
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "service_operations_total",
+			Help: "Количество операций ParcelService по действию и результату проверки доступа.",
+		}, []string{"action", "result"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "store_cache_lookups_total",
+			Help: "Количество обращений к кешу посылок по результату: hit или miss.",
+		}, []string{"result"}),
+		slowQueries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "store_slow_queries_total",
+			Help: "Количество запросов к БД дольше порога db.slow_query_threshold.",
+		}),
+		jobRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "scheduler_job_runs_total",
+			Help: "Количество запусков периодических задач по задаче и результату: ok или error.",
+		}, []string{"job", "result"}),
+		jobDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "scheduler_job_duration_seconds",
+			Help:    "Длительность запусков периодических задач.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+		}, []string{"job"}),
+		jobLastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "scheduler_job_last_success_timestamp_seconds",
+			Help: "Время окончания последнего успешного запуска периодической задачи, Unix-время в секундах.",
+		}, []string{"job"}),
+		overdueParcels: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "parcels_overdue",
+			Help: "Количество посылок, которые дольше срока остаются в статусе, на момент последней проверки.",
+		}, []string{"status"}),
+		overdueAlerts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "overdue_alerts_total",
+			Help: "Количество обнаруженных остановок посылок в статусе дольше срока.",
+		}, []string{"status"}),
+		dbUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "db_up",
+			Help: "Ответила ли БД на последнюю проверку монитора: 1 — да, 0 — нет.",
+		}, []string{"db"}),
+		dbPingDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_ping_duration_seconds",
+			Help:    "Длительность проверок БД монитором.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8),
+		}, []string{"db"}),
+		dbReconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "db_reconnects_total",
+			Help: "Количество переподключений к БД после неудачных проверок подряд.",
+		}, []string{"db"}),
+	}
+
+	m.registry.MustRegister(
+		m.parcelsAdded,
+		m.statusTransitions,
+		m.queryDuration,
+		m.operations,
+		m.cacheLookups,
+		m.slowQueries,
+		m.jobRuns,
+		m.jobDuration,
+		m.jobLastSuccess,
+		m.overdueParcels,
+		m.overdueAlerts,
+		m.dbUp,
+		m.dbPingDuration,
+		m.dbReconnects,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler отдаёт метрики для сбора Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// observeQuery учитывает длительность запроса метода method, начатого в start
+func (m *Metrics) observeQuery(method string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.queryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// slowQuery учитывает медленный запрос
+func (m *Metrics) slowQuery() {
+	if m == nil {
+		return
+	}
+	m.slowQueries.Inc()
+}
+
+// jobRun учитывает запуск задачи планировщика job, начатый в start и завершившийся с ошибкой err
+func (m *Metrics) jobRun(job string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.jobDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.jobRuns.WithLabelValues(job, "error").Inc()
+		return
+	}
+	m.jobRuns.WithLabelValues(job, "ok").Inc()
+	m.jobLastSuccess.WithLabelValues(job).SetToCurrentTime()
+}
+
+// setOverdue записывает число посылок, застрявших в статусе status
+func (m *Metrics) setOverdue(status string, n int) {
+	if m == nil {
+		return
+	}
+	m.overdueParcels.WithLabelValues(status).Set(float64(n))
+}
+
+// overdueAlert учитывает остановку посылки в статусе status, о которой оповестили
+func (m *Metrics) overdueAlert(status string) {
+	if m == nil {
+		return
+	}
+	m.overdueAlerts.WithLabelValues(status).Inc()
+}
+
+// dbPing учитывает проверку БД db, начатую в start и завершившуюся с ошибкой err
+func (m *Metrics) dbPing(db string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.dbPingDuration.WithLabelValues(db).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.dbUp.WithLabelValues(db).Set(0)
+		return
+	}
+	m.dbUp.WithLabelValues(db).Set(1)
+}
+
+// dbReconnect учитывает переподключение к БД db
+func (m *Metrics) dbReconnect(db string) {
+	if m == nil {
+		return
+	}
+	m.dbReconnects.WithLabelValues(db).Inc()
+}
+
+// parcelAdded учитывает регистрацию посылки
+func (m *Metrics) parcelAdded() {
+	if m == nil {
+		return
+	}
+	m.parcelsAdded.Inc()
+}
+
+// statusChanged учитывает смену статуса посылки на status
+func (m *Metrics) statusChanged(status string) {
+	if m == nil {
+		return
+	}
+	m.statusTransitions.WithLabelValues(status).Inc()
+}
+
+// cacheLookup учитывает обращение к кешу посылок: попадание или промах
+func (m *Metrics) cacheLookup(hit bool) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheLookups.WithLabelValues(result).Inc()
+}
+
+// operation учитывает операцию сервиса и результат её проверки
+func (m *Metrics) operation(act action, err error) {
+	if m == nil {
+		return
+	}
+	m.operations.WithLabelValues(act.String(), operationResult(err)).Inc()
+}
+
+// operationResult метка результата проверки операции
+func operationResult(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrUnauthenticated):
+		return "unauthenticated"
+	case errors.Is(err, ErrForbidden):
+		return "forbidden"
+	default:
+		return "error"
+	}
+}
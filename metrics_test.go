@@ -0,0 +1,43 @@
+package tracker
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMetrics проверяет счётчики хранилища и их выдачу на /metrics
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	metrics := NewMetrics()
+	store := NewParcelStore(db, WithStoreMetrics(metrics))
+	srv := httptest.NewServer(NewHTTPHandler(NewParcelService(store, WithMetrics(metrics))))
+	defer srv.Close()
+
+	// add
+	id, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+	require.NoError(t, store.SetStatus(id, ParcelStatusSent))
+	// у несуществующей посылки статус не меняется
+	require.NoError(t, store.SetStatus(id+1_000_000, ParcelStatusSent))
+
+	// check
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.parcelsAdded))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.statusTransitions.WithLabelValues(ParcelStatusSent)))
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `store_query_duration_seconds_count{method="SetStatus"} 2`)
+}
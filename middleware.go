@@ -0,0 +1,266 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ParcelStorage основные операции с посылками. ctx каждого вызова несёт компанию,
+// идентификатор запроса, трассировку и отмену. ParcelStore даёт реализацию методом
+// Storage, а другие реализации — заглушки в тестах, хранилища встраивающих приложений —
+// получают логирование, метрики, кеш, повторы и трассировку обёртками StoreMiddleware.
+type ParcelStorage interface {
+	Add(ctx context.Context, p Parcel) (int, error)
+	Get(ctx context.Context, number int) (Parcel, error)
+	GetByClient(ctx context.Context, client int) ([]Parcel, error)
+	SetStatus(ctx context.Context, number int, status string) error
+	SetAddress(ctx context.Context, number int, address string) error
+	Delete(ctx context.Context, number int) error
+}
+
+// Storage возвращает хранилище как ParcelStorage: каждый вызов выполняется
+// в контексте вызова, как после WithContext
+func (s ParcelStore) Storage() ParcelStorage {
+	return storeStorage{store: s}
+}
+
+// storeStorage ParcelStorage поверх ParcelStore
+type storeStorage struct {
+	store ParcelStore
+}
+
+func (s storeStorage) Add(ctx context.Context, p Parcel) (int, error) {
+	return s.store.WithContext(ctx).Add(p)
+}
+
+func (s storeStorage) Get(ctx context.Context, number int) (Parcel, error) {
+	return s.store.WithContext(ctx).Get(number)
+}
+
+func (s storeStorage) GetByClient(ctx context.Context, client int) ([]Parcel, error) {
+	return s.store.WithContext(ctx).GetByClient(client)
+}
+
+func (s storeStorage) SetStatus(ctx context.Context, number int, status string) error {
+	return s.store.WithContext(ctx).SetStatus(number, status)
+}
+
+func (s storeStorage) SetAddress(ctx context.Context, number int, address string) error {
+	return s.store.WithContext(ctx).SetAddress(number, address)
+}
+
+func (s storeStorage) Delete(ctx context.Context, number int) error {
+	return s.store.WithContext(ctx).Delete(number)
+}
+
+// StoreMiddleware оборачивает хранилище next и возвращает хранилище с тем же поведением
+// и дополнительной обработкой вызовов
+type StoreMiddleware func(next ParcelStorage) ParcelStorage
+
+// Wrap оборачивает s обёртками mws. Первая обёртка внешняя: вызов проходит обёртки
+// в порядке списка, например Wrap(s, LoggingMiddleware(l), RetryMiddleware(p))
+// записывает в лог один вызов на все попытки.
+func Wrap(s ParcelStorage, mws ...StoreMiddleware) ParcelStorage {
+	for i := len(mws) - 1; i >= 0; i-- {
+		s = mws[i](s)
+	}
+	return s
+}
+
+// storageCall вызов метода хранилища, который видят обёртки
+type storageCall struct {
+	// method имя метода ParcelStorage
+	method string
+	// write метод изменяет данные
+	write bool
+	// number и client посылка и клиент вызова; 0, если не известны до вызова
+	number int
+	client int
+}
+
+// logArgs атрибуты записи лога о вызове
+func (c storageCall) logArgs() []any {
+	var args []any
+	if c.number != 0 {
+		args = append(args, "number", c.number)
+	}
+	if c.client != 0 {
+		args = append(args, "client", c.client)
+	}
+	return args
+}
+
+// aroundFunc выполняет вызов c функцией run и может сделать что-то до и после него.
+// run получает контекст, с которым вызов уйдёт в следующее хранилище.
+type aroundFunc func(ctx context.Context, c storageCall, run func(ctx context.Context) error) error
+
+// aroundStorage передаёт каждый вызов в next через around
+type aroundStorage struct {
+	next   ParcelStorage
+	around aroundFunc
+}
+
+// aroundMiddleware обёртка, которая обрабатывает все методы одинаково функцией around
+func aroundMiddleware(around aroundFunc) StoreMiddleware {
+	return func(next ParcelStorage) ParcelStorage {
+		return aroundStorage{next: next, around: around}
+	}
+}
+
+func (a aroundStorage) Add(ctx context.Context, p Parcel) (int, error) {
+	var id int
+	err := a.around(ctx, storageCall{method: "Add", write: true, client: p.Client}, func(ctx context.Context) error {
+		var err error
+		id, err = a.next.Add(ctx, p)
+		return err
+	})
+	return id, err
+}
+
+func (a aroundStorage) Get(ctx context.Context, number int) (Parcel, error) {
+	var p Parcel
+	err := a.around(ctx, storageCall{method: "Get", number: number}, func(ctx context.Context) error {
+		var err error
+		p, err = a.next.Get(ctx, number)
+		return err
+	})
+	return p, err
+}
+
+func (a aroundStorage) GetByClient(ctx context.Context, client int) ([]Parcel, error) {
+	var res []Parcel
+	err := a.around(ctx, storageCall{method: "GetByClient", client: client}, func(ctx context.Context) error {
+		var err error
+		res, err = a.next.GetByClient(ctx, client)
+		return err
+	})
+	return res, err
+}
+
+func (a aroundStorage) SetStatus(ctx context.Context, number int, status string) error {
+	return a.around(ctx, storageCall{method: "SetStatus", write: true, number: number}, func(ctx context.Context) error {
+		return a.next.SetStatus(ctx, number, status)
+	})
+}
+
+func (a aroundStorage) SetAddress(ctx context.Context, number int, address string) error {
+	return a.around(ctx, storageCall{method: "SetAddress", write: true, number: number}, func(ctx context.Context) error {
+		return a.next.SetAddress(ctx, number, address)
+	})
+}
+
+func (a aroundStorage) Delete(ctx context.Context, number int) error {
+	return a.around(ctx, storageCall{method: "Delete", write: true, number: number}, func(ctx context.Context) error {
+		return a.next.Delete(ctx, number)
+	})
+}
+
+// LoggingMiddleware записывает в logger результат и длительность каждого вызова
+func LoggingMiddleware(logger Logger) StoreMiddleware {
+	return aroundMiddleware(func(ctx context.Context, c storageCall, run func(context.Context) error) error {
+		start := time.Now()
+		err := run(ctx)
+		logResult(ctx, logger, "storage."+c.method, start, err, c.logArgs()...)
+		return err
+	})
+}
+
+// MetricsMiddleware учитывает длительность вызовов в метрике длительности запросов
+// хранилища. ParcelStore учитывает её сам, поэтому обёртка нужна другим реализациям.
+func MetricsMiddleware(m *Metrics) StoreMiddleware {
+	return aroundMiddleware(func(ctx context.Context, c storageCall, run func(context.Context) error) error {
+		defer m.observeQuery(c.method, time.Now())
+		return run(ctx)
+	})
+}
+
+// TracingMiddleware начинает спан ParcelStorage.<метод> на каждый вызов. Спан —
+// дочерний к спану из контекста вызова, а следующее хранилище получает контекст
+// с новым спаном, поэтому спаны ParcelStore вложены в него.
+func TracingMiddleware(tp trace.TracerProvider) StoreMiddleware {
+	tracer := tp.Tracer(tracerName)
+	return aroundMiddleware(func(ctx context.Context, c storageCall, run func(context.Context) error) error {
+		var attrs []attribute.KeyValue
+		if c.number != 0 {
+			attrs = append(attrs, attrNumber.Int(c.number))
+		}
+		if c.client != 0 {
+			attrs = append(attrs, attrClient.Int(c.client))
+		}
+		ctx, span := tracer.Start(ctx, "ParcelStorage."+c.method, trace.WithAttributes(attrs...))
+		defer span.End()
+		return spanError(span, run(ctx))
+	})
+}
+
+// RetryMiddleware повторяет изменяющие вызовы по политике p, пока БД заблокирована.
+// Чтения не повторяются. ParcelStore повторяет запись сам, а обёртка поверх него
+// повторяет вызов целиком, когда его собственные попытки кончились. Отмена ctx
+// прерывает ожидание следующей попытки.
+func RetryMiddleware(p RetryPolicy) StoreMiddleware {
+	attempts := max(p.MaxAttempts, 1)
+	return aroundMiddleware(func(ctx context.Context, c storageCall, run func(context.Context) error) error {
+		err := run(ctx)
+		if !c.write {
+			return err
+		}
+		for attempt := 1; attempt < attempts && isBusy(err); attempt++ {
+			t := time.NewTimer(p.delay(attempt - 1))
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return errors.Join(err, ctx.Err())
+			case <-t.C:
+			}
+			err = run(ctx)
+		}
+		return err
+	})
+}
+
+// CacheMiddleware кеширует результаты Get в c; SetStatus, SetAddress и Delete сбрасывают
+// запись посылки после вызова. Кеш общий для всех компаний: посылка не той компании,
+// что в контексте вызова, считается промахом.
+func CacheMiddleware(c ParcelCache) StoreMiddleware {
+	return func(next ParcelStorage) ParcelStorage {
+		return cachedStorage{ParcelStorage: next, cache: c}
+	}
+}
+
+// cachedStorage хранилище с кешем посылок по номеру
+type cachedStorage struct {
+	ParcelStorage
+	cache ParcelCache
+}
+
+func (s cachedStorage) Get(ctx context.Context, number int) (Parcel, error) {
+	tenant := TenantFromContext(ctx)
+	if p, ok := s.cache.Get(ctx, number); ok && (p.Tenant == tenant || tenant == anyTenant) {
+		return p, nil
+	}
+	p, err := s.ParcelStorage.Get(ctx, number)
+	if err != nil {
+		return p, err
+	}
+	s.cache.Set(ctx, p)
+	return p, nil
+}
+
+func (s cachedStorage) SetStatus(ctx context.Context, number int, status string) error {
+	defer s.cache.Invalidate(ctx, number)
+	return s.ParcelStorage.SetStatus(ctx, number, status)
+}
+
+func (s cachedStorage) SetAddress(ctx context.Context, number int, address string) error {
+	defer s.cache.Invalidate(ctx, number)
+	return s.ParcelStorage.SetAddress(ctx, number, address)
+}
+
+func (s cachedStorage) Delete(ctx context.Context, number int) error {
+	defer s.cache.Invalidate(ctx, number)
+	return s.ParcelStorage.Delete(ctx, number)
+}
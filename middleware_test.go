@@ -0,0 +1,130 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestWrap проверяет порядок обёрток и работу обёрток трекера поверх ParcelStore
+func TestWrap(t *testing.T) {
+	t.Parallel()
+
+	// order
+	var calls []string
+	tag := func(name string) StoreMiddleware {
+		return aroundMiddleware(func(ctx context.Context, c storageCall, run func(context.Context) error) error {
+			calls = append(calls, name+"."+c.method)
+			return run(ctx)
+		})
+	}
+	ctx := context.Background()
+	store := newTestStore(t)
+	wrapped := Wrap(store.Storage(), tag("outer"), tag("inner"))
+	id, err := wrapped.Add(ctx, getTestParcel())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"outer.Add", "inner.Add"}, calls)
+
+	// stack
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, "info", "text")
+	require.NoError(t, err)
+	metrics := NewMetrics()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	cache := NewLRUCache(10, 0)
+	wrapped = Wrap(store.Storage(),
+		LoggingMiddleware(logger),
+		MetricsMiddleware(metrics),
+		TracingMiddleware(tp),
+		RetryMiddleware(DefaultRetryPolicy),
+		CacheMiddleware(cache))
+
+	p, err := wrapped.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, id, p.Number)
+	cached, ok := cache.Get(context.Background(), id)
+	require.True(t, ok)
+	assert.Equal(t, p, cached)
+
+	// изменение сбрасывает запись кеша
+	require.NoError(t, wrapped.SetStatus(ctx, id, ParcelStatusSent))
+	_, ok = cache.Get(context.Background(), id)
+	assert.False(t, ok)
+	p, err = wrapped.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusSent, p.Status)
+
+	// посылка другой компании в общем кеше считается промахом
+	_, err = wrapped.Get(WithTenant(ctx, "other"), id)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// check
+	assert.Contains(t, buf.String(), "op=storage.SetStatus")
+	assert.Contains(t, buf.String(), "number="+strconv.Itoa(id))
+	// по серии на метод: Get и SetStatus
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.queryDuration))
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	assert.Equal(t, []string{"ParcelStorage.Get", "ParcelStorage.SetStatus", "ParcelStorage.Get", "ParcelStorage.Get"}, names)
+}
+
+// TestStorageContext проверяет, что обёртки передают контекст вызова дальше:
+// спаны ParcelStore вложены в спан обёртки, а отмена прерывает ожидание повтора
+func TestStorageContext(t *testing.T) {
+	t.Parallel()
+
+	// trace
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	store := newTestStore(t, WithStoreTracerProvider(tp))
+	wrapped := Wrap(store.Storage(), TracingMiddleware(tp))
+	_, err := wrapped.Add(context.Background(), getTestParcel())
+	require.NoError(t, err)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	require.Contains(t, spans, "ParcelStorage.Add")
+	require.Contains(t, spans, "ParcelStore.Add")
+	assert.Equal(t, spans["ParcelStorage.Add"].SpanContext().SpanID(), spans["ParcelStore.Add"].Parent().SpanID())
+
+	// retry
+	// БД заблокирована другой транзакцией, а без busy_timeout SQLite сразу возвращает SQLITE_BUSY
+	cfg := testConfig.DB
+	cfg.Path = filepath.Join(t.TempDir(), "busy.db")
+	cfg.SQLite.BusyTimeout = 0
+	locker, err := OpenDB(cfg)
+	require.NoError(t, err)
+	defer locker.Close()
+	require.NoError(t, Migrate(locker))
+	db, err := OpenDB(cfg)
+	require.NoError(t, err)
+	defer db.Close()
+	tx, err := locker.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	noRetry := NewParcelStore(db, WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	wrapped = Wrap(noRetry.Storage(), RetryMiddleware(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}))
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = wrapped.Add(ctx, getTestParcel())
+	assert.True(t, isBusy(err))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Minute)
+}
@@ -0,0 +1,691 @@
+package tracker
+
+import (
+	"database/sql"
+	"time"
+)
+
+// migration описывает одно изменение схемы БД
+type migration struct {
+	version int
+	name    string
+	query   string
+}
+
+// migrations список изменений схемы в порядке применения.
+// Уже применённые миграции менять нельзя, только добавлять новые в конец.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create parcel",
+		query: `CREATE TABLE IF NOT EXISTS parcel (
+			number     INTEGER CONSTRAINT parcel_pk PRIMARY KEY AUTOINCREMENT,
+			client     INTEGER      NOT NULL,
+			status     VARCHAR(128) NOT NULL,
+			address    VARCHAR(512) NOT NULL,
+			created_at TEXT         NOT NULL
+		)`,
+	},
+	{
+		version: 2,
+		name:    "create parcel_history",
+		query: `CREATE TABLE parcel_history (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			number     INTEGER      NOT NULL,
+			status     VARCHAR(128) NOT NULL,
+			changed_at TEXT         NOT NULL
+		);
+		CREATE INDEX parcel_history_number_idx ON parcel_history (number)`,
+	},
+	{
+		version: 3,
+		name:    "add parcel tracking_token",
+		query: `ALTER TABLE parcel ADD COLUMN tracking_token TEXT;
+		CREATE UNIQUE INDEX parcel_tracking_token_idx ON parcel (tracking_token)`,
+	},
+	{
+		version: 4,
+		name:    "create api_key",
+		query: `CREATE TABLE api_key (
+			key_hash   TEXT PRIMARY KEY,
+			client     INTEGER NOT NULL,
+			created_at TEXT    NOT NULL
+		)`,
+	},
+	{
+		version: 5,
+		name:    "create user_role",
+		query: `CREATE TABLE user_role (
+			subject INTEGER PRIMARY KEY,
+			role    TEXT NOT NULL
+		)`,
+	},
+	{
+		version: 6,
+		name:    "add parcel_history request_id",
+		query:   `ALTER TABLE parcel_history ADD COLUMN request_id TEXT`,
+	},
+	{
+		version: 7,
+		name:    "create outbox",
+		query: `CREATE TABLE outbox (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			type         TEXT    NOT NULL,
+			number       INTEGER NOT NULL,
+			payload      TEXT    NOT NULL,
+			created_at   TEXT    NOT NULL,
+			published_at TEXT
+		);
+		CREATE INDEX outbox_pending_idx ON outbox (id) WHERE published_at IS NULL`,
+	},
+	{
+		version: 8,
+		name:    "create scan_event",
+		query: `CREATE TABLE scan_event (
+			id          TEXT PRIMARY KEY,
+			number      INTEGER NOT NULL,
+			status      TEXT    NOT NULL,
+			received_at TEXT    NOT NULL
+		)`,
+	},
+	{
+		version: 9,
+		name:    "create webhook and webhook_delivery",
+		query: `CREATE TABLE webhook (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			client     INTEGER NOT NULL,
+			url        TEXT    NOT NULL,
+			secret     TEXT    NOT NULL,
+			created_at TEXT    NOT NULL
+		);
+		CREATE INDEX webhook_client_idx ON webhook (client);
+		CREATE TABLE webhook_delivery (
+			id              INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id      INTEGER NOT NULL,
+			event_id        INTEGER NOT NULL,
+			event_type      TEXT    NOT NULL,
+			payload         TEXT    NOT NULL,
+			state           TEXT    NOT NULL,
+			attempts        INTEGER NOT NULL DEFAULT 0,
+			last_error      TEXT    NOT NULL DEFAULT '',
+			next_attempt_at TEXT    NOT NULL,
+			created_at      TEXT    NOT NULL
+		);
+		CREATE INDEX webhook_delivery_due_idx ON webhook_delivery (next_attempt_at) WHERE state = 'pending'`,
+	},
+	{
+		version: 10,
+		name:    "create notification_preference",
+		query: `CREATE TABLE notification_preference (
+			client    INTEGER NOT NULL,
+			channel   TEXT    NOT NULL,
+			recipient TEXT    NOT NULL,
+			statuses  TEXT    NOT NULL,
+			PRIMARY KEY (client, channel)
+		)`,
+	},
+	{
+		version: 11,
+		name:    "create telegram_subscription and telegram_login",
+		query: `CREATE TABLE telegram_subscription (
+			chat_id INTEGER NOT NULL,
+			number  INTEGER NOT NULL,
+			PRIMARY KEY (chat_id, number)
+		);
+		CREATE INDEX telegram_subscription_number_idx ON telegram_subscription (number);
+		CREATE TABLE telegram_login (
+			chat_id  INTEGER PRIMARY KEY,
+			key_hash TEXT NOT NULL
+		)`,
+	},
+	{
+		version: 12,
+		name:    "create shipment",
+		query: `CREATE TABLE shipment (
+			number         INTEGER PRIMARY KEY,
+			carrier        TEXT NOT NULL,
+			external_id    TEXT NOT NULL,
+			carrier_status TEXT NOT NULL DEFAULT '',
+			created_at     TEXT NOT NULL,
+			synced_at      TEXT NOT NULL
+		);
+		CREATE INDEX shipment_synced_idx ON shipment (synced_at)`,
+	},
+	{
+		version: 13,
+		name:    "create parcel_search",
+		// полнотекстовый индекс посылок FTS5: rowid — номер посылки, индекс
+		// поддерживается триггерами. unicode61 не приравнивает «ё» к «е»,
+		// поэтому «ё» заменяется в адресе при индексации и в запросе при поиске.
+		query: `CREATE VIRTUAL TABLE parcel_search USING fts5(number, address, tracking_token, tokenize = 'unicode61');
+		INSERT INTO parcel_search (rowid, number, address, tracking_token)
+			SELECT number, number, replace(replace(address, 'ё', 'е'), 'Ё', 'Е'), COALESCE(tracking_token, '') FROM parcel;
+		CREATE TRIGGER parcel_search_insert AFTER INSERT ON parcel BEGIN
+			INSERT INTO parcel_search (rowid, number, address, tracking_token)
+				VALUES (new.number, new.number, replace(replace(new.address, 'ё', 'е'), 'Ё', 'Е'), COALESCE(new.tracking_token, ''));
+		END;
+		CREATE TRIGGER parcel_search_update AFTER UPDATE OF address, tracking_token ON parcel BEGIN
+			UPDATE parcel_search SET address = replace(replace(new.address, 'ё', 'е'), 'Ё', 'Е'),
+				tracking_token = COALESCE(new.tracking_token, '')
+				WHERE rowid = new.number;
+		END;
+		CREATE TRIGGER parcel_search_delete AFTER DELETE ON parcel BEGIN
+			DELETE FROM parcel_search WHERE rowid = old.number;
+		END`,
+	},
+	{
+		version: 14,
+		name:    "create parcel indexes",
+		// в индексах SQLite за ключом хранится rowid, то есть номер посылки,
+		// поэтому выборки по client и status с ORDER BY number обходятся без сортировки
+		query: `CREATE INDEX parcel_client_idx ON parcel (client);
+		CREATE INDEX parcel_status_idx ON parcel (status);
+		CREATE INDEX parcel_created_at_idx ON parcel (created_at)`,
+	},
+	{
+		version: 15,
+		name:    "create overdue_alert",
+		// последняя остановка посылки, о которой оповестили: статус и время его установки
+		query: `CREATE TABLE overdue_alert (
+			number     INTEGER PRIMARY KEY,
+			status     TEXT NOT NULL,
+			since      TEXT NOT NULL,
+			alerted_at TEXT NOT NULL
+		)`,
+	},
+	{
+		version: 16,
+		name:    "create parcel_archive and parcel_history_archive",
+		// давно доставленные посылки переносятся сюда, чтобы parcel и её индексы
+		// оставались небольшими
+		query: `CREATE TABLE parcel_archive (
+			number         INTEGER PRIMARY KEY,
+			client         INTEGER NOT NULL,
+			status         TEXT    NOT NULL,
+			address        TEXT    NOT NULL,
+			created_at     TEXT    NOT NULL,
+			tracking_token TEXT,
+			delivered_at   TEXT    NOT NULL,
+			archived_at    TEXT    NOT NULL
+		);
+		CREATE INDEX parcel_archive_client_idx ON parcel_archive (client);
+		CREATE TABLE parcel_history_archive (
+			id         INTEGER PRIMARY KEY,
+			number     INTEGER NOT NULL,
+			status     TEXT    NOT NULL,
+			changed_at TEXT    NOT NULL,
+			request_id TEXT
+		);
+		CREATE INDEX parcel_history_archive_number_idx ON parcel_history_archive (number)`,
+	},
+	{
+		version: 17,
+		name:    "create client_erasure",
+		// запись о том, что персональные данные клиента удалены; самих данных в ней нет
+		query: `CREATE TABLE client_erasure (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			client     INTEGER NOT NULL,
+			parcels    INTEGER NOT NULL,
+			erased_at  TEXT    NOT NULL,
+			request_id TEXT
+		)`,
+	},
+	{
+		version: 18,
+		name:    "add tenant_id",
+		// компания, которой принадлежат посылки, ключи, роли, вебхуки и получатели
+		// уведомлений; прежние данные относятся к компании default. Таблицы, ключ
+		// которых содержит идентификатор клиента, пересоздаются: клиенты разных
+		// компаний могут совпадать по номеру
+		query: `ALTER TABLE parcel ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+		ALTER TABLE parcel_archive ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+		ALTER TABLE api_key ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+		ALTER TABLE webhook ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+		ALTER TABLE client_erasure ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+		CREATE TABLE user_role_tenant (
+			tenant_id TEXT    NOT NULL DEFAULT 'default',
+			subject   INTEGER NOT NULL,
+			role      TEXT    NOT NULL,
+			PRIMARY KEY (tenant_id, subject)
+		);
+		INSERT INTO user_role_tenant (subject, role) SELECT subject, role FROM user_role;
+		DROP TABLE user_role;
+		ALTER TABLE user_role_tenant RENAME TO user_role;
+		CREATE TABLE notification_preference_tenant (
+			tenant_id TEXT    NOT NULL DEFAULT 'default',
+			client    INTEGER NOT NULL,
+			channel   TEXT    NOT NULL,
+			recipient TEXT    NOT NULL,
+			statuses  TEXT    NOT NULL,
+			PRIMARY KEY (tenant_id, client, channel)
+		);
+		INSERT INTO notification_preference_tenant (client, channel, recipient, statuses)
+			SELECT client, channel, recipient, statuses FROM notification_preference;
+		DROP TABLE notification_preference;
+		ALTER TABLE notification_preference_tenant RENAME TO notification_preference`,
+	},
+	{
+		version: 19,
+		name:    "add parcel.delivered_at",
+		// время первой доставки: повторная отметка delivered его не меняет. Для уже
+		// доставленных посылок берётся из истории, а без неё — время регистрации
+		query: `ALTER TABLE parcel ADD COLUMN delivered_at TEXT;
+		UPDATE parcel SET delivered_at = COALESCE(
+			(SELECT MIN(h.changed_at) FROM parcel_history h WHERE h.number = parcel.number AND h.status = 'delivered'),
+			created_at)
+		WHERE status = 'delivered'`,
+	},
+	{
+		version: 20,
+		name:    "add parcel.version",
+		// версия для оптимистичной блокировки: каждое изменение статуса или адреса
+		// увеличивает её, а изменение с устаревшей версией отклоняется
+		query: `ALTER TABLE parcel ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+	},
+	{
+		version: 21,
+		name:    "create status_rollback",
+		// откаты ошибочных смен статуса: кто, когда и почему вернул прежний статус.
+		// Откаченная запись истории удаляется, а здесь остаётся
+		query: `CREATE TABLE status_rollback (
+			id             INTEGER PRIMARY KEY AUTOINCREMENT,
+			number         INTEGER NOT NULL,
+			from_status    TEXT    NOT NULL,
+			to_status      TEXT    NOT NULL,
+			changed_at     TEXT    NOT NULL,
+			reason         TEXT    NOT NULL,
+			actor_client   INTEGER,
+			actor_role     TEXT,
+			rolled_back_at TEXT    NOT NULL,
+			request_id     TEXT,
+			tenant_id      TEXT    NOT NULL DEFAULT 'default'
+		);
+		CREATE INDEX status_rollback_number_idx ON status_rollback (number)`,
+	},
+	{
+		version: 22,
+		name:    "create parcel_link",
+		// связи посылок после перепаковки: merge — child едет в составе parent,
+		// split — child выделена из parent
+		query: `CREATE TABLE parcel_link (
+			parent     INTEGER NOT NULL,
+			child      INTEGER NOT NULL,
+			kind       TEXT    NOT NULL,
+			linked_at  TEXT    NOT NULL,
+			request_id TEXT,
+			PRIMARY KEY (parent, child)
+		);
+		CREATE INDEX parcel_link_child_idx ON parcel_link (child)`,
+	},
+	{
+		version: 23,
+		name:    "add parcel.eta",
+		// ожидаемое время доставки по средним срокам доставленных посылок
+		query: `ALTER TABLE parcel ADD COLUMN eta TEXT`,
+	},
+	{
+		version: 24,
+		name:    "create tariff",
+		// тарифы доставки компаний по зонам и срочности, цены в копейках; у посылки
+		// хранятся срочность, вес и стоимость, посчитанные при регистрации
+		query: `CREATE TABLE tariff (
+			tenant_id TEXT    NOT NULL,
+			zone      TEXT    NOT NULL,
+			priority  TEXT    NOT NULL,
+			base      INTEGER NOT NULL,
+			per_kg    INTEGER NOT NULL,
+			PRIMARY KEY (tenant_id, zone, priority)
+		);
+		ALTER TABLE parcel ADD COLUMN priority TEXT NOT NULL DEFAULT 'standard';
+		ALTER TABLE parcel ADD COLUMN weight INTEGER;
+		ALTER TABLE parcel ADD COLUMN price INTEGER`,
+	},
+	{
+		version: 25,
+		name:    "create cod",
+		// наложенные платежи: сумма, кто доставил посылку и кто и когда принял
+		// платёж в кассу. Индекс — для отчёта о неполученных платежах
+		query: `CREATE TABLE cod (
+			number         INTEGER PRIMARY KEY,
+			tenant_id      TEXT    NOT NULL,
+			amount         INTEGER NOT NULL,
+			currency       TEXT    NOT NULL,
+			delivered_at   TEXT,
+			courier        INTEGER,
+			collected_at   TEXT,
+			collected_by   INTEGER,
+			collected_role TEXT,
+			request_id     TEXT
+		);
+		CREATE INDEX cod_uncollected_idx ON cod (tenant_id, courier) WHERE collected_at IS NULL`,
+	},
+	{
+		version: 26,
+		name:    "create invoice",
+		// счета клиентам за месяц со строками по доставленным посылкам; в архив
+		// переносятся срочность, вес и стоимость, чтобы архивные посылки попадали в счёт
+		query: `CREATE TABLE invoice (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			tenant_id  TEXT    NOT NULL,
+			client     INTEGER NOT NULL,
+			month      TEXT    NOT NULL,
+			total      INTEGER NOT NULL,
+			created_at TEXT    NOT NULL,
+			request_id TEXT,
+			UNIQUE (tenant_id, client, month)
+		);
+		CREATE TABLE invoice_line (
+			invoice_id   INTEGER NOT NULL,
+			number       INTEGER NOT NULL,
+			delivered_at TEXT    NOT NULL,
+			priority     TEXT    NOT NULL,
+			weight       INTEGER NOT NULL,
+			price        INTEGER NOT NULL,
+			PRIMARY KEY (invoice_id, number)
+		);
+		CREATE INDEX parcel_client_delivered_idx ON parcel (client, delivered_at);
+		ALTER TABLE parcel_archive ADD COLUMN priority TEXT NOT NULL DEFAULT 'standard';
+		ALTER TABLE parcel_archive ADD COLUMN weight INTEGER;
+		ALTER TABLE parcel_archive ADD COLUMN price INTEGER`,
+	},
+	{
+		version: 27,
+		name:    "create route",
+		// маршруты курьеров на день и посылки в них в порядке доставки
+		query: `CREATE TABLE route (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			tenant_id    TEXT    NOT NULL,
+			courier      INTEGER NOT NULL,
+			day          TEXT    NOT NULL,
+			status       TEXT    NOT NULL,
+			created_at   TEXT    NOT NULL,
+			started_at   TEXT,
+			completed_at TEXT,
+			request_id   TEXT
+		);
+		CREATE INDEX route_courier_day_idx ON route (courier, day);
+		CREATE TABLE route_stop (
+			route_id INTEGER NOT NULL,
+			position INTEGER NOT NULL,
+			number   INTEGER NOT NULL,
+			PRIMARY KEY (route_id, number)
+		);
+		CREATE INDEX route_stop_number_idx ON route_stop (number)`,
+	},
+	{
+		version: 28,
+		name:    "create courier location",
+		// положения, которые сообщают курьеры; последнее по id считается текущим
+		query: `CREATE TABLE courier_location (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			tenant_id   TEXT    NOT NULL,
+			courier     INTEGER NOT NULL,
+			lat         REAL    NOT NULL,
+			lon         REAL    NOT NULL,
+			reported_at TEXT    NOT NULL
+		);
+		CREATE INDEX courier_location_courier_idx ON courier_location (tenant_id, courier, id)`,
+	},
+	{
+		version: 29,
+		name:    "create parcel geo",
+		// координаты адресов доставки от геокодера
+		query: `CREATE TABLE parcel_geo (
+			number      INTEGER PRIMARY KEY,
+			tenant_id   TEXT    NOT NULL,
+			lat         REAL    NOT NULL,
+			lon         REAL    NOT NULL,
+			geocoded_at TEXT    NOT NULL
+		)`,
+	},
+	{
+		version: 30,
+		name:    "create zone",
+		// зоны доставки по диапазонам почтовых индексов и зона каждой посылки
+		query: `CREATE TABLE zone (
+			tenant_id   TEXT NOT NULL,
+			name        TEXT NOT NULL,
+			postal_from TEXT NOT NULL,
+			postal_to   TEXT NOT NULL,
+			PRIMARY KEY (tenant_id, postal_from)
+		);
+		ALTER TABLE parcel ADD COLUMN zone TEXT;
+		CREATE INDEX parcel_zone_idx ON parcel (zone);
+		ALTER TABLE parcel_archive ADD COLUMN zone TEXT`,
+	},
+	{
+		version: 31,
+		name:    "create sla",
+		// обещанный срок доставки по тарифу и срок каждой посылки
+		query: `ALTER TABLE tariff ADD COLUMN days INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE parcel ADD COLUMN deadline TEXT;
+		CREATE INDEX parcel_deadline_idx ON parcel (deadline);
+		ALTER TABLE parcel_archive ADD COLUMN deadline TEXT`,
+	},
+	{
+		version: 32,
+		name:    "add notification_preference locale",
+		// язык уведомлений получателя; прежние получатели уведомляются по-русски, как раньше
+		query: `ALTER TABLE notification_preference ADD COLUMN locale TEXT NOT NULL DEFAULT 'ru'`,
+	},
+	{
+		version: 33,
+		name:    "create customs",
+		// таможенная декларация международной посылки; у внутренних посылок её нет
+		query: `CREATE TABLE customs (
+			number         INTEGER PRIMARY KEY,
+			tenant_id      TEXT NOT NULL,
+			hs_code        TEXT NOT NULL,
+			contents       TEXT NOT NULL,
+			origin_country TEXT NOT NULL,
+			status         TEXT NOT NULL,
+			updated_at     TEXT NOT NULL
+		)`,
+	},
+	{
+		version: 34,
+		name:    "create attachment",
+		// документы посылок; само содержимое лежит в каталоге или бакете под именем blob_key
+		query: `CREATE TABLE attachment (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			number       INTEGER NOT NULL,
+			tenant_id    TEXT    NOT NULL,
+			kind         TEXT    NOT NULL,
+			name         TEXT    NOT NULL,
+			content_type TEXT    NOT NULL,
+			size         INTEGER NOT NULL,
+			sha256       TEXT    NOT NULL,
+			blob_key     TEXT    NOT NULL,
+			created_at   TEXT    NOT NULL,
+			uploaded_by  INTEGER,
+			request_id   TEXT
+		);
+		CREATE INDEX attachment_number_idx ON attachment (number)`,
+	},
+	{
+		version: 35,
+		name:    "create audit_log",
+		// журнал действий с посылками; запись делается в транзакции самого действия
+		query: `CREATE TABLE audit_log (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			action     TEXT    NOT NULL,
+			number     INTEGER NOT NULL,
+			client     INTEGER NOT NULL,
+			tenant_id  TEXT    NOT NULL,
+			request_id TEXT,
+			created_at TEXT    NOT NULL
+		);
+		CREATE INDEX audit_log_number_idx ON audit_log (number)`,
+	},
+	{
+		version: 36,
+		name:    "create change_log",
+		// журнал изменений таблицы parcel для ChangeWatcher; его пишут триггеры, поэтому
+		// в него попадают изменения любого процесса, открывшего БД. Перенос в архив
+		// удалением посылки не считается.
+		query: `CREATE TABLE change_log (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			type       TEXT    NOT NULL,
+			number     INTEGER NOT NULL,
+			tenant_id  TEXT    NOT NULL,
+			status     TEXT,
+			changed_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+		);
+		CREATE INDEX change_log_changed_at_idx ON change_log (changed_at);
+		CREATE TRIGGER parcel_added_log AFTER INSERT ON parcel BEGIN
+			INSERT INTO change_log (type, number, tenant_id) VALUES ('parcel.added', NEW.number, NEW.tenant_id);
+		END;
+		CREATE TRIGGER parcel_status_log AFTER UPDATE OF status ON parcel WHEN NEW.status IS NOT OLD.status BEGIN
+			INSERT INTO change_log (type, number, tenant_id, status) VALUES ('parcel.status_changed', NEW.number, NEW.tenant_id, NEW.status);
+		END;
+		CREATE TRIGGER parcel_address_log AFTER UPDATE OF address ON parcel WHEN NEW.address IS NOT OLD.address BEGIN
+			INSERT INTO change_log (type, number, tenant_id) VALUES ('parcel.address_changed', NEW.number, NEW.tenant_id);
+		END;
+		CREATE TRIGGER parcel_deleted_log AFTER DELETE ON parcel
+		WHEN NOT EXISTS (SELECT 1 FROM parcel_archive WHERE number = OLD.number) BEGIN
+			INSERT INTO change_log (type, number, tenant_id) VALUES ('parcel.deleted', OLD.number, OLD.tenant_id);
+		END`,
+	},
+	{
+		version: 37,
+		name:    "create parcel_archive_token_idx",
+		// публичное отслеживание находит архивные посылки по трекинг-токену
+		query: `CREATE INDEX parcel_archive_token_idx ON parcel_archive (tracking_token)`,
+	},
+	{
+		version: 38,
+		name:    "create daily_stats",
+		// дневные итоги по зонам для дашбордов; пересчитывает задача analytics.
+		// Сумма времени доставки хранится, чтобы среднее считалось за любой период.
+		query: `CREATE TABLE daily_stats (
+			tenant_id        TEXT    NOT NULL,
+			day              TEXT    NOT NULL,
+			zone             TEXT    NOT NULL,
+			created          INTEGER NOT NULL,
+			delivered        INTEGER NOT NULL,
+			delivery_seconds REAL    NOT NULL,
+			PRIMARY KEY (tenant_id, day, zone)
+		);
+		CREATE INDEX daily_stats_day_idx ON daily_stats (day)`,
+	},
+	{
+		version: 39,
+		name:    "create saved_search",
+		// сохранённые фильтры посылок; у каждого пользователя компании свои имена,
+		// период хранится в секундах и отсчитывается от момента выполнения
+		query: `CREATE TABLE saved_search (
+			id           INTEGER PRIMARY KEY AUTOINCREMENT,
+			tenant_id    TEXT    NOT NULL,
+			owner        INTEGER NOT NULL,
+			name         TEXT    NOT NULL,
+			shared       INTEGER NOT NULL DEFAULT 0,
+			status       TEXT    NOT NULL DEFAULT '',
+			zone         TEXT    NOT NULL DEFAULT '',
+			created_from TEXT    NOT NULL DEFAULT '',
+			created_to   TEXT    NOT NULL DEFAULT '',
+			period       INTEGER NOT NULL DEFAULT 0,
+			UNIQUE (tenant_id, owner, name)
+		);
+		CREATE INDEX saved_search_shared_idx ON saved_search (tenant_id, name) WHERE shared = 1`,
+	},
+}
+
+// Migrate применяет к БД все ещё не применённые миграции
+func Migrate(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		err := applyMigration(db, m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SchemaVersion возвращает номер последней применённой миграции
+func SchemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// MigrationState состояние одной миграции схемы
+type MigrationState struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	// AppliedAt время применения; пустое, если миграция ещё не применена
+	AppliedAt string `json:"applied_at,omitempty"`
+}
+
+// MigrationStatus возвращает состояние всех известных миграций в порядке применения
+func MigrationStatus(db *sql.DB) ([]MigrationState, error) {
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]string{}
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		err := rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	res := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		res = append(res, MigrationState{Version: m.version, Name: m.name, AppliedAt: applied[m.version]})
+	}
+	return res, nil
+}
+
+// applyMigration применяет миграцию и записывает её версию в одной транзакции
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(m.query)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (:version, :name, :applied_at)",
+		sql.Named("version", m.version),
+		sql.Named("name", m.name),
+		sql.Named("applied_at", time.Now().UTC().Format(time.RFC3339)))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,185 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// failoverConnector открывает соединения драйвера по текущему DSN из dsns: основному
+// или резервному. reconnect переключает его на следующий DSN и делает уже открытые
+// соединения устаревшими: пул закрывает их вместо повторного использования, и следующие
+// запросы идут через новые соединения без перезапуска сервиса.
+//
+// failoverConnector сам реализует driver.Driver, поэтому db.Driver() у открытой
+// через него БД возвращает его же, и монитор находит коннектор по *sql.DB.
+type failoverConnector struct {
+	driver driver.Driver
+	dsns   []string
+
+	mu sync.Mutex
+	// current индекс DSN, по которому открываются новые соединения
+	current int
+	// generation поколение соединений; reconnect начинает новое
+	generation int
+}
+
+func newFailoverConnector(drv driver.Driver, dsns []string) *failoverConnector {
+	return &failoverConnector{driver: drv, dsns: dsns}
+}
+
+func (c *failoverConnector) Connect(context.Context) (driver.Conn, error) {
+	c.mu.Lock()
+	dsn, generation := c.dsns[c.current], c.generation
+	c.mu.Unlock()
+
+	conn, err := c.driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cc, ok := conn.(contextConn)
+	if !ok {
+		// соединение без контекста переоткрывается только драйвером
+		return conn, nil
+	}
+	return failoverConn{contextConn: cc, connector: c, generation: generation}, nil
+}
+
+func (c *failoverConnector) Driver() driver.Driver {
+	return c
+}
+
+// Open открывает соединение по текущему DSN; name не используется
+func (c *failoverConnector) Open(string) (driver.Conn, error) {
+	return c.Connect(context.Background())
+}
+
+// reconnect начинает новое поколение соединений. Если резервных DSN нет, соединения
+// открываются заново по тому же DSN. Возвращает индекс DSN, на который переключился.
+func (c *failoverConnector) reconnect() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current = (c.current + 1) % len(c.dsns)
+	c.generation++
+	return c.current
+}
+
+// stale сообщает, что соединение поколения generation открыто до последнего reconnect
+func (c *failoverConnector) stale(generation int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return generation != c.generation
+}
+
+// failoverConn соединение, которое пул перестаёт использовать после reconnect
+type failoverConn struct {
+	contextConn
+	connector  *failoverConnector
+	generation int
+}
+
+// ResetSession вызывается пулом перед повторным использованием соединения
+func (c failoverConn) ResetSession(context.Context) error {
+	if c.connector.stale(c.generation) {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// IsValid вызывается пулом при возврате соединения
+func (c failoverConn) IsValid() bool {
+	return !c.connector.stale(c.generation)
+}
+
+// unwrapConn возвращает соединение драйвера под обёртками размыкателя и переподключения
+func unwrapConn(dc any) any {
+	for {
+		switch c := dc.(type) {
+		case breakerConn:
+			dc = c.contextConn
+		case failoverConn:
+			dc = c.contextConn
+		default:
+			return dc
+		}
+	}
+}
+
+// HealthMonitor в фоне проверяет, что БД отвечает, и пишет её доступность в метрики.
+// После failures неудачных проверок подряд соединения БД открываются заново, а если
+// задана резервная БД — уже к ней. Переподключение возможно только для БД, открытой
+// через OpenDB с включённым монитором; у остальных монитор лишь пишет метрики.
+type HealthMonitor struct {
+	db *sql.DB
+	// name имя БД в метриках и логах: primary, reader или код депо
+	name      string
+	connector *failoverConnector
+	interval  time.Duration
+	failures  int
+	metrics   *Metrics
+	logger    Logger
+
+	// streak сколько проверок подряд не удалось; меняется только в Check
+	streak int
+}
+
+// NewHealthMonitor создаёт монитор БД db с именем name по настройкам cfg
+func NewHealthMonitor(db *sql.DB, name string, cfg config.Monitor, metrics *Metrics, logger Logger) *HealthMonitor {
+	connector, _ := db.Driver().(*failoverConnector)
+	return &HealthMonitor{
+		db:        db,
+		name:      name,
+		connector: connector,
+		interval:  cfg.Interval,
+		failures:  max(cfg.Failures, 1),
+		metrics:   metrics,
+		logger:    logger,
+	}
+}
+
+// Run проверяет БД каждые interval, пока не отменён ctx
+func (m *HealthMonitor) Run(ctx context.Context) {
+	t := time.NewTicker(m.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// Check один раз проверяет, что БД отвечает, и при необходимости переподключается
+func (m *HealthMonitor) Check(ctx context.Context) error {
+	pingCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	err := m.db.PingContext(pingCtx)
+	if ctx.Err() != nil {
+		// проверку прервала остановка сервиса, а не БД
+		return err
+	}
+	m.metrics.dbPing(m.name, start, err)
+	if err == nil {
+		m.streak = 0
+		return nil
+	}
+
+	m.streak++
+	m.logger.Log(ctx, slog.LevelWarn, "БД не отвечает", "op", "monitor.Check", "db", m.name, "failures", m.streak, "error", err)
+	if m.streak < m.failures || m.connector == nil {
+		return err
+	}
+	m.streak = 0
+	dsn := m.connector.reconnect()
+	m.metrics.dbReconnect(m.name)
+	m.logger.Log(ctx, slog.LevelWarn, "соединения с БД открываются заново", "op", "monitor.Check", "db", m.name, "standby", dsn > 0)
+	return err
+}
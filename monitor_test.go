@@ -0,0 +1,67 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// TestHealthMonitor проверяет, что после неудачных проверок подряд монитор переключает
+// соединения на резервную БД, а уже открытые соединения пул больше не использует
+func TestHealthMonitor(t *testing.T) {
+	// prepare
+	// основная БД лежит в своём каталоге: без каталога её соединения не открываются
+	dir := t.TempDir()
+	primaryDir := filepath.Join(dir, "primary")
+	require.NoError(t, os.Mkdir(primaryDir, 0o755))
+	cfg := testConfig.DB
+	cfg.Path = filepath.Join(primaryDir, "tracker.db")
+	cfg.Monitor = config.Monitor{Interval: time.Hour, Failures: 2, Standby: filepath.Join(dir, "standby.db")}
+
+	standbyCfg := cfg
+	standbyCfg.Path, standbyCfg.Monitor = cfg.Monitor.Standby, config.Monitor{}
+	migrateTestDB(t, standbyCfg)
+	db := migrateTestDB(t, cfg)
+
+	ctx := context.Background()
+	metrics := NewMetrics()
+	monitor := NewHealthMonitor(db, "primary", cfg.Monitor, metrics, discardLogger{})
+	store := NewParcelStore(db)
+	id := mustAddParcel(t, store, getTestParcel()).Number
+	require.NoError(t, monitor.Check(ctx))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.dbUp.WithLabelValues("primary")))
+
+	// fail
+	require.NoError(t, os.Rename(primaryDir, primaryDir+".moved"))
+	db.SetMaxIdleConns(0)
+	assert.Error(t, monitor.Check(ctx))
+	assert.Zero(t, testutil.ToFloat64(metrics.dbUp.WithLabelValues("primary")))
+	assert.Zero(t, testutil.ToFloat64(metrics.dbReconnects.WithLabelValues("primary")))
+	assert.Error(t, monitor.Check(ctx))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.dbReconnects.WithLabelValues("primary")))
+
+	// standby
+	db.SetMaxIdleConns(2)
+	require.NoError(t, monitor.Check(ctx))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.dbUp.WithLabelValues("primary")))
+	_, err := store.Get(id)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	mustAddParcel(t, store, getTestParcel())
+
+	// reconnect
+	// соединение с резервной БД осталось в пуле, но после переподключения не используется
+	require.NoError(t, os.Rename(primaryDir+".moved", primaryDir))
+	monitor.connector.reconnect()
+	p, err := store.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, id, p.Number)
+}
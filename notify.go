@@ -0,0 +1,524 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// Каналы уведомлений получателей
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// defaultNotifyStatuses статусы, о которых уведомляется получатель, если он не выбрал свои
+var defaultNotifyStatuses = []string{ParcelStatusSent, ParcelStatusDelivered}
+
+// phonePattern номер телефона для SMS в международном формате
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// ErrInvalidPreference возвращается для настройки уведомлений с неизвестным каналом
+// или адресом получателя, не подходящим для канала
+var ErrInvalidPreference = errors.New("некорректная настройка уведомлений")
+
+const (
+	queryUpsertPreference = `INSERT INTO notification_preference (tenant_id, client, channel, recipient, statuses, locale)
+		VALUES (:tenant, :client, :channel, :recipient, :statuses, :locale)
+		ON CONFLICT (tenant_id, client, channel) DO UPDATE SET recipient = excluded.recipient, statuses = excluded.statuses, locale = excluded.locale`
+	queryPreferences = `SELECT client, channel, recipient, statuses, locale FROM notification_preference
+		WHERE client = :client AND tenant_id = :tenant ORDER BY channel`
+	queryDeletePreference = "DELETE FROM notification_preference WHERE client = :client AND channel = :channel AND tenant_id = :tenant"
+)
+
+// Transition смена статуса посылки, о которой уведомляется получатель
+type Transition struct {
+	From      string
+	To        string
+	ChangedAt string
+	// Overdue задан у оповещения о посылке, которая застряла в статусе To:
+	// насколько превышен срок
+	Overdue time.Duration
+}
+
+// Notifier отправляет получателю уведомление о смене статуса посылки по своему каналу.
+// recipient — адрес получателя в этом канале: email или номер телефона.
+type Notifier interface {
+	Notify(ctx context.Context, recipient string, p Parcel, t Transition) error
+}
+
+// NotificationPreference настройка уведомлений клиента в одном канале:
+// куда отправлять, о каких статусах и на каком языке
+type NotificationPreference struct {
+	Client    int      `json:"client"`
+	Channel   string   `json:"channel"`
+	Recipient string   `json:"recipient"`
+	Statuses  []string `json:"statuses"`
+	Locale    string   `json:"locale"`
+}
+
+// SetNotificationPreference сохраняет настройку уведомлений клиента в канале, заменяя прежнюю
+func (s ParcelStore) SetNotificationPreference(p NotificationPreference) error {
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return err
+	}
+	return s.withRetry("store.SetNotificationPreference", func(s ParcelStore) error {
+		_, err := s.exec(nil, queryUpsertPreference,
+			sql.Named("tenant", tenant),
+			sql.Named("client", p.Client),
+			sql.Named("channel", p.Channel),
+			sql.Named("recipient", p.Recipient),
+			sql.Named("statuses", strings.Join(p.Statuses, ",")),
+			sql.Named("locale", p.Locale))
+		return err
+	})
+}
+
+// NotificationPreferences возвращает настройки уведомлений клиента по всем каналам
+func (s ParcelStore) NotificationPreferences(client int) ([]NotificationPreference, error) {
+	st, err := s.stmt(nil, queryPreferences)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := st.Query(sql.Named("client", client), sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []NotificationPreference
+	for rows.Next() {
+		var p NotificationPreference
+		var statuses string
+		err := rows.Scan(&p.Client, &p.Channel, &p.Recipient, &statuses, &p.Locale)
+		if err != nil {
+			return nil, err
+		}
+		p.Statuses = strings.Split(statuses, ",")
+		res = append(res, p)
+	}
+	return res, rows.Err()
+}
+
+// DeleteNotificationPreference отключает уведомления клиента в канале.
+// Если настройки не было, возвращает sql.ErrNoRows.
+func (s ParcelStore) DeleteNotificationPreference(client int, channel string) error {
+	return s.withRetry("store.DeleteNotificationPreference", func(s ParcelStore) error {
+		res, err := s.exec(nil, queryDeletePreference, sql.Named("client", client), sql.Named("channel", channel),
+			sql.Named("tenant", s.tenant()))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// NotificationPublisher уведомляет получателей о смене статусов их посылок.
+// Как EventPublisher релея outbox он получает события parcel.status_changed
+// и отправляет уведомление в каждый канал, который клиент настроил для нового статуса.
+type NotificationPublisher struct {
+	store     ParcelStore
+	notifiers map[string]Notifier
+}
+
+// NewNotificationPublisher создаёт рассылку уведомлений по каналам notifiers;
+// настройки клиентов в каналах, которых нет в notifiers, пропускаются
+func NewNotificationPublisher(store ParcelStore, notifiers map[string]Notifier) *NotificationPublisher {
+	return &NotificationPublisher{store: store, notifiers: notifiers}
+}
+
+// Publish отправляет уведомления о смене статуса из события e.
+// Ошибка возвращается только при сбое БД: уведомление, которое не принял канал,
+// попадает в лог, чтобы недоступный шлюз не задерживал остальные события outbox.
+func (n *NotificationPublisher) Publish(ctx context.Context, e OutboxEntry) error {
+	if e.Type != EventParcelStatusChanged {
+		return nil
+	}
+	var event OutboxEvent
+	err := json.Unmarshal(e.Payload, &event)
+	if err != nil {
+		return err
+	}
+
+	// идентификаторы клиентов у компаний свои, поэтому настройки берутся у компании посылки
+	store := n.store.WithContext(WithTenant(ctx, event.Tenant))
+	prefs, err := store.NotificationPreferences(event.Client)
+	if err != nil {
+		return err
+	}
+	prefs = slices.DeleteFunc(prefs, func(p NotificationPreference) bool {
+		return n.notifiers[p.Channel] == nil || !slices.Contains(p.Statuses, event.Status)
+	})
+	if len(prefs) == 0 {
+		return nil
+	}
+
+	parcel, err := store.Get(event.Number)
+	if errors.Is(err, sql.ErrNoRows) {
+		// посылку удалили раньше, чем дошла очередь уведомления
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	t := Transition{From: event.PreviousStatus, To: event.Status, ChangedAt: event.OccurredAt}
+	for _, p := range prefs {
+		err := n.notifiers[p.Channel].Notify(WithLocale(ctx, p.Locale), p.Recipient, parcel, t)
+		if err != nil {
+			n.store.logger.Log(ctx, slog.LevelError, "не удалось отправить уведомление",
+				"op", "notify.Publish", "event", e.ID, "number", event.Number, "channel", p.Channel, "recipient", p.Recipient, "error", err)
+		}
+	}
+	return nil
+}
+
+// notificationText текст уведомления о смене статуса на языке locale; адреса в нём нет
+func notificationText(p Parcel, t Transition, locale string) string {
+	m := localeMessages(locale)
+	if t.Overdue > 0 {
+		return fmt.Sprintf(m.overdue, p.Number, t.To, t.ChangedAt, t.Overdue.Round(time.Minute))
+	}
+	switch t.To {
+	case ParcelStatusSent:
+		return fmt.Sprintf(m.sent, p.Number, p.TrackingToken)
+	case ParcelStatusDelivered:
+		return fmt.Sprintf(m.delivered, p.Number)
+	default:
+		return fmt.Sprintf(m.status, p.Number, StatusLabel(t.To, locale))
+	}
+}
+
+//go:embed templates/tracking_email.html
+var emailTemplates embed.FS
+
+var trackingEmailTemplate = template.Must(template.ParseFS(emailTemplates, "templates/tracking_email.html"))
+
+// SMTPNotifier отправляет уведомления по email через SMTP-сервер.
+// Если сервер поддерживает STARTTLS, соединение шифруется до передачи пароля и письма.
+type SMTPNotifier struct {
+	addr string
+	from string
+	auth smtp.Auth
+	// publicURL публичный адрес трекера; с ним в письмо добавляется HTML-версия
+	// со ссылкой для отслеживания и её QR-кодом
+	publicURL string
+	timeout   time.Duration
+}
+
+// NewSMTPNotifier создаёт отправку email по настройкам cfg; без username письма отправляются без авторизации
+func NewSMTPNotifier(cfg config.SMTP, publicURL string, timeout time.Duration) *SMTPNotifier {
+	n := &SMTPNotifier{addr: cfg.Addr, from: cfg.From, publicURL: publicURL, timeout: timeout}
+	if cfg.Username != "" {
+		host, _, _ := net.SplitHostPort(cfg.Addr)
+		n.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	return n
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, recipient string, p Parcel, t Transition) error {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	host, _, _ := net.SplitHostPort(n.addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		err = c.StartTLS(&tls.Config{ServerName: host})
+		if err != nil {
+			return err
+		}
+	}
+	if n.auth != nil {
+		err = c.Auth(n.auth)
+		if err != nil {
+			return err
+		}
+	}
+	err = c.Mail(n.from)
+	if err != nil {
+		return err
+	}
+	err = c.Rcpt(recipient)
+	if err != nil {
+		return err
+	}
+
+	msg, err := n.message(recipient, p, t, LocaleFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message собирает письмо с уведомлением. Без публичного адреса или трекинг-токена
+// письмо только текстовое; иначе это multipart/alternative из текста и HTML,
+// в который QR-код ссылки для отслеживания вложен картинкой (multipart/related).
+// Письмо пишется на языке locale.
+func (n *SMTPNotifier) message(recipient string, p Parcel, t Transition, locale string) ([]byte, error) {
+	m := localeMessages(locale)
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", recipient)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", fmt.Sprintf(m.subject, p.Number)))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+
+	text := notificationText(p, t, locale)
+	if n.publicURL == "" || p.TrackingToken == "" {
+		msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+		msg.WriteString(text)
+		msg.WriteString("\r\n")
+		return msg.Bytes(), nil
+	}
+
+	url := TrackingURL(n.publicURL, p.TrackingToken)
+	var qr bytes.Buffer
+	err := WriteCodePNG(&qr, CodeQR, url, 200, 200)
+	if err != nil {
+		return nil, err
+	}
+	const qrID = "qr@tracker"
+	var html bytes.Buffer
+	err = trackingEmailTemplate.Execute(&html, map[string]any{
+		"Lang": locale, "Subject": fmt.Sprintf(m.subject, p.Number), "Text": text,
+		"URL": url, "Track": m.track, "QR": qrID, "QRAlt": m.qr,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	alternative := multipart.NewWriter(&msg)
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", alternative.Boundary())
+	part, err := alternative.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(part, "%s\r\n%s\r\n", text, url)
+
+	var body bytes.Buffer
+	related := multipart.NewWriter(&body)
+	part, err = related.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	part.Write(html.Bytes())
+	part, err = related.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"image/png"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-ID":                {"<" + qrID + ">"},
+		"Content-Disposition":       {`inline; filename="qr.png"`},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// строки письма не длиннее 76 символов (RFC 2045)
+	encoded := base64.StdEncoding.EncodeToString(qr.Bytes())
+	for len(encoded) > 76 {
+		fmt.Fprintf(part, "%s\r\n", encoded[:76])
+		encoded = encoded[76:]
+	}
+	fmt.Fprintf(part, "%s\r\n", encoded)
+	err = related.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	part, err = alternative.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/related; boundary=" + related.Boundary()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	part.Write(body.Bytes())
+	err = alternative.Close()
+	if err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
+
+// SMSNotifier отправляет уведомления через HTTP-шлюз SMS: POST с JSON
+// {"to": "<номер>", "text": "<текст>"} и токеном в заголовке Authorization
+type SMSNotifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewSMSNotifier создаёт отправку SMS по настройкам cfg
+func NewSMSNotifier(cfg config.SMS, timeout time.Duration) *SMSNotifier {
+	return &SMSNotifier{url: cfg.URL, token: cfg.Token, client: &http.Client{Timeout: timeout}}
+}
+
+func (n *SMSNotifier) Notify(ctx context.Context, recipient string, p Parcel, t Transition) error {
+	body, err := json.Marshal(map[string]string{"to": recipient, "text": notificationText(p, t, LocaleFromContext(ctx))})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("шлюз SMS ответил %s", resp.Status)
+	}
+	return nil
+}
+
+// newNotifiers создаёт каналы уведомлений, заданные в настройках; publicURL
+// публичный адрес трекера для ссылок на отслеживание в письмах
+func newNotifiers(cfg config.Notify, publicURL string) map[string]Notifier {
+	notifiers := map[string]Notifier{}
+	if cfg.SMTP.Addr != "" {
+		notifiers[ChannelEmail] = NewSMTPNotifier(cfg.SMTP, publicURL, cfg.Timeout)
+	}
+	if cfg.SMS.URL != "" {
+		notifiers[ChannelSMS] = NewSMSNotifier(cfg.SMS, cfg.Timeout)
+	}
+	return notifiers
+}
+
+// SetNotificationPreference проверяет и сохраняет настройку уведомлений клиента.
+// Без статусов получатель уведомляется об отправке и доставке посылки, без языка — по-русски.
+func (s ParcelService) SetNotificationPreference(ctx context.Context, p NotificationPreference) (NotificationPreference, error) {
+	err := s.checkNotifications(ctx, p.Client)
+	if err != nil {
+		return NotificationPreference{}, err
+	}
+
+	switch p.Channel {
+	case ChannelEmail:
+		addr, err := mail.ParseAddress(p.Recipient)
+		if err != nil || addr.Name != "" {
+			return NotificationPreference{}, fmt.Errorf("%w: некорректный email", ErrInvalidPreference)
+		}
+	case ChannelSMS:
+		if !phonePattern.MatchString(p.Recipient) {
+			return NotificationPreference{}, fmt.Errorf("%w: номер телефона нужен в формате +79991234567", ErrInvalidPreference)
+		}
+	default:
+		return NotificationPreference{}, fmt.Errorf("%w: неизвестный канал %q", ErrInvalidPreference, p.Channel)
+	}
+
+	if len(p.Statuses) == 0 {
+		p.Statuses = slices.Clone(defaultNotifyStatuses)
+	}
+	for _, status := range p.Statuses {
+		if _, ok := statusRank[status]; !ok {
+			return NotificationPreference{}, ErrUnknownStatus
+		}
+	}
+	if p.Locale == "" {
+		p.Locale = defaultLocale
+	}
+	if !supportedLocale(p.Locale) {
+		return NotificationPreference{}, fmt.Errorf("%w: неизвестный язык %q", ErrInvalidPreference, p.Locale)
+	}
+
+	err = s.store.WithContext(ctx).SetNotificationPreference(p)
+	if err != nil {
+		return NotificationPreference{}, err
+	}
+	return p, nil
+}
+
+// NotificationPreferences возвращает настройки уведомлений клиента
+func (s ParcelService) NotificationPreferences(ctx context.Context, client int) ([]NotificationPreference, error) {
+	err := s.checkNotifications(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.WithContext(ctx).NotificationPreferences(client)
+}
+
+// DeleteNotificationPreference отключает уведомления клиента в канале
+func (s ParcelService) DeleteNotificationPreference(ctx context.Context, client int, channel string) error {
+	err := s.checkNotifications(ctx, client)
+	if err != nil {
+		return err
+	}
+	return s.store.WithContext(ctx).DeleteNotificationPreference(client, channel)
+}
+
+// checkNotifications проверяет доступ к настройкам уведомлений: клиент управляет только своими
+func (s ParcelService) checkNotifications(ctx context.Context, client int) error {
+	err := s.check(ctx, actionNotifications)
+	if err != nil {
+		return err
+	}
+	return authorizeOwner(ctx, client)
+}
@@ -0,0 +1,230 @@
+package tracker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image/png"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// recordingNotifier запоминает отправленные уведомления
+type recordingNotifier struct {
+	recipients  []string
+	transitions []Transition
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, recipient string, _ Parcel, t Transition) error {
+	n.recipients = append(n.recipients, recipient)
+	n.transitions = append(n.transitions, t)
+	return nil
+}
+
+// TestNotifications проверяет, что получатели уведомляются только о выбранных статусах
+// в настроенных каналах
+func TestNotifications(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	service := NewParcelService(store)
+	// записи, оставшиеся от других тестов, отправляем заранее
+	_, err := NewOutboxRelay(store, &recordingPublisher{}, 1_000_000).RelayOnce(context.Background())
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var sms []map[string]string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]string
+		json.NewDecoder(r.Body).Decode(&msg)
+		mu.Lock()
+		defer mu.Unlock()
+		sms = append(sms, msg)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+	}))
+	defer gateway.Close()
+
+	parcel := getTestParcel()
+	parcel.Client = randRange.Intn(10_000_000)
+	ctx := context.Background()
+
+	_, err = service.SetNotificationPreference(ctx, NotificationPreference{Client: parcel.Client, Channel: ChannelEmail, Recipient: "не почта"})
+	assert.ErrorIs(t, err, ErrInvalidPreference)
+	_, err = service.SetNotificationPreference(ctx, NotificationPreference{Client: parcel.Client, Channel: ChannelSMS, Recipient: "89991234567"})
+	assert.ErrorIs(t, err, ErrInvalidPreference)
+	_, err = service.SetNotificationPreference(ctx, NotificationPreference{Client: parcel.Client, Channel: ChannelEmail, Recipient: "user@example.com", Statuses: []string{"lost"}})
+	assert.ErrorIs(t, err, ErrUnknownStatus)
+	_, err = service.SetNotificationPreference(ctx, NotificationPreference{Client: parcel.Client, Channel: ChannelSMS, Recipient: "+79991234567", Locale: "de"})
+	assert.ErrorIs(t, err, ErrInvalidPreference)
+
+	_, err = service.SetNotificationPreference(ctx, NotificationPreference{Client: parcel.Client, Channel: ChannelSMS, Recipient: "+79991234567", Locale: LocaleEN})
+	require.NoError(t, err)
+	_, err = service.SetNotificationPreference(ctx, NotificationPreference{Client: parcel.Client, Channel: ChannelEmail, Recipient: "user@example.com", Statuses: []string{ParcelStatusDelivered}})
+	require.NoError(t, err)
+
+	id, err := store.Add(parcel)
+	require.NoError(t, err)
+	require.NoError(t, store.SetStatus(id, ParcelStatusSent))
+	require.NoError(t, store.SetStatus(id, ParcelStatusDelivered))
+
+	email := &recordingNotifier{}
+	notifiers := map[string]Notifier{
+		ChannelEmail: email,
+		ChannelSMS:   NewSMSNotifier(config.SMS{URL: gateway.URL, Token: "token"}, time.Second),
+	}
+
+	// notify
+	_, err = NewOutboxRelay(store, NewNotificationPublisher(store, notifiers), 10).RelayOnce(ctx)
+	require.NoError(t, err)
+
+	// check
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, sms, 2)
+	assert.Equal(t, "+79991234567", sms[0]["to"])
+	// SMS на выбранном получателем языке
+	assert.Contains(t, sms[0]["text"], "has been sent")
+	assert.Contains(t, sms[1]["text"], "has been delivered")
+
+	assert.Equal(t, []string{"user@example.com"}, email.recipients)
+	require.Len(t, email.transitions, 1)
+	assert.Equal(t, ParcelStatusSent, email.transitions[0].From)
+	assert.Equal(t, ParcelStatusDelivered, email.transitions[0].To)
+
+	prefs, err := service.NotificationPreferences(ctx, parcel.Client)
+	require.NoError(t, err)
+	require.Len(t, prefs, 2)
+	assert.Equal(t, defaultNotifyStatuses, prefs[1].Statuses)
+	assert.Equal(t, LocaleRU, prefs[0].Locale)
+	assert.Equal(t, LocaleEN, prefs[1].Locale)
+
+	require.NoError(t, service.DeleteNotificationPreference(ctx, parcel.Client, ChannelSMS))
+	assert.Error(t, service.DeleteNotificationPreference(ctx, parcel.Client, ChannelSMS))
+	require.NoError(t, service.DeleteNotificationPreference(ctx, parcel.Client, ChannelEmail))
+}
+
+// TestSMTPNotifier проверяет отправку письма на SMTP-сервер
+func TestSMTPNotifier(t *testing.T) {
+	// prepare
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	// SMTP-сервер, который принимает одно письмо без STARTTLS и авторизации
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var lines []string
+		r := bufio.NewReader(conn)
+		conn.Write([]byte("220 localhost\r\n"))
+		data := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch {
+			case data && line == ".":
+				data = false
+				conn.Write([]byte("250 OK\r\n"))
+			case data:
+				lines = append(lines, line)
+			case strings.HasPrefix(line, "DATA"):
+				data = true
+				conn.Write([]byte("354 go ahead\r\n"))
+			case strings.HasPrefix(line, "QUIT"):
+				conn.Write([]byte("221 bye\r\n"))
+				received <- lines
+				return
+			default:
+				lines = append(lines, line)
+				conn.Write([]byte("250 OK\r\n"))
+			}
+		}
+	}()
+
+	n := NewSMTPNotifier(config.SMTP{Addr: lis.Addr().String(), From: "tracker@example.com"}, "", time.Second)
+	p := Parcel{Number: 42, TrackingToken: "abc"}
+
+	// notify
+	err = n.Notify(context.Background(), "user@example.com", p, Transition{From: ParcelStatusRegistered, To: ParcelStatusSent})
+	require.NoError(t, err)
+
+	// check
+	lines := <-received
+	assert.Contains(t, lines, "MAIL FROM:<tracker@example.com>")
+	assert.Contains(t, lines, "RCPT TO:<user@example.com>")
+	assert.Contains(t, lines, "To: user@example.com")
+	assert.Contains(t, lines, "Посылка №42 отправлена. Отслеживать её можно по коду abc.")
+}
+
+// TestSMTPNotifierHTML проверяет письмо с HTML-версией и QR-кодом ссылки для отслеживания
+func TestSMTPNotifierHTML(t *testing.T) {
+	// prepare
+	n := NewSMTPNotifier(config.SMTP{From: "tracker@example.com"}, "https://track.example.com", time.Second)
+	p := Parcel{Number: 42, TrackingToken: "abc"}
+
+	// message
+	raw, err := n.message("user@example.com", p, Transition{From: ParcelStatusRegistered, To: ParcelStatusSent}, LocaleRU)
+	require.NoError(t, err)
+
+	// check
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	alternative := multipart.NewReader(msg.Body, params["boundary"])
+	part, err := alternative.NextPart()
+	require.NoError(t, err)
+	text, err := io.ReadAll(part)
+	require.NoError(t, err)
+	assert.Contains(t, string(text), "https://track.example.com/track/abc")
+
+	part, err = alternative.NextPart()
+	require.NoError(t, err)
+	mediaType, params, err = mime.ParseMediaType(part.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/related", mediaType)
+	related := multipart.NewReader(part, params["boundary"])
+	html, err := related.NextPart()
+	require.NoError(t, err)
+	body, err := io.ReadAll(html)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `<a href="https://track.example.com/track/abc">`)
+	assert.Contains(t, string(body), `src="cid:qr@tracker"`)
+
+	image, err := related.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "<qr@tracker>", image.Header.Get("Content-ID"))
+	decoded, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, image))
+	require.NoError(t, err)
+	_, err = png.Decode(bytes.NewReader(decoded))
+	require.NoError(t, err)
+}
@@ -0,0 +1,180 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+// Типы событий outbox
+const (
+	EventParcelAdded          = "parcel.added"
+	EventParcelStatusChanged  = "parcel.status_changed"
+	EventParcelAddressChanged = "parcel.address_changed"
+	EventParcelDeleted        = "parcel.deleted"
+	EventParcelMerged         = "parcel.merged"
+	EventParcelSplit          = "parcel.split"
+	EventParcelCODCollected   = "parcel.cod_collected"
+)
+
+// OutboxEvent содержимое записи outbox, которое получают внешние системы.
+// Адреса в событиях нет: это персональные данные.
+type OutboxEvent struct {
+	Type   string `json:"type"`
+	Number int    `json:"number"`
+	Client int    `json:"client,omitempty"`
+	Status string `json:"status,omitempty"`
+	// PreviousStatus статус до изменения; есть только в parcel.status_changed
+	PreviousStatus string `json:"previous_status,omitempty"`
+	// Parent посылка, в которую объединена эта (parcel.merged) или из которой она
+	// выделена (parcel.split)
+	Parent     int    `json:"parent,omitempty"`
+	OccurredAt string `json:"occurred_at"`
+	RequestID  string `json:"request_id,omitempty"`
+	// Tenant компания посылки; в событиях, записанных до появления компаний, его нет
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// OutboxEntry запись outbox: событие, записанное в одной транзакции с изменением.
+// ID растёт вместе с порядком изменений; по нему получатели отбрасывают повторы.
+type OutboxEntry struct {
+	ID        int64
+	Type      string
+	Number    int
+	Payload   []byte
+	CreatedAt string
+}
+
+// addOutbox записывает событие в outbox в транзакции изменения tx
+func (s ParcelStore) addOutbox(tx *sql.Tx, e OutboxEvent) error {
+	e.OccurredAt = time.Now().UTC().Format(time.RFC3339)
+	e.RequestID = RequestIDFromContext(s.ctx)
+	if e.Tenant == "" {
+		e.Tenant = s.tenant()
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.exec(tx, queryInsertOutbox,
+		sql.Named("type", e.Type),
+		sql.Named("number", e.Number),
+		sql.Named("payload", string(payload)),
+		sql.Named("created_at", e.OccurredAt))
+	return err
+}
+
+// PendingOutbox возвращает до limit ещё не опубликованных записей outbox в порядке записи
+func (s ParcelStore) PendingOutbox(limit int) ([]OutboxEntry, error) {
+	defer s.metrics.observeQuery("PendingOutbox", time.Now())
+
+	// outbox читается из основной БД: реплика может не знать о последних записях
+	st, err := s.stmt(nil, queryPendingOutbox)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := st.Query(sql.Named("limit", limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []OutboxEntry
+	for rows.Next() {
+		var e OutboxEntry
+		var payload string
+		err := rows.Scan(&e.ID, &e.Type, &e.Number, &payload, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		e.Payload = []byte(payload)
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
+
+// MarkOutboxPublished отмечает запись outbox опубликованной
+func (s ParcelStore) MarkOutboxPublished(id int64) error {
+	return s.withRetry("store.MarkOutboxPublished", func(s ParcelStore) error {
+		_, err := s.exec(nil, queryMarkOutboxPublished,
+			sql.Named("id", id),
+			sql.Named("published_at", time.Now().UTC().Format(time.RFC3339)))
+		return err
+	})
+}
+
+// EventPublisher отправляет события об изменениях посылок в шину сообщений.
+// Релей outbox вызывает его по одному событию в порядке изменений; Payload — OutboxEvent в JSON.
+type EventPublisher interface {
+	Publish(ctx context.Context, e OutboxEntry) error
+}
+
+// OutboxRelay переносит записи outbox в шину сообщений с доставкой «хотя бы один раз»:
+// запись отмечается опубликованной только после успешной отправки, поэтому после сбоя
+// между отправкой и отметкой она будет отправлена повторно.
+type OutboxRelay struct {
+	store     ParcelStore
+	publisher EventPublisher
+	batch     int
+}
+
+// NewOutboxRelay создаёт релей, который за один запуск отправляет до batch записей
+func NewOutboxRelay(store ParcelStore, publisher EventPublisher, batch int) *OutboxRelay {
+	return &OutboxRelay{store: store, publisher: publisher, batch: batch}
+}
+
+// Relay отправляет одну порцию записей; это задача планировщика outbox
+func (r *OutboxRelay) Relay(ctx context.Context) error {
+	_, err := r.RelayOnce(ctx)
+	return err
+}
+
+// RelayOnce отправляет одну порцию записей и возвращает число отправленных.
+// На первой ошибке отправка останавливается, чтобы события не обгоняли друг друга.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	store := r.store.WithContext(ctx)
+	entries, err := store.PendingOutbox(r.batch)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, e := range entries {
+		err := r.publisher.Publish(ctx, e)
+		if err != nil {
+			return i, err
+		}
+		err = store.MarkOutboxPublished(e.ID)
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(entries), nil
+}
+
+// fanoutPublisher отправляет событие всем получателям по очереди.
+// Если один из них отказал, релей повторит событие для всех, поэтому
+// получатели должны отбрасывать повторы по ID.
+type fanoutPublisher []EventPublisher
+
+func (f fanoutPublisher) Publish(ctx context.Context, e OutboxEntry) error {
+	for _, p := range f {
+		err := p.Publish(ctx, e)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogPublisher «шина», которая пишет события outbox в лог; пригодна для отладки
+type LogPublisher struct {
+	Logger Logger
+}
+
+func (p LogPublisher) Publish(ctx context.Context, e OutboxEntry) error {
+	p.Logger.Log(ctx, slog.LevelInfo, "событие outbox", "id", e.ID, "type", e.Type, "number", e.Number, "payload", string(e.Payload))
+	return nil
+}
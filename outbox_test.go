@@ -0,0 +1,72 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPublisher запоминает отправленные записи и отказывает, пока задана ошибка
+type recordingPublisher struct {
+	entries []OutboxEntry
+	err     error
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, e OutboxEntry) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.entries = append(p.entries, e)
+	return nil
+}
+
+// TestOutbox проверяет, что изменения попадают в outbox, а релей отправляет их
+// по порядку и повторяет после сбоя шины
+func TestOutbox(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	// записи, оставшиеся от других тестов, отправляем заранее
+	_, err := NewOutboxRelay(store, &recordingPublisher{}, 1_000_000).RelayOnce(context.Background())
+	require.NoError(t, err)
+
+	parcel := getTestParcel()
+	id, err := store.Add(parcel)
+	require.NoError(t, err)
+	require.NoError(t, store.SetAddress(id, "новый адрес"))
+	require.NoError(t, store.SetStatus(id, ParcelStatusSent))
+
+	publisher := &recordingPublisher{err: errors.New("шина недоступна")}
+	relay := NewOutboxRelay(store, publisher, 10)
+
+	// relay
+	n, err := relay.RelayOnce(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, 0, n)
+
+	publisher.err = nil
+	n, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+
+	// check
+	require.Equal(t, 3, n)
+	types := []string{publisher.entries[0].Type, publisher.entries[1].Type, publisher.entries[2].Type}
+	assert.Equal(t, []string{EventParcelAdded, EventParcelAddressChanged, EventParcelStatusChanged}, types)
+
+	var event OutboxEvent
+	require.NoError(t, json.Unmarshal(publisher.entries[2].Payload, &event))
+	assert.Equal(t, id, event.Number)
+	assert.Equal(t, parcel.Client, event.Client)
+	assert.Equal(t, ParcelStatusSent, event.Status)
+
+	pending, err := store.PendingOutbox(10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
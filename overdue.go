@@ -0,0 +1,216 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+const (
+	// queryOverdue посылки, которые дольше срока остаются в статусе registered или sent.
+	// Статус получен последней записью истории, а без истории — при регистрации.
+	// Пустая граница before не пропускает ни одну посылку: срок для статуса не задан.
+	queryOverdue = `SELECT ` + parcelColumns + `, since,
+			EXISTS (SELECT 1 FROM overdue_alert a WHERE a.number = o.number AND a.status = o.status AND a.since = o.since)
+		FROM (SELECT p.*, COALESCE((SELECT MAX(h.changed_at) FROM parcel_history h WHERE h.number = p.number), p.created_at) AS since
+			FROM parcel p WHERE p.status IN ('registered', 'sent') AND (:client = 0 OR p.client = :client) AND ` + tenantCond + `) o
+		WHERE (status = 'registered' AND since < :registered_before) OR (status = 'sent' AND since < :sent_before)
+		ORDER BY since, number LIMIT :limit`
+	queryMarkOverdueAlerted = `INSERT INTO overdue_alert (number, status, since, alerted_at) VALUES (:number, :status, :since, :alerted_at)
+		ON CONFLICT (number) DO UPDATE SET status = excluded.status, since = excluded.since, alerted_at = excluded.alerted_at`
+)
+
+// OverdueSLA сколько посылка может оставаться в статусе; 0 — статус не проверяется
+type OverdueSLA struct {
+	Registered time.Duration
+	Sent       time.Duration
+}
+
+// overdueSLA переводит сроки из настроек
+func overdueSLA(cfg config.Overdue) OverdueSLA {
+	return OverdueSLA{Registered: cfg.Registered, Sent: cfg.Sent}
+}
+
+// limit возвращает срок для статуса status
+func (sla OverdueSLA) limit(status string) time.Duration {
+	switch status {
+	case ParcelStatusRegistered:
+		return sla.Registered
+	case ParcelStatusSent:
+		return sla.Sent
+	default:
+		return 0
+	}
+}
+
+// OverdueParcel посылка, застрявшая в статусе
+type OverdueParcel struct {
+	Parcel
+	// Since когда посылка получила текущий статус, в RFC 3339
+	Since string
+	// Overdue насколько превышен срок
+	Overdue time.Duration
+	// Alerted отправлено ли уже оповещение о том, что посылка застряла в этом статусе
+	Alerted bool
+}
+
+// ListOverdue возвращает посылки, которые на момент now дольше сроков sla остаются
+// в статусе registered или sent, начиная с самых давних. Учитываются фильтры
+// opts.Client и opts.Limit; без Limit возвращаются все такие посылки.
+func (s ParcelStore) ListOverdue(sla OverdueSLA, now time.Time, opts ListOptions) ([]OverdueParcel, error) {
+	defer s.metrics.observeQuery("ListOverdue", time.Now())
+	span := s.startSpan("ListOverdue", attrClient.Int(opts.Client))
+	defer span.End()
+
+	before := func(limit time.Duration) string {
+		if limit <= 0 {
+			return ""
+		}
+		// history и created_at хранятся в RFC 3339 UTC, поэтому строки сравниваются в порядке времени
+		return now.Add(-limit).UTC().Format(time.RFC3339)
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		// в SQLite отрицательный LIMIT снимает ограничение
+		limit = -1
+	}
+	rows, err := s.readQuery(queryOverdue,
+		sql.Named("client", opts.Client),
+		sql.Named("tenant", s.tenant()),
+		sql.Named("registered_before", before(sla.Registered)),
+		sql.Named("sent_before", before(sla.Sent)),
+		sql.Named("limit", limit))
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer rows.Close()
+
+	var res []OverdueParcel
+	for rows.Next() {
+		var p OverdueParcel
+		err := rows.Scan(&p.Number, &p.Client, &p.Status, &p.Address, &p.CreatedAt, &p.TrackingToken, &p.Tenant, &p.DeliveredAt, &p.Version, &p.ETA, &p.Priority, &p.Weight, &p.Price, &p.Zone, &p.Deadline, &p.Since, &p.Alerted)
+		if err == nil {
+			p.Address, err = s.openAddress(p.Address)
+		}
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		since, err := time.Parse(time.RFC3339, p.Since)
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		p.Overdue = now.Sub(since) - sla.limit(p.Status)
+		res = append(res, p)
+	}
+	return res, spanError(span, rows.Err())
+}
+
+// MarkOverdueAlerted запоминает, что об остановке посылки в статусе status с момента since
+// оповестили; при следующей остановке в другом статусе оповещение отправится снова
+func (s ParcelStore) MarkOverdueAlerted(number int, status, since string) error {
+	start := time.Now()
+	span := s.startSpan("MarkOverdueAlerted", attrNumber.Int(number))
+	defer span.End()
+
+	err := s.withRetry("store.MarkOverdueAlerted", func(s ParcelStore) error {
+		_, err := s.exec(nil, queryMarkOverdueAlerted,
+			sql.Named("number", number),
+			sql.Named("status", status),
+			sql.Named("since", since),
+			sql.Named("alerted_at", time.Now().UTC().Format(time.RFC3339)))
+		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("MarkOverdueAlerted", start)
+	logResult(s.ctx, s.logger, "store.MarkOverdueAlerted", start, err, "number", number, "status", status)
+	return err
+}
+
+// WithOverdueSLA задаёт сроки, по которым ListOverdue находит застрявшие посылки
+func WithOverdueSLA(sla OverdueSLA) ServiceOption {
+	return func(s *ParcelService) {
+		s.overdue = sla
+	}
+}
+
+// ListOverdue возвращает застрявшие посылки для панелей мониторинга; клиент видит только свои
+func (s ParcelService) ListOverdue(ctx context.Context, opts ListOptions) ([]OverdueParcel, error) {
+	err := s.check(ctx, actionRead)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := CallerFromContext(ctx); ok && c.Role == RoleClient {
+		err = authorizeOwner(ctx, opts.Client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.store.WithContext(ctx).ListOverdue(s.overdue, time.Now(), opts)
+}
+
+// OverdueAlerts находит застрявшие посылки, обновляет метрику parcels_overdue
+// и оповещает о каждой остановке посылки один раз
+type OverdueAlerts struct {
+	store      ParcelStore
+	sla        OverdueSLA
+	notifiers  map[string]Notifier
+	recipients []config.Alert
+	metrics    *Metrics
+	// now текущее время; подменяется в тестах
+	now func() time.Time
+}
+
+// NewOverdueAlerts создаёт проверку застрявших посылок; оповещения уходят получателям
+// recipients через каналы notifiers, получатели в каналах без notifier пропускаются
+func NewOverdueAlerts(store ParcelStore, sla OverdueSLA, notifiers map[string]Notifier, recipients []config.Alert, metrics *Metrics) *OverdueAlerts {
+	return &OverdueAlerts{store: store, sla: sla, notifiers: notifiers, recipients: recipients, metrics: metrics, now: time.Now}
+}
+
+// Check проверяет посылки один раз; это задача планировщика overdue. Если оповестить
+// не удалось ни одного получателя, посылка не отмечается и попадёт в следующую проверку.
+func (o *OverdueAlerts) Check(ctx context.Context) error {
+	store := o.store.WithContext(ctx)
+	parcels, err := store.ListOverdue(o.sla, o.now(), ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]int{ParcelStatusRegistered: 0, ParcelStatusSent: 0}
+	var errs []error
+	for _, p := range parcels {
+		counts[p.Status]++
+		if p.Alerted {
+			continue
+		}
+		// без получателей оповещение — только метрика overdue_alerts_total
+		sent, attempted := false, false
+		t := Transition{From: p.Status, To: p.Status, ChangedAt: p.Since, Overdue: p.Overdue}
+		for _, r := range o.recipients {
+			n := o.notifiers[r.Channel]
+			if n == nil {
+				continue
+			}
+			attempted = true
+			err := n.Notify(ctx, r.Recipient, p.Parcel, t)
+			if err != nil {
+				o.store.logger.Log(ctx, slog.LevelError, "не удалось оповестить о застрявшей посылке",
+					"op", "overdue.Check", "number", p.Number, "channel", r.Channel, "recipient", r.Recipient, "error", err)
+				continue
+			}
+			sent = true
+		}
+		if sent || !attempted {
+			o.metrics.overdueAlert(p.Status)
+			errs = append(errs, store.MarkOverdueAlerted(p.Number, p.Status, p.Since))
+		}
+	}
+	for status, n := range counts {
+		o.metrics.setOverdue(status, n)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,135 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/api"
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// overdueNotifier запоминает посылки из оповещений
+type overdueNotifier struct {
+	numbers []int
+	texts   []string
+}
+
+func (n *overdueNotifier) Notify(_ context.Context, _ string, p Parcel, t Transition) error {
+	n.numbers = append(n.numbers, p.Number)
+	n.texts = append(n.texts, notificationText(p, t, defaultLocale))
+	return nil
+}
+
+// TestListOverdue проверяет поиск застрявших посылок, оповещения о них и запрос для панелей
+func TestListOverdue(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	client := randRange.Intn(10_000_000) + 1
+	add := func(createdAt string) int {
+		t.Helper()
+		id, err := store.Add(Parcel{Client: client, Status: ParcelStatusRegistered, Address: "test", CreatedAt: createdAt})
+		require.NoError(t, err)
+		return id
+	}
+	now := time.Now().UTC()
+	// зарегистрирована 5 дней назад
+	registered := add(now.AddDate(0, 0, -5).Format(time.RFC3339))
+	// зарегистрирована давно, но отправлена только что
+	sent := add("2001-01-01T00:00:00Z")
+	require.NoError(t, store.SetStatus(sent, ParcelStatusSent))
+	fresh := add(now.Add(-time.Minute).Format(time.RFC3339))
+	delivered := add("2001-01-01T00:00:00Z")
+	require.NoError(t, store.SetStatus(delivered, ParcelStatusDelivered))
+	sla := OverdueSLA{Registered: 72 * time.Hour, Sent: 14 * 24 * time.Hour}
+	numbers := func(parcels []OverdueParcel) []int {
+		var res []int
+		for _, p := range parcels {
+			res = append(res, p.Number)
+		}
+		return res
+	}
+
+	// list
+	parcels, err := store.ListOverdue(sla, now, ListOptions{Client: client})
+	require.NoError(t, err)
+
+	// check
+	require.Equal(t, []int{registered}, numbers(parcels))
+	assert.Equal(t, 48*time.Hour, parcels[0].Overdue.Round(time.Hour))
+	assert.False(t, parcels[0].Alerted)
+
+	// через 15 дней застряли все, кроме доставленной; самые давние первыми
+	later := now.AddDate(0, 0, 15)
+	parcels, err = store.ListOverdue(sla, later, ListOptions{Client: client})
+	require.NoError(t, err)
+	assert.Equal(t, []int{registered, fresh, sent}, numbers(parcels))
+	parcels, err = store.ListOverdue(sla, later, ListOptions{Client: client, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []int{registered}, numbers(parcels))
+	// без срока статус не проверяется
+	parcels, err = store.ListOverdue(OverdueSLA{Sent: sla.Sent}, later, ListOptions{Client: client})
+	require.NoError(t, err)
+	assert.Equal(t, []int{sent}, numbers(parcels))
+
+	// alerts
+	notifier := &overdueNotifier{}
+	metrics := NewMetrics()
+	alerts := NewOverdueAlerts(store, sla, map[string]Notifier{ChannelEmail: notifier},
+		[]config.Alert{{Channel: ChannelEmail, Recipient: "ops@example.com"}, {Channel: ChannelSMS, Recipient: "+79990000000"}}, metrics)
+	alerts.now = func() time.Time { return now }
+	require.NoError(t, alerts.Check(context.Background()))
+	require.Contains(t, notifier.numbers, registered)
+	assert.NotContains(t, notifier.numbers, fresh)
+	text := notifier.texts[slices.Index(notifier.numbers, registered)]
+	assert.Equal(t, fmt.Sprintf("Посылка №%d в статусе registered с %s, срок превышен на 48h0m0s.", registered,
+		now.AddDate(0, 0, -5).Format(time.RFC3339)), text)
+	assert.Positive(t, testutil.ToFloat64(metrics.overdueParcels.WithLabelValues(ParcelStatusRegistered)))
+	assert.Positive(t, testutil.ToFloat64(metrics.overdueAlerts.WithLabelValues(ParcelStatusRegistered)))
+
+	// об одной и той же остановке оповещают один раз
+	notifier.numbers = nil
+	require.NoError(t, alerts.Check(context.Background()))
+	assert.NotContains(t, notifier.numbers, registered)
+	parcels, err = store.ListOverdue(sla, now, ListOptions{Client: client})
+	require.NoError(t, err)
+	require.Len(t, parcels, 1)
+	assert.True(t, parcels[0].Alerted)
+
+	// после смены статуса посылка может застрять снова
+	require.NoError(t, store.SetStatus(registered, ParcelStatusSent))
+	alerts.now = func() time.Time { return later }
+	require.NoError(t, alerts.Check(context.Background()))
+	assert.Contains(t, notifier.numbers, registered)
+	assert.Contains(t, notifier.numbers, sent)
+
+	// http
+	// в статусе registered осталась только fresh, о ней уже оповестили
+	service := NewParcelService(store, WithOverdueSLA(OverdueSLA{Registered: 30 * time.Second}))
+	srv := httptest.NewServer(NewHTTPHandler(service))
+	defer srv.Close()
+	resp, err := http.Get(fmt.Sprintf("%s/parcels/overdue?client=%d&limit=1", srv.URL, client))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var res []api.OverdueParcel
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	require.Len(t, res, 1)
+	assert.Equal(t, fresh, res[0].Parcel.Number)
+	assert.Equal(t, api.Status(ParcelStatusRegistered), res[0].Parcel.Status)
+	assert.True(t, res[0].Alerted)
+	assert.GreaterOrEqual(t, res[0].OverdueSeconds, 30)
+}
@@ -1,71 +1,355 @@
-package main
+package tracker
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 
 	_ "modernc.org/sqlite"
 )
 
 type ParcelStore struct {
 	db *sql.DB
+	// reader БД для чтения посылок и истории; без WithReader совпадает с db
+	reader    *sql.DB
+	readStmts *stmtCache
+	changes   *changeFeed
+	// hooks обработчики изменений из OnChange
+	hooks   *changeHooks
+	logger  Logger
+	metrics *Metrics
+	tracer  trace.Tracer
+	stmts   *stmtCache
+	retry   RetryPolicy
+	// cache кеш Get; nil, если кеширование не включено через WithCache
+	cache ParcelCache
+	// slowQuery порог медленного запроса из WithSlowQueryLog; 0 — не записывать
+	slowQuery time.Duration
+	// keys ключи шифрования адресов из WithEncryption; nil — адреса хранятся открыто
+	keys KeyProvider
+	// redactor скрытие персональных данных из WithRedaction; нулевой ничего не скрывает
+	redactor Redactor
+	// readOnly режим только для чтения, общий для всех копий хранилища
+	readOnly *atomic.Bool
+	// timeouts тайм-ауты операций из WithTimeouts
+	timeouts Timeouts
+	// archiveReads чтение посылок из архива, если их нет в parcel, из WithArchiveReads
+	archiveReads bool
+	// ctx контекст операции, заданный через WithContext; в нём начинаются спаны
+	ctx context.Context
+}
+
+// StoreOption настраивает ParcelStore при создании
+type StoreOption func(*ParcelStore)
+
+// WithStoreLogger задаёт логгер, в который хранилище пишет каждое изменение данных
+func WithStoreLogger(logger Logger) StoreOption {
+	return func(s *ParcelStore) {
+		s.logger = logger
+	}
+}
+
+// WithStoreMetrics задаёт метрики, в которые хранилище пишет длительность запросов
+// и количество добавленных посылок и смен статусов
+func WithStoreMetrics(m *Metrics) StoreOption {
+	return func(s *ParcelStore) {
+		s.metrics = m
+	}
+}
+
+// WithStoreTracerProvider задаёт, откуда хранилище берёт трассировщик;
+// по умолчанию используется глобальный TracerProvider OpenTelemetry
+func WithStoreTracerProvider(tp trace.TracerProvider) StoreOption {
+	return func(s *ParcelStore) {
+		s.tracer = tp.Tracer(tracerName)
+	}
+}
+
+func NewParcelStore(db *sql.DB, opts ...StoreOption) ParcelStore {
+	stmts := newStmtCache(db)
+	s := ParcelStore{
+		db:        db,
+		reader:    db,
+		readStmts: stmts,
+		changes:   newChangeFeed(),
+		hooks:     &changeHooks{},
+		logger:    discardLogger{},
+		tracer:    otel.Tracer(tracerName),
+		stmts:     stmts,
+		retry:     DefaultRetryPolicy,
+		timeouts:  DefaultTimeouts,
+		readOnly:  new(atomic.Bool),
+		ctx:       context.Background(),
+	}
+	s.stmts.warm(hotQueries)
+	for _, opt := range opts {
+		opt(&s)
+	}
+	if s.readStmts != s.stmts {
+		s.readStmts.warm(readQueries)
+	}
+	return s
+}
+
+// WithContext возвращает копию хранилища, операции которой выполняются в контексте ctx:
+// спаны становятся дочерними для спана запроса из ctx
+func (s ParcelStore) WithContext(ctx context.Context) ParcelStore {
+	s.ctx = ctx
+	return s
 }
 
-func NewParcelStore(db *sql.DB) ParcelStore {
-	return ParcelStore{db: db}
+// Subscribe возвращает канал изменений статусов посылок и функцию отписки
+func (s ParcelStore) Subscribe() (<-chan ParcelChange, func()) {
+	return s.changes.subscribe()
+}
+
+// OnChange регистрирует hook, который хранилище вызывает после фиксации каждого
+// добавления посылки, смены статуса и адреса и удаления, и возвращает функцию, которая
+// его снимает. hook не должен долго работать: без ChangeWatcher он вызывается в горутине,
+// выполнившей изменение, и получает только изменения этого процесса. Изменения других
+// процессов приходят через ChangeWatcher с задержкой до его interval; пока наблюдатель
+// работает, через него приходят и свои изменения, а hook вызывается в горутине
+// наблюдателя, а не той, что изменила посылку.
+func (s ParcelStore) OnChange(hook func(Event)) func() {
+	return s.hooks.add(hook)
+}
+
+// added сообщает о зарегистрированных посылках метрикам и обработчикам OnChange
+func (s ParcelStore) added(numbers ...int) {
+	events := make([]Event, len(numbers))
+	for i, number := range numbers {
+		s.metrics.parcelAdded()
+		events[i] = s.event(EventParcelAdded, number, "")
+	}
+	s.hooks.call(events...)
 }
 
 func (s ParcelStore) Add(p Parcel) (int, error) {
-	// добавление строки в таблицу parcel
-	res, err := s.db.Exec("INSERT INTO parcel (client, status, address, created_at) VALUES (:client, :status, :address, :created_at)",
-		sql.Named("client", p.Client),
-		sql.Named("status", p.Status),
-		sql.Named("address", p.Address),
-		sql.Named("created_at", p.CreatedAt))
+	start := time.Now()
+	span := s.startSpan("Add", attrClient.Int(p.Client))
+	defer span.End()
+
+	var id int
+	err := s.withRetry("store.Add", func(s ParcelStore) error {
+		var err error
+		id, err = s.add(p)
+		return err
+	})
+	span.SetAttributes(attrNumber.Int(id))
+	spanError(span, err)
+	s.metrics.observeQuery("Add", start)
+	logResult(s.ctx, s.logger, "store.Add", start, err, "number", id, "client", p.Client, "status", p.Status)
+	if err == nil {
+		s.added(id)
+	}
+	return id, err
+}
+
+func (s ParcelStore) add(p Parcel) (int, error) {
+	tx, err := s.begin()
 	if err != nil {
 		return 0, err
 	}
-	// возвращаем идентификатор последней добавленной записи
-	id, err := res.LastInsertId()
+	defer tx.Rollback()
+
+	id, err := s.insertParcel(tx, p, RequestIDFromContext(s.ctx))
 	if err != nil {
 		return 0, err
 	}
-	return int(id), nil
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// insertParcel регистрирует посылку в транзакции tx вызывающего теми же шагами, что
+// и TrackParcelWorkflow: посылка с начальной записью истории, событие outbox и запись
+// аудита; requestID — идентификатор запроса, который её зарегистрировал
+func (s ParcelStore) insertParcel(tx *sql.Tx, p Parcel, requestID string) (int, error) {
+	return unitOfWork{store: s, tx: tx}.registerParcel(p, requestID)
+}
+
+// parcelColumns колонки таблицы parcel в порядке, который ожидает scanParcel
+const parcelColumns = "number, client, status, address, created_at, COALESCE(tracking_token, ''), tenant_id, COALESCE(delivered_at, ''), version, COALESCE(eta, ''), priority, COALESCE(weight, 0), COALESCE(price, 0), COALESCE(zone, ''), COALESCE(deadline, '')"
+
+// rowScanner общий интерфейс *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanParcel читает посылку из строки, выбранной по parcelColumns
+func scanParcel(row rowScanner) (Parcel, error) {
+	p := Parcel{}
+	err := row.Scan(&p.Number, &p.Client, &p.Status, &p.Address, &p.CreatedAt, &p.TrackingToken, &p.Tenant, &p.DeliveredAt, &p.Version, &p.ETA, &p.Priority, &p.Weight, &p.Price, &p.Zone, &p.Deadline)
+	return p, err
 }
 
 func (s ParcelStore) Get(number int) (Parcel, error) {
+	if s.cache != nil {
+		// в кеше адрес хранится так же, как в БД
+		// кеш общий для всех компаний, поэтому посылка чужой компании считается промахом
+		p, ok := s.cache.Get(s.ctx, number)
+		ok = ok && (p.Tenant == s.tenant() || s.tenant() == anyTenant)
+		s.metrics.cacheLookup(ok)
+		if ok {
+			return s.openParcel(p)
+		}
+	}
+
+	defer s.metrics.observeQuery("Get", time.Now())
+	span := s.startSpan("Get", attrNumber.Int(number))
+	defer span.End()
+
 	// чтение строки по заданному number
-	row := s.db.QueryRow("SELECT number, client, status, address, created_at FROM parcel WHERE number = :number", sql.Named("number", number))
+	row := s.readRow(queryParcelByNumber, sql.Named("number", number), sql.Named("tenant", s.tenant()))
 
-	p := Parcel{}
-	err := row.Scan(&p.Number, &p.Client, &p.Status, &p.Address, &p.CreatedAt)
+	p, err := scanParcel(row)
+	if errors.Is(err, sql.ErrNoRows) && s.archiveReads {
+		p, err = scanParcel(s.readRow(queryArchivedByNumber, sql.Named("number", number), sql.Named("tenant", s.tenant())))
+	}
 	if err != nil {
-		return p, err
+		return p, spanError(span, err)
+	}
+	if s.cache != nil {
+		s.cache.Set(s.ctx, p)
 	}
 
-	return p, nil
+	p, err = s.openParcel(p)
+	return p, spanError(span, err)
+}
+
+// GetByToken возвращает посылку по её трекинг-токену
+func (s ParcelStore) GetByToken(token string) (Parcel, error) {
+	defer s.metrics.observeQuery("GetByToken", time.Now())
+	// сам токен в спан не попадает: по нему посылку видно без аутентификации
+	span := s.startSpan("GetByToken")
+	defer span.End()
+
+	row := s.readRow(queryParcelByToken, sql.Named("token", token), sql.Named("tenant", s.tenant()))
+
+	p, err := scanParcel(row)
+	if errors.Is(err, sql.ErrNoRows) && s.archiveReads {
+		p, err = scanParcel(s.readRow(queryArchivedByToken, sql.Named("token", token), sql.Named("tenant", s.tenant())))
+	}
+	if err != nil {
+		return p, spanError(span, err)
+	}
+	span.SetAttributes(attrNumber.Int(p.Number))
+
+	p, err = s.openParcel(p)
+	return p, spanError(span, err)
 }
 
 func (s ParcelStore) GetByClient(client int) ([]Parcel, error) {
+	defer s.metrics.observeQuery("GetByClient", time.Now())
+	span := s.startSpan("GetByClient", attrClient.Int(client))
+	defer span.End()
+
 	// чтение строк из таблицы parcel по заданному client
-	row, err := s.db.Query("SELECT number, client, status, address, created_at FROM parcel WHERE client = :client", sql.Named("client", client))
+	query := queryParcelsByClient
+	if s.archiveReads {
+		query = queryParcelsByClientWithArchive
+	}
+	rows, err := s.readQuery(query, sql.Named("client", client), sql.Named("tenant", s.tenant()))
 	if err != nil {
-		return nil, err
+		return nil, spanError(span, err)
+	}
+	res, err := s.collectParcels(rows)
+	return res, spanError(span, err)
+}
+
+// GetByStatus возвращает посылки в статусе status в порядке номеров
+func (s ParcelStore) GetByStatus(status string) ([]Parcel, error) {
+	defer s.metrics.observeQuery("GetByStatus", time.Now())
+	span := s.startSpan("GetByStatus", attrStatus.String(status))
+	defer span.End()
+
+	rows, err := s.readQuery(queryParcelsByStatus, sql.Named("status", status), sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	res, err := s.collectParcels(rows)
+	return res, spanError(span, err)
+}
+
+// ListOptions фильтры выборки посылок; нулевые значения не ограничивают выборку
+type ListOptions struct {
+	Client int
+	Status string
+	// Zone зона доставки посылок
+	Zone string
+	// From и To ограничивают время регистрации посылок: не раньше From и раньше To
+	From, To time.Time
+	Limit    int
+}
+
+// query начинает запрос колонок columns из parcel с условиями для компании tenant
+// и фильтров opts, кроме Limit
+func (opts ListOptions) query(columns, tenant string) *queryBuilder {
+	b := selectQuery(columns, "parcel").where(tenantCond, sql.Named("tenant", tenant))
+	if opts.Client != 0 {
+		b.filter("client", "=", opts.Client)
+	}
+	if opts.Status != "" {
+		b.filter("status", "=", opts.Status)
+	}
+	if opts.Zone != "" {
+		b.filter("zone", "=", opts.Zone)
 	}
-	defer row.Close()
+	// created_at хранится в RFC 3339 UTC, поэтому строки сравниваются в порядке времени
+	if !opts.From.IsZero() {
+		b.filter("created_at", ">=", opts.From.UTC().Format(time.RFC3339))
+	}
+	if !opts.To.IsZero() {
+		b.filter("created_at", "<", opts.To.UTC().Format(time.RFC3339))
+	}
+	return b
+}
+
+// List возвращает посылки, подходящие под фильтры, в порядке номеров
+func (s ParcelStore) List(opts ListOptions) ([]Parcel, error) {
+	defer s.metrics.observeQuery("List", time.Now())
+	span := s.startSpan("List", attrClient.Int(opts.Client), attrStatus.String(opts.Status))
+	defer span.End()
+
+	query, args := opts.query(parcelColumns, s.tenant()).orderBy("number").limitTo(opts.Limit).build()
+	rows, err := s.readUnprepared(query, args...)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	res, err := s.collectParcels(rows)
+	return res, spanError(span, err)
+}
+
+// collectParcels читает все посылки из rows с расшифрованными адресами и закрывает rows
+func (s ParcelStore) collectParcels(rows timedRows) ([]Parcel, error) {
+	defer rows.Close()
 	// заполняем срез Parcel данными из таблицы
 	var res []Parcel
 
-	for row.Next() {
-		var parcels Parcel
-
-		err := row.Scan(&parcels.Number, &parcels.Client, &parcels.Status, &parcels.Address, &parcels.CreatedAt)
+	for rows.Next() {
+		p, err := scanParcel(rows)
+		if err == nil {
+			p, err = s.openParcel(p)
+		}
 		if err != nil {
 			return nil, err
 		}
-		res = append(res, parcels)
+		res = append(res, p)
 	}
 
-	if err := row.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
@@ -73,38 +357,583 @@ func (s ParcelStore) GetByClient(client int) ([]Parcel, error) {
 }
 
 func (s ParcelStore) SetStatus(number int, status string) error {
+	start := time.Now()
+	span := s.startSpan("SetStatus", attrNumber.Int(number), attrStatus.String(status))
+	defer span.End()
+
+	var old string
+	var changes []ParcelChange
+	err := s.withRetry("store.SetStatus", func(s ParcelStore) error {
+		var err error
+		old, changes, err = s.setStatus(number, status)
+		return err
+	})
+	s.invalidate(number)
+	s.notify(changes)
+	spanError(span, err)
+	s.metrics.observeQuery("SetStatus", start)
+	logResult(s.ctx, s.logger, "store.SetStatus", start, err, "number", number, "old_status", old, "new_status", status)
+	if err == nil && len(changes) > 0 {
+		s.metrics.statusChanged(status)
+	}
+	return err
+}
+
+// notify сбрасывает кеш посылок из зафиксированных изменений changes — самой посылки
+// и объединённых с ней — и отправляет изменения подписчикам и обработчикам OnChange
+func (s ParcelStore) notify(changes []ParcelChange) {
+	events := make([]Event, len(changes))
+	for i, c := range changes {
+		s.invalidate(c.Number)
+		events[i] = s.event(EventParcelStatusChanged, c.Number, c.Status)
+	}
+	s.changes.publish(changes...)
+	s.hooks.call(events...)
+}
+
+// setStatus меняет статус посылки и возвращает прежний и записанные изменения:
+// несуществующая посылка (прежний статус пустой) и повторная доставка ничего не меняют
+func (s ParcelStore) setStatus(number int, status string) (string, []ParcelChange, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return "", nil, err
+	}
+	defer tx.Rollback()
+
+	var old, tenant string
+	var client int
+	err = s.queryRow(tx, queryParcelStatus, sql.Named("number", number), sql.Named("tenant", s.tenant())).Scan(&old, &client, &tenant)
+	if errors.Is(err, sql.ErrNoRows) {
+		// историю пишем и подписчиков оповещаем, только если посылка существует
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if redelivery(old, status) {
+		return old, nil, nil
+	}
+	if undelivery(old, status) {
+		return old, nil, fmt.Errorf("%w: посылка уже доставлена", ErrInvalidTransition)
+	}
+
+	changes, err := s.updateStatus(tx, number, client, tenant, old, status)
+	if err != nil {
+		return old, nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return old, nil, err
+	}
+	return old, changes, nil
+}
+
+// redelivery сообщает, что уже доставленную посылку снова отмечают доставленной,
+// например при сканировании на нескольких устройствах. Такая отметка ничего не меняет:
+// ни время доставки, ни историю, и не считается ошибкой.
+func redelivery(old, status string) bool {
+	return old == ParcelStatusDelivered && status == ParcelStatusDelivered
+}
+
+// undelivery сообщает, что доставленную посылку пытаются вернуть в путь или к регистрации.
+// Доставка окончательна: после неё посылку нельзя ни отправить снова, ни удалить.
+// Остальные шаги назад, например возврат отправленной по ошибке посылки в registered,
+// хранилище допускает.
+func undelivery(old, status string) bool {
+	return old == ParcelStatusDelivered && status != ParcelStatusDelivered
+}
+
+// SetStatusIf меняет статус посылки на status, только если её текущий статус expected.
+// Проверка и обновление выполняются одним запросом, поэтому одновременные изменения
+// с разных сканеров не затирают друг друга: проигравший получает ErrConflict.
+// Для несуществующей посылки возвращается sql.ErrNoRows. Отметка delivered уже
+// доставленной посылки конфликтом не считается и ничего не меняет.
+func (s ParcelStore) SetStatusIf(number int, expected, status string) error {
+	start := time.Now()
+	span := s.startSpan("SetStatusIf", attrNumber.Int(number), attrStatus.String(status))
+	defer span.End()
+
+	var changes []ParcelChange
+	err := s.withRetry("store.SetStatusIf", func(s ParcelStore) error {
+		var err error
+		changes, err = s.setStatusIf(number, expected, status)
+		return err
+	})
+	s.invalidate(number)
+	s.notify(changes)
+	spanError(span, err)
+	s.metrics.observeQuery("SetStatusIf", start)
+	logResult(s.ctx, s.logger, "store.SetStatusIf", start, err, "number", number, "expected_status", expected, "new_status", status)
+	if err == nil && len(changes) > 0 {
+		s.metrics.statusChanged(status)
+	}
+	return err
+}
+
+func (s ParcelStore) setStatusIf(number int, expected, status string) ([]ParcelChange, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	changedAt := time.Now().UTC().Format(time.RFC3339)
+	res, err := s.exec(tx, queryUpdateStatusIf,
+		sql.Named("status", status),
+		sql.Named("expected", expected),
+		sql.Named("changed_at", changedAt),
+		sql.Named("number", number),
+		sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	// после обновления в той же транзакции читается уже новый статус
+	var current, tenant string
+	var client int
+	err = s.queryRow(tx, queryParcelStatus, sql.Named("number", number), sql.Named("tenant", s.tenant())).Scan(&current, &client, &tenant)
+	if err != nil {
+		return nil, err
+	}
+	if redelivery(current, status) && (n == 0 || redelivery(expected, status)) {
+		// посылку уже доставили; транзакция откатывается, поэтому время доставки не меняется
+		return nil, nil
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("%w: статус посылки %s, а не %s", ErrConflict, current, expected)
+	}
+	if undelivery(expected, status) {
+		return nil, fmt.Errorf("%w: посылка уже доставлена", ErrInvalidTransition)
+	}
+
+	changes, err := s.recordStatus(tx, number, client, tenant, expected, status, changedAt)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// updateStatus меняет статус существующей посылки компании tenant в транзакции tx, записывает
+// историю и событие outbox и возвращает изменения для рассылки подписчикам после фиксации tx
+func (s ParcelStore) updateStatus(tx *sql.Tx, number, client int, tenant, old, status string) ([]ParcelChange, error) {
+	changedAt := time.Now().UTC().Format(time.RFC3339)
 	// обновление статуса в таблице parcel
-	_, err := s.db.Exec("UPDATE parcel SET status = :status WHERE number = :number",
+	res, err := s.exec(tx, queryUpdateStatus,
 		sql.Named("status", status),
-		sql.Named("number", number))
+		sql.Named("changed_at", changedAt),
+		sql.Named("number", number),
+		sql.Named("version", s.ifVersion()),
+		sql.Named("tenant", tenant))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	// посылка существует, поэтому не обновиться она могла только из-за версии
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrVersionMismatch
+	}
+	return s.recordStatus(tx, number, client, tenant, old, status, changedAt)
+}
+
+// recordStatus записывает в транзакции tx историю и событие outbox о смене статуса посылки
+// с old на status в момент changedAt, переносит статус на объединённые с ней посылки
+// и возвращает изменения для рассылки подписчикам. Статус посылки, объединённой
+// с другой, отдельно не меняется: возвращается ErrMergedParcel.
+func (s ParcelStore) recordStatus(tx *sql.Tx, number, client int, tenant, old, status, changedAt string) ([]ParcelChange, error) {
+	parent, err := s.mergedInto(tx, number)
+	if err != nil {
+		return nil, err
+	}
+	if parent != 0 {
+		return nil, fmt.Errorf("%w: посылка в составе № %d", ErrMergedParcel, parent)
+	}
+	change := ParcelChange{
+		Number:    number,
+		Status:    status,
+		ChangedAt: changedAt,
+	}
+	entry := change
+	entry.RequestID = RequestIDFromContext(s.ctx)
+	err = s.addHistory(tx, entry)
+	if err != nil {
+		return nil, err
+	}
+	err = s.addOutbox(tx, OutboxEvent{Type: EventParcelStatusChanged, Number: number, Client: client, Status: status, PreviousStatus: old, Tenant: tenant})
+	if err != nil {
+		return nil, err
+	}
+	merged, err := s.propagateStatus(tx, number, tenant, old, status, changedAt)
+	if err != nil {
+		return nil, err
+	}
+	changes := append([]ParcelChange{change}, merged...)
+	if status == ParcelStatusDelivered {
+		err = s.codDelivered(tx, changes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// maxAddressLen наибольшая длина адреса доставки в символах
+const maxAddressLen = 500
+
+// validateAddress проверяет адрес доставки: непустой текст в UTF-8 не длиннее
+// maxAddressLen символов. Управляющие символы, в том числе переводы строк, ломают
+// этикетки ZPL и строки логов, а символы смены направления текста позволяют показать
+// на этикетке и в админке не тот адрес, что хранится, поэтому они не допускаются.
+func validateAddress(address string) error {
+	switch {
+	case !utf8.ValidString(address):
+		return fmt.Errorf("%w: не UTF-8", ErrInvalidAddress)
+	case strings.TrimSpace(address) == "":
+		return fmt.Errorf("%w: адрес пуст", ErrInvalidAddress)
+	case utf8.RuneCountInString(address) > maxAddressLen:
+		return fmt.Errorf("%w: длиннее %d символов", ErrInvalidAddress, maxAddressLen)
+	}
+	for _, r := range address {
+		if unicode.IsControl(r) || unicode.Is(unicode.Bidi_Control, r) {
+			return fmt.Errorf("%w: недопустимый символ %U", ErrInvalidAddress, r)
+		}
 	}
 	return nil
 }
 
 func (s ParcelStore) SetAddress(number int, address string) error {
+	start := time.Now()
+	span := s.startSpan("SetAddress", attrNumber.Int(number))
+	defer span.End()
+
+	var changed bool
+	err := s.withRetry("store.SetAddress", func(s ParcelStore) error {
+		var err error
+		changed, err = s.setAddress(number, address)
+		return err
+	})
+	s.invalidate(number)
+	// сам адрес в лог не попадает: это персональные данные
+	spanError(span, err)
+	s.metrics.observeQuery("SetAddress", start)
+	logResult(s.ctx, s.logger, "store.SetAddress", start, err, "number", number)
+	if err != nil {
+		return err
+	}
+	if changed {
+		s.hooks.call(s.event(EventParcelAddressChanged, number, ""))
+	}
+	return nil
+}
+
+// setAddress меняет адрес посылки и сообщает, изменён ли он: адрес посылки, которой нет
+// или которая уже не в статусе registered, не меняется
+func (s ParcelStore) setAddress(number int, address string) (bool, error) {
+	// зона определяется по индексу до шифрования адреса
+	postal := postalCode(address)
+	address, err := s.sealAddress(address)
+	if err != nil {
+		return false, err
+	}
+	tx, err := s.begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// адрес посылки в составе другой следует за ней
+	parent, err := s.mergedInto(tx, number)
+	if err != nil {
+		return false, err
+	}
+	if parent != 0 {
+		return false, fmt.Errorf("%w: посылка в составе № %d", ErrMergedParcel, parent)
+	}
+
 	// обновление адреса в таблице parcel
 	// менять адрес можно только если значение статуса registered
-	_, err := s.db.Exec("UPDATE parcel SET address = :address WHERE number = :number AND status = :status",
+	res, err := s.exec(tx, queryUpdateAddress,
 		sql.Named("address", address),
+		sql.Named("postal", postal),
 		sql.Named("number", number),
-		sql.Named("status", ParcelStatusRegistered))
+		sql.Named("status", ParcelStatusRegistered),
+		sql.Named("version", s.ifVersion()),
+		sql.Named("tenant", s.tenant()))
 	if err != nil {
-		return err
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		// посылки нет, она уже не в статусе registered или изменилась
+		return false, s.versionMismatch(tx, number)
+	}
+	// координаты прежнего адреса больше не верны
+	_, err = s.exec(tx, queryDeleteCoordinates, sql.Named("number", number))
+	if err != nil {
+		return false, err
+	}
+	err = s.addOutbox(tx, OutboxEvent{Type: EventParcelAddressChanged, Number: number})
+	if err != nil {
+		return false, err
 	}
 
-	return nil
+	return true, tx.Commit()
 }
 
 func (s ParcelStore) Delete(number int) error {
+	start := time.Now()
+	span := s.startSpan("Delete", attrNumber.Int(number))
+	defer span.End()
+
+	var deleted bool
+	err := s.withRetry("store.Delete", func(s ParcelStore) error {
+		var err error
+		deleted, err = s.delete(number)
+		return err
+	})
+	s.invalidate(number)
+	spanError(span, err)
+	s.metrics.observeQuery("Delete", start)
+	logResult(s.ctx, s.logger, "store.Delete", start, err, "number", number)
+	if err == nil && deleted {
+		s.hooks.call(s.event(EventParcelDeleted, number, ""))
+	}
+	return err
+}
+
+// delete удаляет посылку и сообщает, удалена ли она: посылка, которой нет или
+// которая уже не в статусе registered, не удаляется
+func (s ParcelStore) delete(number int) (bool, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
 	// удаление строки из таблицы parcel
 	// удалять строку можно только если значение статуса registered
-	_, err := s.db.Exec("DELETE FROM parcel WHERE number = :number AND status = :status",
+	res, err := s.exec(tx, queryDeleteParcel,
 		sql.Named("number", number),
-		sql.Named("status", ParcelStatusRegistered))
+		sql.Named("status", ParcelStatusRegistered),
+		sql.Named("version", s.ifVersion()),
+		sql.Named("tenant", s.tenant()))
 	if err != nil {
+		return false, err
+	}
+	// вместе с посылкой удаляется и её история
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		// посылки нет, она уже не в статусе registered или изменилась
+		return false, s.versionMismatch(tx, number)
+	}
+	_, err = s.exec(tx, queryDeleteHistory, sql.Named("number", number))
+	if err != nil {
+		return false, err
+	}
+	// посылки, объединённые в удалённую, снова едут сами по себе
+	_, err = s.exec(tx, queryDeleteMergeLinks, sql.Named("number", number))
+	if err != nil {
+		return false, err
+	}
+	_, err = s.exec(tx, queryDeleteCOD, sql.Named("number", number))
+	if err != nil {
+		return false, err
+	}
+	_, err = s.exec(tx, queryDeleteCustoms, sql.Named("number", number))
+	if err != nil {
+		return false, err
+	}
+	_, err = s.exec(tx, queryDeleteAttachments, sql.Named("number", number))
+	if err != nil {
+		return false, err
+	}
+	_, err = s.exec(tx, queryDeleteCoordinates, sql.Named("number", number))
+	if err != nil {
+		return false, err
+	}
+	err = s.addOutbox(tx, OutboxEvent{Type: EventParcelDeleted, Number: number})
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// invalidate сбрасывает посылку в кеше. Вызывается и после неудачной записи:
+// неизвестно, дошло ли изменение до БД.
+func (s ParcelStore) invalidate(number int) {
+	if s.cache != nil {
+		s.cache.Invalidate(s.ctx, number)
+	}
+}
+
+// History возвращает историю статусов посылки в порядке их установки
+func (s ParcelStore) History(number int) ([]ParcelChange, error) {
+	defer s.metrics.observeQuery("History", time.Now())
+	span := s.startSpan("History", attrNumber.Int(number))
+	defer span.End()
+
+	res, err := s.readHistory(queryHistory, number)
+	if err == nil && len(res) == 0 && s.archiveReads {
+		res, err = s.readHistory(queryArchivedHistory, number)
+	}
+	return res, spanError(span, err)
+}
+
+// readHistory читает историю посылки number запросом query
+func (s ParcelStore) readHistory(query string, number int) ([]ParcelChange, error) {
+	rows, err := s.readQuery(query, sql.Named("number", number), sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []ParcelChange
+	for rows.Next() {
+		var c ParcelChange
+		err := rows.Scan(&c.Number, &c.Status, &c.ChangedAt, &c.RequestID)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// PruneHistory удаляет записи истории доставленных посылок, сделанные раньше before,
+// и возвращает число удалённых записей. История посылок в пути не удаляется.
+// Посылки, доставленные раньше before, пропадают из доставки в ежемесячных отчётах.
+func (s ParcelStore) PruneHistory(before time.Time) (int, error) {
+	start := time.Now()
+	span := s.startSpan("PruneHistory")
+	defer span.End()
+
+	var n int64
+	err := s.withRetry("store.PruneHistory", func(s ParcelStore) error {
+		res, err := s.exec(nil, `DELETE FROM parcel_history WHERE changed_at < :before
+			AND number IN (SELECT number FROM parcel WHERE status = :status AND `+tenantCond+`)`,
+			sql.Named("before", before.UTC().Format(time.RFC3339)),
+			sql.Named("status", ParcelStatusDelivered),
+			sql.Named("tenant", s.tenant()))
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
 		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("PruneHistory", start)
+	logResult(s.ctx, s.logger, "store.PruneHistory", start, err, "before", before, "deleted", n)
+	return int(n), err
+}
+
+// addHistory добавляет запись в историю статусов посылки
+func (s ParcelStore) addHistory(tx *sql.Tx, c ParcelChange) error {
+	_, err := s.exec(tx, queryInsertHistory,
+		sql.Named("number", c.Number),
+		sql.Named("status", c.Status),
+		sql.Named("changed_at", c.ChangedAt),
+		sql.Named("request_id", c.RequestID))
+	return err
+}
+
+// AddAPIKey сохраняет хеш API-ключа клиента
+func (s ParcelStore) AddAPIKey(hash string, client int) error {
+	start := time.Now()
+	span := s.startSpan("AddAPIKey", attrClient.Int(client))
+	defer span.End()
+
+	err := s.withRetry("store.AddAPIKey", func(s ParcelStore) error {
+		tenant, err := s.ownTenant()
+		if err != nil {
+			return err
+		}
+		_, err = s.exec(nil, "INSERT INTO api_key (key_hash, client, created_at, tenant_id) VALUES (:key_hash, :client, :created_at, :tenant)",
+			sql.Named("key_hash", hash),
+			sql.Named("client", client),
+			sql.Named("created_at", time.Now().UTC().Format(time.RFC3339)),
+			sql.Named("tenant", tenant))
+		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("AddAPIKey", start)
+	logResult(s.ctx, s.logger, "store.AddAPIKey", start, err, "client", client)
+	return err
+}
+
+// ClientByAPIKey возвращает клиента и его компанию по хешу API-ключа. Компания
+// запроса ещё неизвестна, поэтому ключ ищется среди ключей всех компаний.
+func (s ParcelStore) ClientByAPIKey(hash string) (int, string, error) {
+	defer s.metrics.observeQuery("ClientByAPIKey", time.Now())
+	span := s.startSpan("ClientByAPIKey")
+	defer span.End()
+
+	var client int
+	var tenant string
+	err := s.queryRow(nil, queryClientByAPIKey, sql.Named("key_hash", hash)).Scan(&client, &tenant)
+	if err != nil {
+		return 0, "", spanError(span, err)
 	}
-	return nil
+	span.SetAttributes(attrClient.Int(client), attrTenant.String(tenant))
+	return client, tenant, nil
+}
+
+// SetRole назначает роль пользователю subject, заменяя прежнюю
+func (s ParcelStore) SetRole(subject int, role Role) error {
+	start := time.Now()
+	span := s.startSpan("SetRole", attrClient.Int(subject))
+	defer span.End()
+
+	err := s.withRetry("store.SetRole", func(s ParcelStore) error {
+		tenant, err := s.ownTenant()
+		if err != nil {
+			return err
+		}
+		_, err = s.exec(nil, `INSERT INTO user_role (tenant_id, subject, role) VALUES (:tenant, :subject, :role)
+			ON CONFLICT (tenant_id, subject) DO UPDATE SET role = excluded.role`,
+			sql.Named("tenant", tenant),
+			sql.Named("subject", subject),
+			sql.Named("role", string(role)))
+		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("SetRole", start)
+	logResult(s.ctx, s.logger, "store.SetRole", start, err, "subject", subject, "role", role)
+	return err
+}
+
+// Role возвращает роль пользователя subject
+func (s ParcelStore) Role(subject int) (Role, error) {
+	defer s.metrics.observeQuery("Role", time.Now())
+	span := s.startSpan("Role", attrClient.Int(subject))
+	defer span.End()
+
+	var role string
+	err := s.queryRow(nil, queryRole, sql.Named("subject", subject), sql.Named("tenant", s.tenant())).Scan(&role)
+	if err != nil {
+		return "", spanError(span, err)
+	}
+	return Role(role), nil
 }
@@ -1,14 +1,19 @@
-package main
+package tracker
 
 import (
 	"database/sql"
+	"errors"
 	"math/rand"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+
 	_ "modernc.org/sqlite"
 )
 
@@ -17,10 +22,23 @@ var (
 	// Для повышения уникальности в качестве seed
 	// используется текущее время в unix формате (в виде числа)
 	randSource = rand.NewSource(time.Now().UnixNano())
-	// randRange использует randSource для генерации случайных чисел
-	randRange = rand.New(randSource)
+	// randRange использует randSource для генерации случайных чисел;
+	// его можно вызывать из параллельных тестов
+	randRange = &lockedRand{r: rand.New(randSource)}
+	// testConfig настройки БД для тестов: те же значения по умолчанию
+	// и переменные окружения TRACKER_*, что и у приложения
+	testConfig = loadTestConfig()
 )
 
+// loadTestConfig загружает настройки для тестов без YAML-файла
+func loadTestConfig() config.Config {
+	cfg, err := config.Load("")
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
 // getTestParcel возвращает тестовую посылку
 func getTestParcel() Parcel {
 	return Parcel{
@@ -28,18 +46,19 @@ func getTestParcel() Parcel {
 		Status:    ParcelStatusRegistered,
 		Address:   "test",
 		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Tenant:    DefaultTenant,
+		Version:   1,
+		Priority:  PriorityStandard,
 	}
 }
 
 // TestAddGetDelete проверяет добавление, получение и удаление посылки
 func TestAddGetDelete(t *testing.T) {
+	t.Parallel()
+
 	// prepare
 	// подключение к БД
-	db, err := sql.Open("sqlite", "tracker.db")
-	if err != nil {
-		require.NoError(t, err)
-	}
-	defer db.Close()
+	db := openTestDB(t)
 
 	store := NewParcelStore(db)
 	parcel := getTestParcel()
@@ -74,27 +93,18 @@ func TestAddGetDelete(t *testing.T) {
 
 // TestSetAddress проверяет обновление адреса
 func TestSetAddress(t *testing.T) {
+	t.Parallel()
 	// prepare
-	// подключение к БД
-	db, err := sql.Open("sqlite", "tracker.db")
-	if err != nil {
-		require.NoError(t, err)
-	}
-	defer db.Close()
-
-	store := NewParcelStore(db)
-	parcel := getTestParcel()
+	store := newTestStore(t)
 
 	// add
-	// добавим новую посылку в БД, убедимся в отсутствии ошибки и наличии идентификатора
-	id, err := store.Add(parcel)
-	require.NoError(t, err)
-	assert.NotEmpty(t, id)
+	// добавим новую посылку в БД
+	id := mustAddParcel(t, store, getTestParcel()).Number
 
 	// set address
 	// обновим адрес, убедимся в отсутствии ошибки
 	newAddress := "new test address"
-	err = store.SetAddress(id, newAddress)
+	err := store.SetAddress(id, newAddress)
 	require.NoError(t, err)
 	// check
 	// получим добавленную посылку и убедитесь, что адрес обновился
@@ -105,26 +115,17 @@ func TestSetAddress(t *testing.T) {
 
 // TestSetStatus проверяет обновление статуса
 func TestSetStatus(t *testing.T) {
+	t.Parallel()
 	// prepare
-	// подключение к БД
-	db, err := sql.Open("sqlite", "tracker.db")
-	if err != nil {
-		require.NoError(t, err)
-	}
-	defer db.Close()
-
-	store := NewParcelStore(db)
-	parcel := getTestParcel()
+	store := newTestStore(t)
 
 	// add
-	// добавим новую посылку в БД, убедимся в отсутствии ошибки и наличии идентификатора
-	id, err := store.Add(parcel)
-	require.NoError(t, err)
-	assert.NotEmpty(t, id)
+	// добавим новую посылку в БД
+	id := mustAddParcel(t, store, getTestParcel()).Number
 
 	// set status
 	// обновим статус, убедимся в отсутствии ошибки
-	err = store.SetStatus(id, ParcelStatusDelivered)
+	err := store.SetStatus(id, ParcelStatusDelivered)
 	require.NoError(t, err)
 
 	// check
@@ -134,40 +135,113 @@ func TestSetStatus(t *testing.T) {
 	assert.Equal(t, ParcelStatusDelivered, checkUpdate.Status)
 }
 
-// TestGetByClient проверяет получение посылок по идентификатору клиента
-func TestGetByClient(t *testing.T) {
+// TestOnChange проверяет, что обработчики OnChange получают изменения после записи
+// и перестают получать их после снятия
+func TestOnChange(t *testing.T) {
+	t.Parallel()
 	// prepare
-	// подключение к БД
-	db, err := sql.Open("sqlite", "tracker.db")
-	if err != nil {
-		require.NoError(t, err)
-	}
-	defer db.Close()
+	store := newTestStore(t)
+	var events []Event
+	cancel := store.OnChange(func(e Event) {
+		events = append(events, e)
+	})
+
+	// change
+	id := mustAddParcel(t, store, getTestParcel()).Number
+	require.NoError(t, store.SetAddress(id, "new test address"))
+	require.NoError(t, store.SetStatus(id, ParcelStatusSent))
+	// отправленная посылка не удаляется, и обработчики ничего не получают
+	require.NoError(t, store.Delete(id))
+
+	// check
+	assert.Equal(t, []Event{
+		{Type: EventParcelAdded, Number: id, Tenant: DefaultTenant},
+		{Type: EventParcelAddressChanged, Number: id, Tenant: DefaultTenant},
+		{Type: EventParcelStatusChanged, Number: id, Tenant: DefaultTenant, Status: ParcelStatusSent},
+	}, events)
+
+	// cancel
+	cancel()
+	require.NoError(t, store.SetStatus(id, ParcelStatusDelivered))
+	assert.Len(t, events, 3)
+}
+
+// TestSetStatusIf проверяет смену статуса только из ожидаемого
+func TestSetStatusIf(t *testing.T) {
+	t.Parallel()
+	// prepare
+	store := newTestStore(t)
+	id := mustAddParcel(t, store, getTestParcel()).Number
+
+	// set status
+	// первый сканер меняет статус, второй с тем же ожиданием получает конфликт
+	require.NoError(t, store.SetStatusIf(id, ParcelStatusRegistered, ParcelStatusSent))
+	err := store.SetStatusIf(id, ParcelStatusRegistered, ParcelStatusDelivered)
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.ErrorIs(t, store.SetStatusIf(-1, ParcelStatusRegistered, ParcelStatusSent), sql.ErrNoRows)
+
+	// check
+	checkUpdate, err := store.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusSent, checkUpdate.Status)
+	history, err := store.History(id)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, ParcelStatusSent, history[1].Status)
+}
+
+// TestRedelivery проверяет, что повторная отметка доставки не меняет время доставки и историю
+func TestRedelivery(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
 
 	store := NewParcelStore(db)
-	parcels := []Parcel{
-		getTestParcel(),
-		getTestParcel(),
-		getTestParcel(),
-	}
+	id, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+	require.NoError(t, store.SetStatus(id, ParcelStatusSent))
+
+	// set status
+	require.NoError(t, store.SetStatus(id, ParcelStatusDelivered))
+	delivered, err := store.Get(id)
+	require.NoError(t, err)
+	require.NotEmpty(t, delivered.DeliveredAt)
+	// время первой доставки сдвигаем в прошлое, чтобы перезапись была заметна
+	_, err = db.Exec("UPDATE parcel SET delivered_at = '2024-01-01T00:00:00Z' WHERE number = :number", sql.Named("number", id))
+	require.NoError(t, err)
+
+	// второй и третий сканеры тоже отмечают доставку
+	require.NoError(t, store.SetStatus(id, ParcelStatusDelivered))
+	require.NoError(t, store.SetStatusIf(id, ParcelStatusSent, ParcelStatusDelivered))
+	require.NoError(t, store.SetStatusIf(id, ParcelStatusDelivered, ParcelStatusDelivered))
+
+	// check
+	stored, err := store.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-01T00:00:00Z", stored.DeliveredAt)
+	history, err := store.History(id)
+	require.NoError(t, err)
+	assert.Len(t, history, 3)
+}
+
+// TestGetByClient проверяет получение посылок по идентификатору клиента
+func TestGetByClient(t *testing.T) {
+	t.Parallel()
+	// prepare
+	store := newTestStore(t)
 	parcelMap := map[int]Parcel{}
 
-	// задаём всем посылкам один и тот же идентификатор клиента
+	// задаём всем посылкам один и тот же идентификатор клиента;
+	// посылка другого клиента в выборку не попадает
 	client := randRange.Intn(10_000_000)
-	parcels[0].Client = client
-	parcels[1].Client = client
-	parcels[2].Client = client
+	mustAddParcel(t, store, newParcelFixture().client(client+1).build())
 
 	// add
-	for i := 0; i < len(parcels); i++ {
-		id, err := store.Add(parcels[i]) // добавим новую посылку в БД, убедимся в отсутствии ошибки и наличии идентификатора
-		require.NoError(t, err)
-		assert.NotEmpty(t, id)
-		// обновляем идентификатор добавленной у посылки
-		parcels[i].Number = id
-
-		// сохраняем добавленную посылку в структуру map, чтобы её можно было легко достать по идентификатору посылки
-		parcelMap[id] = parcels[i]
+	for i := 0; i < 3; i++ {
+		// добавим новую посылку в БД и сохраним её в структуру map, чтобы её можно было легко достать по идентификатору посылки
+		parcel := mustAddParcel(t, store, newParcelFixture().client(client).build())
+		parcelMap[parcel.Number] = parcel
 	}
 
 	// get by client
@@ -175,7 +249,7 @@ func TestGetByClient(t *testing.T) {
 	// убедимся в отсутствии ошибки
 	require.NoError(t, err)
 	// убедимся, что количество полученных посылок совпадает с количеством добавленных
-	require.Equal(t, len(storedParcels), len(parcels))
+	require.Equal(t, len(parcelMap), len(storedParcels))
 
 	// check
 	for _, parcel := range storedParcels {
@@ -185,3 +259,324 @@ func TestGetByClient(t *testing.T) {
 		assert.Equal(t, parcel, parcelMap[parcel.Number])
 	}
 }
+
+// TestHistory проверяет запись истории статусов и её удаление вместе с посылкой
+func TestHistory(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	// подключение к БД
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	parcel := getTestParcel()
+
+	// add
+	// начальный статус попадает в историю
+	id, err := store.Add(parcel)
+	require.NoError(t, err)
+
+	history, err := store.History(id)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, ParcelChange{Number: id, Status: ParcelStatusRegistered, ChangedAt: parcel.CreatedAt}, history[0])
+
+	// set status
+	// каждое изменение статуса добавляет запись в историю
+	require.NoError(t, store.SetStatus(id, ParcelStatusSent))
+	require.NoError(t, store.SetStatus(id, ParcelStatusRegistered))
+
+	history, err = store.History(id)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, ParcelStatusSent, history[1].Status)
+	assert.Equal(t, ParcelStatusRegistered, history[2].Status)
+
+	// delete
+	// история удаляется вместе с посылкой
+	require.NoError(t, store.Delete(id))
+
+	history, err = store.History(id)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+// TestPruneHistory проверяет удаление старой истории доставленных посылок
+func TestPruneHistory(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	delivered, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+	require.NoError(t, store.SetStatus(delivered, ParcelStatusSent))
+	require.NoError(t, store.SetStatus(delivered, ParcelStatusDelivered))
+	sent, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+	require.NoError(t, store.SetStatus(sent, ParcelStatusSent))
+
+	// prune
+	// записи старше срока хранения ещё не появились
+	n, err := store.PruneHistory(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	history, err := store.History(delivered)
+	require.NoError(t, err)
+	assert.Len(t, history, 3)
+
+	n, err = store.PruneHistory(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// check
+	// удаляется история только доставленной посылки
+	assert.GreaterOrEqual(t, n, 3)
+	history, err = store.History(delivered)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+	history, err = store.History(sent)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+// TestList проверяет выборку посылок по фильтрам
+func TestList(t *testing.T) {
+	t.Parallel()
+	// prepare
+	store := newTestStore(t)
+	client := randRange.Intn(10_000_000)
+	now := time.Now()
+
+	// add
+	// три посылки одного клиента, одна из них отправлена, а первая зарегистрирована вчера;
+	// посылка того же клиента в другой компании в выборку не попадает
+	var ids []int
+	for i := 0; i < 3; i++ {
+		parcel := newParcelFixture().client(client).createdAt(now)
+		if i == 0 {
+			parcel = parcel.createdAt(now.AddDate(0, 0, -1))
+		}
+		if i == 1 {
+			parcel = parcel.status(ParcelStatusSent)
+		}
+		ids = append(ids, mustAddParcel(t, store, parcel.build()).Number)
+	}
+	mustAddParcel(t, store, newParcelFixture().client(client).tenant("other").build())
+
+	// list
+	// фильтр по клиенту возвращает все посылки в порядке номеров
+	parcels, err := store.List(ListOptions{Client: client})
+	require.NoError(t, err)
+	require.Len(t, parcels, 3)
+	for i, p := range parcels {
+		assert.Equal(t, ids[i], p.Number)
+	}
+
+	// фильтры по клиенту и статусу складываются
+	parcels, err = store.List(ListOptions{Client: client, Status: ParcelStatusSent})
+	require.NoError(t, err)
+	require.Len(t, parcels, 1)
+	assert.Equal(t, ids[1], parcels[0].Number)
+
+	// limit ограничивает размер выборки
+	parcels, err = store.List(ListOptions{Client: client, Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, parcels, 2)
+
+	// from ограничивает время регистрации
+	parcels, err = store.List(ListOptions{Client: client, From: now.Add(-time.Hour)})
+	require.NoError(t, err)
+	assert.Len(t, parcels, 2)
+}
+
+// TestGetByStatus проверяет получение посылок по статусу
+func TestGetByStatus(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	id, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+	require.NoError(t, store.SetStatus(id, ParcelStatusSent))
+
+	// get by status
+	parcels, err := store.GetByStatus(ParcelStatusSent)
+	require.NoError(t, err)
+
+	// check
+	numbers := make([]int, 0, len(parcels))
+	for _, p := range parcels {
+		assert.Equal(t, ParcelStatusSent, p.Status)
+		numbers = append(numbers, p.Number)
+	}
+	assert.Contains(t, numbers, id)
+	assert.True(t, slices.IsSorted(numbers))
+
+	parcels, err = store.GetByStatus(ParcelStatusRegistered)
+	require.NoError(t, err)
+	for _, p := range parcels {
+		assert.NotEqual(t, id, p.Number)
+	}
+}
+
+// TestParcelIndexes проверяет, что выборки по клиенту, статусу и времени
+// регистрации идут по индексам, а не полным просмотром таблицы
+func TestParcelIndexes(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	plan := func(query string, args ...any) string {
+		t.Helper()
+		rows, err := db.Query("EXPLAIN QUERY PLAN "+query, args...)
+		require.NoError(t, err)
+		defer rows.Close()
+		var steps []string
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			require.NoError(t, rows.Scan(&id, &parent, &unused, &detail))
+			steps = append(steps, detail)
+		}
+		require.NoError(t, rows.Err())
+		return strings.Join(steps, "\n")
+	}
+
+	// check
+	byClient := plan(queryParcelsByClient, sql.Named("client", 1), sql.Named("tenant", DefaultTenant))
+	assert.Contains(t, byClient, "USING INDEX parcel_client_idx")
+	// номер посылки хранится в индексе, сортировка не нужна
+	assert.NotContains(t, byClient, "TEMP B-TREE")
+
+	byStatus := plan(queryParcelsByStatus, sql.Named("status", ParcelStatusSent), sql.Named("tenant", DefaultTenant))
+	assert.Contains(t, byStatus, "USING INDEX parcel_status_idx")
+	assert.NotContains(t, byStatus, "TEMP B-TREE")
+
+	assert.Contains(t, plan("SELECT number FROM parcel WHERE created_at >= :from",
+		sql.Named("from", "2024-01-01T00:00:00Z")), "USING COVERING INDEX parcel_created_at_idx")
+}
+
+// FuzzAddress проверяет, что любой адрес либо отклоняется как ErrInvalidAddress, либо
+// сохраняется и читается без изменений при добавлении и смене адреса:
+// go test -run '^$' -fuzz FuzzAddress -fuzztime 30s .
+func FuzzAddress(f *testing.F) {
+	for _, seed := range []string{
+		"Псков, ул. Колотушкина, д. 5",
+		"東京都千代田区千代田1-1",
+		"شارع الملك فهد، الرياض",
+		"Zürich, Bahnhofstraße 1 🏠",
+		"'; DROP TABLE parcel; --",
+		`" OR 1=1 /* NEAR(a b) */`,
+		"‮евокслоП",
+		"ул. Ленина\nд. 5",
+		"\x00",
+		" ",
+		strings.Repeat("д", maxAddressLen+1),
+		"\xff\xfe",
+	} {
+		f.Add(seed)
+	}
+	store := newTestStore(f)
+	number := mustAddParcel(f, store, getTestParcel()).Number
+
+	f.Fuzz(func(t *testing.T, address string) {
+		err := validateAddress(address)
+		p := getTestParcel()
+		p.Address = address
+		id, addErr := store.Add(p)
+		setErr := store.SetAddress(number, address)
+		if err != nil {
+			assert.ErrorIs(t, err, ErrInvalidAddress)
+			assert.ErrorIs(t, addErr, ErrInvalidAddress)
+			assert.ErrorIs(t, setErr, ErrInvalidAddress)
+			return
+		}
+		require.NoError(t, addErr)
+		require.NoError(t, setErr)
+		for _, n := range []int{id, number} {
+			stored, err := store.Get(n)
+			require.NoError(t, err)
+			assert.Equal(t, address, stored.Address)
+		}
+	})
+}
+
+// transitionOps сколько случайных последовательностей смен статуса проверяет
+// FuzzStatusTransitions при обычном запуске тестов
+const transitionOps = 30
+
+// FuzzStatusTransitions выполняет над посылкой случайную последовательность смен статуса
+// и удалений и после каждого шага проверяет инварианты жизненного цикла: доставленная
+// посылка не возвращается в путь, в истории столько записей, сколько было смен статуса,
+// а удаляется только зарегистрированная посылка. Каждый байт входа — один шаг:
+// go test -run '^$' -fuzz FuzzStatusTransitions -fuzztime 30s .
+func FuzzStatusTransitions(f *testing.F) {
+	f.Add([]byte{1, 3, 0, 2, 1, 5})
+	f.Add([]byte{3, 0, 1, 2, 4, 5})
+	for range transitionOps {
+		steps := make([]byte, 1+randRange.Intn(20))
+		for i := range steps {
+			steps[i] = byte(randRange.Intn(256))
+		}
+		f.Add(steps)
+	}
+	store := newTestStore(f)
+
+	f.Fuzz(func(t *testing.T, steps []byte) {
+		id := mustAddParcel(t, store, getTestParcel()).Number
+		status, changes := ParcelStatusRegistered, 1
+
+		for _, b := range steps {
+			target := parcelStatuses[b/6%4]
+			var err error
+			switch b % 6 {
+			case 0, 1, 2, 3:
+				target = parcelStatuses[b%6]
+				err = store.SetStatus(id, target)
+			case 4:
+				err = store.SetStatusIf(id, parcelStatuses[b/24%4], target)
+				if errors.Is(err, ErrConflict) {
+					continue
+				}
+			case 5:
+				require.NoError(t, store.Delete(id))
+				_, err := store.Get(id)
+				if status == ParcelStatusRegistered {
+					// удалённая посылка исчезает вместе с историей
+					require.ErrorIs(t, err, sql.ErrNoRows)
+					history, err := store.History(id)
+					require.NoError(t, err)
+					assert.Empty(t, history)
+					return
+				}
+				// посылку в пути или доставленную удаление не трогает
+				require.NoError(t, err)
+				continue
+			}
+
+			if status == ParcelStatusDelivered {
+				// доставленная посылка не возвращается в путь, а повторная доставка ничего не меняет
+				if target != ParcelStatusDelivered {
+					require.ErrorIs(t, err, ErrInvalidTransition)
+				} else {
+					require.NoError(t, err)
+				}
+			} else {
+				require.NoError(t, err)
+				status = target
+				changes++
+			}
+
+			stored, err := store.Get(id)
+			require.NoError(t, err)
+			require.Equal(t, status, stored.Status)
+			history, err := store.History(id)
+			require.NoError(t, err)
+			require.Len(t, history, changes)
+			assert.Equal(t, status, history[len(history)-1].Status)
+		}
+	})
+}
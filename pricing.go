@@ -0,0 +1,254 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// PriorityStandard обычная доставка; так считаются посылки, зарегистрированные без расчёта стоимости
+	PriorityStandard = "standard"
+	// PriorityExpress срочная доставка
+	PriorityExpress = "express"
+)
+
+// anyZone зона тарифа, который действует для городов без собственного тарифа
+const anyZone = "*"
+
+// volumetricDivisor делитель объёмного веса: посылка объёмом 5000 см³ считается весом в 1 кг
+const volumetricDivisor = 5000
+
+var (
+	// ErrNoTariff возвращается, если для зоны доставки и срочности нет ни своего, ни общего тарифа
+	ErrNoTariff = errors.New("нет тарифа для доставки")
+	// ErrInvalidDimensions возвращается при неположительных весе или размерах посылки
+	ErrInvalidDimensions = errors.New("неверные вес или размеры посылки")
+	// ErrUnknownPriority возвращается при срочности доставки, которой нет в трекере
+	ErrUnknownPriority = errors.New("неизвестная срочность доставки")
+)
+
+const (
+	queryUpsertTariff = `INSERT INTO tariff (tenant_id, zone, priority, base, per_kg, days) VALUES (:tenant, :zone, :priority, :base, :per_kg, :days)
+		ON CONFLICT (tenant_id, zone, priority) DO UPDATE SET base = excluded.base, per_kg = excluded.per_kg, days = excluded.days`
+	queryTariffs = "SELECT zone, priority, base, per_kg, days FROM tariff WHERE tenant_id = :tenant ORDER BY zone, priority"
+	// queryFindTariff тариф зоны, а если его нет — общий тариф
+	queryFindTariff = `SELECT zone, priority, base, per_kg, days FROM tariff
+		WHERE tenant_id = :tenant AND zone IN (:zone, '` + anyZone + `') AND priority = :priority
+		ORDER BY zone = '` + anyZone + `' LIMIT 1`
+)
+
+// QuoteRequest срочность, вес и размеры посылки, по которым считается стоимость доставки
+type QuoteRequest struct {
+	// Priority срочность доставки; пустая — обычная
+	Priority    string
+	WeightGrams int
+	LengthCm    int
+	WidthCm     int
+	HeightCm    int
+}
+
+// validate проверяет срочность, вес и размеры и подставляет обычную срочность вместо пустой
+func (req *QuoteRequest) validate() error {
+	if req.Priority == "" {
+		req.Priority = PriorityStandard
+	}
+	if req.Priority != PriorityStandard && req.Priority != PriorityExpress {
+		return fmt.Errorf("%w: %s", ErrUnknownPriority, req.Priority)
+	}
+	if req.WeightGrams <= 0 || req.LengthCm <= 0 || req.WidthCm <= 0 || req.HeightCm <= 0 {
+		return ErrInvalidDimensions
+	}
+	return nil
+}
+
+// chargeableGrams оплачиваемый вес: фактический или объёмный, если он больше
+func (req QuoteRequest) chargeableGrams() int {
+	volumetric := req.LengthCm * req.WidthCm * req.HeightCm * 1000 / volumetricDivisor
+	return max(req.WeightGrams, volumetric)
+}
+
+// Tariff тариф доставки в зону со срочностью Priority; цены в копейках
+type Tariff struct {
+	// Zone город назначения в нижнем регистре или anyZone для остальных городов
+	Zone     string
+	Priority string
+	// Base стоимость доставки посылки, PerKg — каждого начатого килограмма оплачиваемого веса
+	Base  int64
+	PerKg int64
+	// Days обещанный срок доставки в днях от регистрации; 0 — срок не обещается
+	Days int
+}
+
+// price стоимость доставки посылки с оплачиваемым весом grams
+func (t Tariff) price(grams int) int64 {
+	kg := int64((grams + 999) / 1000)
+	return t.Base + t.PerKg*kg
+}
+
+// Quote расчёт стоимости доставки
+type Quote struct {
+	Zone            string
+	Priority        string
+	ChargeableGrams int
+	// Price стоимость в копейках
+	Price int64
+}
+
+// destinationZone зона доставки по адресу: город назначения в нижнем регистре
+func destinationZone(address string) string {
+	return strings.ToLower(addressCity(address))
+}
+
+// formatPrice записывает цену в копейках в рублях
+func formatPrice(kopecks int64) string {
+	return rubles(kopecks) + " руб."
+}
+
+// SetTariff добавляет или заменяет тариф компании из контекста хранилища
+func (s ParcelStore) SetTariff(t Tariff) error {
+	start := time.Now()
+	span := s.startSpan("SetTariff")
+	defer span.End()
+
+	err := s.withRetry("store.SetTariff", func(s ParcelStore) error {
+		tenant, err := s.ownTenant()
+		if err != nil {
+			return err
+		}
+		_, err = s.exec(nil, queryUpsertTariff,
+			sql.Named("tenant", tenant),
+			sql.Named("zone", t.Zone),
+			sql.Named("priority", t.Priority),
+			sql.Named("base", t.Base),
+			sql.Named("per_kg", t.PerKg),
+			sql.Named("days", t.Days))
+		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("SetTariff", start)
+	logResult(s.ctx, s.logger, "store.SetTariff", start, err, "zone", t.Zone, "priority", t.Priority, "base", t.Base, "per_kg", t.PerKg, "days", t.Days)
+	return err
+}
+
+// Tariffs возвращает тарифы компании из контекста хранилища по зонам и срочности
+func (s ParcelStore) Tariffs() ([]Tariff, error) {
+	defer s.metrics.observeQuery("Tariffs", time.Now())
+	span := s.startSpan("Tariffs")
+	defer span.End()
+
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	rows, err := s.readQuery(queryTariffs, sql.Named("tenant", tenant))
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer rows.Close()
+
+	var res []Tariff
+	for rows.Next() {
+		var t Tariff
+		err := rows.Scan(&t.Zone, &t.Priority, &t.Base, &t.PerKg, &t.Days)
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		res = append(res, t)
+	}
+	return res, spanError(span, rows.Err())
+}
+
+// FindTariff возвращает тариф доставки в зону zone со срочностью priority или общий тариф,
+// если у зоны своего нет; без обоих — ErrNoTariff
+func (s ParcelStore) FindTariff(zone, priority string) (Tariff, error) {
+	defer s.metrics.observeQuery("FindTariff", time.Now())
+	span := s.startSpan("FindTariff")
+	defer span.End()
+
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return Tariff{}, spanError(span, err)
+	}
+	var t Tariff
+	err = s.readRow(queryFindTariff,
+		sql.Named("tenant", tenant),
+		sql.Named("zone", zone),
+		sql.Named("priority", priority)).Scan(&t.Zone, &t.Priority, &t.Base, &t.PerKg, &t.Days)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("%w в %q со срочностью %s", ErrNoTariff, zone, priority)
+	}
+	return t, spanError(span, err)
+}
+
+// SetTariff задаёт тариф компании из ctx; доступно только администратору
+func (s ParcelService) SetTariff(ctx context.Context, t Tariff) error {
+	err := s.check(ctx, actionTariffs)
+	if err != nil {
+		return err
+	}
+	t.Zone = strings.ToLower(strings.TrimSpace(t.Zone))
+	if t.Zone == "" {
+		return errors.New("зона тарифа не указана")
+	}
+	if t.Priority != PriorityStandard && t.Priority != PriorityExpress {
+		return fmt.Errorf("%w: %s", ErrUnknownPriority, t.Priority)
+	}
+	if t.Base < 0 || t.PerKg < 0 {
+		return errors.New("цены тарифа не могут быть отрицательными")
+	}
+	if t.Days < 0 {
+		return errors.New("срок доставки тарифа не может быть отрицательным")
+	}
+	return s.store.WithContext(ctx).SetTariff(t)
+}
+
+// Tariffs возвращает тарифы компании из ctx
+func (s ParcelService) Tariffs(ctx context.Context) ([]Tariff, error) {
+	err := s.check(ctx, actionRead)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.WithContext(ctx).Tariffs()
+}
+
+// Quote считает стоимость доставки посылки на адрес address до регистрации
+func (s ParcelService) Quote(ctx context.Context, address string, req QuoteRequest) (Quote, error) {
+	err := s.check(ctx, actionRegister)
+	if err != nil {
+		return Quote{}, err
+	}
+	return s.quote(ctx, address, req)
+}
+
+// quote считает стоимость доставки по тарифу компании из ctx без проверки доступа
+func (s ParcelService) quote(ctx context.Context, address string, req QuoteRequest) (Quote, error) {
+	err := req.validate()
+	if err != nil {
+		return Quote{}, err
+	}
+	store := s.store.WithContext(ctx)
+	// зона по индексу точнее города
+	zone, err := store.ZoneOf(address)
+	if err != nil {
+		return Quote{}, err
+	}
+	if zone == "" {
+		zone = destinationZone(address)
+	}
+	t, err := store.FindTariff(zone, req.Priority)
+	if err != nil {
+		return Quote{}, err
+	}
+	grams := req.chargeableGrams()
+	return Quote{Zone: t.Zone, Priority: req.Priority, ChargeableGrams: grams, Price: t.price(grams)}, nil
+}
+
+// RegisterQuoted регистрирует посылку, как Register, и записывает в неё срочность, вес
+// и стоимость доставки по тарифу компании
+func (s ParcelService) RegisterQuoted(ctx context.Context, client int, address string, req QuoteRequest) (Parcel, error) {
+	return s.register(ctx, client, address, &req)
+}
@@ -0,0 +1,70 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestQuote проверяет расчёт стоимости по тарифам зоны и общему тарифу и её запись в посылку
+func TestQuote(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	tenant := fmt.Sprintf("pricing-%d", randRange.Intn(10_000_000))
+	ctx := WithTenant(context.Background(), tenant)
+	service := NewParcelService(NewParcelStore(db))
+
+	// тарифы задаёт только администратор
+	err := service.SetTariff(WithCaller(ctx, Caller{Role: RoleOperator}), Tariff{Zone: "Москва", Priority: PriorityStandard, Base: 30000, PerKg: 5000})
+	assert.ErrorIs(t, err, ErrForbidden)
+	require.NoError(t, service.SetTariff(ctx, Tariff{Zone: "Москва", Priority: PriorityStandard, Base: 30000, PerKg: 5000}))
+	require.NoError(t, service.SetTariff(ctx, Tariff{Zone: anyZone, Priority: PriorityStandard, Base: 50000, PerKg: 10000}))
+	tariffs, err := service.Tariffs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Tariff{
+		{Zone: anyZone, Priority: PriorityStandard, Base: 50000, PerKg: 10000},
+		{Zone: "москва", Priority: PriorityStandard, Base: 30000, PerKg: 5000},
+	}, tariffs)
+
+	// quote
+	// 1,2 кг фактического веса — оплачиваются 2 начатых килограмма
+	small := QuoteRequest{WeightGrams: 1200, LengthCm: 10, WidthCm: 10, HeightCm: 10}
+	q, err := service.Quote(ctx, "Москва, ул. Ленина, д. 1", small)
+	require.NoError(t, err)
+	assert.Equal(t, Quote{Zone: "москва", Priority: PriorityStandard, ChargeableGrams: 1200, Price: 40000}, q)
+
+	// лёгкая, но большая посылка оплачивается по объёмному весу: 50×40×30 см — 12 кг
+	q, err = service.Quote(ctx, "Тверь, ул. Советская, д. 2", QuoteRequest{WeightGrams: 500, LengthCm: 50, WidthCm: 40, HeightCm: 30})
+	require.NoError(t, err)
+	assert.Equal(t, Quote{Zone: anyZone, Priority: PriorityStandard, ChargeableGrams: 12000, Price: 170000}, q)
+
+	_, err = service.Quote(ctx, "Москва, ул. Ленина, д. 1", QuoteRequest{Priority: PriorityExpress, WeightGrams: 1200, LengthCm: 10, WidthCm: 10, HeightCm: 10})
+	assert.ErrorIs(t, err, ErrNoTariff)
+	_, err = service.Quote(ctx, "Москва, ул. Ленина, д. 1", QuoteRequest{WeightGrams: 1200})
+	assert.ErrorIs(t, err, ErrInvalidDimensions)
+	_, err = service.Quote(ctx, "Москва, ул. Ленина, д. 1", QuoteRequest{Priority: "overnight", WeightGrams: 1200, LengthCm: 1, WidthCm: 1, HeightCm: 1})
+	assert.ErrorIs(t, err, ErrUnknownPriority)
+
+	// register
+	p, err := service.RegisterQuoted(ctx, 1000, "Москва, ул. Ленина, д. 1", small)
+	require.NoError(t, err)
+	stored, err := service.Get(ctx, p.Number)
+	require.NoError(t, err)
+	assert.Equal(t, PriorityStandard, stored.Priority)
+	assert.Equal(t, 1200, stored.Weight)
+	assert.Equal(t, int64(40000), stored.Price)
+
+	// без расчёта у посылки нет стоимости
+	p, err = service.Register(ctx, 1000, "Москва, ул. Ленина, д. 1")
+	require.NoError(t, err)
+	stored, err = service.Get(ctx, p.Number)
+	require.NoError(t, err)
+	assert.Equal(t, PriorityStandard, stored.Priority)
+	assert.Zero(t, stored.Price)
+}
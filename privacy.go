@@ -0,0 +1,141 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ErasedAddress адрес посылок клиента, персональные данные которого удалены.
+// Посылки остаются в отчётах и истории, но адрес больше не восстановить.
+const ErasedAddress = "[удалено]"
+
+const (
+	queryClientParcelNumbers = "SELECT number FROM parcel WHERE client = :client AND tenant_id = :tenant"
+	queryEraseAddresses      = "UPDATE parcel SET address = :address WHERE client = :client AND tenant_id = :tenant"
+	queryEraseArchived       = "UPDATE parcel_archive SET address = :address WHERE client = :client AND tenant_id = :tenant"
+	// подписки в Telegram удаляются до обезличивания посылок, пока известно, чьи они
+	queryEraseSubscriptions = `DELETE FROM telegram_subscription
+		WHERE number IN (SELECT number FROM parcel WHERE client = :client AND tenant_id = :tenant)`
+	queryEraseTelegramLogins = `DELETE FROM telegram_login
+		WHERE key_hash IN (SELECT key_hash FROM api_key WHERE client = :client AND tenant_id = :tenant)`
+	queryErasePreferences = "DELETE FROM notification_preference WHERE client = :client AND tenant_id = :tenant"
+	queryEraseAPIKeys     = "DELETE FROM api_key WHERE client = :client AND tenant_id = :tenant"
+	queryEraseRole        = "DELETE FROM user_role WHERE subject = :client AND tenant_id = :tenant"
+	// координаты указывают на адрес доставки, поэтому удаляются вместе с ним
+	queryEraseCoordinates = `DELETE FROM parcel_geo WHERE number IN (
+		SELECT number FROM parcel WHERE client = :client AND tenant_id = :tenant
+		UNION SELECT number FROM parcel_archive WHERE client = :client AND tenant_id = :tenant)`
+	queryInsertErasure = `INSERT INTO client_erasure (client, parcels, erased_at, request_id, tenant_id)
+		VALUES (:client, :parcels, :erased_at, NULLIF(:request_id, ''), :tenant)`
+)
+
+// ClientErasure запись об удалении персональных данных клиента
+type ClientErasure struct {
+	Client int
+	// Parcels сколько посылок клиента обезличено, включая архивные
+	Parcels  int
+	ErasedAt string
+}
+
+// ForgetClient удаляет персональные данные клиента в одной транзакции: адреса его
+// посылок, в том числе архивных, заменяются на ErasedAddress, а получатели уведомлений,
+// подписки и входы в боте Telegram, API-ключи и роль клиента удаляются. Сами посылки
+// и история статусов остаются для отчётов. О каждом удалении в client_erasure
+// остаётся запись без персональных данных. Событий outbox удаление не создаёт:
+// адресов в них и так нет.
+func (s ParcelStore) ForgetClient(client int) (ClientErasure, error) {
+	start := time.Now()
+	span := s.startSpan("ForgetClient", attrClient.Int(client))
+	defer span.End()
+
+	var erasure ClientErasure
+	var numbers []int
+	err := s.withRetry("store.ForgetClient", func(s ParcelStore) error {
+		var err error
+		erasure, numbers, err = s.forgetClient(client)
+		return err
+	})
+	for _, number := range numbers {
+		s.invalidate(number)
+	}
+	spanError(span, err)
+	s.metrics.observeQuery("ForgetClient", start)
+	logResult(s.ctx, s.logger, "store.ForgetClient", start, err, "client", client, "parcels", erasure.Parcels)
+	return erasure, err
+}
+
+// forgetClient удаляет данные клиента и возвращает запись об удалении и номера
+// обезличенных посылок основной таблицы, кеш которых нужно сбросить
+func (s ParcelStore) forgetClient(client int) (ClientErasure, []int, error) {
+	erasure := ClientErasure{Client: client, ErasedAt: time.Now().UTC().Format(time.RFC3339)}
+	// идентификаторы клиентов у компаний свои, поэтому удаляются данные клиента одной компании
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return erasure, nil, err
+	}
+	tx, err := s.begin()
+	if err != nil {
+		return erasure, nil, err
+	}
+	defer tx.Rollback()
+
+	arg, tenantArg := sql.Named("client", client), sql.Named("tenant", tenant)
+	rows, err := s.query(tx, queryClientParcelNumbers, arg, tenantArg)
+	if err != nil {
+		return erasure, nil, err
+	}
+	var numbers []int
+	for rows.Next() {
+		var n int
+		err = rows.Scan(&n)
+		if err != nil {
+			rows.Close()
+			return erasure, nil, err
+		}
+		numbers = append(numbers, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return erasure, nil, err
+	}
+
+	for _, q := range []string{queryEraseSubscriptions, queryEraseTelegramLogins, queryErasePreferences, queryEraseAPIKeys, queryEraseRole, queryEraseCoordinates} {
+		_, err = s.exec(tx, q, arg, tenantArg)
+		if err != nil {
+			return erasure, nil, err
+		}
+	}
+	address := sql.Named("address", ErasedAddress)
+	_, err = s.exec(tx, queryEraseAddresses, arg, tenantArg, address)
+	if err != nil {
+		return erasure, nil, err
+	}
+	res, err := s.exec(tx, queryEraseArchived, arg, tenantArg, address)
+	if err != nil {
+		return erasure, nil, err
+	}
+	archived, err := res.RowsAffected()
+	if err != nil {
+		return erasure, nil, err
+	}
+	erasure.Parcels = len(numbers) + int(archived)
+
+	_, err = s.exec(tx, queryInsertErasure, arg, tenantArg,
+		sql.Named("parcels", erasure.Parcels),
+		sql.Named("erased_at", erasure.ErasedAt),
+		sql.Named("request_id", RequestIDFromContext(s.ctx)))
+	if err != nil {
+		return erasure, nil, err
+	}
+	return erasure, numbers, tx.Commit()
+}
+
+// ForgetClient удаляет персональные данные клиента; доступно только администратору
+func (s ParcelService) ForgetClient(ctx context.Context, client int) (ClientErasure, error) {
+	err := s.check(ctx, actionForget)
+	if err != nil {
+		return ClientErasure{}, err
+	}
+	return s.store.WithContext(ctx).ForgetClient(client)
+}
@@ -0,0 +1,77 @@
+package tracker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestForgetClient проверяет удаление персональных данных клиента
+func TestForgetClient(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	// пул из одного соединения, как в настройках по умолчанию: удаление идёт в одной транзакции
+	store := tracker.NewParcelStore(db, tracker.WithPool(tracker.PoolOptions{MaxOpenConns: 1, MaxIdleConns: 1}))
+	service := tracker.NewParcelService(store)
+	ctx := context.Background()
+	client := testsupport.Intn(10_000_000) + 1
+	add := func(client int) int {
+		t.Helper()
+		p := testsupport.NewParcel().Build()
+		p.Client = client
+		id, err := store.Add(p)
+		require.NoError(t, err)
+		return id
+	}
+	parcel := add(client)
+	other := add(client + 1)
+	archived := testsupport.Intn(10_000_000) + 10_000_000
+	_, err := db.Exec(`INSERT INTO parcel_archive (number, client, status, address, created_at, delivered_at, archived_at)
+		VALUES (?, ?, 'delivered', 'test', '2001-01-01T00:00:00Z', '2001-01-01T00:00:00Z', '2001-02-01T00:00:00Z')`, archived, client)
+	require.NoError(t, err)
+	require.NoError(t, store.SetNotificationPreference(tracker.NotificationPreference{
+		Client: client, Channel: tracker.ChannelEmail, Recipient: "client@example.com", Statuses: []string{tracker.ParcelStatusSent}}))
+	require.NoError(t, service.AssignRole(context.Background(), client, tracker.RoleClient))
+	key, err := service.IssueAPIKey(context.Background(), client)
+	require.NoError(t, err)
+
+	// forget
+	// клиенту удаление недоступно
+	_, err = service.ForgetClient(tracker.WithCaller(ctx, tracker.Caller{Client: client, Role: tracker.RoleClient}), client)
+	assert.ErrorIs(t, err, tracker.ErrForbidden)
+	erasure, err := service.ForgetClient(tracker.WithCaller(ctx, tracker.Caller{Role: tracker.RoleAdmin}), client)
+	require.NoError(t, err)
+
+	// check
+	assert.Equal(t, 2, erasure.Parcels)
+	stored, err := store.Get(parcel)
+	require.NoError(t, err)
+	assert.Equal(t, tracker.ErasedAddress, stored.Address)
+	assert.Equal(t, client, stored.Client)
+	var address string
+	require.NoError(t, db.QueryRow("SELECT address FROM parcel_archive WHERE number = ?", archived).Scan(&address))
+	assert.Equal(t, tracker.ErasedAddress, address)
+	// посылки других клиентов не меняются
+	stored, err = store.Get(other)
+	require.NoError(t, err)
+	assert.Equal(t, "test", stored.Address)
+
+	prefs, err := store.NotificationPreferences(client)
+	require.NoError(t, err)
+	assert.Empty(t, prefs)
+	_, err = service.Authenticate(context.Background(), key)
+	assert.ErrorIs(t, err, tracker.ErrUnauthenticated)
+
+	// запись об удалении остаётся
+	var parcels int
+	require.NoError(t, db.QueryRow("SELECT parcels FROM client_erasure WHERE client = ? ORDER BY id DESC", client).Scan(&parcels))
+	assert.Equal(t, 2, parcels)
+}
@@ -0,0 +1,83 @@
+package tracker
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// queryBuilder собирает запрос SELECT из необязательных частей. Условие фильтра
+// построитель составляет сам из колонки и оператора и сам называет его параметр,
+// поэтому фильтры складываются в любом сочетании без склейки строк вызывающим
+// и без параметров, у которых два значения или нет ни одного.
+type queryBuilder struct {
+	columns string
+	from    string
+	conds   []string
+	args    []any
+	// names занятые имена параметров
+	names map[string]bool
+	order string
+	limit int
+}
+
+// selectQuery начинает запрос колонок columns из from
+func selectQuery(columns, from string) *queryBuilder {
+	return &queryBuilder{columns: columns, from: from, names: map[string]bool{}}
+}
+
+// where добавляет готовое условие cond с его параметрами args, например tenantCond
+func (b *queryBuilder) where(cond string, args ...sql.NamedArg) *queryBuilder {
+	b.conds = append(b.conds, cond)
+	for _, a := range args {
+		b.names[a.Name] = true
+		b.args = append(b.args, a)
+	}
+	return b
+}
+
+// filter добавляет условие «column op значение». Параметр называется по колонке,
+// а если имя уже занято — с номером: created_at, created_at_2.
+func (b *queryBuilder) filter(column, op string, value any) *queryBuilder {
+	name := column
+	for i := 2; b.names[name]; i++ {
+		name = fmt.Sprintf("%s_%d", column, i)
+	}
+	return b.where(column+" "+op+" :"+name, sql.Named(name, value))
+}
+
+// orderBy задаёт порядок строк
+func (b *queryBuilder) orderBy(columns string) *queryBuilder {
+	b.order = columns
+	return b
+}
+
+// limitTo ограничивает число строк; 0 — без ограничения
+func (b *queryBuilder) limitTo(n int) *queryBuilder {
+	b.limit = n
+	return b
+}
+
+// whereClause возвращает условие WHERE с пробелом в начале или пустую строку
+// без условий; годится для подстановки в запрос-шаблон вроде searchQuery
+func (b *queryBuilder) whereClause() string {
+	if len(b.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.conds, " AND ")
+}
+
+// build возвращает текст запроса и его аргументы
+func (b *queryBuilder) build() (string, []any) {
+	var q strings.Builder
+	q.WriteString("SELECT " + b.columns + " FROM " + b.from + b.whereClause())
+	if b.order != "" {
+		q.WriteString(" ORDER BY " + b.order)
+	}
+	args := b.args
+	if b.limit > 0 {
+		q.WriteString(" LIMIT :limit")
+		args = append(args[:len(args):len(args)], sql.Named("limit", b.limit))
+	}
+	return q.String(), args
+}
@@ -0,0 +1,93 @@
+package tracker
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestQueryBuilder проверяет текст запроса и параметры при разных сочетаниях фильтров
+func TestQueryBuilder(t *testing.T) {
+	q, args := selectQuery("number", "parcel").build()
+	assert.Equal(t, "SELECT number FROM parcel", q)
+	assert.Empty(t, args)
+
+	// параметр второго условия по той же колонке получает номер
+	q, args = selectQuery("number", "parcel").
+		where(tenantCond, sql.Named("tenant", "acme")).
+		filter("created_at", ">=", "2024-01-01T00:00:00Z").
+		filter("created_at", "<", "2024-02-01T00:00:00Z").
+		orderBy("number").
+		limitTo(5).
+		build()
+	assert.Equal(t, "SELECT number FROM parcel WHERE "+tenantCond+
+		" AND created_at >= :created_at AND created_at < :created_at_2 ORDER BY number LIMIT :limit", q)
+	assert.Equal(t, []any{
+		sql.Named("tenant", "acme"),
+		sql.Named("created_at", "2024-01-01T00:00:00Z"),
+		sql.Named("created_at_2", "2024-02-01T00:00:00Z"),
+		sql.Named("limit", 5),
+	}, args)
+
+	// каждый параметр в тексте запроса имеет ровно одно значение при любом сочетании фильтров
+	from := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	for mask := range 1 << 6 {
+		var opts ListOptions
+		if mask&1 != 0 {
+			opts.Client = 7
+		}
+		if mask&2 != 0 {
+			opts.Status = ParcelStatusSent
+		}
+		if mask&4 != 0 {
+			opts.Zone = "msk"
+		}
+		if mask&8 != 0 {
+			opts.From = from
+		}
+		if mask&16 != 0 {
+			opts.To = from.AddDate(0, 1, 0)
+		}
+		if mask&32 != 0 {
+			opts.Limit = 10
+		}
+		q, args := opts.query(parcelColumns, DefaultTenant).orderBy("number").limitTo(opts.Limit).build()
+		names := map[string]int{}
+		for _, m := range queryParamPattern.FindAllStringSubmatch(q, -1) {
+			names[m[1]]++
+		}
+		require.Len(t, args, len(names), q)
+		for _, a := range args {
+			assert.Contains(t, names, a.(sql.NamedArg).Name, q)
+		}
+	}
+}
+
+// TestListFilters проверяет, что List сочетает фильтры
+func TestListFilters(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	store := newTestStore(t)
+	sent := mustAddParcel(t, store, newParcelFixture().client(7).status(ParcelStatusSent).build())
+	mustAddParcel(t, store, newParcelFixture().client(7).build())
+	mustAddParcel(t, store, newParcelFixture().client(8).status(ParcelStatusSent).build())
+
+	// list
+	got, err := store.List(ListOptions{Client: 7, Status: ParcelStatusSent})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, sent.Number, got[0].Number)
+
+	got, err = store.List(ListOptions{Status: ParcelStatusSent, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, sent.Number, got[0].Number)
+
+	got, err = store.List(ListOptions{Client: 7, From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+}
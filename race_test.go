@@ -0,0 +1,108 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// raceWorkers и raceOps сколько горутин и сколько операций каждой выполняет TestServiceConcurrency
+const (
+	raceWorkers = 8
+	raceOps     = 40
+)
+
+// TestServiceConcurrency одновременно регистрирует посылки, меняет статус и адрес одной
+// посылки и читает её через сервис с кешем, а затем проверяет, что кеш, история и сама
+// посылка согласованы. Гонки данных в сервисе и хранилище ищет запуск с детектором:
+// go test -race -run TestServiceConcurrency .
+func TestServiceConcurrency(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+	metrics := NewMetrics()
+	store := NewParcelStore(db, WithCache(NewLRUCache(100, 0)), WithStoreMetrics(metrics))
+	service := NewParcelService(store, WithMetrics(metrics), WithRateLimit(1e6, 1e6))
+	admin := WithCaller(context.Background(), Caller{Client: 1, Role: RoleAdmin})
+	p, err := service.Register(admin, 1000, "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+
+	changes, cancel := service.Watch(p.Number)
+	defer cancel()
+	watched := make(chan int)
+	go func() {
+		n := 0
+		for range changes {
+			n++
+		}
+		watched <- n
+	}()
+
+	// run
+	var wg sync.WaitGroup
+	for w := range raceWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range raceOps {
+				// у каждой операции свой контекст, как у отдельного запроса API
+				ctx := WithRequestID(admin, fmt.Sprintf("race-%d-%d", w, i))
+				status := parcelStatuses[randRange.Intn(len(parcelStatuses))]
+				switch randRange.Intn(6) {
+				case 0:
+					_, err := service.Register(ctx, 1000, fmt.Sprintf("Москва, ул. Гонок, д. %d", i))
+					assert.NoError(t, err)
+				case 1:
+					err := service.SetStatus(ctx, p.Number, status)
+					assert.True(t, err == nil || errors.Is(err, ErrInvalidTransition), err)
+				case 2:
+					got, err := service.Get(ctx, p.Number)
+					if assert.NoError(t, err) {
+						err = service.SetStatusIf(ctx, p.Number, got.Status, status)
+						assert.True(t, err == nil || errors.Is(err, ErrConflict) || errors.Is(err, ErrInvalidTransition), err)
+					}
+				case 3:
+					assert.NoError(t, service.ChangeAddress(ctx, p.Number, fmt.Sprintf("Псков, ул. Колотушкина, д. %d", i)))
+				case 4:
+					got, err := service.Get(ctx, p.Number)
+					if assert.NoError(t, err) {
+						assert.Contains(t, parcelStatuses, got.Status)
+					}
+				default:
+					_, err := service.History(ctx, p.Number)
+					assert.NoError(t, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// check
+	// посылка из кеша совпадает с посылкой в БД
+	cached, err := service.Get(admin, p.Number)
+	require.NoError(t, err)
+	stored, err := NewParcelStore(db).Get(p.Number)
+	require.NoError(t, err)
+	assert.Equal(t, stored, cached)
+
+	// история заканчивается текущим статусом, а после доставки статус не меняется
+	history, err := service.History(admin, p.Number)
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+	assert.Equal(t, stored.Status, history[len(history)-1].Status)
+	for i := 1; i < len(history); i++ {
+		if history[i-1].Status == ParcelStatusDelivered {
+			assert.Equal(t, ParcelStatusDelivered, history[i].Status)
+		}
+	}
+
+	// подписчик получает изменения после регистрации; медленному часть может не достаться
+	cancel()
+	assert.LessOrEqual(t, <-watched, len(history)-1)
+}
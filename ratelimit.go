@@ -0,0 +1,105 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrRateLimited возвращается, если пользователь превысил допустимую частоту запросов
+var ErrRateLimited = errors.New("слишком много запросов, повторите позже")
+
+// minLimiterIdle не раньше какого простоя token bucket пользователя удаляется
+const minLimiterIdle = time.Minute
+
+// rateLimiter ограничивает частоту запросов каждого пользователя
+// отдельным token bucket с одинаковыми rps и burst
+type rateLimiter struct {
+	rps   rate.Limit
+	burst int
+	// idle после какого простоя bucket пользователя удаляется: к этому времени он
+	// снова полон, и новый bucket при следующем запросе ничем от него не отличается
+	idle time.Duration
+
+	mu       sync.Mutex
+	limiters map[limiterKey]*limiterEntry
+	// swept когда limiters последний раз очищались от простаивающих bucket
+	swept time.Time
+}
+
+// limiterEntry token bucket пользователя и время его последнего запроса
+type limiterEntry struct {
+	lim  *rate.Limiter
+	seen time.Time
+}
+
+// limiterKey пользователь, у которого свой token bucket: идентификаторы
+// клиентов разных компаний могут совпадать
+type limiterKey struct {
+	tenant string
+	client int
+}
+
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	// время, за которое пустой bucket наполняется целиком
+	refill := time.Duration(float64(max(burst, 1)) / rps * float64(time.Second))
+	return &rateLimiter{
+		rps:      rate.Limit(rps),
+		burst:    burst,
+		idle:     max(refill, minLimiterIdle),
+		limiters: map[limiterKey]*limiterEntry{},
+		swept:    time.Now(),
+	}
+}
+
+// allow расходует токен пользователя из ctx.
+// Запросы без пользователя в контексте не ограничиваются.
+func (l *rateLimiter) allow(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	c, ok := CallerFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	if !l.limiter(limiterKey{tenant: c.Tenant, client: c.Client}, time.Now()).Allow() {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// limiter возвращает token bucket пользователя key, создавая его при первом запросе.
+// Не чаще раза в idle удаляет bucket пользователей, которые не обращались дольше idle,
+// чтобы карта не росла с каждым новым пользователем.
+func (l *rateLimiter) limiter(key limiterKey, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) >= l.idle {
+		for k, e := range l.limiters {
+			if now.Sub(e.seen) >= l.idle {
+				delete(l.limiters, k)
+			}
+		}
+		l.swept = now
+	}
+	e, ok := l.limiters[key]
+	if !ok {
+		e = &limiterEntry{lim: rate.NewLimiter(l.rps, l.burst)}
+		l.limiters[key] = e
+	}
+	e.seen = now
+	return e.lim
+}
+
+// WithRateLimit ограничивает каждого пользователя rps запросами в секунду
+// с кратковременными всплесками до burst запросов
+func WithRateLimit(rps float64, burst int) ServiceOption {
+	return func(s *ParcelService) {
+		s.limiter = newRateLimiter(rps, burst)
+	}
+}
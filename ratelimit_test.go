@@ -0,0 +1,65 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRateLimit проверяет ограничение частоты запросов каждого пользователя
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	// пополнение бакета настолько медленное, что за время теста токены не появятся
+	service := NewParcelService(NewParcelStore(db), WithRateLimit(0.001, 2))
+	owner := randRange.Intn(10_000_000)
+	hot := WithCaller(context.Background(), Caller{Client: owner, Role: RoleClient})
+	other := WithCaller(context.Background(), Caller{Client: owner + 1, Role: RoleClient})
+
+	// check
+	// после исчерпания burst запросы пользователя отклоняются, другие пользователи не затронуты
+	_, err := service.ClientParcels(hot, owner)
+	require.NoError(t, err)
+	_, err = service.ClientParcels(hot, owner)
+	require.NoError(t, err)
+	_, err = service.ClientParcels(hot, owner)
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	_, err = service.ClientParcels(other, owner+1)
+	assert.NoError(t, err)
+	// клиент с тем же идентификатором в другой компании — другой пользователь
+	otherTenant := WithCaller(context.Background(), Caller{Client: owner, Role: RoleClient, Tenant: "other"})
+	_, err = service.ClientParcels(otherTenant, owner)
+	assert.NoError(t, err)
+
+	// внутренние вызовы без пользователя не ограничиваются
+	_, err = service.ClientParcels(context.Background(), owner)
+	assert.NoError(t, err)
+}
+
+// TestRateLimitEviction проверяет, что token bucket пользователей, которые долго
+// не обращались, удаляются, а у активных пользователей сохраняются
+func TestRateLimitEviction(t *testing.T) {
+	// prepare
+	l := newRateLimiter(1, 2)
+	require.Equal(t, minLimiterIdle, l.idle)
+	now := l.swept
+	idle, active := limiterKey{client: 1}, limiterKey{client: 2}
+
+	// check
+	l.limiter(idle, now)
+	hot := l.limiter(active, now)
+	require.True(t, hot.AllowN(now, 2))
+	l.limiter(active, now.Add(l.idle/2))
+	assert.Len(t, l.limiters, 2)
+
+	// через idle простаивавший bucket удалён, а активный остался вместе с потраченными токенами
+	assert.Same(t, hot, l.limiter(active, now.Add(l.idle)))
+	assert.Len(t, l.limiters, 1)
+	assert.NotContains(t, l.limiters, idle)
+}
@@ -0,0 +1,169 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+)
+
+// Role роль пользователя трекера
+type Role string
+
+const (
+	// RoleClient отправитель: видит и меняет адреса только своих посылок
+	RoleClient Role = "client"
+	// RoleOperator оператор склада: регистрирует посылки и меняет их статусы и адреса
+	RoleOperator Role = "operator"
+	// RoleCourier курьер: только меняет статусы посылок
+	RoleCourier Role = "courier"
+	// RoleAdmin администратор: может всё, в том числе удалять посылки
+	RoleAdmin Role = "admin"
+)
+
+// ErrUnknownRole возвращается при назначении роли, которой нет в трекере
+var ErrUnknownRole = errors.New("неизвестная роль")
+
+// action операция сервиса, доступ к которой проверяется по роли
+type action int
+
+const (
+	actionRead action = iota
+	actionRegister
+	actionSetStatus
+	actionChangeAddress
+	actionDelete
+	actionDebug
+	actionWebhooks
+	actionNotifications
+	actionHandOff
+	actionImport
+	actionForget
+	actionMaintenance
+	actionRollback
+	actionRepack
+	actionTariffs
+	actionCOD
+	actionBilling
+	actionRoutes
+	actionAttachments
+	actionSavedSearches
+)
+
+// actionNames имена операций для логов
+var actionNames = map[action]string{
+	actionRead:          "read",
+	actionRegister:      "register",
+	actionSetStatus:     "set_status",
+	actionChangeAddress: "change_address",
+	actionDelete:        "delete",
+	actionDebug:         "debug",
+	actionWebhooks:      "webhooks",
+	actionNotifications: "notifications",
+	actionHandOff:       "hand_off",
+	actionImport:        "import",
+	actionForget:        "forget",
+	actionMaintenance:   "maintenance",
+	actionRollback:      "rollback",
+	actionRepack:        "repack",
+	actionTariffs:       "tariffs",
+	actionCOD:           "cod",
+	actionBilling:       "billing",
+	actionRoutes:        "routes",
+	actionAttachments:   "attachments",
+	actionSavedSearches: "saved_searches",
+}
+
+func (a action) String() string {
+	return actionNames[a]
+}
+
+// rolePermissions операции, разрешённые каждой роли
+var rolePermissions = map[Role]map[action]bool{
+	RoleClient: {
+		actionRead:          true,
+		actionRegister:      true,
+		actionChangeAddress: true,
+		actionWebhooks:      true,
+		actionNotifications: true,
+		actionAttachments:   true,
+	},
+	RoleOperator: {
+		actionRead:          true,
+		actionRegister:      true,
+		actionSetStatus:     true,
+		actionChangeAddress: true,
+		actionWebhooks:      true,
+		actionNotifications: true,
+		actionHandOff:       true,
+		actionImport:        true,
+		actionRepack:        true,
+		actionCOD:           true,
+		actionRoutes:        true,
+		actionAttachments:   true,
+		actionSavedSearches: true,
+	},
+	RoleCourier: {
+		actionRead:        true,
+		actionSetStatus:   true,
+		actionAttachments: true,
+	},
+	RoleAdmin: {
+		actionRead:          true,
+		actionRegister:      true,
+		actionSetStatus:     true,
+		actionChangeAddress: true,
+		actionDelete:        true,
+		actionDebug:         true,
+		actionWebhooks:      true,
+		actionNotifications: true,
+		actionHandOff:       true,
+		actionImport:        true,
+		actionForget:        true,
+		actionMaintenance:   true,
+		actionRollback:      true,
+		actionRepack:        true,
+		actionTariffs:       true,
+		actionCOD:           true,
+		actionBilling:       true,
+		actionRoutes:        true,
+		actionAttachments:   true,
+		actionSavedSearches: true,
+	},
+}
+
+// validRole проверяет, что роль известна трекеру
+func validRole(r Role) bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// authorize проверяет, что роли пользователя из ctx разрешена операция act.
+// Запросы без пользователя в контексте (внутренние вызовы, аутентификация отключена)
+// не ограничиваются.
+func authorize(ctx context.Context, act action) error {
+	c, ok := CallerFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if !rolePermissions[c.Role][act] {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// authorizeOwner проверяет, что клиент из ctx работает со своими посылками.
+// Остальные роли работают с посылками любых клиентов.
+func authorizeOwner(ctx context.Context, client int) error {
+	c, ok := CallerFromContext(ctx)
+	if ok && c.Role == RoleClient && c.Client != client {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// AssignRole назначает роль пользователю с идентификатором subject в компании из ctx
+func (s ParcelService) AssignRole(ctx context.Context, subject int, role Role) error {
+	if !validRole(role) {
+		return ErrUnknownRole
+	}
+	return s.store.WithContext(ctx).SetRole(subject, role)
+}
@@ -0,0 +1,88 @@
+package tracker_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestRolePermissions проверяет ограничения операций сервиса по ролям
+func TestRolePermissions(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	service := tracker.NewParcelService(tracker.NewParcelStore(db))
+
+	owner := testsupport.Intn(10_000_000)
+	client := tracker.WithCaller(context.Background(), tracker.Caller{Client: owner, Role: tracker.RoleClient})
+	stranger := tracker.WithCaller(context.Background(), tracker.Caller{Client: owner + 1, Role: tracker.RoleClient})
+	courier := tracker.WithCaller(context.Background(), tracker.Caller{Client: owner + 2, Role: tracker.RoleCourier})
+	operator := tracker.WithCaller(context.Background(), tracker.Caller{Client: owner + 3, Role: tracker.RoleOperator})
+	admin := tracker.WithCaller(context.Background(), tracker.Caller{Client: owner + 4, Role: tracker.RoleAdmin})
+
+	// client
+	// клиент регистрирует только свои посылки и меняет только их адреса
+	p, err := service.Register(client, owner, "test")
+	require.NoError(t, err)
+	_, err = service.Register(stranger, owner, "test")
+	assert.ErrorIs(t, err, tracker.ErrForbidden)
+
+	assert.NoError(t, service.ChangeAddress(client, p.Number, "new test address"))
+	assert.ErrorIs(t, service.ChangeAddress(stranger, p.Number, "other"), tracker.ErrForbidden)
+	_, err = service.Get(stranger, p.Number)
+	assert.ErrorIs(t, err, tracker.ErrForbidden)
+	assert.ErrorIs(t, service.SetStatus(client, p.Number, tracker.ParcelStatusSent), tracker.ErrForbidden)
+	assert.ErrorIs(t, service.Delete(client, p.Number), tracker.ErrForbidden)
+
+	// courier
+	// курьер только меняет статусы
+	assert.ErrorIs(t, service.ChangeAddress(courier, p.Number, "other"), tracker.ErrForbidden)
+	assert.ErrorIs(t, service.Delete(courier, p.Number), tracker.ErrForbidden)
+	assert.NoError(t, service.SetStatus(courier, p.Number, tracker.ParcelStatusRegistered))
+
+	// operator
+	// оператор работает с посылками любых клиентов, но не удаляет их
+	assert.NoError(t, service.ChangeAddress(operator, p.Number, "operator address"))
+	assert.ErrorIs(t, service.Delete(operator, p.Number), tracker.ErrForbidden)
+
+	// admin
+	// удалять посылки может только администратор
+	require.NoError(t, service.Delete(admin, p.Number))
+	_, err = service.Get(admin, p.Number)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+// TestAuthenticateRole проверяет, что роль пользователя берётся из БД
+func TestAuthenticateRole(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	service := tracker.NewParcelService(tracker.NewParcelStore(db))
+	subject := testsupport.Intn(10_000_000)
+
+	key, err := service.IssueAPIKey(context.Background(), subject)
+	require.NoError(t, err)
+
+	// check
+	// без назначенной роли пользователь считается клиентом
+	caller, err := service.Authenticate(context.Background(), key)
+	require.NoError(t, err)
+	assert.Equal(t, tracker.Caller{Client: subject, Role: tracker.RoleClient, Tenant: tracker.DefaultTenant}, caller)
+
+	require.NoError(t, service.AssignRole(context.Background(), subject, tracker.RoleCourier))
+	caller, err = service.Authenticate(context.Background(), key)
+	require.NoError(t, err)
+	assert.Equal(t, tracker.RoleCourier, caller.Role)
+
+	assert.ErrorIs(t, service.AssignRole(context.Background(), subject, tracker.Role("root")), tracker.ErrUnknownRole)
+}
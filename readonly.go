@@ -0,0 +1,60 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// ErrReadOnly возвращается любой операцией, которая меняет данные, пока трекер
+// работает в режиме только для чтения
+var ErrReadOnly = errors.New("трекер работает в режиме только для чтения")
+
+// WithReadOnly запускает хранилище в режиме только для чтения, например на реплике;
+// режим можно переключить и потом через SetReadOnly
+func WithReadOnly(enabled bool) StoreOption {
+	return func(s *ParcelStore) {
+		s.readOnly.Store(enabled)
+	}
+}
+
+// ReadOnly сообщает, работает ли хранилище в режиме только для чтения
+func (s ParcelStore) ReadOnly() bool {
+	return s.readOnly != nil && s.readOnly.Load()
+}
+
+// SetReadOnly включает или выключает режим только для чтения. Режим общий для всех копий
+// хранилища из WithContext; запись, начатая до включения, завершается.
+func (s ParcelStore) SetReadOnly(enabled bool) {
+	if s.readOnly.Swap(enabled) != enabled {
+		s.logger.Log(s.ctx, slog.LevelWarn, "режим только для чтения переключён",
+			withRequestID(s.ctx, []any{"op", "store.SetReadOnly", "read_only", enabled})...)
+	}
+}
+
+// checkWritable возвращает ErrReadOnly в режиме только для чтения
+func (s ParcelStore) checkWritable() error {
+	if s.ReadOnly() {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// ReadOnly сообщает, работает ли трекер в режиме только для чтения; доступно только администратору
+func (s ParcelService) ReadOnly(ctx context.Context) (bool, error) {
+	err := s.check(ctx, actionMaintenance)
+	if err != nil {
+		return false, err
+	}
+	return s.store.ReadOnly(), nil
+}
+
+// SetReadOnly переключает режим только для чтения на время обслуживания; доступно только администратору
+func (s ParcelService) SetReadOnly(ctx context.Context, enabled bool) error {
+	err := s.check(ctx, actionMaintenance)
+	if err != nil {
+		return err
+	}
+	s.store.WithContext(ctx).SetReadOnly(enabled)
+	return nil
+}
@@ -0,0 +1,67 @@
+package tracker_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestReadOnly проверяет, что в режиме только для чтения изменения отклоняются, а чтения работают
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	store := tracker.NewParcelStore(db)
+	service := tracker.NewParcelService(store)
+	p := testsupport.NewParcel().Build()
+	number, err := store.Add(p)
+	require.NoError(t, err)
+
+	// включить режим может только администратор
+	ctx := context.Background()
+	operator := tracker.WithCaller(ctx, tracker.Caller{Role: tracker.RoleOperator})
+	assert.ErrorIs(t, service.SetReadOnly(operator, true), tracker.ErrForbidden)
+	require.NoError(t, service.SetReadOnly(tracker.WithCaller(ctx, tracker.Caller{Role: tracker.RoleAdmin}), true))
+
+	// check
+	enabled, err := service.ReadOnly(ctx)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+	_, err = store.Add(p)
+	assert.ErrorIs(t, err, tracker.ErrReadOnly)
+	assert.ErrorIs(t, store.SetStatus(number, tracker.ParcelStatusSent), tracker.ErrReadOnly)
+	assert.ErrorIs(t, store.Delete(number), tracker.ErrReadOnly)
+	// режим общий для копий хранилища в другом контексте
+	assert.ErrorIs(t, store.WithContext(ctx).SetAddress(number, "new"), tracker.ErrReadOnly)
+	stored, err := store.Get(number)
+	require.NoError(t, err)
+	assert.Equal(t, tracker.ParcelStatusRegistered, stored.Status)
+
+	// HTTP API отвечает 503 на изменения и переключает режим через /admin/read-only
+	srv := httptest.NewServer(tracker.NewHTTPHandler(service))
+	defer srv.Close()
+	resp, err := http.Post(srv.URL+"/parcels", "application/json", strings.NewReader(`{"client":1,"address":"test"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/admin/read-only", strings.NewReader(`{"enabled":false}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.False(t, store.ReadOnly())
+	require.NoError(t, store.SetStatus(number, tracker.ParcelStatusSent))
+}
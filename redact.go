@@ -0,0 +1,226 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// redactedValue значение поля, скрытого целиком
+const redactedValue = "***"
+
+// Поля персональных данных, которые скрывает Redactor
+const (
+	fieldAddress   = "address"
+	fieldClient    = "client"
+	fieldRecipient = "recipient"
+	fieldCity      = "city"
+)
+
+// redactKeys поля персональных данных по ключам атрибутов лога и именам
+// параметров запросов; subject — клиент в ролях, chat — чат Telegram
+var redactKeys = map[string]string{
+	"address":   fieldAddress,
+	"client":    fieldClient,
+	"subject":   fieldClient,
+	"recipient": fieldRecipient,
+	"chat":      fieldRecipient,
+}
+
+// Redactor скрывает персональные данные по режимам из настроек redaction.
+// Нулевое значение ничего не скрывает.
+type Redactor struct {
+	cfg config.Redaction
+}
+
+// NewRedactor создаёт Redactor с режимами полей из настроек
+func NewRedactor(cfg config.Redaction) Redactor {
+	return Redactor{cfg: cfg}
+}
+
+// WithRedaction задаёт, как хранилище скрывает персональные данные в параметрах
+// медленных запросов, публичном отслеживании и выгрузке CSV
+func WithRedaction(r Redactor) StoreOption {
+	return func(s *ParcelStore) {
+		s.redactor = r
+	}
+}
+
+// mode возвращает режим поля field
+func (r Redactor) mode(field string) string {
+	switch field {
+	case fieldAddress:
+		return r.cfg.Address
+	case fieldClient:
+		return r.cfg.Client
+	case fieldRecipient:
+		return r.cfg.Recipient
+	case fieldCity:
+		return r.cfg.City
+	}
+	return config.RedactNone
+}
+
+// redact возвращает значение поля field, скрытое по его режиму
+func (r Redactor) redact(field, value string) string {
+	switch r.mode(field) {
+	case config.RedactMask:
+		switch field {
+		case fieldAddress:
+			return maskAddress(value)
+		case fieldRecipient:
+			return maskRecipient(value)
+		case fieldCity:
+			return maskPrefix(value)
+		default:
+			return maskSuffix(value)
+		}
+	case config.RedactRemove:
+		return redactedValue
+	}
+	return value
+}
+
+// Address возвращает адрес доставки, скрытый по режиму redaction.address
+func (r Redactor) Address(address string) string {
+	return r.redact(fieldAddress, address)
+}
+
+// Client возвращает идентификатор клиента, скрытый по режиму redaction.client
+func (r Redactor) Client(client int) string {
+	return r.redact(fieldClient, strconv.Itoa(client))
+}
+
+// City возвращает город для публичного отслеживания; в режиме remove город не показывается
+func (r Redactor) City(city string) string {
+	if r.mode(fieldCity) == config.RedactRemove {
+		return ""
+	}
+	return r.redact(fieldCity, city)
+}
+
+// maskAddress оставляет от адреса вида «Город, улица, дом» только город
+func maskAddress(address string) string {
+	city, _, ok := strings.Cut(address, ",")
+	if !ok {
+		return maskPrefix(address)
+	}
+	return strings.TrimSpace(city) + ", " + redactedValue
+}
+
+// maskRecipient оставляет от почты первую букву и домен, от телефона и чата — последние две цифры
+func maskRecipient(recipient string) string {
+	local, domain, ok := strings.Cut(recipient, "@")
+	if !ok {
+		return maskSuffix(recipient)
+	}
+	return maskPrefix(local) + "@" + domain
+}
+
+// maskPrefix оставляет от значения первую букву
+func maskPrefix(value string) string {
+	r, size := utf8.DecodeRuneInString(value)
+	if size == 0 || size == len(value) {
+		return redactedValue
+	}
+	return string(r) + redactedValue
+}
+
+// maskSuffix оставляет от значения две последние буквы
+func maskSuffix(value string) string {
+	if utf8.RuneCountInString(value) <= 2 {
+		return redactedValue
+	}
+	runes := []rune(value)
+	return redactedValue + string(runes[len(runes)-2:])
+}
+
+// formatArg возвращает параметр запроса для лога, скрывая персональные данные
+func (r Redactor) formatArg(arg sql.NamedArg) string {
+	if field, ok := redactKeys[arg.Name]; ok {
+		return r.redact(field, fmt.Sprint(arg.Value))
+	}
+	return fmt.Sprint(arg.Value)
+}
+
+// csvValue возвращает значение колонки выгрузки CSV, скрытое, если включено redaction.csv
+func (r Redactor) csvValue(column, value string) string {
+	field, ok := redactKeys[column]
+	if !r.cfg.CSV || !ok {
+		return value
+	}
+	return r.redact(field, value)
+}
+
+// redactingLogger скрывает персональные данные в атрибутах записей
+// перед передачей их следующему логгеру
+type redactingLogger struct {
+	next     Logger
+	redactor Redactor
+}
+
+// NewRedactingLogger оборачивает logger: значения атрибутов client, subject, address,
+// recipient и chat скрываются по режимам redactor, а скрытые адреса и получатели
+// вырезаются и из текста ошибки в атрибуте error той же записи
+func NewRedactingLogger(logger Logger, redactor Redactor) Logger {
+	return redactingLogger{next: logger, redactor: redactor}
+}
+
+func (l redactingLogger) Log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	out := make([]any, len(args))
+	copy(out, args)
+	// pairs значения, скрытые в этой записи, и их замены для текста ошибки; идентификаторы
+	// клиентов в тексте не заменяются: короткое число совпало бы с посторонними цифрами
+	var pairs []string
+	errIndex := -1
+	for i := 0; i < len(out); i++ {
+		if attr, ok := out[i].(slog.Attr); ok {
+			if attr.Key == "error" {
+				errIndex = i
+			}
+			if field, ok := redactKeys[attr.Key]; ok {
+				value := attr.Value.String()
+				masked := l.redactor.redact(field, value)
+				out[i] = slog.String(attr.Key, masked)
+				if field != fieldClient && masked != value {
+					pairs = append(pairs, value, masked)
+				}
+			}
+			continue
+		}
+		key, ok := out[i].(string)
+		if !ok || i+1 == len(out) {
+			continue
+		}
+		i++
+		if key == "error" {
+			errIndex = i
+		}
+		if field, ok := redactKeys[key]; ok {
+			value := fmt.Sprint(out[i])
+			masked := l.redactor.redact(field, value)
+			out[i] = masked
+			if field != fieldClient && masked != value {
+				pairs = append(pairs, value, masked)
+			}
+		}
+	}
+	if errIndex >= 0 && len(pairs) > 0 {
+		switch v := out[errIndex].(type) {
+		case error:
+			out[errIndex] = errors.New(strings.NewReplacer(pairs...).Replace(v.Error()))
+		case string:
+			out[errIndex] = strings.NewReplacer(pairs...).Replace(v)
+		case slog.Attr:
+			out[errIndex] = slog.String(v.Key, strings.NewReplacer(pairs...).Replace(v.Value.String()))
+		}
+	}
+	l.next.Log(ctx, level, msg, out...)
+}
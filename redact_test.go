@@ -0,0 +1,77 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// TestRedactingLogger проверяет скрытие персональных данных в атрибутах и тексте ошибки
+func TestRedactingLogger(t *testing.T) {
+	// prepare
+	var buf bytes.Buffer
+	base, err := NewLogger(&buf, "info", "text")
+	require.NoError(t, err)
+	redactor := NewRedactor(config.Redaction{Address: config.RedactMask, Client: config.RedactMask,
+		Recipient: config.RedactMask, City: config.RedactNone})
+	logger := NewRedactingLogger(base, redactor)
+
+	// log
+	logger.Log(context.Background(), slog.LevelError, "не удалось отправить уведомление",
+		"client", 123456, "recipient", "ivan@example.com", "address", "Псков, ул. Колотушкина, д. 5",
+		"error", errors.New("550 ivan@example.com: нет такого ящика"))
+
+	// check
+	out := buf.String()
+	assert.Contains(t, out, "client=***56")
+	assert.Contains(t, out, "recipient=i***@example.com")
+	assert.Contains(t, out, `address="Псков, ***"`)
+	assert.Contains(t, out, `error="550 i***@example.com: нет такого ящика"`)
+	assert.NotContains(t, out, "ivan")
+	assert.NotContains(t, out, "Колотушкина")
+
+	// режим remove скрывает поле целиком, none оставляет как есть
+	redactor = NewRedactor(config.Redaction{Address: config.RedactRemove, Client: config.RedactNone})
+	assert.Equal(t, "***", redactor.Address("Псков, ул. Колотушкина, д. 5"))
+	assert.Equal(t, "123456", redactor.Client(123456))
+	assert.Equal(t, "+79991234567", Redactor{}.redact(fieldRecipient, "+79991234567"))
+}
+
+// TestRedactTracking проверяет скрытие города в публичном отслеживании и данных в выгрузке CSV
+func TestRedactTracking(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	redactor := NewRedactor(config.Redaction{Address: config.RedactMask, Client: config.RedactRemove,
+		City: config.RedactRemove, CSV: true})
+	store := NewParcelStore(db, WithRedaction(redactor))
+	service := NewParcelService(store)
+	ctx := WithCaller(context.Background(), Caller{Role: RoleAdmin})
+	p, err := service.Register(ctx, randRange.Intn(10_000_000)+1, "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+	stored, err := store.Get(p.Number)
+	require.NoError(t, err)
+
+	// track
+	view, err := service.Track(context.Background(), stored.TrackingToken)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = store.ExportCSV(&buf, CSVExportOptions{ListOptions: ListOptions{Client: p.Client}, Columns: []string{"number", "client", "address"}})
+	require.NoError(t, err)
+
+	// check
+	assert.Empty(t, view.City)
+	assert.Contains(t, buf.String(), `***,"Псков, ***"`)
+	assert.NotContains(t, buf.String(), "Колотушкина")
+	// хранилище по-прежнему отдаёт полный адрес
+	assert.Equal(t, "Псков, ул. Колотушкина, д. 5", stored.Address)
+}
@@ -0,0 +1,73 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix префикс ключей посылок в Redis
+const redisKeyPrefix = "tracker:parcel:"
+
+// RedisCache кеш посылок в Redis, общий для нескольких экземпляров трекера:
+// изменение через любой экземпляр сбрасывает запись для всех.
+// Сбои Redis не ломают чтение: они пишутся в лог, а обращение считается промахом.
+type RedisCache struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+	logger Logger
+}
+
+// NewRedisCache создаёт кеш в Redis с записями, живущими ttl
+func NewRedisCache(client redis.UniversalClient, ttl time.Duration, logger Logger) *RedisCache {
+	return &RedisCache{client: client, ttl: ttl, logger: logger}
+}
+
+func redisKey(number int) string {
+	return redisKeyPrefix + strconv.Itoa(number)
+}
+
+// Get возвращает посылку из Redis, если она там есть
+func (c *RedisCache) Get(ctx context.Context, number int) (Parcel, bool) {
+	b, err := c.client.Get(ctx, redisKey(number)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Parcel{}, false
+	}
+	if err != nil {
+		c.logger.Log(ctx, slog.LevelWarn, "кеш Redis недоступен", "op", "cache.Get", "number", number, "error", err)
+		return Parcel{}, false
+	}
+
+	var p Parcel
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		c.logger.Log(ctx, slog.LevelWarn, "повреждённая запись кеша Redis", "op", "cache.Get", "number", number, "error", err)
+		return Parcel{}, false
+	}
+	return p, true
+}
+
+// Set кладёт посылку в Redis
+func (c *RedisCache) Set(ctx context.Context, p Parcel) {
+	b, err := json.Marshal(p)
+	if err == nil {
+		err = c.client.Set(ctx, redisKey(p.Number), b, c.ttl).Err()
+	}
+	if err != nil {
+		c.logger.Log(ctx, slog.LevelWarn, "кеш Redis недоступен", "op", "cache.Set", "number", p.Number, "error", err)
+	}
+}
+
+// Invalidate удаляет посылку из Redis. Если Redis недоступен, запись останется
+// до истечения срока жизни.
+func (c *RedisCache) Invalidate(ctx context.Context, number int) {
+	err := c.client.Del(ctx, redisKey(number)).Err()
+	if err != nil {
+		c.logger.Log(ctx, slog.LevelError, "не удалось сбросить запись кеша Redis", "op", "cache.Invalidate", "number", number, "error", err)
+	}
+}
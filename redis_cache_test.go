@@ -0,0 +1,50 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRedisCache проверяет, что два хранилища с общим Redis видят сброс записи друг друга
+func TestRedisCache(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	db := openTestDB(t)
+
+	first := NewParcelStore(db, WithCache(NewRedisCache(client, time.Minute, discardLogger{})))
+	second := NewParcelStore(db, WithCache(NewRedisCache(client, time.Minute, discardLogger{})))
+
+	id, err := first.Add(getTestParcel())
+	require.NoError(t, err)
+	_, err = first.Get(id)
+	require.NoError(t, err)
+
+	// check
+	assert.True(t, mr.Exists(redisKey(id)))
+	assert.Equal(t, time.Minute, mr.TTL(redisKey(id)))
+
+	require.NoError(t, second.SetStatus(id, ParcelStatusSent))
+	assert.False(t, mr.Exists(redisKey(id)))
+	stored, err := first.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusSent, stored.Status)
+
+	// при недоступном Redis чтение идёт в БД
+	mr.Close()
+	stored, err = first.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, id, stored.Number)
+	_, ok := NewRedisCache(client, time.Minute, discardLogger{}).Get(context.Background(), id)
+	assert.False(t, ok)
+}
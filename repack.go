@@ -0,0 +1,390 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrInvalidRepack возвращается, если посылки нельзя объединить или разделить
+	ErrInvalidRepack = errors.New("посылки нельзя перепаковать")
+	// ErrMergedParcel возвращается при попытке отдельно изменить посылку, объединённую
+	// с другой: её статус следует за посылкой, в составе которой она едет
+	ErrMergedParcel = errors.New("посылка объединена с другой")
+)
+
+// Виды связей между посылками
+const (
+	// LinkMerge посылка Child объединена в посылку Parent и едет в её составе
+	LinkMerge = "merge"
+	// LinkSplit посылка Child выделена из посылки Parent при разделении
+	LinkSplit = "split"
+)
+
+// maxSplit на сколько частей можно разделить посылку за раз
+const maxSplit = 100
+
+const (
+	queryMergedInto = "SELECT parent FROM parcel_link WHERE child = :number AND kind = '" + LinkMerge + "'"
+	queryMergeLinks = "SELECT EXISTS (SELECT 1 FROM parcel_link WHERE kind = '" + LinkMerge + "' AND (parent = :number OR child = :number))"
+	// посылки, объединённые в :number, в той же компании
+	queryMergedParcels = `SELECT p.number, p.client, p.status FROM parcel p JOIN parcel_link l ON l.child = p.number
+		WHERE l.parent = :number AND l.kind = '` + LinkMerge + `' AND p.tenant_id = :tenant ORDER BY p.number`
+	queryInsertLink = `INSERT INTO parcel_link (parent, child, kind, linked_at, request_id)
+		VALUES (:parent, :child, :kind, :linked_at, NULLIF(:request_id, ''))`
+	queryDeleteMergeLinks = "DELETE FROM parcel_link WHERE kind = '" + LinkMerge + "' AND (parent = :number OR child = :number)"
+	queryParcelLinks      = `SELECT parent, child, kind FROM parcel_link WHERE (parent = :number OR child = :number)
+		AND EXISTS (SELECT 1 FROM parcel WHERE number = :number AND ` + tenantCond + `) ORDER BY parent, child`
+	queryCopyHistory = `INSERT INTO parcel_history (number, status, changed_at, request_id)
+		SELECT :child, status, changed_at, request_id FROM parcel_history WHERE number = :number ORDER BY id`
+)
+
+// ParcelLink связь между посылками после объединения или разделения
+type ParcelLink struct {
+	Parent int
+	Child  int
+	// Kind LinkMerge или LinkSplit
+	Kind string
+}
+
+// mergedInto возвращает номер посылки, в которую объединена посылка number, или 0
+func (s ParcelStore) mergedInto(tx *sql.Tx, number int) (int, error) {
+	var parent int
+	err := s.queryRow(tx, queryMergedInto, sql.Named("number", number)).Scan(&parent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return parent, err
+}
+
+// checkNotMerged возвращает ErrMergedParcel, если посылка объединена с другими
+// или другие объединены в неё
+func (s ParcelStore) checkNotMerged(tx *sql.Tx, number int) error {
+	var merged bool
+	err := s.queryRow(tx, queryMergeLinks, sql.Named("number", number)).Scan(&merged)
+	if err != nil {
+		return err
+	}
+	if merged {
+		return fmt.Errorf("%w: № %d", ErrMergedParcel, number)
+	}
+	return nil
+}
+
+// propagateStatus переносит смену статуса посылки number с old на status на посылки,
+// объединённые в неё, в транзакции tx и возвращает их изменения для подписчиков
+func (s ParcelStore) propagateStatus(tx *sql.Tx, number int, tenant, old, status, changedAt string) ([]ParcelChange, error) {
+	rows, err := s.query(tx, queryMergedParcels, sql.Named("number", number), sql.Named("tenant", tenant))
+	if err != nil {
+		return nil, err
+	}
+	type merged struct {
+		number, client int
+		status         string
+	}
+	var children []merged
+	for rows.Next() {
+		var m merged
+		err = rows.Scan(&m.number, &m.client, &m.status)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		children = append(children, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var changes []ParcelChange
+	for _, m := range children {
+		// ожидаемая версия из контекста относится к самой посылке, а не к объединённым
+		_, err = s.exec(tx, queryUpdateStatus,
+			sql.Named("status", status),
+			sql.Named("changed_at", changedAt),
+			sql.Named("number", m.number),
+			sql.Named("version", 0),
+			sql.Named("tenant", tenant))
+		if err != nil {
+			return nil, err
+		}
+		change := ParcelChange{Number: m.number, Status: status, ChangedAt: changedAt}
+		entry := change
+		entry.RequestID = RequestIDFromContext(s.ctx)
+		err = s.addHistory(tx, entry)
+		if err != nil {
+			return nil, err
+		}
+		err = s.addOutbox(tx, OutboxEvent{Type: EventParcelStatusChanged, Number: m.number, Client: m.client,
+			Status: status, PreviousStatus: m.status, Tenant: tenant})
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// Merge объединяет посылки numbers в первую из них, например при перепаковке на складе.
+// Посылки должны принадлежать одному клиенту, ехать на один адрес и быть в одном статусе,
+// ещё не delivered. Объединённые посылки остаются со своими номерами и историей,
+// но их статус дальше меняется только вместе со статусом первой посылки.
+func (s ParcelStore) Merge(numbers []int) error {
+	if len(numbers) == 0 {
+		return fmt.Errorf("%w: не указаны посылки", ErrInvalidRepack)
+	}
+	start := time.Now()
+	span := s.startSpan("Merge", attrNumber.Int(numbers[0]))
+	defer span.End()
+
+	err := s.withRetry("store.Merge", func(s ParcelStore) error {
+		return s.merge(numbers)
+	})
+	for _, number := range numbers {
+		s.invalidate(number)
+	}
+	spanError(span, err)
+	s.metrics.observeQuery("Merge", start)
+	logResult(s.ctx, s.logger, "store.Merge", start, err, "number", numbers[0], "merged", numbers[1:])
+	return err
+}
+
+func (s ParcelStore) merge(numbers []int) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	parcels := make([]Parcel, 0, len(numbers))
+	for _, number := range numbers {
+		p, err := scanParcel(s.queryRow(tx, queryParcelByNumber, sql.Named("number", number), sql.Named("tenant", s.tenant())))
+		if err != nil {
+			return err
+		}
+		p, err = s.openParcel(p)
+		if err != nil {
+			return err
+		}
+		parcels = append(parcels, p)
+	}
+	parent := parcels[0]
+	if parent.Status == ParcelStatusDelivered {
+		return fmt.Errorf("%w: посылка № %d уже доставлена", ErrInvalidRepack, parent.Number)
+	}
+	// в первую посылку можно объединять и повторно, но сама она ни в какую не объединена
+	merged, err := s.mergedInto(tx, parent.Number)
+	if err != nil {
+		return err
+	}
+	if merged != 0 {
+		return fmt.Errorf("%w: посылка в составе № %d", ErrMergedParcel, merged)
+	}
+
+	linkedAt := time.Now().UTC().Format(time.RFC3339)
+	for _, p := range parcels[1:] {
+		switch {
+		case p.Client != parent.Client || p.Tenant != parent.Tenant:
+			return fmt.Errorf("%w: у посылок № %d и № %d разные клиенты", ErrInvalidRepack, parent.Number, p.Number)
+		case !sameAddress(p.Address, parent.Address):
+			return fmt.Errorf("%w: у посылок № %d и № %d разные адреса", ErrInvalidRepack, parent.Number, p.Number)
+		case p.Status != parent.Status:
+			return fmt.Errorf("%w: у посылок № %d и № %d разные статусы", ErrInvalidRepack, parent.Number, p.Number)
+		}
+		err = s.checkNotMerged(tx, p.Number)
+		if err != nil {
+			return err
+		}
+		_, err = s.exec(tx, queryInsertLink,
+			sql.Named("parent", parent.Number),
+			sql.Named("child", p.Number),
+			sql.Named("kind", LinkMerge),
+			sql.Named("linked_at", linkedAt),
+			sql.Named("request_id", RequestIDFromContext(s.ctx)))
+		if err != nil {
+			return err
+		}
+		err = s.addOutbox(tx, OutboxEvent{Type: EventParcelMerged, Number: p.Number, Client: p.Client,
+			Status: p.Status, Parent: parent.Number, Tenant: p.Tenant})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Split делит посылку number на n частей, например при перепаковке на складе, и возвращает
+// номера частей: первая — сама посылка, остальные регистрируются заново с тем же клиентом,
+// адресом и статусом, своими трекинг-токенами и копией её истории. Дальше части едут
+// независимо. Доставленную и объединённую с другими посылку разделить нельзя.
+func (s ParcelStore) Split(number, n int) ([]int, error) {
+	start := time.Now()
+	span := s.startSpan("Split", attrNumber.Int(number))
+	defer span.End()
+
+	var numbers []int
+	err := s.withRetry("store.Split", func(s ParcelStore) error {
+		var err error
+		numbers, err = s.split(number, n)
+		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("Split", start)
+	logResult(s.ctx, s.logger, "store.Split", start, err, "number", number, "parts", numbers)
+	if err == nil {
+		s.added(numbers[1:]...)
+	}
+	return numbers, err
+}
+
+func (s ParcelStore) split(number, n int) ([]int, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	p, err := scanParcel(s.queryRow(tx, queryParcelByNumber, sql.Named("number", number), sql.Named("tenant", s.tenant())))
+	if err != nil {
+		return nil, err
+	}
+	p, err = s.openParcel(p)
+	if err != nil {
+		return nil, err
+	}
+	if p.Status == ParcelStatusDelivered {
+		return nil, fmt.Errorf("%w: посылка № %d уже доставлена", ErrInvalidRepack, number)
+	}
+	err = s.checkNotMerged(tx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	requestID := RequestIDFromContext(s.ctx)
+	linkedAt := time.Now().UTC().Format(time.RFC3339)
+	numbers := []int{number}
+	for range n - 1 {
+		part := p
+		part.TrackingToken, err = newTrackingToken()
+		if err != nil {
+			return nil, err
+		}
+		id, err := s.insertParcel(tx, part, requestID)
+		if err != nil {
+			return nil, err
+		}
+		// вместо начальной записи часть получает всю историю исходной посылки
+		_, err = s.exec(tx, queryDeleteHistory, sql.Named("number", id))
+		if err != nil {
+			return nil, err
+		}
+		_, err = s.exec(tx, queryCopyHistory, sql.Named("child", id), sql.Named("number", number))
+		if err != nil {
+			return nil, err
+		}
+		_, err = s.exec(tx, queryInsertLink,
+			sql.Named("parent", number),
+			sql.Named("child", id),
+			sql.Named("kind", LinkSplit),
+			sql.Named("linked_at", linkedAt),
+			sql.Named("request_id", requestID))
+		if err != nil {
+			return nil, err
+		}
+		err = s.addOutbox(tx, OutboxEvent{Type: EventParcelSplit, Number: id, Client: p.Client,
+			Status: p.Status, Parent: number, Tenant: p.Tenant})
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, id)
+	}
+	return numbers, tx.Commit()
+}
+
+// Links возвращает связи посылки с посылками, с которыми она объединена или из которых выделена
+func (s ParcelStore) Links(number int) ([]ParcelLink, error) {
+	defer s.metrics.observeQuery("Links", time.Now())
+	span := s.startSpan("Links", attrNumber.Int(number))
+	defer span.End()
+
+	rows, err := s.query(nil, queryParcelLinks, sql.Named("number", number), sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer rows.Close()
+
+	var res []ParcelLink
+	for rows.Next() {
+		var l ParcelLink
+		err := rows.Scan(&l.Parent, &l.Child, &l.Kind)
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		res = append(res, l)
+	}
+	return res, spanError(span, rows.Err())
+}
+
+// Merge объединяет посылки numbers в первую из них и возвращает её
+func (s ParcelService) Merge(ctx context.Context, numbers []int) (Parcel, error) {
+	err := s.check(ctx, actionRepack)
+	if err != nil {
+		return Parcel{}, err
+	}
+	if len(numbers) < 2 {
+		return Parcel{}, fmt.Errorf("%w: нужно не меньше двух посылок", ErrInvalidRepack)
+	}
+	seen := map[int]bool{}
+	for _, number := range numbers {
+		if seen[number] {
+			return Parcel{}, fmt.Errorf("%w: посылка № %d указана дважды", ErrInvalidRepack, number)
+		}
+		seen[number] = true
+	}
+
+	store := s.store.WithContext(ctx)
+	err = store.Merge(numbers)
+	if err != nil {
+		return Parcel{}, err
+	}
+	return store.Get(numbers[0])
+}
+
+// Split делит посылку на n частей и возвращает их, начиная с самой посылки
+func (s ParcelService) Split(ctx context.Context, number, n int) ([]Parcel, error) {
+	err := s.check(ctx, actionRepack)
+	if err != nil {
+		return nil, err
+	}
+	if n < 2 || n > maxSplit {
+		return nil, fmt.Errorf("%w: посылку можно разделить на 2–%d части", ErrInvalidRepack, maxSplit)
+	}
+
+	store := s.store.WithContext(ctx)
+	numbers, err := store.Split(number, n)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]Parcel, 0, len(numbers))
+	for _, number := range numbers {
+		p, err := store.Get(number)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+// Links возвращает связи посылки после объединения и разделения; права те же, что на просмотр посылки
+func (s ParcelService) Links(ctx context.Context, number int) ([]ParcelLink, error) {
+	_, err := s.Get(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.WithContext(ctx).Links(number)
+}
@@ -0,0 +1,134 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMerge проверяет объединение посылок и смену статуса объединённых вместе с основной
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	// пул из одного соединения, как в настройках по умолчанию, и кеш посылок:
+	// объединённые посылки меняются в транзакции основной и должны уйти из кеша
+	store := NewParcelStore(db, WithPool(PoolOptions{MaxOpenConns: 1, MaxIdleConns: 1}),
+		WithCache(NewLRUCache(100, time.Minute)))
+	service := NewParcelService(store)
+	ctx := context.Background()
+	parcel := getTestParcel()
+	parcel.Client = randRange.Intn(10_000_000)
+	add := func(p Parcel) int {
+		t.Helper()
+		number, err := store.Add(p)
+		require.NoError(t, err)
+		return number
+	}
+	first, second, third := add(parcel), add(parcel), add(parcel)
+	other := parcel
+	other.Address = "другой адрес"
+	elsewhere := add(other)
+
+	// merge
+	// курьер не перепаковывает посылки, а посылки на разные адреса не объединяются
+	_, err := service.Merge(WithCaller(ctx, Caller{Role: RoleCourier}), []int{first, second})
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.ErrorIs(t, store.Merge(nil), ErrInvalidRepack)
+	_, err = service.Merge(ctx, []int{first, elsewhere})
+	assert.ErrorIs(t, err, ErrInvalidRepack)
+	_, err = service.Merge(ctx, []int{first, first})
+	assert.ErrorIs(t, err, ErrInvalidRepack)
+	var merged Parcel
+	withinDeadline(t, func() {
+		merged, err = service.Merge(ctx, []int{first, second, third})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, first, merged.Number)
+
+	// check
+	// объединённая посылка не меняется отдельно, а следует за основной
+	assert.ErrorIs(t, service.SetStatus(ctx, second, ParcelStatusSent), ErrMergedParcel)
+	assert.ErrorIs(t, service.ChangeAddress(ctx, second, "новый адрес"), ErrMergedParcel)
+	report, err := store.ImportParcels([]ManifestRow{{Line: 1, Number: second, Address: "новый адрес"}})
+	require.NoError(t, err)
+	require.Len(t, report.Errors, 1)
+	assert.ErrorIs(t, report.Errors[0].Err, ErrMergedParcel)
+	// объединённые посылки лежат в кеше со старым статусом
+	for _, number := range []int{second, third} {
+		_, err = store.Get(number)
+		require.NoError(t, err)
+	}
+	withinDeadline(t, func() {
+		err = service.SetStatus(ctx, first, ParcelStatusSent)
+	})
+	require.NoError(t, err)
+	for _, number := range []int{second, third} {
+		stored, err := store.Get(number)
+		require.NoError(t, err)
+		assert.Equal(t, ParcelStatusSent, stored.Status)
+		history, err := store.History(number)
+		require.NoError(t, err)
+		assert.Len(t, history, 2)
+	}
+	links, err := service.Links(ctx, second)
+	require.NoError(t, err)
+	assert.Equal(t, []ParcelLink{{Parent: first, Child: second, Kind: LinkMerge}}, links)
+	_, err = service.Split(ctx, first, 2)
+	assert.ErrorIs(t, err, ErrMergedParcel)
+}
+
+// TestSplit проверяет разделение посылки на части с копией истории
+func TestSplit(t *testing.T) {
+	// prepare
+	_, store := singleConnStore(t)
+	service := NewParcelService(store)
+	ctx := context.Background()
+	p, err := service.Register(ctx, randRange.Intn(10_000_000), "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+	require.NoError(t, service.SetStatus(ctx, p.Number, ParcelStatusSent))
+
+	// split
+	_, err = service.Split(ctx, p.Number, 1)
+	assert.ErrorIs(t, err, ErrInvalidRepack)
+	var parts []Parcel
+	withinDeadline(t, func() {
+		parts, err = service.Split(ctx, p.Number, 3)
+	})
+	require.NoError(t, err)
+
+	// check
+	require.Len(t, parts, 3)
+	assert.Equal(t, p.Number, parts[0].Number)
+	history, err := store.History(p.Number)
+	require.NoError(t, err)
+	for _, part := range parts[1:] {
+		assert.Equal(t, p.Client, part.Client)
+		assert.Equal(t, p.Address, part.Address)
+		assert.Equal(t, ParcelStatusSent, part.Status)
+		assert.NotEqual(t, p.TrackingToken, part.TrackingToken)
+		partHistory, err := store.History(part.Number)
+		require.NoError(t, err)
+		require.Len(t, partHistory, len(history))
+		for i := range history {
+			assert.Equal(t, history[i].Status, partHistory[i].Status)
+			assert.Equal(t, history[i].ChangedAt, partHistory[i].ChangedAt)
+		}
+	}
+	links, err := service.Links(ctx, p.Number)
+	require.NoError(t, err)
+	assert.Len(t, links, 2)
+
+	// части едут независимо
+	require.NoError(t, service.SetStatus(ctx, parts[1].Number, ParcelStatusDelivered))
+	stored, err := store.Get(parts[2].Number)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusSent, stored.Status)
+	_, err = service.Split(ctx, parts[1].Number, 2)
+	assert.ErrorIs(t, err, ErrInvalidRepack)
+}
@@ -0,0 +1,243 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	queryReportStatuses = `SELECT status, COUNT(*) FROM parcel
+		WHERE created_at >= :from AND created_at < :to AND ` + tenantCond + ` GROUP BY status`
+	// queryReportDelivery время от регистрации до первой отметки о доставке
+	// у посылок, доставленных за месяц, в секундах
+	queryReportDelivery = `SELECT COUNT(*), COALESCE(AVG(seconds), 0), COALESCE(MAX(seconds), 0) FROM (
+		SELECT (julianday(MIN(h.changed_at)) - julianday(p.created_at)) * 86400 AS seconds
+		FROM parcel p JOIN parcel_history h ON h.number = p.number AND h.status = 'delivered'
+		WHERE ` + tenantCond + `
+		GROUP BY p.number HAVING MIN(h.changed_at) >= :from AND MIN(h.changed_at) < :to)`
+	queryReportClients = `SELECT client, COUNT(*), SUM(status = 'delivered') FROM parcel
+		WHERE created_at >= :from AND created_at < :to AND ` + tenantCond + ` GROUP BY client ORDER BY COUNT(*) DESC, client`
+)
+
+// MonthlyReport операционный отчёт за месяц
+type MonthlyReport struct {
+	// Month первое число месяца в UTC
+	Month time.Time
+	// Statuses текущие статусы посылок, зарегистрированных за месяц
+	Statuses map[string]int
+	Delivery DeliveryStats
+	// Clients объёмы клиентов по убыванию числа посылок
+	Clients []ClientVolume
+}
+
+// DeliveryStats время доставки посылок, доставленных за месяц
+type DeliveryStats struct {
+	Delivered int
+	Average   time.Duration
+	Max       time.Duration
+}
+
+// ClientVolume посылки клиента, зарегистрированные за месяц, и сколько из них доставлено
+type ClientVolume struct {
+	Client    int
+	Parcels   int
+	Delivered int
+}
+
+// monthStart возвращает первое число месяца t в UTC
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// MonthlyReport собирает отчёт за месяц, в который попадает month
+func (s ParcelStore) MonthlyReport(month time.Time) (MonthlyReport, error) {
+	defer s.metrics.observeQuery("MonthlyReport", time.Now())
+	span := s.startSpan("MonthlyReport")
+	defer span.End()
+
+	r, err := s.monthlyReport(month)
+	return r, spanError(span, err)
+}
+
+func (s ParcelStore) monthlyReport(month time.Time) (MonthlyReport, error) {
+	r := MonthlyReport{Month: monthStart(month), Statuses: map[string]int{}}
+	args := []any{
+		sql.Named("from", r.Month.Format(time.RFC3339)),
+		sql.Named("to", r.Month.AddDate(0, 1, 0).Format(time.RFC3339)),
+		sql.Named("tenant", s.tenant()),
+	}
+
+	rows, err := s.readQuery(queryReportStatuses, args...)
+	if err != nil {
+		return r, err
+	}
+	for rows.Next() {
+		var status string
+		var n int
+		err = rows.Scan(&status, &n)
+		if err != nil {
+			rows.Close()
+			return r, err
+		}
+		r.Statuses[status] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return r, err
+	}
+
+	var avg, maximum float64
+	err = s.readRow(queryReportDelivery, args...).Scan(&r.Delivery.Delivered, &avg, &maximum)
+	if err != nil {
+		return r, err
+	}
+	r.Delivery.Average = time.Duration(avg * float64(time.Second)).Round(time.Second)
+	r.Delivery.Max = time.Duration(maximum * float64(time.Second)).Round(time.Second)
+
+	rows, err = s.readQuery(queryReportClients, args...)
+	if err != nil {
+		return r, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c ClientVolume
+		err = rows.Scan(&c.Client, &c.Parcels, &c.Delivered)
+		if err != nil {
+			return r, err
+		}
+		r.Clients = append(r.Clients, c)
+	}
+	return r, rows.Err()
+}
+
+// WriteXLSX записывает отчёт в книгу Excel с листами «Статусы», «Доставка» и «Клиенты»
+func (r MonthlyReport) WriteXLSX(w io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	// excelize создаёт книгу с листом Sheet1, он становится первым листом отчёта
+	const statuses, delivery, clients = "Статусы", "Доставка", "Клиенты"
+	err := f.SetSheetName("Sheet1", statuses)
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{delivery, clients} {
+		_, err = f.NewSheet(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	setRow := func(sheet string, row int, values ...any) {
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err == nil {
+			err = f.SetSheetRow(sheet, cell, &values)
+		}
+		errs = append(errs, err)
+	}
+
+	month := r.Month.Format("2006-01")
+	setRow(statuses, 1, "Посылки, зарегистрированные за "+month)
+	setRow(statuses, 2, "Статус", "Посылок")
+	total := 0
+	for i, status := range parcelStatuses {
+		setRow(statuses, i+3, status, r.Statuses[status])
+		total += r.Statuses[status]
+	}
+	setRow(statuses, len(parcelStatuses)+3, "Всего", total)
+
+	setRow(delivery, 1, "Посылки, доставленные за "+month)
+	setRow(delivery, 2, "Доставлено", "Среднее время, ч", "Наибольшее время, ч")
+	setRow(delivery, 3, r.Delivery.Delivered, r.Delivery.Average.Hours(), r.Delivery.Max.Hours())
+
+	setRow(clients, 1, "Посылки клиентов, зарегистрированные за "+month)
+	setRow(clients, 2, "Клиент", "Посылок", "Доставлено")
+	for i, c := range r.Clients {
+		setRow(clients, i+3, c.Client, c.Parcels, c.Delivered)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	_, err = f.WriteTo(w)
+	return err
+}
+
+// Reports формирует ежемесячные отчёты в каталоге dir
+type Reports struct {
+	store ParcelStore
+	dir   string
+	// now текущее время; подменяется в тестах
+	now func() time.Time
+}
+
+// NewReports создаёт формирование отчётов в каталоге dir
+func NewReports(store ParcelStore, dir string) *Reports {
+	return &Reports{store: store, dir: dir, now: time.Now}
+}
+
+// Path возвращает путь к файлу отчёта за месяц month
+func (r *Reports) Path(month time.Time) string {
+	return filepath.Join(r.dir, "report-"+monthStart(month).Format("2006-01")+".xlsx")
+}
+
+// Generate формирует отчёт за месяц month и возвращает путь к файлу.
+// Файл пишется под временным именем и переименовывается, поэтому
+// недописанный отчёт не попадает в каталог под именем готового.
+func (r *Reports) Generate(ctx context.Context, month time.Time) (string, error) {
+	report, err := r.store.WithContext(ctx).MonthlyReport(month)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(r.dir, 0o755)
+	if err != nil {
+		return "", err
+	}
+	path := r.Path(month)
+	tmp, err := os.CreateTemp(r.dir, ".report-*.xlsx")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = report.WriteXLSX(tmp)
+	if err != nil {
+		tmp.Close()
+		return "", err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp.Name(), path)
+}
+
+// GenerateDue формирует отчёт за прошедший месяц, если его файла ещё нет. Это
+// задача планировщика reports: при запуске раз в час отчёт появляется в первый
+// час нового месяца, а после ошибки формируется повторно.
+func (r *Reports) GenerateDue(ctx context.Context) error {
+	previous := monthStart(r.now()).AddDate(0, -1, 0)
+	_, err := os.Stat(r.Path(previous))
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	path, err := r.Generate(ctx, previous)
+	if err != nil {
+		return fmt.Errorf("отчёт за %s: %w", previous.Format("2006-01"), err)
+	}
+	r.store.logger.Log(ctx, slog.LevelInfo, "отчёт сформирован", "op", "reports.Generate", "path", path)
+	return nil
+}
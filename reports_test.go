@@ -0,0 +1,101 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// TestMonthlyReport проверяет сбор месячного отчёта, его запись в xlsx
+// и формирование по расписанию
+func TestMonthlyReport(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	// месяц в далёком будущем, чтобы в отчёт не попали посылки других тестов
+	month := time.Date(2100+randRange.Intn(800), time.March, 1, 0, 0, 0, 0, time.UTC)
+	at := func(days int) string {
+		return month.AddDate(0, 0, days).Format(time.RFC3339)
+	}
+	client := randRange.Intn(10_000_000) + 1
+	parcels := []parcelRecord{
+		{Client: client, Address: "test", Status: ParcelStatusDelivered, CreatedAt: at(0), History: []historyRecord{
+			{Status: ParcelStatusRegistered, ChangedAt: at(0)}, {Status: ParcelStatusDelivered, ChangedAt: at(1)},
+		}},
+		{Client: client, Address: "test", Status: ParcelStatusDelivered, CreatedAt: at(2), History: []historyRecord{
+			{Status: ParcelStatusRegistered, ChangedAt: at(2)}, {Status: ParcelStatusDelivered, ChangedAt: at(5)},
+		}},
+		{Client: client, Address: "test", Status: ParcelStatusSent, CreatedAt: at(3), History: []historyRecord{
+			{Status: ParcelStatusRegistered, ChangedAt: at(3)}, {Status: ParcelStatusSent, ChangedAt: at(4)},
+		}},
+		{Client: client + 1, Address: "test", Status: ParcelStatusRegistered, CreatedAt: at(10), History: []historyRecord{
+			{Status: ParcelStatusRegistered, ChangedAt: at(10)},
+		}},
+		// зарегистрирована в следующем месяце
+		{Client: client + 1, Address: "test", Status: ParcelStatusRegistered, CreatedAt: at(31), History: []historyRecord{
+			{Status: ParcelStatusRegistered, ChangedAt: at(31)},
+		}},
+	}
+	// посылки загружаются выгрузкой, чтобы задать время регистрации и доставки
+	var ndjson strings.Builder
+	base := 2_000_000_000 + randRange.Intn(100_000_000)
+	for i, p := range parcels {
+		p.Number = base + i
+		require.NoError(t, json.NewEncoder(&ndjson).Encode(p))
+	}
+	_, err := store.ImportJSON(strings.NewReader(ndjson.String()))
+	require.NoError(t, err)
+	defer func() {
+		db.Exec("DELETE FROM parcel_history WHERE number BETWEEN ? AND ?", base, base+len(parcels))
+		db.Exec("DELETE FROM parcel WHERE number BETWEEN ? AND ?", base, base+len(parcels))
+	}()
+
+	// report
+	report, err := store.MonthlyReport(month.AddDate(0, 0, 14))
+	require.NoError(t, err)
+
+	// check
+	assert.Equal(t, month, report.Month)
+	assert.Equal(t, map[string]int{ParcelStatusRegistered: 1, ParcelStatusSent: 1, ParcelStatusDelivered: 2}, report.Statuses)
+	assert.Equal(t, DeliveryStats{Delivered: 2, Average: 48 * time.Hour, Max: 72 * time.Hour}, report.Delivery)
+	assert.Equal(t, []ClientVolume{{Client: client, Parcels: 3, Delivered: 2}, {Client: client + 1, Parcels: 1}}, report.Clients)
+
+	reports := NewReports(store, t.TempDir())
+	reports.now = func() time.Time { return month.AddDate(0, 1, 3) }
+	require.NoError(t, reports.GenerateDue(context.Background()))
+	path := reports.Path(month)
+	require.FileExists(t, path)
+	// готовый отчёт не формируется повторно
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, reports.GenerateDue(context.Background()))
+	again, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, info.ModTime(), again.ModTime())
+
+	f, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, []string{"Статусы", "Доставка", "Клиенты"}, f.GetSheetList())
+	rows, err := f.GetRows("Статусы")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Всего", "4"}, rows[6])
+	rows, err = f.GetRows("Доставка")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2", "48", "72"}, rows[2])
+	rows, err = f.GetRows("Клиенты")
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, []string{fmt.Sprint(client), "3", "2"}, rows[2])
+}
@@ -0,0 +1,90 @@
+package tracker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDHeader заголовок HTTP и ключ метаданных gRPC с идентификатором запроса
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen наибольшая длина идентификатора, принимаемого от клиента;
+// более длинный заменяется сгенерированным
+const maxRequestIDLen = 128
+
+// attrRequestID атрибут спана с идентификатором запроса
+var attrRequestID = attribute.Key("request.id")
+
+type requestIDKey struct{}
+
+// WithRequestID возвращает контекст с идентификатором запроса id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext возвращает идентификатор запроса из контекста или пустую строку
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID генерирует случайный идентификатор запроса
+func newRequestID() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read не возвращает ошибок
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestID возвращает идентификатор, присланный клиентом, или новый, если клиент его не прислал
+func requestID(received string) string {
+	if received == "" || len(received) > maxRequestIDLen {
+		return newRequestID()
+	}
+	return received
+}
+
+// RequestIDMiddleware кладёт в контекст запроса идентификатор из заголовка X-Request-ID
+// (или новый) и возвращает его в том же заголовке ответа, чтобы обращение клиента
+// можно было сопоставить с логами и записями истории посылки
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestID(r.Header.Get(requestIDHeader))
+		w.Header().Set(requestIDHeader, id)
+		trace.SpanFromContext(r.Context()).SetAttributes(attrRequestID.String(id))
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+	})
+}
+
+// RequestIDServerOptions возвращает перехватчики gRPC, которые берут идентификатор запроса
+// из метаданных x-request-id (или создают новый) и возвращают его в заголовке ответа
+func RequestIDServerOptions() []grpc.ServerOption {
+	withID := func(ctx context.Context) context.Context {
+		var received string
+		md, _ := metadata.FromIncomingContext(ctx)
+		if ids := md.Get(requestIDHeader); len(ids) > 0 {
+			received = ids[0]
+		}
+
+		id := requestID(received)
+		grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, id))
+		trace.SpanFromContext(ctx).SetAttributes(attrRequestID.String(id))
+		return WithRequestID(ctx, id)
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return handler(withID(ctx), req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			return handler(srv, contextStream{ServerStream: ss, ctx: withID(ss.Context())})
+		}),
+	}
+}
@@ -0,0 +1,54 @@
+package tracker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRequestID проверяет, что идентификатор запроса возвращается в ответе
+// и сохраняется в истории статусов посылки
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	srv := httptest.NewServer(RequestIDMiddleware(NewHTTPHandler(NewParcelService(store))))
+	defer srv.Close()
+
+	id, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+
+	// set status
+	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/parcels/%d", srv.URL, id), strings.NewReader(`{"status":"sent"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(requestIDHeader, "support-42")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// check
+	assert.Equal(t, "support-42", resp.Header.Get(requestIDHeader))
+
+	history, err := store.History(id)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Empty(t, history[0].RequestID)
+	assert.Equal(t, "support-42", history[1].RequestID)
+
+	// без заголовка идентификатор генерируется
+	resp, err = http.Get(fmt.Sprintf("%s/parcels/%d", srv.URL, id))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Len(t, resp.Header.Get(requestIDHeader), 32)
+}
@@ -0,0 +1,103 @@
+package tracker
+
+import (
+	"errors"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// RetryPolicy повтор записи, упёршейся в блокировку БД: задержка перед попыткой n
+// растёт как BaseDelay·2ⁿ, но не больше MaxDelay, и выбирается случайно от нуля
+// до этого значения, чтобы конкурирующие записи не повторялись одновременно
+type RetryPolicy struct {
+	// MaxAttempts наибольшее число попыток, включая первую; 1 — без повторов
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy политика повторов хранилища по умолчанию
+var DefaultRetryPolicy = retryPolicy(config.Default().DB.Retry)
+
+// retryPolicy переводит настройки повторов в политику хранилища
+func retryPolicy(r config.Retry) RetryPolicy {
+	return RetryPolicy{MaxAttempts: r.MaxAttempts, BaseDelay: r.BaseDelay, MaxDelay: r.MaxDelay}
+}
+
+// WithRetryPolicy задаёт политику повторов записи при блокировке БД
+func WithRetryPolicy(p RetryPolicy) StoreOption {
+	return func(s *ParcelStore) {
+		s.retry = p
+	}
+}
+
+// isBusy сообщает, что операция не выполнена из-за блокировки БД другим соединением
+func isBusy(err error) bool {
+	var e *sqlite.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	// младший байт расширенного кода — основной код ошибки
+	switch e.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return true
+	default:
+		return false
+	}
+}
+
+// delay возвращает задержку перед повтором после попытки attempt (с нуля)
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.MaxDelay
+	if attempt < 30 {
+		d = min(p.BaseDelay<<attempt, p.MaxDelay)
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// withRetry выполняет запись op, повторяя её по политике хранилища, пока БД заблокирована.
+// write получает копию хранилища, контекст которой ограничен тайм-аутом записи на все
+// попытки вместе; по его истечении возвращается ErrStoreTimeout. Ожидание прерывается
+// и отменой контекста хранилища. В режиме только для чтения запись не выполняется
+// и возвращается ErrReadOnly.
+func (s ParcelStore) withRetry(op string, write func(s ParcelStore) error) error {
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+	ctx, cancel := s.withTimeout(s.timeouts.Write)
+	defer cancel()
+	s.ctx = ctx
+	attempts := max(s.retry.MaxAttempts, 1)
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			d := s.retry.delay(attempt - 1)
+			s.logger.Log(s.ctx, slog.LevelWarn, "БД заблокирована, запись повторяется",
+				withRequestID(s.ctx, []any{"op", op, "attempt", attempt + 1, "delay", d})...)
+
+			t := time.NewTimer(d)
+			select {
+			case <-s.ctx.Done():
+				t.Stop()
+				return timeoutError(ctx, errors.Join(err, s.ctx.Err()))
+			case <-t.C:
+			}
+		}
+
+		err = write(s)
+		if !isBusy(err) {
+			return timeoutError(ctx, err)
+		}
+	}
+	return timeoutError(ctx, err)
+}
@@ -0,0 +1,57 @@
+package tracker
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRetryOnBusy проверяет, что запись, упёршаяся в блокировку БД, повторяется,
+// а без повторов ошибка блокировки доходит до вызывающего
+func TestRetryOnBusy(t *testing.T) {
+	// prepare
+	// без busy_timeout SQLite сразу возвращает SQLITE_BUSY
+	cfg := testConfig.DB
+	cfg.Path = filepath.Join(t.TempDir(), "busy.db")
+	cfg.SQLite.BusyTimeout = 0
+
+	locker, err := OpenDB(cfg)
+	require.NoError(t, err)
+	defer locker.Close()
+	require.NoError(t, Migrate(locker))
+
+	db, err := OpenDB(cfg)
+	require.NoError(t, err)
+	defer db.Close()
+
+	noRetry := NewParcelStore(db, WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	store := NewParcelStore(db, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 50,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    20 * time.Millisecond,
+	}))
+
+	// lock
+	tx, err := locker.Begin()
+	require.NoError(t, err)
+
+	_, err = noRetry.Add(getTestParcel())
+	require.Error(t, err)
+	assert.True(t, isBusy(err))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		tx.Commit()
+	}()
+
+	// add
+	id, err := store.Add(getTestParcel())
+
+	// check
+	require.NoError(t, err)
+	_, err = store.Get(id)
+	assert.NoError(t, err)
+}
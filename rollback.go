@@ -0,0 +1,194 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNothingToRollback возвращается, если у посылки нет смены статуса, которую можно откатить:
+	// она только зарегистрирована или её история уже удалена
+	ErrNothingToRollback = errors.New("у посылки нет смены статуса для отката")
+	// ErrRollbackReason возвращается при откате без объяснения причины
+	ErrRollbackReason = errors.New("укажите причину отката")
+)
+
+const (
+	// две последние записи истории: откатываемая смена статуса и статус до неё
+	queryLastHistory      = "SELECT id, status, changed_at FROM parcel_history WHERE number = :number ORDER BY id DESC LIMIT 2"
+	queryDeleteHistoryRow = "DELETE FROM parcel_history WHERE id = :id"
+	// время доставки остаётся, только если посылка возвращается в delivered
+	queryRollbackStatus = `UPDATE parcel SET status = :status,
+		delivered_at = CASE WHEN :status = '` + ParcelStatusDelivered + `' THEN delivered_at END, version = version + 1
+		WHERE number = :number AND status = :current AND ` + versionCond + " AND " + tenantCond
+	queryInsertRollback = `INSERT INTO status_rollback
+		(number, from_status, to_status, changed_at, reason, actor_client, actor_role, rolled_back_at, request_id, tenant_id)
+		VALUES (:number, :from_status, :to_status, :changed_at, :reason, :actor_client, NULLIF(:actor_role, ''),
+			:rolled_back_at, NULLIF(:request_id, ''), :tenant)`
+)
+
+// StatusRollback запись об откате последней смены статуса посылки
+type StatusRollback struct {
+	Number int
+	// From статус, который откатили, To — статус, к которому вернулась посылка
+	From string
+	To   string
+	// ChangedAt когда был установлен откаченный статус
+	ChangedAt string
+	Reason    string
+	// Actor кто откатил статус; пустой, если вызов шёл без пользователя, например из CLI
+	Actor        Caller
+	RolledBackAt string
+}
+
+// Rollback отменяет последнюю смену статуса посылки, например ошибочное сканирование:
+// посылка возвращается к предыдущему статусу из истории, а запись об откаченном
+// статусе удаляется из истории. Кто, когда и почему откатил статус, записывается
+// в status_rollback; подписчики и outbox получают обычное событие смены статуса.
+func (s ParcelStore) Rollback(number int, reason string) (StatusRollback, error) {
+	start := time.Now()
+	span := s.startSpan("Rollback", attrNumber.Int(number))
+	defer span.End()
+
+	var rollback StatusRollback
+	err := s.withRetry("store.Rollback", func(s ParcelStore) error {
+		var err error
+		rollback, err = s.rollback(number, reason)
+		return err
+	})
+	s.invalidate(number)
+	spanError(span, err)
+	s.metrics.observeQuery("Rollback", start)
+	logResult(s.ctx, s.logger, "store.Rollback", start, err, "number", number,
+		"old_status", rollback.From, "new_status", rollback.To, "reason", reason)
+	return rollback, err
+}
+
+func (s ParcelStore) rollback(number int, reason string) (StatusRollback, error) {
+	rollback := StatusRollback{Number: number, Reason: reason, RolledBackAt: time.Now().UTC().Format(time.RFC3339)}
+	rollback.Actor, _ = CallerFromContext(s.ctx)
+	tx, err := s.begin()
+	if err != nil {
+		return rollback, err
+	}
+	defer tx.Rollback()
+
+	var tenant string
+	var client int
+	err = s.queryRow(tx, queryParcelStatus, sql.Named("number", number), sql.Named("tenant", s.tenant())).Scan(&rollback.From, &client, &tenant)
+	if err != nil {
+		return rollback, err
+	}
+
+	// статусы объединённых посылок меняются только вместе
+	err = s.checkNotMerged(tx, number)
+	if err != nil {
+		return rollback, err
+	}
+
+	rows, err := s.query(tx, queryLastHistory, sql.Named("number", number))
+	if err != nil {
+		return rollback, err
+	}
+	var ids []int64
+	var statuses []string
+	for rows.Next() {
+		var id int64
+		var status, changedAt string
+		err = rows.Scan(&id, &status, &changedAt)
+		if err != nil {
+			rows.Close()
+			return rollback, err
+		}
+		if len(ids) == 0 {
+			rollback.ChangedAt = changedAt
+		}
+		ids = append(ids, id)
+		statuses = append(statuses, status)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return rollback, err
+	}
+	// последняя запись истории должна быть текущим статусом, иначе откатывать не к чему
+	if len(ids) < 2 || statuses[0] != rollback.From {
+		return rollback, ErrNothingToRollback
+	}
+	rollback.To = statuses[1]
+
+	res, err := s.exec(tx, queryRollbackStatus,
+		sql.Named("status", rollback.To),
+		sql.Named("current", rollback.From),
+		sql.Named("number", number),
+		sql.Named("version", s.ifVersion()),
+		sql.Named("tenant", tenant))
+	if err != nil {
+		return rollback, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return rollback, err
+	}
+	if n == 0 {
+		return rollback, ErrVersionMismatch
+	}
+	_, err = s.exec(tx, queryDeleteHistoryRow, sql.Named("id", ids[0]))
+	if err != nil {
+		return rollback, err
+	}
+	if rollback.From == ParcelStatusDelivered {
+		_, err = s.exec(tx, queryCODUndelivered, sql.Named("number", number))
+		if err != nil {
+			return rollback, err
+		}
+	}
+
+	var actorClient any
+	if rollback.Actor.Role != "" {
+		actorClient = rollback.Actor.Client
+	}
+	_, err = s.exec(tx, queryInsertRollback,
+		sql.Named("number", number),
+		sql.Named("from_status", rollback.From),
+		sql.Named("to_status", rollback.To),
+		sql.Named("changed_at", rollback.ChangedAt),
+		sql.Named("reason", reason),
+		sql.Named("actor_client", actorClient),
+		sql.Named("actor_role", string(rollback.Actor.Role)),
+		sql.Named("rolled_back_at", rollback.RolledBackAt),
+		sql.Named("request_id", RequestIDFromContext(s.ctx)),
+		sql.Named("tenant", tenant))
+	if err != nil {
+		return rollback, err
+	}
+	err = s.addOutbox(tx, OutboxEvent{Type: EventParcelStatusChanged, Number: number, Client: client,
+		Status: rollback.To, PreviousStatus: rollback.From, Tenant: tenant})
+	if err != nil {
+		return rollback, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return rollback, err
+	}
+	s.changes.publish(ParcelChange{Number: number, Status: rollback.To, ChangedAt: rollback.RolledBackAt})
+	s.hooks.call(s.event(EventParcelStatusChanged, number, rollback.To))
+	return rollback, nil
+}
+
+// Rollback отменяет последнюю смену статуса посылки; доступно только администратору.
+// Причина обязательна: она остаётся в журнале откатов.
+func (s ParcelService) Rollback(ctx context.Context, number int, reason string) (StatusRollback, error) {
+	err := s.check(ctx, actionRollback)
+	if err != nil {
+		return StatusRollback{}, err
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return StatusRollback{}, ErrRollbackReason
+	}
+	return s.store.WithContext(ctx).Rollback(number, reason)
+}
@@ -0,0 +1,65 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRollback проверяет откат ошибочной смены статуса и запись о нём в журнале
+func TestRollback(t *testing.T) {
+	// prepare
+	// пул из одного соединения, как в настройках по умолчанию: откат идёт в транзакции
+	db, store := singleConnStore(t)
+	service := NewParcelService(store)
+	number, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	admin := WithCaller(ctx, Caller{Client: 42, Role: RoleAdmin})
+	// у только что зарегистрированной посылки откатывать нечего
+	_, err = service.Rollback(admin, number, "ошибка")
+	assert.ErrorIs(t, err, ErrNothingToRollback)
+
+	require.NoError(t, store.SetStatus(number, ParcelStatusSent))
+	require.NoError(t, store.SetStatus(number, ParcelStatusDelivered))
+
+	// rollback
+	// откатить может только администратор и только с причиной
+	_, err = service.Rollback(WithCaller(ctx, Caller{Role: RoleOperator}), number, "ошибка")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = service.Rollback(admin, number, "  ")
+	assert.ErrorIs(t, err, ErrRollbackReason)
+	rb, err := service.Rollback(admin, number, "отсканировали не ту посылку")
+	require.NoError(t, err)
+
+	// check
+	assert.Equal(t, ParcelStatusDelivered, rb.From)
+	assert.Equal(t, ParcelStatusSent, rb.To)
+	stored, err := store.Get(number)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusSent, stored.Status)
+	assert.Empty(t, stored.DeliveredAt)
+	assert.Equal(t, 4, stored.Version)
+	history, err := store.History(number)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, ParcelStatusSent, history[1].Status)
+
+	var actor int
+	var role, reason string
+	require.NoError(t, db.QueryRow("SELECT actor_client, actor_role, reason FROM status_rollback WHERE number = ?", number).
+		Scan(&actor, &role, &reason))
+	assert.Equal(t, 42, actor)
+	assert.Equal(t, string(RoleAdmin), role)
+	assert.Equal(t, "отсканировали не ту посылку", reason)
+
+	// откаты идут по истории назад до регистрации
+	rb, err = service.Rollback(admin, number, "и отправка тоже ошибочная")
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusRegistered, rb.To)
+	_, err = service.Rollback(admin, number, "ещё раз")
+	assert.ErrorIs(t, err, ErrNothingToRollback)
+}
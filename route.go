@@ -0,0 +1,550 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Состояния маршрута курьера
+const (
+	RoutePlanned   = "planned"
+	RouteStarted   = "started"
+	RouteCompleted = "completed"
+)
+
+var (
+	// ErrRouteState возвращается при изменении маршрута, который уже нельзя так изменить:
+	// начатый нельзя начать снова, завершённый — ни изменить, ни завершить
+	ErrRouteState = errors.New("маршрут нельзя изменить в его состоянии")
+	// ErrInvalidRoute возвращается при пустом маршруте, повторе посылки в нём или
+	// порядке посылок, который не совпадает с составом маршрута
+	ErrInvalidRoute = errors.New("неверный состав маршрута")
+	// ErrParcelRouted возвращается, если посылка уже в незавершённом маршруте или её нельзя везти
+	ErrParcelRouted = errors.New("посылку нельзя включить в маршрут")
+)
+
+const (
+	queryInsertRoute = `INSERT INTO route (tenant_id, courier, day, status, created_at, request_id)
+		VALUES (:tenant, :courier, :day, '` + RoutePlanned + `', :created_at, NULLIF(:request_id, ''))`
+	queryRoute = `SELECT id, courier, day, status, created_at, COALESCE(started_at, ''), COALESCE(completed_at, '')
+		FROM route WHERE id = :id AND ` + tenantCond
+	queryRouteIDs = "SELECT id FROM route WHERE courier = :courier AND day = :day AND " + tenantCond + " ORDER BY id"
+	// queryActiveRoute незавершённый маршрут, в который уже включена посылка
+	queryActiveRoute = `SELECT r.id FROM route_stop s JOIN route r ON r.id = s.route_id
+		WHERE s.number = :number AND r.status != '` + RouteCompleted + `'`
+	queryInsertStop  = "INSERT INTO route_stop (route_id, position, number) VALUES (:route_id, :position, :number)"
+	queryDeleteStops = "DELETE FROM route_stop WHERE route_id = :route_id"
+	queryRouteStops  = "SELECT number FROM route_stop WHERE route_id = :route_id ORDER BY position"
+	queryStartRoute  = "UPDATE route SET status = '" + RouteStarted + "', started_at = :at WHERE id = :id"
+	queryFinishRoute = "UPDATE route SET status = '" + RouteCompleted + "', completed_at = :at WHERE id = :id"
+	// queryZoneRoutable недоставленные посылки зоны, которые можно включить в маршрут
+	queryZoneRoutable = `SELECT p.number FROM parcel p
+		WHERE p.zone = :zone AND ` + tenantCond + ` AND p.status != '` + ParcelStatusDelivered + `'
+			AND NOT EXISTS (SELECT 1 FROM route_stop s JOIN route r ON r.id = s.route_id
+				WHERE s.number = p.number AND r.status != '` + RouteCompleted + `')
+			AND NOT EXISTS (SELECT 1 FROM parcel_link l WHERE l.child = p.number AND l.kind = '` + LinkMerge + `')
+		ORDER BY p.number`
+)
+
+// Route маршрут курьера на день: посылки в порядке доставки
+type Route struct {
+	ID      int64
+	Courier int
+	// Day день маршрута в виде 2006-01-02
+	Day    string
+	Status string
+	// Parcels номера посылок в порядке доставки
+	Parcels     []int
+	CreatedAt   string
+	StartedAt   string
+	CompletedAt string
+}
+
+// BuildRoute составляет маршрут курьера courier на день day из посылок numbers в порядке
+// доставки. Посылки должны быть ещё не доставлены и не входить в другой незавершённый
+// маршрут; посылку, объединённую с другой, везут в составе основной.
+func (s ParcelStore) BuildRoute(courier int, day string, numbers []int) (Route, error) {
+	start := time.Now()
+	span := s.startSpan("BuildRoute", attrClient.Int(courier))
+	defer span.End()
+
+	var route Route
+	err := s.withRetry("store.BuildRoute", func(s ParcelStore) error {
+		var err error
+		route, err = s.buildRoute(courier, day, numbers)
+		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("BuildRoute", start)
+	logResult(s.ctx, s.logger, "store.BuildRoute", start, err, "route", route.ID, "courier", courier, "day", day, "parcels", len(numbers))
+	return route, err
+}
+
+func (s ParcelStore) buildRoute(courier int, day string, numbers []int) (Route, error) {
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return Route{}, err
+	}
+	tx, err := s.begin()
+	if err != nil {
+		return Route{}, err
+	}
+	defer tx.Rollback()
+
+	for _, n := range numbers {
+		err = s.checkRoutable(tx, n)
+		if err != nil {
+			return Route{}, err
+		}
+	}
+	route := Route{Courier: courier, Day: day, Status: RoutePlanned, Parcels: numbers, CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	res, err := s.exec(tx, queryInsertRoute,
+		sql.Named("tenant", tenant),
+		sql.Named("courier", courier),
+		sql.Named("day", day),
+		sql.Named("created_at", route.CreatedAt),
+		sql.Named("request_id", RequestIDFromContext(s.ctx)))
+	if err != nil {
+		return Route{}, err
+	}
+	route.ID, err = res.LastInsertId()
+	if err != nil {
+		return Route{}, err
+	}
+	err = s.insertStops(tx, route.ID, numbers)
+	if err != nil {
+		return Route{}, err
+	}
+	return route, tx.Commit()
+}
+
+// checkRoutable проверяет в транзакции tx, что посылку number можно включить в маршрут:
+// она есть у компании, ещё не доставлена, не объединена с другой и не входит в другой
+// незавершённый маршрут
+func (s ParcelStore) checkRoutable(tx *sql.Tx, number int) error {
+	var status, tenant string
+	var client int
+	err := s.queryRow(tx, queryParcelStatus, sql.Named("number", number), sql.Named("tenant", s.tenant())).Scan(&status, &client, &tenant)
+	if err != nil {
+		return err
+	}
+	if status == ParcelStatusDelivered {
+		return fmt.Errorf("%w: посылка № %d уже доставлена", ErrParcelRouted, number)
+	}
+	parent, err := s.mergedInto(tx, number)
+	if err != nil {
+		return err
+	}
+	if parent != 0 {
+		return fmt.Errorf("%w: посылка № %d едет в составе № %d", ErrParcelRouted, number, parent)
+	}
+	var other int64
+	err = s.queryRow(tx, queryActiveRoute, sql.Named("number", number)).Scan(&other)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: посылка № %d уже в маршруте %d", ErrParcelRouted, number, other)
+}
+
+// insertStops записывает посылки маршрута route в порядке numbers
+func (s ParcelStore) insertStops(tx *sql.Tx, route int64, numbers []int) error {
+	for i, n := range numbers {
+		_, err := s.exec(tx, queryInsertStop, sql.Named("route_id", route), sql.Named("position", i), sql.Named("number", n))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Route возвращает маршрут id с посылками или sql.ErrNoRows, если его нет
+func (s ParcelStore) Route(id int64) (Route, error) {
+	defer s.metrics.observeQuery("Route", time.Now())
+	span := s.startSpan("Route")
+	defer span.End()
+
+	route, err := s.route(nil, id)
+	return route, spanError(span, err)
+}
+
+// route читает маршрут id с посылками; tx может быть nil
+func (s ParcelStore) route(tx *sql.Tx, id int64) (Route, error) {
+	var r Route
+	err := s.queryRow(tx, queryRoute, sql.Named("id", id), sql.Named("tenant", s.tenant())).Scan(
+		&r.ID, &r.Courier, &r.Day, &r.Status, &r.CreatedAt, &r.StartedAt, &r.CompletedAt)
+	if err != nil {
+		return Route{}, err
+	}
+	rows, err := s.query(tx, queryRouteStops, sql.Named("route_id", id))
+	if err != nil {
+		return Route{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var n int
+		err = rows.Scan(&n)
+		if err != nil {
+			return Route{}, err
+		}
+		r.Parcels = append(r.Parcels, n)
+	}
+	return r, rows.Err()
+}
+
+// Routes возвращает маршруты курьера courier на день day
+func (s ParcelStore) Routes(courier int, day string) ([]Route, error) {
+	defer s.metrics.observeQuery("Routes", time.Now())
+	span := s.startSpan("Routes", attrClient.Int(courier))
+	defer span.End()
+
+	rows, err := s.query(nil, queryRouteIDs, sql.Named("courier", courier), sql.Named("day", day), sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return nil, spanError(span, err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, spanError(span, err)
+	}
+
+	routes := make([]Route, 0, len(ids))
+	for _, id := range ids {
+		r, err := s.route(nil, id)
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
+
+// ReorderRoute меняет порядок посылок незавершённого маршрута id на numbers;
+// состав маршрута должен остаться прежним
+func (s ParcelStore) ReorderRoute(id int64, numbers []int) error {
+	start := time.Now()
+	span := s.startSpan("ReorderRoute")
+	defer span.End()
+
+	err := s.withRetry("store.ReorderRoute", func(s ParcelStore) error {
+		return s.reorderRoute(id, numbers)
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("ReorderRoute", start)
+	logResult(s.ctx, s.logger, "store.ReorderRoute", start, err, "route", id)
+	return err
+}
+
+func (s ParcelStore) reorderRoute(id int64, numbers []int) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	route, err := s.route(tx, id)
+	if err != nil {
+		return err
+	}
+	if route.Status == RouteCompleted {
+		return ErrRouteState
+	}
+	if !slices.Equal(sortedCopy(route.Parcels), sortedCopy(numbers)) {
+		return ErrInvalidRoute
+	}
+	_, err = s.exec(tx, queryDeleteStops, sql.Named("route_id", id))
+	if err != nil {
+		return err
+	}
+	err = s.insertStops(tx, id, numbers)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// sortedCopy возвращает отсортированную копию номеров
+func sortedCopy(numbers []int) []int {
+	res := slices.Clone(numbers)
+	slices.Sort(res)
+	return res
+}
+
+// StartRoute начинает маршрут id: зарегистрированные посылки маршрута переводятся в статус
+// sent в той же транзакции, с историей, событиями outbox и оповещением подписчиков
+func (s ParcelStore) StartRoute(id int64) (Route, error) {
+	start := time.Now()
+	span := s.startSpan("StartRoute")
+	defer span.End()
+
+	var route Route
+	var changes []ParcelChange
+	err := s.withRetry("store.StartRoute", func(s ParcelStore) error {
+		var err error
+		route, changes, err = s.startRoute(id)
+		return err
+	})
+	s.notify(changes)
+	spanError(span, err)
+	s.metrics.observeQuery("StartRoute", start)
+	logResult(s.ctx, s.logger, "store.StartRoute", start, err, "route", id, "sent", len(changes))
+	return route, err
+}
+
+func (s ParcelStore) startRoute(id int64) (Route, []ParcelChange, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return Route{}, nil, err
+	}
+	defer tx.Rollback()
+
+	route, err := s.route(tx, id)
+	if err != nil {
+		return Route{}, nil, err
+	}
+	if route.Status != RoutePlanned {
+		return route, nil, ErrRouteState
+	}
+
+	var changes []ParcelChange
+	for _, n := range route.Parcels {
+		var status, tenant string
+		var client int
+		err = s.queryRow(tx, queryParcelStatus, sql.Named("number", n), sql.Named("tenant", s.tenant())).Scan(&status, &client, &tenant)
+		if errors.Is(err, sql.ErrNoRows) {
+			// посылку удалили после составления маршрута
+			continue
+		}
+		if err != nil {
+			return route, nil, err
+		}
+		if status != ParcelStatusRegistered {
+			continue
+		}
+		// ожидаемая версия из контекста относится к посылке, а не к маршруту
+		c, err := s.WithContext(WithIfVersion(s.ctx, 0)).updateStatus(tx, n, client, tenant, status, ParcelStatusSent)
+		if err != nil {
+			return route, nil, err
+		}
+		changes = append(changes, c...)
+	}
+
+	route.Status, route.StartedAt = RouteStarted, time.Now().UTC().Format(time.RFC3339)
+	_, err = s.exec(tx, queryStartRoute, sql.Named("at", route.StartedAt), sql.Named("id", id))
+	if err != nil {
+		return route, nil, err
+	}
+	return route, changes, tx.Commit()
+}
+
+// CompleteRoute завершает начатый маршрут id. Статусы посылок не меняются: недоставленные
+// посылки возвращаются на склад и могут войти в новый маршрут.
+func (s ParcelStore) CompleteRoute(id int64) (Route, error) {
+	start := time.Now()
+	span := s.startSpan("CompleteRoute")
+	defer span.End()
+
+	var route Route
+	err := s.withRetry("store.CompleteRoute", func(s ParcelStore) error {
+		var err error
+		route, err = s.completeRoute(id)
+		return err
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("CompleteRoute", start)
+	logResult(s.ctx, s.logger, "store.CompleteRoute", start, err, "route", id)
+	return route, err
+}
+
+func (s ParcelStore) completeRoute(id int64) (Route, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return Route{}, err
+	}
+	defer tx.Rollback()
+
+	route, err := s.route(tx, id)
+	if err != nil {
+		return Route{}, err
+	}
+	if route.Status != RouteStarted {
+		return route, ErrRouteState
+	}
+	route.Status, route.CompletedAt = RouteCompleted, time.Now().UTC().Format(time.RFC3339)
+	_, err = s.exec(tx, queryFinishRoute, sql.Named("at", route.CompletedAt), sql.Named("id", id))
+	if err != nil {
+		return route, err
+	}
+	return route, tx.Commit()
+}
+
+// ZoneRoutable возвращает номера недоставленных посылок зоны zone, которые не входят
+// в незавершённые маршруты и не объединены с другими посылками
+func (s ParcelStore) ZoneRoutable(zone string) ([]int, error) {
+	defer s.metrics.observeQuery("ZoneRoutable", time.Now())
+	span := s.startSpan("ZoneRoutable")
+	defer span.End()
+
+	rows, err := s.readQuery(queryZoneRoutable, sql.Named("zone", zone), sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer rows.Close()
+
+	var numbers []int
+	for rows.Next() {
+		var n int
+		err = rows.Scan(&n)
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, spanError(span, rows.Err())
+}
+
+// validateRoute проверяет, что маршрут не пустой и посылки в нём не повторяются
+func validateRoute(numbers []int) error {
+	if len(numbers) == 0 || len(slices.Compact(sortedCopy(numbers))) != len(numbers) {
+		return ErrInvalidRoute
+	}
+	return nil
+}
+
+// BuildRoute составляет маршрут курьера на день day (2006-01-02); доступно оператору и администратору
+func (s ParcelService) BuildRoute(ctx context.Context, courier int, day string, numbers []int) (Route, error) {
+	err := s.check(ctx, actionRoutes)
+	if err != nil {
+		return Route{}, err
+	}
+	_, err = time.Parse(time.DateOnly, day)
+	if err != nil {
+		return Route{}, fmt.Errorf("%w: день %q", ErrInvalidRoute, day)
+	}
+	err = validateRoute(numbers)
+	if err != nil {
+		return Route{}, err
+	}
+	return s.store.WithContext(ctx).BuildRoute(courier, day, numbers)
+}
+
+// BuildZoneRoute составляет маршрут курьера на день day из всех посылок зоны zone, которые
+// можно везти, в порядке номеров; доступно оператору и администратору
+func (s ParcelService) BuildZoneRoute(ctx context.Context, courier int, day, zone string) (Route, error) {
+	err := s.check(ctx, actionRoutes)
+	if err != nil {
+		return Route{}, err
+	}
+	_, err = time.Parse(time.DateOnly, day)
+	if err != nil {
+		return Route{}, fmt.Errorf("%w: день %q", ErrInvalidRoute, day)
+	}
+	store := s.store.WithContext(ctx)
+	numbers, err := store.ZoneRoutable(zone)
+	if err != nil {
+		return Route{}, err
+	}
+	if len(numbers) == 0 {
+		return Route{}, fmt.Errorf("%w: в зоне %s нет посылок для маршрута", ErrInvalidRoute, zone)
+	}
+	return store.BuildRoute(courier, day, numbers)
+}
+
+// ReorderRoute меняет порядок посылок маршрута; доступно оператору и администратору
+func (s ParcelService) ReorderRoute(ctx context.Context, id int64, numbers []int) error {
+	err := s.check(ctx, actionRoutes)
+	if err != nil {
+		return err
+	}
+	err = validateRoute(numbers)
+	if err != nil {
+		return err
+	}
+	return s.store.WithContext(ctx).ReorderRoute(id, numbers)
+}
+
+// Route возвращает маршрут; курьер видит только свои маршруты
+func (s ParcelService) Route(ctx context.Context, id int64) (Route, error) {
+	err := s.check(ctx, actionRead)
+	if err != nil {
+		return Route{}, err
+	}
+	return s.ownRoute(ctx, id)
+}
+
+// Routes возвращает маршруты курьера на день; курьер видит только свои маршруты
+func (s ParcelService) Routes(ctx context.Context, courier int, day string) ([]Route, error) {
+	err := s.check(ctx, actionRead)
+	if err != nil {
+		return nil, err
+	}
+	err = authorizeCourier(ctx, courier)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.WithContext(ctx).Routes(courier, day)
+}
+
+// StartRoute начинает маршрут и отправляет его посылки; курьер начинает только свои маршруты
+func (s ParcelService) StartRoute(ctx context.Context, id int64) (Route, error) {
+	err := s.check(ctx, actionSetStatus)
+	if err != nil {
+		return Route{}, err
+	}
+	_, err = s.ownRoute(ctx, id)
+	if err != nil {
+		return Route{}, err
+	}
+	return s.store.WithContext(ctx).StartRoute(id)
+}
+
+// CompleteRoute завершает маршрут; курьер завершает только свои маршруты
+func (s ParcelService) CompleteRoute(ctx context.Context, id int64) (Route, error) {
+	err := s.check(ctx, actionSetStatus)
+	if err != nil {
+		return Route{}, err
+	}
+	_, err = s.ownRoute(ctx, id)
+	if err != nil {
+		return Route{}, err
+	}
+	return s.store.WithContext(ctx).CompleteRoute(id)
+}
+
+// ownRoute возвращает маршрут id, если он доступен пользователю из ctx
+func (s ParcelService) ownRoute(ctx context.Context, id int64) (Route, error) {
+	route, err := s.store.WithContext(ctx).Route(id)
+	if err != nil {
+		return Route{}, err
+	}
+	err = authorizeCourier(ctx, route.Courier)
+	if err != nil {
+		return Route{}, err
+	}
+	return route, nil
+}
+
+// authorizeCourier проверяет, что курьер из ctx работает со своими маршрутами.
+// Клиентам маршруты недоступны, остальные роли работают с маршрутами любых курьеров.
+func authorizeCourier(ctx context.Context, courier int) error {
+	c, ok := CallerFromContext(ctx)
+	if ok && (c.Role == RoleClient || c.Role == RoleCourier && c.Client != courier) {
+		return ErrForbidden
+	}
+	return nil
+}
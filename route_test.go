@@ -0,0 +1,91 @@
+package tracker_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestRoute проверяет составление, перестановку, начало с отправкой посылок и завершение маршрута
+func TestRoute(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	tenant := fmt.Sprintf("route-%d", testsupport.Intn(10_000_000))
+	ctx := tracker.WithTenant(context.Background(), tenant)
+	store := tracker.NewParcelStore(db)
+	service := tracker.NewParcelService(store)
+	operator := tracker.WithCaller(ctx, tracker.Caller{Client: 3, Role: tracker.RoleOperator, Tenant: tenant})
+	courier := tracker.WithCaller(ctx, tracker.Caller{Client: 7, Role: tracker.RoleCourier, Tenant: tenant})
+	stranger := tracker.WithCaller(ctx, tracker.Caller{Client: 8, Role: tracker.RoleCourier, Tenant: tenant})
+
+	var numbers []int
+	for range 3 {
+		p, err := service.Register(ctx, 1000, "Псков, ул. Колотушкина, д. 5")
+		require.NoError(t, err)
+		numbers = append(numbers, p.Number)
+	}
+	// одна посылка уже в пути: при начале маршрута её статус не меняется
+	require.NoError(t, service.SetStatus(ctx, numbers[2], tracker.ParcelStatusSent))
+
+	// build
+	_, err := service.BuildRoute(courier, 7, "2024-05-01", numbers)
+	assert.ErrorIs(t, err, tracker.ErrForbidden)
+	_, err = service.BuildRoute(operator, 7, "2024-05-01", []int{numbers[0], numbers[0]})
+	assert.ErrorIs(t, err, tracker.ErrInvalidRoute)
+	route, err := service.BuildRoute(operator, 7, "2024-05-01", numbers)
+	require.NoError(t, err)
+	assert.Equal(t, tracker.RoutePlanned, route.Status)
+	// посылка не может быть в двух незавершённых маршрутах
+	_, err = service.BuildRoute(operator, 8, "2024-05-01", numbers[:1])
+	assert.ErrorIs(t, err, tracker.ErrParcelRouted)
+
+	// reorder
+	reordered := []int{numbers[2], numbers[0], numbers[1]}
+	assert.ErrorIs(t, service.ReorderRoute(operator, route.ID, numbers[:2]), tracker.ErrInvalidRoute)
+	require.NoError(t, service.ReorderRoute(operator, route.ID, reordered))
+	got, err := service.Route(courier, route.ID)
+	require.NoError(t, err)
+	assert.Equal(t, reordered, got.Parcels)
+	_, err = service.Route(stranger, route.ID)
+	assert.ErrorIs(t, err, tracker.ErrForbidden)
+
+	// start
+	changes, cancel := store.Subscribe()
+	defer cancel()
+	_, err = service.StartRoute(stranger, route.ID)
+	assert.ErrorIs(t, err, tracker.ErrForbidden)
+	route, err = service.StartRoute(courier, route.ID)
+	require.NoError(t, err)
+	assert.Equal(t, tracker.RouteStarted, route.Status)
+	for _, n := range numbers {
+		p, err := store.WithContext(ctx).Get(n)
+		require.NoError(t, err)
+		assert.Equal(t, tracker.ParcelStatusSent, p.Status)
+	}
+	assert.Equal(t, numbers[0], (<-changes).Number)
+	assert.Equal(t, numbers[1], (<-changes).Number)
+	_, err = service.StartRoute(courier, route.ID)
+	assert.ErrorIs(t, err, tracker.ErrRouteState)
+
+	// complete
+	route, err = service.CompleteRoute(courier, route.ID)
+	require.NoError(t, err)
+	assert.Equal(t, tracker.RouteCompleted, route.Status)
+	assert.ErrorIs(t, service.ReorderRoute(operator, route.ID, numbers), tracker.ErrRouteState)
+	// после завершения маршрута недоставленные посылки можно везти снова
+	_, err = service.BuildRoute(operator, 8, "2024-05-02", numbers[:1])
+	require.NoError(t, err)
+	routes, err := service.Routes(operator, 7, "2024-05-01")
+	require.NoError(t, err)
+	require.Len(t, routes, 1)
+	assert.Equal(t, route.ID, routes[0].ID)
+}
@@ -0,0 +1,325 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxSavedSearchName наибольшая длина имени сохранённого поиска в символах
+const maxSavedSearchName = 100
+
+var (
+	// ErrInvalidSavedSearch возвращается при сохранённом поиске без имени или с неверными фильтрами
+	ErrInvalidSavedSearch = errors.New("неверный сохранённый поиск")
+	// ErrNoSavedSearch возвращается, если у пользователя нет своего или общего поиска с таким именем
+	ErrNoSavedSearch = errors.New("сохранённый поиск не найден")
+	// ErrSavedSearchTaken возвращается, если поиск делается общим, а другой
+	// пользователь компании уже сделал общим поиск с тем же именем
+	ErrSavedSearchTaken = errors.New("общий поиск с таким именем уже есть")
+)
+
+const (
+	savedSearchColumns     = "id, owner, name, shared, status, zone, created_from, created_to, period"
+	queryUpsertSavedSearch = `INSERT INTO saved_search (tenant_id, owner, name, shared, status, zone, created_from, created_to, period)
+		VALUES (:tenant, :owner, :name, :shared, :status, :zone, :created_from, :created_to, :period)
+		ON CONFLICT (tenant_id, owner, name) DO UPDATE SET shared = excluded.shared, status = excluded.status, zone = excluded.zone,
+			created_from = excluded.created_from, created_to = excluded.created_to, period = excluded.period`
+	// querySharedSearchOwner владелец общего поиска компании с именем :name, кроме :owner
+	querySharedSearchOwner = "SELECT owner FROM saved_search WHERE tenant_id = :tenant AND name = :name AND shared = 1 AND owner != :owner LIMIT 1"
+	// queryFindSavedSearch свой поиск пользователя, а если его нет — общий поиск компании
+	queryFindSavedSearch = "SELECT " + savedSearchColumns + ` FROM saved_search
+		WHERE tenant_id = :tenant AND name = :name AND (owner = :owner OR shared = 1)
+		ORDER BY owner != :owner LIMIT 1`
+	querySavedSearches = "SELECT " + savedSearchColumns + ` FROM saved_search
+		WHERE tenant_id = :tenant AND (owner = :owner OR shared = 1) ORDER BY name, owner != :owner`
+	queryDeleteSavedSearch = "DELETE FROM saved_search WHERE tenant_id = :tenant AND owner = :owner AND name = :name"
+)
+
+// SavedSearch именованный фильтр посылок: статус, зона и время регистрации.
+// Поиск принадлежит пользователю, который его сохранил; общий поиск (Shared)
+// видят и выполняют все пользователи его компании, а меняет и удаляет только владелец.
+type SavedSearch struct {
+	ID   int64
+	Name string
+	// Owner идентификатор пользователя-владельца; 0 — командная строка
+	// и запросы без аутентификации
+	Owner  int
+	Shared bool
+	Status string
+	// Zone зона доставки посылок
+	Zone string
+	// From и To ограничивают время регистрации посылок: не раньше From и раньше To
+	From, To time.Time
+	// Period выбирает посылки, зарегистрированные за Period до выполнения поиска;
+	// задаётся вместо From
+	Period time.Duration
+}
+
+// validate проверяет имя, статус и границы времени поиска
+func (q SavedSearch) validate() error {
+	switch {
+	case q.Name == "" || utf8.RuneCountInString(q.Name) > maxSavedSearchName:
+		return fmt.Errorf("%w: имя должно быть непустым и не длиннее %d символов", ErrInvalidSavedSearch, maxSavedSearchName)
+	case q.Status != "" && statusRank[q.Status] == 0:
+		return fmt.Errorf("%w: %w %q", ErrInvalidSavedSearch, ErrUnknownStatus, q.Status)
+	case q.Period < 0:
+		return fmt.Errorf("%w: период не может быть отрицательным", ErrInvalidSavedSearch)
+	case q.Period > 0 && !q.From.IsZero():
+		return fmt.Errorf("%w: задайте период или начало, но не оба", ErrInvalidSavedSearch)
+	case !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To):
+		return fmt.Errorf("%w: начало должно быть раньше конца", ErrInvalidSavedSearch)
+	}
+	return nil
+}
+
+// ListOptions возвращает фильтры выборки поиска, выполненного в момент now
+func (q SavedSearch) ListOptions(now time.Time) ListOptions {
+	opts := ListOptions{Status: q.Status, Zone: q.Zone, From: q.From, To: q.To}
+	if q.Period > 0 {
+		opts.From = now.Add(-q.Period)
+	}
+	return opts
+}
+
+// formatSavedTime записывает границу времени поиска; нулевое время — пустая строка
+func formatSavedTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// parseSavedTime читает границу времени поиска, записанную formatSavedTime
+func parseSavedTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// scanSavedSearch читает поиск из строки, выбранной по savedSearchColumns
+func scanSavedSearch(row rowScanner) (SavedSearch, error) {
+	var q SavedSearch
+	var from, to string
+	var period int64
+	err := row.Scan(&q.ID, &q.Owner, &q.Name, &q.Shared, &q.Status, &q.Zone, &from, &to, &period)
+	if err != nil {
+		return q, err
+	}
+	q.Period = time.Duration(period) * time.Second
+	q.From, err = parseSavedTime(from)
+	if err != nil {
+		return q, err
+	}
+	q.To, err = parseSavedTime(to)
+	return q, err
+}
+
+// SaveSearch сохраняет поиск пользователя q.Owner в компании из контекста хранилища
+// или заменяет его поиск с тем же именем. Сделать поиск общим можно, только если
+// другой пользователь не сделал общим поиск с тем же именем, иначе — ErrSavedSearchTaken.
+func (s ParcelStore) SaveSearch(q SavedSearch) error {
+	start := time.Now()
+	span := s.startSpan("SaveSearch")
+	defer span.End()
+
+	err := s.withRetry("store.SaveSearch", func(s ParcelStore) error {
+		return s.saveSearch(q)
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("SaveSearch", start)
+	logResult(s.ctx, s.logger, "store.SaveSearch", start, err, "owner", q.Owner, "name", q.Name, "shared", q.Shared)
+	return err
+}
+
+func (s ParcelStore) saveSearch(q SavedSearch) error {
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return err
+	}
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if q.Shared {
+		var other int
+		err = s.queryRow(tx, querySharedSearchOwner,
+			sql.Named("tenant", tenant),
+			sql.Named("name", q.Name),
+			sql.Named("owner", q.Owner)).Scan(&other)
+		if err == nil {
+			return fmt.Errorf("%w: %q у пользователя %d", ErrSavedSearchTaken, q.Name, other)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+	_, err = s.exec(tx, queryUpsertSavedSearch,
+		sql.Named("tenant", tenant),
+		sql.Named("owner", q.Owner),
+		sql.Named("name", q.Name),
+		sql.Named("shared", q.Shared),
+		sql.Named("status", q.Status),
+		sql.Named("zone", q.Zone),
+		sql.Named("created_from", formatSavedTime(q.From)),
+		sql.Named("created_to", formatSavedTime(q.To)),
+		sql.Named("period", int64(q.Period/time.Second)))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindSavedSearch возвращает поиск с именем name, доступный пользователю owner:
+// его собственный, а если такого нет — общий поиск компании
+func (s ParcelStore) FindSavedSearch(owner int, name string) (SavedSearch, error) {
+	defer s.metrics.observeQuery("FindSavedSearch", time.Now())
+	span := s.startSpan("FindSavedSearch")
+	defer span.End()
+
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return SavedSearch{}, spanError(span, err)
+	}
+	q, err := scanSavedSearch(s.readRow(queryFindSavedSearch,
+		sql.Named("tenant", tenant),
+		sql.Named("owner", owner),
+		sql.Named("name", name)))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("%w: %q", ErrNoSavedSearch, name)
+	}
+	return q, spanError(span, err)
+}
+
+// SavedSearches возвращает поиски пользователя owner и общие поиски компании
+// в порядке имён; при совпадении имён свой поиск идёт первым
+func (s ParcelStore) SavedSearches(owner int) ([]SavedSearch, error) {
+	defer s.metrics.observeQuery("SavedSearches", time.Now())
+	span := s.startSpan("SavedSearches")
+	defer span.End()
+
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	rows, err := s.readQuery(querySavedSearches, sql.Named("tenant", tenant), sql.Named("owner", owner))
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer rows.Close()
+
+	var res []SavedSearch
+	for rows.Next() {
+		q, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		res = append(res, q)
+	}
+	return res, spanError(span, rows.Err())
+}
+
+// DeleteSavedSearch удаляет поиск пользователя owner с именем name; чужие общие
+// поиски не удаляются, для них возвращается ErrNoSavedSearch
+func (s ParcelStore) DeleteSavedSearch(owner int, name string) error {
+	start := time.Now()
+	span := s.startSpan("DeleteSavedSearch")
+	defer span.End()
+
+	err := s.withRetry("store.DeleteSavedSearch", func(s ParcelStore) error {
+		tenant, err := s.ownTenant()
+		if err != nil {
+			return err
+		}
+		res, err := s.exec(nil, queryDeleteSavedSearch,
+			sql.Named("tenant", tenant),
+			sql.Named("owner", owner),
+			sql.Named("name", name))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("%w: %q", ErrNoSavedSearch, name)
+		}
+		return nil
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("DeleteSavedSearch", start)
+	logResult(s.ctx, s.logger, "store.DeleteSavedSearch", start, err, "owner", owner, "name", name)
+	return err
+}
+
+// searchOwner возвращает пользователя из ctx, которому принадлежат его поиски;
+// без пользователя в контексте — 0
+func searchOwner(ctx context.Context) int {
+	c, _ := CallerFromContext(ctx)
+	return c.Client
+}
+
+// SaveSearch проверяет и сохраняет поиск пользователя из ctx в его компании;
+// доступно операторам и администраторам
+func (s ParcelService) SaveSearch(ctx context.Context, q SavedSearch) (SavedSearch, error) {
+	err := s.check(ctx, actionSavedSearches)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	q.Owner = searchOwner(ctx)
+	q.Name = strings.TrimSpace(q.Name)
+	q.Zone = strings.ToLower(strings.TrimSpace(q.Zone))
+	err = q.validate()
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	store := s.store.WithContext(ctx)
+	err = store.SaveSearch(q)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	return store.FindSavedSearch(q.Owner, q.Name)
+}
+
+// SavedSearches возвращает поиски пользователя из ctx и общие поиски его компании
+func (s ParcelService) SavedSearches(ctx context.Context) ([]SavedSearch, error) {
+	err := s.check(ctx, actionSavedSearches)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.WithContext(ctx).SavedSearches(searchOwner(ctx))
+}
+
+// DeleteSavedSearch удаляет поиск пользователя из ctx
+func (s ParcelService) DeleteSavedSearch(ctx context.Context, name string) error {
+	err := s.check(ctx, actionSavedSearches)
+	if err != nil {
+		return err
+	}
+	return s.store.WithContext(ctx).DeleteSavedSearch(searchOwner(ctx), name)
+}
+
+// ListSaved выполняет через List поиск с именем name, доступный пользователю из ctx,
+// и возвращает не больше limit посылок в порядке номеров; 0 — без ограничения
+func (s ParcelService) ListSaved(ctx context.Context, name string, limit int) ([]Parcel, error) {
+	err := s.check(ctx, actionSavedSearches)
+	if err != nil {
+		return nil, err
+	}
+	store := s.store.WithContext(ctx)
+	q, err := store.FindSavedSearch(searchOwner(ctx), name)
+	if err != nil {
+		return nil, err
+	}
+	opts := q.ListOptions(time.Now())
+	opts.Limit = limit
+	return store.List(opts)
+}
@@ -0,0 +1,100 @@
+package tracker_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestSavedSearches проверяет сохранение поисков, их выполнение по имени
+// и общие поиски пользователей одной компании
+func TestSavedSearches(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+	store := tracker.NewParcelStore(db)
+	service := tracker.NewParcelService(store)
+	tenant := fmt.Sprintf("searches-%d", testsupport.Intn(10_000_000))
+	ctx := tracker.WithTenant(context.Background(), tenant)
+	alice := tracker.WithCaller(ctx, tracker.Caller{Client: 1, Role: tracker.RoleOperator, Tenant: tenant})
+	bob := tracker.WithCaller(ctx, tracker.Caller{Client: 2, Role: tracker.RoleOperator, Tenant: tenant})
+	stranger := tracker.WithCaller(context.Background(), tracker.Caller{Client: 1, Role: tracker.RoleOperator, Tenant: tenant + "-other"})
+	client := tracker.WithCaller(ctx, tracker.Caller{Client: 3, Role: tracker.RoleClient, Tenant: tenant})
+
+	tenantStore := store.WithContext(ctx)
+	now := time.Now()
+	add := func(createdAt time.Time, zone, status string) int {
+		t.Helper()
+		id := testsupport.MustAddParcel(t, tenantStore, testsupport.NewParcel().Tenant(tenant).CreatedAt(createdAt).Build()).Number
+		_, err := db.Exec("UPDATE parcel SET zone = ?, status = ? WHERE number = ?", zone, status, id)
+		require.NoError(t, err)
+		return id
+	}
+	recent := add(now.Add(-time.Hour), "msk", tracker.ParcelStatusSent)
+	add(now.Add(-48*time.Hour), "msk", tracker.ParcelStatusSent)
+	add(now.Add(-time.Hour), "spb", tracker.ParcelStatusSent)
+	add(now.Add(-time.Hour), "msk", tracker.ParcelStatusRegistered)
+
+	// save
+	saved, err := service.SaveSearch(alice, tracker.SavedSearch{Name: " msk sent ", Status: tracker.ParcelStatusSent, Zone: "MSK", Period: 24 * time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, tracker.SavedSearch{ID: saved.ID, Name: "msk sent", Owner: 1, Status: tracker.ParcelStatusSent, Zone: "msk", Period: 24 * time.Hour}, saved)
+
+	_, err = service.SaveSearch(alice, tracker.SavedSearch{Name: "bad", Status: "lost"})
+	assert.ErrorIs(t, err, tracker.ErrInvalidSavedSearch)
+	_, err = service.SaveSearch(alice, tracker.SavedSearch{Name: "bad", From: now, Period: time.Hour})
+	assert.ErrorIs(t, err, tracker.ErrInvalidSavedSearch)
+	_, err = service.SaveSearch(client, tracker.SavedSearch{Name: "mine"})
+	assert.ErrorIs(t, err, tracker.ErrForbidden)
+
+	// run
+	parcels, err := service.ListSaved(alice, "msk sent", 0)
+	require.NoError(t, err)
+	require.Len(t, parcels, 1)
+	assert.Equal(t, recent, parcels[0].Number)
+
+	// share
+	// чужой личный поиск не виден, общий — виден всей компании, но не другой
+	_, err = service.ListSaved(bob, "msk sent", 0)
+	assert.ErrorIs(t, err, tracker.ErrNoSavedSearch)
+	_, err = service.SaveSearch(alice, tracker.SavedSearch{Name: "msk sent", Status: tracker.ParcelStatusSent, Zone: "msk", Period: 24 * time.Hour, Shared: true})
+	require.NoError(t, err)
+	parcels, err = service.ListSaved(bob, "msk sent", 0)
+	require.NoError(t, err)
+	require.Len(t, parcels, 1)
+	_, err = service.ListSaved(stranger, "msk sent", 0)
+	assert.ErrorIs(t, err, tracker.ErrNoSavedSearch)
+
+	// имя общего поиска занято, а свой поиск с тем же именем важнее общего
+	_, err = service.SaveSearch(bob, tracker.SavedSearch{Name: "msk sent", Shared: true})
+	assert.ErrorIs(t, err, tracker.ErrSavedSearchTaken)
+	_, err = service.SaveSearch(bob, tracker.SavedSearch{Name: "msk sent", Zone: "spb"})
+	require.NoError(t, err)
+	parcels, err = service.ListSaved(bob, "msk sent", 0)
+	require.NoError(t, err)
+	require.Len(t, parcels, 1)
+	assert.Equal(t, "spb", parcels[0].Zone)
+
+	searches, err := service.SavedSearches(bob)
+	require.NoError(t, err)
+	require.Len(t, searches, 2)
+	assert.Equal(t, 2, searches[0].Owner)
+	assert.Equal(t, 1, searches[1].Owner)
+
+	// delete
+	// общий поиск удаляет только владелец
+	require.NoError(t, service.DeleteSavedSearch(bob, "msk sent"))
+	assert.ErrorIs(t, service.DeleteSavedSearch(bob, "msk sent"), tracker.ErrNoSavedSearch)
+	require.NoError(t, service.DeleteSavedSearch(alice, "msk sent"))
+	searches, err = service.SavedSearches(bob)
+	require.NoError(t, err)
+	assert.Empty(t, searches)
+}
@@ -0,0 +1,166 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// scanBuffer сколько событий сканирования может ждать обработки в канале подписки
+const scanBuffer = 64
+
+// maxScanIDLen наибольшая длина идентификатора события сканирования
+const maxScanIDLen = 128
+
+// ErrInvalidScan возвращается для событий сканирования без идентификатора или номера посылки
+var ErrInvalidScan = errors.New("некорректное событие сканирования")
+
+// ScanEvent событие сканирования посылки на складе перевозчика.
+// ID назначает сканер; по нему повторная доставка того же события не меняет статус дважды.
+type ScanEvent struct {
+	ID        string `json:"id"`
+	Number    int    `json:"number"`
+	Status    string `json:"status"`
+	ScannedAt string `json:"scanned_at,omitempty"`
+}
+
+// ApplyScan применяет событие сканирования: меняет статус посылки, если событие ещё
+// не обрабатывалось и статус не возвращает посылку назад (CheckTransition).
+// Повтор события и скан с текущим статусом ничего не меняют и возвращают false.
+// Для неизвестной посылки возвращается sql.ErrNoRows.
+func (s ParcelStore) ApplyScan(e ScanEvent) (bool, error) {
+	start := time.Now()
+	span := s.startSpan("ApplyScan", attrNumber.Int(e.Number), attrStatus.String(e.Status))
+	defer span.End()
+
+	var old string
+	var changes []ParcelChange
+	err := s.withRetry("store.ApplyScan", func(s ParcelStore) error {
+		var err error
+		old, changes, err = s.applyScan(e)
+		return err
+	})
+	s.invalidate(e.Number)
+	s.notify(changes)
+	applied := len(changes) > 0
+	spanError(span, err)
+	s.metrics.observeQuery("ApplyScan", start)
+	logResult(s.ctx, s.logger, "store.ApplyScan", start, err,
+		"scan_id", e.ID, "number", e.Number, "old_status", old, "new_status", e.Status, "applied", applied)
+	if applied {
+		s.metrics.statusChanged(e.Status)
+	}
+	return applied, err
+}
+
+func (s ParcelStore) applyScan(e ScanEvent) (string, []ParcelChange, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return "", nil, err
+	}
+	defer tx.Rollback()
+
+	// событие отмечается обработанным в той же транзакции, что и смена статуса
+	res, err := s.exec(tx, queryInsertScanEvent,
+		sql.Named("id", e.ID),
+		sql.Named("number", e.Number),
+		sql.Named("status", e.Status),
+		sql.Named("received_at", time.Now().UTC().Format(time.RFC3339)))
+	if err != nil {
+		return "", nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return "", nil, err
+	}
+	if n == 0 {
+		return "", nil, nil
+	}
+
+	var old, tenant string
+	var client int
+	err = s.queryRow(tx, queryParcelStatus, sql.Named("number", e.Number), sql.Named("tenant", s.tenant())).Scan(&old, &client, &tenant)
+	if err != nil {
+		return "", nil, err
+	}
+	err = CheckTransition(old, e.Status)
+	if err != nil {
+		return old, nil, err
+	}
+	if old == e.Status {
+		return old, nil, tx.Commit()
+	}
+
+	changes, err := s.updateStatus(tx, e.Number, client, tenant, old, e.Status)
+	if err != nil {
+		return old, nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return old, nil, err
+	}
+	return old, changes, nil
+}
+
+// ScanConsumer принимает события сканирования из очереди и применяет их к посылкам,
+// чтобы сканеры на складах обновляли статусы без обращения к API
+type ScanConsumer struct {
+	store ParcelStore
+}
+
+func NewScanConsumer(store ParcelStore) *ScanConsumer {
+	return &ScanConsumer{store: store}
+}
+
+// Handle разбирает событие сканирования из data и применяет его.
+// Идентификатор события становится идентификатором запроса в истории посылки.
+func (c *ScanConsumer) Handle(ctx context.Context, data []byte) error {
+	var e ScanEvent
+	err := json.Unmarshal(data, &e)
+	if err != nil {
+		return errors.Join(ErrInvalidScan, err)
+	}
+	if e.ID == "" || len(e.ID) > maxScanIDLen || e.Number == 0 {
+		return ErrInvalidScan
+	}
+
+	// склады сканируют посылки всех компаний: номер посылки уникален во всём трекере
+	_, err = c.store.WithContext(withAnyTenant(WithRequestID(ctx, e.ID))).ApplyScan(e)
+	return err
+}
+
+// RunNATS читает события из темы subject NATS, пока не отменён ctx.
+// Экземпляры трекера в одной группе queue делят события между собой.
+// События обрабатываются по одному, поэтому после отмены ctx обработка не прерывается на середине.
+func (c *ScanConsumer) RunNATS(ctx context.Context, conn *nats.Conn, subject, queue string) error {
+	msgs := make(chan *nats.Msg, scanBuffer)
+	sub, err := conn.ChanQueueSubscribe(subject, queue, msgs)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-msgs:
+			err := c.Handle(ctx, msg.Data)
+			if err != nil {
+				// неизвестная посылка, недопустимый статус и некорректное событие
+				// не исправятся при повторе, поэтому событие только попадает в лог
+				level := slog.LevelError
+				if errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrInvalidTransition) ||
+					errors.Is(err, ErrUnknownStatus) || errors.Is(err, ErrInvalidScan) {
+					level = slog.LevelWarn
+				}
+				c.store.logger.Log(ctx, level, "событие сканирования отклонено", "op", "scans.Handle", "subject", msg.Subject, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package tracker_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestApplyScan проверяет идемпотентность событий сканирования и проверку смены статуса
+func TestApplyScan(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	store := tracker.NewParcelStore(db)
+	id, err := store.Add(testsupport.NewParcel().Build())
+	require.NoError(t, err)
+	scanID := fmt.Sprintf("scan-%d-%d", id, time.Now().UnixNano())
+
+	// apply
+	applied, err := store.ApplyScan(tracker.ScanEvent{ID: scanID, Number: id, Status: tracker.ParcelStatusDelivered})
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	// check
+	// повтор того же события ничего не меняет
+	applied, err = store.ApplyScan(tracker.ScanEvent{ID: scanID, Number: id, Status: tracker.ParcelStatusDelivered})
+	require.NoError(t, err)
+	assert.False(t, applied)
+
+	// вернуть доставленную посылку в отправленные нельзя
+	_, err = store.ApplyScan(tracker.ScanEvent{ID: scanID + "-back", Number: id, Status: tracker.ParcelStatusSent})
+	assert.ErrorIs(t, err, tracker.ErrInvalidTransition)
+
+	_, err = store.ApplyScan(tracker.ScanEvent{ID: scanID + "-missing", Number: id + 1_000_000, Status: tracker.ParcelStatusSent})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	history, err := store.History(id)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, tracker.ParcelStatusDelivered, history[1].Status)
+}
+
+// TestScanConsumerNATS проверяет, что события сканирования из NATS меняют статус посылки
+func TestScanConsumerNATS(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	srv := natsserver.RunServer(&opts)
+	defer srv.Shutdown()
+
+	db := testsupport.OpenDB(t)
+
+	store := tracker.NewParcelStore(db)
+	id, err := store.Add(testsupport.NewParcel().Build())
+	require.NoError(t, err)
+
+	conn, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- tracker.NewScanConsumer(store).RunNATS(ctx, conn, "tracker.scans", "tracker")
+	}()
+	// подписка оформляется асинхронно, поэтому событие публикуется, пока статус не сменится
+	event := fmt.Sprintf(`{"id":"nats-%d-%d","number":%d,"status":"sent"}`, id, time.Now().UnixNano(), id)
+
+	// check
+	assert.Eventually(t, func() bool {
+		if err := conn.Publish("tracker.scans", []byte(event)); err != nil {
+			return false
+		}
+		p, err := store.Get(id)
+		return err == nil && p.Status == tracker.ParcelStatusSent
+	}, 5*time.Second, 20*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
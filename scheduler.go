@@ -0,0 +1,122 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// Job периодическая задача планировщика
+type Job struct {
+	// Name имя задачи в логах, метриках и настройках jobs
+	Name string
+	// Enabled запускается ли задача, если в настройках не сказано иное
+	Enabled  bool
+	Interval time.Duration
+	// Jitter наибольшая случайная добавка к паузе перед каждым запуском
+	Jitter time.Duration
+	// Run выполняет задачу один раз
+	Run func(ctx context.Context) error
+}
+
+// Scheduler запускает периодические задачи: каждую в своей горутине, сразу
+// после запуска и затем через Interval плюс случайную задержку до Jitter.
+// Следующий запуск задачи начинается только после окончания предыдущего.
+type Scheduler struct {
+	cfg     config.Jobs
+	logger  Logger
+	metrics *Metrics
+	jobs    []Job
+}
+
+// NewScheduler создаёт планировщик; настройки cfg переопределяют значения задач по умолчанию
+func NewScheduler(cfg config.Jobs, logger Logger, metrics *Metrics) *Scheduler {
+	return &Scheduler{cfg: cfg, logger: logger, metrics: metrics}
+}
+
+// Add добавляет задачу с настройками из jobs.<имя задачи>. Выключенная задача не запускается.
+func (s *Scheduler) Add(job Job) {
+	if c, ok := s.cfg[job.Name]; ok {
+		if c.Enabled != nil {
+			job.Enabled = *c.Enabled
+		}
+		if c.Interval > 0 {
+			job.Interval = c.Interval
+		}
+		if c.Jitter > 0 {
+			job.Jitter = c.Jitter
+		}
+	}
+	if !job.Enabled {
+		return
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// Jobs возвращает включённые задачи
+func (s *Scheduler) Jobs() []Job {
+	return s.jobs
+}
+
+// Run выполняет задачи, пока не отменён ctx, и возвращается после завершения всех запусков
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}()
+	}
+	wg.Wait()
+}
+
+// loop запускает задачу job, пока не отменён ctx
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	delay := jitter(job.Jitter)
+	for {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.RunJob(ctx, job)
+		delay = job.Interval + jitter(job.Jitter)
+	}
+}
+
+// RunJob выполняет задачу один раз и записывает результат в лог и метрики
+func (s *Scheduler) RunJob(ctx context.Context, job Job) error {
+	start := time.Now()
+	err := job.Run(ctx)
+	// задача, прерванная остановкой, не считается ни успешной, ни ошибочной
+	if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		return err
+	}
+
+	s.metrics.jobRun(job.Name, start, err)
+	if err != nil {
+		s.logger.Log(ctx, slog.LevelError, "задача завершилась с ошибкой", "op", "scheduler.RunJob",
+			"job", job.Name, "duration", time.Since(start), "error", err)
+		return err
+	}
+	s.logger.Log(ctx, slog.LevelDebug, "задача выполнена", "op", "scheduler.RunJob",
+		"job", job.Name, "duration", time.Since(start))
+	return nil
+}
+
+// jitter возвращает случайную задержку от 0 до limit
+func jitter(limit time.Duration) time.Duration {
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit + 1)
+}
@@ -0,0 +1,75 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// TestScheduler проверяет настройки задач, их периодический запуск, метрики и запись ошибок в лог
+func TestScheduler(t *testing.T) {
+	// prepare
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, "warn", "text")
+	require.NoError(t, err)
+	metrics := NewMetrics()
+	disabled := false
+	enabled := true
+	scheduler := NewScheduler(config.Jobs{
+		"off":  {Enabled: &disabled},
+		"on":   {Enabled: &enabled, Interval: time.Millisecond},
+		"fail": {Jitter: time.Millisecond},
+	}, logger, metrics)
+
+	var ticks, failures atomic.Int32
+	scheduler.Add(Job{Name: "off", Enabled: true, Interval: time.Millisecond, Run: func(context.Context) error {
+		t.Error("выключенная задача запущена")
+		return nil
+	}})
+	// задача включена и получает интервал из настроек
+	scheduler.Add(Job{Name: "on", Interval: time.Hour, Run: func(context.Context) error {
+		ticks.Add(1)
+		return nil
+	}})
+	scheduler.Add(Job{Name: "fail", Enabled: true, Interval: time.Hour, Run: func(context.Context) error {
+		failures.Add(1)
+		return errors.New("нет связи")
+	}})
+
+	require.Len(t, scheduler.Jobs(), 2)
+	assert.Equal(t, time.Millisecond, scheduler.Jobs()[0].Interval)
+	assert.Equal(t, time.Millisecond, scheduler.Jobs()[1].Jitter)
+
+	// run
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		scheduler.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		return ticks.Load() >= 3 && failures.Load() == 1
+	}, 5*time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	// check
+	// задача с ошибкой ждёт следующего запуска час
+	assert.Equal(t, int32(1), failures.Load())
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.jobRuns.WithLabelValues("fail", "error")))
+	assert.Equal(t, float64(ticks.Load()), testutil.ToFloat64(metrics.jobRuns.WithLabelValues("on", "ok")))
+	assert.Positive(t, testutil.ToFloat64(metrics.jobLastSuccess.WithLabelValues("on")))
+	assert.Zero(t, testutil.ToFloat64(metrics.jobLastSuccess.WithLabelValues("fail")))
+	assert.Contains(t, buf.String(), "задача завершилась с ошибкой")
+	assert.Contains(t, buf.String(), "job=fail")
+	assert.Contains(t, buf.String(), "нет связи")
+}
@@ -0,0 +1,105 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// searchDefaultLimit сколько посылок возвращает поиск без заданного Limit
+const searchDefaultLimit = 50
+
+// maxSearchWords сколько разных слов поисковой строки попадает в запрос FTS5: время
+// запроса растёт быстрее числа слов, и строка из тысяч слов занимала БД на минуты
+const maxSearchWords = 10
+
+// searchQuery выбирает посылки, найденные в полнотекстовом индексе parcel_search.
+// Совпадение в номере или трекинг-коде весит в 10 раз больше совпадения в адресе;
+// %s — условие WHERE из ListOptions.
+const searchQuery = `SELECT ` + parcelColumns + ` FROM parcel
+	JOIN (SELECT rowid AS match_number, bm25(parcel_search, 10.0, 1.0, 10.0) AS match_rank
+		FROM parcel_search WHERE parcel_search MATCH :query) ON match_number = number%s
+	ORDER BY match_rank, number LIMIT :limit`
+
+// ftsQuery переводит поисковую строку в запрос FTS5: посылка должна содержать все
+// слова. Слово из букв ищется как начало слова, а число — целиком, чтобы «12»
+// не находило дом 120 и посылку 1234. Каждое слово передаётся в кавычках, поэтому
+// синтаксис FTS5 из строки пользователя не выполняется. Слова делятся только по
+// пробелам и управляющим символам, а знаки внутри слова разбирает сам FTS5: его
+// таблицы Unicode старше таблиц Go, и слово, разделённое здесь по символу, который
+// FTS5 считает частью слова, не находило бы собственный адрес. Повторы слов отбрасываются, а в запрос
+// попадают только первые maxSearchWords разных слов.
+func ftsQuery(query string) string {
+	query = strings.NewReplacer("ё", "е", "Ё", "Е").Replace(query)
+	// управляющие символы FTS5 тоже считает разделителями, а нулевой байт обрывал бы запрос
+	chunks := strings.FieldsFunc(query, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	})
+	seen := map[string]bool{}
+	terms := make([]string, 0, min(len(chunks), maxSearchWords))
+	for _, chunk := range chunks {
+		// слово без букв и цифр, например «—», ничего не ищет
+		words := strings.FieldsFunc(chunk, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		key := strings.ToLower(chunk)
+		if len(words) == 0 || seen[key] {
+			continue
+		}
+		if len(terms) == maxSearchWords {
+			break
+		}
+		seen[key] = true
+		term := `"` + strings.ReplaceAll(chunk, `"`, `""`) + `"`
+		if strings.ContainsFunc(words[len(words)-1], unicode.IsLetter) {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
+
+// Search ищет посылки по словам из адреса, номеру и трекинг-коду, например
+// «Ленина 12», начиная с лучших совпадений. Фильтры opts сужают выборку;
+// без Limit возвращается не больше searchDefaultLimit посылок.
+func (s ParcelStore) Search(query string, opts ListOptions) ([]Parcel, error) {
+	defer s.metrics.observeQuery("Search", time.Now())
+	span := s.startSpan("Search", attrClient.Int(opts.Client), attrStatus.String(opts.Status))
+	defer span.End()
+
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = searchDefaultLimit
+	}
+
+	b := opts.query(parcelColumns, s.tenant())
+	args := append(b.args, sql.Named("query", match), sql.Named("limit", opts.Limit))
+	rows, err := s.readUnprepared(fmt.Sprintf(searchQuery, b.whereClause()), args...)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	res, err := s.collectParcels(rows)
+	return res, spanError(span, err)
+}
+
+// Search ищет посылки по адресу, номеру и трекинг-коду; клиент ищет только среди своих посылок
+func (s ParcelService) Search(ctx context.Context, query string, opts ListOptions) ([]Parcel, error) {
+	err := s.check(ctx, actionRead)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := CallerFromContext(ctx); ok && c.Role == RoleClient {
+		err = authorizeOwner(ctx, opts.Client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.store.WithContext(ctx).Search(query, opts)
+}
@@ -0,0 +1,145 @@
+package tracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/api"
+)
+
+// TestSearch проверяет полнотекстовый поиск посылок по адресу, номеру и трекинг-коду
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	// метка в адресах, чтобы поиск не находил посылки других тестов
+	marker := fmt.Sprintf("метка%d", randRange.Intn(10_000_000))
+	client := randRange.Intn(10_000_000) + 1
+	add := func(client int, address, token string) int {
+		t.Helper()
+		id, err := store.Add(Parcel{Client: client, Status: ParcelStatusRegistered, Address: address + " " + marker,
+			CreatedAt: "2024-01-01T00:00:00Z", TrackingToken: token})
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Delete(id) })
+		return id
+	}
+	lenina := add(client, "г. Тверь, ул. Ленина, д. 12", "")
+	add(client, "г. Тверь, ул. Ленинградская, д. 3", "")
+	pushkina := add(client+1, "ул. Пушкина, д. 12", "")
+	token := "tok" + marker[len("метка"):]
+	semenovskaya := add(client, "ул. Семёновская, д. 1", token)
+	numbers := func(parcels []Parcel) []int {
+		var res []int
+		for _, p := range parcels {
+			res = append(res, p.Number)
+		}
+		return res
+	}
+	search := func(query string, opts ListOptions) []int {
+		t.Helper()
+		parcels, err := store.Search(query, opts)
+		require.NoError(t, err)
+		return numbers(parcels)
+	}
+
+	// check
+	assert.Equal(t, []int{lenina}, search("Ленина 12 "+marker, ListOptions{}))
+	assert.Len(t, search("ленин "+marker, ListOptions{}), 2)
+	assert.ElementsMatch(t, []int{lenina, pushkina}, search("12 "+marker, ListOptions{}))
+	assert.Equal(t, []int{pushkina}, search("12 "+marker, ListOptions{Client: client + 1}))
+	assert.Len(t, search("12 "+marker, ListOptions{Limit: 1}), 1)
+	// короткий адрес, где слова занимают большую часть, ранжируется выше
+	assert.Equal(t, []int{pushkina, lenina}, search("12 "+marker, ListOptions{}))
+	assert.Equal(t, []int{semenovskaya}, search("семеновская "+marker, ListOptions{}))
+	assert.Equal(t, []int{semenovskaya}, search(token, ListOptions{}))
+	assert.Equal(t, []int{pushkina}, search(fmt.Sprint(pushkina), ListOptions{Client: client + 1}))
+	// синтаксис FTS5 в строке поиска не выполняется
+	assert.Empty(t, search(`"`, ListOptions{}))
+	assert.Empty(t, search("NOT OR "+marker, ListOptions{}))
+
+	// индекс следует за изменениями посылок
+	require.NoError(t, store.SetAddress(pushkina, "ул. Гоголя, д. 7 "+marker))
+	assert.Empty(t, search("пушкина "+marker, ListOptions{}))
+	assert.Equal(t, []int{pushkina}, search("гоголя "+marker, ListOptions{}))
+	require.NoError(t, store.Delete(lenina))
+	assert.Empty(t, search("Ленина 12 "+marker, ListOptions{}))
+
+	// http
+	srv := httptest.NewServer(NewHTTPHandler(NewParcelService(store)))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/parcels/search?q=" + url.QueryEscape("Семёновская "+marker))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var found []api.Parcel
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&found))
+	require.Len(t, found, 1)
+	assert.Equal(t, semenovskaya, found[0].Number)
+}
+
+// TestFTSQuery проверяет перевод поисковой строки в запрос FTS5
+func TestFTSQuery(t *testing.T) {
+	assert.Equal(t, `"ул."* "Ленина,"* "12"`, ftsQuery("ул. Ленина, 12"))
+	// операторы FTS5 и кавычки ищутся как обычные слова
+	assert.Equal(t, `"""елка"""* "OR"* "Елка*"* "NEAR(a"*`, ftsQuery(`"ёлка" OR Ёлка* NEAR(a`))
+	// знак, которого нет в таблицах Unicode у FTS5, не делит слово
+	assert.Equal(t, `"0Лен߽00"`, ftsQuery("0Лен߽00"))
+	assert.Equal(t, `"00"`, ftsQuery("\x0000"))
+	// повторы не утяжеляют запрос, а слов в нём не больше maxSearchWords
+	assert.Equal(t, `"а"*`, ftsQuery(strings.Repeat("а А ", 5000)))
+	var words []string
+	for i := 0; i < 3*maxSearchWords; i++ {
+		words = append(words, fmt.Sprint(i))
+	}
+	assert.Equal(t, strings.Join(words[:maxSearchWords], " "), strings.ReplaceAll(ftsQuery(strings.Join(words, " ")), `"`, ""))
+	assert.Empty(t, ftsQuery("'; -- /* */"))
+}
+
+// FuzzSearch проверяет, что поиск по любой строке не падает на синтаксисе FTS5
+// и находит посылку по её собственному адресу:
+// go test -run '^$' -fuzz FuzzSearch -fuzztime 30s .
+func FuzzSearch(f *testing.F) {
+	for _, seed := range []string{
+		"Ленина 12",
+		`"Ленина" OR 12*`,
+		"NEAR(a b) AND NOT c:d ^e",
+		"'; DROP TABLE parcel_search; --",
+		"ёлка Ёж",
+		"Zürich Straße",
+		"東京 千代田",
+		"\x00\xff",
+		strings.Repeat("а ", 1000),
+	} {
+		f.Add(seed)
+	}
+	store := newTestStore(f)
+
+	f.Fuzz(func(t *testing.T, query string) {
+		parcels, err := store.Search(query, ListOptions{})
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(parcels), searchDefaultLimit)
+
+		if validateAddress(query) != nil || ftsQuery(query) == "" {
+			return
+		}
+		p := mustAddParcel(t, store, newParcelFixture().address(query).build())
+		parcels, err = store.Search(query, ListOptions{Limit: 1000})
+		require.NoError(t, err)
+		var numbers []int
+		for _, found := range parcels {
+			numbers = append(numbers, found.Number)
+		}
+		assert.Contains(t, numbers, p.Number, "запрос FTS5: %s", ftsQuery(query))
+	})
+}
@@ -0,0 +1,216 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// seedBatch сколько сгенерированных посылок записывается одной транзакцией
+const seedBatch = 500
+
+// ErrInvalidSeed возвращается при неположительном числе посылок или клиентов и пустом периоде
+var ErrInvalidSeed = errors.New("неверные параметры генерации")
+
+// queryMaxNumber наибольший номер посылки, в том числе перенесённой в архив
+const queryMaxNumber = `SELECT MAX(n) FROM (
+		SELECT COALESCE(MAX(number), 0) AS n FROM parcel
+		UNION ALL SELECT COALESCE(MAX(number), 0) FROM parcel_archive
+	) m`
+
+// seedCities города с почтовыми индексами, улицы и типы улиц, из которых собираются адреса
+var (
+	seedCities = []struct{ name, postal string }{
+		{"Москва", "101000"}, {"Санкт-Петербург", "190000"}, {"Псков", "180000"}, {"Саратов", "410000"},
+		{"Казань", "420000"}, {"Томск", "634000"}, {"Новосибирск", "630000"}, {"Екатеринбург", "620000"},
+		{"Нижний Новгород", "603000"}, {"Самара", "443000"}, {"Калининград", "236000"}, {"Владивосток", "690000"},
+	}
+	seedStreets = []string{"Ленина", "Советская", "Мира", "Садовая", "Гагарина", "Пушкина", "Лесная",
+		"Колотушкина", "Баумана", "Козлова", "Набережная", "Молодёжная", "Школьная", "Зелёная"}
+	seedStreetKinds = []string{"ул.", "ул.", "ул.", "пр.", "пер.", "наб."}
+)
+
+// SeedOptions объём и период данных, которые генерирует Seed
+type SeedOptions struct {
+	// Parcels сколько посылок сгенерировать
+	Parcels int
+	// Clients между сколькими клиентами (1..Clients) распределить посылки; у немногих
+	// клиентов посылок много, у большинства — несколько, как у настоящих отправителей
+	Clients int
+	// From и To период регистрации посылок; по умолчанию последние 30 дней.
+	// Статусы и история посылок доходят до момента To.
+	From, To time.Time
+	// Seed зерно генератора: с одним зерном и параметрами получаются одни и те же посылки
+	// (кроме номеров и кодов отслеживания)
+	Seed uint64
+}
+
+// withDefaults возвращает параметры с периодом по умолчанию
+func (o SeedOptions) withDefaults() SeedOptions {
+	if o.To.IsZero() {
+		o.To = time.Now()
+	}
+	if o.From.IsZero() {
+		o.From = o.To.AddDate(0, 0, -30)
+	}
+	o.From, o.To = o.From.UTC(), o.To.UTC()
+	return o
+}
+
+// validate проверяет параметры генерации
+func (o SeedOptions) validate() error {
+	if o.Parcels < 1 || o.Clients < 1 || !o.From.Before(o.To) {
+		return fmt.Errorf("%w: нужны посылки и клиенты и период, где from раньше to", ErrInvalidSeed)
+	}
+	return nil
+}
+
+// seeder генерирует посылки с историей статусов
+type seeder struct {
+	rnd     *rand.Rand
+	clients *rand.Zipf
+	opts    SeedOptions
+}
+
+func newSeeder(opts SeedOptions) *seeder {
+	rnd := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15))
+	return &seeder{
+		rnd: rnd,
+		// распределение Ципфа: клиент 1 отправляет больше всех, дальше всё реже
+		clients: rand.NewZipf(rnd, 1.2, 2, uint64(opts.Clients-1)),
+		opts:    opts,
+	}
+}
+
+// seedStep шаг пути посылки: статус и через сколько после предыдущего шага он наступает
+type seedStep struct {
+	status string
+	after  time.Duration
+}
+
+// between возвращает случайную длительность от min до max
+func (g *seeder) between(min, max time.Duration) time.Duration {
+	return min + time.Duration(g.rnd.Int64N(int64(max-min)+1))
+}
+
+// address собирает адрес доставки с почтовым индексом
+func (g *seeder) address() string {
+	city := seedCities[g.rnd.IntN(len(seedCities))]
+	return fmt.Sprintf("%s, %s, %s %s, д. %d", city.postal, city.name,
+		seedStreetKinds[g.rnd.IntN(len(seedStreetKinds))], seedStreets[g.rnd.IntN(len(seedStreets))], g.rnd.IntN(120)+1)
+}
+
+// parcel генерирует посылку с номером number: регистрация в периоде, отправка
+// через 1–48 часов, доставка через 12 часов – 7 дней после отправки; у каждой
+// двадцатой посылки в пути — задержка на таможне до трёх суток. Шаги позже
+// окончания периода не наступают, поэтому свежие посылки ещё в пути.
+func (g *seeder) parcel(number int) (parcelRecord, error) {
+	token, err := newTrackingToken()
+	if err != nil {
+		return parcelRecord{}, err
+	}
+	created := g.opts.From.Add(time.Duration(g.rnd.Int64N(int64(g.opts.To.Sub(g.opts.From)))))
+	p := parcelRecord{
+		Number:        number,
+		Client:        int(g.clients.Uint64()) + 1,
+		Address:       g.address(),
+		CreatedAt:     created.Format(time.RFC3339),
+		TrackingToken: token,
+	}
+	at := created
+	steps := []seedStep{{ParcelStatusRegistered, 0}, {ParcelStatusSent, g.between(time.Hour, 48*time.Hour)}}
+	if g.rnd.IntN(20) == 0 {
+		steps = append(steps,
+			seedStep{ParcelStatusCustomsHold, g.between(6*time.Hour, 48*time.Hour)},
+			seedStep{ParcelStatusSent, g.between(12*time.Hour, 72*time.Hour)})
+	}
+	steps = append(steps, seedStep{ParcelStatusDelivered, g.between(12*time.Hour, 7*24*time.Hour)})
+
+	for _, step := range steps {
+		at = at.Add(step.after)
+		if at.After(g.opts.To) {
+			break
+		}
+		p.Status = step.status
+		p.History = append(p.History, historyRecord{Status: step.status, ChangedAt: at.Format(time.RFC3339)})
+	}
+	p.Version = len(p.History)
+	return p, nil
+}
+
+// Seed генерирует opts.Parcels правдоподобных посылок с историей статусов для демонстраций
+// и нагрузочных тестов: адреса в разных городах, клиенты с разным числом посылок, время
+// регистрации в периоде opts.From–opts.To и продвижение по статусам до его конца.
+// Посылки записываются пачками с номерами после наибольшего существующего в компанию
+// из контекста; событий outbox и уведомлений не создаётся. Возвращает число посылок.
+func (s ParcelStore) Seed(opts SeedOptions) (int, error) {
+	start := time.Now()
+	span := s.startSpan("Seed")
+	defer span.End()
+
+	n, err := s.seed(opts)
+	spanError(span, err)
+	s.metrics.observeQuery("Seed", start)
+	logResult(s.ctx, s.logger, "store.Seed", start, err, "parcels", n, "clients", opts.Clients)
+	return n, err
+}
+
+func (s ParcelStore) seed(opts SeedOptions) (int, error) {
+	opts = opts.withDefaults()
+	err := opts.validate()
+	if err != nil {
+		return 0, err
+	}
+	g := newSeeder(opts)
+
+	n := 0
+	for n < opts.Parcels {
+		size := min(seedBatch, opts.Parcels-n)
+		err = s.withRetry("store.Seed", func(s ParcelStore) error {
+			return s.seedBatch(g, size)
+		})
+		if err != nil {
+			return n, err
+		}
+		n += size
+	}
+	return n, nil
+}
+
+// seedBatch записывает в одной транзакции size посылок генератора g. Генератор
+// продвигается при каждой попытке, поэтому повтор после блокировки записывает другие посылки.
+func (s ParcelStore) seedBatch(g *seeder, size int) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var last int
+	err = s.queryRow(tx, queryMaxNumber).Scan(&last)
+	if err != nil {
+		return err
+	}
+	for i := 1; i <= size; i++ {
+		p, err := g.parcel(last + i)
+		if err != nil {
+			return err
+		}
+		err = s.restoreParcel(tx, p)
+		if err != nil {
+			return fmt.Errorf("посылка № %d: %w", p.Number, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Seed заполняет БД сгенерированными посылками; доступно тем, кто загружает посылки
+func (s ParcelService) Seed(ctx context.Context, opts SeedOptions) (int, error) {
+	err := s.check(ctx, actionImport)
+	if err != nil {
+		return 0, err
+	}
+	return s.store.WithContext(ctx).Seed(opts)
+}
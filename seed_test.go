@@ -0,0 +1,85 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSeed проверяет генерацию посылок: объём, период, историю статусов и повторяемость по зерну
+func TestSeed(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	service := NewParcelService(NewParcelStore(db))
+	from := time.Date(2001, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 10)
+	opts := SeedOptions{Parcels: seedBatch + 20, Clients: 7, From: from, To: to, Seed: 42}
+	seed := func() []Parcel {
+		ctx := WithTenant(context.Background(), fmt.Sprintf("seed-%d", randRange.Intn(10_000_000)))
+		n, err := service.Seed(ctx, opts)
+		require.NoError(t, err)
+		assert.Equal(t, opts.Parcels, n)
+		parcels, err := service.List(ctx, ListOptions{})
+		require.NoError(t, err)
+		require.Len(t, parcels, opts.Parcels)
+		return parcels
+	}
+
+	// seed
+	parcels := seed()
+	ctx := WithTenant(context.Background(), parcels[0].Tenant)
+	statuses := map[string]int{}
+	clients := map[int]int{}
+	for _, p := range parcels {
+		created, err := time.Parse(time.RFC3339, p.CreatedAt)
+		require.NoError(t, err)
+		assert.False(t, created.Before(from) || created.After(to), p.CreatedAt)
+		assert.NotEmpty(t, p.TrackingToken)
+		assert.True(t, p.Client >= 1 && p.Client <= opts.Clients, p.Client)
+		statuses[p.Status]++
+		clients[p.Client]++
+
+		// история идёт вперёд по времени и статусам и заканчивается текущим статусом
+		history, err := service.History(ctx, p.Number)
+		require.NoError(t, err)
+		require.NotEmpty(t, history)
+		assert.Equal(t, ParcelStatusRegistered, history[0].Status)
+		assert.Equal(t, p.Status, history[len(history)-1].Status)
+		assert.Equal(t, len(history), p.Version)
+		for i := 1; i < len(history); i++ {
+			assert.NoError(t, CheckTransition(history[i-1].Status, history[i].Status))
+			assert.LessOrEqual(t, history[i-1].ChangedAt, history[i].ChangedAt)
+		}
+		if p.Status == ParcelStatusDelivered {
+			assert.Equal(t, history[len(history)-1].ChangedAt, p.DeliveredAt)
+		}
+	}
+	// за 10 дней часть посылок доставлена, а свежие ещё в пути
+	assert.Positive(t, statuses[ParcelStatusDelivered])
+	assert.Positive(t, statuses[ParcelStatusRegistered]+statuses[ParcelStatusSent])
+	// у первого клиента посылок больше, чем у последнего
+	assert.Greater(t, clients[1], clients[opts.Clients])
+
+	// с тем же зерном получаются те же посылки
+	again := seed()
+	for i := range parcels {
+		assert.Equal(t, parcels[i].Address, again[i].Address)
+		assert.Equal(t, parcels[i].Client, again[i].Client)
+		assert.Equal(t, parcels[i].CreatedAt, again[i].CreatedAt)
+		assert.Equal(t, parcels[i].Status, again[i].Status)
+	}
+	assert.Greater(t, again[0].Number, parcels[len(parcels)-1].Number)
+
+	// validate
+	_, err := service.Seed(ctx, SeedOptions{Parcels: 1, Clients: 1, From: to, To: from})
+	assert.ErrorIs(t, err, ErrInvalidSeed)
+	_, err = service.Seed(WithCaller(ctx, Caller{Client: 1, Role: RoleCourier}), opts)
+	assert.ErrorIs(t, err, ErrForbidden)
+}
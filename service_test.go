@@ -0,0 +1,104 @@
+package tracker_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/trackermock"
+)
+
+// newMockService возвращает сервис без БД: его основные операции идут в заглушку storage
+func newMockService(storage *trackermock.Storage) tracker.ParcelService {
+	return tracker.NewParcelService(tracker.ParcelStore{}, tracker.WithStorage(storage))
+}
+
+// TestServiceWithMockStorage проверяет правила сервиса на заглушке хранилища
+func TestServiceWithMockStorage(t *testing.T) {
+	t.Parallel()
+
+	// next status
+	storage := trackermock.NewStorage(t)
+	storage.GetFunc = func(ctx context.Context, number int) (tracker.Parcel, error) {
+		return tracker.Parcel{Number: number, Client: 1, Status: tracker.ParcelStatusSent}, nil
+	}
+	storage.SetStatusFunc = func(ctx context.Context, number int, status string) error {
+		return nil
+	}
+	service := newMockService(storage)
+	require.NoError(t, service.NextStatus(context.Background(), 7))
+	assert.Equal(t, []trackermock.Call{
+		{Method: "Get", Args: []any{7}},
+		{Method: "SetStatus", Args: []any{7, tracker.ParcelStatusDelivered}},
+	}, storage.Calls())
+
+	// unknown status
+	// неизвестный статус отклоняется до обращения к хранилищу
+	storage = trackermock.NewStorage(t)
+	service = newMockService(storage)
+	assert.ErrorIs(t, service.SetStatus(context.Background(), 7, "lost"), tracker.ErrUnknownStatus)
+	assert.Empty(t, storage.Calls())
+
+	// foreign parcel
+	// клиент не меняет адрес чужой посылки
+	storage = trackermock.NewStorage(t)
+	storage.GetFunc = func(ctx context.Context, number int) (tracker.Parcel, error) {
+		return tracker.Parcel{Number: number, Client: 2, Status: tracker.ParcelStatusRegistered}, nil
+	}
+	service = newMockService(storage)
+	client := tracker.WithCaller(context.Background(), tracker.Caller{Client: 1, Role: tracker.RoleClient})
+	assert.ErrorIs(t, service.ChangeAddress(client, 7, "new address"), tracker.ErrForbidden)
+	assert.Equal(t, []trackermock.Call{{Method: "Get", Args: []any{7}}}, storage.Calls())
+
+	// context
+	// хранилище получает контекст вызова сервиса
+	storage = trackermock.NewStorage(t)
+	storage.GetByClientFunc = func(ctx context.Context, client int) ([]tracker.Parcel, error) {
+		assert.Equal(t, "acme", tracker.TenantFromContext(ctx))
+		return []tracker.Parcel{{Number: 7, Client: client}}, nil
+	}
+	service = newMockService(storage)
+	parcels, err := service.ClientParcels(tracker.WithTenant(context.Background(), "acme"), 1)
+	require.NoError(t, err)
+	assert.Len(t, parcels, 1)
+}
+
+// TestHTTPWithMockStorage проверяет ответы HTTP API на заглушке хранилища
+func TestHTTPWithMockStorage(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	storage := trackermock.NewStorage(t)
+	storage.GetFunc = func(ctx context.Context, number int) (tracker.Parcel, error) {
+		if number != 7 {
+			return tracker.Parcel{}, sql.ErrNoRows
+		}
+		return tracker.Parcel{Number: 7, Client: 1, Status: tracker.ParcelStatusSent, Version: 3}, nil
+	}
+	handler := tracker.NewHTTPHandler(newMockService(storage))
+
+	// get
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/parcels/7", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+	var body struct {
+		Number int    `json:"number"`
+		Status string `json:"status"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, 7, body.Number)
+	assert.Equal(t, tracker.ParcelStatusSent, body.Status)
+
+	// not found
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/parcels/8", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,272 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// shardSpan сколько номеров посылок приходится на одно депо: номер посылки депо
+// с префиксом prefix лежит в [prefix*shardSpan, (prefix+1)*shardSpan)
+const shardSpan = 1_000_000_000
+
+// ErrUnknownDepot возвращается для кода депо или номера посылки, которые не относятся ни к одной БД депо
+var ErrUnknownDepot = errors.New("неизвестное депо")
+
+// DepotShard БД посылок одного депо
+type DepotShard struct {
+	Depot  string
+	Prefix int
+	Store  ParcelStore
+}
+
+// ShardedStore распределяет посылки по БД депо: новая посылка записывается в БД
+// своего депо, а операции с посылкой идут в БД, префикс которой стоит в её номере
+type ShardedStore struct {
+	// shards БД депо по возрастанию префикса, поэтому и номера посылок в них возрастают
+	shards []DepotShard
+	// dbs подключения, открытые OpenShards; закрываются в Close
+	dbs []*sql.DB
+}
+
+// NewShardedStore создаёт маршрутизацию по хранилищам депо; номера посылок в хранилищах
+// должны начинаться с их префиксов, например после reserveShardNumbers
+func NewShardedStore(shards ...DepotShard) (*ShardedStore, error) {
+	sorted := slices.Clone(shards)
+	slices.SortFunc(sorted, func(a, b DepotShard) int { return a.Prefix - b.Prefix })
+	for i, sh := range sorted {
+		if sh.Prefix < 1 {
+			return nil, fmt.Errorf("депо %s: префикс должен быть положительным", sh.Depot)
+		}
+		for _, prev := range sorted[:i] {
+			if prev.Depot == sh.Depot || prev.Prefix == sh.Prefix {
+				return nil, fmt.Errorf("депо %s: код или префикс повторяется", sh.Depot)
+			}
+		}
+	}
+	return &ShardedStore{shards: sorted}, nil
+}
+
+// OpenShards открывает БД депо из cfg.Shards с параметрами подключения cfg, приводит их
+// схему к актуальной версии и резервирует в каждой номера посылок с префиксом депо
+func OpenShards(cfg config.DB, opts ...StoreOption) (*ShardedStore, error) {
+	var shards []DepotShard
+	var dbs []*sql.DB
+	closeAll := func(err error) error {
+		for _, db := range dbs {
+			err = errors.Join(err, db.Close())
+		}
+		return err
+	}
+	for _, sh := range cfg.Shards {
+		shardCfg := cfg
+		shardCfg.Path = sh.Path
+		// резервная БД задана только для основной
+		shardCfg.Monitor.Standby = ""
+		db, err := OpenDB(shardCfg)
+		if err != nil {
+			return nil, closeAll(err)
+		}
+		dbs = append(dbs, db)
+		err = Migrate(db)
+		if err == nil {
+			err = reserveShardNumbers(db, sh.Prefix)
+		}
+		if err != nil {
+			return nil, closeAll(fmt.Errorf("депо %s: %w", sh.Depot, err))
+		}
+		shards = append(shards, DepotShard{Depot: sh.Depot, Prefix: sh.Prefix, Store: NewParcelStore(db, opts...)})
+	}
+	s, err := NewShardedStore(shards...)
+	if err != nil {
+		return nil, closeAll(err)
+	}
+	s.dbs = dbs
+	return s, nil
+}
+
+// reserveShardNumbers сдвигает счётчик номеров посылок новой БД депо к началу диапазона
+// его префикса; если в БД уже есть номера вне диапазона, возвращается ошибка
+func reserveShardNumbers(db *sql.DB, prefix int) error {
+	var maxNumber int
+	err := db.QueryRow("SELECT COALESCE(MAX(seq), 0) FROM sqlite_sequence WHERE name = 'parcel'").Scan(&maxNumber)
+	if err != nil {
+		return err
+	}
+	switch {
+	case maxNumber/shardSpan == prefix:
+		return nil
+	case maxNumber != 0:
+		// посылки без префикса в номере не нашлись бы через ForNumber
+		return fmt.Errorf("номера посылок в БД не относятся к префиксу %d", prefix)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("DELETE FROM sqlite_sequence WHERE name = 'parcel'")
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO sqlite_sequence (name, seq) VALUES ('parcel', :seq)", sql.Named("seq", prefix*shardSpan))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// WithContext возвращает маршрутизацию, хранилища депо которой работают в контексте ctx
+func (s *ShardedStore) WithContext(ctx context.Context) *ShardedStore {
+	shards := make([]DepotShard, len(s.shards))
+	for i, sh := range s.shards {
+		sh.Store = sh.Store.WithContext(ctx)
+		shards[i] = sh
+	}
+	return &ShardedStore{shards: shards, dbs: s.dbs}
+}
+
+// Shards возвращает БД депо по возрастанию префикса
+func (s *ShardedStore) Shards() []DepotShard {
+	return slices.Clone(s.shards)
+}
+
+// OnChange регистрирует hook в хранилище каждого депо (см. ParcelStore.OnChange)
+// и возвращает функцию, которая снимает его во всех депо. Изменения других процессов
+// приходят, если для БД каждого депо запущен свой ChangeWatcher.
+func (s *ShardedStore) OnChange(hook func(Event)) func() {
+	cancels := make([]func(), len(s.shards))
+	for i, sh := range s.shards {
+		cancels[i] = sh.Store.OnChange(hook)
+	}
+	return func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
+
+// Depot возвращает хранилище депо с кодом depot
+func (s *ShardedStore) Depot(depot string) (ParcelStore, error) {
+	for _, sh := range s.shards {
+		if sh.Depot == depot {
+			return sh.Store, nil
+		}
+	}
+	return ParcelStore{}, fmt.Errorf("%w %q", ErrUnknownDepot, depot)
+}
+
+// ForNumber возвращает хранилище депо по префиксу номера посылки
+func (s *ShardedStore) ForNumber(number int) (ParcelStore, error) {
+	prefix := number / shardSpan
+	for _, sh := range s.shards {
+		if sh.Prefix == prefix {
+			return sh.Store, nil
+		}
+	}
+	return ParcelStore{}, fmt.Errorf("%w для посылки %d", ErrUnknownDepot, number)
+}
+
+// Add регистрирует посылку в БД депо с кодом depot и возвращает её номер
+func (s *ShardedStore) Add(depot string, p Parcel) (int, error) {
+	store, err := s.Depot(depot)
+	if err != nil {
+		return 0, err
+	}
+	return store.Add(p)
+}
+
+// Get возвращает посылку из БД депо, префикс которой стоит в номере
+func (s *ShardedStore) Get(number int) (Parcel, error) {
+	store, err := s.ForNumber(number)
+	if err != nil {
+		return Parcel{}, err
+	}
+	return store.Get(number)
+}
+
+// SetStatus меняет статус посылки в БД её депо
+func (s *ShardedStore) SetStatus(number int, status string) error {
+	store, err := s.ForNumber(number)
+	if err != nil {
+		return err
+	}
+	return store.SetStatus(number, status)
+}
+
+// SetAddress меняет адрес посылки в БД её депо
+func (s *ShardedStore) SetAddress(number int, address string) error {
+	store, err := s.ForNumber(number)
+	if err != nil {
+		return err
+	}
+	return store.SetAddress(number, address)
+}
+
+// Delete удаляет посылку из БД её депо
+func (s *ShardedStore) Delete(number int) error {
+	store, err := s.ForNumber(number)
+	if err != nil {
+		return err
+	}
+	return store.Delete(number)
+}
+
+// History возвращает историю статусов посылки из БД её депо
+func (s *ShardedStore) History(number int) ([]ParcelChange, error) {
+	store, err := s.ForNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	return store.History(number)
+}
+
+// GetByClient опрашивает БД всех депо одновременно и возвращает посылки клиента
+// по возрастанию номера. Если хотя бы одна БД недоступна, возвращается ошибка.
+func (s *ShardedStore) GetByClient(client int) ([]Parcel, error) {
+	results := make([][]Parcel, len(s.shards))
+	errs := make([]error, len(s.shards))
+	var wg sync.WaitGroup
+	for i, sh := range s.shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = sh.Store.GetByClient(client)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("депо %s: %w", sh.Depot, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	// префиксы депо возрастают, поэтому склеенные результаты уже упорядочены по номеру
+	return slices.Concat(results...), nil
+}
+
+// Board возвращает доску диспетчера депо с кодом depot
+func (s *ShardedStore) Board(depot string, opts BoardOptions) (Board, error) {
+	store, err := s.Depot(depot)
+	if err != nil {
+		return Board{}, err
+	}
+	return store.GetBoard(opts)
+}
+
+// Close закрывает подготовленные запросы хранилищ депо и БД, открытые OpenShards
+func (s *ShardedStore) Close() error {
+	var err error
+	for _, sh := range s.shards {
+		err = errors.Join(err, sh.Store.Close())
+	}
+	for _, db := range s.dbs {
+		err = errors.Join(err, db.Close())
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// TestShardedStore проверяет запись посылок в БД депо и маршрутизацию по префиксу номера
+func TestShardedStore(t *testing.T) {
+	// prepare
+	cfg := testConfig.DB
+	dir := t.TempDir()
+	cfg.Shards = []config.Shard{
+		{Depot: "spb", Prefix: 2, Path: filepath.Join(dir, "spb.db")},
+		{Depot: "msk", Prefix: 1, Path: filepath.Join(dir, "msk.db")},
+	}
+	shards, err := OpenShards(cfg)
+	require.NoError(t, err)
+	defer shards.Close()
+	store := shards.WithContext(context.Background())
+
+	// add
+	p := getTestParcel()
+	spb, err := store.Add("spb", p)
+	require.NoError(t, err)
+	msk, err := store.Add("msk", p)
+	require.NoError(t, err)
+
+	// check
+	assert.Equal(t, 2, spb/shardSpan)
+	assert.Equal(t, 1, msk/shardSpan)
+	mskStore, err := store.Depot("msk")
+	require.NoError(t, err)
+	_, err = mskStore.Get(spb)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, store.SetStatus(spb, ParcelStatusSent))
+	stored, err := store.Get(spb)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusSent, stored.Status)
+
+	// посылки клиента из всех депо по возрастанию номера
+	parcels, err := store.GetByClient(p.Client)
+	require.NoError(t, err)
+	require.Len(t, parcels, 2)
+	assert.Equal(t, msk, parcels[0].Number)
+	assert.Equal(t, spb, parcels[1].Number)
+
+	// доска депо видит только его посылки
+	board, err := store.Board("spb", BoardOptions{})
+	require.NoError(t, err)
+	col, _ := board.Column(ParcelStatusSent)
+	assert.Equal(t, 1, col.Count)
+	col, _ = board.Column(ParcelStatusRegistered)
+	assert.Zero(t, col.Count)
+
+	_, err = store.Add("kzn", p)
+	assert.ErrorIs(t, err, ErrUnknownDepot)
+	_, err = store.Board("kzn", BoardOptions{})
+	assert.ErrorIs(t, err, ErrUnknownDepot)
+	_, err = store.Get(3*shardSpan + 1)
+	assert.ErrorIs(t, err, ErrUnknownDepot)
+
+	// повторное открытие продолжает номера депо, а чужой префикс для БД с посылками не подходит
+	reopened, err := OpenShards(cfg)
+	require.NoError(t, err)
+	defer reopened.Close()
+	next, err := reopened.Add("msk", p)
+	require.NoError(t, err)
+	assert.Equal(t, msk+1, next)
+
+	cfg.Shards[1].Prefix = 3
+	_, err = OpenShards(cfg)
+	assert.Error(t, err)
+}
+
+// TestShardedChangeWatchers проверяет, что у каждой БД депо свой журнал изменений
+// и наблюдатель депо передаёт обработчикам ShardedStore.OnChange только её изменения
+func TestShardedChangeWatchers(t *testing.T) {
+	// prepare
+	cfg := testConfig.DB
+	dir := t.TempDir()
+	cfg.Shards = []config.Shard{
+		{Depot: "spb", Prefix: 2, Path: filepath.Join(dir, "spb.db")},
+		{Depot: "msk", Prefix: 1, Path: filepath.Join(dir, "msk.db")},
+	}
+	shards, err := OpenShards(cfg)
+	require.NoError(t, err)
+	defer shards.Close()
+	var events []Event
+	cancel := shards.OnChange(func(e Event) {
+		events = append(events, e)
+	})
+	defer cancel()
+	ctx := context.Background()
+	var watchers []*ChangeWatcher
+	for _, sh := range shards.Shards() {
+		w := NewChangeWatcher(sh.Store, config.Changes{Interval: time.Second}, discardLogger{})
+		require.NoError(t, w.Poll(ctx))
+		watchers = append(watchers, w)
+	}
+
+	// change
+	store := shards.WithContext(ctx)
+	spb, err := store.Add("spb", getTestParcel())
+	require.NoError(t, err)
+	msk, err := store.Add("msk", getTestParcel())
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// check
+	// watchers идут по возрастанию префикса: сначала msk, затем spb
+	require.NoError(t, watchers[0].Poll(ctx))
+	assert.Equal(t, []Event{{Type: EventParcelAdded, Number: msk, Tenant: DefaultTenant}}, events)
+	require.NoError(t, watchers[1].Poll(ctx))
+	assert.Equal(t, []Event{
+		{Type: EventParcelAdded, Number: msk, Tenant: DefaultTenant},
+		{Type: EventParcelAdded, Number: spb, Tenant: DefaultTenant},
+	}, events)
+}
@@ -0,0 +1,223 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// querySLABreaches посылки со сроком доставки в [:from, :to), которые к моменту :now
+// не доставлены или доставлены позже срока. Сроки хранятся в RFC 3339 UTC,
+// поэтому строки сравниваются в порядке времени.
+const querySLABreaches = `SELECT ` + parcelColumns + ` FROM parcel
+	WHERE deadline >= :from AND deadline < :to AND deadline < :now
+		AND (delivered_at IS NULL OR delivered_at > deadline)
+		AND (:client = 0 OR client = :client) AND ` + tenantCond + `
+	ORDER BY deadline, number LIMIT :limit`
+
+// SLABreach посылка, не доставленная в обещанный срок
+type SLABreach struct {
+	Parcel
+	// Late насколько позже срока доставлена посылка, а для недоставленной — насколько срок уже превышен
+	Late time.Duration
+}
+
+// deadline возвращает срок доставки посылки по тарифу её зоны и срочности: дата регистрации
+// плюс Tariff.Days. Без тарифа или без срока в тарифе срок не обещается и остаётся пустым.
+func (s ParcelService) deadline(ctx context.Context, p Parcel) (string, error) {
+	zone := p.Zone
+	if zone == "" {
+		zone = destinationZone(p.Address)
+	}
+	priority := p.Priority
+	if priority == "" {
+		priority = PriorityStandard
+	}
+	t, err := s.store.WithContext(ctx).FindTariff(zone, priority)
+	if errors.Is(err, ErrNoTariff) || (err == nil && t.Days == 0) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	created, err := time.Parse(time.RFC3339, p.CreatedAt)
+	if err != nil {
+		return "", err
+	}
+	return created.AddDate(0, 0, t.Days).UTC().Format(time.RFC3339), nil
+}
+
+// SLABreaches возвращает посылки со сроком доставки от from до to, нарушившие срок
+// к текущему моменту, начиная с самого раннего срока. Учитываются фильтры
+// opts.Client и opts.Limit; без Limit возвращаются все такие посылки.
+func (s ParcelStore) SLABreaches(from, to time.Time, opts ListOptions) ([]SLABreach, error) {
+	defer s.metrics.observeQuery("SLABreaches", time.Now())
+	span := s.startSpan("SLABreaches", attrClient.Int(opts.Client))
+	defer span.End()
+
+	now := time.Now()
+	if to.IsZero() {
+		to = now
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		// в SQLite отрицательный LIMIT снимает ограничение
+		limit = -1
+	}
+	rows, err := s.readQuery(querySLABreaches,
+		sql.Named("from", from.UTC().Format(time.RFC3339)),
+		sql.Named("to", to.UTC().Format(time.RFC3339)),
+		sql.Named("now", now.UTC().Format(time.RFC3339)),
+		sql.Named("client", opts.Client),
+		sql.Named("tenant", s.tenant()),
+		sql.Named("limit", limit))
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer rows.Close()
+
+	var res []SLABreach
+	for rows.Next() {
+		p, err := scanParcel(rows)
+		if err == nil {
+			p, err = s.openParcel(p)
+		}
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		b := SLABreach{Parcel: p}
+		b.Late, err = lateness(p, now)
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		res = append(res, b)
+	}
+	return res, spanError(span, rows.Err())
+}
+
+// lateness насколько посылка p опоздала относительно срока на момент now
+func lateness(p Parcel, now time.Time) (time.Duration, error) {
+	deadline, err := time.Parse(time.RFC3339, p.Deadline)
+	if err != nil {
+		return 0, err
+	}
+	if p.DeliveredAt != "" {
+		delivered, err := time.Parse(time.RFC3339, p.DeliveredAt)
+		if err != nil {
+			return 0, err
+		}
+		return delivered.Sub(deadline), nil
+	}
+	return now.Sub(deadline), nil
+}
+
+// SLABreaches возвращает посылки, нарушившие обещанный срок доставки; клиент видит только свои
+func (s ParcelService) SLABreaches(ctx context.Context, from, to time.Time, opts ListOptions) ([]SLABreach, error) {
+	err := s.check(ctx, actionRead)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := CallerFromContext(ctx); ok && c.Role == RoleClient {
+		err = authorizeOwner(ctx, opts.Client)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.store.WithContext(ctx).SLABreaches(from, to, opts)
+}
+
+// dayStart возвращает начало суток t в UTC
+func dayStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// writeSLABreaches записывает нарушения сроков в CSV с заголовком; адреса в отчёт не попадают
+func writeSLABreaches(w io.Writer, breaches []SLABreach) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"tenant_id", "number", "client", "status", "zone", "priority", "deadline", "delivered_at", "late_hours"})
+	if err != nil {
+		return err
+	}
+	for _, b := range breaches {
+		err = cw.Write([]string{
+			b.Tenant,
+			strconv.Itoa(b.Number),
+			strconv.Itoa(b.Client),
+			b.Status,
+			b.Zone,
+			b.Priority,
+			b.Deadline,
+			b.DeliveredAt,
+			strconv.FormatFloat(b.Late.Hours(), 'f', 1, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// SLAPath возвращает путь к отчёту о нарушениях сроков, истёкших в сутки day
+func (r *Reports) SLAPath(day time.Time) string {
+	return filepath.Join(r.dir, "sla-"+dayStart(day).Format(time.DateOnly)+".csv")
+}
+
+// GenerateSLA формирует отчёт о посылках, срок доставки которых истёк в сутки day
+// и которые не доставлены вовремя, и возвращает путь к файлу
+func (r *Reports) GenerateSLA(ctx context.Context, day time.Time) (string, error) {
+	from := dayStart(day)
+	breaches, err := r.store.WithContext(ctx).SLABreaches(from, from.AddDate(0, 0, 1), ListOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(r.dir, 0o755)
+	if err != nil {
+		return "", err
+	}
+	path := r.SLAPath(day)
+	tmp, err := os.CreateTemp(r.dir, ".sla-*.csv")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = writeSLABreaches(tmp, breaches)
+	if err != nil {
+		tmp.Close()
+		return "", err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp.Name(), path)
+}
+
+// GenerateSLADue формирует отчёт о нарушениях сроков за прошедшие сутки, если его
+// файла ещё нет. Это задача планировщика sla: при запуске раз в час отчёт
+// появляется в первый час новых суток.
+func (r *Reports) GenerateSLADue(ctx context.Context) error {
+	yesterday := dayStart(r.now()).AddDate(0, 0, -1)
+	_, err := os.Stat(r.SLAPath(yesterday))
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	path, err := r.GenerateSLA(ctx, yesterday)
+	if err != nil {
+		return fmt.Errorf("отчёт о сроках за %s: %w", yesterday.Format(time.DateOnly), err)
+	}
+	r.store.logger.Log(ctx, slog.LevelInfo, "отчёт о сроках сформирован", "op", "reports.GenerateSLA", "path", path)
+	return nil
+}
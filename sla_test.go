@@ -0,0 +1,95 @@
+package tracker
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSLABreaches проверяет срок доставки по тарифу, поиск нарушений срока и отчёт о них
+func TestSLABreaches(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	tenant := fmt.Sprintf("sla-%d", randRange.Intn(10_000_000))
+	ctx := WithTenant(context.Background(), tenant)
+	service := NewParcelService(NewParcelStore(db))
+	require.NoError(t, service.SetTariff(ctx, Tariff{Zone: "псков", Priority: PriorityStandard, Base: 10000, Days: 3}))
+	require.NoError(t, service.SetTariff(ctx, Tariff{Zone: anyZone, Priority: PriorityExpress, Base: 50000, Days: 1}))
+	require.NoError(t, service.SetTariff(ctx, Tariff{Zone: anyZone, Priority: PriorityStandard, Base: 5000}))
+
+	// deadline
+	late, err := service.Register(ctx, 1000, "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+	created, err := time.Parse(time.RFC3339, late.CreatedAt)
+	require.NoError(t, err)
+	assert.Equal(t, created.AddDate(0, 0, 3).Format(time.RFC3339), late.Deadline)
+	express, err := service.RegisterQuoted(ctx, 1001, "Тверь, ул. Советская, д. 1",
+		QuoteRequest{Priority: PriorityExpress, WeightGrams: 500, LengthCm: 10, WidthCm: 10, HeightCm: 10})
+	require.NoError(t, err)
+	stored, err := service.Get(ctx, express.Number)
+	require.NoError(t, err)
+	created, err = time.Parse(time.RFC3339, express.CreatedAt)
+	require.NoError(t, err)
+	assert.Equal(t, created.AddDate(0, 0, 1).Format(time.RFC3339), stored.Deadline)
+	// общий тариф без срока срок не обещает
+	noDeadline, err := service.Register(ctx, 1000, "Тверь, ул. Советская, д. 1")
+	require.NoError(t, err)
+	assert.Empty(t, noDeadline.Deadline)
+	onTime, err := service.Register(ctx, 1000, "Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+
+	// сроки переносятся в прошлое, чтобы посылки их нарушили
+	day := time.Date(2001, time.February, 3, 0, 0, 0, 0, time.UTC)
+	setDeadline := func(number int, deadline time.Time) {
+		_, err := db.Exec("UPDATE parcel SET deadline = ? WHERE number = ?", deadline.Format(time.RFC3339), number)
+		require.NoError(t, err)
+	}
+	setDeadline(late.Number, day.Add(10*time.Hour))
+	setDeadline(express.Number, day.Add(12*time.Hour))
+	setDeadline(onTime.Number, day.Add(14*time.Hour))
+	setDelivered := func(number int, at time.Time) {
+		_, err := db.Exec("UPDATE parcel SET status = 'delivered', delivered_at = ? WHERE number = ?", at.Format(time.RFC3339), number)
+		require.NoError(t, err)
+	}
+	setDelivered(express.Number, day.Add(18*time.Hour))
+	setDelivered(onTime.Number, day.Add(13*time.Hour))
+
+	// breaches
+	breaches, err := service.SLABreaches(ctx, day, day.AddDate(0, 0, 1), ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, breaches, 2)
+	assert.Equal(t, late.Number, breaches[0].Number)
+	assert.Greater(t, breaches[0].Late, 24*time.Hour)
+	assert.Equal(t, express.Number, breaches[1].Number)
+	assert.Equal(t, 6*time.Hour, breaches[1].Late)
+
+	breaches, err = service.SLABreaches(ctx, time.Time{}, time.Time{}, ListOptions{Client: 1001})
+	require.NoError(t, err)
+	require.Len(t, breaches, 1)
+	assert.Equal(t, express.Number, breaches[0].Number)
+	_, err = service.SLABreaches(WithCaller(ctx, Caller{Client: 1000, Role: RoleClient, Tenant: tenant}), day, time.Time{}, ListOptions{Client: 1001})
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	// report
+	reports := NewReports(NewParcelStore(db), t.TempDir())
+	reports.now = func() time.Time { return day.AddDate(0, 0, 1).Add(time.Hour) }
+	require.NoError(t, reports.GenerateSLADue(ctx))
+	f, err := os.Open(reports.SLAPath(day))
+	require.NoError(t, err)
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "deadline", records[0][6])
+	assert.Equal(t, []string{tenant, fmt.Sprint(express.Number), "1001", ParcelStatusDelivered, "", PriorityExpress,
+		day.Add(12 * time.Hour).Format(time.RFC3339), day.Add(18 * time.Hour).Format(time.RFC3339), "6.0"}, records[2])
+}
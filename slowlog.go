@@ -0,0 +1,75 @@
+package tracker
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// WithSlowQueryLog включает запись медленных запросов: запрос, который выполняется
+// дольше threshold, попадает в лог на уровне warn с параметрами и стеком вызова
+// и учитывается в метрике store_slow_queries_total. Ноль выключает запись.
+func WithSlowQueryLog(threshold time.Duration) StoreOption {
+	return func(s *ParcelStore) {
+		s.slowQuery = threshold
+	}
+}
+
+// observeSlowQuery записывает запрос query с аргументами args, начатый в start,
+// если он выполнялся дольше порога WithSlowQueryLog
+func (s ParcelStore) observeSlowQuery(query string, args []any, start time.Time) {
+	elapsed := time.Since(start)
+	if s.slowQuery <= 0 || elapsed < s.slowQuery {
+		return
+	}
+
+	s.metrics.slowQuery()
+	logArgs := withRequestID(s.ctx, []any{"op", "store.slow_query", "query", query,
+		"args", formatQueryArgs(args, s.redactor), "duration", elapsed, "stack", callerStack()})
+	s.logger.Log(s.ctx, slog.LevelWarn, "медленный запрос", logArgs...)
+}
+
+// formatQueryArgs записывает аргументы запроса в строку вида "number=42 status=sent",
+// скрывая персональные данные в именованных аргументах
+func formatQueryArgs(args []any, r Redactor) string {
+	parts := make([]string, 0, len(args))
+	for i, a := range args {
+		if named, ok := a.(sql.NamedArg); ok {
+			parts = append(parts, named.Name+"="+r.formatArg(named))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("$%d=%v", i+1, a))
+	}
+	return strings.Join(parts, " ")
+}
+
+// callerStack возвращает вызовы трекера, которые привели к запросу, от ближнего
+// к дальнему, например "ParcelStore.Get (parcel.go:187) < ParcelService.Get (main.go:160)".
+// Вызовы стандартной библиотеки и зависимостей опускаются.
+func callerStack() string {
+	// имя пакета трекера берётся из имени самой функции: это main в сборке
+	// и путь модуля в тестах
+	self, _, _, _ := runtime.Caller(0)
+	pkg := strings.TrimSuffix(runtime.FuncForPC(self).Name(), "callerStack")
+
+	pc := make([]uintptr, 32)
+	// пропускаем runtime.Callers, callerStack и observeSlowQuery
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+
+	var calls []string
+	for {
+		f, more := frames.Next()
+		if name, ok := strings.CutPrefix(f.Function, pkg); ok {
+			calls = append(calls, fmt.Sprintf("%s (%s:%d)", name, filepath.Base(f.File), f.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return strings.Join(calls, " < ")
+}
@@ -0,0 +1,54 @@
+package tracker
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSlowQueryLog проверяет запись медленных запросов в лог и метрику
+func TestSlowQueryLog(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, "warn", "text")
+	require.NoError(t, err)
+	metrics := NewMetrics()
+	id, err := NewParcelStore(db).Add(getTestParcel())
+	require.NoError(t, err)
+
+	// без порога запросы не записываются
+	_, err = NewParcelStore(db, WithStoreLogger(logger), WithStoreMetrics(metrics)).Get(id)
+	require.NoError(t, err)
+	assert.Empty(t, buf.String())
+
+	// порог в наносекунду превышает любой запрос
+	store := NewParcelStore(db, WithStoreLogger(logger), WithStoreMetrics(metrics), WithSlowQueryLog(time.Nanosecond))
+	_, err = store.Get(id)
+	require.NoError(t, err)
+
+	// check
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.slowQueries))
+	out := buf.String()
+	assert.Contains(t, out, "level=WARN")
+	assert.Contains(t, out, "медленный запрос")
+	assert.Contains(t, out, "op=store.slow_query")
+	assert.Contains(t, out, fmt.Sprintf(`args="number=%d tenant=default"`, id))
+	assert.Contains(t, out, "ParcelStore.Get (parcel.go:")
+	assert.Contains(t, out, "TestSlowQueryLog (slowlog_test.go:")
+
+	// долгий запрос порог не проходит
+	buf.Reset()
+	store = NewParcelStore(db, WithStoreLogger(logger), WithSlowQueryLog(time.Hour))
+	_, err = store.GetByClient(1000)
+	require.NoError(t, err)
+	assert.Empty(t, buf.String())
+}
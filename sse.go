@@ -0,0 +1,51 @@
+package tracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// sseHandler обслуживает /events: поток server-sent events,
+// где каждое изменение статуса посылки приходит событием status с ParcelChange в JSON
+type sseHandler struct {
+	service ParcelService
+}
+
+func (h sseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "потоковая передача не поддерживается", http.StatusInternalServerError)
+		return
+	}
+
+	changes, cancel := h.service.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case c, ok := <-changes:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(c)
+			if err != nil {
+				return
+			}
+			_, err = fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package tracker_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/testsupport"
+)
+
+// TestSSEStatusEvents проверяет, что изменения статусов приходят в поток /events
+func TestSSEStatusEvents(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := testsupport.OpenDB(t)
+
+	store := tracker.NewParcelStore(db)
+	srv := httptest.NewServer(tracker.NewHTTPHandler(tracker.NewParcelService(store)))
+	defer srv.Close()
+
+	id, err := store.Add(testsupport.NewParcel().Build())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", nil)
+	require.NoError(t, err)
+	// заголовки приходят сразу, поэтому после Do подписка уже действует
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// set status
+	require.NoError(t, store.SetStatus(id, tracker.ParcelStatusSent))
+
+	// check
+	scanner := bufio.NewScanner(resp.Body)
+	require.True(t, scanner.Scan())
+	assert.Equal(t, "event: status", scanner.Text())
+	require.True(t, scanner.Scan())
+
+	var change tracker.ParcelChange
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(scanner.Text(), "data: ")), &change))
+	assert.Equal(t, id, change.Number)
+	assert.Equal(t, tracker.ParcelStatusSent, change.Status)
+}
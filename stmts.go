@@ -0,0 +1,245 @@
+package tracker
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Частые запросы хранилища; они подготавливаются один раз и переиспользуются
+const (
+	queryInsertParcel    = "INSERT INTO parcel (client, status, address, created_at, tracking_token, tenant_id, delivered_at, eta, priority, weight, price, zone, deadline) VALUES (:client, :status, :address, :created_at, NULLIF(:tracking_token, ''), :tenant, " + deliveredAtOnInsert + ", NULLIF(:eta, ''), COALESCE(NULLIF(:priority, ''), '" + PriorityStandard + "'), NULLIF(:weight, 0), NULLIF(:price, 0), " + zoneByPostal + ", NULLIF(:deadline, ''))"
+	queryParcelByNumber  = "SELECT " + parcelColumns + " FROM parcel WHERE number = :number AND " + tenantCond
+	queryParcelByToken   = "SELECT " + parcelColumns + " FROM parcel WHERE tracking_token = :token AND " + tenantCond
+	queryParcelsByClient = "SELECT " + parcelColumns + " FROM parcel WHERE client = :client AND " + tenantCond + " ORDER BY number"
+	queryParcelsByStatus = "SELECT " + parcelColumns + " FROM parcel WHERE status = :status AND " + tenantCond + " ORDER BY number"
+	queryParcelStatus    = "SELECT status, client, tenant_id FROM parcel WHERE number = :number AND " + tenantCond
+	queryUpdateStatus    = "UPDATE parcel SET status = :status, delivered_at = " + deliveredAtOnUpdate + ", version = version + 1 WHERE number = :number AND " + versionCond + " AND " + tenantCond
+	queryUpdateStatusIf  = "UPDATE parcel SET status = :status, delivered_at = " + deliveredAtOnUpdate + ", version = version + 1 WHERE number = :number AND status = :expected AND " + tenantCond
+	queryUpdateAddress   = "UPDATE parcel SET address = :address, zone = " + parcelZoneByPostal + ", version = version + 1 WHERE number = :number AND status = :status AND " + versionCond + " AND " + tenantCond
+	queryDeleteParcel    = "DELETE FROM parcel WHERE number = :number AND status = :status AND " + versionCond + " AND " + tenantCond
+	queryDeleteHistory   = "DELETE FROM parcel_history WHERE number = :number"
+	queryInsertHistory   = "INSERT INTO parcel_history (number, status, changed_at, request_id) VALUES (:number, :status, :changed_at, NULLIF(:request_id, ''))"
+	queryHistory         = "SELECT number, status, changed_at, COALESCE(request_id, '') FROM parcel_history WHERE number = :number AND EXISTS (SELECT 1 FROM parcel WHERE number = :number AND " + tenantCond + ") ORDER BY id"
+	queryClientByAPIKey  = "SELECT client, tenant_id FROM api_key WHERE key_hash = :key_hash"
+	queryRole            = "SELECT role FROM user_role WHERE subject = :subject AND tenant_id = :tenant"
+
+	queryInsertOutbox        = "INSERT INTO outbox (type, number, payload, created_at) VALUES (:type, :number, :payload, :created_at)"
+	queryPendingOutbox       = "SELECT id, type, number, payload, created_at FROM outbox WHERE published_at IS NULL ORDER BY id LIMIT :limit"
+	queryMarkOutboxPublished = "UPDATE outbox SET published_at = :published_at WHERE id = :id"
+
+	queryInsertScanEvent = "INSERT INTO scan_event (id, number, status, received_at) VALUES (:id, :number, :status, :received_at) ON CONFLICT (id) DO NOTHING"
+)
+
+// deliveredAtOnInsert и deliveredAtOnUpdate время первой доставки посылки: оно
+// записывается, когда посылка впервые получает статус delivered, и дальше не меняется
+const (
+	deliveredAtOnInsert = "CASE WHEN :status = '" + ParcelStatusDelivered + "' THEN :created_at END"
+	deliveredAtOnUpdate = "CASE WHEN :status = '" + ParcelStatusDelivered + "' THEN COALESCE(delivered_at, :changed_at) ELSE delivered_at END"
+)
+
+// hotQueries запросы, которые NewParcelStore подготавливает сразу в основной БД
+var hotQueries = []string{
+	queryInsertParcel,
+	queryParcelByNumber,
+	queryParcelByToken,
+	queryParcelsByClient,
+	queryParcelsByStatus,
+	queryParcelStatus,
+	queryUpdateStatus,
+	queryUpdateStatusIf,
+	queryUpdateAddress,
+	queryDeleteParcel,
+	queryDeleteHistory,
+	queryInsertHistory,
+	queryHistory,
+	queryClientByAPIKey,
+	queryRole,
+	queryInsertOutbox,
+	queryPendingOutbox,
+	queryMarkOutboxPublished,
+	queryInsertScanEvent,
+}
+
+// readQueries запросы, которые выполняются в БД для чтения, если она задана через WithReader
+var readQueries = []string{
+	queryParcelByNumber,
+	queryParcelByToken,
+	queryParcelsByClient,
+	queryParcelsByStatus,
+	queryHistory,
+}
+
+// stmtCache кеш подготовленных запросов, общий для всех копий ParcelStore
+type stmtCache struct {
+	db    *sql.DB
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+func newStmtCache(db *sql.DB) *stmtCache {
+	return &stmtCache{db: db, stmts: map[string]*sql.Stmt{}}
+}
+
+// warm подготавливает запросы queries. Ошибки не возвращаются: например, до миграций
+// таблиц ещё нет, и такой запрос будет подготовлен при первом использовании.
+func (c *stmtCache) warm(queries []string) {
+	for _, q := range queries {
+		c.get(q)
+	}
+}
+
+// cached возвращает уже подготовленный запрос query, не подготавливая его
+func (c *stmtCache) cached(query string) (*sql.Stmt, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	st, ok := c.stmts[query]
+	return st, ok
+}
+
+// get возвращает подготовленный запрос query, подготавливая его при первом обращении
+func (c *stmtCache) get(query string) (*sql.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if st, ok := c.stmts[query]; ok {
+		return st, nil
+	}
+	st, err := c.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[query] = st
+	return st, nil
+}
+
+// len возвращает количество подготовленных запросов
+func (c *stmtCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.stmts)
+}
+
+// close закрывает все подготовленные запросы; следующие обращения подготовят их заново
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for q, st := range c.stmts {
+		errs = append(errs, st.Close())
+		delete(c.stmts, q)
+	}
+	return errors.Join(errs...)
+}
+
+// stmt возвращает подготовленный запрос query; внутри транзакции tx — его копию для tx.
+// Запрос, которого ещё нет в кеше, подготавливается в самой транзакции: транзакция
+// держит соединение, и при пуле из одного соединения подготовка в пуле ждала бы его вечно.
+func (s ParcelStore) stmt(tx *sql.Tx, query string) (*sql.Stmt, error) {
+	if tx != nil {
+		if st, ok := s.stmts.cached(query); ok {
+			return tx.Stmt(st), nil
+		}
+		return tx.Prepare(query)
+	}
+	st, err := s.stmts.get(query)
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// exec выполняет подготовленный запрос query, в транзакции tx, если она задана,
+// с тайм-аутом записи
+func (s ParcelStore) exec(tx *sql.Tx, query string, args ...any) (sql.Result, error) {
+	if err := s.checkWritable(); err != nil {
+		return nil, err
+	}
+	st, err := s.stmt(tx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer s.observeSlowQuery(query, args, time.Now())
+	ctx, cancel := s.withTimeout(s.timeouts.Write)
+	defer cancel()
+	res, err := st.ExecContext(ctx, args...)
+	return res, timeoutError(ctx, err)
+}
+
+// query выполняет подготовленный запрос query, возвращающий строки, в транзакции tx
+// или в основной БД, если tx равен nil
+func (s ParcelStore) query(tx *sql.Tx, query string, args ...any) (timedRows, error) {
+	st, err := s.stmt(tx, query)
+	if err != nil {
+		return timedRows{}, err
+	}
+	defer s.observeSlowQuery(query, args, time.Now())
+	ctx, cancel := s.withTimeout(s.timeouts.Read)
+	rows, err := st.QueryContext(ctx, args...)
+	return newTimedRows(ctx, cancel, rows, err)
+}
+
+// queryRow выполняет подготовленный запрос query, возвращающий одну строку.
+// Если запрос не подготовился, он выполняется как есть и вернёт ту же ошибку при Scan.
+func (s ParcelStore) queryRow(tx *sql.Tx, query string, args ...any) timedRow {
+	defer s.observeSlowQuery(query, args, time.Now())
+	ctx, cancel := s.withTimeout(s.timeouts.Read)
+	row := timedRow{ctx: ctx, cancel: cancel}
+	st, err := s.stmt(tx, query)
+	switch {
+	case err == nil:
+		row.Row = st.QueryRowContext(ctx, args...)
+	case tx != nil:
+		row.Row = tx.QueryRowContext(ctx, query, args...)
+	default:
+		row.Row = s.db.QueryRowContext(ctx, query, args...)
+	}
+	return row
+}
+
+// readRow выполняет в БД для чтения подготовленный запрос query, возвращающий одну строку
+func (s ParcelStore) readRow(query string, args ...any) timedRow {
+	defer s.observeSlowQuery(query, args, time.Now())
+	ctx, cancel := s.withTimeout(s.timeouts.Read)
+	row := timedRow{ctx: ctx, cancel: cancel}
+	st, err := s.readStmts.get(query)
+	if err != nil {
+		row.Row = s.reader.QueryRowContext(ctx, query, args...)
+		return row
+	}
+	row.Row = st.QueryRowContext(ctx, args...)
+	return row
+}
+
+// readQuery выполняет в БД для чтения подготовленный запрос query, возвращающий несколько строк
+func (s ParcelStore) readQuery(query string, args ...any) (timedRows, error) {
+	st, err := s.readStmts.get(query)
+	if err != nil {
+		return timedRows{}, err
+	}
+	defer s.observeSlowQuery(query, args, time.Now())
+	ctx, cancel := s.withTimeout(s.timeouts.Read)
+	rows, err := st.QueryContext(ctx, args...)
+	return newTimedRows(ctx, cancel, rows, err)
+}
+
+// readUnprepared выполняет в БД для чтения запрос без подготовки. Подходит для
+// запросов, собранных из фильтров: их вариантов много, и кеш подготовленных
+// запросов разрастался бы без пользы.
+func (s ParcelStore) readUnprepared(query string, args ...any) (timedRows, error) {
+	defer s.observeSlowQuery(query, args, time.Now())
+	ctx, cancel := s.withTimeout(s.timeouts.Read)
+	rows, err := s.reader.QueryContext(ctx, query, args...)
+	return newTimedRows(ctx, cancel, rows, err)
+}
+
+// Close закрывает подготовленные запросы хранилища. Сами БД закрывает их владелец.
+func (s ParcelStore) Close() error {
+	if s.readStmts == s.stmts {
+		return s.stmts.close()
+	}
+	return errors.Join(s.stmts.close(), s.readStmts.close())
+}
@@ -0,0 +1,83 @@
+package tracker
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPreparedStatements проверяет, что частые запросы подготавливаются при создании хранилища,
+// а после Close хранилищем по-прежнему можно пользоваться
+func TestPreparedStatements(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+
+	// check
+	stats, err := store.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, len(hotQueries), stats.PreparedStatements)
+
+	require.NoError(t, store.Close())
+	stats, err = store.Stats()
+	require.NoError(t, err)
+	assert.Zero(t, stats.PreparedStatements)
+
+	id, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+	_, err = store.Get(id)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+}
+
+// singleConnStore возвращает хранилище с пулом из одного соединения, как в настройках
+// по умолчанию: запрос, который в транзакции ждёт второе соединение, зависнет
+func singleConnStore(t *testing.T) (*sql.DB, ParcelStore) {
+	t.Helper()
+
+	db := openTestDB(t)
+	return db, NewParcelStore(db, WithPool(PoolOptions{MaxOpenConns: 1, MaxIdleConns: 1}))
+}
+
+// withinDeadline выполняет f и проваливает тест, если f не завершилась за несколько секунд
+func withinDeadline(t *testing.T, f func()) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("операция зависла: вероятно, ждёт второе соединение пула")
+	}
+}
+
+// TestStatementsInTransactionSingleConn проверяет, что запрос, ещё не подготовленный
+// в кеше, выполняется в транзакции при пуле из одного соединения
+func TestStatementsInTransactionSingleConn(t *testing.T) {
+	// prepare
+	_, store := singleConnStore(t)
+	// после Close в кеше нет ни одного запроса
+	require.NoError(t, store.Close())
+
+	// check
+	withinDeadline(t, func() {
+		number, err := store.Add(getTestParcel())
+		require.NoError(t, err)
+		require.NoError(t, store.SetAddress(number, "new test address"))
+		require.NoError(t, store.SetStatus(number, ParcelStatusSent))
+		require.NoError(t, store.SetStatusIf(number, ParcelStatusSent, ParcelStatusDelivered))
+		number, err = store.Add(getTestParcel())
+		require.NoError(t, err)
+		require.NoError(t, store.Delete(number))
+	})
+}
@@ -0,0 +1,443 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// telegramRetryDelay пауза перед повтором запроса обновлений после ошибки Bot API
+const telegramRetryDelay = 5 * time.Second
+
+const (
+	queryAddTelegramSubscription     = "INSERT INTO telegram_subscription (chat_id, number) VALUES (:chat_id, :number) ON CONFLICT DO NOTHING"
+	queryDeleteTelegramSubscription  = "DELETE FROM telegram_subscription WHERE chat_id = :chat_id AND number = :number"
+	queryDeleteTelegramSubscriptions = "DELETE FROM telegram_subscription WHERE number = :number"
+	queryTelegramSubscribers         = "SELECT chat_id FROM telegram_subscription WHERE number = :number ORDER BY chat_id"
+	queryUpsertTelegramLogin         = `INSERT INTO telegram_login (chat_id, key_hash) VALUES (:chat_id, :key_hash)
+		ON CONFLICT (chat_id) DO UPDATE SET key_hash = excluded.key_hash`
+	queryTelegramLogin       = "SELECT key_hash FROM telegram_login WHERE chat_id = :chat_id"
+	queryDeleteTelegramLogin = "DELETE FROM telegram_login WHERE chat_id = :chat_id"
+)
+
+// telegramHelp ответ на /start и неизвестные команды
+const telegramHelp = `Команды:
+/track <код> — получать уведомления о посылке по трекинг-коду
+/untrack <код> — отписаться от посылки
+Для курьеров:
+/login <API-ключ> — войти, сообщение с ключом будет удалено
+/next <номер> — перевести посылку в следующий статус
+/status <номер> <статус> — установить статус (registered, sent, customs_hold, delivered)
+/logout — выйти`
+
+// AddTelegramSubscription подписывает чат на изменения статуса посылки
+func (s ParcelStore) AddTelegramSubscription(chat int64, number int) error {
+	return s.withRetry("store.AddTelegramSubscription", func(s ParcelStore) error {
+		_, err := s.exec(nil, queryAddTelegramSubscription, sql.Named("chat_id", chat), sql.Named("number", number))
+		return err
+	})
+}
+
+// DeleteTelegramSubscription отписывает чат от посылки; если подписки не было, возвращает sql.ErrNoRows
+func (s ParcelStore) DeleteTelegramSubscription(chat int64, number int) error {
+	return s.withRetry("store.DeleteTelegramSubscription", func(s ParcelStore) error {
+		res, err := s.exec(nil, queryDeleteTelegramSubscription, sql.Named("chat_id", chat), sql.Named("number", number))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// TelegramSubscribers возвращает чаты, подписанные на посылку
+func (s ParcelStore) TelegramSubscribers(number int) ([]int64, error) {
+	st, err := s.stmt(nil, queryTelegramSubscribers)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := st.Query(sql.Named("number", number))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []int64
+	for rows.Next() {
+		var chat int64
+		err := rows.Scan(&chat)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, chat)
+	}
+	return res, rows.Err()
+}
+
+// deleteTelegramSubscriptions удаляет подписки на удалённую посылку
+func (s ParcelStore) deleteTelegramSubscriptions(number int) error {
+	return s.withRetry("store.DeleteTelegramSubscriptions", func(s ParcelStore) error {
+		_, err := s.exec(nil, queryDeleteTelegramSubscriptions, sql.Named("number", number))
+		return err
+	})
+}
+
+// setTelegramLogin запоминает хеш API-ключа, с которым вошли в чате
+func (s ParcelStore) setTelegramLogin(chat int64, keyHash string) error {
+	return s.withRetry("store.SetTelegramLogin", func(s ParcelStore) error {
+		_, err := s.exec(nil, queryUpsertTelegramLogin, sql.Named("chat_id", chat), sql.Named("key_hash", keyHash))
+		return err
+	})
+}
+
+// telegramLogin возвращает хеш API-ключа чата; если в чате не входили, возвращает sql.ErrNoRows
+func (s ParcelStore) telegramLogin(chat int64) (string, error) {
+	var hash string
+	err := s.queryRow(nil, queryTelegramLogin, sql.Named("chat_id", chat)).Scan(&hash)
+	return hash, err
+}
+
+func (s ParcelStore) deleteTelegramLogin(chat int64) error {
+	return s.withRetry("store.DeleteTelegramLogin", func(s ParcelStore) error {
+		_, err := s.exec(nil, queryDeleteTelegramLogin, sql.Named("chat_id", chat))
+		return err
+	})
+}
+
+// telegramUpdate обновление Bot API; бот обрабатывает только текстовые сообщения
+type telegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *telegramMessage `json:"message"`
+}
+
+type telegramMessage struct {
+	MessageID int64  `json:"message_id"`
+	Text      string `json:"text"`
+	Chat      struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+}
+
+// telegramAPI клиент методов Bot API, которыми пользуется бот
+type telegramAPI struct {
+	url    string
+	client *http.Client
+}
+
+// call вызывает метод Bot API с параметрами params в JSON и разбирает result в res
+func (a telegramAPI) call(ctx context.Context, method string, params, res any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// в адресе запроса токен бота, поэтому в ошибку он не попадает
+		err = fmt.Errorf("telegram %s: %w", method, urlErr.Err)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		Description string          `json:"description"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&envelope)
+	if err != nil {
+		return fmt.Errorf("telegram %s: %s: %w", method, resp.Status, err)
+	}
+	if !envelope.OK {
+		return fmt.Errorf("telegram %s: %s", method, envelope.Description)
+	}
+	if res == nil {
+		return nil
+	}
+	return json.Unmarshal(envelope.Result, res)
+}
+
+// TelegramBot бот Telegram: получатели подписываются в нём на посылки по трекинг-коду
+// и получают смены статусов, а курьеры, войдя по API-ключу, меняют статусы командами.
+// Как EventPublisher релея outbox он рассылает события parcel.status_changed подписчикам.
+type TelegramBot struct {
+	service     ParcelService
+	store       ParcelStore
+	api         telegramAPI
+	pollTimeout time.Duration
+}
+
+// NewTelegramBot создаёт бота по настройкам cfg; команды курьеров выполняются через service,
+// поэтому для них действуют те же роли, что и в API
+func NewTelegramBot(service ParcelService, cfg config.Telegram) *TelegramBot {
+	return &TelegramBot{
+		service: service,
+		store:   service.store,
+		api: telegramAPI{
+			url: strings.TrimRight(cfg.APIURL, "/") + "/bot" + cfg.Token,
+			// запрос обновлений висит до PollTimeout, поэтому общий таймаут больше
+			client: &http.Client{Timeout: cfg.PollTimeout + 10*time.Second},
+		},
+		pollTimeout: cfg.PollTimeout,
+	}
+}
+
+// Run получает сообщения боту длинными запросами getUpdates и отвечает на них, пока не отменён ctx
+func (b *TelegramBot) Run(ctx context.Context) {
+	var offset int64
+	for ctx.Err() == nil {
+		var updates []telegramUpdate
+		err := b.api.call(ctx, "getUpdates", map[string]any{
+			"offset":          offset,
+			"timeout":         int(b.pollTimeout.Seconds()),
+			"allowed_updates": []string{"message"},
+		}, &updates)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			b.store.logger.Log(ctx, slog.LevelError, "не удалось получить сообщения Telegram", "op", "telegram.Run", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(telegramRetryDelay):
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message == nil || u.Message.Text == "" {
+				continue
+			}
+			b.reply(ctx, *u.Message)
+		}
+	}
+}
+
+// reply выполняет команду из сообщения и отвечает в тот же чат
+func (b *TelegramBot) reply(ctx context.Context, m telegramMessage) {
+	text, err := b.handle(ctx, m)
+	if err != nil {
+		b.store.logger.Log(ctx, slog.LevelError, "не удалось выполнить команду Telegram", "op", "telegram.Handle", "chat", m.Chat.ID, "error", err)
+		text = "Не удалось выполнить команду, попробуйте позже."
+	}
+	err = b.send(ctx, m.Chat.ID, text)
+	if err != nil {
+		b.store.logger.Log(ctx, slog.LevelError, "не удалось ответить в Telegram", "op", "telegram.Send", "chat", m.Chat.ID, "error", err)
+	}
+}
+
+// handle выполняет команду и возвращает ответ. Ошибка возвращается только при сбое;
+// ошибки пользователя, например неизвестный код посылки, становятся ответом.
+func (b *TelegramBot) handle(ctx context.Context, m telegramMessage) (string, error) {
+	args := strings.Fields(m.Text)
+	if len(args) == 0 {
+		return telegramHelp, nil
+	}
+	chat := m.Chat.ID
+	store := b.store.WithContext(ctx)
+
+	// в группах команды приходят как /track@имя_бота
+	command, _, _ := strings.Cut(args[0], "@")
+	switch command {
+	case "/track", "/untrack":
+		if len(args) != 2 {
+			return "Укажите трекинг-код: " + command + " <код>", nil
+		}
+		p, err := store.GetByToken(args[1])
+		if errors.Is(err, sql.ErrNoRows) {
+			return "Посылка с таким кодом не найдена.", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if command == "/untrack" {
+			err = store.DeleteTelegramSubscription(chat, p.Number)
+			if errors.Is(err, sql.ErrNoRows) {
+				return "Вы не подписаны на эту посылку.", nil
+			}
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Уведомления о посылке №%d отключены.", p.Number), nil
+		}
+		err = store.AddTelegramSubscription(chat, p.Number)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Вы подписаны на посылку №%d. Текущий статус: %s.", p.Number, p.Status), nil
+
+	case "/login":
+		// ключ не должен оставаться в истории чата
+		b.api.call(ctx, "deleteMessage", map[string]any{"chat_id": chat, "message_id": m.MessageID}, nil)
+		if len(args) != 2 {
+			return "Укажите API-ключ: /login <ключ>", nil
+		}
+		_, err := b.service.Authenticate(ctx, args[1])
+		if errors.Is(err, ErrUnauthenticated) {
+			return "Неверный API-ключ.", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		err = store.setTelegramLogin(chat, hashAPIKey(args[1]))
+		if err != nil {
+			return "", err
+		}
+		return "Вы вошли.", nil
+
+	case "/logout":
+		err := store.deleteTelegramLogin(chat)
+		if err != nil {
+			return "", err
+		}
+		return "Вы вышли.", nil
+
+	case "/next", "/status":
+		return b.changeStatus(ctx, chat, command, args[1:])
+
+	default:
+		return telegramHelp, nil
+	}
+}
+
+// changeStatus меняет статус посылки от имени пользователя, вошедшего в чате.
+// Ключ проверяется заново при каждой команде, поэтому отозванный ключ перестаёт действовать сразу.
+func (b *TelegramBot) changeStatus(ctx context.Context, chat int64, command string, args []string) (string, error) {
+	wantArgs := map[string]int{"/next": 1, "/status": 2}[command]
+	if len(args) != wantArgs {
+		return telegramHelp, nil
+	}
+	number, err := strconv.Atoi(args[0])
+	if err != nil {
+		return "Номер посылки должен быть числом.", nil
+	}
+
+	hash, err := b.store.WithContext(ctx).telegramLogin(chat)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "Сначала войдите: /login <API-ключ>", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	caller, err := b.service.authenticateHash(ctx, hash)
+	if errors.Is(err, ErrUnauthenticated) {
+		return "API-ключ больше не действует, войдите снова.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	ctx = WithCaller(ctx, caller)
+
+	p, err := b.service.Get(ctx, number)
+	if err != nil {
+		return statusReply(err)
+	}
+	var status string
+	if command == "/next" {
+		var ok bool
+		status, ok = NextParcelStatus(p.Status)
+		if !ok {
+			return fmt.Sprintf("Посылка №%d уже доставлена.", number), nil
+		}
+	} else {
+		status = args[1]
+		err = CheckTransition(p.Status, status)
+		if err != nil {
+			return statusReply(err)
+		}
+	}
+
+	err = b.service.SetStatus(ctx, number, status)
+	if err != nil {
+		return statusReply(err)
+	}
+	return fmt.Sprintf("Посылка №%d: %s → %s.", number, p.Status, status), nil
+}
+
+// statusReply переводит ошибку смены статуса в ответ пользователю; прочие ошибки возвращает как есть
+func statusReply(err error) (string, error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return "Посылка не найдена.", nil
+	case errors.Is(err, ErrForbidden):
+		return "Недостаточно прав.", nil
+	case errors.Is(err, ErrRateLimited):
+		return "Слишком много запросов, попробуйте позже.", nil
+	case errors.Is(err, ErrUnknownStatus), errors.Is(err, ErrInvalidTransition):
+		return "Недопустимый статус: " + err.Error() + ".", nil
+	default:
+		return "", err
+	}
+}
+
+func (b *TelegramBot) send(ctx context.Context, chat int64, text string) error {
+	return b.api.call(ctx, "sendMessage", map[string]any{"chat_id": chat, "text": text}, nil)
+}
+
+// Publish рассылает подписчикам посылки смену её статуса из события e,
+// а после удаления посылки удаляет подписки на неё.
+// Как и в NotificationPublisher, ошибка возвращается только при сбое БД.
+func (b *TelegramBot) Publish(ctx context.Context, e OutboxEntry) error {
+	store := b.store.WithContext(ctx)
+	switch e.Type {
+	case EventParcelDeleted:
+		return store.deleteTelegramSubscriptions(e.Number)
+	case EventParcelStatusChanged:
+	default:
+		return nil
+	}
+
+	var event OutboxEvent
+	err := json.Unmarshal(e.Payload, &event)
+	if err != nil {
+		return err
+	}
+	chats, err := store.TelegramSubscribers(event.Number)
+	if err != nil || len(chats) == 0 {
+		return err
+	}
+	p, err := store.Get(event.Number)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	text := notificationText(p, Transition{From: event.PreviousStatus, To: event.Status, ChangedAt: event.OccurredAt}, defaultLocale)
+	for _, chat := range chats {
+		err := b.send(ctx, chat, text)
+		if err != nil {
+			b.store.logger.Log(ctx, slog.LevelError, "не удалось отправить уведомление в Telegram",
+				"op", "telegram.Publish", "event", e.ID, "number", event.Number, "chat", chat, "error", err)
+		}
+	}
+	return nil
+}
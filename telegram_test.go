@@ -0,0 +1,132 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// fakeBotAPI Bot API, который отдаёт сообщения из updates и запоминает ответы бота
+type fakeBotAPI struct {
+	mu      sync.Mutex
+	updates []telegramUpdate
+	sent    []map[string]any
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params map[string]any
+	json.NewDecoder(r.Body).Decode(&params)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result any = true
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+		result = f.updates
+		f.updates = nil
+	case strings.HasSuffix(r.URL.Path, "/sendMessage"):
+		f.sent = append(f.sent, params)
+	}
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+// texts возвращает тексты ответов бота в чат chat
+func (f *fakeBotAPI) texts(chat int64) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var res []string
+	for _, m := range f.sent {
+		if m["chat_id"] == float64(chat) {
+			res = append(res, m["text"].(string))
+		}
+	}
+	return res
+}
+
+func telegramText(chat int64, id int64, text string) telegramUpdate {
+	m := &telegramMessage{MessageID: id, Text: text}
+	m.Chat.ID = chat
+	return telegramUpdate{UpdateID: id, Message: m}
+}
+
+// TestTelegramBot проверяет подписку на посылку, смену статуса курьером и рассылку подписчикам
+func TestTelegramBot(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	service := NewParcelService(store)
+	// записи, оставшиеся от других тестов, отправляем заранее
+	_, err := NewOutboxRelay(store, &recordingPublisher{}, 1_000_000).RelayOnce(context.Background())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	parcel, err := service.Register(ctx, randRange.Intn(10_000_000), "test")
+	require.NoError(t, err)
+
+	courier := randRange.Intn(10_000_000)
+	key, err := service.IssueAPIKey(context.Background(), courier)
+	require.NoError(t, err)
+	require.NoError(t, store.SetRole(courier, RoleCourier))
+
+	api := &fakeBotAPI{}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+	bot := NewTelegramBot(service, config.Telegram{Token: "token", APIURL: srv.URL, PollTimeout: time.Second})
+
+	recipientChat := int64(randRange.Intn(10_000_000))
+	courierChat := recipientChat + 1
+	api.updates = []telegramUpdate{
+		telegramText(recipientChat, 1, "/track "+parcel.TrackingToken),
+		telegramText(courierChat, 2, "/next 1"),
+		telegramText(courierChat, 3, "/login "+key),
+		telegramText(courierChat, 4, "/status "+strconv.Itoa(parcel.Number)+" delivered"),
+		telegramText(courierChat, 5, "/status "+strconv.Itoa(parcel.Number)+" sent"),
+	}
+
+	// run
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		bot.Run(runCtx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		return len(api.texts(courierChat)) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	// check
+	assert.Equal(t, []string{"Вы подписаны на посылку №" + strconv.Itoa(parcel.Number) + ". Текущий статус: registered."}, api.texts(recipientChat))
+	replies := api.texts(courierChat)
+	assert.Equal(t, "Сначала войдите: /login <API-ключ>", replies[0])
+	assert.Equal(t, "Вы вошли.", replies[1])
+	assert.Equal(t, "Посылка №"+strconv.Itoa(parcel.Number)+": registered → delivered.", replies[2])
+	assert.Contains(t, replies[3], "Недопустимый статус")
+
+	p, err := store.Get(parcel.Number)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusDelivered, p.Status)
+
+	// подписчик узнаёт о смене статуса из outbox
+	_, err = NewOutboxRelay(store, bot, 10).RelayOnce(ctx)
+	require.NoError(t, err)
+	texts := api.texts(recipientChat)
+	require.Len(t, texts, 2)
+	assert.Equal(t, "Посылка №"+strconv.Itoa(parcel.Number)+" доставлена.", texts[1])
+}
@@ -0,0 +1,136 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// tenantHeader заголовок HTTP и ключ метаданных gRPC с идентификатором компании
+// для запросов без API-ключа; у запросов с ключом компания берётся из ключа
+const tenantHeader = "X-Tenant-ID"
+
+// DefaultTenant компания, к которой относятся данные, записанные до появления
+// нескольких компаний, и запросы, в которых компания не указана
+const DefaultTenant = "default"
+
+// anyTenant служебное значение для фоновых задач трекера: отправки событий, сканирований,
+// синхронизации перевозчиков, архива и других, которые обходят посылки всех компаний.
+// Получить его из запроса нельзя: tenantPattern не допускает «*».
+const anyTenant = "*"
+
+// tenantPattern допустимый идентификатор компании
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ErrInvalidTenant возвращается для идентификатора компании не по tenantPattern
+var ErrInvalidTenant = errors.New("идентификатор компании: строчные латинские буквы, цифры, _ и -, до 64 символов")
+
+// attrTenant атрибут спана с компанией
+var attrTenant = attribute.Key("tenant.id")
+
+// tenantCond условие запроса к таблице с колонкой tenant_id: строки компании :tenant
+// или всех компаний для anyTenant
+const tenantCond = "(tenant_id = :tenant OR :tenant = '" + anyTenant + "')"
+
+type tenantKey struct{}
+
+// WithTenant возвращает контекст, операции хранилища в котором видят и меняют
+// только данные компании tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext возвращает компанию из контекста или DefaultTenant
+func TenantFromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok && tenant != "" {
+		return tenant
+	}
+	return DefaultTenant
+}
+
+// withAnyTenant возвращает контекст фоновой задачи, которая обходит посылки всех компаний
+func withAnyTenant(ctx context.Context) context.Context {
+	return WithTenant(ctx, anyTenant)
+}
+
+// ValidateTenant проверяет идентификатор компании, полученный извне
+func ValidateTenant(tenant string) error {
+	if !tenantPattern.MatchString(tenant) {
+		return ErrInvalidTenant
+	}
+	return nil
+}
+
+// tenant возвращает компанию, данные которой видит хранилище
+func (s ParcelStore) tenant() string {
+	return TenantFromContext(s.ctx)
+}
+
+// ownTenant возвращает компанию для новых записей; фоновая задача со всеми
+// компаниями сама ничего не создаёт, поэтому для anyTenant возвращается ошибка
+func (s ParcelStore) ownTenant() (string, error) {
+	tenant := s.tenant()
+	if tenant == anyTenant {
+		return "", errors.New("для записи нужна конкретная компания")
+	}
+	return tenant, nil
+}
+
+// TenantMiddleware кладёт в контекст запроса компанию из заголовка X-Tenant-ID
+// или DefaultTenant; APIKeyMiddleware затем заменяет её компанией API-ключа
+func TenantMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := r.Header.Get(tenantHeader)
+		if tenant == "" {
+			tenant = DefaultTenant
+		}
+		if err := ValidateTenant(tenant); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		trace.SpanFromContext(r.Context()).SetAttributes(attrTenant.String(tenant))
+		next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), tenant)))
+	})
+}
+
+// TenantServerOptions возвращает перехватчики gRPC, которые берут компанию
+// из метаданных x-tenant-id или DefaultTenant
+func TenantServerOptions() []grpc.ServerOption {
+	withTenant := func(ctx context.Context) (context.Context, error) {
+		tenant := DefaultTenant
+		md, _ := metadata.FromIncomingContext(ctx)
+		if tenants := md.Get(tenantHeader); len(tenants) > 0 {
+			tenant = tenants[0]
+		}
+		if err := ValidateTenant(tenant); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		trace.SpanFromContext(ctx).SetAttributes(attrTenant.String(tenant))
+		return WithTenant(ctx, tenant), nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := withTenant(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := withTenant(ss.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, contextStream{ServerStream: ss, ctx: ctx})
+		}),
+	}
+}
@@ -0,0 +1,73 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTenantIsolation проверяет, что компании не видят и не меняют посылки и настройки друг друга
+func TestTenantIsolation(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	suffix := randRange.Intn(10_000_000)
+	acme := WithTenant(context.Background(), fmt.Sprintf("acme-%d", suffix))
+	globex := WithTenant(context.Background(), fmt.Sprintf("globex-%d", suffix))
+	store := NewParcelStore(db)
+	service := NewParcelService(store)
+
+	// add
+	p := getTestParcel()
+	p.Client = randRange.Intn(10_000_000) + 1
+	p.Tenant = ""
+	number, err := store.WithContext(acme).Add(p)
+	require.NoError(t, err)
+
+	// check
+	stored, err := store.WithContext(acme).Get(number)
+	require.NoError(t, err)
+	assert.Equal(t, TenantFromContext(acme), stored.Tenant)
+	_, err = store.WithContext(globex).Get(number)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	parcels, err := store.WithContext(globex).GetByClient(p.Client)
+	require.NoError(t, err)
+	assert.Empty(t, parcels)
+	// фоновые задачи видят посылки всех компаний
+	_, err = store.WithContext(withAnyTenant(context.Background())).Get(number)
+	require.NoError(t, err)
+
+	// чужая компания не меняет статус посылки
+	require.NoError(t, store.WithContext(globex).SetStatus(number, ParcelStatusSent))
+	stored, err = store.WithContext(acme).Get(number)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusRegistered, stored.Status)
+
+	// у клиентов с одинаковым идентификатором в разных компаниях свои настройки
+	require.NoError(t, store.WithContext(acme).SetNotificationPreference(NotificationPreference{
+		Client: p.Client, Channel: "email", Recipient: "acme@example.com", Statuses: []string{ParcelStatusSent}}))
+	prefs, err := store.WithContext(globex).NotificationPreferences(p.Client)
+	require.NoError(t, err)
+	assert.Empty(t, prefs)
+
+	// компания берётся из API-ключа
+	key, err := service.IssueAPIKey(acme, p.Client)
+	require.NoError(t, err)
+	caller, err := service.Authenticate(context.Background(), key)
+	require.NoError(t, err)
+	assert.Equal(t, TenantFromContext(acme), caller.Tenant)
+	_, err = service.Get(WithCaller(globex, caller), number)
+	require.NoError(t, err)
+
+	// новые записи без конкретной компании не создаются
+	p.Tenant = ""
+	_, err = store.WithContext(withAnyTenant(context.Background())).Add(p)
+	assert.Error(t, err)
+	assert.ErrorIs(t, ValidateTenant(anyTenant), ErrInvalidTenant)
+}
@@ -0,0 +1,143 @@
+// Package testsupport содержит общие помощники тестов трекера: отдельную БД на каждый
+// тест, хранилище над ней и построение тестовых посылок. Помощники не делят состояние
+// между тестами, поэтому тесты с ними можно запускать через t.Parallel.
+//
+// Пакет импортирует tracker, поэтому им пользуются внешние тесты (package tracker_test)
+// и интеграционные тесты; тесты внутри пакета tracker обходятся своими помощниками
+// из testsupport_test.go.
+package testsupport
+
+import (
+	"database/sql"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+var (
+	// randMu защищает randGen: *rand.Rand нельзя вызывать из нескольких горутин
+	randMu  sync.Mutex
+	randGen = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	loadConfig = sync.OnceValue(func() config.Config {
+		cfg, err := config.Load("")
+		if err != nil {
+			panic(err)
+		}
+		return cfg
+	})
+)
+
+// Intn возвращает случайное число от 0 до n-1; её можно вызывать из параллельных тестов
+func Intn(n int) int {
+	randMu.Lock()
+	defer randMu.Unlock()
+	return randGen.Intn(n)
+}
+
+// Config возвращает настройки для тестов без YAML-файла: те же значения по умолчанию
+// и переменные окружения TRACKER_*, что и у приложения
+func Config() config.Config {
+	return loadConfig()
+}
+
+// DBConfig возвращает настройки отдельной БД теста: для SQLite — новый файл во
+// временном каталоге теста, который удаляется после теста. Для других драйверов
+// остаётся БД из настроек.
+func DBConfig(t testing.TB) config.DB {
+	t.Helper()
+	cfg := Config().DB
+	if cfg.Driver == "sqlite" {
+		cfg.Path = filepath.Join(t.TempDir(), "tracker.db")
+	}
+	return cfg
+}
+
+// MigrateDB открывает БД с настройками cfg, приводит её схему к последней версии
+// и закрывает БД после теста
+func MigrateDB(t testing.TB, cfg config.DB) *sql.DB {
+	t.Helper()
+	db, err := tracker.OpenDB(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, tracker.Migrate(db))
+	return db
+}
+
+// OpenDB открывает отдельную БД теста со схемой последней версии
+func OpenDB(t testing.TB) *sql.DB {
+	t.Helper()
+	return MigrateDB(t, DBConfig(t))
+}
+
+// NewStore возвращает хранилище над отдельной БД теста
+func NewStore(t testing.TB, opts ...tracker.StoreOption) tracker.ParcelStore {
+	t.Helper()
+	return tracker.NewParcelStore(OpenDB(t), opts...)
+}
+
+// MustAddParcel добавляет посылку p и возвращает её с номером; при ошибке тест проваливается
+func MustAddParcel(t testing.TB, store tracker.ParcelStore, p tracker.Parcel) tracker.Parcel {
+	t.Helper()
+	number, err := store.Add(p)
+	require.NoError(t, err)
+	p.Number = number
+	return p
+}
+
+// ParcelFixture строит тестовую посылку: от посылки по умолчанию меняются только
+// нужные тесту поля, например NewParcel().Client(7).Status(tracker.ParcelStatusSent).Build()
+type ParcelFixture struct {
+	p tracker.Parcel
+}
+
+// NewParcel начинает построение с зарегистрированной сейчас посылки клиента 1000
+// компании tracker.DefaultTenant
+func NewParcel() ParcelFixture {
+	return ParcelFixture{p: tracker.Parcel{
+		Client:    1000,
+		Status:    tracker.ParcelStatusRegistered,
+		Address:   "test",
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Tenant:    tracker.DefaultTenant,
+		Version:   1,
+		Priority:  tracker.PriorityStandard,
+	}}
+}
+
+func (f ParcelFixture) Client(client int) ParcelFixture {
+	f.p.Client = client
+	return f
+}
+
+func (f ParcelFixture) Status(status string) ParcelFixture {
+	f.p.Status = status
+	return f
+}
+
+func (f ParcelFixture) Address(address string) ParcelFixture {
+	f.p.Address = address
+	return f
+}
+
+func (f ParcelFixture) Tenant(tenant string) ParcelFixture {
+	f.p.Tenant = tenant
+	return f
+}
+
+// CreatedAt задаёт время регистрации посылки
+func (f ParcelFixture) CreatedAt(at time.Time) ParcelFixture {
+	f.p.CreatedAt = at.UTC().Format(time.RFC3339)
+	return f
+}
+
+func (f ParcelFixture) Build() tracker.Parcel {
+	return f.p
+}
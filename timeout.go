@@ -0,0 +1,104 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// ErrStoreTimeout возвращается, когда операция хранилища не уложилась в тайм-аут из
+// WithTimeouts: например, БД надолго заблокирована другим процессом или все соединения
+// пула заняты
+var ErrStoreTimeout = errors.New("БД не ответила вовремя")
+
+// Timeouts ограничения времени операций хранилища; 0 — без ограничения
+type Timeouts struct {
+	// Read один запрос чтения вместе с ожиданием соединения из пула и чтением строк
+	Read time.Duration
+	// Write одна запись: её транзакция и все повторы при блокировке БД
+	Write time.Duration
+}
+
+// DefaultTimeouts тайм-ауты хранилища по умолчанию
+var DefaultTimeouts = storeTimeouts(config.Default().DB.Timeouts)
+
+// storeTimeouts переводит настройки тайм-аутов в тайм-ауты хранилища
+func storeTimeouts(t config.Timeouts) Timeouts {
+	return Timeouts{Read: t.Read, Write: t.Write}
+}
+
+// WithTimeouts задаёт, сколько операция хранилища ждёт БД. Тайм-аут накладывается
+// на контекст операции из WithContext, поэтому запрос API, упёршийся в заблокированную
+// БД, получает ErrStoreTimeout, а не висит, пока блокировку не снимут.
+func WithTimeouts(t Timeouts) StoreOption {
+	return func(s *ParcelStore) {
+		s.timeouts = t
+	}
+}
+
+// withTimeout возвращает контекст операции хранилища, ограниченный тайм-аутом d
+func (s ParcelStore) withTimeout(d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(s.ctx)
+	}
+	return context.WithTimeout(s.ctx, d)
+}
+
+// timeoutError помечает ошибку err операции в контексте ctx как ErrStoreTimeout,
+// если операция прервана истёкшим тайм-аутом
+func timeoutError(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, ErrStoreTimeout) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrStoreTimeout, err)
+}
+
+// begin начинает транзакцию в контексте операции. Внутри withRetry контекст ограничен
+// тайм-аутом записи, и транзакция, не уложившаяся в него, откатывается.
+func (s ParcelStore) begin() (*sql.Tx, error) {
+	return s.db.BeginTx(s.ctx, nil)
+}
+
+// timedRow строка запроса, выполненного с тайм-аутом; Scan освобождает его контекст
+type timedRow struct {
+	*sql.Row
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (r timedRow) Scan(dest ...any) error {
+	defer r.cancel()
+	return timeoutError(r.ctx, r.Row.Scan(dest...))
+}
+
+// timedRows строки запроса, выполненного с тайм-аутом; Close освобождает его контекст.
+// Тайм-аут ограничивает и чтение строк: запрос, строки которого читаются дольше,
+// прерывается.
+type timedRows struct {
+	*sql.Rows
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (r timedRows) Err() error {
+	return timeoutError(r.ctx, r.Rows.Err())
+}
+
+func (r timedRows) Close() error {
+	defer r.cancel()
+	return r.Rows.Close()
+}
+
+// newTimedRows оборачивает результат запроса rows, err, выполненного в контексте ctx.
+// При ошибке контекст освобождается сразу.
+func newTimedRows(ctx context.Context, cancel context.CancelFunc, rows *sql.Rows, err error) (timedRows, error) {
+	if err != nil {
+		cancel()
+		return timedRows{}, timeoutError(ctx, err)
+	}
+	return timedRows{Rows: rows, ctx: ctx, cancel: cancel}, nil
+}
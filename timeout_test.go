@@ -0,0 +1,64 @@
+package tracker
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStoreTimeouts проверяет, что запись, ждущая заблокированную БД, и чтение, ждущее
+// занятое соединение пула, прерываются по тайм-ауту с ErrStoreTimeout
+func TestStoreTimeouts(t *testing.T) {
+	// prepare
+	// блокировку держит другое соединение, и запись повторяется, пока не истечёт тайм-аут
+	cfg := testConfig.DB
+	cfg.Path = filepath.Join(t.TempDir(), "timeout.db")
+	cfg.SQLite.BusyTimeout = 20 * time.Millisecond
+
+	locker, err := OpenDB(cfg)
+	require.NoError(t, err)
+	defer locker.Close()
+	require.NoError(t, Migrate(locker))
+
+	db, err := OpenDB(cfg)
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	timeouts := Timeouts{Read: 100 * time.Millisecond, Write: 200 * time.Millisecond}
+	store := NewParcelStore(db, WithTimeouts(timeouts), WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 50,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    20 * time.Millisecond,
+	}))
+	id := mustAddParcel(t, store, getTestParcel()).Number
+
+	// write
+	tx, err := locker.Begin()
+	require.NoError(t, err)
+	_, err = tx.Exec("DELETE FROM parcel_history WHERE number = -1")
+	require.NoError(t, err)
+
+	start := time.Now()
+	err = store.SetStatus(id, ParcelStatusSent)
+	assert.ErrorIs(t, err, ErrStoreTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, http.StatusGatewayTimeout, httpErrorCode(err))
+	require.NoError(t, tx.Rollback())
+	require.NoError(t, store.SetStatus(id, ParcelStatusSent))
+
+	// read
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	_, err = store.Get(id)
+	assert.ErrorIs(t, err, ErrStoreTimeout)
+	require.NoError(t, conn.Close())
+	p, err := store.Get(id)
+	require.NoError(t, err)
+	assert.Equal(t, ParcelStatusSent, p.Status)
+}
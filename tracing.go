@@ -0,0 +1,71 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// tracerName имя инструментирующей библиотеки в спанах трекера
+const tracerName = "github.com/DaniilStelmakh/tracker-parcel-go"
+
+// Атрибуты спанов с данными посылки
+var (
+	attrNumber = attribute.Key("parcel.number")
+	attrClient = attribute.Key("parcel.client")
+	attrStatus = attribute.Key("parcel.status")
+)
+
+// setupTracing настраивает глобальный TracerProvider по настройкам и возвращает функцию,
+// которая отправляет накопленные спаны и останавливает его. Без адреса коллектора
+// спаны не экспортируются, но контекст трассировки из входящих запросов всё равно передаётся дальше.
+func setupTracing(ctx context.Context, cfg config.Tracing) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// startSpan начинает спан метода хранилища в контексте s.ctx
+func (s ParcelStore) startSpan(method string, attrs ...attribute.KeyValue) trace.Span {
+	_, span := s.tracer.Start(s.ctx, "ParcelStore."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attrs, semconv.DBSystemSqlite)...))
+	return span
+}
+
+// spanError отмечает в спане ошибку err и возвращает её.
+// Отсутствие строк ошибкой не считается: это обычный ответ на поиск.
+func spanError(span trace.Span, err error) error {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestStoreSpans проверяет, что спаны хранилища продолжают трассировку запроса из контекста
+func TestStoreSpans(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	store := NewParcelStore(db, WithStoreTracerProvider(tp))
+	service := NewParcelService(store)
+
+	id, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+
+	// get
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	_, err = service.Get(ctx, id)
+	require.NoError(t, err)
+	parent.End()
+
+	// check
+	var get sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "ParcelStore.Get" {
+			get = span
+		}
+	}
+	require.NotNil(t, get)
+	assert.Equal(t, parent.SpanContext().SpanID(), get.Parent().SpanID())
+	assert.Contains(t, get.Attributes(), attrNumber.Int(id))
+}
@@ -0,0 +1,105 @@
+// Package trackermock содержит заглушку хранилища tracker.ParcelStorage для модульных
+// тестов сервиса и HTTP-обработчиков без БД.
+package trackermock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	tracker "github.com/DaniilStelmakh/tracker-parcel-go"
+)
+
+// ErrUnexpectedCall возвращается из метода, для которого тест не задал поведение
+var ErrUnexpectedCall = errors.New("неожиданный вызов хранилища")
+
+// Call вызов метода заглушки: имя метода ParcelStorage и аргументы без контекста
+type Call struct {
+	Method string
+	Args   []any
+}
+
+// Storage заглушка tracker.ParcelStorage. Каждый метод вызывает одноимённое поле
+// с суффиксом Func; если оно не задано, тест отмечается проваленным, а метод
+// возвращает ErrUnexpectedCall. Заглушку можно вызывать из нескольких горутин.
+type Storage struct {
+	AddFunc         func(ctx context.Context, p tracker.Parcel) (int, error)
+	GetFunc         func(ctx context.Context, number int) (tracker.Parcel, error)
+	GetByClientFunc func(ctx context.Context, client int) ([]tracker.Parcel, error)
+	SetStatusFunc   func(ctx context.Context, number int, status string) error
+	SetAddressFunc  func(ctx context.Context, number int, address string) error
+	DeleteFunc      func(ctx context.Context, number int) error
+
+	t     testing.TB
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ tracker.ParcelStorage = (*Storage)(nil)
+
+// NewStorage возвращает заглушку без заданного поведения: тест задаёт поля Func
+// тех методов, которые ожидает
+func NewStorage(t testing.TB) *Storage {
+	return &Storage{t: t}
+}
+
+// Calls возвращает вызовы заглушки в порядке их выполнения
+func (s *Storage) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// record запоминает вызов и сообщает, задано ли для метода поведение
+func (s *Storage) record(method string, defined bool, args ...any) bool {
+	s.mu.Lock()
+	s.calls = append(s.calls, Call{Method: method, Args: args})
+	s.mu.Unlock()
+	if !defined {
+		s.t.Errorf("trackermock: неожиданный вызов %s%v", method, args)
+	}
+	return defined
+}
+
+func (s *Storage) Add(ctx context.Context, p tracker.Parcel) (int, error) {
+	if !s.record("Add", s.AddFunc != nil, p) {
+		return 0, ErrUnexpectedCall
+	}
+	return s.AddFunc(ctx, p)
+}
+
+func (s *Storage) Get(ctx context.Context, number int) (tracker.Parcel, error) {
+	if !s.record("Get", s.GetFunc != nil, number) {
+		return tracker.Parcel{}, ErrUnexpectedCall
+	}
+	return s.GetFunc(ctx, number)
+}
+
+func (s *Storage) GetByClient(ctx context.Context, client int) ([]tracker.Parcel, error) {
+	if !s.record("GetByClient", s.GetByClientFunc != nil, client) {
+		return nil, ErrUnexpectedCall
+	}
+	return s.GetByClientFunc(ctx, client)
+}
+
+func (s *Storage) SetStatus(ctx context.Context, number int, status string) error {
+	if !s.record("SetStatus", s.SetStatusFunc != nil, number, status) {
+		return ErrUnexpectedCall
+	}
+	return s.SetStatusFunc(ctx, number, status)
+}
+
+func (s *Storage) SetAddress(ctx context.Context, number int, address string) error {
+	if !s.record("SetAddress", s.SetAddressFunc != nil, number, address) {
+		return ErrUnexpectedCall
+	}
+	return s.SetAddressFunc(ctx, number, address)
+}
+
+func (s *Storage) Delete(ctx context.Context, number int) error {
+	if !s.record("Delete", s.DeleteFunc != nil, number) {
+		return ErrUnexpectedCall
+	}
+	return s.DeleteFunc(ctx, number)
+}
@@ -0,0 +1,373 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: tracker.proto
+
+package trackerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ParcelTracker_AddParcel_FullMethodName    = "/tracker.v1.ParcelTracker/AddParcel"
+	ParcelTracker_GetParcel_FullMethodName    = "/tracker.v1.ParcelTracker/GetParcel"
+	ParcelTracker_ListByClient_FullMethodName = "/tracker.v1.ParcelTracker/ListByClient"
+	ParcelTracker_SetStatus_FullMethodName    = "/tracker.v1.ParcelTracker/SetStatus"
+	ParcelTracker_SetAddress_FullMethodName   = "/tracker.v1.ParcelTracker/SetAddress"
+	ParcelTracker_DeleteParcel_FullMethodName = "/tracker.v1.ParcelTracker/DeleteParcel"
+	ParcelTracker_WatchParcel_FullMethodName  = "/tracker.v1.ParcelTracker/WatchParcel"
+)
+
+// ParcelTrackerClient is the client API for ParcelTracker service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ParcelTracker предоставляет доступ к посылкам трекера для внутренних сервисов.
+type ParcelTrackerClient interface {
+	// AddParcel регистрирует новую посылку.
+	AddParcel(ctx context.Context, in *AddParcelRequest, opts ...grpc.CallOption) (*Parcel, error)
+	// GetParcel возвращает посылку по номеру.
+	GetParcel(ctx context.Context, in *GetParcelRequest, opts ...grpc.CallOption) (*Parcel, error)
+	// ListByClient возвращает все посылки клиента.
+	ListByClient(ctx context.Context, in *ListByClientRequest, opts ...grpc.CallOption) (*ListByClientResponse, error)
+	// SetStatus меняет статус посылки.
+	SetStatus(ctx context.Context, in *SetStatusRequest, opts ...grpc.CallOption) (*Empty, error)
+	// SetAddress меняет адрес доставки, если посылка ещё не отправлена.
+	SetAddress(ctx context.Context, in *SetAddressRequest, opts ...grpc.CallOption) (*Empty, error)
+	// DeleteParcel удаляет посылку, если она ещё не отправлена.
+	DeleteParcel(ctx context.Context, in *DeleteParcelRequest, opts ...grpc.CallOption) (*Empty, error)
+	// WatchParcel отправляет текущий статус посылки, а затем каждое его изменение.
+	// Поток завершается, когда посылка доставлена.
+	WatchParcel(ctx context.Context, in *WatchParcelRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ParcelStatusUpdate], error)
+}
+
+type parcelTrackerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewParcelTrackerClient(cc grpc.ClientConnInterface) ParcelTrackerClient {
+	return &parcelTrackerClient{cc}
+}
+
+func (c *parcelTrackerClient) AddParcel(ctx context.Context, in *AddParcelRequest, opts ...grpc.CallOption) (*Parcel, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Parcel)
+	err := c.cc.Invoke(ctx, ParcelTracker_AddParcel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parcelTrackerClient) GetParcel(ctx context.Context, in *GetParcelRequest, opts ...grpc.CallOption) (*Parcel, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Parcel)
+	err := c.cc.Invoke(ctx, ParcelTracker_GetParcel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parcelTrackerClient) ListByClient(ctx context.Context, in *ListByClientRequest, opts ...grpc.CallOption) (*ListByClientResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListByClientResponse)
+	err := c.cc.Invoke(ctx, ParcelTracker_ListByClient_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parcelTrackerClient) SetStatus(ctx context.Context, in *SetStatusRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, ParcelTracker_SetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parcelTrackerClient) SetAddress(ctx context.Context, in *SetAddressRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, ParcelTracker_SetAddress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parcelTrackerClient) DeleteParcel(ctx context.Context, in *DeleteParcelRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, ParcelTracker_DeleteParcel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *parcelTrackerClient) WatchParcel(ctx context.Context, in *WatchParcelRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ParcelStatusUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ParcelTracker_ServiceDesc.Streams[0], ParcelTracker_WatchParcel_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchParcelRequest, ParcelStatusUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ParcelTracker_WatchParcelClient = grpc.ServerStreamingClient[ParcelStatusUpdate]
+
+// ParcelTrackerServer is the server API for ParcelTracker service.
+// All implementations must embed UnimplementedParcelTrackerServer
+// for forward compatibility.
+//
+// ParcelTracker предоставляет доступ к посылкам трекера для внутренних сервисов.
+type ParcelTrackerServer interface {
+	// AddParcel регистрирует новую посылку.
+	AddParcel(context.Context, *AddParcelRequest) (*Parcel, error)
+	// GetParcel возвращает посылку по номеру.
+	GetParcel(context.Context, *GetParcelRequest) (*Parcel, error)
+	// ListByClient возвращает все посылки клиента.
+	ListByClient(context.Context, *ListByClientRequest) (*ListByClientResponse, error)
+	// SetStatus меняет статус посылки.
+	SetStatus(context.Context, *SetStatusRequest) (*Empty, error)
+	// SetAddress меняет адрес доставки, если посылка ещё не отправлена.
+	SetAddress(context.Context, *SetAddressRequest) (*Empty, error)
+	// DeleteParcel удаляет посылку, если она ещё не отправлена.
+	DeleteParcel(context.Context, *DeleteParcelRequest) (*Empty, error)
+	// WatchParcel отправляет текущий статус посылки, а затем каждое его изменение.
+	// Поток завершается, когда посылка доставлена.
+	WatchParcel(*WatchParcelRequest, grpc.ServerStreamingServer[ParcelStatusUpdate]) error
+	mustEmbedUnimplementedParcelTrackerServer()
+}
+
+// UnimplementedParcelTrackerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedParcelTrackerServer struct{}
+
+func (UnimplementedParcelTrackerServer) AddParcel(context.Context, *AddParcelRequest) (*Parcel, error) {
+	return nil, status.Error(codes.Unimplemented, "method AddParcel not implemented")
+}
+func (UnimplementedParcelTrackerServer) GetParcel(context.Context, *GetParcelRequest) (*Parcel, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetParcel not implemented")
+}
+func (UnimplementedParcelTrackerServer) ListByClient(context.Context, *ListByClientRequest) (*ListByClientResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListByClient not implemented")
+}
+func (UnimplementedParcelTrackerServer) SetStatus(context.Context, *SetStatusRequest) (*Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetStatus not implemented")
+}
+func (UnimplementedParcelTrackerServer) SetAddress(context.Context, *SetAddressRequest) (*Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetAddress not implemented")
+}
+func (UnimplementedParcelTrackerServer) DeleteParcel(context.Context, *DeleteParcelRequest) (*Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteParcel not implemented")
+}
+func (UnimplementedParcelTrackerServer) WatchParcel(*WatchParcelRequest, grpc.ServerStreamingServer[ParcelStatusUpdate]) error {
+	return status.Error(codes.Unimplemented, "method WatchParcel not implemented")
+}
+func (UnimplementedParcelTrackerServer) mustEmbedUnimplementedParcelTrackerServer() {}
+func (UnimplementedParcelTrackerServer) testEmbeddedByValue()                       {}
+
+// UnsafeParcelTrackerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ParcelTrackerServer will
+// result in compilation errors.
+type UnsafeParcelTrackerServer interface {
+	mustEmbedUnimplementedParcelTrackerServer()
+}
+
+func RegisterParcelTrackerServer(s grpc.ServiceRegistrar, srv ParcelTrackerServer) {
+	// If the following call panics, it indicates UnimplementedParcelTrackerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ParcelTracker_ServiceDesc, srv)
+}
+
+func _ParcelTracker_AddParcel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddParcelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParcelTrackerServer).AddParcel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParcelTracker_AddParcel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParcelTrackerServer).AddParcel(ctx, req.(*AddParcelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParcelTracker_GetParcel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetParcelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParcelTrackerServer).GetParcel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParcelTracker_GetParcel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParcelTrackerServer).GetParcel(ctx, req.(*GetParcelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParcelTracker_ListByClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListByClientRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParcelTrackerServer).ListByClient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParcelTracker_ListByClient_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParcelTrackerServer).ListByClient(ctx, req.(*ListByClientRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParcelTracker_SetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParcelTrackerServer).SetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParcelTracker_SetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParcelTrackerServer).SetStatus(ctx, req.(*SetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParcelTracker_SetAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParcelTrackerServer).SetAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParcelTracker_SetAddress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParcelTrackerServer).SetAddress(ctx, req.(*SetAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParcelTracker_DeleteParcel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteParcelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ParcelTrackerServer).DeleteParcel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ParcelTracker_DeleteParcel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ParcelTrackerServer).DeleteParcel(ctx, req.(*DeleteParcelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ParcelTracker_WatchParcel_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchParcelRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ParcelTrackerServer).WatchParcel(m, &grpc.GenericServerStream[WatchParcelRequest, ParcelStatusUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ParcelTracker_WatchParcelServer = grpc.ServerStreamingServer[ParcelStatusUpdate]
+
+// ParcelTracker_ServiceDesc is the grpc.ServiceDesc for ParcelTracker service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ParcelTracker_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tracker.v1.ParcelTracker",
+	HandlerType: (*ParcelTrackerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddParcel",
+			Handler:    _ParcelTracker_AddParcel_Handler,
+		},
+		{
+			MethodName: "GetParcel",
+			Handler:    _ParcelTracker_GetParcel_Handler,
+		},
+		{
+			MethodName: "ListByClient",
+			Handler:    _ParcelTracker_ListByClient_Handler,
+		},
+		{
+			MethodName: "SetStatus",
+			Handler:    _ParcelTracker_SetStatus_Handler,
+		},
+		{
+			MethodName: "SetAddress",
+			Handler:    _ParcelTracker_SetAddress_Handler,
+		},
+		{
+			MethodName: "DeleteParcel",
+			Handler:    _ParcelTracker_DeleteParcel_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchParcel",
+			Handler:       _ParcelTracker_WatchParcel_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "tracker.proto",
+}
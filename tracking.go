@@ -0,0 +1,55 @@
+package tracker
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+)
+
+// trackingTokenBytes количество случайных байт в трекинг-токене
+const trackingTokenBytes = 10
+
+// TrackingView публичное представление посылки для получателя:
+// без полного адреса и идентификатора клиента
+type TrackingView struct {
+	Status    string
+	City      string
+	CreatedAt string
+	// ETA ожидаемое время доставки; пусто, если оценки нет
+	ETA     string
+	History []ParcelChange
+	// Courier положение курьера, который везёт посылку; nil, если посылка ещё не в маршруте
+	Courier *CourierNearby
+}
+
+// newTrackingToken генерирует случайный трекинг-токен,
+// который нельзя подобрать перебором номеров посылок
+func newTrackingToken() (string, error) {
+	b := make([]byte, trackingTokenBytes)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)), nil
+}
+
+// newTrackingView собирает публичное представление посылки; город скрывается по режиму redaction.city
+func newTrackingView(p Parcel, history []ParcelChange, r Redactor) TrackingView {
+	// идентификаторы запросов служебные и получателю не показываются
+	for i := range history {
+		history[i].RequestID = ""
+	}
+	return TrackingView{
+		Status:    p.Status,
+		City:      r.City(addressCity(p.Address)),
+		CreatedAt: p.CreatedAt,
+		ETA:       p.ETA,
+		History:   history,
+	}
+}
+
+// addressCity возвращает город из адреса вида «Город, улица, дом»
+func addressCity(address string) string {
+	city, _, _ := strings.Cut(address, ",")
+	return strings.TrimSpace(city)
+}
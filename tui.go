@@ -0,0 +1,190 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tuiRefreshInterval как часто дашборд перечитывает посылки из БД,
+// чтобы видеть изменения других процессов
+const tuiRefreshInterval = 2 * time.Second
+
+// tuiStatusFilters порядок переключения фильтра по статусу; пустая строка — все статусы
+var tuiStatusFilters = append([]string{""}, parcelStatuses...)
+
+// tuiModel состояние терминального дашборда диспетчера
+type tuiModel struct {
+	service ParcelService
+
+	parcels []Parcel
+	cursor  int
+	err     error
+
+	statusFilter int
+	client       int
+	// editingClient включён ввод идентификатора клиента для фильтра
+	editingClient bool
+	clientInput   string
+}
+
+type tuiParcelsMsg struct {
+	parcels []Parcel
+	err     error
+}
+
+type tuiTickMsg struct{}
+
+func newTUIModel(service ParcelService) tuiModel {
+	return tuiModel{service: service}
+}
+
+// RunTUI запускает терминальный дашборд и работает до выхода пользователя
+func RunTUI(service ParcelService) error {
+	_, err := tea.NewProgram(newTUIModel(service), tea.WithAltScreen()).Run()
+	return err
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return tea.Batch(m.load(), tuiTick())
+}
+
+// load читает посылки с учётом текущих фильтров
+func (m tuiModel) load() tea.Cmd {
+	opts := ListOptions{Client: m.client, Status: tuiStatusFilters[m.statusFilter]}
+	return func() tea.Msg {
+		parcels, err := m.service.List(context.Background(), opts)
+		return tuiParcelsMsg{parcels: parcels, err: err}
+	}
+}
+
+func tuiTick() tea.Cmd {
+	return tea.Tick(tuiRefreshInterval, func(time.Time) tea.Msg { return tuiTickMsg{} })
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tuiParcelsMsg:
+		m.parcels, m.err = msg.parcels, msg.err
+		if m.cursor >= len(m.parcels) {
+			m.cursor = max(len(m.parcels)-1, 0)
+		}
+		return m, nil
+
+	case tuiTickMsg:
+		return m, tea.Batch(m.load(), tuiTick())
+
+	case tea.KeyMsg:
+		if m.editingClient {
+			return m.updateClientInput(msg)
+		}
+		return m.updateKeys(msg)
+	}
+
+	return m, nil
+}
+
+// updateKeys обрабатывает горячие клавиши списка
+func (m tuiModel) updateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.parcels)-1 {
+			m.cursor++
+		}
+	case "s":
+		m.statusFilter = (m.statusFilter + 1) % len(tuiStatusFilters)
+		m.cursor = 0
+		return m, m.load()
+	case "c":
+		m.editingClient = true
+		m.clientInput = ""
+	case "r":
+		return m, m.load()
+	case "n", "enter":
+		if len(m.parcels) == 0 {
+			return m, nil
+		}
+		p := m.parcels[m.cursor]
+		next, ok := NextParcelStatus(p.Status)
+		if !ok {
+			return m, nil
+		}
+		err := m.service.SetStatus(context.Background(), p.Number, next)
+		if err != nil {
+			m.err = err
+			return m, nil
+		}
+		return m, m.load()
+	}
+
+	return m, nil
+}
+
+// updateClientInput обрабатывает ввод идентификатора клиента; пустой ввод снимает фильтр
+func (m tuiModel) updateClientInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.editingClient = false
+		m.client, _ = strconv.Atoi(m.clientInput)
+		m.cursor = 0
+		return m, m.load()
+	case tea.KeyEsc:
+		m.editingClient = false
+	case tea.KeyBackspace:
+		if len(m.clientInput) > 0 {
+			m.clientInput = m.clientInput[:len(m.clientInput)-1]
+		}
+	case tea.KeyRunes:
+		for _, r := range msg.Runes {
+			if r >= '0' && r <= '9' {
+				m.clientInput += string(r)
+			}
+		}
+	}
+
+	return m, nil
+}
+
+func (m tuiModel) View() string {
+	var b strings.Builder
+
+	status := tuiStatusFilters[m.statusFilter]
+	if status == "" {
+		status = "все"
+	}
+	client := "все"
+	if m.client != 0 {
+		client = strconv.Itoa(m.client)
+	}
+	fmt.Fprintf(&b, "Посылки — статус: %s, клиент: %s, найдено: %d\n\n", status, client, len(m.parcels))
+
+	for i, p := range m.parcels {
+		marker := "  "
+		if i == m.cursor {
+			marker = "> "
+		}
+		fmt.Fprintf(&b, "%s№ %-6d клиент %-8d %-10s %s\n", marker, p.Number, p.Client, p.Status, p.Address)
+	}
+
+	b.WriteString("\n")
+	if m.err != nil {
+		fmt.Fprintf(&b, "Ошибка: %v\n", m.err)
+	}
+	if m.editingClient {
+		fmt.Fprintf(&b, "Клиент (Enter — применить, Esc — отмена): %s\n", m.clientInput)
+	} else {
+		b.WriteString("↑/↓ выбор · n следующий статус · s фильтр статуса · c фильтр клиента · r обновить · q выход\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrVersionMismatch возвращается, если посылка изменилась после того, как вызывающий
+// прочитал её версию: изменение по устаревшим данным не выполняется
+var ErrVersionMismatch = errors.New("посылка изменилась, получите её заново")
+
+// versionCond условие запроса к таблице parcel: версия посылки равна :version
+// или проверка версии не нужна
+const versionCond = "(:version = 0 OR version = :version)"
+
+// queryParcelVersion текущая версия посылки
+const queryParcelVersion = "SELECT version FROM parcel WHERE number = :number AND " + tenantCond
+
+type ifVersionKey struct{}
+
+// WithIfVersion возвращает контекст, операции хранилища в котором меняют и удаляют
+// посылку, только если её версия всё ещё version; иначе возвращается ErrVersionMismatch.
+// Каждое изменение статуса или адреса увеличивает версию на единицу.
+func WithIfVersion(ctx context.Context, version int) context.Context {
+	return context.WithValue(ctx, ifVersionKey{}, version)
+}
+
+// IfVersionFromContext возвращает ожидаемую версию посылки из контекста; 0 — без проверки
+func IfVersionFromContext(ctx context.Context) int {
+	version, _ := ctx.Value(ifVersionKey{}).(int)
+	return version
+}
+
+// ifVersion возвращает версию, которую операции хранилища ожидают у посылки
+func (s ParcelStore) ifVersion() int {
+	return IfVersionFromContext(s.ctx)
+}
+
+// versionMismatch объясняет, почему запрос с versionCond не изменил посылку в транзакции tx:
+// возвращает ErrVersionMismatch, если посылка есть, но её версия не та, что ожидалась
+func (s ParcelStore) versionMismatch(tx *sql.Tx, number int) error {
+	expected := s.ifVersion()
+	if expected == 0 {
+		return nil
+	}
+	var version int
+	err := s.queryRow(tx, queryParcelVersion, sql.Named("number", number), sql.Named("tenant", s.tenant())).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if version != expected {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// ETag возвращает сильный ETag HTTP для версии посылки
+func ETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// parseETag возвращает версию посылки из ETag; false — ETag не выдан трекером,
+// например слабый, и ни с одной версией не совпадает
+func parseETag(etag string) (int, bool) {
+	etag = strings.TrimSpace(etag)
+	unquoted, err := strconv.Unquote(etag)
+	if err != nil || !strings.HasPrefix(etag, `"`) {
+		return 0, false
+	}
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
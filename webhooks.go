@@ -0,0 +1,463 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Заголовки запроса с событием, который трекер отправляет на вебхук
+const (
+	webhookSignatureHeader = "X-Tracker-Signature"
+	webhookEventHeader     = "X-Tracker-Event"
+	webhookEventIDHeader   = "X-Tracker-Event-ID"
+)
+
+// webhookSecretBytes количество случайных байт в секрете подписи вебхука
+const webhookSecretBytes = 32
+
+// Состояния доставки события на вебхук
+const (
+	deliveryPending   = "pending"
+	deliveryDelivered = "delivered"
+	// deliveryDead доставка не удалась за все попытки; её можно повторить через ReplayWebhookFailure
+	deliveryDead = "dead"
+)
+
+// ErrInvalidWebhookURL возвращается при регистрации вебхука с адресом не http(s)
+var ErrInvalidWebhookURL = errors.New("адрес вебхука должен быть абсолютным http(s)-адресом")
+
+const (
+	queryInsertWebhook = `INSERT INTO webhook (client, url, secret, created_at, tenant_id)
+		VALUES (:client, :url, :secret, :created_at, :tenant)`
+	queryWebhooks         = "SELECT id, client, url, secret, created_at FROM webhook WHERE client = :client AND tenant_id = :tenant ORDER BY id"
+	queryDeleteWebhook    = "DELETE FROM webhook WHERE id = :id AND client = :client AND tenant_id = :tenant"
+	queryDeleteDeliveries = "DELETE FROM webhook_delivery WHERE webhook_id = :id"
+	// queryEnqueueWebhooks ставит событие в очередь доставки на все вебхуки клиента
+	queryEnqueueWebhooks = `INSERT INTO webhook_delivery (webhook_id, event_id, event_type, payload, state, next_attempt_at, created_at)
+		SELECT id, :event_id, :event_type, :payload, 'pending', :now, :now FROM webhook WHERE client = :client AND tenant_id = :tenant`
+	queryDueDeliveries = `SELECT d.id, d.webhook_id, w.client, w.url, w.secret, d.event_id, d.event_type, d.payload, d.state, d.attempts, d.last_error, d.created_at
+		FROM webhook_delivery d JOIN webhook w ON w.id = d.webhook_id
+		WHERE d.state = 'pending' AND d.next_attempt_at <= :now ORDER BY d.id LIMIT :limit`
+	queryDeadDeliveries = `SELECT d.id, d.webhook_id, w.client, w.url, w.secret, d.event_id, d.event_type, d.payload, d.state, d.attempts, d.last_error, d.created_at
+		FROM webhook_delivery d JOIN webhook w ON w.id = d.webhook_id
+		WHERE w.client = :client AND w.tenant_id = :tenant AND d.state = 'dead' ORDER BY d.id`
+	queryUpdateDelivery = "UPDATE webhook_delivery SET state = :state, attempts = :attempts, last_error = :last_error, next_attempt_at = :next_attempt_at WHERE id = :id"
+	queryReplayDelivery = `UPDATE webhook_delivery SET state = 'pending', attempts = 0, next_attempt_at = :now
+		WHERE id = :id AND state = 'dead' AND webhook_id IN (SELECT id FROM webhook WHERE client = :client AND tenant_id = :tenant)`
+)
+
+// Webhook адрес клиента, на который трекер отправляет события смены статусов его посылок.
+// Secret показывается только при регистрации: им клиент проверяет подпись событий.
+type Webhook struct {
+	ID        int64  `json:"id"`
+	Client    int    `json:"client"`
+	URL       string `json:"url"`
+	Secret    string `json:"secret,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// WebhookDelivery доставка одного события на один вебхук
+type WebhookDelivery struct {
+	ID        int64  `json:"id"`
+	WebhookID int64  `json:"webhook_id"`
+	Client    int    `json:"-"`
+	URL       string `json:"url"`
+	Secret    string `json:"-"`
+	EventID   int64  `json:"event_id"`
+	EventType string `json:"event_type"`
+	Payload   []byte `json:"-"`
+	State     string `json:"state"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// AddWebhook регистрирует вебхук и возвращает его идентификатор
+func (s ParcelStore) AddWebhook(w Webhook) (int64, error) {
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = s.withRetry("store.AddWebhook", func(s ParcelStore) error {
+		res, err := s.exec(nil, queryInsertWebhook,
+			sql.Named("client", w.Client),
+			sql.Named("url", w.URL),
+			sql.Named("secret", w.Secret),
+			sql.Named("created_at", w.CreatedAt),
+			sql.Named("tenant", tenant))
+		if err != nil {
+			return err
+		}
+		id, err = res.LastInsertId()
+		return err
+	})
+	return id, err
+}
+
+// Webhooks возвращает вебхуки клиента
+func (s ParcelStore) Webhooks(client int) ([]Webhook, error) {
+	st, err := s.stmt(nil, queryWebhooks)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := st.Query(sql.Named("client", client), sql.Named("tenant", s.tenant()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []Webhook
+	for rows.Next() {
+		var w Webhook
+		err := rows.Scan(&w.ID, &w.Client, &w.URL, &w.Secret, &w.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, w)
+	}
+	return res, rows.Err()
+}
+
+// DeleteWebhook удаляет вебхук клиента вместе с его доставками.
+// Для чужого или несуществующего вебхука возвращает sql.ErrNoRows.
+func (s ParcelStore) DeleteWebhook(client int, id int64) error {
+	return s.withRetry("store.DeleteWebhook", func(s ParcelStore) error {
+		tx, err := s.begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		res, err := s.exec(tx, queryDeleteWebhook, sql.Named("id", id), sql.Named("client", client),
+			sql.Named("tenant", s.tenant()))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		_, err = s.exec(tx, queryDeleteDeliveries, sql.Named("id", id))
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// enqueueWebhooks ставит событие outbox в очередь доставки на вебхуки клиента
+func (s ParcelStore) enqueueWebhooks(e OutboxEntry, client int) error {
+	return s.withRetry("store.EnqueueWebhooks", func(s ParcelStore) error {
+		_, err := s.exec(nil, queryEnqueueWebhooks,
+			sql.Named("event_id", e.ID),
+			sql.Named("event_type", e.Type),
+			sql.Named("payload", string(e.Payload)),
+			sql.Named("now", time.Now().UTC().Format(time.RFC3339)),
+			sql.Named("client", client),
+			sql.Named("tenant", s.tenant()))
+		return err
+	})
+}
+
+// dueDeliveries возвращает до limit доставок, время попытки которых наступило
+func (s ParcelStore) dueDeliveries(limit int) ([]WebhookDelivery, error) {
+	return s.deliveries(queryDueDeliveries,
+		sql.Named("now", time.Now().UTC().Format(time.RFC3339)),
+		sql.Named("limit", limit))
+}
+
+// DeadWebhookDeliveries возвращает доставки клиента, не удавшиеся за все попытки
+func (s ParcelStore) DeadWebhookDeliveries(client int) ([]WebhookDelivery, error) {
+	return s.deliveries(queryDeadDeliveries, sql.Named("client", client), sql.Named("tenant", s.tenant()))
+}
+
+func (s ParcelStore) deliveries(query string, args ...any) ([]WebhookDelivery, error) {
+	st, err := s.stmt(nil, query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := st.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload string
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.Client, &d.URL, &d.Secret, &d.EventID, &d.EventType,
+			&payload, &d.State, &d.Attempts, &d.LastError, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
+
+// updateDelivery сохраняет состояние доставки после попытки
+func (s ParcelStore) updateDelivery(d WebhookDelivery, next time.Time) error {
+	return s.withRetry("store.UpdateWebhookDelivery", func(s ParcelStore) error {
+		_, err := s.exec(nil, queryUpdateDelivery,
+			sql.Named("id", d.ID),
+			sql.Named("state", d.State),
+			sql.Named("attempts", d.Attempts),
+			sql.Named("last_error", d.LastError),
+			sql.Named("next_attempt_at", next.UTC().Format(time.RFC3339)))
+		return err
+	})
+}
+
+// ReplayWebhookDelivery возвращает неудавшуюся доставку клиента в очередь с новым запасом попыток.
+// Для чужой, несуществующей или не проваленной доставки возвращает sql.ErrNoRows.
+func (s ParcelStore) ReplayWebhookDelivery(client int, id int64) error {
+	return s.withRetry("store.ReplayWebhookDelivery", func(s ParcelStore) error {
+		res, err := s.exec(nil, queryReplayDelivery,
+			sql.Named("id", id),
+			sql.Named("client", client),
+			sql.Named("tenant", s.tenant()),
+			sql.Named("now", time.Now().UTC().Format(time.RFC3339)))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// signWebhook возвращает подпись тела события: sha256=<HMAC-SHA256 тела на секрете вебхука в hex>
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookDispatcher доставляет события смены статусов на вебхуки клиентов.
+// Как EventPublisher релея outbox он только ставит событие в очередь webhook_delivery,
+// а Run отправляет очередь, повторяя неудачные попытки с растущей задержкой.
+// Доставка, не удавшаяся за все попытки, остаётся в состоянии dead до повтора через API.
+type WebhookDispatcher struct {
+	store    ParcelStore
+	client   *http.Client
+	retry    RetryPolicy
+	interval time.Duration
+	batch    int
+}
+
+// NewWebhookDispatcher создаёт доставку вебхуков: раз в interval отправляется до batch
+// событий, каждый запрос ограничен timeout, повторы — по retry
+func NewWebhookDispatcher(store ParcelStore, retry RetryPolicy, timeout, interval time.Duration, batch int) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		store:    store,
+		client:   &http.Client{Timeout: timeout},
+		retry:    retry,
+		interval: interval,
+		batch:    batch,
+	}
+}
+
+// Publish ставит событие смены статуса в очередь доставки на вебхуки владельца посылки
+func (d *WebhookDispatcher) Publish(ctx context.Context, e OutboxEntry) error {
+	if e.Type != EventParcelStatusChanged {
+		return nil
+	}
+	var event OutboxEvent
+	err := json.Unmarshal(e.Payload, &event)
+	if err != nil {
+		return err
+	}
+	// идентификаторы клиентов у компаний свои, поэтому вебхуки берутся у компании посылки
+	return d.store.WithContext(WithTenant(ctx, event.Tenant)).enqueueWebhooks(e, event.Client)
+}
+
+// Run отправляет очередь доставки, пока не отменён ctx
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := d.DeliverDue(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			d.store.logger.Log(ctx, slog.LevelError, "не удалось отправить вебхуки", "op", "webhooks.Deliver", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue делает по одной попытке для доставок, время которых наступило,
+// и возвращает число успешных
+func (d *WebhookDispatcher) DeliverDue(ctx context.Context) (int, error) {
+	store := d.store.WithContext(ctx)
+	due, err := store.dueDeliveries(d.batch)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+
+		next := time.Now()
+		err := d.send(ctx, delivery)
+		delivery.Attempts++
+		switch {
+		case err == nil:
+			delivery.State = deliveryDelivered
+			delivery.LastError = ""
+			delivered++
+		case delivery.Attempts >= d.retry.MaxAttempts:
+			delivery.State = deliveryDead
+			delivery.LastError = err.Error()
+		default:
+			delivery.State = deliveryPending
+			delivery.LastError = err.Error()
+			next = next.Add(d.retry.delay(delivery.Attempts - 1))
+		}
+		if err != nil {
+			store.logger.Log(ctx, slog.LevelWarn, "вебхук не принял событие",
+				"op", "webhooks.Deliver", "delivery", delivery.ID, "attempt", delivery.Attempts, "state", delivery.State, "error", err)
+		}
+
+		err = store.updateDelivery(delivery, next)
+		if err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+// send отправляет событие на вебхук; успехом считается любой ответ 2xx
+func (d *WebhookDispatcher) send(ctx context.Context, delivery WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, delivery.EventType)
+	req.Header.Set(webhookEventIDHeader, strconv.FormatInt(delivery.EventID, 10))
+	req.Header.Set(webhookSignatureHeader, signWebhook(delivery.Secret, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("вебхук ответил %s", resp.Status)
+	}
+	return nil
+}
+
+// AddWebhook регистрирует вебхук клиента и возвращает его вместе с секретом подписи
+func (s ParcelService) AddWebhook(ctx context.Context, client int, rawURL string) (Webhook, error) {
+	err := s.checkWebhooks(ctx, client)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Webhook{}, ErrInvalidWebhookURL
+	}
+
+	b := make([]byte, webhookSecretBytes)
+	// crypto/rand.Read не возвращает ошибок
+	rand.Read(b)
+
+	w := Webhook{
+		Client:    client,
+		URL:       u.String(),
+		Secret:    hex.EncodeToString(b),
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	w.ID, err = s.store.WithContext(ctx).AddWebhook(w)
+	if err != nil {
+		return Webhook{}, err
+	}
+	return w, nil
+}
+
+// Webhooks возвращает вебхуки клиента без секретов
+func (s ParcelService) Webhooks(ctx context.Context, client int) ([]Webhook, error) {
+	err := s.checkWebhooks(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, err := s.store.WithContext(ctx).Webhooks(client)
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, err
+}
+
+// DeleteWebhook удаляет вебхук клиента
+func (s ParcelService) DeleteWebhook(ctx context.Context, client int, id int64) error {
+	err := s.checkWebhooks(ctx, client)
+	if err != nil {
+		return err
+	}
+	return s.store.WithContext(ctx).DeleteWebhook(client, id)
+}
+
+// WebhookFailures возвращает доставки событий клиенту, не удавшиеся за все попытки
+func (s ParcelService) WebhookFailures(ctx context.Context, client int) ([]WebhookDelivery, error) {
+	err := s.checkWebhooks(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.WithContext(ctx).DeadWebhookDeliveries(client)
+}
+
+// ReplayWebhookFailure повторяет неудавшуюся доставку события клиенту
+func (s ParcelService) ReplayWebhookFailure(ctx context.Context, client int, id int64) error {
+	err := s.checkWebhooks(ctx, client)
+	if err != nil {
+		return err
+	}
+	return s.store.WithContext(ctx).ReplayWebhookDelivery(client, id)
+}
+
+// checkWebhooks проверяет доступ к вебхукам клиента: клиент управляет только своими
+func (s ParcelService) checkWebhooks(ctx context.Context, client int) error {
+	err := s.check(ctx, actionWebhooks)
+	if err != nil {
+		return err
+	}
+	return authorizeOwner(ctx, client)
+}
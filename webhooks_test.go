@@ -0,0 +1,169 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DaniilStelmakh/tracker-parcel-go/api"
+	"github.com/DaniilStelmakh/tracker-parcel-go/config"
+)
+
+// webhookReceiver принимает события вебхука и отвечает 500, пока задан fail
+type webhookReceiver struct {
+	mu      sync.Mutex
+	fail    bool
+	bodies  [][]byte
+	headers []http.Header
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	r.bodies = append(r.bodies, body)
+	r.headers = append(r.headers, req.Header.Clone())
+}
+
+// TestWebhookDelivery проверяет подпись событий, перевод доставки в dead
+// после всех попыток и её повтор
+func TestWebhookDelivery(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	service := NewParcelService(store)
+	// записи, оставшиеся от других тестов, отправляем заранее
+	_, err := NewOutboxRelay(store, &recordingPublisher{}, 1_000_000).RelayOnce(context.Background())
+	require.NoError(t, err)
+
+	receiver := &webhookReceiver{fail: true}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	parcel := getTestParcel()
+	parcel.Client = randRange.Intn(10_000_000)
+
+	_, err = service.AddWebhook(context.Background(), parcel.Client, "ftp://example.com")
+	require.ErrorIs(t, err, ErrInvalidWebhookURL)
+
+	hook, err := service.AddWebhook(context.Background(), parcel.Client, srv.URL)
+	require.NoError(t, err)
+	require.NotEmpty(t, hook.Secret)
+	defer service.DeleteWebhook(context.Background(), parcel.Client, hook.ID)
+
+	id, err := store.Add(parcel)
+	require.NoError(t, err)
+	require.NoError(t, store.SetStatus(id, ParcelStatusSent))
+
+	dispatcher := NewWebhookDispatcher(store, RetryPolicy{MaxAttempts: 1}, 0, 0, 10)
+	_, err = NewOutboxRelay(store, dispatcher, 10).RelayOnce(context.Background())
+	require.NoError(t, err)
+
+	// deliver
+	// единственная попытка не удаётся, и доставка становится проваленной
+	n, err := dispatcher.DeliverDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	failures, err := service.WebhookFailures(context.Background(), parcel.Client)
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, EventParcelStatusChanged, failures[0].EventType)
+	assert.Equal(t, 1, failures[0].Attempts)
+	assert.NotEmpty(t, failures[0].LastError)
+
+	// replay
+	receiver.mu.Lock()
+	receiver.fail = false
+	receiver.mu.Unlock()
+	require.NoError(t, service.ReplayWebhookFailure(context.Background(), parcel.Client, failures[0].ID))
+	assert.ErrorIs(t, service.ReplayWebhookFailure(context.Background(), parcel.Client, failures[0].ID), sql.ErrNoRows)
+
+	n, err = dispatcher.DeliverDue(context.Background())
+	require.NoError(t, err)
+
+	// check
+	require.Equal(t, 1, n)
+	require.Len(t, receiver.bodies, 1)
+	assert.Equal(t, signWebhook(hook.Secret, receiver.bodies[0]), receiver.headers[0].Get(webhookSignatureHeader))
+	assert.Equal(t, EventParcelStatusChanged, receiver.headers[0].Get(webhookEventHeader))
+	assert.Equal(t, fmt.Sprint(failures[0].EventID), receiver.headers[0].Get(webhookEventIDHeader))
+	assert.Contains(t, string(receiver.bodies[0]), fmt.Sprintf(`"number":%d`, id))
+
+	failures, err = service.WebhookFailures(context.Background(), parcel.Client)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+
+	hooks, err := service.Webhooks(context.Background(), parcel.Client)
+	require.NoError(t, err)
+	require.Len(t, hooks, 1)
+	assert.Empty(t, hooks[0].Secret)
+}
+
+// TestHTTPWebhooks проверяет, что API вебхуков подключается флагом webhooks
+func TestHTTPWebhooks(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	// пул из одного соединения, как в настройках по умолчанию: вебхук удаляется в транзакции
+	service := NewParcelService(NewParcelStore(db, WithPool(PoolOptions{MaxOpenConns: 1, MaxIdleConns: 1})))
+	disabled := httptest.NewServer(newHTTPHandler(service, config.Features{}))
+	defer disabled.Close()
+	enabled := httptest.NewServer(newHTTPHandler(service, config.Features{config.FeatureWebhooks: true}))
+	defer enabled.Close()
+
+	client := randRange.Intn(10_000_000)
+	path := fmt.Sprintf("/clients/%d/webhooks", client)
+
+	// check
+	var list []api.Webhook
+	getJSON(t, disabled.URL+path, http.StatusNotFound, nil)
+	getJSON(t, enabled.URL+path, http.StatusOK, &list)
+	assert.Empty(t, list)
+
+	resp, err := http.Post(enabled.URL+path, "application/json", strings.NewReader(`{"url": "not a url"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(enabled.URL+path, "application/json", strings.NewReader(`{"url": "https://example.com/hook"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	getJSON(t, enabled.URL+path, http.StatusOK, &list)
+	require.Len(t, list, 1)
+	assert.Equal(t, "https://example.com/hook", list[0].Url)
+	assert.Nil(t, list[0].Secret)
+
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s%s/%d", enabled.URL, path, list[0].Id), nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,83 @@
+package tracker
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsRequest сообщение клиента WebSocket-канала: номера посылок,
+// на изменения которых нужно подписаться или от которых отписаться
+type wsRequest struct {
+	Subscribe   []int `json:"subscribe"`
+	Unsubscribe []int `json:"unsubscribe"`
+}
+
+var wsUpgrader = websocket.Upgrader{}
+
+// wsHandler обслуживает /ws: клиент присылает wsRequest,
+// сервер присылает ParcelChange по каждой посылке из подписки
+type wsHandler struct {
+	service ParcelService
+}
+
+func (h wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade уже ответил клиенту ошибкой
+		return
+	}
+	defer conn.Close()
+
+	changes, cancel := h.service.Subscribe()
+	defer cancel()
+
+	var mu sync.Mutex
+	numbers := map[int]bool{}
+
+	// чтение подписок; ошибка чтения означает, что клиент отключился
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var req wsRequest
+			err := conn.ReadJSON(&req)
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			for _, n := range req.Subscribe {
+				numbers[n] = true
+			}
+			for _, n := range req.Unsubscribe {
+				delete(numbers, n)
+			}
+			mu.Unlock()
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case c, ok := <-changes:
+			if !ok {
+				return
+			}
+
+			mu.Lock()
+			subscribed := numbers[c.Number]
+			mu.Unlock()
+			if !subscribed {
+				continue
+			}
+
+			err := conn.WriteJSON(c)
+			if err != nil {
+				return
+			}
+		}
+	}
+}
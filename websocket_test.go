@@ -0,0 +1,49 @@
+package tracker
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWebSocketSubscription проверяет, что по /ws приходят изменения только подписанных посылок
+func TestWebSocketSubscription(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	store := NewParcelStore(db)
+	srv := httptest.NewServer(NewHTTPHandler(NewParcelService(store)))
+	defer srv.Close()
+
+	watched, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+	other, err := store.Add(getTestParcel())
+	require.NoError(t, err)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// subscribe
+	require.NoError(t, conn.WriteJSON(wsRequest{Subscribe: []int{watched}}))
+	// подписка обрабатывается асинхронно, даём серверу её применить
+	time.Sleep(50 * time.Millisecond)
+
+	// check
+	// изменение чужой посылки не приходит, изменение подписанной — приходит
+	require.NoError(t, store.SetStatus(other, ParcelStatusSent))
+	require.NoError(t, store.SetStatus(watched, ParcelStatusSent))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	var change ParcelChange
+	require.NoError(t, conn.ReadJSON(&change))
+	assert.Equal(t, watched, change.Number)
+	assert.Equal(t, ParcelStatusSent, change.Status)
+}
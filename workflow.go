@@ -0,0 +1,246 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// unitOfWork шаги одного действия, записывающие изменения в общую транзакцию:
+// изменения всех шагов фиксируются вместе или вместе откатываются. Побочные эффекты
+// вне БД — метрики, обращения к внешним сервисам — шаги откладывают через afterCommit,
+// и они выполняются только после фиксации.
+type unitOfWork struct {
+	store ParcelStore
+	tx    *sql.Tx
+	// hooks действия после фиксации; nil, если транзакцией владеет вызывающий
+	hooks *[]func()
+}
+
+// inUnitOfWork выполняет work в новой транзакции с повторами при блокировке БД
+// и после фиксации вызывает отложенные через afterCommit действия
+func (s ParcelStore) inUnitOfWork(op string, work func(u unitOfWork) error) error {
+	var hooks []func()
+	err := s.withRetry(op, func(s ParcelStore) error {
+		// повтор начинает работу заново, действия прошлой попытки отбрасываются
+		hooks = nil
+		tx, err := s.begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		err = work(unitOfWork{store: s, tx: tx, hooks: &hooks})
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return err
+	}
+	for _, f := range hooks {
+		f()
+	}
+	return nil
+}
+
+// afterCommit откладывает f до фиксации транзакции. Если транзакцией владеет
+// вызывающий, f выполняется сразу.
+func (u unitOfWork) afterCommit(f func()) {
+	if u.hooks == nil {
+		f()
+		return
+	}
+	*u.hooks = append(*u.hooks, f)
+}
+
+// register добавляет посылку с начальной записью истории и возвращает её с номером
+// и компанией; requestID — идентификатор запроса, который её зарегистрировал
+func (u unitOfWork) register(p Parcel, requestID string) (Parcel, error) {
+	s := u.store
+	// посылки из пачки BatchWriter несут компанию запроса, который их зарегистрировал
+	if p.Tenant == "" || p.Tenant == anyTenant {
+		var err error
+		p.Tenant, err = s.ownTenant()
+		if err != nil {
+			return p, err
+		}
+	}
+	address, err := s.sealAddress(p.Address)
+	if err != nil {
+		return p, err
+	}
+	// добавление строки в таблицу parcel
+	// пустой трекинг-токен хранится как NULL, чтобы не нарушать уникальность
+	res, err := s.exec(u.tx, queryInsertParcel,
+		sql.Named("client", p.Client),
+		sql.Named("status", p.Status),
+		sql.Named("address", address),
+		sql.Named("created_at", p.CreatedAt),
+		sql.Named("tracking_token", p.TrackingToken),
+		sql.Named("tenant", p.Tenant),
+		sql.Named("eta", p.ETA),
+		sql.Named("priority", p.Priority),
+		sql.Named("weight", p.Weight),
+		sql.Named("price", p.Price),
+		sql.Named("deadline", p.Deadline),
+		sql.Named("postal", postalCode(p.Address)))
+	if err != nil {
+		return p, err
+	}
+	// возвращаем идентификатор последней добавленной записи
+	id, err := res.LastInsertId()
+	if err != nil {
+		return p, err
+	}
+	p.Number = int(id)
+
+	// начальный статус тоже попадает в историю
+	err = s.addHistory(u.tx, ParcelChange{Number: p.Number, Status: p.Status, ChangedAt: p.CreatedAt, RequestID: requestID})
+	return p, err
+}
+
+// notify записывает событие для уведомлений и получателей outbox; оно уходит
+// к ним только после фиксации
+func (u unitOfWork) notify(e OutboxEvent) error {
+	return u.store.addOutbox(u.tx, e)
+}
+
+// audit записывает действие в журнал аудита
+func (u unitOfWork) audit(e AuditEntry) error {
+	return u.store.addAudit(u.tx, e)
+}
+
+// registerParcel регистрирует посылку p шагами единицы работы: посылка с историей,
+// событие parcel.added и запись аудита — и возвращает её номер
+func (u unitOfWork) registerParcel(p Parcel, requestID string) (int, error) {
+	p, err := u.register(p, requestID)
+	if err != nil {
+		return 0, err
+	}
+	err = u.notify(OutboxEvent{Type: EventParcelAdded, Number: p.Number, Client: p.Client, Status: p.Status, Tenant: p.Tenant})
+	if err != nil {
+		return 0, err
+	}
+	err = u.audit(AuditEntry{Action: AuditRegister, Number: p.Number, Client: p.Client, Tenant: p.Tenant, RequestID: requestID})
+	if err != nil {
+		return 0, err
+	}
+	return p.Number, nil
+}
+
+// TrackParcelRequest посылка, которую регистрирует TrackParcelWorkflow
+type TrackParcelRequest struct {
+	Client  int
+	Address string
+	// Quote срочность и вес для расчёта стоимости по тарифу; nil — без расчёта
+	Quote *QuoteRequest
+}
+
+// TrackParcelWorkflow сценарий регистрации посылки: проверка и подготовка посылки,
+// выпуск трекинг-токена, затем единица работы — запись посылки, события для
+// уведомлений и записи аудита в одной транзакции — и после фиксации определение
+// координат адреса. Если любой шаг записи не удался, не остаётся ни посылки,
+// ни уведомления, ни записи аудита.
+type TrackParcelWorkflow struct {
+	service ParcelService
+}
+
+// NewTrackParcelWorkflow создаёт сценарий регистрации посылок сервиса s
+func NewTrackParcelWorkflow(s ParcelService) TrackParcelWorkflow {
+	return TrackParcelWorkflow{service: s}
+}
+
+// Run регистрирует посылку по req от имени пользователя из ctx
+func (w TrackParcelWorkflow) Run(ctx context.Context, req TrackParcelRequest) (Parcel, error) {
+	parcel, err := w.prepare(ctx, req)
+	if err != nil {
+		return parcel, err
+	}
+	parcel.TrackingToken, err = newTrackingToken()
+	if err != nil {
+		return parcel, err
+	}
+	parcel, err = w.record(ctx, parcel)
+	if err != nil {
+		return parcel, err
+	}
+	w.service.geocode(ctx, parcel.Number, parcel.Address)
+	return parcel, nil
+}
+
+// prepare проверяет права пользователя и заполняет посылку: зону, стоимость по тарифу,
+// обещанный срок и ожидаемое время доставки
+func (w TrackParcelWorkflow) prepare(ctx context.Context, req TrackParcelRequest) (Parcel, error) {
+	s := w.service
+	parcel := Parcel{
+		Client:    req.Client,
+		Status:    ParcelStatusRegistered,
+		Address:   req.Address,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	err := s.check(ctx, actionRegister)
+	if err != nil {
+		return parcel, err
+	}
+	err = authorizeOwner(ctx, req.Client)
+	if err != nil {
+		return parcel, err
+	}
+	// зону запрос регистрации определит сам, здесь она нужна только для ответа
+	parcel.Zone, err = s.store.WithContext(ctx).ZoneOf(req.Address)
+	if err != nil {
+		return parcel, err
+	}
+	if req.Quote != nil {
+		q, err := s.quote(ctx, req.Address, *req.Quote)
+		if err != nil {
+			return parcel, err
+		}
+		parcel.Priority, parcel.Weight, parcel.Price = q.Priority, req.Quote.WeightGrams, q.Price
+	}
+	parcel.Deadline, err = s.deadline(ctx, parcel)
+	if err != nil {
+		return parcel, err
+	}
+	if s.eta != nil {
+		parcel.ETA, err = s.eta.Estimate(ctx, parcel.Address, parcel.Status, time.Now())
+		if err != nil {
+			return parcel, err
+		}
+	}
+	return parcel, nil
+}
+
+// record записывает посылку единицей работы. Поиск повтора идёт в одной транзакции
+// с записью, а без него посылка может записаться пачкой вместе с посылками других
+// запросов; в обоих случаях транзакция делает те же шаги, что и registerParcel.
+func (w TrackParcelWorkflow) record(ctx context.Context, parcel Parcel) (Parcel, error) {
+	s := w.service
+	// нестабильный клиент может отправить одну посылку дважды; такие посылки не пишутся пачкой
+	if s.duplicates.Window > 0 {
+		return s.addUnique(ctx, parcel)
+	}
+	if s.writer != nil {
+		number, err := s.writer.Add(ctx, parcel)
+		parcel.Number = number
+		return parcel, err
+	}
+
+	start := time.Now()
+	store := s.store.WithContext(ctx)
+	err := store.inUnitOfWork("workflow.TrackParcel", func(u unitOfWork) error {
+		number, err := u.registerParcel(parcel, RequestIDFromContext(ctx))
+		if err != nil {
+			return err
+		}
+		parcel.Number = number
+		u.afterCommit(func() { store.added(parcel.Number) })
+		return nil
+	})
+	store.metrics.observeQuery("TrackParcel", start)
+	logResult(ctx, store.logger, "workflow.TrackParcel", start, err, "number", parcel.Number, "client", parcel.Client)
+	return parcel, err
+}
@@ -0,0 +1,60 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTrackParcelWorkflow проверяет, что регистрация записывает посылку, событие
+// для уведомлений и запись аудита вместе, а сбой любого шага не оставляет ничего
+func TestTrackParcelWorkflow(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+	metrics := NewMetrics()
+	store := NewParcelStore(db, WithStoreMetrics(metrics))
+	workflow := NewTrackParcelWorkflow(NewParcelService(store))
+	ctx := WithRequestID(context.Background(), "req-track")
+	req := TrackParcelRequest{Client: 1000, Address: "Псков, ул. Колотушкина, д. 5"}
+
+	// run
+	p, err := workflow.Run(ctx, req)
+	require.NoError(t, err)
+	assert.NotEmpty(t, p.TrackingToken)
+
+	// check
+	stored, err := store.Get(p.Number)
+	require.NoError(t, err)
+	assert.Equal(t, p.TrackingToken, stored.TrackingToken)
+	audit, err := store.AuditLog(p.Number)
+	require.NoError(t, err)
+	require.Len(t, audit, 1)
+	assert.Equal(t, AuditRegister, audit[0].Action)
+	assert.Equal(t, req.Client, audit[0].Client)
+	assert.Equal(t, "req-track", audit[0].RequestID)
+	pending, err := store.PendingOutbox(10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, EventParcelAdded, pending[0].Type)
+	assert.Equal(t, p.Number, pending[0].Number)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.parcelsAdded))
+
+	// rollback
+	// без журнала аудита последний шаг не выполняется, и откатываются все
+	_, err = db.Exec("DROP TABLE audit_log")
+	require.NoError(t, err)
+	_, err = workflow.Run(ctx, req)
+	require.Error(t, err)
+	parcels, err := store.GetByClient(req.Client)
+	require.NoError(t, err)
+	assert.Len(t, parcels, 1)
+	pending, err = store.PendingOutbox(10)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.parcelsAdded))
+}
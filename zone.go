@@ -0,0 +1,216 @@
+package tracker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidZone возвращается при зоне без имени или с неверным диапазоном индексов
+	ErrInvalidZone = errors.New("неверная зона доставки")
+	// ErrZoneOverlap возвращается, если диапазон индексов зоны пересекается с другой зоной
+	ErrZoneOverlap = errors.New("диапазон индексов пересекается с другой зоной")
+)
+
+// postalCodePattern почтовый индекс в адресе: шесть цифр подряд
+var postalCodePattern = regexp.MustCompile(`(?:^|\D)(\d{6})(?:\D|$)`)
+
+const (
+	// zoneByPostal зона компании :tenant, в диапазон которой попадает индекс :postal;
+	// для адреса без индекса — NULL. Подставляется в запросы записи посылки.
+	zoneByPostal = `(SELECT name FROM zone WHERE tenant_id = :tenant AND postal_from <= :postal AND postal_to >= :postal)`
+	// parcelZoneByPostal то же для смены адреса: зона ищется среди зон компании посылки
+	parcelZoneByPostal = `(SELECT z.name FROM zone z WHERE z.tenant_id = parcel.tenant_id AND z.postal_from <= :postal AND z.postal_to >= :postal)`
+
+	queryUpsertZone = `INSERT INTO zone (tenant_id, name, postal_from, postal_to) VALUES (:tenant, :name, :postal_from, :postal_to)
+		ON CONFLICT (tenant_id, postal_from) DO UPDATE SET name = excluded.name, postal_to = excluded.postal_to`
+	// queryOverlappingZone другая зона, диапазон которой пересекается с новым
+	queryOverlappingZone = `SELECT name FROM zone WHERE tenant_id = :tenant AND postal_from != :postal_from
+		AND postal_from <= :postal_to AND postal_to >= :postal_from LIMIT 1`
+	queryZones      = "SELECT name, postal_from, postal_to FROM zone WHERE tenant_id = :tenant ORDER BY postal_from"
+	queryDeleteZone = "DELETE FROM zone WHERE tenant_id = :tenant AND postal_from = :postal_from"
+	queryZoneOf     = "SELECT COALESCE(" + zoneByPostal + ", '')"
+)
+
+// Zone зона доставки: диапазон почтовых индексов от From до To включительно
+type Zone struct {
+	Name string
+	From string
+	To   string
+}
+
+// postalCode возвращает первый почтовый индекс в адресе или пустую строку
+func postalCode(address string) string {
+	m := postalCodePattern.FindStringSubmatch(address)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// validate проверяет имя зоны и что границы — индексы, причём From не больше To
+func (z Zone) validate() error {
+	if z.Name == "" || postalCode(z.From) != z.From || postalCode(z.To) != z.To || z.From > z.To {
+		return fmt.Errorf("%w: %s %s-%s", ErrInvalidZone, z.Name, z.From, z.To)
+	}
+	return nil
+}
+
+// SetZone добавляет зону компании из контекста хранилища или меняет зону с той же
+// нижней границей. Диапазоны зон не пересекаются. Зона определяется при регистрации
+// и смене адреса, поэтому уже записанные посылки остаются в прежних зонах.
+func (s ParcelStore) SetZone(z Zone) error {
+	start := time.Now()
+	span := s.startSpan("SetZone")
+	defer span.End()
+
+	err := s.withRetry("store.SetZone", func(s ParcelStore) error {
+		return s.setZone(z)
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("SetZone", start)
+	logResult(s.ctx, s.logger, "store.SetZone", start, err, "zone", z.Name, "from", z.From, "to", z.To)
+	return err
+}
+
+func (s ParcelStore) setZone(z Zone) error {
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return err
+	}
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var other string
+	err = s.queryRow(tx, queryOverlappingZone,
+		sql.Named("tenant", tenant),
+		sql.Named("postal_from", z.From),
+		sql.Named("postal_to", z.To)).Scan(&other)
+	if err == nil {
+		return fmt.Errorf("%w %s", ErrZoneOverlap, other)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	_, err = s.exec(tx, queryUpsertZone,
+		sql.Named("tenant", tenant),
+		sql.Named("name", z.Name),
+		sql.Named("postal_from", z.From),
+		sql.Named("postal_to", z.To))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteZone удаляет зону компании с нижней границей from
+func (s ParcelStore) DeleteZone(from string) error {
+	start := time.Now()
+	span := s.startSpan("DeleteZone")
+	defer span.End()
+
+	err := s.withRetry("store.DeleteZone", func(s ParcelStore) error {
+		tenant, err := s.ownTenant()
+		if err != nil {
+			return err
+		}
+		res, err := s.exec(nil, queryDeleteZone, sql.Named("tenant", tenant), sql.Named("postal_from", from))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+	spanError(span, err)
+	s.metrics.observeQuery("DeleteZone", start)
+	logResult(s.ctx, s.logger, "store.DeleteZone", start, err, "from", from)
+	return err
+}
+
+// Zones возвращает зоны компании из контекста хранилища в порядке индексов
+func (s ParcelStore) Zones() ([]Zone, error) {
+	defer s.metrics.observeQuery("Zones", time.Now())
+	span := s.startSpan("Zones")
+	defer span.End()
+
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	rows, err := s.readQuery(queryZones, sql.Named("tenant", tenant))
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	defer rows.Close()
+
+	var res []Zone
+	for rows.Next() {
+		var z Zone
+		err := rows.Scan(&z.Name, &z.From, &z.To)
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		res = append(res, z)
+	}
+	return res, spanError(span, rows.Err())
+}
+
+// ZoneOf возвращает зону адреса address по его почтовому индексу или пустую строку,
+// если индекса в адресе нет или он не входит ни в одну зону
+func (s ParcelStore) ZoneOf(address string) (string, error) {
+	defer s.metrics.observeQuery("ZoneOf", time.Now())
+
+	tenant, err := s.ownTenant()
+	if err != nil {
+		return "", err
+	}
+	var zone string
+	err = s.readRow(queryZoneOf, sql.Named("tenant", tenant), sql.Named("postal", postalCode(address))).Scan(&zone)
+	return zone, err
+}
+
+// SetZone задаёт зону компании из ctx; доступно только администратору
+func (s ParcelService) SetZone(ctx context.Context, z Zone) error {
+	err := s.check(ctx, actionTariffs)
+	if err != nil {
+		return err
+	}
+	z.Name = strings.ToLower(strings.TrimSpace(z.Name))
+	err = z.validate()
+	if err != nil {
+		return err
+	}
+	return s.store.WithContext(ctx).SetZone(z)
+}
+
+// DeleteZone удаляет зону компании из ctx; доступно только администратору
+func (s ParcelService) DeleteZone(ctx context.Context, from string) error {
+	err := s.check(ctx, actionTariffs)
+	if err != nil {
+		return err
+	}
+	return s.store.WithContext(ctx).DeleteZone(from)
+}
+
+// Zones возвращает зоны компании из ctx
+func (s ParcelService) Zones(ctx context.Context) ([]Zone, error) {
+	err := s.check(ctx, actionRead)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.WithContext(ctx).Zones()
+}
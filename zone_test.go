@@ -0,0 +1,88 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestZones проверяет зоны по индексам: зону посылки, выборку по зоне, тариф и маршрут зоны
+func TestZones(t *testing.T) {
+	t.Parallel()
+
+	// prepare
+	db := openTestDB(t)
+
+	tenant := fmt.Sprintf("zone-%d", randRange.Intn(10_000_000))
+	ctx := WithTenant(context.Background(), tenant)
+	service := NewParcelService(NewParcelStore(db))
+
+	// set
+	require.NoError(t, service.SetZone(ctx, Zone{Name: "Северо-Запад", From: "180000", To: "189999"}))
+	require.NoError(t, service.SetZone(ctx, Zone{Name: "центр", From: "170000", To: "179999"}))
+	assert.ErrorIs(t, service.SetZone(ctx, Zone{Name: "юг", From: "175000", To: "180500"}), ErrZoneOverlap)
+	assert.ErrorIs(t, service.SetZone(ctx, Zone{Name: "юг", From: "350000", To: "34"}), ErrInvalidZone)
+	assert.ErrorIs(t, service.SetZone(WithCaller(ctx, Caller{Client: 3, Role: RoleOperator}), Zone{Name: "юг", From: "350000", To: "359999"}), ErrForbidden)
+	zones, err := service.Zones(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Zone{{Name: "центр", From: "170000", To: "179999"}, {Name: "северо-запад", From: "180000", To: "189999"}}, zones)
+
+	// register
+	pskov, err := service.Register(ctx, 1000, "180000, Псков, ул. Колотушкина, д. 5")
+	require.NoError(t, err)
+	assert.Equal(t, "северо-запад", pskov.Zone)
+	tver, err := service.Register(ctx, 1000, "Тверь, ул. Советская, д. 1, 170100")
+	require.NoError(t, err)
+	stored, err := service.Get(ctx, tver.Number)
+	require.NoError(t, err)
+	assert.Equal(t, "центр", stored.Zone)
+	unknown, err := service.Register(ctx, 1000, "Сочи, ул. Морская, д. 1")
+	require.NoError(t, err)
+	assert.Empty(t, unknown.Zone)
+
+	parcels, err := service.List(ctx, ListOptions{Zone: "северо-запад"})
+	require.NoError(t, err)
+	require.Len(t, parcels, 1)
+	assert.Equal(t, pskov.Number, parcels[0].Number)
+
+	// при смене адреса зона определяется заново
+	require.NoError(t, service.ChangeAddress(ctx, tver.Number, "182100, Великие Луки, ул. Ленина, д. 1"))
+	stored, err = service.Get(ctx, tver.Number)
+	require.NoError(t, err)
+	assert.Equal(t, "северо-запад", stored.Zone)
+
+	// тариф зоны важнее тарифа города
+	require.NoError(t, service.SetTariff(ctx, Tariff{Zone: "псков", Priority: PriorityStandard, Base: 10000}))
+	require.NoError(t, service.SetTariff(ctx, Tariff{Zone: "северо-запад", Priority: PriorityStandard, Base: 20000}))
+	q, err := service.Quote(ctx, "180000, Псков, ул. Колотушкина, д. 5", QuoteRequest{WeightGrams: 500, LengthCm: 10, WidthCm: 10, HeightCm: 10})
+	require.NoError(t, err)
+	assert.Equal(t, Quote{Zone: "северо-запад", Priority: PriorityStandard, ChargeableGrams: 500, Price: 20000}, q)
+	q, err = service.Quote(ctx, "Псков, ул. Колотушкина, д. 5", QuoteRequest{WeightGrams: 500, LengthCm: 10, WidthCm: 10, HeightCm: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "псков", q.Zone)
+
+	// route
+	route, err := service.BuildZoneRoute(ctx, 7, "2024-05-01", "северо-запад")
+	require.NoError(t, err)
+	assert.Equal(t, []int{pskov.Number, tver.Number}, route.Parcels)
+	// посылки зоны уже в маршруте
+	_, err = service.BuildZoneRoute(ctx, 8, "2024-05-01", "северо-запад")
+	assert.ErrorIs(t, err, ErrInvalidRoute)
+
+	// delete
+	require.NoError(t, service.DeleteZone(ctx, "170000"))
+	zones, err = service.Zones(ctx)
+	require.NoError(t, err)
+	assert.Len(t, zones, 1)
+}
+
+// TestPostalCode проверяет поиск почтового индекса в адресе
+func TestPostalCode(t *testing.T) {
+	assert.Equal(t, "180000", postalCode("180000, Псков, ул. Колотушкина, д. 5"))
+	assert.Equal(t, "170100", postalCode("Тверь, ул. Советская, д. 1, 170100"))
+	assert.Empty(t, postalCode("Псков, ул. Колотушкина, д. 1800001"))
+	assert.Empty(t, postalCode("Псков, ул. Колотушкина, д. 5"))
+}